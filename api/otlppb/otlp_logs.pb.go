@@ -0,0 +1,1143 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v6.33.2
+// source: otlp_logs.proto
+
+// The OpenTelemetry logs service, trimmed to what the OTLP receiver reads.
+// Field numbers and the service name match opentelemetry-proto v1.x, so
+// any OTLP/gRPC exporter can send to it; the messages the upstream
+// definition spreads over the common, resource and logs packages live in
+// this one file.
+
+package otlppb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SeverityNumber int32
+
+const (
+	SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED SeverityNumber = 0
+	SeverityNumber_SEVERITY_NUMBER_TRACE       SeverityNumber = 1
+	SeverityNumber_SEVERITY_NUMBER_TRACE2      SeverityNumber = 2
+	SeverityNumber_SEVERITY_NUMBER_TRACE3      SeverityNumber = 3
+	SeverityNumber_SEVERITY_NUMBER_TRACE4      SeverityNumber = 4
+	SeverityNumber_SEVERITY_NUMBER_DEBUG       SeverityNumber = 5
+	SeverityNumber_SEVERITY_NUMBER_DEBUG2      SeverityNumber = 6
+	SeverityNumber_SEVERITY_NUMBER_DEBUG3      SeverityNumber = 7
+	SeverityNumber_SEVERITY_NUMBER_DEBUG4      SeverityNumber = 8
+	SeverityNumber_SEVERITY_NUMBER_INFO        SeverityNumber = 9
+	SeverityNumber_SEVERITY_NUMBER_INFO2       SeverityNumber = 10
+	SeverityNumber_SEVERITY_NUMBER_INFO3       SeverityNumber = 11
+	SeverityNumber_SEVERITY_NUMBER_INFO4       SeverityNumber = 12
+	SeverityNumber_SEVERITY_NUMBER_WARN        SeverityNumber = 13
+	SeverityNumber_SEVERITY_NUMBER_WARN2       SeverityNumber = 14
+	SeverityNumber_SEVERITY_NUMBER_WARN3       SeverityNumber = 15
+	SeverityNumber_SEVERITY_NUMBER_WARN4       SeverityNumber = 16
+	SeverityNumber_SEVERITY_NUMBER_ERROR       SeverityNumber = 17
+	SeverityNumber_SEVERITY_NUMBER_ERROR2      SeverityNumber = 18
+	SeverityNumber_SEVERITY_NUMBER_ERROR3      SeverityNumber = 19
+	SeverityNumber_SEVERITY_NUMBER_ERROR4      SeverityNumber = 20
+	SeverityNumber_SEVERITY_NUMBER_FATAL       SeverityNumber = 21
+	SeverityNumber_SEVERITY_NUMBER_FATAL2      SeverityNumber = 22
+	SeverityNumber_SEVERITY_NUMBER_FATAL3      SeverityNumber = 23
+	SeverityNumber_SEVERITY_NUMBER_FATAL4      SeverityNumber = 24
+)
+
+// Enum value maps for SeverityNumber.
+var (
+	SeverityNumber_name = map[int32]string{
+		0:  "SEVERITY_NUMBER_UNSPECIFIED",
+		1:  "SEVERITY_NUMBER_TRACE",
+		2:  "SEVERITY_NUMBER_TRACE2",
+		3:  "SEVERITY_NUMBER_TRACE3",
+		4:  "SEVERITY_NUMBER_TRACE4",
+		5:  "SEVERITY_NUMBER_DEBUG",
+		6:  "SEVERITY_NUMBER_DEBUG2",
+		7:  "SEVERITY_NUMBER_DEBUG3",
+		8:  "SEVERITY_NUMBER_DEBUG4",
+		9:  "SEVERITY_NUMBER_INFO",
+		10: "SEVERITY_NUMBER_INFO2",
+		11: "SEVERITY_NUMBER_INFO3",
+		12: "SEVERITY_NUMBER_INFO4",
+		13: "SEVERITY_NUMBER_WARN",
+		14: "SEVERITY_NUMBER_WARN2",
+		15: "SEVERITY_NUMBER_WARN3",
+		16: "SEVERITY_NUMBER_WARN4",
+		17: "SEVERITY_NUMBER_ERROR",
+		18: "SEVERITY_NUMBER_ERROR2",
+		19: "SEVERITY_NUMBER_ERROR3",
+		20: "SEVERITY_NUMBER_ERROR4",
+		21: "SEVERITY_NUMBER_FATAL",
+		22: "SEVERITY_NUMBER_FATAL2",
+		23: "SEVERITY_NUMBER_FATAL3",
+		24: "SEVERITY_NUMBER_FATAL4",
+	}
+	SeverityNumber_value = map[string]int32{
+		"SEVERITY_NUMBER_UNSPECIFIED": 0,
+		"SEVERITY_NUMBER_TRACE":       1,
+		"SEVERITY_NUMBER_TRACE2":      2,
+		"SEVERITY_NUMBER_TRACE3":      3,
+		"SEVERITY_NUMBER_TRACE4":      4,
+		"SEVERITY_NUMBER_DEBUG":       5,
+		"SEVERITY_NUMBER_DEBUG2":      6,
+		"SEVERITY_NUMBER_DEBUG3":      7,
+		"SEVERITY_NUMBER_DEBUG4":      8,
+		"SEVERITY_NUMBER_INFO":        9,
+		"SEVERITY_NUMBER_INFO2":       10,
+		"SEVERITY_NUMBER_INFO3":       11,
+		"SEVERITY_NUMBER_INFO4":       12,
+		"SEVERITY_NUMBER_WARN":        13,
+		"SEVERITY_NUMBER_WARN2":       14,
+		"SEVERITY_NUMBER_WARN3":       15,
+		"SEVERITY_NUMBER_WARN4":       16,
+		"SEVERITY_NUMBER_ERROR":       17,
+		"SEVERITY_NUMBER_ERROR2":      18,
+		"SEVERITY_NUMBER_ERROR3":      19,
+		"SEVERITY_NUMBER_ERROR4":      20,
+		"SEVERITY_NUMBER_FATAL":       21,
+		"SEVERITY_NUMBER_FATAL2":      22,
+		"SEVERITY_NUMBER_FATAL3":      23,
+		"SEVERITY_NUMBER_FATAL4":      24,
+	}
+)
+
+func (x SeverityNumber) Enum() *SeverityNumber {
+	p := new(SeverityNumber)
+	*p = x
+	return p
+}
+
+func (x SeverityNumber) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SeverityNumber) Descriptor() protoreflect.EnumDescriptor {
+	return file_otlp_logs_proto_enumTypes[0].Descriptor()
+}
+
+func (SeverityNumber) Type() protoreflect.EnumType {
+	return &file_otlp_logs_proto_enumTypes[0]
+}
+
+func (x SeverityNumber) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SeverityNumber.Descriptor instead.
+func (SeverityNumber) EnumDescriptor() ([]byte, []int) {
+	return file_otlp_logs_proto_rawDescGZIP(), []int{0}
+}
+
+type ExportLogsServiceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ResourceLogs  []*ResourceLogs        `protobuf:"bytes,1,rep,name=resource_logs,json=resourceLogs,proto3" json:"resource_logs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportLogsServiceRequest) Reset() {
+	*x = ExportLogsServiceRequest{}
+	mi := &file_otlp_logs_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportLogsServiceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportLogsServiceRequest) ProtoMessage() {}
+
+func (x *ExportLogsServiceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_otlp_logs_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportLogsServiceRequest.ProtoReflect.Descriptor instead.
+func (*ExportLogsServiceRequest) Descriptor() ([]byte, []int) {
+	return file_otlp_logs_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ExportLogsServiceRequest) GetResourceLogs() []*ResourceLogs {
+	if x != nil {
+		return x.ResourceLogs
+	}
+	return nil
+}
+
+type ExportLogsServiceResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Set when some records were rejected; unset means all were accepted.
+	PartialSuccess *ExportLogsPartialSuccess `protobuf:"bytes,1,opt,name=partial_success,json=partialSuccess,proto3" json:"partial_success,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ExportLogsServiceResponse) Reset() {
+	*x = ExportLogsServiceResponse{}
+	mi := &file_otlp_logs_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportLogsServiceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportLogsServiceResponse) ProtoMessage() {}
+
+func (x *ExportLogsServiceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_otlp_logs_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportLogsServiceResponse.ProtoReflect.Descriptor instead.
+func (*ExportLogsServiceResponse) Descriptor() ([]byte, []int) {
+	return file_otlp_logs_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ExportLogsServiceResponse) GetPartialSuccess() *ExportLogsPartialSuccess {
+	if x != nil {
+		return x.PartialSuccess
+	}
+	return nil
+}
+
+type ExportLogsPartialSuccess struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	RejectedLogRecords int64                  `protobuf:"varint,1,opt,name=rejected_log_records,json=rejectedLogRecords,proto3" json:"rejected_log_records,omitempty"`
+	ErrorMessage       string                 `protobuf:"bytes,2,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *ExportLogsPartialSuccess) Reset() {
+	*x = ExportLogsPartialSuccess{}
+	mi := &file_otlp_logs_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportLogsPartialSuccess) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportLogsPartialSuccess) ProtoMessage() {}
+
+func (x *ExportLogsPartialSuccess) ProtoReflect() protoreflect.Message {
+	mi := &file_otlp_logs_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportLogsPartialSuccess.ProtoReflect.Descriptor instead.
+func (*ExportLogsPartialSuccess) Descriptor() ([]byte, []int) {
+	return file_otlp_logs_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ExportLogsPartialSuccess) GetRejectedLogRecords() int64 {
+	if x != nil {
+		return x.RejectedLogRecords
+	}
+	return 0
+}
+
+func (x *ExportLogsPartialSuccess) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+// ResourceLogs are the logs of one resource, e.g. one pod's container.
+type ResourceLogs struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Resource      *Resource              `protobuf:"bytes,1,opt,name=resource,proto3" json:"resource,omitempty"`
+	ScopeLogs     []*ScopeLogs           `protobuf:"bytes,2,rep,name=scope_logs,json=scopeLogs,proto3" json:"scope_logs,omitempty"`
+	SchemaUrl     string                 `protobuf:"bytes,3,opt,name=schema_url,json=schemaUrl,proto3" json:"schema_url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResourceLogs) Reset() {
+	*x = ResourceLogs{}
+	mi := &file_otlp_logs_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResourceLogs) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceLogs) ProtoMessage() {}
+
+func (x *ResourceLogs) ProtoReflect() protoreflect.Message {
+	mi := &file_otlp_logs_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceLogs.ProtoReflect.Descriptor instead.
+func (*ResourceLogs) Descriptor() ([]byte, []int) {
+	return file_otlp_logs_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ResourceLogs) GetResource() *Resource {
+	if x != nil {
+		return x.Resource
+	}
+	return nil
+}
+
+func (x *ResourceLogs) GetScopeLogs() []*ScopeLogs {
+	if x != nil {
+		return x.ScopeLogs
+	}
+	return nil
+}
+
+func (x *ResourceLogs) GetSchemaUrl() string {
+	if x != nil {
+		return x.SchemaUrl
+	}
+	return ""
+}
+
+// ScopeLogs are the logs of one instrumentation scope.
+type ScopeLogs struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Scope         *InstrumentationScope  `protobuf:"bytes,1,opt,name=scope,proto3" json:"scope,omitempty"`
+	LogRecords    []*LogRecord           `protobuf:"bytes,2,rep,name=log_records,json=logRecords,proto3" json:"log_records,omitempty"`
+	SchemaUrl     string                 `protobuf:"bytes,3,opt,name=schema_url,json=schemaUrl,proto3" json:"schema_url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScopeLogs) Reset() {
+	*x = ScopeLogs{}
+	mi := &file_otlp_logs_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScopeLogs) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScopeLogs) ProtoMessage() {}
+
+func (x *ScopeLogs) ProtoReflect() protoreflect.Message {
+	mi := &file_otlp_logs_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScopeLogs.ProtoReflect.Descriptor instead.
+func (*ScopeLogs) Descriptor() ([]byte, []int) {
+	return file_otlp_logs_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ScopeLogs) GetScope() *InstrumentationScope {
+	if x != nil {
+		return x.Scope
+	}
+	return nil
+}
+
+func (x *ScopeLogs) GetLogRecords() []*LogRecord {
+	if x != nil {
+		return x.LogRecords
+	}
+	return nil
+}
+
+func (x *ScopeLogs) GetSchemaUrl() string {
+	if x != nil {
+		return x.SchemaUrl
+	}
+	return ""
+}
+
+type Resource struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	Attributes             []*KeyValue            `protobuf:"bytes,1,rep,name=attributes,proto3" json:"attributes,omitempty"`
+	DroppedAttributesCount uint32                 `protobuf:"varint,2,opt,name=dropped_attributes_count,json=droppedAttributesCount,proto3" json:"dropped_attributes_count,omitempty"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *Resource) Reset() {
+	*x = Resource{}
+	mi := &file_otlp_logs_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Resource) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Resource) ProtoMessage() {}
+
+func (x *Resource) ProtoReflect() protoreflect.Message {
+	mi := &file_otlp_logs_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Resource.ProtoReflect.Descriptor instead.
+func (*Resource) Descriptor() ([]byte, []int) {
+	return file_otlp_logs_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Resource) GetAttributes() []*KeyValue {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *Resource) GetDroppedAttributesCount() uint32 {
+	if x != nil {
+		return x.DroppedAttributesCount
+	}
+	return 0
+}
+
+type InstrumentationScope struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	Name                   string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Version                string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	Attributes             []*KeyValue            `protobuf:"bytes,3,rep,name=attributes,proto3" json:"attributes,omitempty"`
+	DroppedAttributesCount uint32                 `protobuf:"varint,4,opt,name=dropped_attributes_count,json=droppedAttributesCount,proto3" json:"dropped_attributes_count,omitempty"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *InstrumentationScope) Reset() {
+	*x = InstrumentationScope{}
+	mi := &file_otlp_logs_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InstrumentationScope) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InstrumentationScope) ProtoMessage() {}
+
+func (x *InstrumentationScope) ProtoReflect() protoreflect.Message {
+	mi := &file_otlp_logs_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InstrumentationScope.ProtoReflect.Descriptor instead.
+func (*InstrumentationScope) Descriptor() ([]byte, []int) {
+	return file_otlp_logs_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *InstrumentationScope) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *InstrumentationScope) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *InstrumentationScope) GetAttributes() []*KeyValue {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *InstrumentationScope) GetDroppedAttributesCount() uint32 {
+	if x != nil {
+		return x.DroppedAttributesCount
+	}
+	return 0
+}
+
+type LogRecord struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	TimeUnixNano           uint64                 `protobuf:"fixed64,1,opt,name=time_unix_nano,json=timeUnixNano,proto3" json:"time_unix_nano,omitempty"`                            // 0 = unknown
+	ObservedTimeUnixNano   uint64                 `protobuf:"fixed64,11,opt,name=observed_time_unix_nano,json=observedTimeUnixNano,proto3" json:"observed_time_unix_nano,omitempty"` // When the record was collected
+	SeverityNumber         SeverityNumber         `protobuf:"varint,2,opt,name=severity_number,json=severityNumber,proto3,enum=opentelemetry.proto.collector.logs.v1.SeverityNumber" json:"severity_number,omitempty"`
+	SeverityText           string                 `protobuf:"bytes,3,opt,name=severity_text,json=severityText,proto3" json:"severity_text,omitempty"`
+	Body                   *AnyValue              `protobuf:"bytes,5,opt,name=body,proto3" json:"body,omitempty"`
+	Attributes             []*KeyValue            `protobuf:"bytes,6,rep,name=attributes,proto3" json:"attributes,omitempty"`
+	DroppedAttributesCount uint32                 `protobuf:"varint,7,opt,name=dropped_attributes_count,json=droppedAttributesCount,proto3" json:"dropped_attributes_count,omitempty"`
+	Flags                  uint32                 `protobuf:"fixed32,8,opt,name=flags,proto3" json:"flags,omitempty"`
+	TraceId                []byte                 `protobuf:"bytes,9,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"` // 16 bytes, or empty
+	SpanId                 []byte                 `protobuf:"bytes,10,opt,name=span_id,json=spanId,proto3" json:"span_id,omitempty"`   // 8 bytes, or empty
+	EventName              string                 `protobuf:"bytes,12,opt,name=event_name,json=eventName,proto3" json:"event_name,omitempty"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *LogRecord) Reset() {
+	*x = LogRecord{}
+	mi := &file_otlp_logs_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogRecord) ProtoMessage() {}
+
+func (x *LogRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_otlp_logs_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogRecord.ProtoReflect.Descriptor instead.
+func (*LogRecord) Descriptor() ([]byte, []int) {
+	return file_otlp_logs_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *LogRecord) GetTimeUnixNano() uint64 {
+	if x != nil {
+		return x.TimeUnixNano
+	}
+	return 0
+}
+
+func (x *LogRecord) GetObservedTimeUnixNano() uint64 {
+	if x != nil {
+		return x.ObservedTimeUnixNano
+	}
+	return 0
+}
+
+func (x *LogRecord) GetSeverityNumber() SeverityNumber {
+	if x != nil {
+		return x.SeverityNumber
+	}
+	return SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED
+}
+
+func (x *LogRecord) GetSeverityText() string {
+	if x != nil {
+		return x.SeverityText
+	}
+	return ""
+}
+
+func (x *LogRecord) GetBody() *AnyValue {
+	if x != nil {
+		return x.Body
+	}
+	return nil
+}
+
+func (x *LogRecord) GetAttributes() []*KeyValue {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *LogRecord) GetDroppedAttributesCount() uint32 {
+	if x != nil {
+		return x.DroppedAttributesCount
+	}
+	return 0
+}
+
+func (x *LogRecord) GetFlags() uint32 {
+	if x != nil {
+		return x.Flags
+	}
+	return 0
+}
+
+func (x *LogRecord) GetTraceId() []byte {
+	if x != nil {
+		return x.TraceId
+	}
+	return nil
+}
+
+func (x *LogRecord) GetSpanId() []byte {
+	if x != nil {
+		return x.SpanId
+	}
+	return nil
+}
+
+func (x *LogRecord) GetEventName() string {
+	if x != nil {
+		return x.EventName
+	}
+	return ""
+}
+
+type AnyValue struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Value:
+	//
+	//	*AnyValue_StringValue
+	//	*AnyValue_BoolValue
+	//	*AnyValue_IntValue
+	//	*AnyValue_DoubleValue
+	//	*AnyValue_ArrayValue
+	//	*AnyValue_KvlistValue
+	//	*AnyValue_BytesValue
+	Value         isAnyValue_Value `protobuf_oneof:"value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AnyValue) Reset() {
+	*x = AnyValue{}
+	mi := &file_otlp_logs_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnyValue) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnyValue) ProtoMessage() {}
+
+func (x *AnyValue) ProtoReflect() protoreflect.Message {
+	mi := &file_otlp_logs_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnyValue.ProtoReflect.Descriptor instead.
+func (*AnyValue) Descriptor() ([]byte, []int) {
+	return file_otlp_logs_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *AnyValue) GetValue() isAnyValue_Value {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *AnyValue) GetStringValue() string {
+	if x != nil {
+		if x, ok := x.Value.(*AnyValue_StringValue); ok {
+			return x.StringValue
+		}
+	}
+	return ""
+}
+
+func (x *AnyValue) GetBoolValue() bool {
+	if x != nil {
+		if x, ok := x.Value.(*AnyValue_BoolValue); ok {
+			return x.BoolValue
+		}
+	}
+	return false
+}
+
+func (x *AnyValue) GetIntValue() int64 {
+	if x != nil {
+		if x, ok := x.Value.(*AnyValue_IntValue); ok {
+			return x.IntValue
+		}
+	}
+	return 0
+}
+
+func (x *AnyValue) GetDoubleValue() float64 {
+	if x != nil {
+		if x, ok := x.Value.(*AnyValue_DoubleValue); ok {
+			return x.DoubleValue
+		}
+	}
+	return 0
+}
+
+func (x *AnyValue) GetArrayValue() *ArrayValue {
+	if x != nil {
+		if x, ok := x.Value.(*AnyValue_ArrayValue); ok {
+			return x.ArrayValue
+		}
+	}
+	return nil
+}
+
+func (x *AnyValue) GetKvlistValue() *KeyValueList {
+	if x != nil {
+		if x, ok := x.Value.(*AnyValue_KvlistValue); ok {
+			return x.KvlistValue
+		}
+	}
+	return nil
+}
+
+func (x *AnyValue) GetBytesValue() []byte {
+	if x != nil {
+		if x, ok := x.Value.(*AnyValue_BytesValue); ok {
+			return x.BytesValue
+		}
+	}
+	return nil
+}
+
+type isAnyValue_Value interface {
+	isAnyValue_Value()
+}
+
+type AnyValue_StringValue struct {
+	StringValue string `protobuf:"bytes,1,opt,name=string_value,json=stringValue,proto3,oneof"`
+}
+
+type AnyValue_BoolValue struct {
+	BoolValue bool `protobuf:"varint,2,opt,name=bool_value,json=boolValue,proto3,oneof"`
+}
+
+type AnyValue_IntValue struct {
+	IntValue int64 `protobuf:"varint,3,opt,name=int_value,json=intValue,proto3,oneof"`
+}
+
+type AnyValue_DoubleValue struct {
+	DoubleValue float64 `protobuf:"fixed64,4,opt,name=double_value,json=doubleValue,proto3,oneof"`
+}
+
+type AnyValue_ArrayValue struct {
+	ArrayValue *ArrayValue `protobuf:"bytes,5,opt,name=array_value,json=arrayValue,proto3,oneof"`
+}
+
+type AnyValue_KvlistValue struct {
+	KvlistValue *KeyValueList `protobuf:"bytes,6,opt,name=kvlist_value,json=kvlistValue,proto3,oneof"`
+}
+
+type AnyValue_BytesValue struct {
+	BytesValue []byte `protobuf:"bytes,7,opt,name=bytes_value,json=bytesValue,proto3,oneof"`
+}
+
+func (*AnyValue_StringValue) isAnyValue_Value() {}
+
+func (*AnyValue_BoolValue) isAnyValue_Value() {}
+
+func (*AnyValue_IntValue) isAnyValue_Value() {}
+
+func (*AnyValue_DoubleValue) isAnyValue_Value() {}
+
+func (*AnyValue_ArrayValue) isAnyValue_Value() {}
+
+func (*AnyValue_KvlistValue) isAnyValue_Value() {}
+
+func (*AnyValue_BytesValue) isAnyValue_Value() {}
+
+type ArrayValue struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        []*AnyValue            `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ArrayValue) Reset() {
+	*x = ArrayValue{}
+	mi := &file_otlp_logs_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ArrayValue) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ArrayValue) ProtoMessage() {}
+
+func (x *ArrayValue) ProtoReflect() protoreflect.Message {
+	mi := &file_otlp_logs_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ArrayValue.ProtoReflect.Descriptor instead.
+func (*ArrayValue) Descriptor() ([]byte, []int) {
+	return file_otlp_logs_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ArrayValue) GetValues() []*AnyValue {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type KeyValueList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        []*KeyValue            `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeyValueList) Reset() {
+	*x = KeyValueList{}
+	mi := &file_otlp_logs_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyValueList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyValueList) ProtoMessage() {}
+
+func (x *KeyValueList) ProtoReflect() protoreflect.Message {
+	mi := &file_otlp_logs_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyValueList.ProtoReflect.Descriptor instead.
+func (*KeyValueList) Descriptor() ([]byte, []int) {
+	return file_otlp_logs_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *KeyValueList) GetValues() []*KeyValue {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type KeyValue struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value         *AnyValue              `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeyValue) Reset() {
+	*x = KeyValue{}
+	mi := &file_otlp_logs_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyValue) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyValue) ProtoMessage() {}
+
+func (x *KeyValue) ProtoReflect() protoreflect.Message {
+	mi := &file_otlp_logs_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyValue.ProtoReflect.Descriptor instead.
+func (*KeyValue) Descriptor() ([]byte, []int) {
+	return file_otlp_logs_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *KeyValue) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *KeyValue) GetValue() *AnyValue {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+var File_otlp_logs_proto protoreflect.FileDescriptor
+
+const file_otlp_logs_proto_rawDesc = "" +
+	"\n" +
+	"\x0fotlp_logs.proto\x12%opentelemetry.proto.collector.logs.v1\"t\n" +
+	"\x18ExportLogsServiceRequest\x12X\n" +
+	"\rresource_logs\x18\x01 \x03(\v23.opentelemetry.proto.collector.logs.v1.ResourceLogsR\fresourceLogs\"\x85\x01\n" +
+	"\x19ExportLogsServiceResponse\x12h\n" +
+	"\x0fpartial_success\x18\x01 \x01(\v2?.opentelemetry.proto.collector.logs.v1.ExportLogsPartialSuccessR\x0epartialSuccess\"q\n" +
+	"\x18ExportLogsPartialSuccess\x120\n" +
+	"\x14rejected_log_records\x18\x01 \x01(\x03R\x12rejectedLogRecords\x12#\n" +
+	"\rerror_message\x18\x02 \x01(\tR\ferrorMessage\"\xd3\x01\n" +
+	"\fResourceLogs\x12K\n" +
+	"\bresource\x18\x01 \x01(\v2/.opentelemetry.proto.collector.logs.v1.ResourceR\bresource\x12O\n" +
+	"\n" +
+	"scope_logs\x18\x02 \x03(\v20.opentelemetry.proto.collector.logs.v1.ScopeLogsR\tscopeLogs\x12\x1d\n" +
+	"\n" +
+	"schema_url\x18\x03 \x01(\tR\tschemaUrlJ\x06\b\xe8\a\x10\xe9\a\"\xd0\x01\n" +
+	"\tScopeLogs\x12Q\n" +
+	"\x05scope\x18\x01 \x01(\v2;.opentelemetry.proto.collector.logs.v1.InstrumentationScopeR\x05scope\x12Q\n" +
+	"\vlog_records\x18\x02 \x03(\v20.opentelemetry.proto.collector.logs.v1.LogRecordR\n" +
+	"logRecords\x12\x1d\n" +
+	"\n" +
+	"schema_url\x18\x03 \x01(\tR\tschemaUrl\"\x95\x01\n" +
+	"\bResource\x12O\n" +
+	"\n" +
+	"attributes\x18\x01 \x03(\v2/.opentelemetry.proto.collector.logs.v1.KeyValueR\n" +
+	"attributes\x128\n" +
+	"\x18dropped_attributes_count\x18\x02 \x01(\rR\x16droppedAttributesCount\"\xcf\x01\n" +
+	"\x14InstrumentationScope\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\x12O\n" +
+	"\n" +
+	"attributes\x18\x03 \x03(\v2/.opentelemetry.proto.collector.logs.v1.KeyValueR\n" +
+	"attributes\x128\n" +
+	"\x18dropped_attributes_count\x18\x04 \x01(\rR\x16droppedAttributesCount\"\xac\x04\n" +
+	"\tLogRecord\x12$\n" +
+	"\x0etime_unix_nano\x18\x01 \x01(\x06R\ftimeUnixNano\x125\n" +
+	"\x17observed_time_unix_nano\x18\v \x01(\x06R\x14observedTimeUnixNano\x12^\n" +
+	"\x0fseverity_number\x18\x02 \x01(\x0e25.opentelemetry.proto.collector.logs.v1.SeverityNumberR\x0eseverityNumber\x12#\n" +
+	"\rseverity_text\x18\x03 \x01(\tR\fseverityText\x12C\n" +
+	"\x04body\x18\x05 \x01(\v2/.opentelemetry.proto.collector.logs.v1.AnyValueR\x04body\x12O\n" +
+	"\n" +
+	"attributes\x18\x06 \x03(\v2/.opentelemetry.proto.collector.logs.v1.KeyValueR\n" +
+	"attributes\x128\n" +
+	"\x18dropped_attributes_count\x18\a \x01(\rR\x16droppedAttributesCount\x12\x14\n" +
+	"\x05flags\x18\b \x01(\aR\x05flags\x12\x19\n" +
+	"\btrace_id\x18\t \x01(\fR\atraceId\x12\x17\n" +
+	"\aspan_id\x18\n" +
+	" \x01(\fR\x06spanId\x12\x1d\n" +
+	"\n" +
+	"event_name\x18\f \x01(\tR\teventNameJ\x04\b\x04\x10\x05\"\xf0\x02\n" +
+	"\bAnyValue\x12#\n" +
+	"\fstring_value\x18\x01 \x01(\tH\x00R\vstringValue\x12\x1f\n" +
+	"\n" +
+	"bool_value\x18\x02 \x01(\bH\x00R\tboolValue\x12\x1d\n" +
+	"\tint_value\x18\x03 \x01(\x03H\x00R\bintValue\x12#\n" +
+	"\fdouble_value\x18\x04 \x01(\x01H\x00R\vdoubleValue\x12T\n" +
+	"\varray_value\x18\x05 \x01(\v21.opentelemetry.proto.collector.logs.v1.ArrayValueH\x00R\n" +
+	"arrayValue\x12X\n" +
+	"\fkvlist_value\x18\x06 \x01(\v23.opentelemetry.proto.collector.logs.v1.KeyValueListH\x00R\vkvlistValue\x12!\n" +
+	"\vbytes_value\x18\a \x01(\fH\x00R\n" +
+	"bytesValueB\a\n" +
+	"\x05value\"U\n" +
+	"\n" +
+	"ArrayValue\x12G\n" +
+	"\x06values\x18\x01 \x03(\v2/.opentelemetry.proto.collector.logs.v1.AnyValueR\x06values\"W\n" +
+	"\fKeyValueList\x12G\n" +
+	"\x06values\x18\x01 \x03(\v2/.opentelemetry.proto.collector.logs.v1.KeyValueR\x06values\"c\n" +
+	"\bKeyValue\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12E\n" +
+	"\x05value\x18\x02 \x01(\v2/.opentelemetry.proto.collector.logs.v1.AnyValueR\x05value*\xc3\x05\n" +
+	"\x0eSeverityNumber\x12\x1f\n" +
+	"\x1bSEVERITY_NUMBER_UNSPECIFIED\x10\x00\x12\x19\n" +
+	"\x15SEVERITY_NUMBER_TRACE\x10\x01\x12\x1a\n" +
+	"\x16SEVERITY_NUMBER_TRACE2\x10\x02\x12\x1a\n" +
+	"\x16SEVERITY_NUMBER_TRACE3\x10\x03\x12\x1a\n" +
+	"\x16SEVERITY_NUMBER_TRACE4\x10\x04\x12\x19\n" +
+	"\x15SEVERITY_NUMBER_DEBUG\x10\x05\x12\x1a\n" +
+	"\x16SEVERITY_NUMBER_DEBUG2\x10\x06\x12\x1a\n" +
+	"\x16SEVERITY_NUMBER_DEBUG3\x10\a\x12\x1a\n" +
+	"\x16SEVERITY_NUMBER_DEBUG4\x10\b\x12\x18\n" +
+	"\x14SEVERITY_NUMBER_INFO\x10\t\x12\x19\n" +
+	"\x15SEVERITY_NUMBER_INFO2\x10\n" +
+	"\x12\x19\n" +
+	"\x15SEVERITY_NUMBER_INFO3\x10\v\x12\x19\n" +
+	"\x15SEVERITY_NUMBER_INFO4\x10\f\x12\x18\n" +
+	"\x14SEVERITY_NUMBER_WARN\x10\r\x12\x19\n" +
+	"\x15SEVERITY_NUMBER_WARN2\x10\x0e\x12\x19\n" +
+	"\x15SEVERITY_NUMBER_WARN3\x10\x0f\x12\x19\n" +
+	"\x15SEVERITY_NUMBER_WARN4\x10\x10\x12\x19\n" +
+	"\x15SEVERITY_NUMBER_ERROR\x10\x11\x12\x1a\n" +
+	"\x16SEVERITY_NUMBER_ERROR2\x10\x12\x12\x1a\n" +
+	"\x16SEVERITY_NUMBER_ERROR3\x10\x13\x12\x1a\n" +
+	"\x16SEVERITY_NUMBER_ERROR4\x10\x14\x12\x19\n" +
+	"\x15SEVERITY_NUMBER_FATAL\x10\x15\x12\x1a\n" +
+	"\x16SEVERITY_NUMBER_FATAL2\x10\x16\x12\x1a\n" +
+	"\x16SEVERITY_NUMBER_FATAL3\x10\x17\x12\x1a\n" +
+	"\x16SEVERITY_NUMBER_FATAL4\x10\x182\x9d\x01\n" +
+	"\vLogsService\x12\x8d\x01\n" +
+	"\x06Export\x12?.opentelemetry.proto.collector.logs.v1.ExportLogsServiceRequest\x1a@.opentelemetry.proto.collector.logs.v1.ExportLogsServiceResponse\"\x00B)Z'github.com/kubelogs/kubelogs/api/otlppbb\x06proto3"
+
+var (
+	file_otlp_logs_proto_rawDescOnce sync.Once
+	file_otlp_logs_proto_rawDescData []byte
+)
+
+func file_otlp_logs_proto_rawDescGZIP() []byte {
+	file_otlp_logs_proto_rawDescOnce.Do(func() {
+		file_otlp_logs_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_otlp_logs_proto_rawDesc), len(file_otlp_logs_proto_rawDesc)))
+	})
+	return file_otlp_logs_proto_rawDescData
+}
+
+var file_otlp_logs_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_otlp_logs_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_otlp_logs_proto_goTypes = []any{
+	(SeverityNumber)(0),               // 0: opentelemetry.proto.collector.logs.v1.SeverityNumber
+	(*ExportLogsServiceRequest)(nil),  // 1: opentelemetry.proto.collector.logs.v1.ExportLogsServiceRequest
+	(*ExportLogsServiceResponse)(nil), // 2: opentelemetry.proto.collector.logs.v1.ExportLogsServiceResponse
+	(*ExportLogsPartialSuccess)(nil),  // 3: opentelemetry.proto.collector.logs.v1.ExportLogsPartialSuccess
+	(*ResourceLogs)(nil),              // 4: opentelemetry.proto.collector.logs.v1.ResourceLogs
+	(*ScopeLogs)(nil),                 // 5: opentelemetry.proto.collector.logs.v1.ScopeLogs
+	(*Resource)(nil),                  // 6: opentelemetry.proto.collector.logs.v1.Resource
+	(*InstrumentationScope)(nil),      // 7: opentelemetry.proto.collector.logs.v1.InstrumentationScope
+	(*LogRecord)(nil),                 // 8: opentelemetry.proto.collector.logs.v1.LogRecord
+	(*AnyValue)(nil),                  // 9: opentelemetry.proto.collector.logs.v1.AnyValue
+	(*ArrayValue)(nil),                // 10: opentelemetry.proto.collector.logs.v1.ArrayValue
+	(*KeyValueList)(nil),              // 11: opentelemetry.proto.collector.logs.v1.KeyValueList
+	(*KeyValue)(nil),                  // 12: opentelemetry.proto.collector.logs.v1.KeyValue
+}
+var file_otlp_logs_proto_depIdxs = []int32{
+	4,  // 0: opentelemetry.proto.collector.logs.v1.ExportLogsServiceRequest.resource_logs:type_name -> opentelemetry.proto.collector.logs.v1.ResourceLogs
+	3,  // 1: opentelemetry.proto.collector.logs.v1.ExportLogsServiceResponse.partial_success:type_name -> opentelemetry.proto.collector.logs.v1.ExportLogsPartialSuccess
+	6,  // 2: opentelemetry.proto.collector.logs.v1.ResourceLogs.resource:type_name -> opentelemetry.proto.collector.logs.v1.Resource
+	5,  // 3: opentelemetry.proto.collector.logs.v1.ResourceLogs.scope_logs:type_name -> opentelemetry.proto.collector.logs.v1.ScopeLogs
+	7,  // 4: opentelemetry.proto.collector.logs.v1.ScopeLogs.scope:type_name -> opentelemetry.proto.collector.logs.v1.InstrumentationScope
+	8,  // 5: opentelemetry.proto.collector.logs.v1.ScopeLogs.log_records:type_name -> opentelemetry.proto.collector.logs.v1.LogRecord
+	12, // 6: opentelemetry.proto.collector.logs.v1.Resource.attributes:type_name -> opentelemetry.proto.collector.logs.v1.KeyValue
+	12, // 7: opentelemetry.proto.collector.logs.v1.InstrumentationScope.attributes:type_name -> opentelemetry.proto.collector.logs.v1.KeyValue
+	0,  // 8: opentelemetry.proto.collector.logs.v1.LogRecord.severity_number:type_name -> opentelemetry.proto.collector.logs.v1.SeverityNumber
+	9,  // 9: opentelemetry.proto.collector.logs.v1.LogRecord.body:type_name -> opentelemetry.proto.collector.logs.v1.AnyValue
+	12, // 10: opentelemetry.proto.collector.logs.v1.LogRecord.attributes:type_name -> opentelemetry.proto.collector.logs.v1.KeyValue
+	10, // 11: opentelemetry.proto.collector.logs.v1.AnyValue.array_value:type_name -> opentelemetry.proto.collector.logs.v1.ArrayValue
+	11, // 12: opentelemetry.proto.collector.logs.v1.AnyValue.kvlist_value:type_name -> opentelemetry.proto.collector.logs.v1.KeyValueList
+	9,  // 13: opentelemetry.proto.collector.logs.v1.ArrayValue.values:type_name -> opentelemetry.proto.collector.logs.v1.AnyValue
+	12, // 14: opentelemetry.proto.collector.logs.v1.KeyValueList.values:type_name -> opentelemetry.proto.collector.logs.v1.KeyValue
+	9,  // 15: opentelemetry.proto.collector.logs.v1.KeyValue.value:type_name -> opentelemetry.proto.collector.logs.v1.AnyValue
+	1,  // 16: opentelemetry.proto.collector.logs.v1.LogsService.Export:input_type -> opentelemetry.proto.collector.logs.v1.ExportLogsServiceRequest
+	2,  // 17: opentelemetry.proto.collector.logs.v1.LogsService.Export:output_type -> opentelemetry.proto.collector.logs.v1.ExportLogsServiceResponse
+	17, // [17:18] is the sub-list for method output_type
+	16, // [16:17] is the sub-list for method input_type
+	16, // [16:16] is the sub-list for extension type_name
+	16, // [16:16] is the sub-list for extension extendee
+	0,  // [0:16] is the sub-list for field type_name
+}
+
+func init() { file_otlp_logs_proto_init() }
+func file_otlp_logs_proto_init() {
+	if File_otlp_logs_proto != nil {
+		return
+	}
+	file_otlp_logs_proto_msgTypes[8].OneofWrappers = []any{
+		(*AnyValue_StringValue)(nil),
+		(*AnyValue_BoolValue)(nil),
+		(*AnyValue_IntValue)(nil),
+		(*AnyValue_DoubleValue)(nil),
+		(*AnyValue_ArrayValue)(nil),
+		(*AnyValue_KvlistValue)(nil),
+		(*AnyValue_BytesValue)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_otlp_logs_proto_rawDesc), len(file_otlp_logs_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_otlp_logs_proto_goTypes,
+		DependencyIndexes: file_otlp_logs_proto_depIdxs,
+		EnumInfos:         file_otlp_logs_proto_enumTypes,
+		MessageInfos:      file_otlp_logs_proto_msgTypes,
+	}.Build()
+	File_otlp_logs_proto = out.File
+	file_otlp_logs_proto_goTypes = nil
+	file_otlp_logs_proto_depIdxs = nil
+}
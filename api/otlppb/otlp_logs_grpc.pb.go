@@ -0,0 +1,131 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.0
+// - protoc             v6.33.2
+// source: otlp_logs.proto
+
+// The OpenTelemetry logs service, trimmed to what the OTLP receiver reads.
+// Field numbers and the service name match opentelemetry-proto v1.x, so
+// any OTLP/gRPC exporter can send to it; the messages the upstream
+// definition spreads over the common, resource and logs packages live in
+// this one file.
+
+package otlppb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	LogsService_Export_FullMethodName = "/opentelemetry.proto.collector.logs.v1.LogsService/Export"
+)
+
+// LogsServiceClient is the client API for LogsService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// LogsService receives logs from OpenTelemetry SDKs and collectors.
+type LogsServiceClient interface {
+	Export(ctx context.Context, in *ExportLogsServiceRequest, opts ...grpc.CallOption) (*ExportLogsServiceResponse, error)
+}
+
+type logsServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLogsServiceClient(cc grpc.ClientConnInterface) LogsServiceClient {
+	return &logsServiceClient{cc}
+}
+
+func (c *logsServiceClient) Export(ctx context.Context, in *ExportLogsServiceRequest, opts ...grpc.CallOption) (*ExportLogsServiceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExportLogsServiceResponse)
+	err := c.cc.Invoke(ctx, LogsService_Export_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LogsServiceServer is the server API for LogsService service.
+// All implementations must embed UnimplementedLogsServiceServer
+// for forward compatibility.
+//
+// LogsService receives logs from OpenTelemetry SDKs and collectors.
+type LogsServiceServer interface {
+	Export(context.Context, *ExportLogsServiceRequest) (*ExportLogsServiceResponse, error)
+	mustEmbedUnimplementedLogsServiceServer()
+}
+
+// UnimplementedLogsServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedLogsServiceServer struct{}
+
+func (UnimplementedLogsServiceServer) Export(context.Context, *ExportLogsServiceRequest) (*ExportLogsServiceResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Export not implemented")
+}
+func (UnimplementedLogsServiceServer) mustEmbedUnimplementedLogsServiceServer() {}
+func (UnimplementedLogsServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeLogsServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LogsServiceServer will
+// result in compilation errors.
+type UnsafeLogsServiceServer interface {
+	mustEmbedUnimplementedLogsServiceServer()
+}
+
+func RegisterLogsServiceServer(s grpc.ServiceRegistrar, srv LogsServiceServer) {
+	// If the following call panics, it indicates UnimplementedLogsServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&LogsService_ServiceDesc, srv)
+}
+
+func _LogsService_Export_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportLogsServiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LogsServiceServer).Export(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LogsService_Export_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LogsServiceServer).Export(ctx, req.(*ExportLogsServiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// LogsService_ServiceDesc is the grpc.ServiceDesc for LogsService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var LogsService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "opentelemetry.proto.collector.logs.v1.LogsService",
+	HandlerType: (*LogsServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Export",
+			Handler:    _LogsService_Export_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "otlp_logs.proto",
+}
@@ -21,6 +21,56 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// AttributeOp is the comparison of an attribute term.
+type AttributeOp int32
+
+const (
+	AttributeOp_ATTRIBUTE_EQUAL     AttributeOp = 0 // Present and matching value
+	AttributeOp_ATTRIBUTE_NOT_EQUAL AttributeOp = 1 // Absent or not matching value
+	AttributeOp_ATTRIBUTE_EXISTS    AttributeOp = 2 // Present, with any value
+)
+
+// Enum value maps for AttributeOp.
+var (
+	AttributeOp_name = map[int32]string{
+		0: "ATTRIBUTE_EQUAL",
+		1: "ATTRIBUTE_NOT_EQUAL",
+		2: "ATTRIBUTE_EXISTS",
+	}
+	AttributeOp_value = map[string]int32{
+		"ATTRIBUTE_EQUAL":     0,
+		"ATTRIBUTE_NOT_EQUAL": 1,
+		"ATTRIBUTE_EXISTS":    2,
+	}
+)
+
+func (x AttributeOp) Enum() *AttributeOp {
+	p := new(AttributeOp)
+	*p = x
+	return p
+}
+
+func (x AttributeOp) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (AttributeOp) Descriptor() protoreflect.EnumDescriptor {
+	return file_storage_proto_enumTypes[0].Descriptor()
+}
+
+func (AttributeOp) Type() protoreflect.EnumType {
+	return &file_storage_proto_enumTypes[0]
+}
+
+func (x AttributeOp) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use AttributeOp.Descriptor instead.
+func (AttributeOp) EnumDescriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{0}
+}
+
 // Order defines sort order for query results.
 type Order int32
 
@@ -52,11 +102,11 @@ func (x Order) String() string {
 }
 
 func (Order) Descriptor() protoreflect.EnumDescriptor {
-	return file_storage_proto_enumTypes[0].Descriptor()
+	return file_storage_proto_enumTypes[1].Descriptor()
 }
 
 func (Order) Type() protoreflect.EnumType {
-	return &file_storage_proto_enumTypes[0]
+	return &file_storage_proto_enumTypes[1]
 }
 
 func (x Order) Number() protoreflect.EnumNumber {
@@ -65,7 +115,101 @@ func (x Order) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use Order.Descriptor instead.
 func (Order) EnumDescriptor() ([]byte, []int) {
-	return file_storage_proto_rawDescGZIP(), []int{0}
+	return file_storage_proto_rawDescGZIP(), []int{1}
+}
+
+// Consistency defines which acknowledged writes a query sees.
+type Consistency int32
+
+const (
+	Consistency_CONSISTENCY_STRONG   Consistency = 0 // All of them; buffered writes are stored first
+	Consistency_CONSISTENCY_EVENTUAL Consistency = 1 // May miss writes still buffered, up to a few seconds
+)
+
+// Enum value maps for Consistency.
+var (
+	Consistency_name = map[int32]string{
+		0: "CONSISTENCY_STRONG",
+		1: "CONSISTENCY_EVENTUAL",
+	}
+	Consistency_value = map[string]int32{
+		"CONSISTENCY_STRONG":   0,
+		"CONSISTENCY_EVENTUAL": 1,
+	}
+)
+
+func (x Consistency) Enum() *Consistency {
+	p := new(Consistency)
+	*p = x
+	return p
+}
+
+func (x Consistency) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Consistency) Descriptor() protoreflect.EnumDescriptor {
+	return file_storage_proto_enumTypes[2].Descriptor()
+}
+
+func (Consistency) Type() protoreflect.EnumType {
+	return &file_storage_proto_enumTypes[2]
+}
+
+func (x Consistency) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Consistency.Descriptor instead.
+func (Consistency) EnumDescriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{2}
+}
+
+// OrderBy defines the sort key for query results.
+type OrderBy int32
+
+const (
+	OrderBy_ORDER_BY_ID        OrderBy = 0
+	OrderBy_ORDER_BY_TIMESTAMP OrderBy = 1
+)
+
+// Enum value maps for OrderBy.
+var (
+	OrderBy_name = map[int32]string{
+		0: "ORDER_BY_ID",
+		1: "ORDER_BY_TIMESTAMP",
+	}
+	OrderBy_value = map[string]int32{
+		"ORDER_BY_ID":        0,
+		"ORDER_BY_TIMESTAMP": 1,
+	}
+)
+
+func (x OrderBy) Enum() *OrderBy {
+	p := new(OrderBy)
+	*p = x
+	return p
+}
+
+func (x OrderBy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OrderBy) Descriptor() protoreflect.EnumDescriptor {
+	return file_storage_proto_enumTypes[3].Descriptor()
+}
+
+func (OrderBy) Type() protoreflect.EnumType {
+	return &file_storage_proto_enumTypes[3]
+}
+
+func (x OrderBy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OrderBy.Descriptor instead.
+func (OrderBy) EnumDescriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{3}
 }
 
 // LogEntry represents a single log record.
@@ -79,6 +223,13 @@ type LogEntry struct {
 	Severity       uint32                 `protobuf:"varint,6,opt,name=severity,proto3" json:"severity,omitempty"`
 	Message        string                 `protobuf:"bytes,7,opt,name=message,proto3" json:"message,omitempty"`
 	Attributes     map[string]string      `protobuf:"bytes,8,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Cluster        string                 `protobuf:"bytes,9,opt,name=cluster,proto3" json:"cluster,omitempty"`
+	// Dedup hash of the entry (see storage.DedupHash), set by writers that
+	// go through the ingest queue. Zero if unset.
+	DedupHash int64 `protobuf:"varint,10,opt,name=dedup_hash,json=dedupHash,proto3" json:"dedup_hash,omitempty"`
+	// When the entry is deleted regardless of the server's retention, in
+	// Unix nanoseconds. Zero follows the retention.
+	ExpiresAtNanos int64 `protobuf:"varint,11,opt,name=expires_at_nanos,json=expiresAtNanos,proto3" json:"expires_at_nanos,omitempty"`
 	unknownFields  protoimpl.UnknownFields
 	sizeCache      protoimpl.SizeCache
 }
@@ -169,10 +320,38 @@ func (x *LogEntry) GetAttributes() map[string]string {
 	return nil
 }
 
+func (x *LogEntry) GetCluster() string {
+	if x != nil {
+		return x.Cluster
+	}
+	return ""
+}
+
+func (x *LogEntry) GetDedupHash() int64 {
+	if x != nil {
+		return x.DedupHash
+	}
+	return 0
+}
+
+func (x *LogEntry) GetExpiresAtNanos() int64 {
+	if x != nil {
+		return x.ExpiresAtNanos
+	}
+	return 0
+}
+
 // WriteRequest contains log entries to persist.
 type WriteRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Entries       []*LogEntry            `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Entries []*LogEntry            `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	// Identifies the batch across retries and queue redeliveries, so the
+	// queue consumer can skip batches it already stored. Optional.
+	BatchId string `protobuf:"bytes,2,opt,name=batch_id,json=batchId,proto3" json:"batch_id,omitempty"`
+	// Time to live of the entries without expires_at_nanos, counted from
+	// when the server receives the batch, e.g. for debug dumps that
+	// shouldn't be kept as long as other logs. Zero follows the retention.
+	TtlMillis     int64 `protobuf:"varint,3,opt,name=ttl_millis,json=ttlMillis,proto3" json:"ttl_millis,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -214,12 +393,34 @@ func (x *WriteRequest) GetEntries() []*LogEntry {
 	return nil
 }
 
+func (x *WriteRequest) GetBatchId() string {
+	if x != nil {
+		return x.BatchId
+	}
+	return ""
+}
+
+func (x *WriteRequest) GetTtlMillis() int64 {
+	if x != nil {
+		return x.TtlMillis
+	}
+	return 0
+}
+
 // WriteResponse contains the result of a write operation.
 type WriteResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Count         int32                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Count int32                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	// Set when the server is under pressure: the entries were written,
+	// but the client should wait this long before its next write and
+	// send larger batches.
+	RetryAfterMillis int64 `protobuf:"varint,2,opt,name=retry_after_millis,json=retryAfterMillis,proto3" json:"retry_after_millis,omitempty"`
+	// Entries of the batch accepted per severity (0 unknown to 6 fatal),
+	// leaving out severities without any. Entries dropped over a cluster
+	// quota aren't counted; duplicates skipped by the store are.
+	SeverityCounts map[uint32]int64 `protobuf:"bytes,3,rep,name=severity_counts,json=severityCounts,proto3" json:"severity_counts,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *WriteResponse) Reset() {
@@ -259,6 +460,20 @@ func (x *WriteResponse) GetCount() int32 {
 	return 0
 }
 
+func (x *WriteResponse) GetRetryAfterMillis() int64 {
+	if x != nil {
+		return x.RetryAfterMillis
+	}
+	return 0
+}
+
+func (x *WriteResponse) GetSeverityCounts() map[uint32]int64 {
+	if x != nil {
+		return x.SeverityCounts
+	}
+	return nil
+}
+
 // QueryRequest contains search criteria for log entries.
 type QueryRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -276,10 +491,35 @@ type QueryRequest struct {
 	// Attribute filters (exact match, AND logic).
 	Attributes map[string]string `protobuf:"bytes,8,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	// Pagination controls.
-	Limit         int32 `protobuf:"varint,9,opt,name=limit,proto3" json:"limit,omitempty"`
-	AfterId       int64 `protobuf:"varint,10,opt,name=after_id,json=afterId,proto3" json:"after_id,omitempty"`
-	BeforeId      int64 `protobuf:"varint,11,opt,name=before_id,json=beforeId,proto3" json:"before_id,omitempty"`
-	Order         Order `protobuf:"varint,12,opt,name=order,proto3,enum=kubelogs.storage.v1.Order" json:"order,omitempty"`
+	Limit    int32   `protobuf:"varint,9,opt,name=limit,proto3" json:"limit,omitempty"`
+	AfterId  int64   `protobuf:"varint,10,opt,name=after_id,json=afterId,proto3" json:"after_id,omitempty"`
+	BeforeId int64   `protobuf:"varint,11,opt,name=before_id,json=beforeId,proto3" json:"before_id,omitempty"`
+	Order    Order   `protobuf:"varint,12,opt,name=order,proto3,enum=kubelogs.storage.v1.Order" json:"order,omitempty"`
+	OrderBy  OrderBy `protobuf:"varint,13,opt,name=order_by,json=orderBy,proto3,enum=kubelogs.storage.v1.OrderBy" json:"order_by,omitempty"`
+	// Timestamp half of the (timestamp, id) keyset cursor, used with
+	// ORDER_BY_TIMESTAMP. Zero means the cursor is ID only.
+	AfterTimestampNanos  int64 `protobuf:"varint,14,opt,name=after_timestamp_nanos,json=afterTimestampNanos,proto3" json:"after_timestamp_nanos,omitempty"`
+	BeforeTimestampNanos int64 `protobuf:"varint,15,opt,name=before_timestamp_nanos,json=beforeTimestampNanos,proto3" json:"before_timestamp_nanos,omitempty"`
+	// Cluster filter (exact match).
+	Cluster string `protobuf:"bytes,16,opt,name=cluster,proto3" json:"cluster,omitempty"`
+	// Excludes entries with a higher ID, for a consistent cut across
+	// pages. Zero means no limit.
+	MaxId int64 `protobuf:"varint,17,opt,name=max_id,json=maxId,proto3" json:"max_id,omitempty"`
+	// Filters in the query string syntax of the HTTP API, e.g.
+	// "namespace=prod&minSeverity=5&search=timeout&attr.region=eu".
+	// They're combined with the fields above, which win where both set
+	// the same filter. Pagination parameters in it are ignored.
+	QueryString string `protobuf:"bytes,18,opt,name=query_string,json=queryString,proto3" json:"query_string,omitempty"`
+	// Attribute expressions, ANDed with each other and with attributes.
+	AttributeExprs []*AttributeExpr `protobuf:"bytes,19,rep,name=attribute_exprs,json=attributeExprs,proto3" json:"attribute_exprs,omitempty"`
+	// Fraction of the matching entries to return, picked at random, for
+	// exploring huge ranges. 0 or 1 returns every match.
+	Sample float64 `protobuf:"fixed64,20,opt,name=sample,proto3" json:"sample,omitempty"`
+	// Which acknowledged writes the results must include.
+	Consistency Consistency `protobuf:"varint,21,opt,name=consistency,proto3,enum=kubelogs.storage.v1.Consistency" json:"consistency,omitempty"`
+	// Count the matches of the filters on every page into total_estimate,
+	// up to 100,000. Stores that can't count leave it -1.
+	Count         bool `protobuf:"varint,22,opt,name=count,proto3" json:"count,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -398,98 +638,99 @@ func (x *QueryRequest) GetOrder() Order {
 	return Order_ORDER_DESC
 }
 
-// QueryResponse contains the results of a log query.
-type QueryResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Entries       []*LogEntry            `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
-	HasMore       bool                   `protobuf:"varint,2,opt,name=has_more,json=hasMore,proto3" json:"has_more,omitempty"`
-	NextCursor    int64                  `protobuf:"varint,3,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
-	TotalEstimate int64                  `protobuf:"varint,4,opt,name=total_estimate,json=totalEstimate,proto3" json:"total_estimate,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *QueryRequest) GetOrderBy() OrderBy {
+	if x != nil {
+		return x.OrderBy
+	}
+	return OrderBy_ORDER_BY_ID
 }
 
-func (x *QueryResponse) Reset() {
-	*x = QueryResponse{}
-	mi := &file_storage_proto_msgTypes[4]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *QueryRequest) GetAfterTimestampNanos() int64 {
+	if x != nil {
+		return x.AfterTimestampNanos
+	}
+	return 0
 }
 
-func (x *QueryResponse) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *QueryRequest) GetBeforeTimestampNanos() int64 {
+	if x != nil {
+		return x.BeforeTimestampNanos
+	}
+	return 0
 }
 
-func (*QueryResponse) ProtoMessage() {}
+func (x *QueryRequest) GetCluster() string {
+	if x != nil {
+		return x.Cluster
+	}
+	return ""
+}
 
-func (x *QueryResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_storage_proto_msgTypes[4]
+func (x *QueryRequest) GetMaxId() int64 {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.MaxId
 	}
-	return mi.MessageOf(x)
+	return 0
 }
 
-// Deprecated: Use QueryResponse.ProtoReflect.Descriptor instead.
-func (*QueryResponse) Descriptor() ([]byte, []int) {
-	return file_storage_proto_rawDescGZIP(), []int{4}
+func (x *QueryRequest) GetQueryString() string {
+	if x != nil {
+		return x.QueryString
+	}
+	return ""
 }
 
-func (x *QueryResponse) GetEntries() []*LogEntry {
+func (x *QueryRequest) GetAttributeExprs() []*AttributeExpr {
 	if x != nil {
-		return x.Entries
+		return x.AttributeExprs
 	}
 	return nil
 }
 
-func (x *QueryResponse) GetHasMore() bool {
+func (x *QueryRequest) GetSample() float64 {
 	if x != nil {
-		return x.HasMore
+		return x.Sample
 	}
-	return false
+	return 0
 }
 
-func (x *QueryResponse) GetNextCursor() int64 {
+func (x *QueryRequest) GetConsistency() Consistency {
 	if x != nil {
-		return x.NextCursor
+		return x.Consistency
 	}
-	return 0
+	return Consistency_CONSISTENCY_STRONG
 }
 
-func (x *QueryResponse) GetTotalEstimate() int64 {
+func (x *QueryRequest) GetCount() bool {
 	if x != nil {
-		return x.TotalEstimate
+		return x.Count
 	}
-	return 0
+	return false
 }
 
-// GetByIDRequest requests a single log entry by ID.
-type GetByIDRequest struct {
+// AttributeExpr matches entries matching any of its terms.
+type AttributeExpr struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Terms         []*AttributeTerm       `protobuf:"bytes,1,rep,name=terms,proto3" json:"terms,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetByIDRequest) Reset() {
-	*x = GetByIDRequest{}
-	mi := &file_storage_proto_msgTypes[5]
+func (x *AttributeExpr) Reset() {
+	*x = AttributeExpr{}
+	mi := &file_storage_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetByIDRequest) String() string {
+func (x *AttributeExpr) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetByIDRequest) ProtoMessage() {}
+func (*AttributeExpr) ProtoMessage() {}
 
-func (x *GetByIDRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_storage_proto_msgTypes[5]
+func (x *AttributeExpr) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -500,41 +741,44 @@ func (x *GetByIDRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetByIDRequest.ProtoReflect.Descriptor instead.
-func (*GetByIDRequest) Descriptor() ([]byte, []int) {
-	return file_storage_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use AttributeExpr.ProtoReflect.Descriptor instead.
+func (*AttributeExpr) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *GetByIDRequest) GetId() int64 {
+func (x *AttributeExpr) GetTerms() []*AttributeTerm {
 	if x != nil {
-		return x.Id
+		return x.Terms
 	}
-	return 0
+	return nil
 }
 
-// GetByIDResponse contains a single log entry.
-type GetByIDResponse struct {
+// AttributeTerm compares one attribute. A * in value matches any run of
+// characters.
+type AttributeTerm struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Entry         *LogEntry              `protobuf:"bytes,1,opt,name=entry,proto3" json:"entry,omitempty"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Op            AttributeOp            `protobuf:"varint,2,opt,name=op,proto3,enum=kubelogs.storage.v1.AttributeOp" json:"op,omitempty"`
+	Value         string                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetByIDResponse) Reset() {
-	*x = GetByIDResponse{}
-	mi := &file_storage_proto_msgTypes[6]
+func (x *AttributeTerm) Reset() {
+	*x = AttributeTerm{}
+	mi := &file_storage_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetByIDResponse) String() string {
+func (x *AttributeTerm) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetByIDResponse) ProtoMessage() {}
+func (*AttributeTerm) ProtoMessage() {}
 
-func (x *GetByIDResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_storage_proto_msgTypes[6]
+func (x *AttributeTerm) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -545,41 +789,61 @@ func (x *GetByIDResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetByIDResponse.ProtoReflect.Descriptor instead.
-func (*GetByIDResponse) Descriptor() ([]byte, []int) {
-	return file_storage_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use AttributeTerm.ProtoReflect.Descriptor instead.
+func (*AttributeTerm) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *GetByIDResponse) GetEntry() *LogEntry {
+func (x *AttributeTerm) GetKey() string {
 	if x != nil {
-		return x.Entry
+		return x.Key
 	}
-	return nil
+	return ""
 }
 
-// DeleteRequest specifies entries to delete by age.
-type DeleteRequest struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	OlderThanNanos int64                  `protobuf:"varint,1,opt,name=older_than_nanos,json=olderThanNanos,proto3" json:"older_than_nanos,omitempty"`
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+func (x *AttributeTerm) GetOp() AttributeOp {
+	if x != nil {
+		return x.Op
+	}
+	return AttributeOp_ATTRIBUTE_EQUAL
 }
 
-func (x *DeleteRequest) Reset() {
-	*x = DeleteRequest{}
-	mi := &file_storage_proto_msgTypes[7]
+func (x *AttributeTerm) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+// QueryResponse contains the results of a log query.
+type QueryResponse struct {
+	state                    protoimpl.MessageState `protogen:"open.v1"`
+	Entries                  []*LogEntry            `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	HasMore                  bool                   `protobuf:"varint,2,opt,name=has_more,json=hasMore,proto3" json:"has_more,omitempty"`
+	NextCursor               int64                  `protobuf:"varint,3,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	TotalEstimate            int64                  `protobuf:"varint,4,opt,name=total_estimate,json=totalEstimate,proto3" json:"total_estimate,omitempty"` // -1 unless counted
+	NextCursorTimestampNanos int64                  `protobuf:"varint,5,opt,name=next_cursor_timestamp_nanos,json=nextCursorTimestampNanos,proto3" json:"next_cursor_timestamp_nanos,omitempty"`
+	SampleRate               float64                `protobuf:"fixed64,6,opt,name=sample_rate,json=sampleRate,proto3" json:"sample_rate,omitempty"`   // Fraction sampled, or 0 if not sampled
+	TotalCapped              bool                   `protobuf:"varint,7,opt,name=total_capped,json=totalCapped,proto3" json:"total_capped,omitempty"` // Counting stopped at total_estimate; there are more
+	unknownFields            protoimpl.UnknownFields
+	sizeCache                protoimpl.SizeCache
+}
+
+func (x *QueryResponse) Reset() {
+	*x = QueryResponse{}
+	mi := &file_storage_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DeleteRequest) String() string {
+func (x *QueryResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DeleteRequest) ProtoMessage() {}
+func (*QueryResponse) ProtoMessage() {}
 
-func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_storage_proto_msgTypes[7]
+func (x *QueryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -590,41 +854,785 @@ func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DeleteRequest.ProtoReflect.Descriptor instead.
-func (*DeleteRequest) Descriptor() ([]byte, []int) {
-	return file_storage_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use QueryResponse.ProtoReflect.Descriptor instead.
+func (*QueryResponse) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *QueryResponse) GetEntries() []*LogEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetHasMore() bool {
+	if x != nil {
+		return x.HasMore
+	}
+	return false
+}
+
+func (x *QueryResponse) GetNextCursor() int64 {
+	if x != nil {
+		return x.NextCursor
+	}
+	return 0
+}
+
+func (x *QueryResponse) GetTotalEstimate() int64 {
+	if x != nil {
+		return x.TotalEstimate
+	}
+	return 0
+}
+
+func (x *QueryResponse) GetNextCursorTimestampNanos() int64 {
+	if x != nil {
+		return x.NextCursorTimestampNanos
+	}
+	return 0
+}
+
+func (x *QueryResponse) GetSampleRate() float64 {
+	if x != nil {
+		return x.SampleRate
+	}
+	return 0
+}
+
+func (x *QueryResponse) GetTotalCapped() bool {
+	if x != nil {
+		return x.TotalCapped
+	}
+	return false
+}
+
+// TailResponse carries entries written since the previous response.
+type TailResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*LogEntry            `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TailResponse) Reset() {
+	*x = TailResponse{}
+	mi := &file_storage_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TailResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TailResponse) ProtoMessage() {}
+
+func (x *TailResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TailResponse.ProtoReflect.Descriptor instead.
+func (*TailResponse) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *TailResponse) GetEntries() []*LogEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+// GetByIDRequest requests a single log entry by ID.
+type GetByIDRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetByIDRequest) Reset() {
+	*x = GetByIDRequest{}
+	mi := &file_storage_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetByIDRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetByIDRequest) ProtoMessage() {}
+
+func (x *GetByIDRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetByIDRequest.ProtoReflect.Descriptor instead.
+func (*GetByIDRequest) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetByIDRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+// GetByIDResponse contains a single log entry.
+type GetByIDResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entry         *LogEntry              `protobuf:"bytes,1,opt,name=entry,proto3" json:"entry,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetByIDResponse) Reset() {
+	*x = GetByIDResponse{}
+	mi := &file_storage_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetByIDResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetByIDResponse) ProtoMessage() {}
+
+func (x *GetByIDResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetByIDResponse.ProtoReflect.Descriptor instead.
+func (*GetByIDResponse) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetByIDResponse) GetEntry() *LogEntry {
+	if x != nil {
+		return x.Entry
+	}
+	return nil
+}
+
+// GetByIDsRequest requests several log entries by ID.
+type GetByIDsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ids           []int64                `protobuf:"varint,1,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetByIDsRequest) Reset() {
+	*x = GetByIDsRequest{}
+	mi := &file_storage_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetByIDsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetByIDsRequest) ProtoMessage() {}
+
+func (x *GetByIDsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetByIDsRequest.ProtoReflect.Descriptor instead.
+func (*GetByIDsRequest) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetByIDsRequest) GetIds() []int64 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+// GetByIDsResponse contains the entries that were found, in request order.
+type GetByIDsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*LogEntry            `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetByIDsResponse) Reset() {
+	*x = GetByIDsResponse{}
+	mi := &file_storage_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetByIDsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetByIDsResponse) ProtoMessage() {}
+
+func (x *GetByIDsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetByIDsResponse.ProtoReflect.Descriptor instead.
+func (*GetByIDsResponse) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetByIDsResponse) GetEntries() []*LogEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+// DeleteRequest specifies entries to delete by age.
+type DeleteRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	OlderThanNanos int64                  `protobuf:"varint,1,opt,name=older_than_nanos,json=olderThanNanos,proto3" json:"older_than_nanos,omitempty"`
+	// Token of a preview of the same delete; ignored by previews.
+	Confirm       string `protobuf:"bytes,2,opt,name=confirm,proto3" json:"confirm,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRequest) Reset() {
+	*x = DeleteRequest{}
+	mi := &file_storage_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRequest) ProtoMessage() {}
+
+func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRequest.ProtoReflect.Descriptor instead.
+func (*DeleteRequest) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DeleteRequest) GetOlderThanNanos() int64 {
+	if x != nil {
+		return x.OlderThanNanos
+	}
+	return 0
+}
+
+func (x *DeleteRequest) GetConfirm() string {
+	if x != nil {
+		return x.Confirm
+	}
+	return ""
+}
+
+// DeleteByQueryRequest specifies entries to delete by their filters.
+type DeleteByQueryRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Query *QueryRequest          `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	// Token of a preview of the same delete; ignored by previews.
+	Confirm       string `protobuf:"bytes,2,opt,name=confirm,proto3" json:"confirm,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteByQueryRequest) Reset() {
+	*x = DeleteByQueryRequest{}
+	mi := &file_storage_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteByQueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteByQueryRequest) ProtoMessage() {}
+
+func (x *DeleteByQueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteByQueryRequest.ProtoReflect.Descriptor instead.
+func (*DeleteByQueryRequest) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *DeleteByQueryRequest) GetQuery() *QueryRequest {
+	if x != nil {
+		return x.Query
+	}
+	return nil
+}
+
+func (x *DeleteByQueryRequest) GetConfirm() string {
+	if x != nil {
+		return x.Confirm
+	}
+	return ""
+}
+
+// PreviewDeleteResponse contains what a delete would remove, and the
+// token confirming it.
+type PreviewDeleteResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Entries matching, -1 if the store can't count them.
+	Matched        int64  `protobuf:"varint,1,opt,name=matched,proto3" json:"matched,omitempty"`
+	Token          string `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
+	ExpiresAtNanos int64  `protobuf:"varint,3,opt,name=expires_at_nanos,json=expiresAtNanos,proto3" json:"expires_at_nanos,omitempty"`
+	// Someone other than who previewed must confirm, e.g. an admin of the
+	// web UI.
+	SecondApprover bool `protobuf:"varint,4,opt,name=second_approver,json=secondApprover,proto3" json:"second_approver,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *PreviewDeleteResponse) Reset() {
+	*x = PreviewDeleteResponse{}
+	mi := &file_storage_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PreviewDeleteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PreviewDeleteResponse) ProtoMessage() {}
+
+func (x *PreviewDeleteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PreviewDeleteResponse.ProtoReflect.Descriptor instead.
+func (*PreviewDeleteResponse) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *PreviewDeleteResponse) GetMatched() int64 {
+	if x != nil {
+		return x.Matched
+	}
+	return 0
+}
+
+func (x *PreviewDeleteResponse) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *PreviewDeleteResponse) GetExpiresAtNanos() int64 {
+	if x != nil {
+		return x.ExpiresAtNanos
+	}
+	return 0
+}
+
+func (x *PreviewDeleteResponse) GetSecondApprover() bool {
+	if x != nil {
+		return x.SecondApprover
+	}
+	return false
+}
+
+// DeleteResponse contains the result of a delete operation.
+type DeleteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DeletedCount  int64                  `protobuf:"varint,1,opt,name=deleted_count,json=deletedCount,proto3" json:"deleted_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteResponse) Reset() {
+	*x = DeleteResponse{}
+	mi := &file_storage_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteResponse) ProtoMessage() {}
+
+func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteResponse.ProtoReflect.Descriptor instead.
+func (*DeleteResponse) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *DeleteResponse) GetDeletedCount() int64 {
+	if x != nil {
+		return x.DeletedCount
+	}
+	return 0
+}
+
+// StatsRequest requests storage statistics.
+type StatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatsRequest) Reset() {
+	*x = StatsRequest{}
+	mi := &file_storage_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsRequest) ProtoMessage() {}
+
+func (x *StatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsRequest.ProtoReflect.Descriptor instead.
+func (*StatsRequest) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{16}
+}
+
+// StatsResponse contains storage statistics.
+type StatsResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	TotalEntries     int64                  `protobuf:"varint,1,opt,name=total_entries,json=totalEntries,proto3" json:"total_entries,omitempty"`
+	DiskSizeBytes    int64                  `protobuf:"varint,2,opt,name=disk_size_bytes,json=diskSizeBytes,proto3" json:"disk_size_bytes,omitempty"`
+	OldestEntryNanos int64                  `protobuf:"varint,3,opt,name=oldest_entry_nanos,json=oldestEntryNanos,proto3" json:"oldest_entry_nanos,omitempty"`
+	NewestEntryNanos int64                  `protobuf:"varint,4,opt,name=newest_entry_nanos,json=newestEntryNanos,proto3" json:"newest_entry_nanos,omitempty"`
+	Namespaces       []*NamespaceUsage      `protobuf:"bytes,5,rep,name=namespaces,proto3" json:"namespaces,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *StatsResponse) Reset() {
+	*x = StatsResponse{}
+	mi := &file_storage_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsResponse) ProtoMessage() {}
+
+func (x *StatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsResponse.ProtoReflect.Descriptor instead.
+func (*StatsResponse) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *StatsResponse) GetTotalEntries() int64 {
+	if x != nil {
+		return x.TotalEntries
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetDiskSizeBytes() int64 {
+	if x != nil {
+		return x.DiskSizeBytes
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetOldestEntryNanos() int64 {
+	if x != nil {
+		return x.OldestEntryNanos
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetNewestEntryNanos() int64 {
+	if x != nil {
+		return x.NewestEntryNanos
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetNamespaces() []*NamespaceUsage {
+	if x != nil {
+		return x.Namespaces
+	}
+	return nil
+}
+
+// NamespaceUsage is the stored volume of one namespace.
+type NamespaceUsage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Entries       int64                  `protobuf:"varint,2,opt,name=entries,proto3" json:"entries,omitempty"`
+	Bytes         int64                  `protobuf:"varint,3,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NamespaceUsage) Reset() {
+	*x = NamespaceUsage{}
+	mi := &file_storage_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NamespaceUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NamespaceUsage) ProtoMessage() {}
+
+func (x *NamespaceUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NamespaceUsage.ProtoReflect.Descriptor instead.
+func (*NamespaceUsage) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *NamespaceUsage) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *NamespaceUsage) GetEntries() int64 {
+	if x != nil {
+		return x.Entries
+	}
+	return 0
+}
+
+func (x *NamespaceUsage) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
 }
 
-func (x *DeleteRequest) GetOlderThanNanos() int64 {
+// AggregateRequest selects entries to count. The query's pagination
+// fields are ignored.
+type AggregateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Query         *QueryRequest          `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	IntervalNanos int64                  `protobuf:"varint,2,opt,name=interval_nanos,json=intervalNanos,proto3" json:"interval_nanos,omitempty"` // Bucket width, aligned to the Unix epoch
+	// Fields to group by: cluster, namespace, pod, container, severity or
+	// attr.<key>, up to 4. When set, or with value_attribute, groups are
+	// returned instead of buckets and interval_nanos is optional.
+	GroupBy []string `protobuf:"bytes,3,rep,name=group_by,json=groupBy,proto3" json:"group_by,omitempty"`
+	// Attribute whose numeric values are summarized per group.
+	ValueAttribute string `protobuf:"bytes,4,opt,name=value_attribute,json=valueAttribute,proto3" json:"value_attribute,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *AggregateRequest) Reset() {
+	*x = AggregateRequest{}
+	mi := &file_storage_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AggregateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AggregateRequest) ProtoMessage() {}
+
+func (x *AggregateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[19]
 	if x != nil {
-		return x.OlderThanNanos
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AggregateRequest.ProtoReflect.Descriptor instead.
+func (*AggregateRequest) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *AggregateRequest) GetQuery() *QueryRequest {
+	if x != nil {
+		return x.Query
+	}
+	return nil
+}
+
+func (x *AggregateRequest) GetIntervalNanos() int64 {
+	if x != nil {
+		return x.IntervalNanos
 	}
 	return 0
 }
 
-// DeleteResponse contains the result of a delete operation.
-type DeleteResponse struct {
+func (x *AggregateRequest) GetGroupBy() []string {
+	if x != nil {
+		return x.GroupBy
+	}
+	return nil
+}
+
+func (x *AggregateRequest) GetValueAttribute() string {
+	if x != nil {
+		return x.ValueAttribute
+	}
+	return ""
+}
+
+// AggregateResponse holds the non-empty buckets, sorted by start, then
+// severity, or the groups, sorted by start, then keys.
+type AggregateResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	DeletedCount  int64                  `protobuf:"varint,1,opt,name=deleted_count,json=deletedCount,proto3" json:"deleted_count,omitempty"`
+	Buckets       []*HistogramBucket     `protobuf:"bytes,1,rep,name=buckets,proto3" json:"buckets,omitempty"`
+	Groups        []*AggregateGroup      `protobuf:"bytes,2,rep,name=groups,proto3" json:"groups,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DeleteResponse) Reset() {
-	*x = DeleteResponse{}
-	mi := &file_storage_proto_msgTypes[8]
+func (x *AggregateResponse) Reset() {
+	*x = AggregateResponse{}
+	mi := &file_storage_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DeleteResponse) String() string {
+func (x *AggregateResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DeleteResponse) ProtoMessage() {}
+func (*AggregateResponse) ProtoMessage() {}
 
-func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_storage_proto_msgTypes[8]
+func (x *AggregateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -635,40 +1643,153 @@ func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DeleteResponse.ProtoReflect.Descriptor instead.
-func (*DeleteResponse) Descriptor() ([]byte, []int) {
-	return file_storage_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use AggregateResponse.ProtoReflect.Descriptor instead.
+func (*AggregateResponse) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{20}
 }
 
-func (x *DeleteResponse) GetDeletedCount() int64 {
+func (x *AggregateResponse) GetBuckets() []*HistogramBucket {
 	if x != nil {
-		return x.DeletedCount
+		return x.Buckets
+	}
+	return nil
+}
+
+func (x *AggregateResponse) GetGroups() []*AggregateGroup {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
+// AggregateGroup summarizes the entries of one group.
+type AggregateGroup struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	StartNanos int64                  `protobuf:"varint,1,opt,name=start_nanos,json=startNanos,proto3" json:"start_nanos,omitempty"` // Bucket start; 0 without interval_nanos
+	Keys       []string               `protobuf:"bytes,2,rep,name=keys,proto3" json:"keys,omitempty"`                                // Values of group_by, in order
+	Count      int64                  `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	// Entries whose value_attribute is a number, and their sum, minimum,
+	// maximum and mean.
+	ValueCount    int64   `protobuf:"varint,4,opt,name=value_count,json=valueCount,proto3" json:"value_count,omitempty"`
+	Sum           float64 `protobuf:"fixed64,5,opt,name=sum,proto3" json:"sum,omitempty"`
+	Min           float64 `protobuf:"fixed64,6,opt,name=min,proto3" json:"min,omitempty"`
+	Max           float64 `protobuf:"fixed64,7,opt,name=max,proto3" json:"max,omitempty"`
+	Avg           float64 `protobuf:"fixed64,8,opt,name=avg,proto3" json:"avg,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AggregateGroup) Reset() {
+	*x = AggregateGroup{}
+	mi := &file_storage_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AggregateGroup) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AggregateGroup) ProtoMessage() {}
+
+func (x *AggregateGroup) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AggregateGroup.ProtoReflect.Descriptor instead.
+func (*AggregateGroup) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *AggregateGroup) GetStartNanos() int64 {
+	if x != nil {
+		return x.StartNanos
 	}
 	return 0
 }
 
-// StatsRequest requests storage statistics.
-type StatsRequest struct {
+func (x *AggregateGroup) GetKeys() []string {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+func (x *AggregateGroup) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *AggregateGroup) GetValueCount() int64 {
+	if x != nil {
+		return x.ValueCount
+	}
+	return 0
+}
+
+func (x *AggregateGroup) GetSum() float64 {
+	if x != nil {
+		return x.Sum
+	}
+	return 0
+}
+
+func (x *AggregateGroup) GetMin() float64 {
+	if x != nil {
+		return x.Min
+	}
+	return 0
+}
+
+func (x *AggregateGroup) GetMax() float64 {
+	if x != nil {
+		return x.Max
+	}
+	return 0
+}
+
+func (x *AggregateGroup) GetAvg() float64 {
+	if x != nil {
+		return x.Avg
+	}
+	return 0
+}
+
+// HistogramBucket is the number of entries of one severity in a bucket.
+type HistogramBucket struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	StartNanos    int64                  `protobuf:"varint,1,opt,name=start_nanos,json=startNanos,proto3" json:"start_nanos,omitempty"`
+	Severity      uint32                 `protobuf:"varint,2,opt,name=severity,proto3" json:"severity,omitempty"`
+	Count         int64                  `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StatsRequest) Reset() {
-	*x = StatsRequest{}
-	mi := &file_storage_proto_msgTypes[9]
+func (x *HistogramBucket) Reset() {
+	*x = HistogramBucket{}
+	mi := &file_storage_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StatsRequest) String() string {
+func (x *HistogramBucket) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StatsRequest) ProtoMessage() {}
+func (*HistogramBucket) ProtoMessage() {}
 
-func (x *StatsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_storage_proto_msgTypes[9]
+func (x *HistogramBucket) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -679,37 +1800,68 @@ func (x *StatsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StatsRequest.ProtoReflect.Descriptor instead.
-func (*StatsRequest) Descriptor() ([]byte, []int) {
-	return file_storage_proto_rawDescGZIP(), []int{9}
+// Deprecated: Use HistogramBucket.ProtoReflect.Descriptor instead.
+func (*HistogramBucket) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{22}
 }
 
-// StatsResponse contains storage statistics.
-type StatsResponse struct {
-	state            protoimpl.MessageState `protogen:"open.v1"`
-	TotalEntries     int64                  `protobuf:"varint,1,opt,name=total_entries,json=totalEntries,proto3" json:"total_entries,omitempty"`
-	DiskSizeBytes    int64                  `protobuf:"varint,2,opt,name=disk_size_bytes,json=diskSizeBytes,proto3" json:"disk_size_bytes,omitempty"`
-	OldestEntryNanos int64                  `protobuf:"varint,3,opt,name=oldest_entry_nanos,json=oldestEntryNanos,proto3" json:"oldest_entry_nanos,omitempty"`
-	NewestEntryNanos int64                  `protobuf:"varint,4,opt,name=newest_entry_nanos,json=newestEntryNanos,proto3" json:"newest_entry_nanos,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+func (x *HistogramBucket) GetStartNanos() int64 {
+	if x != nil {
+		return x.StartNanos
+	}
+	return 0
 }
 
-func (x *StatsResponse) Reset() {
-	*x = StatsResponse{}
-	mi := &file_storage_proto_msgTypes[10]
+func (x *HistogramBucket) GetSeverity() uint32 {
+	if x != nil {
+		return x.Severity
+	}
+	return 0
+}
+
+func (x *HistogramBucket) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+// ReportCollectorStatusRequest is a collector's periodic health report.
+// Counters are totals since the collector started.
+type ReportCollectorStatusRequest struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Node              string                 `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Cluster           string                 `protobuf:"bytes,2,opt,name=cluster,proto3" json:"cluster,omitempty"`
+	StartedAtNanos    int64                  `protobuf:"varint,3,opt,name=started_at_nanos,json=startedAtNanos,proto3" json:"started_at_nanos,omitempty"`
+	IntervalMillis    int64                  `protobuf:"varint,4,opt,name=interval_millis,json=intervalMillis,proto3" json:"interval_millis,omitempty"` // Time until the next report
+	ActiveStreams     int32                  `protobuf:"varint,5,opt,name=active_streams,json=activeStreams,proto3" json:"active_streams,omitempty"`
+	CatchingUpStreams int32                  `protobuf:"varint,6,opt,name=catching_up_streams,json=catchingUpStreams,proto3" json:"catching_up_streams,omitempty"`
+	LinesRead         int64                  `protobuf:"varint,7,opt,name=lines_read,json=linesRead,proto3" json:"lines_read,omitempty"`
+	StreamErrors      int64                  `protobuf:"varint,8,opt,name=stream_errors,json=streamErrors,proto3" json:"stream_errors,omitempty"`
+	EntriesWritten    int64                  `protobuf:"varint,9,opt,name=entries_written,json=entriesWritten,proto3" json:"entries_written,omitempty"`
+	WriteErrors       int64                  `protobuf:"varint,10,opt,name=write_errors,json=writeErrors,proto3" json:"write_errors,omitempty"`
+	BufferedEntries   int32                  `protobuf:"varint,11,opt,name=buffered_entries,json=bufferedEntries,proto3" json:"buffered_entries,omitempty"`
+	RetryQueueBatches int32                  `protobuf:"varint,12,opt,name=retry_queue_batches,json=retryQueueBatches,proto3" json:"retry_queue_batches,omitempty"`
+	CircuitOpen       bool                   `protobuf:"varint,13,opt,name=circuit_open,json=circuitOpen,proto3" json:"circuit_open,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *ReportCollectorStatusRequest) Reset() {
+	*x = ReportCollectorStatusRequest{}
+	mi := &file_storage_proto_msgTypes[23]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StatsResponse) String() string {
+func (x *ReportCollectorStatusRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StatsResponse) ProtoMessage() {}
+func (*ReportCollectorStatusRequest) ProtoMessage() {}
 
-func (x *StatsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_storage_proto_msgTypes[10]
+func (x *ReportCollectorStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[23]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -720,44 +1872,144 @@ func (x *StatsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StatsResponse.ProtoReflect.Descriptor instead.
-func (*StatsResponse) Descriptor() ([]byte, []int) {
-	return file_storage_proto_rawDescGZIP(), []int{10}
+// Deprecated: Use ReportCollectorStatusRequest.ProtoReflect.Descriptor instead.
+func (*ReportCollectorStatusRequest) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{23}
 }
 
-func (x *StatsResponse) GetTotalEntries() int64 {
+func (x *ReportCollectorStatusRequest) GetNode() string {
 	if x != nil {
-		return x.TotalEntries
+		return x.Node
+	}
+	return ""
+}
+
+func (x *ReportCollectorStatusRequest) GetCluster() string {
+	if x != nil {
+		return x.Cluster
+	}
+	return ""
+}
+
+func (x *ReportCollectorStatusRequest) GetStartedAtNanos() int64 {
+	if x != nil {
+		return x.StartedAtNanos
 	}
 	return 0
 }
 
-func (x *StatsResponse) GetDiskSizeBytes() int64 {
+func (x *ReportCollectorStatusRequest) GetIntervalMillis() int64 {
 	if x != nil {
-		return x.DiskSizeBytes
+		return x.IntervalMillis
 	}
 	return 0
 }
 
-func (x *StatsResponse) GetOldestEntryNanos() int64 {
+func (x *ReportCollectorStatusRequest) GetActiveStreams() int32 {
 	if x != nil {
-		return x.OldestEntryNanos
+		return x.ActiveStreams
 	}
 	return 0
 }
 
-func (x *StatsResponse) GetNewestEntryNanos() int64 {
+func (x *ReportCollectorStatusRequest) GetCatchingUpStreams() int32 {
 	if x != nil {
-		return x.NewestEntryNanos
+		return x.CatchingUpStreams
+	}
+	return 0
+}
+
+func (x *ReportCollectorStatusRequest) GetLinesRead() int64 {
+	if x != nil {
+		return x.LinesRead
+	}
+	return 0
+}
+
+func (x *ReportCollectorStatusRequest) GetStreamErrors() int64 {
+	if x != nil {
+		return x.StreamErrors
+	}
+	return 0
+}
+
+func (x *ReportCollectorStatusRequest) GetEntriesWritten() int64 {
+	if x != nil {
+		return x.EntriesWritten
+	}
+	return 0
+}
+
+func (x *ReportCollectorStatusRequest) GetWriteErrors() int64 {
+	if x != nil {
+		return x.WriteErrors
+	}
+	return 0
+}
+
+func (x *ReportCollectorStatusRequest) GetBufferedEntries() int32 {
+	if x != nil {
+		return x.BufferedEntries
 	}
 	return 0
 }
 
+func (x *ReportCollectorStatusRequest) GetRetryQueueBatches() int32 {
+	if x != nil {
+		return x.RetryQueueBatches
+	}
+	return 0
+}
+
+func (x *ReportCollectorStatusRequest) GetCircuitOpen() bool {
+	if x != nil {
+		return x.CircuitOpen
+	}
+	return false
+}
+
+// ReportCollectorStatusResponse acknowledges a status report.
+type ReportCollectorStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportCollectorStatusResponse) Reset() {
+	*x = ReportCollectorStatusResponse{}
+	mi := &file_storage_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportCollectorStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportCollectorStatusResponse) ProtoMessage() {}
+
+func (x *ReportCollectorStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportCollectorStatusResponse.ProtoReflect.Descriptor instead.
+func (*ReportCollectorStatusResponse) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{24}
+}
+
 var File_storage_proto protoreflect.FileDescriptor
 
 const file_storage_proto_rawDesc = "" +
 	"\n" +
-	"\rstorage.proto\x12\x13kubelogs.storage.v1\"\xd5\x02\n" +
+	"\rstorage.proto\x12\x13kubelogs.storage.v1\"\xb8\x03\n" +
 	"\bLogEntry\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\x03R\x02id\x12'\n" +
 	"\x0ftimestamp_nanos\x18\x02 \x01(\x03R\x0etimestampNanos\x12\x1c\n" +
@@ -768,14 +2020,27 @@ const file_storage_proto_rawDesc = "" +
 	"\amessage\x18\a \x01(\tR\amessage\x12M\n" +
 	"\n" +
 	"attributes\x18\b \x03(\v2-.kubelogs.storage.v1.LogEntry.AttributesEntryR\n" +
-	"attributes\x1a=\n" +
+	"attributes\x12\x18\n" +
+	"\acluster\x18\t \x01(\tR\acluster\x12\x1d\n" +
+	"\n" +
+	"dedup_hash\x18\n" +
+	" \x01(\x03R\tdedupHash\x12(\n" +
+	"\x10expires_at_nanos\x18\v \x01(\x03R\x0eexpiresAtNanos\x1a=\n" +
 	"\x0fAttributesEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"G\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x81\x01\n" +
 	"\fWriteRequest\x127\n" +
-	"\aentries\x18\x01 \x03(\v2\x1d.kubelogs.storage.v1.LogEntryR\aentries\"%\n" +
+	"\aentries\x18\x01 \x03(\v2\x1d.kubelogs.storage.v1.LogEntryR\aentries\x12\x19\n" +
+	"\bbatch_id\x18\x02 \x01(\tR\abatchId\x12\x1d\n" +
+	"\n" +
+	"ttl_millis\x18\x03 \x01(\x03R\tttlMillis\"\xf7\x01\n" +
 	"\rWriteResponse\x12\x14\n" +
-	"\x05count\x18\x01 \x01(\x05R\x05count\"\xf9\x03\n" +
+	"\x05count\x18\x01 \x01(\x05R\x05count\x12,\n" +
+	"\x12retry_after_millis\x18\x02 \x01(\x03R\x10retryAfterMillis\x12_\n" +
+	"\x0fseverity_counts\x18\x03 \x03(\v26.kubelogs.storage.v1.WriteResponse.SeverityCountsEntryR\x0eseverityCounts\x1aA\n" +
+	"\x13SeverityCountsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\rR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"\xaf\a\n" +
 	"\fQueryRequest\x12(\n" +
 	"\x10start_time_nanos\x18\x01 \x01(\x03R\x0estartTimeNanos\x12$\n" +
 	"\x0eend_time_nanos\x18\x02 \x01(\x03R\fendTimeNanos\x12\x16\n" +
@@ -791,40 +2056,142 @@ const file_storage_proto_rawDesc = "" +
 	"\bafter_id\x18\n" +
 	" \x01(\x03R\aafterId\x12\x1b\n" +
 	"\tbefore_id\x18\v \x01(\x03R\bbeforeId\x120\n" +
-	"\x05order\x18\f \x01(\x0e2\x1a.kubelogs.storage.v1.OrderR\x05order\x1a=\n" +
+	"\x05order\x18\f \x01(\x0e2\x1a.kubelogs.storage.v1.OrderR\x05order\x127\n" +
+	"\border_by\x18\r \x01(\x0e2\x1c.kubelogs.storage.v1.OrderByR\aorderBy\x122\n" +
+	"\x15after_timestamp_nanos\x18\x0e \x01(\x03R\x13afterTimestampNanos\x124\n" +
+	"\x16before_timestamp_nanos\x18\x0f \x01(\x03R\x14beforeTimestampNanos\x12\x18\n" +
+	"\acluster\x18\x10 \x01(\tR\acluster\x12\x15\n" +
+	"\x06max_id\x18\x11 \x01(\x03R\x05maxId\x12!\n" +
+	"\fquery_string\x18\x12 \x01(\tR\vqueryString\x12K\n" +
+	"\x0fattribute_exprs\x18\x13 \x03(\v2\".kubelogs.storage.v1.AttributeExprR\x0eattributeExprs\x12\x16\n" +
+	"\x06sample\x18\x14 \x01(\x01R\x06sample\x12B\n" +
+	"\vconsistency\x18\x15 \x01(\x0e2 .kubelogs.storage.v1.ConsistencyR\vconsistency\x12\x14\n" +
+	"\x05count\x18\x16 \x01(\bR\x05count\x1a=\n" +
 	"\x0fAttributesEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xab\x01\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"I\n" +
+	"\rAttributeExpr\x128\n" +
+	"\x05terms\x18\x01 \x03(\v2\".kubelogs.storage.v1.AttributeTermR\x05terms\"i\n" +
+	"\rAttributeTerm\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x120\n" +
+	"\x02op\x18\x02 \x01(\x0e2 .kubelogs.storage.v1.AttributeOpR\x02op\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\tR\x05value\"\xae\x02\n" +
 	"\rQueryResponse\x127\n" +
 	"\aentries\x18\x01 \x03(\v2\x1d.kubelogs.storage.v1.LogEntryR\aentries\x12\x19\n" +
 	"\bhas_more\x18\x02 \x01(\bR\ahasMore\x12\x1f\n" +
 	"\vnext_cursor\x18\x03 \x01(\x03R\n" +
 	"nextCursor\x12%\n" +
-	"\x0etotal_estimate\x18\x04 \x01(\x03R\rtotalEstimate\" \n" +
+	"\x0etotal_estimate\x18\x04 \x01(\x03R\rtotalEstimate\x12=\n" +
+	"\x1bnext_cursor_timestamp_nanos\x18\x05 \x01(\x03R\x18nextCursorTimestampNanos\x12\x1f\n" +
+	"\vsample_rate\x18\x06 \x01(\x01R\n" +
+	"sampleRate\x12!\n" +
+	"\ftotal_capped\x18\a \x01(\bR\vtotalCapped\"G\n" +
+	"\fTailResponse\x127\n" +
+	"\aentries\x18\x01 \x03(\v2\x1d.kubelogs.storage.v1.LogEntryR\aentries\" \n" +
 	"\x0eGetByIDRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\x03R\x02id\"F\n" +
 	"\x0fGetByIDResponse\x123\n" +
-	"\x05entry\x18\x01 \x01(\v2\x1d.kubelogs.storage.v1.LogEntryR\x05entry\"9\n" +
+	"\x05entry\x18\x01 \x01(\v2\x1d.kubelogs.storage.v1.LogEntryR\x05entry\"#\n" +
+	"\x0fGetByIDsRequest\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\x03R\x03ids\"K\n" +
+	"\x10GetByIDsResponse\x127\n" +
+	"\aentries\x18\x01 \x03(\v2\x1d.kubelogs.storage.v1.LogEntryR\aentries\"S\n" +
 	"\rDeleteRequest\x12(\n" +
-	"\x10older_than_nanos\x18\x01 \x01(\x03R\x0eolderThanNanos\"5\n" +
+	"\x10older_than_nanos\x18\x01 \x01(\x03R\x0eolderThanNanos\x12\x18\n" +
+	"\aconfirm\x18\x02 \x01(\tR\aconfirm\"i\n" +
+	"\x14DeleteByQueryRequest\x127\n" +
+	"\x05query\x18\x01 \x01(\v2!.kubelogs.storage.v1.QueryRequestR\x05query\x12\x18\n" +
+	"\aconfirm\x18\x02 \x01(\tR\aconfirm\"\x9a\x01\n" +
+	"\x15PreviewDeleteResponse\x12\x18\n" +
+	"\amatched\x18\x01 \x01(\x03R\amatched\x12\x14\n" +
+	"\x05token\x18\x02 \x01(\tR\x05token\x12(\n" +
+	"\x10expires_at_nanos\x18\x03 \x01(\x03R\x0eexpiresAtNanos\x12'\n" +
+	"\x0fsecond_approver\x18\x04 \x01(\bR\x0esecondApprover\"5\n" +
 	"\x0eDeleteResponse\x12#\n" +
 	"\rdeleted_count\x18\x01 \x01(\x03R\fdeletedCount\"\x0e\n" +
-	"\fStatsRequest\"\xb8\x01\n" +
+	"\fStatsRequest\"\xfd\x01\n" +
 	"\rStatsResponse\x12#\n" +
 	"\rtotal_entries\x18\x01 \x01(\x03R\ftotalEntries\x12&\n" +
 	"\x0fdisk_size_bytes\x18\x02 \x01(\x03R\rdiskSizeBytes\x12,\n" +
 	"\x12oldest_entry_nanos\x18\x03 \x01(\x03R\x10oldestEntryNanos\x12,\n" +
-	"\x12newest_entry_nanos\x18\x04 \x01(\x03R\x10newestEntryNanos*&\n" +
+	"\x12newest_entry_nanos\x18\x04 \x01(\x03R\x10newestEntryNanos\x12C\n" +
+	"\n" +
+	"namespaces\x18\x05 \x03(\v2#.kubelogs.storage.v1.NamespaceUsageR\n" +
+	"namespaces\"^\n" +
+	"\x0eNamespaceUsage\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x18\n" +
+	"\aentries\x18\x02 \x01(\x03R\aentries\x12\x14\n" +
+	"\x05bytes\x18\x03 \x01(\x03R\x05bytes\"\xb6\x01\n" +
+	"\x10AggregateRequest\x127\n" +
+	"\x05query\x18\x01 \x01(\v2!.kubelogs.storage.v1.QueryRequestR\x05query\x12%\n" +
+	"\x0einterval_nanos\x18\x02 \x01(\x03R\rintervalNanos\x12\x19\n" +
+	"\bgroup_by\x18\x03 \x03(\tR\agroupBy\x12'\n" +
+	"\x0fvalue_attribute\x18\x04 \x01(\tR\x0evalueAttribute\"\x90\x01\n" +
+	"\x11AggregateResponse\x12>\n" +
+	"\abuckets\x18\x01 \x03(\v2$.kubelogs.storage.v1.HistogramBucketR\abuckets\x12;\n" +
+	"\x06groups\x18\x02 \x03(\v2#.kubelogs.storage.v1.AggregateGroupR\x06groups\"\xc4\x01\n" +
+	"\x0eAggregateGroup\x12\x1f\n" +
+	"\vstart_nanos\x18\x01 \x01(\x03R\n" +
+	"startNanos\x12\x12\n" +
+	"\x04keys\x18\x02 \x03(\tR\x04keys\x12\x14\n" +
+	"\x05count\x18\x03 \x01(\x03R\x05count\x12\x1f\n" +
+	"\vvalue_count\x18\x04 \x01(\x03R\n" +
+	"valueCount\x12\x10\n" +
+	"\x03sum\x18\x05 \x01(\x01R\x03sum\x12\x10\n" +
+	"\x03min\x18\x06 \x01(\x01R\x03min\x12\x10\n" +
+	"\x03max\x18\a \x01(\x01R\x03max\x12\x10\n" +
+	"\x03avg\x18\b \x01(\x01R\x03avg\"d\n" +
+	"\x0fHistogramBucket\x12\x1f\n" +
+	"\vstart_nanos\x18\x01 \x01(\x03R\n" +
+	"startNanos\x12\x1a\n" +
+	"\bseverity\x18\x02 \x01(\rR\bseverity\x12\x14\n" +
+	"\x05count\x18\x03 \x01(\x03R\x05count\"\x84\x04\n" +
+	"\x1cReportCollectorStatusRequest\x12\x12\n" +
+	"\x04node\x18\x01 \x01(\tR\x04node\x12\x18\n" +
+	"\acluster\x18\x02 \x01(\tR\acluster\x12(\n" +
+	"\x10started_at_nanos\x18\x03 \x01(\x03R\x0estartedAtNanos\x12'\n" +
+	"\x0finterval_millis\x18\x04 \x01(\x03R\x0eintervalMillis\x12%\n" +
+	"\x0eactive_streams\x18\x05 \x01(\x05R\ractiveStreams\x12.\n" +
+	"\x13catching_up_streams\x18\x06 \x01(\x05R\x11catchingUpStreams\x12\x1d\n" +
+	"\n" +
+	"lines_read\x18\a \x01(\x03R\tlinesRead\x12#\n" +
+	"\rstream_errors\x18\b \x01(\x03R\fstreamErrors\x12'\n" +
+	"\x0fentries_written\x18\t \x01(\x03R\x0eentriesWritten\x12!\n" +
+	"\fwrite_errors\x18\n" +
+	" \x01(\x03R\vwriteErrors\x12)\n" +
+	"\x10buffered_entries\x18\v \x01(\x05R\x0fbufferedEntries\x12.\n" +
+	"\x13retry_queue_batches\x18\f \x01(\x05R\x11retryQueueBatches\x12!\n" +
+	"\fcircuit_open\x18\r \x01(\bR\vcircuitOpen\"\x1f\n" +
+	"\x1dReportCollectorStatusResponse*Q\n" +
+	"\vAttributeOp\x12\x13\n" +
+	"\x0fATTRIBUTE_EQUAL\x10\x00\x12\x17\n" +
+	"\x13ATTRIBUTE_NOT_EQUAL\x10\x01\x12\x14\n" +
+	"\x10ATTRIBUTE_EXISTS\x10\x02*&\n" +
 	"\x05Order\x12\x0e\n" +
 	"\n" +
 	"ORDER_DESC\x10\x00\x12\r\n" +
-	"\tORDER_ASC\x10\x012\xa9\x03\n" +
+	"\tORDER_ASC\x10\x01*?\n" +
+	"\vConsistency\x12\x16\n" +
+	"\x12CONSISTENCY_STRONG\x10\x00\x12\x18\n" +
+	"\x14CONSISTENCY_EVENTUAL\x10\x01*2\n" +
+	"\aOrderBy\x12\x0f\n" +
+	"\vORDER_BY_ID\x10\x00\x12\x16\n" +
+	"\x12ORDER_BY_TIMESTAMP\x10\x012\xb5\x06\n" +
 	"\x0eStorageService\x12N\n" +
-	"\x05Write\x12!.kubelogs.storage.v1.WriteRequest\x1a\".kubelogs.storage.v1.WriteResponse\x12N\n" +
+	"\x05Write\x12!.kubelogs.storage.v1.WriteRequest\x1a\".kubelogs.storage.v1.WriteResponse\x12X\n" +
+	"\vWriteStream\x12!.kubelogs.storage.v1.WriteRequest\x1a\".kubelogs.storage.v1.WriteResponse(\x010\x01\x12N\n" +
 	"\x05Query\x12!.kubelogs.storage.v1.QueryRequest\x1a\".kubelogs.storage.v1.QueryResponse\x12T\n" +
-	"\aGetByID\x12#.kubelogs.storage.v1.GetByIDRequest\x1a$.kubelogs.storage.v1.GetByIDResponse\x12Q\n" +
-	"\x06Delete\x12\".kubelogs.storage.v1.DeleteRequest\x1a#.kubelogs.storage.v1.DeleteResponse\x12N\n" +
-	"\x05Stats\x12!.kubelogs.storage.v1.StatsRequest\x1a\".kubelogs.storage.v1.StatsResponseB,Z*github.com/kubelogs/kubelogs/api/storagepbb\x06proto3"
+	"\aGetByID\x12#.kubelogs.storage.v1.GetByIDRequest\x1a$.kubelogs.storage.v1.GetByIDResponse\x12W\n" +
+	"\bGetByIDs\x12$.kubelogs.storage.v1.GetByIDsRequest\x1a%.kubelogs.storage.v1.GetByIDsResponse\x12N\n" +
+	"\x05Stats\x12!.kubelogs.storage.v1.StatsRequest\x1a\".kubelogs.storage.v1.StatsResponse\x12N\n" +
+	"\x04Tail\x12!.kubelogs.storage.v1.QueryRequest\x1a!.kubelogs.storage.v1.TailResponse0\x01\x12Z\n" +
+	"\tAggregate\x12%.kubelogs.storage.v1.AggregateRequest\x1a&.kubelogs.storage.v1.AggregateResponse\x12~\n" +
+	"\x15ReportCollectorStatus\x121.kubelogs.storage.v1.ReportCollectorStatusRequest\x1a2.kubelogs.storage.v1.ReportCollectorStatusResponse2\x92\x03\n" +
+	"\fAdminService\x12_\n" +
+	"\rPreviewDelete\x12\".kubelogs.storage.v1.DeleteRequest\x1a*.kubelogs.storage.v1.PreviewDeleteResponse\x12Q\n" +
+	"\x06Delete\x12\".kubelogs.storage.v1.DeleteRequest\x1a#.kubelogs.storage.v1.DeleteResponse\x12m\n" +
+	"\x14PreviewDeleteByQuery\x12).kubelogs.storage.v1.DeleteByQueryRequest\x1a*.kubelogs.storage.v1.PreviewDeleteResponse\x12_\n" +
+	"\rDeleteByQuery\x12).kubelogs.storage.v1.DeleteByQueryRequest\x1a#.kubelogs.storage.v1.DeleteResponseB,Z*github.com/kubelogs/kubelogs/api/storagepbb\x06proto3"
 
 var (
 	file_storage_proto_rawDescOnce sync.Once
@@ -838,46 +2205,93 @@ func file_storage_proto_rawDescGZIP() []byte {
 	return file_storage_proto_rawDescData
 }
 
-var file_storage_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_storage_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_storage_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
+var file_storage_proto_msgTypes = make([]protoimpl.MessageInfo, 28)
 var file_storage_proto_goTypes = []any{
-	(Order)(0),              // 0: kubelogs.storage.v1.Order
-	(*LogEntry)(nil),        // 1: kubelogs.storage.v1.LogEntry
-	(*WriteRequest)(nil),    // 2: kubelogs.storage.v1.WriteRequest
-	(*WriteResponse)(nil),   // 3: kubelogs.storage.v1.WriteResponse
-	(*QueryRequest)(nil),    // 4: kubelogs.storage.v1.QueryRequest
-	(*QueryResponse)(nil),   // 5: kubelogs.storage.v1.QueryResponse
-	(*GetByIDRequest)(nil),  // 6: kubelogs.storage.v1.GetByIDRequest
-	(*GetByIDResponse)(nil), // 7: kubelogs.storage.v1.GetByIDResponse
-	(*DeleteRequest)(nil),   // 8: kubelogs.storage.v1.DeleteRequest
-	(*DeleteResponse)(nil),  // 9: kubelogs.storage.v1.DeleteResponse
-	(*StatsRequest)(nil),    // 10: kubelogs.storage.v1.StatsRequest
-	(*StatsResponse)(nil),   // 11: kubelogs.storage.v1.StatsResponse
-	nil,                     // 12: kubelogs.storage.v1.LogEntry.AttributesEntry
-	nil,                     // 13: kubelogs.storage.v1.QueryRequest.AttributesEntry
+	(AttributeOp)(0),                      // 0: kubelogs.storage.v1.AttributeOp
+	(Order)(0),                            // 1: kubelogs.storage.v1.Order
+	(Consistency)(0),                      // 2: kubelogs.storage.v1.Consistency
+	(OrderBy)(0),                          // 3: kubelogs.storage.v1.OrderBy
+	(*LogEntry)(nil),                      // 4: kubelogs.storage.v1.LogEntry
+	(*WriteRequest)(nil),                  // 5: kubelogs.storage.v1.WriteRequest
+	(*WriteResponse)(nil),                 // 6: kubelogs.storage.v1.WriteResponse
+	(*QueryRequest)(nil),                  // 7: kubelogs.storage.v1.QueryRequest
+	(*AttributeExpr)(nil),                 // 8: kubelogs.storage.v1.AttributeExpr
+	(*AttributeTerm)(nil),                 // 9: kubelogs.storage.v1.AttributeTerm
+	(*QueryResponse)(nil),                 // 10: kubelogs.storage.v1.QueryResponse
+	(*TailResponse)(nil),                  // 11: kubelogs.storage.v1.TailResponse
+	(*GetByIDRequest)(nil),                // 12: kubelogs.storage.v1.GetByIDRequest
+	(*GetByIDResponse)(nil),               // 13: kubelogs.storage.v1.GetByIDResponse
+	(*GetByIDsRequest)(nil),               // 14: kubelogs.storage.v1.GetByIDsRequest
+	(*GetByIDsResponse)(nil),              // 15: kubelogs.storage.v1.GetByIDsResponse
+	(*DeleteRequest)(nil),                 // 16: kubelogs.storage.v1.DeleteRequest
+	(*DeleteByQueryRequest)(nil),          // 17: kubelogs.storage.v1.DeleteByQueryRequest
+	(*PreviewDeleteResponse)(nil),         // 18: kubelogs.storage.v1.PreviewDeleteResponse
+	(*DeleteResponse)(nil),                // 19: kubelogs.storage.v1.DeleteResponse
+	(*StatsRequest)(nil),                  // 20: kubelogs.storage.v1.StatsRequest
+	(*StatsResponse)(nil),                 // 21: kubelogs.storage.v1.StatsResponse
+	(*NamespaceUsage)(nil),                // 22: kubelogs.storage.v1.NamespaceUsage
+	(*AggregateRequest)(nil),              // 23: kubelogs.storage.v1.AggregateRequest
+	(*AggregateResponse)(nil),             // 24: kubelogs.storage.v1.AggregateResponse
+	(*AggregateGroup)(nil),                // 25: kubelogs.storage.v1.AggregateGroup
+	(*HistogramBucket)(nil),               // 26: kubelogs.storage.v1.HistogramBucket
+	(*ReportCollectorStatusRequest)(nil),  // 27: kubelogs.storage.v1.ReportCollectorStatusRequest
+	(*ReportCollectorStatusResponse)(nil), // 28: kubelogs.storage.v1.ReportCollectorStatusResponse
+	nil,                                   // 29: kubelogs.storage.v1.LogEntry.AttributesEntry
+	nil,                                   // 30: kubelogs.storage.v1.WriteResponse.SeverityCountsEntry
+	nil,                                   // 31: kubelogs.storage.v1.QueryRequest.AttributesEntry
 }
 var file_storage_proto_depIdxs = []int32{
-	12, // 0: kubelogs.storage.v1.LogEntry.attributes:type_name -> kubelogs.storage.v1.LogEntry.AttributesEntry
-	1,  // 1: kubelogs.storage.v1.WriteRequest.entries:type_name -> kubelogs.storage.v1.LogEntry
-	13, // 2: kubelogs.storage.v1.QueryRequest.attributes:type_name -> kubelogs.storage.v1.QueryRequest.AttributesEntry
-	0,  // 3: kubelogs.storage.v1.QueryRequest.order:type_name -> kubelogs.storage.v1.Order
-	1,  // 4: kubelogs.storage.v1.QueryResponse.entries:type_name -> kubelogs.storage.v1.LogEntry
-	1,  // 5: kubelogs.storage.v1.GetByIDResponse.entry:type_name -> kubelogs.storage.v1.LogEntry
-	2,  // 6: kubelogs.storage.v1.StorageService.Write:input_type -> kubelogs.storage.v1.WriteRequest
-	4,  // 7: kubelogs.storage.v1.StorageService.Query:input_type -> kubelogs.storage.v1.QueryRequest
-	6,  // 8: kubelogs.storage.v1.StorageService.GetByID:input_type -> kubelogs.storage.v1.GetByIDRequest
-	8,  // 9: kubelogs.storage.v1.StorageService.Delete:input_type -> kubelogs.storage.v1.DeleteRequest
-	10, // 10: kubelogs.storage.v1.StorageService.Stats:input_type -> kubelogs.storage.v1.StatsRequest
-	3,  // 11: kubelogs.storage.v1.StorageService.Write:output_type -> kubelogs.storage.v1.WriteResponse
-	5,  // 12: kubelogs.storage.v1.StorageService.Query:output_type -> kubelogs.storage.v1.QueryResponse
-	7,  // 13: kubelogs.storage.v1.StorageService.GetByID:output_type -> kubelogs.storage.v1.GetByIDResponse
-	9,  // 14: kubelogs.storage.v1.StorageService.Delete:output_type -> kubelogs.storage.v1.DeleteResponse
-	11, // 15: kubelogs.storage.v1.StorageService.Stats:output_type -> kubelogs.storage.v1.StatsResponse
-	11, // [11:16] is the sub-list for method output_type
-	6,  // [6:11] is the sub-list for method input_type
-	6,  // [6:6] is the sub-list for extension type_name
-	6,  // [6:6] is the sub-list for extension extendee
-	0,  // [0:6] is the sub-list for field type_name
+	29, // 0: kubelogs.storage.v1.LogEntry.attributes:type_name -> kubelogs.storage.v1.LogEntry.AttributesEntry
+	4,  // 1: kubelogs.storage.v1.WriteRequest.entries:type_name -> kubelogs.storage.v1.LogEntry
+	30, // 2: kubelogs.storage.v1.WriteResponse.severity_counts:type_name -> kubelogs.storage.v1.WriteResponse.SeverityCountsEntry
+	31, // 3: kubelogs.storage.v1.QueryRequest.attributes:type_name -> kubelogs.storage.v1.QueryRequest.AttributesEntry
+	1,  // 4: kubelogs.storage.v1.QueryRequest.order:type_name -> kubelogs.storage.v1.Order
+	3,  // 5: kubelogs.storage.v1.QueryRequest.order_by:type_name -> kubelogs.storage.v1.OrderBy
+	8,  // 6: kubelogs.storage.v1.QueryRequest.attribute_exprs:type_name -> kubelogs.storage.v1.AttributeExpr
+	2,  // 7: kubelogs.storage.v1.QueryRequest.consistency:type_name -> kubelogs.storage.v1.Consistency
+	9,  // 8: kubelogs.storage.v1.AttributeExpr.terms:type_name -> kubelogs.storage.v1.AttributeTerm
+	0,  // 9: kubelogs.storage.v1.AttributeTerm.op:type_name -> kubelogs.storage.v1.AttributeOp
+	4,  // 10: kubelogs.storage.v1.QueryResponse.entries:type_name -> kubelogs.storage.v1.LogEntry
+	4,  // 11: kubelogs.storage.v1.TailResponse.entries:type_name -> kubelogs.storage.v1.LogEntry
+	4,  // 12: kubelogs.storage.v1.GetByIDResponse.entry:type_name -> kubelogs.storage.v1.LogEntry
+	4,  // 13: kubelogs.storage.v1.GetByIDsResponse.entries:type_name -> kubelogs.storage.v1.LogEntry
+	7,  // 14: kubelogs.storage.v1.DeleteByQueryRequest.query:type_name -> kubelogs.storage.v1.QueryRequest
+	22, // 15: kubelogs.storage.v1.StatsResponse.namespaces:type_name -> kubelogs.storage.v1.NamespaceUsage
+	7,  // 16: kubelogs.storage.v1.AggregateRequest.query:type_name -> kubelogs.storage.v1.QueryRequest
+	26, // 17: kubelogs.storage.v1.AggregateResponse.buckets:type_name -> kubelogs.storage.v1.HistogramBucket
+	25, // 18: kubelogs.storage.v1.AggregateResponse.groups:type_name -> kubelogs.storage.v1.AggregateGroup
+	5,  // 19: kubelogs.storage.v1.StorageService.Write:input_type -> kubelogs.storage.v1.WriteRequest
+	5,  // 20: kubelogs.storage.v1.StorageService.WriteStream:input_type -> kubelogs.storage.v1.WriteRequest
+	7,  // 21: kubelogs.storage.v1.StorageService.Query:input_type -> kubelogs.storage.v1.QueryRequest
+	12, // 22: kubelogs.storage.v1.StorageService.GetByID:input_type -> kubelogs.storage.v1.GetByIDRequest
+	14, // 23: kubelogs.storage.v1.StorageService.GetByIDs:input_type -> kubelogs.storage.v1.GetByIDsRequest
+	20, // 24: kubelogs.storage.v1.StorageService.Stats:input_type -> kubelogs.storage.v1.StatsRequest
+	7,  // 25: kubelogs.storage.v1.StorageService.Tail:input_type -> kubelogs.storage.v1.QueryRequest
+	23, // 26: kubelogs.storage.v1.StorageService.Aggregate:input_type -> kubelogs.storage.v1.AggregateRequest
+	27, // 27: kubelogs.storage.v1.StorageService.ReportCollectorStatus:input_type -> kubelogs.storage.v1.ReportCollectorStatusRequest
+	16, // 28: kubelogs.storage.v1.AdminService.PreviewDelete:input_type -> kubelogs.storage.v1.DeleteRequest
+	16, // 29: kubelogs.storage.v1.AdminService.Delete:input_type -> kubelogs.storage.v1.DeleteRequest
+	17, // 30: kubelogs.storage.v1.AdminService.PreviewDeleteByQuery:input_type -> kubelogs.storage.v1.DeleteByQueryRequest
+	17, // 31: kubelogs.storage.v1.AdminService.DeleteByQuery:input_type -> kubelogs.storage.v1.DeleteByQueryRequest
+	6,  // 32: kubelogs.storage.v1.StorageService.Write:output_type -> kubelogs.storage.v1.WriteResponse
+	6,  // 33: kubelogs.storage.v1.StorageService.WriteStream:output_type -> kubelogs.storage.v1.WriteResponse
+	10, // 34: kubelogs.storage.v1.StorageService.Query:output_type -> kubelogs.storage.v1.QueryResponse
+	13, // 35: kubelogs.storage.v1.StorageService.GetByID:output_type -> kubelogs.storage.v1.GetByIDResponse
+	15, // 36: kubelogs.storage.v1.StorageService.GetByIDs:output_type -> kubelogs.storage.v1.GetByIDsResponse
+	21, // 37: kubelogs.storage.v1.StorageService.Stats:output_type -> kubelogs.storage.v1.StatsResponse
+	11, // 38: kubelogs.storage.v1.StorageService.Tail:output_type -> kubelogs.storage.v1.TailResponse
+	24, // 39: kubelogs.storage.v1.StorageService.Aggregate:output_type -> kubelogs.storage.v1.AggregateResponse
+	28, // 40: kubelogs.storage.v1.StorageService.ReportCollectorStatus:output_type -> kubelogs.storage.v1.ReportCollectorStatusResponse
+	18, // 41: kubelogs.storage.v1.AdminService.PreviewDelete:output_type -> kubelogs.storage.v1.PreviewDeleteResponse
+	19, // 42: kubelogs.storage.v1.AdminService.Delete:output_type -> kubelogs.storage.v1.DeleteResponse
+	18, // 43: kubelogs.storage.v1.AdminService.PreviewDeleteByQuery:output_type -> kubelogs.storage.v1.PreviewDeleteResponse
+	19, // 44: kubelogs.storage.v1.AdminService.DeleteByQuery:output_type -> kubelogs.storage.v1.DeleteResponse
+	32, // [32:45] is the sub-list for method output_type
+	19, // [19:32] is the sub-list for method input_type
+	19, // [19:19] is the sub-list for extension type_name
+	19, // [19:19] is the sub-list for extension extendee
+	0,  // [0:19] is the sub-list for field type_name
 }
 
 func init() { file_storage_proto_init() }
@@ -890,10 +2304,10 @@ func file_storage_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_storage_proto_rawDesc), len(file_storage_proto_rawDesc)),
-			NumEnums:      1,
-			NumMessages:   13,
+			NumEnums:      4,
+			NumMessages:   28,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_storage_proto_goTypes,
 		DependencyIndexes: file_storage_proto_depIdxs,
@@ -19,11 +19,15 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	StorageService_Write_FullMethodName   = "/kubelogs.storage.v1.StorageService/Write"
-	StorageService_Query_FullMethodName   = "/kubelogs.storage.v1.StorageService/Query"
-	StorageService_GetByID_FullMethodName = "/kubelogs.storage.v1.StorageService/GetByID"
-	StorageService_Delete_FullMethodName  = "/kubelogs.storage.v1.StorageService/Delete"
-	StorageService_Stats_FullMethodName   = "/kubelogs.storage.v1.StorageService/Stats"
+	StorageService_Write_FullMethodName                 = "/kubelogs.storage.v1.StorageService/Write"
+	StorageService_WriteStream_FullMethodName           = "/kubelogs.storage.v1.StorageService/WriteStream"
+	StorageService_Query_FullMethodName                 = "/kubelogs.storage.v1.StorageService/Query"
+	StorageService_GetByID_FullMethodName               = "/kubelogs.storage.v1.StorageService/GetByID"
+	StorageService_GetByIDs_FullMethodName              = "/kubelogs.storage.v1.StorageService/GetByIDs"
+	StorageService_Stats_FullMethodName                 = "/kubelogs.storage.v1.StorageService/Stats"
+	StorageService_Tail_FullMethodName                  = "/kubelogs.storage.v1.StorageService/Tail"
+	StorageService_Aggregate_FullMethodName             = "/kubelogs.storage.v1.StorageService/Aggregate"
+	StorageService_ReportCollectorStatus_FullMethodName = "/kubelogs.storage.v1.StorageService/ReportCollectorStatus"
 )
 
 // StorageServiceClient is the client API for StorageService service.
@@ -34,14 +38,35 @@ const (
 type StorageServiceClient interface {
 	// Write persists a batch of log entries.
 	Write(ctx context.Context, in *WriteRequest, opts ...grpc.CallOption) (*WriteResponse, error)
+	// WriteStream writes batches over one long-lived stream, saving the
+	// per-call overhead of Write for writers that send them continuously.
+	// Each request is a batch, written as by Write and acknowledged by a
+	// response with its count, in order. An error ends the stream with the
+	// status Write would have returned; batches not acknowledged weren't
+	// written.
+	WriteStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[WriteRequest, WriteResponse], error)
 	// Query searches for log entries matching the given criteria.
 	Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*QueryResponse, error)
 	// GetByID retrieves a single entry by its ID.
 	GetByID(ctx context.Context, in *GetByIDRequest, opts ...grpc.CallOption) (*GetByIDResponse, error)
-	// Delete removes entries older than the given timestamp.
-	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	// GetByIDs retrieves multiple entries by ID in one call.
+	GetByIDs(ctx context.Context, in *GetByIDsRequest, opts ...grpc.CallOption) (*GetByIDsResponse, error)
 	// Stats returns storage statistics.
 	Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error)
+	// Tail streams entries matching the query as they are written, oldest
+	// first. It starts after the request's after_id, or with entries
+	// written after the call if unset. Limit, order and order_by are
+	// ignored.
+	Tail(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[TailResponse], error)
+	// Aggregate counts the entries matching a query per severity in time
+	// buckets, without returning them. With group_by or value_attribute,
+	// it summarizes them per group instead. Stores that can't count
+	// return UNIMPLEMENTED.
+	Aggregate(ctx context.Context, in *AggregateRequest, opts ...grpc.CallOption) (*AggregateResponse, error)
+	// ReportCollectorStatus records a collector's health. Collectors call
+	// it periodically; the server keeps the latest report of each node in
+	// memory.
+	ReportCollectorStatus(ctx context.Context, in *ReportCollectorStatusRequest, opts ...grpc.CallOption) (*ReportCollectorStatusResponse, error)
 }
 
 type storageServiceClient struct {
@@ -62,6 +87,19 @@ func (c *storageServiceClient) Write(ctx context.Context, in *WriteRequest, opts
 	return out, nil
 }
 
+func (c *storageServiceClient) WriteStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[WriteRequest, WriteResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &StorageService_ServiceDesc.Streams[0], StorageService_WriteStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WriteRequest, WriteResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StorageService_WriteStreamClient = grpc.BidiStreamingClient[WriteRequest, WriteResponse]
+
 func (c *storageServiceClient) Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*QueryResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(QueryResponse)
@@ -82,10 +120,10 @@ func (c *storageServiceClient) GetByID(ctx context.Context, in *GetByIDRequest,
 	return out, nil
 }
 
-func (c *storageServiceClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+func (c *storageServiceClient) GetByIDs(ctx context.Context, in *GetByIDsRequest, opts ...grpc.CallOption) (*GetByIDsResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(DeleteResponse)
-	err := c.cc.Invoke(ctx, StorageService_Delete_FullMethodName, in, out, cOpts...)
+	out := new(GetByIDsResponse)
+	err := c.cc.Invoke(ctx, StorageService_GetByIDs_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -102,6 +140,45 @@ func (c *storageServiceClient) Stats(ctx context.Context, in *StatsRequest, opts
 	return out, nil
 }
 
+func (c *storageServiceClient) Tail(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[TailResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &StorageService_ServiceDesc.Streams[1], StorageService_Tail_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[QueryRequest, TailResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StorageService_TailClient = grpc.ServerStreamingClient[TailResponse]
+
+func (c *storageServiceClient) Aggregate(ctx context.Context, in *AggregateRequest, opts ...grpc.CallOption) (*AggregateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AggregateResponse)
+	err := c.cc.Invoke(ctx, StorageService_Aggregate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageServiceClient) ReportCollectorStatus(ctx context.Context, in *ReportCollectorStatusRequest, opts ...grpc.CallOption) (*ReportCollectorStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportCollectorStatusResponse)
+	err := c.cc.Invoke(ctx, StorageService_ReportCollectorStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // StorageServiceServer is the server API for StorageService service.
 // All implementations must embed UnimplementedStorageServiceServer
 // for forward compatibility.
@@ -110,14 +187,35 @@ func (c *storageServiceClient) Stats(ctx context.Context, in *StatsRequest, opts
 type StorageServiceServer interface {
 	// Write persists a batch of log entries.
 	Write(context.Context, *WriteRequest) (*WriteResponse, error)
+	// WriteStream writes batches over one long-lived stream, saving the
+	// per-call overhead of Write for writers that send them continuously.
+	// Each request is a batch, written as by Write and acknowledged by a
+	// response with its count, in order. An error ends the stream with the
+	// status Write would have returned; batches not acknowledged weren't
+	// written.
+	WriteStream(grpc.BidiStreamingServer[WriteRequest, WriteResponse]) error
 	// Query searches for log entries matching the given criteria.
 	Query(context.Context, *QueryRequest) (*QueryResponse, error)
 	// GetByID retrieves a single entry by its ID.
 	GetByID(context.Context, *GetByIDRequest) (*GetByIDResponse, error)
-	// Delete removes entries older than the given timestamp.
-	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+	// GetByIDs retrieves multiple entries by ID in one call.
+	GetByIDs(context.Context, *GetByIDsRequest) (*GetByIDsResponse, error)
 	// Stats returns storage statistics.
 	Stats(context.Context, *StatsRequest) (*StatsResponse, error)
+	// Tail streams entries matching the query as they are written, oldest
+	// first. It starts after the request's after_id, or with entries
+	// written after the call if unset. Limit, order and order_by are
+	// ignored.
+	Tail(*QueryRequest, grpc.ServerStreamingServer[TailResponse]) error
+	// Aggregate counts the entries matching a query per severity in time
+	// buckets, without returning them. With group_by or value_attribute,
+	// it summarizes them per group instead. Stores that can't count
+	// return UNIMPLEMENTED.
+	Aggregate(context.Context, *AggregateRequest) (*AggregateResponse, error)
+	// ReportCollectorStatus records a collector's health. Collectors call
+	// it periodically; the server keeps the latest report of each node in
+	// memory.
+	ReportCollectorStatus(context.Context, *ReportCollectorStatusRequest) (*ReportCollectorStatusResponse, error)
 	mustEmbedUnimplementedStorageServiceServer()
 }
 
@@ -131,18 +229,30 @@ type UnimplementedStorageServiceServer struct{}
 func (UnimplementedStorageServiceServer) Write(context.Context, *WriteRequest) (*WriteResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method Write not implemented")
 }
+func (UnimplementedStorageServiceServer) WriteStream(grpc.BidiStreamingServer[WriteRequest, WriteResponse]) error {
+	return status.Error(codes.Unimplemented, "method WriteStream not implemented")
+}
 func (UnimplementedStorageServiceServer) Query(context.Context, *QueryRequest) (*QueryResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method Query not implemented")
 }
 func (UnimplementedStorageServiceServer) GetByID(context.Context, *GetByIDRequest) (*GetByIDResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method GetByID not implemented")
 }
-func (UnimplementedStorageServiceServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "method Delete not implemented")
+func (UnimplementedStorageServiceServer) GetByIDs(context.Context, *GetByIDsRequest) (*GetByIDsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetByIDs not implemented")
 }
 func (UnimplementedStorageServiceServer) Stats(context.Context, *StatsRequest) (*StatsResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method Stats not implemented")
 }
+func (UnimplementedStorageServiceServer) Tail(*QueryRequest, grpc.ServerStreamingServer[TailResponse]) error {
+	return status.Error(codes.Unimplemented, "method Tail not implemented")
+}
+func (UnimplementedStorageServiceServer) Aggregate(context.Context, *AggregateRequest) (*AggregateResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Aggregate not implemented")
+}
+func (UnimplementedStorageServiceServer) ReportCollectorStatus(context.Context, *ReportCollectorStatusRequest) (*ReportCollectorStatusResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ReportCollectorStatus not implemented")
+}
 func (UnimplementedStorageServiceServer) mustEmbedUnimplementedStorageServiceServer() {}
 func (UnimplementedStorageServiceServer) testEmbeddedByValue()                        {}
 
@@ -182,6 +292,13 @@ func _StorageService_Write_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StorageService_WriteStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(StorageServiceServer).WriteStream(&grpc.GenericServerStream[WriteRequest, WriteResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StorageService_WriteStreamServer = grpc.BidiStreamingServer[WriteRequest, WriteResponse]
+
 func _StorageService_Query_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(QueryRequest)
 	if err := dec(in); err != nil {
@@ -218,20 +335,20 @@ func _StorageService_GetByID_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
-func _StorageService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DeleteRequest)
+func _StorageService_GetByIDs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetByIDsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(StorageServiceServer).Delete(ctx, in)
+		return srv.(StorageServiceServer).GetByIDs(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: StorageService_Delete_FullMethodName,
+		FullMethod: StorageService_GetByIDs_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(StorageServiceServer).Delete(ctx, req.(*DeleteRequest))
+		return srv.(StorageServiceServer).GetByIDs(ctx, req.(*GetByIDsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -254,6 +371,53 @@ func _StorageService_Stats_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StorageService_Tail_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(QueryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StorageServiceServer).Tail(m, &grpc.GenericServerStream[QueryRequest, TailResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StorageService_TailServer = grpc.ServerStreamingServer[TailResponse]
+
+func _StorageService_Aggregate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AggregateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServiceServer).Aggregate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageService_Aggregate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServiceServer).Aggregate(ctx, req.(*AggregateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageService_ReportCollectorStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportCollectorStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServiceServer).ReportCollectorStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageService_ReportCollectorStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServiceServer).ReportCollectorStatus(ctx, req.(*ReportCollectorStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // StorageService_ServiceDesc is the grpc.ServiceDesc for StorageService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -274,13 +438,281 @@ var StorageService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _StorageService_GetByID_Handler,
 		},
 		{
-			MethodName: "Delete",
-			Handler:    _StorageService_Delete_Handler,
+			MethodName: "GetByIDs",
+			Handler:    _StorageService_GetByIDs_Handler,
 		},
 		{
 			MethodName: "Stats",
 			Handler:    _StorageService_Stats_Handler,
 		},
+		{
+			MethodName: "Aggregate",
+			Handler:    _StorageService_Aggregate_Handler,
+		},
+		{
+			MethodName: "ReportCollectorStatus",
+			Handler:    _StorageService_ReportCollectorStatus_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WriteStream",
+			Handler:       _StorageService_WriteStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Tail",
+			Handler:       _StorageService_Tail_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "storage.proto",
+}
+
+const (
+	AdminService_PreviewDelete_FullMethodName        = "/kubelogs.storage.v1.AdminService/PreviewDelete"
+	AdminService_Delete_FullMethodName               = "/kubelogs.storage.v1.AdminService/Delete"
+	AdminService_PreviewDeleteByQuery_FullMethodName = "/kubelogs.storage.v1.AdminService/PreviewDeleteByQuery"
+	AdminService_DeleteByQuery_FullMethodName        = "/kubelogs.storage.v1.AdminService/DeleteByQuery"
+)
+
+// AdminServiceClient is the client API for AdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// AdminService provides the operations destroying stored logs. It is a
+// separate service so token authentication can require an admin token
+// for it, keeping collector credentials from deleting logs. Each delete
+// must present the token of a preview of the same delete, like those of
+// the web UI, which can confirm previews made here and the reverse.
+// Without one, deletes fail with FAILED_PRECONDITION.
+type AdminServiceClient interface {
+	// PreviewDelete counts the entries Delete would remove and issues its
+	// confirmation token.
+	PreviewDelete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*PreviewDeleteResponse, error)
+	// Delete removes entries older than the given timestamp.
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	// PreviewDeleteByQuery counts the entries DeleteByQuery would remove
+	// and issues its confirmation token.
+	PreviewDeleteByQuery(ctx context.Context, in *DeleteByQueryRequest, opts ...grpc.CallOption) (*PreviewDeleteResponse, error)
+	// DeleteByQuery removes the entries matching a query's filters, e.g. to
+	// purge a leaked secret. Pagination is ignored. A query without
+	// filters fails with INVALID_ARGUMENT; stores that can't delete by
+	// query return UNIMPLEMENTED.
+	DeleteByQuery(ctx context.Context, in *DeleteByQueryRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+}
+
+type adminServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminServiceClient(cc grpc.ClientConnInterface) AdminServiceClient {
+	return &adminServiceClient{cc}
+}
+
+func (c *adminServiceClient) PreviewDelete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*PreviewDeleteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PreviewDeleteResponse)
+	err := c.cc.Invoke(ctx, AdminService_PreviewDelete_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, AdminService_Delete_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) PreviewDeleteByQuery(ctx context.Context, in *DeleteByQueryRequest, opts ...grpc.CallOption) (*PreviewDeleteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PreviewDeleteResponse)
+	err := c.cc.Invoke(ctx, AdminService_PreviewDeleteByQuery_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) DeleteByQuery(ctx context.Context, in *DeleteByQueryRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, AdminService_DeleteByQuery_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServiceServer is the server API for AdminService service.
+// All implementations must embed UnimplementedAdminServiceServer
+// for forward compatibility.
+//
+// AdminService provides the operations destroying stored logs. It is a
+// separate service so token authentication can require an admin token
+// for it, keeping collector credentials from deleting logs. Each delete
+// must present the token of a preview of the same delete, like those of
+// the web UI, which can confirm previews made here and the reverse.
+// Without one, deletes fail with FAILED_PRECONDITION.
+type AdminServiceServer interface {
+	// PreviewDelete counts the entries Delete would remove and issues its
+	// confirmation token.
+	PreviewDelete(context.Context, *DeleteRequest) (*PreviewDeleteResponse, error)
+	// Delete removes entries older than the given timestamp.
+	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+	// PreviewDeleteByQuery counts the entries DeleteByQuery would remove
+	// and issues its confirmation token.
+	PreviewDeleteByQuery(context.Context, *DeleteByQueryRequest) (*PreviewDeleteResponse, error)
+	// DeleteByQuery removes the entries matching a query's filters, e.g. to
+	// purge a leaked secret. Pagination is ignored. A query without
+	// filters fails with INVALID_ARGUMENT; stores that can't delete by
+	// query return UNIMPLEMENTED.
+	DeleteByQuery(context.Context, *DeleteByQueryRequest) (*DeleteResponse, error)
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+// UnimplementedAdminServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAdminServiceServer struct{}
+
+func (UnimplementedAdminServiceServer) PreviewDelete(context.Context, *DeleteRequest) (*PreviewDeleteResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method PreviewDelete not implemented")
+}
+func (UnimplementedAdminServiceServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedAdminServiceServer) PreviewDeleteByQuery(context.Context, *DeleteByQueryRequest) (*PreviewDeleteResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method PreviewDeleteByQuery not implemented")
+}
+func (UnimplementedAdminServiceServer) DeleteByQuery(context.Context, *DeleteByQueryRequest) (*DeleteResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteByQuery not implemented")
+}
+func (UnimplementedAdminServiceServer) mustEmbedUnimplementedAdminServiceServer() {}
+func (UnimplementedAdminServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeAdminServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServiceServer will
+// result in compilation errors.
+type UnsafeAdminServiceServer interface {
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+func RegisterAdminServiceServer(s grpc.ServiceRegistrar, srv AdminServiceServer) {
+	// If the following call panics, it indicates UnimplementedAdminServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AdminService_ServiceDesc, srv)
+}
+
+func _AdminService_PreviewDelete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).PreviewDelete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_PreviewDelete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).PreviewDelete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_PreviewDeleteByQuery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteByQueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).PreviewDeleteByQuery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_PreviewDeleteByQuery_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).PreviewDeleteByQuery(ctx, req.(*DeleteByQueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_DeleteByQuery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteByQueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).DeleteByQuery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_DeleteByQuery_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).DeleteByQuery(ctx, req.(*DeleteByQueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AdminService_ServiceDesc is the grpc.ServiceDesc for AdminService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AdminService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "kubelogs.storage.v1.AdminService",
+	HandlerType: (*AdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "PreviewDelete",
+			Handler:    _AdminService_PreviewDelete_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _AdminService_Delete_Handler,
+		},
+		{
+			MethodName: "PreviewDeleteByQuery",
+			Handler:    _AdminService_PreviewDeleteByQuery_Handler,
+		},
+		{
+			MethodName: "DeleteByQuery",
+			Handler:    _AdminService_DeleteByQuery_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "storage.proto",
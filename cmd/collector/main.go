@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -12,16 +13,23 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/kubelogs/kubelogs/internal/collector"
+	"github.com/kubelogs/kubelogs/internal/logging"
+	"github.com/kubelogs/kubelogs/internal/metrics"
+	"github.com/kubelogs/kubelogs/internal/queue"
 	"github.com/kubelogs/kubelogs/internal/storage"
 	"github.com/kubelogs/kubelogs/internal/storage/remote"
 	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+	"github.com/kubelogs/kubelogs/internal/tlsconfig"
 )
 
 func main() {
 	// Initialize logger
-	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelDebug,
-	})))
+	logCfg, err := logging.ConfigFromEnv()
+	logLevel := logging.Setup(os.Stdout, logCfg)
+	if err != nil {
+		slog.Error("invalid logging configuration", "error", err)
+		os.Exit(1)
+	}
 
 	// Load collector configuration
 	cfg := collector.ConfigFromEnv()
@@ -38,11 +46,14 @@ func main() {
 	}
 	defer store.Close()
 
-	// Initialize Kubernetes client
-	clientset, err := initKubernetesClient()
-	if err != nil {
-		slog.Error("failed to initialize kubernetes client", "error", err)
-		os.Exit(1)
+	// Initialize Kubernetes client, unless tailing files
+	var clientset kubernetes.Interface
+	if len(cfg.LogFiles) == 0 {
+		clientset, err = initKubernetesClient()
+		if err != nil {
+			slog.Error("failed to initialize kubernetes client", "error", err)
+			os.Exit(1)
+		}
 	}
 
 	// Create collector
@@ -52,10 +63,25 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Serve Prometheus metrics
+	if cfg.MetricsEnabled {
+		reg := metrics.NewRegistry()
+		c.RegisterMetrics(reg)
+		go serveMetrics(cfg.MetricsAddr, reg, logLevel.Handler(logCfg.Token))
+	}
+
+	// Serve liveness and readiness probes
+	if cfg.HealthEnabled {
+		go serveHealth(cfg.HealthAddr, c)
+	}
+
 	// Setup context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// SIGUSR1 toggles debug logging
+	logLevel.NotifyDebug(ctx)
+
 	// Handle shutdown signals
 	go func() {
 		sigCh := make(chan os.Signal, 1)
@@ -81,11 +107,42 @@ func main() {
 }
 
 // initStore initializes the storage backend.
-// Uses remote storage if KUBELOGS_STORAGE_ADDR is set, otherwise local SQLite.
+// Publishes to the ingest queue if KUBELOGS_QUEUE_ADDR is set, uses remote
+// storage if KUBELOGS_STORAGE_ADDR is set (over TLS and with a token if
+// configured), otherwise local SQLite.
 func initStore() (storage.Store, error) {
+	if qcfg := queue.ConfigFromEnv(); qcfg.Addr != "" {
+		stream, err := queue.New(qcfg)
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("using ingest queue", "address", qcfg.Addr, "stream", qcfg.Stream)
+		return remote.NewQueueWriter(stream), nil
+	}
+
 	if addr := os.Getenv("KUBELOGS_STORAGE_ADDR"); addr != "" {
-		slog.Info("using remote storage", "address", addr)
-		return remote.NewClient(addr)
+		var opts []remote.ClientOption
+		caFile := os.Getenv("KUBELOGS_STORAGE_TLS_CA_FILE")
+		certFile := os.Getenv("KUBELOGS_STORAGE_TLS_CERT_FILE")
+		keyFile := os.Getenv("KUBELOGS_STORAGE_TLS_KEY_FILE")
+		useTLS := os.Getenv("KUBELOGS_STORAGE_TLS") == "true" || caFile != "" || certFile != "" || keyFile != ""
+		if useTLS {
+			tlsCfg, err := tlsconfig.Client(caFile, certFile, keyFile, os.Getenv("KUBELOGS_STORAGE_TLS_SERVER_NAME"))
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, remote.WithTLS(tlsCfg))
+		}
+		token := os.Getenv("KUBELOGS_STORAGE_TOKEN")
+		if token != "" {
+			opts = append(opts, remote.WithToken(token))
+		}
+		compression := os.Getenv("KUBELOGS_STORAGE_COMPRESSION")
+		if compression != "" {
+			opts = append(opts, remote.WithCompression(compression))
+		}
+		slog.Info("using remote storage", "address", addr, "tls", useTLS, "mtls", certFile != "", "token", token != "", "compression", compression)
+		return remote.NewClient(addr, opts...)
 	}
 
 	dbPath := os.Getenv("KUBELOGS_DB_PATH")
@@ -97,6 +154,28 @@ func initStore() (storage.Store, error) {
 	return sqlite.New(sqlite.Config{Path: dbPath})
 }
 
+// serveMetrics serves reg on /metrics at addr, and level on /loglevel.
+// Failures are logged rather than fatal, since collection works without
+// metrics.
+func serveMetrics(addr string, reg *metrics.Registry, level http.Handler) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", reg)
+	mux.Handle("/loglevel", level)
+	slog.Info("metrics server starting", "address", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		slog.Error("metrics server error", "error", err)
+	}
+}
+
+// serveHealth serves the health endpoints of c at addr. Failures are
+// logged rather than fatal, like those of the metrics server.
+func serveHealth(addr string, c *collector.Collector) {
+	slog.Info("health server starting", "address", addr)
+	if err := http.ListenAndServe(addr, c.HealthHandler()); err != nil {
+		slog.Error("health server error", "error", err)
+	}
+}
+
 // initKubernetesClient initializes the Kubernetes client.
 // Uses in-cluster config if available, falls back to kubeconfig.
 func initKubernetesClient() (kubernetes.Interface, error) {
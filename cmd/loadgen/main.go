@@ -5,17 +5,22 @@ import (
 	"flag"
 	"fmt"
 	"log/slog"
+	"math"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/keepalive"
 
 	"github.com/kubelogs/kubelogs/api/storagepb"
 	"github.com/kubelogs/kubelogs/internal/loadgen"
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/remote"
+	"github.com/kubelogs/kubelogs/internal/tlsconfig"
 )
 
 var (
@@ -44,23 +49,60 @@ func main() {
 		Level: level,
 	})))
 
-	slog.Info("kubelogs-loadgen starting",
-		"version", Version,
-		"addr", cfg.Addr,
-		"rate", cfg.Rate,
-		"duration", cfg.Duration,
-		"batch_size", cfg.BatchSize,
-	)
+	// Create generator; a scenario sets its own rates and duration
+	gen := loadgen.NewGenerator(cfg)
+	duration := cfg.Duration
+	if cfg.Mode == "query" {
+		slog.Info("kubelogs-loadgen starting",
+			"version", Version,
+			"addr", cfg.Addr,
+			"mode", cfg.Mode,
+			"concurrency", cfg.Concurrency,
+			"duration", duration,
+		)
+	} else if sc := gen.Scenario(); sc != nil {
+		duration = sc.Duration()
+		slog.Info("kubelogs-loadgen starting",
+			"version", Version,
+			"addr", cfg.Addr,
+			"scenario", cfg.Scenario,
+			"phases", len(sc.Phases),
+			"duration", duration,
+			"batch_size", cfg.BatchSize,
+		)
+	} else {
+		slog.Info("kubelogs-loadgen starting",
+			"version", Version,
+			"addr", cfg.Addr,
+			"rate", cfg.Rate,
+			"duration", duration,
+			"batch_size", cfg.BatchSize,
+		)
+	}
+
+	creds := insecure.NewCredentials()
+	if cfg.TLS {
+		tlsCfg, err := tlsconfig.Client(cfg.TLSCAFile, cfg.TLSCertFile, cfg.TLSKeyFile, "")
+		if err != nil {
+			slog.Error("invalid TLS configuration", "error", err)
+			os.Exit(1)
+		}
+		creds = credentials.NewTLS(tlsCfg)
+	}
 
 	// Create gRPC connection (following remote/client.go pattern)
-	conn, err := grpc.NewClient(cfg.Addr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
 		grpc.WithKeepaliveParams(keepalive.ClientParameters{
 			Time:                10 * time.Second,
 			Timeout:             5 * time.Second,
 			PermitWithoutStream: true,
 		}),
-	)
+	}
+	if cfg.Token != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(remote.TokenCredentials(cfg.Token)))
+	}
+	conn, err := grpc.NewClient(cfg.Addr, dialOpts...)
 	if err != nil {
 		slog.Error("failed to connect", "error", err)
 		os.Exit(1)
@@ -70,7 +112,7 @@ func main() {
 	client := storagepb.NewStorageServiceClient(conn)
 
 	// Setup context with cancellation and deadline
-	ctx, cancel := context.WithTimeout(context.Background(), cfg.Duration)
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
 	defer cancel()
 
 	// Handle shutdown signals
@@ -82,12 +124,19 @@ func main() {
 		cancel()
 	}()
 
-	// Create generator and sender
-	gen := loadgen.NewGenerator(cfg)
+	if cfg.Mode == "query" {
+		runQueries(ctx, client, cfg)
+		return
+	}
+
 	sender := loadgen.NewSender(client, cfg.BatchSize)
 
 	// Run the load generator
-	if err := run(ctx, gen, sender, cfg); err != nil && err != context.DeadlineExceeded && err != context.Canceled {
+	runFn := run
+	if gen.Scenario() != nil {
+		runFn = runScenario
+	}
+	if err := runFn(ctx, gen, sender, cfg); err != nil && err != context.DeadlineExceeded && err != context.Canceled {
 		slog.Error("load generator error", "error", err)
 		os.Exit(1)
 	}
@@ -100,6 +149,15 @@ func main() {
 		"errors", stats.Errors,
 		"duration", time.Since(stats.StartTime).Round(time.Millisecond),
 	)
+	// The server's counts per severity confirm the generated distribution
+	// arrived intact
+	for _, sev := range stats.SeverityMismatches() {
+		slog.Warn("server counted a different number of entries of a severity",
+			"severity", storage.Severity(sev).String(),
+			"generated", stats.SentSeverities[sev],
+			"counted", stats.WrittenSeverities[sev],
+		)
+	}
 }
 
 func run(ctx context.Context, gen *loadgen.Generator, sender *loadgen.Sender, cfg loadgen.Config) error {
@@ -121,3 +179,68 @@ func run(ctx context.Context, gen *loadgen.Generator, sender *loadgen.Sender, cf
 		}
 	}
 }
+
+// runQueries issues concurrent reads until ctx is done and logs the
+// latency percentiles of each kind.
+func runQueries(ctx context.Context, client storagepb.StorageServiceClient, cfg loadgen.Config) {
+	q := loadgen.NewQuerier(client, cfg)
+	start := time.Now()
+	q.Run(ctx)
+	elapsed := time.Since(start)
+
+	for _, s := range q.Stats() {
+		slog.Info("query latency",
+			"op", s.Op,
+			"calls", s.Calls,
+			"errors", s.Errors,
+			"per_second", math.Round(float64(s.Calls)/elapsed.Seconds()*10)/10,
+			"p50", s.P50.Round(time.Microsecond),
+			"p90", s.P90.Round(time.Microsecond),
+			"p99", s.P99.Round(time.Microsecond),
+			"max", s.Max.Round(time.Microsecond),
+		)
+	}
+	slog.Info("query load complete", "duration", elapsed.Round(time.Millisecond))
+}
+
+// scenarioTick is how often runScenario sends the lines due at the
+// scenario's current rate.
+const scenarioTick = 10 * time.Millisecond
+
+// runScenario sends lines at the rate of the generator's scenario, which
+// changes over its phases, until ctx is done.
+func runScenario(ctx context.Context, gen *loadgen.Generator, sender *loadgen.Sender, _ loadgen.Config) error {
+	sc := gen.Scenario()
+	ticker := time.NewTicker(scenarioTick)
+	defer ticker.Stop()
+
+	start := time.Now()
+	last := start
+	phase := -1
+	var due float64
+	for {
+		select {
+		case <-ctx.Done():
+			return sender.Flush(context.Background())
+		case now := <-ticker.C:
+			elapsed := now.Sub(start)
+			if p := sc.Phase(elapsed); p != phase && p >= 0 {
+				phase = p
+				slog.Info("phase started",
+					"phase", sc.Phases[p].Name,
+					"index", p+1,
+					"duration", time.Duration(sc.Phases[p].Duration),
+					"rate", int(sc.Rate(elapsed)),
+				)
+			}
+			// Lines owed since the last tick; fractions carry over
+			due += sc.Rate(elapsed) * now.Sub(last).Seconds()
+			last = now
+			for ; due >= 1; due-- {
+				if err := sender.Send(ctx, gen.Next()); err != nil {
+					slog.Warn("send failed", "error", err)
+				}
+			}
+		}
+	}
+}
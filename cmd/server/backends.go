@@ -0,0 +1,11 @@
+package main
+
+// Storage backends compiled into the server, selectable by name with
+// KUBELOGS_STORAGE_BACKEND or in a routing spec. Each package registers
+// itself with storage.Register when imported; to add a backend, import
+// its package here.
+import (
+	_ "github.com/kubelogs/kubelogs/internal/storage/objstore"
+	_ "github.com/kubelogs/kubelogs/internal/storage/postgres"
+	_ "github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
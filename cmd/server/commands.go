@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/signal"
+	"os/user"
+	"syscall"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/audit"
+	"github.com/kubelogs/kubelogs/internal/server"
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// progressInterval is how often commands log their progress.
+const progressInterval = 5 * time.Second
+
+// runCommand runs the subcommand named by args[0] against store instead
+// of serving, and returns the exit code. db holds the metadata, such as
+// the audit log.
+func runCommand(args []string, cfg server.Config, store storage.Store, db *sql.DB) int {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	switch args[0] {
+	case "rebuild-search-index":
+		return rebuildSearchIndex(ctx, store)
+	case "restore-archive":
+		return restoreArchive(ctx, cfg, store, db, args[1:])
+	case "apply-retention":
+		return applyRetention(ctx, cfg, store, db, args[1:])
+	default:
+		slog.Error("unknown command", "command", args[0], "commands", "rebuild-search-index, restore-archive, apply-retention")
+		return 2
+	}
+}
+
+// rebuildSearchIndex rebuilds the full-text index of store, logging
+// progress as it goes.
+func rebuildSearchIndex(ctx context.Context, store storage.Store) int {
+	rb, ok := store.(storage.SearchIndexRebuilder)
+	if !ok {
+		slog.Error("storage backend does not support rebuilding the search index")
+		return 1
+	}
+
+	slog.Info("rebuilding search index")
+	start := time.Now()
+	var logged time.Time
+	indexed, err := rb.RebuildSearchIndex(ctx, func(p storage.IndexProgress) {
+		if time.Since(logged) < progressInterval {
+			return
+		}
+		logged = time.Now()
+		slog.Info("rebuilding search index", "indexed", p.Indexed, "total", p.Total)
+	})
+	if err != nil {
+		slog.Error("search index rebuild failed", "indexed", indexed, "error", err)
+		return 1
+	}
+	slog.Info("rebuilt search index", "indexed", indexed, "duration", time.Since(start).Round(time.Millisecond))
+	return 0
+}
+
+// restoreArchive writes the archived entries of the days in args, a
+// start day and an optional end day (inclusive, default the start day),
+// to store. Without -confirm it lists what would be restored and the
+// token confirming it.
+func restoreArchive(ctx context.Context, cfg server.Config, store storage.Store, db *sql.DB, args []string) int {
+	if cfg.ArchiveBucket == "" {
+		slog.Error("no archive configured, set KUBELOGS_ARCHIVE_BUCKET")
+		return 1
+	}
+	fs := flag.NewFlagSet("restore-archive", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	token := fs.String("confirm", "", "token printed by a run without -confirm")
+	if err := fs.Parse(args); err != nil {
+		slog.Error("usage: restore-archive [-confirm TOKEN] START_DAY [END_DAY]", "error", err)
+		return 2
+	}
+	start, end, err := parseDays(fs.Args())
+	if err != nil {
+		slog.Error("usage: restore-archive [-confirm TOKEN] START_DAY [END_DAY]", "error", err)
+		return 2
+	}
+	archiver, err := newArchiver(cfg)
+	if err != nil {
+		slog.Error("invalid archive configuration", "error", err)
+		return 1
+	}
+
+	// The token covers the days and the objects found, so it no longer
+	// matches if the archive changes in between
+	objects, err := archiver.Objects(ctx, start, end)
+	if err != nil {
+		slog.Error("listing archive failed", "error", err)
+		return 1
+	}
+	filter := fmt.Sprintf(`{"start":%q,"end":%q}`, start.Format(time.DateOnly), end.Add(-24*time.Hour).Format(time.DateOnly))
+	op := "restore " + filter
+	var entries int64
+	for _, o := range objects {
+		op += "\n" + o.Key
+		entries += int64(o.Entries)
+	}
+	if *token != confirmToken(op) {
+		if *token != "" {
+			slog.Error("confirm token does not match this restore; the days or the archive differ from the preview")
+			return 1
+		}
+		slog.Info("restore preview: run again with the token to restore",
+			"filter", filter, "objects", len(objects), "max_entries", entries, "token", confirmToken(op))
+		return 0
+	}
+
+	slog.Info("restoring archived entries", "start", start.Format(time.DateOnly), "end", end.Format(time.DateOnly))
+	restored, err := archiver.Restore(ctx, store, start, end)
+	if err != nil {
+		slog.Error("restore failed", "restored", restored, "error", err)
+		return 1
+	}
+	slog.Info("restored archived entries", "restored", restored)
+	recordCommand(ctx, db, audit.OpRestore, filter, restored)
+	return 0
+}
+
+// applyRetention applies a configured retention shorter than the one
+// applied, which the server otherwise keeps to. Without a token in args
+// it counts the entries the change deletes and prints the token
+// confirming it.
+func applyRetention(ctx context.Context, cfg server.Config, store storage.Store, db *sql.DB, args []string) int {
+	if len(args) > 1 {
+		slog.Error("usage: apply-retention [TOKEN]")
+		return 2
+	}
+	w := server.NewRetentionWorker(store, cfg)
+	w.GuardShrinkage(db)
+	applied, configured, pending, err := w.PendingShrinkage(ctx)
+	if err != nil {
+		slog.Error("reading retention policy failed", "error", err)
+		return 1
+	}
+	if !pending {
+		slog.Info("the configured retention doesn't shorten the one applied, nothing to confirm")
+		return 0
+	}
+
+	filter := server.RetentionChange(applied, configured)
+	token := confirmToken("retention " + filter)
+	if len(args) == 1 && args[0] != token {
+		slog.Error("confirm token does not match this retention change")
+		return 1
+	}
+	matched, err := server.CountShrinkage(ctx, store, applied, configured)
+	if err != nil {
+		slog.Error("counting entries failed", "error", err)
+		return 1
+	}
+	if len(args) == 0 {
+		slog.Info("retention preview: run again with the token to apply", "change", filter, "matched", matched, "token", token)
+		return 0
+	}
+
+	if err := w.ApplyRetention(ctx); err != nil {
+		slog.Error("applying retention failed", "error", err)
+		return 1
+	}
+	slog.Info("applied shorter retention; the server deletes accordingly on its next run", "change", filter)
+	recordCommand(ctx, db, audit.OpRetention, filter, matched)
+	return 0
+}
+
+// confirmToken returns the token confirming op on the command line. It
+// is derived from op, so a preview and the confirming run, which are
+// separate processes, agree on it.
+func confirmToken(op string) string {
+	sum := sha256.Sum256([]byte(op))
+	return hex.EncodeToString(sum[:8])
+}
+
+// recordCommand adds an operation confirmed on the command line to the
+// audit log, as requested and approved by the user running it.
+func recordCommand(ctx context.Context, db *sql.DB, op, filter string, count int64) {
+	name := "cli"
+	if u, err := user.Current(); err == nil {
+		name = "cli:" + u.Username
+	}
+	r := audit.Record{Operation: op, Filter: filter, Requester: name, Approver: name, Count: count}
+	if err := audit.NewStore(db).Add(context.WithoutCancel(ctx), r); err != nil {
+		slog.Error("failed to write audit record", "operation", op, "error", err)
+	}
+}
+
+// parseDays parses a start day and an optional end day, as 2006-01-02
+// in UTC, into the range from the start of the first to the end of the
+// last.
+func parseDays(args []string) (time.Time, time.Time, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return time.Time{}, time.Time{}, fmt.Errorf("want 1 or 2 days, got %d arguments", len(args))
+	}
+	start, err := time.Parse(time.DateOnly, args[0])
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	last := start
+	if len(args) == 2 {
+		if last, err = time.Parse(time.DateOnly, args[1]); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	if last.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("end day %s is before start day %s", args[1], args[0])
+	}
+	return start, last.Add(24 * time.Hour), nil
+}
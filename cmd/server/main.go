@@ -7,18 +7,31 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/reflection"
 
+	"github.com/kubelogs/kubelogs/api/otlppb"
 	"github.com/kubelogs/kubelogs/api/storagepb"
+	"github.com/kubelogs/kubelogs/internal/archive"
+	"github.com/kubelogs/kubelogs/internal/audit"
+	"github.com/kubelogs/kubelogs/internal/logging"
+	"github.com/kubelogs/kubelogs/internal/metrics"
+	"github.com/kubelogs/kubelogs/internal/queue"
 	"github.com/kubelogs/kubelogs/internal/server"
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/objstore"
+	"github.com/kubelogs/kubelogs/internal/storage/router"
 	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+	"github.com/kubelogs/kubelogs/internal/tlsconfig"
 )
 
 func main() {
@@ -26,32 +39,85 @@ func main() {
 	cfg := server.ConfigFromEnv()
 
 	// Initialize logger
-	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
-	})))
+	logCfg, err := logging.ConfigFromEnv()
+	logLevel := logging.Setup(os.Stdout, logCfg)
+	if err != nil {
+		slog.Error("invalid logging configuration", "error", err)
+		os.Exit(1)
+	}
+
+	reg := metrics.NewRegistry()
 
-	// Open SQLite store
-	store, err := sqlite.New(sqlite.Config{Path: cfg.DBPath})
+	// Open SQLite store, with the storage options when it holds logs
+	dbSpec := router.StoreSpec{Path: cfg.DBPath}
+	if cfg.StorageBackend == "sqlite" && cfg.StorageRoutesFile == "" {
+		dbSpec.Extra = cfg.StorageOptions
+	}
+	dbOpts := sqliteOptions(cfg, dbSpec.Options())
+	dbOpts["path"] = cfg.DBPath
+	dbCfg, err := sqlite.ConfigFromOptions(dbOpts)
+	if err != nil {
+		slog.Error("invalid SQLite options", "error", err)
+		os.Exit(1)
+	}
+	db, err := sqlite.New(dbCfg)
 	if err != nil {
 		slog.Error("failed to open database", "path", cfg.DBPath, "error", err)
 		os.Exit(1)
 	}
-	defer store.Close()
+	defer db.Close()
+	db.RegisterMetrics(reg)
 
 	slog.Info("database opened", "path", cfg.DBPath)
 
+	// Logs go to SQLite unless another backend or routing is configured;
+	// SQLite always holds metadata such as users and sessions
+	store, err := openLogStore(cfg, db, reg)
+	if err != nil {
+		slog.Error("failed to open log storage", "error", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	// Subcommands, such as rebuild-search-index, work on the stores and
+	// exit instead of serving
+	if len(os.Args) > 1 {
+		code := runCommand(os.Args[1:], cfg, store, db.DB())
+		store.Close()
+		db.Close()
+		os.Exit(code)
+	}
+
 	// Setup context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Start retention worker (if enabled)
-	if cfg.RetentionEnabled() {
-		retentionWorker := server.NewRetentionWorker(store, cfg)
-		go retentionWorker.Run(ctx)
+	// SIGUSR1 toggles debug logging
+	logLevel.NotifyDebug(ctx)
+
+	// Start retention worker; it returns at once unless retention is
+	// enabled or the store keeps entry expiry times
+	retentionWorker := server.NewRetentionWorker(store, cfg)
+	retentionWorker.RegisterMetrics(reg)
+	retentionWorker.GuardShrinkage(db.DB())
+	if cfg.ArchiveBucket != "" {
+		archiver, err := newArchiver(cfg)
+		if err != nil {
+			slog.Error("invalid archive configuration", "error", err)
+			os.Exit(1)
+		}
+		retentionWorker.SetArchiver(archiver)
+		slog.Info("archiving entries before retention", "bucket", cfg.ArchiveBucket, "prefix", cfg.ArchivePrefix)
+	}
+	go retentionWorker.Run(ctx)
+
+	// Keep hourly entry counts for /api/stats/timeseries (if enabled)
+	if cfg.LogStatsInterval > 0 {
+		go server.NewStatsAggregator(store, db.DB(), cfg).Run(ctx)
 	}
 
 	// Create gRPC server with keepalive to detect dead connections
-	grpcServer := grpc.NewServer(
+	grpcOpts := []grpc.ServerOption{
 		grpc.KeepaliveParams(keepalive.ServerParameters{
 			Time:    15 * time.Second, // Ping client every 15s if idle
 			Timeout: 5 * time.Second,  // Wait 5s for ping ack
@@ -60,24 +126,102 @@ func main() {
 			MinTime:             10 * time.Second, // Minimum time between client pings
 			PermitWithoutStream: true,
 		}),
-	)
-	storagepb.RegisterStorageServiceServer(grpcServer, server.New(store))
+	}
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		tlsCfg, err := tlsconfig.Server(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSClientCAFile)
+		if err != nil {
+			slog.Error("invalid TLS configuration", "error", err)
+			os.Exit(1)
+		}
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	} else if cfg.TLSClientCAFile != "" {
+		slog.Error("invalid TLS configuration", "error", "KUBELOGS_TLS_CLIENT_CA_FILE requires a server certificate")
+		os.Exit(1)
+	}
+	if tokenAuth := server.NewTokenAuth(cfg.GRPCToken, cfg.GRPCCollectorTokens, cfg.GRPCAdminToken); tokenAuth != nil {
+		grpcOpts = append(grpcOpts,
+			grpc.ChainUnaryInterceptor(tokenAuth.UnaryInterceptor()),
+			grpc.ChainStreamInterceptor(tokenAuth.StreamInterceptor()),
+		)
+	} else if cfg.AuthEnabled {
+		slog.Warn("KUBELOGS_AUTH_ENABLED protects only the web UI; set KUBELOGS_GRPC_TOKEN to authenticate gRPC clients")
+	}
+	grpcServer := grpc.NewServer(grpcOpts...)
+	storageServer := server.New(store)
+	storageServer.SetClusterQuotas(cfg.ClusterQuotas)
+	storageServer.SetBackpressure(cfg.BackpressureLatency, cfg.MinFreeDiskBytes, cfg.DBPath)
+	storageServer.SetQueryTimeout(cfg.QueryTimeout)
+	fleet := server.NewFleet()
+	storageServer.SetFleet(fleet)
+	enrichers, err := server.NewEnrichers(cfg)
+	if err != nil {
+		slog.Error("invalid enricher configuration", "error", err)
+		os.Exit(1)
+	}
+	storageServer.SetEnrichers(enrichers...)
+	storageServer.RegisterMetrics(reg)
+	storagepb.RegisterStorageServiceServer(grpcServer, storageServer)
+	// Deletes are previewed and confirmed with a token on the web UI and
+	// the AdminService alike, and recorded in the audit log
+	confirms := server.NewConfirmations(cfg.RequireSecondApprover, audit.NewStore(db.DB()))
+	// The AdminService is served only with its own token, so it is never
+	// open to whoever reaches the gRPC port
+	if cfg.GRPCAdminToken != "" {
+		storagepb.RegisterAdminServiceServer(grpcServer, server.NewAdminServer(store, confirms))
+	}
+	if cfg.OTLPReceiver {
+		otlppb.RegisterLogsServiceServer(grpcServer, server.NewOTLPReceiver(storageServer))
+	}
+
+	// Consume batches collectors publish to the ingest queue
+	if qcfg := queue.ConfigFromEnv(); qcfg.Addr != "" {
+		stream, err := queue.New(qcfg)
+		if err != nil {
+			slog.Error("invalid ingest queue configuration", "error", err)
+			os.Exit(1)
+		}
+		defer stream.Close()
+		slog.Info("consuming ingest queue", "address", qcfg.Addr, "stream", qcfg.Stream, "consumer", qcfg.Consumer)
+		go storageServer.ConsumeQueue(ctx, stream)
+	}
 
 	// Register health check service
-	healthServer := health.NewServer()
-	grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
-	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+	var healthServer *health.Server
+	if cfg.GRPCHealth {
+		healthServer = health.NewServer()
+		grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
+		healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+	}
 
 	// Register reflection for debugging
-	reflection.Register(grpcServer)
+	if cfg.GRPCReflection {
+		reflection.Register(grpcServer)
+	}
+
+	grpcAddr, err := server.ResolveListenAddr(cfg.ListenAddr)
+	if err != nil {
+		slog.Error("invalid gRPC listen address", "error", err)
+		os.Exit(1)
+	}
+	httpAddr, err := server.ResolveListenAddr(cfg.HTTPListenAddr)
+	if cfg.HTTPEnabled && err != nil {
+		slog.Error("invalid HTTP listen address", "error", err)
+		os.Exit(1)
+	}
 
 	// Start HTTP server for web UI
+	var httpServer *server.HTTPServer
 	if cfg.HTTPEnabled {
-		httpServer, err := server.NewHTTPServer(store, store.DB(), cfg)
+		httpServer, err = server.NewHTTPServer(store, db.DB(), cfg)
 		if err != nil {
 			slog.Error("failed to create HTTP server", "error", err)
 			os.Exit(1)
 		}
+		httpServer.RegisterMetrics(reg)
+		httpServer.SetFleet(fleet)
+		httpServer.SetConfirmations(confirms)
+		httpServer.SetRetention(retentionWorker)
+		httpServer.SetLogLevel(logLevel)
 
 		// Start session cleanup goroutine if auth is enabled
 		if cfg.AuthEnabled && httpServer.SessionStore() != nil {
@@ -100,27 +244,67 @@ func main() {
 			}()
 		}
 
+		httpLis, err := net.Listen("tcp", httpAddr)
+		if err != nil {
+			slog.Error("failed to listen", "address", httpAddr, "error", err)
+			os.Exit(1)
+		}
+		if cfg.ProxyProtocol {
+			httpLis, err = server.NewProxyListener(httpLis, cfg.TrustedProxies)
+			if err != nil {
+				slog.Error("invalid PROXY protocol configuration", "error", err)
+				os.Exit(1)
+			}
+		}
+
 		go func() {
-			slog.Info("HTTP server starting", "address", cfg.HTTPListenAddr)
-			if err := http.ListenAndServe(cfg.HTTPListenAddr, httpServer.Routes()); err != nil && err != http.ErrServerClosed {
+			slog.Info("HTTP server starting", "address", httpAddr, "proxy_protocol", cfg.ProxyProtocol)
+			if err := httpServer.Serve(httpLis); err != nil && err != http.ErrServerClosed {
 				slog.Error("HTTP server error", "error", err)
 			}
 		}()
 	}
 
+	// Serve Prometheus metrics and the log level on their own listener,
+	// outside web UI auth, so changing the level there needs a token
+	if cfg.MetricsEnabled {
+		metricsLis, err := net.Listen("tcp", cfg.MetricsListenAddr)
+		if err != nil {
+			slog.Error("failed to listen", "address", cfg.MetricsListenAddr, "error", err)
+			os.Exit(1)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", reg)
+		mux.Handle("/loglevel", logLevel.Handler(logCfg.Token))
+		go func() {
+			slog.Info("metrics server starting", "address", cfg.MetricsListenAddr)
+			if err := http.Serve(metricsLis, mux); err != nil && err != http.ErrServerClosed {
+				slog.Error("metrics server error", "error", err)
+			}
+		}()
+	}
+
 	// Start listening
-	lis, err := net.Listen("tcp", cfg.ListenAddr)
+	lis, err := net.Listen("tcp", grpcAddr)
 	if err != nil {
-		slog.Error("failed to listen", "address", cfg.ListenAddr, "error", err)
+		slog.Error("failed to listen", "address", grpcAddr, "error", err)
 		os.Exit(1)
 	}
 
 	slog.Info("server starting",
-		"grpc_address", cfg.ListenAddr,
-		"http_address", cfg.HTTPListenAddr,
+		"grpc_address", grpcAddr,
+		"http_address", httpAddr,
+		"grpc_health", cfg.GRPCHealth,
+		"grpc_reflection", cfg.GRPCReflection,
+		"grpc_tls", cfg.TLSCertFile != "",
+		"grpc_mtls", cfg.TLSClientCAFile != "",
+		"grpc_token_auth", cfg.GRPCToken != "" || len(cfg.GRPCCollectorTokens) > 0 || cfg.GRPCAdminToken != "",
+		"grpc_admin_service", cfg.GRPCAdminToken != "",
 		"http_enabled", cfg.HTTPEnabled,
 		"auth_enabled", cfg.AuthEnabled,
 		"retention_days", cfg.RetentionDays,
+		"storage_backend", cfg.StorageBackend,
+		"enrichers", cfg.Enrichers,
 	)
 
 	// Handle shutdown
@@ -130,8 +314,38 @@ func main() {
 		<-sigCh
 
 		slog.Info("shutdown signal received")
-		healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
-		grpcServer.GracefulStop()
+		if healthServer != nil {
+			healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+		}
+
+		// Drain HTTP requests while gRPC streams finish
+		httpDone := make(chan struct{})
+		go func() {
+			defer close(httpDone)
+			if httpServer == nil {
+				return
+			}
+			shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.HTTPShutdownTimeout)
+			defer cancelShutdown()
+			if err := httpServer.Shutdown(shutdownCtx); err != nil {
+				slog.Warn("HTTP requests still running at shutdown timeout", "timeout", cfg.HTTPShutdownTimeout, "error", err)
+			}
+		}()
+		// Streams stay open as long as their clients; end them so the
+		// graceful stop can finish, and cut it short like HTTP's
+		storageServer.Stop()
+		grpcDone := make(chan struct{})
+		go func() {
+			defer close(grpcDone)
+			grpcServer.GracefulStop()
+		}()
+		select {
+		case <-grpcDone:
+		case <-time.After(cfg.HTTPShutdownTimeout):
+			slog.Warn("gRPC calls still running at shutdown timeout", "timeout", cfg.HTTPShutdownTimeout)
+			grpcServer.Stop()
+		}
+		<-httpDone
 		cancel()
 	}()
 
@@ -144,3 +358,95 @@ func main() {
 	<-ctx.Done()
 	slog.Info("server stopped")
 }
+
+// sqliteOptions adds the server's SQLite preset, indexed attributes, FTS
+// shedding backlog and hash chain to opts, unless they set their own.
+func sqliteOptions(cfg server.Config, opts storage.Options) storage.Options {
+	if _, ok := opts["preset"]; !ok && cfg.SQLitePreset != "" {
+		opts["preset"] = cfg.SQLitePreset
+	}
+	if _, ok := opts["indexed_attributes"]; !ok && len(cfg.SQLiteIndexedAttributes) > 0 {
+		opts["indexed_attributes"] = strings.Join(cfg.SQLiteIndexedAttributes, " ")
+	}
+	if _, ok := opts["fts_shed_backlog"]; !ok && cfg.SQLiteFTSShedBacklog > 0 {
+		opts["fts_shed_backlog"] = strconv.Itoa(cfg.SQLiteFTSShedBacklog)
+	}
+	if _, ok := opts["hash_chain"]; !ok && cfg.SQLiteHashChain {
+		opts["hash_chain"] = "true"
+	}
+	return opts
+}
+
+// newArchiver creates the archiver for the bucket in cfg.
+func newArchiver(cfg server.Config) (*archive.Archiver, error) {
+	bucket, err := objstore.NewS3Bucket(objstore.S3Config{
+		Endpoint:        cfg.ArchiveEndpoint,
+		Region:          cfg.ArchiveRegion,
+		Bucket:          cfg.ArchiveBucket,
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		PathStyle:       cfg.ArchivePathStyle,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return archive.New(bucket, cfg.ArchivePrefix), nil
+}
+
+// openLogStore opens the store for log entries described by cfg. db is
+// reused for SQLite stores at cfg.DBPath; other SQLite stores register
+// their metrics with reg.
+func openLogStore(cfg server.Config, db *sqlite.Store, reg *metrics.Registry) (storage.Store, error) {
+	open := func(backend string, opts storage.Options) (storage.Store, error) {
+		if backend == "sqlite" && opts["path"] == cfg.DBPath {
+			return db, nil
+		}
+		if _, ok := opts["cache_max_bytes"]; !ok {
+			opts["cache_max_bytes"] = strconv.FormatInt(cfg.S3CacheMaxBytes, 10)
+		}
+		if backend == "sqlite" {
+			opts = sqliteOptions(cfg, opts)
+		}
+		store, err := storage.Open(backend, opts)
+		if s, ok := store.(*sqlite.Store); ok {
+			s.RegisterMetrics(reg)
+		}
+		return store, err
+	}
+
+	if cfg.StorageRoutesFile != "" {
+		spec, err := router.LoadSpec(cfg.StorageRoutesFile)
+		if err != nil {
+			return nil, err
+		}
+		r, err := spec.Build(db.DB(), func(ss router.StoreSpec) (storage.Store, error) {
+			return open(ss.Backend, ss.Options())
+		})
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("storage routes loaded", "file", cfg.StorageRoutesFile, "stores", len(spec.Stores), "routes", len(spec.Routes))
+		return r, nil
+	}
+
+	opts := router.StoreSpec{
+		Path:      cfg.DBPath,
+		DSN:       cfg.PostgresDSN,
+		Endpoint:  cfg.S3Endpoint,
+		Region:    cfg.S3Region,
+		Bucket:    cfg.S3Bucket,
+		Prefix:    cfg.S3Prefix,
+		PathStyle: cfg.S3PathStyle,
+		CacheDir:  cfg.S3CacheDir,
+		Extra:     cfg.StorageOptions,
+	}.Options()
+	store, err := open(cfg.StorageBackend, opts)
+	if err != nil {
+		return nil, err
+	}
+	if store != db {
+		slog.Info("log store opened", "backend", cfg.StorageBackend)
+	}
+	return store, nil
+}
@@ -0,0 +1,321 @@
+// Package archive exports log entries to an object storage bucket before
+// retention deletes them, and imports them back so archived days can be
+// queried again.
+//
+// Entries are stored as gzip-compressed NDJSON, one JSON object per
+// line in the form of the export API, grouped by the UTC day of their
+// timestamp:
+//
+//	<day>/<first>-<last>-<count>.ndjson.gz  entries sorted by timestamp
+//	state.json                             how far entries are archived
+//
+// day is "2006-01-02"; first and last are the zero-padded Unix
+// nanosecond timestamps of the object's first and last entry.
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/objstore"
+)
+
+const (
+	// maxObjectEntries caps the entries per object, bounding the memory
+	// used to build and read one.
+	maxObjectEntries = 100000
+
+	// pageSize is the number of entries read per store query.
+	pageSize = 1000
+
+	// stateKey holds the archive's state under the prefix.
+	stateKey = "state.json"
+
+	dayLayout = "2006-01-02"
+)
+
+// Archiver exports and imports the entries of a store.
+type Archiver struct {
+	bucket objstore.Bucket
+	prefix string
+}
+
+// New creates an Archiver keeping objects in bucket under prefix, e.g.
+// "kubelogs/archive/".
+func New(bucket objstore.Bucket, prefix string) *Archiver {
+	return &Archiver{bucket: bucket, prefix: prefix}
+}
+
+// state is the JSON content of the state object.
+type state struct {
+	// ArchivedBefore is the cutoff of the last successful Archive, in
+	// Unix nanoseconds.
+	ArchivedBefore int64 `json:"archivedBefore"`
+}
+
+// record is the serialized form of an entry.
+type record struct {
+	ID        int64             `json:"id"`
+	Timestamp int64             `json:"timestamp"` // Unix nanoseconds
+	Cluster   string            `json:"cluster,omitempty"`
+	Namespace string            `json:"namespace"`
+	Pod       string            `json:"pod"`
+	Container string            `json:"container"`
+	Severity  int               `json:"severity"`
+	Message   string            `json:"message"`
+	Attrs     map[string]string `json:"attrs,omitempty"`
+}
+
+// Archive exports the entries of store with timestamps before cutoff
+// that earlier calls haven't, and returns the number exported. Entries
+// written later with a timestamp before the previous cutoff are left
+// out. If it fails, the next call exports the same entries again.
+func (a *Archiver) Archive(ctx context.Context, store storage.Store, cutoff time.Time) (int64, error) {
+	st, err := a.loadState(ctx)
+	if err != nil {
+		return 0, err
+	}
+	q := storage.Query{
+		EndTime: cutoff,
+		Pagination: storage.Pagination{
+			Limit:   pageSize,
+			Order:   storage.OrderAsc,
+			OrderBy: storage.OrderByTimestamp,
+		},
+	}
+	if st.ArchivedBefore != 0 {
+		q.StartTime = time.Unix(0, st.ArchivedBefore)
+		if !q.StartTime.Before(cutoff) {
+			return 0, nil
+		}
+	}
+
+	var exported int64
+	var pending []storage.LogEntry
+	for {
+		result, err := store.Query(ctx, q)
+		if err != nil {
+			return exported, fmt.Errorf("query entries: %w", err)
+		}
+		for _, e := range result.Entries {
+			if len(pending) > 0 && (len(pending) == maxObjectEntries || day(e.Timestamp) != day(pending[0].Timestamp)) {
+				if err := a.put(ctx, pending); err != nil {
+					return exported, err
+				}
+				exported += int64(len(pending))
+				pending = pending[:0]
+			}
+			pending = append(pending, e)
+		}
+		if !result.HasMore || len(result.Entries) == 0 {
+			break
+		}
+		last := result.Entries[len(result.Entries)-1]
+		q.Pagination.AfterTimestamp = last.Timestamp
+		q.Pagination.AfterID = last.ID
+	}
+	if len(pending) > 0 {
+		if err := a.put(ctx, pending); err != nil {
+			return exported, err
+		}
+		exported += int64(len(pending))
+	}
+
+	if err := a.saveState(ctx, state{ArchivedBefore: cutoff.UnixNano()}); err != nil {
+		return exported, err
+	}
+	return exported, nil
+}
+
+// Object is an archived object, as named by its key.
+type Object struct {
+	Key         string
+	First, Last time.Time // Timestamps of its first and last entry
+	Entries     int
+}
+
+// Objects lists the archived objects holding entries with timestamps in
+// [start, end), in key order.
+func (a *Archiver) Objects(ctx context.Context, start, end time.Time) ([]Object, error) {
+	var objects []Object
+	for d := start.UTC().Truncate(24 * time.Hour); d.Before(end); d = d.Add(24 * time.Hour) {
+		keys, err := a.bucket.List(ctx, a.prefix+day(d)+"/")
+		if err != nil {
+			return nil, fmt.Errorf("list %s: %w", day(d), err)
+		}
+		for _, key := range keys {
+			o, ok := parseObjectKey(key)
+			if !ok || o.Last.Before(start) || !o.First.Before(end) {
+				continue
+			}
+			objects = append(objects, o)
+		}
+	}
+	return objects, nil
+}
+
+// parseObjectKey parses the key of an object, failing for other keys.
+func parseObjectKey(key string) (Object, bool) {
+	name, ok := strings.CutSuffix(key[strings.LastIndexByte(key, '/')+1:], ".ndjson.gz")
+	if !ok {
+		return Object{}, false
+	}
+	var first, last int64
+	var entries int
+	if _, err := fmt.Sscanf(name, "%d-%d-%d", &first, &last, &entries); err != nil {
+		return Object{}, false
+	}
+	return Object{Key: key, First: time.Unix(0, first), Last: time.Unix(0, last), Entries: entries}, true
+}
+
+// Restore writes the archived entries with timestamps in [start, end)
+// to store and returns the number written. The store assigns new IDs;
+// entries it still holds are skipped as duplicates.
+func (a *Archiver) Restore(ctx context.Context, store storage.Store, start, end time.Time) (int64, error) {
+	objects, err := a.Objects(ctx, start, end)
+	if err != nil {
+		return 0, err
+	}
+	var restored int64
+	for _, o := range objects {
+		n, err := a.restoreObject(ctx, store, o.Key, start, end)
+		restored += int64(n)
+		if err != nil {
+			return restored, err
+		}
+	}
+	if wo, ok := store.(storage.WriteOptimizer); ok {
+		if err := wo.Flush(ctx); err != nil {
+			return restored, err
+		}
+	}
+	return restored, nil
+}
+
+// restoreObject writes the entries of one object in [start, end) to
+// store.
+func (a *Archiver) restoreObject(ctx context.Context, store storage.Store, key string, start, end time.Time) (int, error) {
+	data, err := a.bucket.Get(ctx, key)
+	if err != nil {
+		return 0, fmt.Errorf("get %s: %w", key, err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("read %s: %w", key, err)
+	}
+	defer zr.Close()
+
+	var restored int
+	var batch storage.LogBatch
+	write := func() error {
+		n, err := store.Write(ctx, batch)
+		restored += n
+		batch = nil // Stores may keep the slice buffered
+		return err
+	}
+	dec := json.NewDecoder(bufio.NewReader(zr))
+	for {
+		var r record
+		if err := dec.Decode(&r); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return restored, fmt.Errorf("decode %s: %w", key, err)
+		}
+		ts := time.Unix(0, r.Timestamp)
+		if ts.Before(start) || !ts.Before(end) {
+			continue
+		}
+		batch = append(batch, storage.LogEntry{
+			Timestamp:  ts,
+			Cluster:    r.Cluster,
+			Namespace:  r.Namespace,
+			Pod:        r.Pod,
+			Container:  r.Container,
+			Severity:   storage.Severity(r.Severity),
+			Message:    r.Message,
+			Attributes: r.Attrs,
+		})
+		if len(batch) == pageSize {
+			if err := write(); err != nil {
+				return restored, fmt.Errorf("write entries: %w", err)
+			}
+		}
+	}
+	if len(batch) > 0 {
+		if err := write(); err != nil {
+			return restored, fmt.Errorf("write entries: %w", err)
+		}
+	}
+	return restored, nil
+}
+
+// put uploads entries of one day, sorted by timestamp, as one object.
+func (a *Archiver) put(ctx context.Context, entries []storage.LogEntry) error {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(zw)
+	for _, e := range entries {
+		err := enc.Encode(record{
+			ID:        e.ID,
+			Timestamp: e.Timestamp.UnixNano(),
+			Cluster:   e.Cluster,
+			Namespace: e.Namespace,
+			Pod:       e.Pod,
+			Container: e.Container,
+			Severity:  int(e.Severity),
+			Message:   e.Message,
+			Attrs:     e.Attributes,
+		})
+		if err != nil {
+			return fmt.Errorf("encode entry: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("compress entries: %w", err)
+	}
+
+	first, last := entries[0].Timestamp, entries[len(entries)-1].Timestamp
+	key := fmt.Sprintf("%s%s/%020d-%020d-%d.ndjson.gz", a.prefix, day(first), first.UnixNano(), last.UnixNano(), len(entries))
+	if err := a.bucket.Put(ctx, key, buf.Bytes()); err != nil {
+		return fmt.Errorf("put %s: %w", key, err)
+	}
+	return nil
+}
+
+func (a *Archiver) loadState(ctx context.Context) (state, error) {
+	var st state
+	data, err := a.bucket.Get(ctx, a.prefix+stateKey)
+	if errors.Is(err, objstore.ErrObjectNotFound) {
+		return st, nil
+	}
+	if err != nil {
+		return st, fmt.Errorf("get archive state: %w", err)
+	}
+	if err := json.Unmarshal(data, &st); err != nil {
+		return st, fmt.Errorf("decode archive state: %w", err)
+	}
+	return st, nil
+}
+
+func (a *Archiver) saveState(ctx context.Context, st state) error {
+	data, _ := json.Marshal(st)
+	if err := a.bucket.Put(ctx, a.prefix+stateKey, data); err != nil {
+		return fmt.Errorf("put archive state: %w", err)
+	}
+	return nil
+}
+
+// day returns the UTC day of t, as used in object keys.
+func day(t time.Time) string {
+	return t.UTC().Format(dayLayout)
+}
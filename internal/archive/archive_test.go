@@ -0,0 +1,147 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/objstore"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+// memBucket is an in-memory objstore.Bucket for tests.
+type memBucket struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newMemBucket() *memBucket {
+	return &memBucket{objects: make(map[string][]byte)}
+}
+
+func (b *memBucket) Put(ctx context.Context, key string, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.objects[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (b *memBucket) Get(ctx context.Context, key string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, ok := b.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", key, objstore.ErrObjectNotFound)
+	}
+	return data, nil
+}
+
+func (b *memBucket) Delete(ctx context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.objects, key)
+	return nil
+}
+
+func (b *memBucket) List(ctx context.Context, prefix string) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var keys []string
+	for k := range b.objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+func newStore(t *testing.T) *sqlite.Store {
+	t.Helper()
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:", WriteBufferSize: 1})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestArchiveRestore(t *testing.T) {
+	ctx := context.Background()
+	src := newStore(t)
+	day1 := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	day3 := day2.Add(24 * time.Hour)
+	batch := storage.LogBatch{
+		{Timestamp: day1.Add(time.Hour), Cluster: "prod", Namespace: "app", Pod: "p", Container: "c", Severity: storage.SeverityInfo, Message: "first", Attributes: map[string]string{"k": "v"}},
+		{Timestamp: day1.Add(2 * time.Hour), Namespace: "app", Pod: "p", Container: "c", Severity: storage.SeverityError, Message: "second"},
+		{Timestamp: day2.Add(time.Hour), Namespace: "app", Pod: "p", Container: "c", Message: "third"},
+		{Timestamp: day3.Add(time.Hour), Namespace: "app", Pod: "p", Container: "c", Message: "not yet"},
+	}
+	if _, err := src.Write(ctx, batch); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	bucket := newMemBucket()
+	a := New(bucket, "archive/")
+	n, err := a.Archive(ctx, src, day3)
+	if err != nil || n != 3 {
+		t.Fatalf("Archive = %d, %v, want 3", n, err)
+	}
+	keys, _ := bucket.List(ctx, "archive/")
+	if len(keys) != 3 || !strings.HasPrefix(keys[0], "archive/2024-03-01/") ||
+		!strings.HasPrefix(keys[1], "archive/2024-03-02/") || keys[2] != "archive/state.json" {
+		t.Fatalf("keys = %v", keys)
+	}
+
+	// Entries before the previous cutoff aren't exported again
+	n, err = a.Archive(ctx, src, day3.Add(2*time.Hour))
+	if err != nil || n != 1 {
+		t.Fatalf("second Archive = %d, %v, want 1", n, err)
+	}
+	n, err = a.Archive(ctx, src, day3.Add(2*time.Hour))
+	if err != nil || n != 0 {
+		t.Fatalf("repeated Archive = %d, %v, want 0", n, err)
+	}
+
+	objects, err := a.Objects(ctx, day1, day2)
+	if err != nil || len(objects) != 1 || objects[0].Entries != 2 ||
+		!objects[0].First.Equal(batch[0].Timestamp) || !objects[0].Last.Equal(batch[1].Timestamp) {
+		t.Fatalf("Objects = %+v, %v, want the object of day 1", objects, err)
+	}
+
+	dst := newStore(t)
+	n, err = a.Restore(ctx, dst, day1, day2)
+	if err != nil || n != 2 {
+		t.Fatalf("Restore = %d, %v, want 2", n, err)
+	}
+	result, err := dst.Query(ctx, storage.Query{Pagination: storage.Pagination{Order: storage.OrderAsc, OrderBy: storage.OrderByTimestamp}})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(result.Entries) != 2 {
+		t.Fatalf("restored %d entries, want 2", len(result.Entries))
+	}
+	got := result.Entries[0]
+	if !got.Timestamp.Equal(batch[0].Timestamp) || got.Cluster != "prod" || got.Message != "first" ||
+		got.Severity != storage.SeverityInfo || got.Attributes["k"] != "v" {
+		t.Errorf("restored entry = %+v", got)
+	}
+	if result.Entries[1].Severity != storage.SeverityError {
+		t.Errorf("second entry severity = %v", result.Entries[1].Severity)
+	}
+
+	// Restoring again skips the entries the store already holds
+	if _, err := a.Restore(ctx, dst, day1, day3); err != nil {
+		t.Fatalf("second Restore: %v", err)
+	}
+	result, err = dst.Query(ctx, storage.Query{})
+	if err != nil || len(result.Entries) != 3 {
+		t.Fatalf("after second Restore: %d entries, %v, want 3", len(result.Entries), err)
+	}
+}
@@ -0,0 +1,81 @@
+// Package audit records the destructive admin operations confirmed on
+// the server: what they did, to which entries, who asked for them and
+// who approved them.
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Operations recorded.
+const (
+	OpPurge     = "purge"     // Entries deleted by query
+	OpDelete    = "delete"    // Entries deleted before a cutoff
+	OpRetention = "retention" // A shorter retention policy applied
+	OpRestore   = "restore"   // Archived entries written back
+)
+
+// DefaultLimit bounds the records List returns when not told otherwise.
+const DefaultLimit = 100
+
+// Record is one confirmed operation.
+type Record struct {
+	ID        int64
+	Time      time.Time
+	Operation string
+	Filter    string // JSON object selecting the entries, or the policy applied
+	Requester string // Who previewed the operation
+	Approver  string // Who confirmed it; the requester unless a second approver is required
+	Count     int64  // Entries deleted or restored; for retention, the entries previewed
+}
+
+// Store manages audit record persistence. Records are only added.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a Store with the given database connection.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Add records r, at the current time if r.Time is zero.
+func (s *Store) Add(ctx context.Context, r Record) error {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO audit_log (time, operation, filter, requester, approver, count) VALUES (?, ?, ?, ?, ?, ?)`,
+		r.Time.UnixNano(), r.Operation, r.Filter, r.Requester, r.Approver, r.Count,
+	)
+	return err
+}
+
+// List returns up to limit records, newest first.
+func (s *Store) List(ctx context.Context, limit int) ([]Record, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, time, operation, filter, requester, approver, count FROM audit_log ORDER BY id DESC LIMIT ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		var r Record
+		var t int64
+		if err := rows.Scan(&r.ID, &t, &r.Operation, &r.Filter, &r.Requester, &r.Approver, &r.Count); err != nil {
+			return nil, err
+		}
+		r.Time = time.Unix(0, t)
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// ErrNoCredentials is returned by an Authenticator for requests that
+// carry no credentials it handles, so the next one can be tried.
+var ErrNoCredentials = errors.New("auth: no credentials")
+
+// Authenticator identifies the user making a request, by a session
+// cookie, a header set by a trusted proxy or the like. The server picks
+// backends by name (KUBELOGS_AUTH_METHODS) and combines them with Chain.
+type Authenticator interface {
+	// Authenticate returns the user r is made by, ErrNoCredentials if r
+	// carries none for this backend, or another error if they're invalid.
+	Authenticate(r *http.Request) (*User, error)
+}
+
+// Chain tries each of its authenticators in turn and returns the first
+// user found. If none finds one, it returns the first error other than
+// ErrNoCredentials, or ErrNoCredentials.
+type Chain []Authenticator
+
+// Authenticate implements Authenticator.
+func (c Chain) Authenticate(r *http.Request) (*User, error) {
+	err := ErrNoCredentials
+	for _, a := range c {
+		user, aerr := a.Authenticate(r)
+		if aerr == nil {
+			return user, nil
+		}
+		if errors.Is(err, ErrNoCredentials) {
+			err = aerr
+		}
+	}
+	return nil, err
+}
+
+// HeaderAuthenticator authenticates users by a header that an
+// authenticating reverse proxy, such as oauth2-proxy or Pomerium, sets to
+// the username. Users are created on first sight.
+type HeaderAuthenticator struct {
+	users   *UserStore
+	headers []string
+	trusted func(r *http.Request) bool
+}
+
+// NewHeaderAuthenticator creates a HeaderAuthenticator reading the first
+// of headers that is set. The headers are only believed for requests
+// that trusted reports came from the proxy, since anyone else could set
+// them too.
+func NewHeaderAuthenticator(users *UserStore, headers []string, trusted func(r *http.Request) bool) *HeaderAuthenticator {
+	return &HeaderAuthenticator{users: users, headers: headers, trusted: trusted}
+}
+
+// Authenticate implements Authenticator.
+func (a *HeaderAuthenticator) Authenticate(r *http.Request) (*User, error) {
+	username := ""
+	for _, h := range a.headers {
+		if username = strings.TrimSpace(r.Header.Get(h)); username != "" {
+			break
+		}
+	}
+	if username == "" {
+		return nil, ErrNoCredentials
+	}
+	if !a.trusted(r) {
+		slog.Warn("ignoring user header from untrusted address",
+			"username", username, "remote", r.RemoteAddr)
+		return nil, ErrNoCredentials
+	}
+	return a.users.EnsureUser(r.Context(), username)
+}
+
+// SessionAuthenticator authenticates local users, who log in with a
+// password stored in UserStore, by their session cookie.
+type SessionAuthenticator struct {
+	users      *UserStore
+	sessions   *SessionStore
+	cookieName string
+}
+
+// NewSessionAuthenticator creates a SessionAuthenticator reading the
+// session ID from the cookie named cookieName.
+func NewSessionAuthenticator(users *UserStore, sessions *SessionStore, cookieName string) *SessionAuthenticator {
+	return &SessionAuthenticator{users: users, sessions: sessions, cookieName: cookieName}
+}
+
+// Authenticate implements Authenticator.
+func (a *SessionAuthenticator) Authenticate(r *http.Request) (*User, error) {
+	cookie, err := r.Cookie(a.cookieName)
+	if err != nil {
+		return nil, ErrNoCredentials
+	}
+	session, err := a.sessions.Get(r.Context(), cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+	return a.users.GetByID(r.Context(), session.UserID)
+}
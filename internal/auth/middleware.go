@@ -6,17 +6,19 @@ import (
 
 // Middleware provides authentication middleware.
 type Middleware struct {
-	userStore    *UserStore
-	sessionStore *SessionStore
+	authn        Authenticator
+	loginPath    string
 	cookieName   string
 	cookieSecure bool
 }
 
-// NewMiddleware creates auth middleware.
-func NewMiddleware(users *UserStore, sessions *SessionStore, cookieName string, secure bool) *Middleware {
+// NewMiddleware creates auth middleware identifying users with authn.
+// Pages redirect unauthenticated requests to loginPath, or answer 401 if
+// it's empty. cookieName and secure configure the session cookie.
+func NewMiddleware(authn Authenticator, loginPath, cookieName string, secure bool) *Middleware {
 	return &Middleware{
-		userStore:    users,
-		sessionStore: sessions,
+		authn:        authn,
+		loginPath:    loginPath,
 		cookieName:   cookieName,
 		cookieSecure: secure,
 	}
@@ -27,27 +29,25 @@ func (m *Middleware) CookieName() string {
 	return m.cookieName
 }
 
+// Authenticate returns the user r is made by, as Authenticator does.
+func (m *Middleware) Authenticate(r *http.Request) (*User, error) {
+	return m.authn.Authenticate(r)
+}
+
 // RequireAuth wraps a handler to require authentication.
-// Redirects unauthenticated requests to /login.
+// Redirects unauthenticated requests to the login page.
 func (m *Middleware) RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie(m.cookieName)
-		if err != nil {
-			http.Redirect(w, r, "/login", http.StatusSeeOther)
-			return
-		}
-
-		session, err := m.sessionStore.Get(r.Context(), cookie.Value)
-		if err != nil {
-			m.clearCookie(w)
-			http.Redirect(w, r, "/login", http.StatusSeeOther)
-			return
-		}
-
-		user, err := m.userStore.GetByID(r.Context(), session.UserID)
+		user, err := m.authn.Authenticate(r)
 		if err != nil {
-			m.clearCookie(w)
-			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			if _, cerr := r.Cookie(m.cookieName); cerr == nil {
+				m.clearCookie(w)
+			}
+			if m.loginPath == "" {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			http.Redirect(w, r, m.loginPath, http.StatusSeeOther)
 			return
 		}
 
@@ -60,19 +60,7 @@ func (m *Middleware) RequireAuth(next http.Handler) http.Handler {
 // Returns 401 Unauthorized instead of redirecting.
 func (m *Middleware) RequireAuthAPI(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie(m.cookieName)
-		if err != nil {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-
-		session, err := m.sessionStore.Get(r.Context(), cookie.Value)
-		if err != nil {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-
-		user, err := m.userStore.GetByID(r.Context(), session.UserID)
+		user, err := m.authn.Authenticate(r)
 		if err != nil {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
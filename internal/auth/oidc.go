@@ -0,0 +1,476 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// oidcFlowTTL is how long a login started at the identity provider
+	// can take.
+	oidcFlowTTL = 10 * time.Minute
+
+	// oidcClockSkew is how far the provider's clock may be ahead of ours
+	// or an expired ID token still be accepted.
+	oidcClockSkew = time.Minute
+
+	// oidcKeyRefresh is the least time between fetches of the provider's
+	// keys for an unknown key ID.
+	oidcKeyRefresh = time.Minute
+)
+
+// OIDCConfig configures logging in with an OpenID Connect provider.
+type OIDCConfig struct {
+	Issuer        string   // Provider URL, e.g. https://accounts.example.com
+	ClientID      string   // This server's client at the provider
+	ClientSecret  string   // "" for public clients, which rely on PKCE
+	RedirectURL   string   // Where the provider sends users back: <server URL>/auth/oidc/callback
+	Scopes        []string // Must include "openid"
+	UsernameClaim string   // ID token claim naming the user, e.g. "email"
+}
+
+// OIDCLogin logs users in with an OpenID Connect provider, with the
+// authorization code flow and PKCE. Users are created on first login,
+// like those of HeaderAuthenticator, and then carry a session like
+// local users. The provider is discovered on first use.
+type OIDCLogin struct {
+	cfg        OIDCConfig
+	users      *UserStore
+	client     *http.Client
+	cookieName string
+	secure     bool
+
+	mu          sync.Mutex
+	provider    *oidcProvider // nil until discovered
+	keys        map[string]crypto.PublicKey
+	keysFetched time.Time
+}
+
+// oidcProvider holds the provider metadata the login uses.
+type oidcProvider struct {
+	Issuer                   string   `json:"issuer"`
+	AuthorizationEndpoint    string   `json:"authorization_endpoint"`
+	TokenEndpoint            string   `json:"token_endpoint"`
+	JWKSURI                  string   `json:"jwks_uri"`
+	TokenEndpointAuthMethods []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// NewOIDCLogin creates an OIDCLogin for cfg, keeping the state of logins
+// in progress in a cookie named cookieName (secure for HTTPS only).
+// client makes the requests to the provider.
+func NewOIDCLogin(cfg OIDCConfig, users *UserStore, client *http.Client, cookieName string, secure bool) *OIDCLogin {
+	return &OIDCLogin{cfg: cfg, users: users, client: client, cookieName: cookieName, secure: secure}
+}
+
+// Start redirects the browser to the provider to log in.
+func (o *OIDCLogin) Start(w http.ResponseWriter, r *http.Request) error {
+	p, err := o.discover(r.Context())
+	if err != nil {
+		return err
+	}
+	state, nonce, verifier := randomToken(), randomToken(), randomToken()
+	challenge := sha256.Sum256([]byte(verifier))
+
+	authURL, err := url.Parse(p.AuthorizationEndpoint)
+	if err != nil {
+		return fmt.Errorf("oidc: authorization endpoint: %w", err)
+	}
+	q := authURL.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", o.cfg.ClientID)
+	q.Set("redirect_uri", o.cfg.RedirectURL)
+	q.Set("scope", strings.Join(o.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	authURL.RawQuery = q.Encode()
+
+	o.setFlowCookie(w, state+"."+nonce+"."+verifier, int(oidcFlowTTL.Seconds()))
+	http.Redirect(w, r, authURL.String(), http.StatusSeeOther)
+	return nil
+}
+
+// Finish completes a login on the redirect back from the provider and
+// returns the user logged in.
+func (o *OIDCLogin) Finish(w http.ResponseWriter, r *http.Request) (*User, error) {
+	cookie, err := r.Cookie(o.cookieName)
+	if err != nil {
+		return nil, errors.New("oidc: no login in progress")
+	}
+	o.setFlowCookie(w, "", -1)
+	state, rest, _ := strings.Cut(cookie.Value, ".")
+	nonce, verifier, _ := strings.Cut(rest, ".")
+
+	params := r.URL.Query()
+	if e := params.Get("error"); e != "" {
+		return nil, fmt.Errorf("oidc: provider refused login: %s %s", e, params.Get("error_description"))
+	}
+	if state == "" || subtle.ConstantTimeCompare([]byte(params.Get("state")), []byte(state)) != 1 {
+		return nil, errors.New("oidc: state mismatch")
+	}
+	code := params.Get("code")
+	if code == "" {
+		return nil, errors.New("oidc: no authorization code")
+	}
+
+	p, err := o.discover(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	rawIDToken, err := o.exchange(r.Context(), p, code, verifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := o.verify(r.Context(), p, rawIDToken, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := claims["nonce"].(string); subtle.ConstantTimeCompare([]byte(n), []byte(nonce)) != 1 {
+		return nil, errors.New("oidc: nonce mismatch")
+	}
+	username, err := o.username(claims)
+	if err != nil {
+		return nil, err
+	}
+	return o.users.EnsureUser(r.Context(), username)
+}
+
+// username returns the user an ID token's claims name.
+func (o *OIDCLogin) username(claims map[string]any) (string, error) {
+	username, _ := claims[o.cfg.UsernameClaim].(string)
+	if username = strings.TrimSpace(username); username == "" {
+		return "", fmt.Errorf("oidc: ID token has no %q claim", o.cfg.UsernameClaim)
+	}
+	// Addresses users can set themselves aren't theirs until verified,
+	// and providers that don't say whether they are might not check
+	if o.cfg.UsernameClaim == "email" && !emailVerified(claims) {
+		return "", fmt.Errorf("oidc: email %q is not verified", username)
+	}
+	return username, nil
+}
+
+// emailVerified reports whether claims assert that the email claim was
+// verified. Some providers send the flag as the string "true".
+func emailVerified(claims map[string]any) bool {
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+func (o *OIDCLogin) setFlowCookie(w http.ResponseWriter, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     o.cookieName,
+		Value:    value,
+		Path:     "/auth/oidc/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   o.secure,
+		SameSite: http.SameSiteLaxMode, // Sent on the provider's redirect back
+	})
+}
+
+// discover fetches the provider's metadata, once it succeeds.
+func (o *OIDCLogin) discover(ctx context.Context) (*oidcProvider, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.provider != nil {
+		return o.provider, nil
+	}
+
+	var p oidcProvider
+	wellKnown := strings.TrimSuffix(o.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := o.getJSON(ctx, wellKnown, &p); err != nil {
+		return nil, fmt.Errorf("oidc: discovery: %w", err)
+	}
+	if p.Issuer != o.cfg.Issuer {
+		return nil, fmt.Errorf("oidc: discovery: issuer is %q, not %q", p.Issuer, o.cfg.Issuer)
+	}
+	if p.AuthorizationEndpoint == "" || p.TokenEndpoint == "" || p.JWKSURI == "" {
+		return nil, errors.New("oidc: discovery: endpoints missing")
+	}
+	o.provider = &p
+	return o.provider, nil
+}
+
+// exchange trades an authorization code for an ID token.
+func (o *OIDCLogin) exchange(ctx context.Context, p *oidcProvider, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.cfg.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	// client_secret_basic is the default; some providers only take the
+	// secret in the form
+	postSecret := o.cfg.ClientSecret == "" ||
+		slices.Contains(p.TokenEndpointAuthMethods, "client_secret_post") && !slices.Contains(p.TokenEndpointAuthMethods, "client_secret_basic")
+	if postSecret {
+		form.Set("client_id", o.cfg.ClientID)
+		if o.cfg.ClientSecret != "" {
+			form.Set("client_secret", o.cfg.ClientSecret)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if !postSecret {
+		req.SetBasicAuth(url.QueryEscape(o.cfg.ClientID), url.QueryEscape(o.cfg.ClientSecret))
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("oidc: token request: %w", err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("oidc: token response (%s): %w", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		return "", fmt.Errorf("oidc: token request: %s: %s %s", resp.Status, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", errors.New("oidc: token response has no ID token")
+	}
+	return body.IDToken, nil
+}
+
+// verify checks an ID token's signature, issuer, audience and expiry and
+// returns its claims.
+func (o *OIDCLogin) verify(ctx context.Context, p *oidcProvider, token string, now time.Time) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("oidc: malformed ID token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("oidc: ID token header: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("oidc: ID token signature: %w", err)
+	}
+	key, err := o.key(ctx, p, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("oidc: ID token claims: %w", err)
+	}
+	if iss, _ := claims["iss"].(string); iss != p.Issuer {
+		return nil, fmt.Errorf("oidc: ID token issued by %q, not %q", iss, p.Issuer)
+	}
+	var aud []string
+	switch v := claims["aud"].(type) {
+	case string:
+		aud = []string{v}
+	case []any:
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				aud = append(aud, s)
+			}
+		}
+	}
+	if !slices.Contains(aud, o.cfg.ClientID) {
+		return nil, errors.New("oidc: ID token is for another client")
+	}
+	if azp, ok := claims["azp"].(string); ok && azp != o.cfg.ClientID {
+		return nil, errors.New("oidc: ID token is for another client")
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(exp), 0).Add(oidcClockSkew)) {
+		return nil, errors.New("oidc: ID token expired")
+	}
+	return claims, nil
+}
+
+// key returns the provider's signing key kid, fetching the key set again
+// if it isn't known, at most once a minute.
+func (o *OIDCLogin) key(ctx context.Context, p *oidcProvider, kid string) (crypto.PublicKey, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if key := o.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	if time.Since(o.keysFetched) < oidcKeyRefresh {
+		return nil, fmt.Errorf("oidc: unknown signing key %q", kid)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	o.keysFetched = time.Now()
+	if err := o.getJSON(ctx, p.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("oidc: fetch signing keys: %w", err)
+	}
+	o.keys = make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			o.keys[k.Kid] = key
+		}
+	}
+	if key := o.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("oidc: unknown signing key %q", kid)
+}
+
+// lookupKey returns the known key kid, or the only one if the token
+// names none.
+func (o *OIDCLogin) lookupKey(kid string) crypto.PublicKey {
+	if kid == "" && len(o.keys) == 1 {
+		for _, key := range o.keys {
+			return key
+		}
+	}
+	return o.keys[kid]
+}
+
+func (o *OIDCLogin) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// jwk is a JSON Web Key of a provider's key set.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey returns the RSA or P-256 key k holds.
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("EC point not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// verifySignature checks the RS256 or ES256 signature sig of signed.
+// Other algorithms, "none" in particular, are refused.
+func verifySignature(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+	switch alg {
+	case "RS256":
+		if k, ok := key.(*rsa.PublicKey); ok && rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil {
+			return nil
+		}
+	case "ES256":
+		if k, ok := key.(*ecdsa.PublicKey); ok && len(sig) == 64 {
+			r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+			if ecdsa.Verify(k, digest[:], r, s) {
+				return nil
+			}
+		}
+	default:
+		return fmt.Errorf("oidc: unsupported ID token algorithm %q", alg)
+	}
+	return errors.New("oidc: invalid ID token signature")
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// randomToken returns 32 random bytes, base64url-encoded.
+func randomToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
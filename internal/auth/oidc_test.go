@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOIDCLogin_Username(t *testing.T) {
+	tests := []struct {
+		name   string
+		claim  string
+		claims map[string]any
+		want   string // "" for an error
+	}{
+		{"verified email", "email", map[string]any{"email": "alice@example.com", "email_verified": true}, "alice@example.com"},
+		{"verified as string", "email", map[string]any{"email": "alice@example.com", "email_verified": "true"}, "alice@example.com"},
+		{"unverified email", "email", map[string]any{"email": "alice@example.com", "email_verified": false}, ""},
+		{"email without verification", "email", map[string]any{"email": "root@example.com"}, ""},
+		{"missing claim", "email", map[string]any{"sub": "1234", "email_verified": true}, ""},
+		{"blank claim", "preferred_username", map[string]any{"preferred_username": "  "}, ""},
+		{"other claim", "preferred_username", map[string]any{"preferred_username": "alice"}, "alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &OIDCLogin{cfg: OIDCConfig{UsernameClaim: tt.claim}}
+			got, err := o.username(tt.claims)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("username = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("username = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOIDCLogin_Verify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	b64 := base64.RawURLEncoding.EncodeToString
+	var fetches int
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		json.NewEncoder(w).Encode(map[string]any{"keys": []jwk{
+			{Kty: "RSA", Kid: "rsa", N: b64(rsaKey.N.Bytes()), E: b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{Kty: "EC", Kid: "ec", Crv: "P-256", X: b64(ecKey.X.FillBytes(make([]byte, 32))), Y: b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+			{Kty: "RSA", Kid: "enc", Use: "enc", N: b64(rsaKey.N.Bytes()), E: b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		}})
+	}))
+	defer jwks.Close()
+
+	p := &oidcProvider{Issuer: "https://idp.example.com", JWKSURI: jwks.URL}
+	o := &OIDCLogin{cfg: OIDCConfig{ClientID: "kubelogs"}, client: jwks.Client()}
+	now := time.Now()
+
+	sign := func(alg, kid string, claims map[string]any) string {
+		enc := func(v any) string {
+			b, _ := json.Marshal(v)
+			return b64(b)
+		}
+		signed := enc(map[string]string{"alg": alg, "kid": kid}) + "." + enc(claims)
+		digest := sha256.Sum256([]byte(signed))
+		var sig []byte
+		switch alg {
+		case "RS256":
+			sig, _ = rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+		case "ES256":
+			r, s, _ := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+			sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+		return signed + "." + b64(sig)
+	}
+	valid := func() map[string]any {
+		return map[string]any{"iss": p.Issuer, "aud": "kubelogs", "exp": now.Add(time.Hour).Unix()}
+	}
+	with := func(k string, v any) map[string]any {
+		c := valid()
+		if v == nil {
+			delete(c, k)
+		} else {
+			c[k] = v
+		}
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"RS256", sign("RS256", "rsa", valid()), true},
+		{"ES256", sign("ES256", "ec", valid()), true},
+		{"audience list", sign("RS256", "rsa", with("aud", []string{"other", "kubelogs"})), true},
+		{"within clock skew", sign("RS256", "rsa", with("exp", now.Add(-oidcClockSkew/2).Unix())), true},
+		{"expired", sign("RS256", "rsa", with("exp", now.Add(-2*oidcClockSkew).Unix())), false},
+		{"no expiry", sign("RS256", "rsa", with("exp", nil)), false},
+		{"other issuer", sign("RS256", "rsa", with("iss", "https://evil.example.com")), false},
+		{"other audience", sign("RS256", "rsa", with("aud", "other")), false},
+		{"other authorized party", sign("RS256", "rsa", with("azp", "other")), false},
+		{"key of another type", sign("RS256", "ec", valid()), false},
+		{"encryption key", sign("RS256", "enc", valid()), false},
+		{"unknown key", sign("RS256", "gone", valid()), false},
+		{"unsigned", sign("none", "rsa", valid()), false},
+		{"tampered", sign("RS256", "rsa", valid())[:40] + "x" + sign("RS256", "rsa", valid())[41:], false},
+		{"malformed", "not-a-token", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := o.verify(context.Background(), p, tt.token, now)
+			if tt.ok && err != nil {
+				t.Errorf("verify: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Error("verify succeeded, want an error")
+			}
+		})
+	}
+
+	// Unknown key IDs don't make every token refetch the key set
+	if fetches != 1 {
+		t.Errorf("fetched keys %d times, want 1", fetches)
+	}
+}
@@ -97,6 +97,35 @@ func (s *UserStore) GetByID(ctx context.Context, id int64) (*User, error) {
 	return &user, nil
 }
 
+// EnsureUser returns the user named username, creating it if there is
+// none. Users created this way have no password, so they can't log in
+// on /login; they are identified by a trusted proxy instead.
+func (s *UserStore) EnsureUser(ctx context.Context, username string) (*User, error) {
+	nowNano := time.Now().UnixNano()
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO users (username, password, created_at, updated_at) VALUES (?, '', ?, ?)
+		 ON CONFLICT (username) DO NOTHING`,
+		username, nowNano, nowNano,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var user User
+	var createdAt, updatedAt int64
+	err = s.db.QueryRowContext(ctx,
+		`SELECT id, username, created_at, updated_at FROM users WHERE username = ?`,
+		username,
+	).Scan(&user.ID, &user.Username, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	user.CreatedAt = time.Unix(0, createdAt)
+	user.UpdatedAt = time.Unix(0, updatedAt)
+	return &user, nil
+}
+
 // HasUsers returns true if any users exist.
 func (s *UserStore) HasUsers(ctx context.Context) (bool, error) {
 	var count int
@@ -0,0 +1,113 @@
+// Package bookmark stores per-user bookmarks and notes on log entries.
+package bookmark
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when a bookmark doesn't exist.
+var ErrNotFound = errors.New("bookmark: not found")
+
+// MaxNoteLength bounds the size of a bookmark note in bytes.
+const MaxNoteLength = 4096
+
+// Bookmark marks a log entry for a user, with an optional note.
+type Bookmark struct {
+	EntryID   int64
+	UserID    int64
+	Note      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Store manages bookmark persistence.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a Store with the given database connection.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Put creates a bookmark or replaces the note on an existing one.
+func (s *Store) Put(ctx context.Context, userID, entryID int64, note string) (*Bookmark, error) {
+	now := time.Now().UnixNano()
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO bookmarks (entry_id, user_id, note, created_at, updated_at) VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT (entry_id, user_id) DO UPDATE SET note = excluded.note, updated_at = excluded.updated_at`,
+		entryID, userID, note, now, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(ctx, userID, entryID)
+}
+
+// Get retrieves a single bookmark.
+func (s *Store) Get(ctx context.Context, userID, entryID int64) (*Bookmark, error) {
+	b := Bookmark{EntryID: entryID, UserID: userID}
+	var createdAt, updatedAt int64
+
+	err := s.db.QueryRowContext(ctx,
+		`SELECT note, created_at, updated_at FROM bookmarks WHERE entry_id = ? AND user_id = ?`,
+		entryID, userID,
+	).Scan(&b.Note, &createdAt, &updatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b.CreatedAt = time.Unix(0, createdAt)
+	b.UpdatedAt = time.Unix(0, updatedAt)
+	return &b, nil
+}
+
+// Delete removes a bookmark.
+func (s *Store) Delete(ctx context.Context, userID, entryID int64) error {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM bookmarks WHERE entry_id = ? AND user_id = ?`,
+		entryID, userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// List returns all bookmarks for a user, oldest first.
+func (s *Store) List(ctx context.Context, userID int64) ([]Bookmark, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT entry_id, note, created_at, updated_at FROM bookmarks WHERE user_id = ? ORDER BY created_at, entry_id`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bookmarks []Bookmark
+	for rows.Next() {
+		b := Bookmark{UserID: userID}
+		var createdAt, updatedAt int64
+		if err := rows.Scan(&b.EntryID, &b.Note, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		b.CreatedAt = time.Unix(0, createdAt)
+		b.UpdatedAt = time.Unix(0, updatedAt)
+		bookmarks = append(bookmarks, b)
+	}
+	return bookmarks, rows.Err()
+}
@@ -3,6 +3,7 @@ package collector
 import (
 	"context"
 	"log/slog"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -15,6 +16,23 @@ type Batcher struct {
 	store         storage.Store
 	batchSize     int
 	flushInterval time.Duration
+	cluster       string // Set on every entry
+
+	// podAttributes, if set, returns attributes added to every entry of
+	// the pod with the given UID
+	podAttributes func(podUID string) map[string]string
+
+	// onDrop, if set, is told of each batch dropped from the full retry
+	// queue
+	onDrop func(batch storage.LogBatch)
+
+	// severityRules, if set, may change the severity of entries
+	severityRules *SeverityRules
+
+	// While catchingUp reports true, batches are catchUpFactor times
+	// larger and written oldest entry first
+	catchUpFactor int
+	catchingUp    func() bool
 
 	input <-chan LogLine
 
@@ -22,21 +40,44 @@ type Batcher struct {
 	buffer    storage.LogBatch
 	lastFlush time.Time
 
+	// While the store asks writers to slow down, batches are slowdown
+	// times larger and flushed slowdown times less often, and not before
+	// holdUntil unless the buffer reaches its limit. Guarded by mu.
+	slowdown  int
+	holdUntil time.Time
+
 	// Retry queue for failed batches
 	retryMu    sync.Mutex
 	retryQueue []storage.LogBatch
 	backoff    time.Duration
+	retrying   bool // retryQueue[0] is being written
+
+	// spool, if set, keeps failed batches on disk instead of retryQueue
+	spool *Spool
 
 	// Circuit breaker
 	consecutiveFailures int
 	circuitOpen         bool
 	circuitOpenUntil    time.Time
 
+	// Retry policy, guarded by retryMu
+	minBackoff       time.Duration
+	maxBackoff       time.Duration
+	maxRetryQueue    int // Maximum number of batches to queue for retry
+	circuitThreshold int // Consecutive failures before opening circuit
+	circuitTimeout   time.Duration
+	dropPolicy       DropPolicy
+
 	// Metrics
-	totalWrites  atomic.Int64
-	totalEntries atomic.Int64
-	writeErrors  atomic.Int64
+	totalWrites    atomic.Int64
+	totalEntries   atomic.Int64
+	writeErrors    atomic.Int64
 	retriedBatches atomic.Int64
+	droppedEntries atomic.Int64
+
+	// Entries written per severity, as the store accepted them if it
+	// reports that
+	writtenSeverities [storage.SeverityFatal + 1]atomic.Int64
 }
 
 // BatcherStats contains batcher statistics.
@@ -47,15 +88,48 @@ type BatcherStats struct {
 	BufferSize     int
 	RetryQueueSize int
 	RetriedBatches int64
+	DroppedEntries int64 // In batches dropped from the full retry queue
 	CircuitOpen    bool
+	Slowdown       int // Factor batch sizes and intervals are scaled by
+	Spool          SpoolStats
+
+	// WrittenSeverities counts the entries written per severity, leaving
+	// out severities without any
+	WrittenSeverities map[storage.Severity]int64
 }
 
+// DropPolicy chooses the batch dropped when the retry queue is full.
+type DropPolicy string
+
 const (
-	minBackoff       = time.Second
-	maxBackoff       = 30 * time.Second
-	maxRetryQueue    = 100 // Maximum number of batches to queue for retry
-	circuitThreshold = 5   // Consecutive failures before opening circuit
-	circuitTimeout   = 30 * time.Second
+	// DropOldest drops the batch queued first.
+	DropOldest DropPolicy = "oldest"
+
+	// DropNewest drops the batch that didn't fit, keeping the queue.
+	DropNewest DropPolicy = "newest"
+
+	// DropLowestSeverity drops the batch whose most severe entry is the
+	// least severe, the oldest of those if several tie, so batches with
+	// errors are kept longest.
+	DropLowestSeverity DropPolicy = "severity"
+)
+
+// Valid reports whether p is a known policy.
+func (p DropPolicy) Valid() bool {
+	switch p {
+	case DropOldest, DropNewest, DropLowestSeverity:
+		return true
+	}
+	return false
+}
+
+const (
+	defaultMinBackoff       = time.Second
+	defaultMaxBackoff       = 30 * time.Second
+	defaultMaxRetryQueue    = 100
+	defaultCircuitThreshold = 5
+	defaultCircuitTimeout   = 30 * time.Second
+	maxSlowdown             = 8 // Largest factor batches grow by under backpressure
 )
 
 // NewBatcher creates a log batcher.
@@ -73,10 +147,63 @@ func NewBatcher(
 		buffer:        make(storage.LogBatch, 0, batchSize),
 		lastFlush:     time.Now(),
 		retryQueue:    make([]storage.LogBatch, 0),
-		backoff:       minBackoff,
+		backoff:       defaultMinBackoff,
+		slowdown:      1,
+
+		minBackoff:       defaultMinBackoff,
+		maxBackoff:       defaultMaxBackoff,
+		maxRetryQueue:    defaultMaxRetryQueue,
+		circuitThreshold: defaultCircuitThreshold,
+		circuitTimeout:   defaultCircuitTimeout,
+		dropPolicy:       DropOldest,
 	}
 }
 
+// SetDropPolicy chooses the batch dropped when the retry queue is full.
+// Call before Run.
+func (b *Batcher) SetDropPolicy(p DropPolicy) {
+	b.retryMu.Lock()
+	defer b.retryMu.Unlock()
+	b.dropPolicy = p
+}
+
+// SetRetryPolicy replaces the default retry policy: failed batches wait
+// between minBackoff and maxBackoff, doubling on each failure, at most
+// maxRetryQueue batches are kept, and circuitThreshold consecutive
+// failures stop writes for circuitTimeout. Call before Run.
+func (b *Batcher) SetRetryPolicy(minBackoff, maxBackoff time.Duration, maxRetryQueue, circuitThreshold int, circuitTimeout time.Duration) {
+	b.retryMu.Lock()
+	defer b.retryMu.Unlock()
+
+	b.minBackoff = minBackoff
+	b.maxBackoff = maxBackoff
+	b.maxRetryQueue = maxRetryQueue
+	b.circuitThreshold = circuitThreshold
+	b.circuitTimeout = circuitTimeout
+	b.backoff = minBackoff
+}
+
+// SetSpool makes failed batches go to spool rather than the in-memory
+// retry queue, which then only holds batches the spool failed to write.
+// Spooled batches are retried after the queue's, oldest first, with the
+// same backoff. Call before Run.
+func (b *Batcher) SetSpool(s *Spool) {
+	b.spool = s
+}
+
+// SetCatchUp makes batches factor times larger, and sorts them oldest
+// entry first, while catchingUp reports that streams are reading a
+// backlog. Call before Run.
+func (b *Batcher) SetCatchUp(factor int, catchingUp func() bool) {
+	b.catchUpFactor = factor
+	b.catchingUp = catchingUp
+}
+
+// isCatchingUp reports whether streams are catching up.
+func (b *Batcher) isCatchingUp() bool {
+	return b.catchingUp != nil && b.catchingUp()
+}
+
 // Run processes log lines until ctx is canceled.
 // Performs final flush on shutdown.
 func (b *Batcher) Run(ctx context.Context) error {
@@ -101,7 +228,7 @@ func (b *Batcher) Run(ctx context.Context) error {
 
 			b.mu.Lock()
 			b.buffer = append(b.buffer, entry)
-			shouldFlush := len(b.buffer) >= b.batchSize
+			shouldFlush := b.full(time.Now())
 			b.mu.Unlock()
 
 			if shouldFlush {
@@ -112,7 +239,8 @@ func (b *Batcher) Run(ctx context.Context) error {
 
 		case <-ticker.C:
 			b.mu.Lock()
-			shouldFlush := len(b.buffer) > 0 && time.Since(b.lastFlush) >= b.flushInterval
+			now := time.Now()
+			shouldFlush := len(b.buffer) > 0 && now.Sub(b.lastFlush) >= b.flushInterval*time.Duration(b.slowdown) && !now.Before(b.holdUntil)
 			b.mu.Unlock()
 
 			if shouldFlush {
@@ -135,10 +263,11 @@ func (b *Batcher) Run(ctx context.Context) error {
 		case <-healthTicker.C:
 			// Periodic health check - log warning if circuit is open or retry queue has items
 			stats := b.Stats()
-			if stats.CircuitOpen || stats.RetryQueueSize > 0 {
+			if stats.CircuitOpen || stats.RetryQueueSize > 0 || stats.Spool.Batches > 0 {
 				slog.Warn("batcher health check",
 					"circuitOpen", stats.CircuitOpen,
 					"retryQueueSize", stats.RetryQueueSize,
+					"spooledBatches", stats.Spool.Batches,
 					"writeErrors", stats.WriteErrors,
 					"totalWrites", stats.TotalWrites,
 				)
@@ -154,6 +283,63 @@ func (b *Batcher) Run(ctx context.Context) error {
 	}
 }
 
+// Add buffers a line produced outside the stream manager, such as a
+// synthetic lifecycle entry. It's written with the next flush.
+func (b *Batcher) Add(line LogLine) {
+	entry := b.convertToEntry(line)
+	b.mu.Lock()
+	b.buffer = append(b.buffer, entry)
+	b.mu.Unlock()
+}
+
+// full reports whether the buffer holds a batch. Callers hold b.mu.
+func (b *Batcher) full(now time.Time) bool {
+	n := len(b.buffer)
+	if n >= b.batchSize*maxSlowdown {
+		return true
+	}
+	scale := b.slowdown
+	if b.isCatchingUp() {
+		scale = max(scale, b.catchUpFactor)
+	}
+	return n >= b.batchSize*scale && !now.Before(b.holdUntil)
+}
+
+// adjustSlowdown follows the store's requests to slow down after a
+// write: batches double in size and interval, up to maxSlowdown, while
+// the store asks for a delay, and shrink back by half once it doesn't.
+func (b *Batcher) adjustSlowdown() {
+	t, ok := b.store.(storage.WriteThrottler)
+	if !ok {
+		return
+	}
+	delay := t.WriteDelay()
+
+	b.mu.Lock()
+	prev := b.slowdown
+	if delay > 0 {
+		b.slowdown = min(b.slowdown*2, maxSlowdown)
+		b.holdUntil = time.Now().Add(delay)
+	} else if b.slowdown > 1 {
+		b.slowdown /= 2
+	}
+	slowdown := b.slowdown
+	b.mu.Unlock()
+
+	if delay > 0 {
+		b.retryMu.Lock()
+		b.backoff = max(b.backoff, min(delay, b.maxBackoff))
+		b.retryMu.Unlock()
+	}
+	if slowdown != prev {
+		slog.Info("storage backpressure, adjusting batches",
+			"slowdown", slowdown,
+			"batchSize", b.batchSize*slowdown,
+			"delay", delay,
+		)
+	}
+}
+
 // Flush forces an immediate write of buffered logs.
 func (b *Batcher) Flush(ctx context.Context) error {
 	return b.flush(ctx)
@@ -171,6 +357,14 @@ func (b *Batcher) flush(ctx context.Context) error {
 	b.lastFlush = time.Now()
 	b.mu.Unlock()
 
+	// Backlogs of several streams interleave; writing them oldest first
+	// keeps the stored order close to the order the logs were produced
+	if b.isCatchingUp() {
+		slices.SortStableFunc(batch, func(x, y storage.LogEntry) int {
+			return x.Timestamp.Compare(y.Timestamp)
+		})
+	}
+
 	// Check circuit breaker before attempting write
 	if b.isCircuitOpen() {
 		b.addToRetryQueue(batch)
@@ -181,6 +375,7 @@ func (b *Batcher) flush(ctx context.Context) error {
 	if err != nil {
 		b.writeErrors.Add(1)
 		b.recordFailure()
+		b.adjustSlowdown()
 		b.addToRetryQueue(batch)
 		slog.Warn("batch write failed, queued for retry",
 			"entries", len(batch),
@@ -191,6 +386,8 @@ func (b *Batcher) flush(ctx context.Context) error {
 	}
 
 	b.recordSuccess()
+	b.adjustSlowdown()
+	b.countSeverities(batch)
 	b.totalWrites.Add(1)
 	b.totalEntries.Add(int64(n))
 
@@ -214,9 +411,9 @@ func (b *Batcher) recordFailure() {
 	defer b.retryMu.Unlock()
 
 	b.consecutiveFailures++
-	if b.consecutiveFailures >= circuitThreshold {
+	if b.consecutiveFailures >= b.circuitThreshold {
 		b.circuitOpen = true
-		b.circuitOpenUntil = time.Now().Add(circuitTimeout)
+		b.circuitOpenUntil = time.Now().Add(b.circuitTimeout)
 		slog.Warn("circuit breaker opened",
 			"failures", b.consecutiveFailures,
 			"reopen_at", b.circuitOpenUntil,
@@ -229,62 +426,172 @@ func (b *Batcher) recordSuccess() {
 	defer b.retryMu.Unlock()
 
 	b.consecutiveFailures = 0
-	b.backoff = minBackoff
+	b.backoff = b.minBackoff
 }
 
 func (b *Batcher) addToRetryQueue(batch storage.LogBatch) {
+	if b.spool != nil {
+		err := b.spool.Put(batch)
+		if err == nil {
+			return
+		}
+		slog.Error("failed to spool batch, queueing it in memory", "entries", len(batch), "error", err)
+	}
+
 	b.retryMu.Lock()
 	defer b.retryMu.Unlock()
 
-	if len(b.retryQueue) >= maxRetryQueue {
-		slog.Warn("retry queue full, dropping oldest batch",
-			"queue_size", len(b.retryQueue),
-			"dropped_entries", len(b.retryQueue[0]),
-		)
-		b.retryQueue = b.retryQueue[1:] // Drop oldest
+	if len(b.retryQueue) < b.maxRetryQueue {
+		b.retryQueue = append(b.retryQueue, batch)
+		return
 	}
 
-	b.retryQueue = append(b.retryQueue, batch)
+	i := b.dropIndex(batch)
+	dropped := batch
+	if i < len(b.retryQueue) {
+		dropped = b.retryQueue[i]
+		b.retryQueue = append(slices.Delete(b.retryQueue, i, i+1), batch)
+	}
+	b.droppedEntries.Add(int64(len(dropped)))
+	if b.onDrop != nil {
+		b.onDrop(dropped)
+	}
+	slog.Warn("retry queue full, dropping batch",
+		"policy", b.dropPolicy,
+		"queue_size", len(b.retryQueue),
+		"dropped_entries", len(dropped),
+		"dropped_severity", maxSeverity(dropped).String(),
+	)
+}
+
+// dropIndex returns the index of the queued batch to drop for batch, or
+// len(b.retryQueue) to drop batch itself. The batch being retried is
+// never dropped. Callers hold b.retryMu.
+func (b *Batcher) dropIndex(batch storage.LogBatch) int {
+	first := 0
+	if b.retrying {
+		first = 1
+	}
+	n := len(b.retryQueue)
+	switch {
+	case first >= n || b.dropPolicy == DropNewest:
+		return n
+	case b.dropPolicy == DropLowestSeverity:
+		drop, lowest := n, maxSeverity(batch)
+		for i := n - 1; i >= first; i-- {
+			if sev := maxSeverity(b.retryQueue[i]); sev <= lowest {
+				drop, lowest = i, sev
+			}
+		}
+		return drop
+	default:
+		return first
+	}
+}
+
+// maxSeverity returns the severity of the most severe entry in batch.
+func maxSeverity(batch storage.LogBatch) storage.Severity {
+	var sev storage.Severity
+	for i := range batch {
+		sev = max(sev, batch[i].Severity)
+	}
+	return sev
 }
 
 func (b *Batcher) processRetryQueue(ctx context.Context) {
 	b.retryMu.Lock()
 	if len(b.retryQueue) == 0 {
 		b.retryMu.Unlock()
+		if b.spool != nil {
+			b.processSpool(ctx)
+		}
 		return
 	}
 
-	// Take first batch from queue
+	// Take first batch from queue, keeping it there while it's written
 	batch := b.retryQueue[0]
+	b.retrying = true
 	b.retryMu.Unlock()
 
 	n, err := b.store.Write(ctx, batch)
+
+	b.retryMu.Lock()
+	b.retrying = false
+	if err == nil {
+		b.retryQueue = b.retryQueue[1:]
+	}
+	b.retryMu.Unlock()
+
 	if err != nil {
-		b.recordFailure()
-		slog.Warn("retry failed, will try again",
-			"entries", len(batch),
-			"backoff", b.backoff,
-			"error", err,
-		)
-		// Exponential backoff
-		b.retryMu.Lock()
-		b.backoff = min(b.backoff*2, maxBackoff)
-		b.retryMu.Unlock()
+		b.retryFailed(len(batch), err)
 		return
 	}
+	b.retrySucceeded(batch, n)
+}
+
+// processSpool writes the oldest spooled batch, removing it from the
+// spool once it's written.
+func (b *Batcher) processSpool(ctx context.Context) {
+	batch, seq, ok := b.spool.Oldest()
+	if !ok {
+		return
+	}
+
+	n, err := b.store.Write(ctx, batch)
+	if err != nil {
+		b.retryFailed(len(batch), err)
+		return
+	}
+	b.spool.Remove(seq)
+	b.retrySucceeded(batch, n)
+}
 
-	// Success - remove from queue
+func (b *Batcher) retryFailed(entries int, err error) {
+	b.recordFailure()
+	b.adjustSlowdown()
+	slog.Warn("retry failed, will try again",
+		"entries", entries,
+		"backoff", b.backoff,
+		"error", err,
+	)
+	// Exponential backoff
 	b.retryMu.Lock()
-	b.retryQueue = b.retryQueue[1:]
+	b.backoff = min(b.backoff*2, b.maxBackoff)
 	b.retryMu.Unlock()
+}
 
+func (b *Batcher) retrySucceeded(batch storage.LogBatch, n int) {
 	b.recordSuccess()
+	b.adjustSlowdown()
+	b.countSeverities(batch)
 	b.retriedBatches.Add(1)
 	b.totalWrites.Add(1)
 	b.totalEntries.Add(int64(n))
 	slog.Info("retry succeeded", "entries", n)
 }
 
+// countSeverities adds the entries of the written batch to the counts per
+// severity, taking them from the store if it reports the severities it
+// accepted (a remote server may drop some over quota), and from batch
+// otherwise.
+func (b *Batcher) countSeverities(batch storage.LogBatch) {
+	if c, ok := b.store.(storage.SeverityCounter); ok {
+		if counts := c.WrittenSeverities(); counts != nil {
+			for sev, n := range counts {
+				if int(sev) < len(b.writtenSeverities) {
+					b.writtenSeverities[sev].Add(n)
+				}
+			}
+			return
+		}
+	}
+	for _, e := range batch {
+		if int(e.Severity) < len(b.writtenSeverities) {
+			b.writtenSeverities[e.Severity].Add(1)
+		}
+	}
+}
+
 func (b *Batcher) convertToEntry(line LogLine) storage.LogEntry {
 	// Start with extracted attributes from parsed log (may be nil)
 	attrs := line.Attributes
@@ -293,13 +600,24 @@ func (b *Batcher) convertToEntry(line LogLine) storage.LogEntry {
 	}
 	// Always add pod_uid
 	attrs["pod_uid"] = line.Container.PodUID
+	if b.podAttributes != nil {
+		for k, v := range b.podAttributes(line.Container.PodUID) {
+			attrs[k] = v
+		}
+	}
+
+	severity := line.Severity
+	if b.severityRules != nil {
+		severity = b.severityRules.Apply(line)
+	}
 
 	return storage.LogEntry{
 		Timestamp:  line.Timestamp,
+		Cluster:    b.cluster,
 		Namespace:  line.Container.Namespace,
 		Pod:        line.Container.PodName,
 		Container:  line.Container.ContainerName,
-		Severity:   line.Severity,
+		Severity:   severity,
 		Message:    line.Message,
 		Attributes: attrs,
 	}
@@ -309,6 +627,7 @@ func (b *Batcher) convertToEntry(line LogLine) storage.LogEntry {
 func (b *Batcher) Stats() BatcherStats {
 	b.mu.Lock()
 	bufSize := len(b.buffer)
+	slowdown := b.slowdown
 	b.mu.Unlock()
 
 	b.retryMu.Lock()
@@ -316,13 +635,29 @@ func (b *Batcher) Stats() BatcherStats {
 	circuitOpen := b.circuitOpen
 	b.retryMu.Unlock()
 
+	var spool SpoolStats
+	if b.spool != nil {
+		spool = b.spool.Stats()
+	}
+
+	severities := make(map[storage.Severity]int64)
+	for sev := range b.writtenSeverities {
+		if n := b.writtenSeverities[sev].Load(); n > 0 {
+			severities[storage.Severity(sev)] = n
+		}
+	}
+
 	return BatcherStats{
-		TotalWrites:    b.totalWrites.Load(),
-		TotalEntries:   b.totalEntries.Load(),
-		WriteErrors:    b.writeErrors.Load(),
-		BufferSize:     bufSize,
-		RetryQueueSize: retrySize,
-		RetriedBatches: b.retriedBatches.Load(),
-		CircuitOpen:    circuitOpen,
+		TotalWrites:       b.totalWrites.Load(),
+		TotalEntries:      b.totalEntries.Load(),
+		WriteErrors:       b.writeErrors.Load(),
+		BufferSize:        bufSize,
+		RetryQueueSize:    retrySize,
+		RetriedBatches:    b.retriedBatches.Load(),
+		DroppedEntries:    b.droppedEntries.Load(),
+		CircuitOpen:       circuitOpen,
+		Slowdown:          slowdown,
+		Spool:             spool,
+		WrittenSeverities: severities,
 	}
 }
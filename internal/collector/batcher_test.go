@@ -2,6 +2,9 @@ package collector
 
 import (
 	"context"
+	"errors"
+	"maps"
+	"slices"
 	"sync"
 	"testing"
 	"time"
@@ -31,6 +34,10 @@ func (m *mockStore) GetByID(ctx context.Context, id int64) (*storage.LogEntry, e
 	return nil, storage.ErrNotFound
 }
 
+func (m *mockStore) GetByIDs(ctx context.Context, ids []int64) ([]storage.LogEntry, error) {
+	return nil, nil
+}
+
 func (m *mockStore) Delete(ctx context.Context, olderThan time.Time) (int64, error) {
 	return 0, nil
 }
@@ -197,4 +204,267 @@ func TestBatcher_Stats(t *testing.T) {
 	if stats.TotalEntries != 4 {
 		t.Errorf("expected 4 total entries, got %d", stats.TotalEntries)
 	}
+	if want := map[storage.Severity]int64{storage.SeverityInfo: 4}; !maps.Equal(stats.WrittenSeverities, want) {
+		t.Errorf("WrittenSeverities = %v, want %v", stats.WrittenSeverities, want)
+	}
+}
+
+// countingStore reports the severities its server accepted, dropping
+// the ERROR entries.
+type countingStore struct {
+	mockStore
+}
+
+func (s *countingStore) WrittenSeverities() map[storage.Severity]int64 {
+	return map[storage.Severity]int64{storage.SeverityInfo: 1}
+}
+
+func TestBatcher_StoreSeverities(t *testing.T) {
+	batcher := NewBatcher(&countingStore{}, nil, 10, time.Hour)
+	ref := ContainerRef{Namespace: "default", PodName: "test-pod", ContainerName: "test"}
+	batcher.Add(LogLine{Container: ref, Severity: storage.SeverityInfo, Message: "ok"})
+	batcher.Add(LogLine{Container: ref, Severity: storage.SeverityError, Message: "over quota"})
+	batcher.Flush(context.Background())
+
+	if want := map[storage.Severity]int64{storage.SeverityInfo: 1}; !maps.Equal(batcher.Stats().WrittenSeverities, want) {
+		t.Errorf("WrittenSeverities = %v, want the store's %v", batcher.Stats().WrittenSeverities, want)
+	}
+}
+
+// throttlingStore asks for a delay after every write while delay is set.
+type throttlingStore struct {
+	mockStore
+	delay time.Duration
+}
+
+func (s *throttlingStore) WriteDelay() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.delay
+}
+
+func TestBatcher_Backpressure(t *testing.T) {
+	store := &throttlingStore{delay: time.Hour}
+	batcher := NewBatcher(store, nil, 2, time.Hour)
+	line := LogLine{Container: ContainerRef{Namespace: "default", PodName: "test-pod", ContainerName: "test"}, Message: "test"}
+	ctx := context.Background()
+
+	batcher.Add(line)
+	batcher.Add(line)
+	batcher.Flush(ctx)
+	if got := batcher.Stats().Slowdown; got != 2 {
+		t.Fatalf("Slowdown after a throttled write = %d, want 2", got)
+	}
+
+	// Held back until the delay passes, even at the larger batch size,
+	// unless the buffer reaches its limit
+	now := time.Now()
+	for range 4 {
+		batcher.Add(line)
+	}
+	if batcher.full(now) {
+		t.Error("buffer of a doubled batch flushed during the delay")
+	}
+	for range 2*maxSlowdown - 4 {
+		batcher.Add(line)
+	}
+	if !batcher.full(now) {
+		t.Error("buffer at its limit not flushed")
+	}
+
+	for range 4 {
+		batcher.Flush(ctx)
+		batcher.Add(line)
+	}
+	if got := batcher.Stats().Slowdown; got != maxSlowdown {
+		t.Errorf("Slowdown = %d, want at most %d", got, maxSlowdown)
+	}
+
+	// Recovers gradually once the store stops asking
+	store.mu.Lock()
+	store.delay = 0
+	store.mu.Unlock()
+	batcher.Flush(ctx)
+	if got := batcher.Stats().Slowdown; got != maxSlowdown/2 {
+		t.Errorf("Slowdown after an unthrottled write = %d, want %d", got, maxSlowdown/2)
+	}
+	if len(store.getEntries()) != 2+2*maxSlowdown+4 {
+		t.Errorf("stored %d entries, want all %d", len(store.getEntries()), 2+2*maxSlowdown+4)
+	}
+}
+
+// failingStore fails every write.
+type failingStore struct {
+	mockStore
+}
+
+func (s *failingStore) Write(ctx context.Context, entries storage.LogBatch) (int, error) {
+	return 0, errors.New("unavailable")
+}
+
+func TestBatcher_RetryPolicy(t *testing.T) {
+	batcher := NewBatcher(&failingStore{}, nil, 1, time.Hour)
+	batcher.SetRetryPolicy(10*time.Millisecond, 40*time.Millisecond, 2, 3, time.Hour)
+	line := LogLine{Container: ContainerRef{Namespace: "default", PodName: "test-pod", ContainerName: "test"}, Message: "test"}
+	ctx := context.Background()
+
+	for range 2 {
+		batcher.Add(line)
+		batcher.Flush(ctx)
+	}
+	if stats := batcher.Stats(); stats.CircuitOpen || stats.RetryQueueSize != 2 {
+		t.Fatalf("after 2 failures: circuit open %v, retry queue %d, want closed, 2", stats.CircuitOpen, stats.RetryQueueSize)
+	}
+
+	// The third failure opens the circuit, and the queue keeps the newest 2
+	batcher.Add(line)
+	batcher.Flush(ctx)
+	if stats := batcher.Stats(); !stats.CircuitOpen || stats.RetryQueueSize != 2 {
+		t.Errorf("after 3 failures: circuit open %v, retry queue %d, want open, 2", stats.CircuitOpen, stats.RetryQueueSize)
+	}
+
+	// Backoff doubles up to the maximum
+	for range 3 {
+		batcher.processRetryQueue(ctx)
+	}
+	if batcher.backoff != 40*time.Millisecond {
+		t.Errorf("backoff = %v, want 40ms", batcher.backoff)
+	}
+}
+
+func TestBatcher_DropPolicy(t *testing.T) {
+	batch := func(msg string, sev storage.Severity) storage.LogBatch {
+		return storage.LogBatch{{Message: msg, Severity: storage.SeverityInfo}, {Message: msg, Severity: sev}}
+	}
+
+	tests := []struct {
+		policy   DropPolicy
+		retrying bool
+		want     []string
+	}{
+		{DropOldest, false, []string{"warn", "info2", "error2"}},
+		{DropOldest, true, []string{"error", "info2", "error2"}},
+		{DropNewest, false, []string{"error", "warn", "info2"}},
+		// info2 makes room for error2, then info3 is the least severe itself
+		{DropLowestSeverity, false, []string{"error", "warn", "error2"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			b := NewBatcher(&failingStore{}, nil, 1, time.Hour)
+			b.SetRetryPolicy(time.Second, time.Second, 3, 100, time.Hour)
+			b.SetDropPolicy(tt.policy)
+			b.retrying = tt.retrying
+
+			b.addToRetryQueue(batch("error", storage.SeverityError))
+			b.addToRetryQueue(batch("warn", storage.SeverityWarn))
+			b.addToRetryQueue(batch("info2", storage.SeverityInfo))
+			b.addToRetryQueue(batch("error2", storage.SeverityError))
+			if tt.policy == DropLowestSeverity {
+				b.addToRetryQueue(batch("info3", storage.SeverityInfo))
+			}
+
+			var got []string
+			for _, q := range b.retryQueue {
+				got = append(got, q[0].Message)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("retry queue = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatcher_CatchUp(t *testing.T) {
+	store := &mockStore{}
+	b := NewBatcher(store, nil, 1, time.Hour)
+	var catchingUp bool
+	b.SetCatchUp(3, func() bool { return catchingUp })
+
+	now := time.Now()
+	add := func(msg string, age time.Duration) {
+		b.Add(LogLine{Timestamp: now.Add(-age), Message: msg})
+	}
+
+	// Batches are three times larger while streams catch up
+	catchingUp = true
+	add("b", 2*time.Hour)
+	add("c", time.Hour)
+	if b.full(now) {
+		t.Fatal("full() with 2 entries while catching up, want 3")
+	}
+	add("a", 3*time.Hour)
+	if !b.full(now) {
+		t.Fatal("full() = false with 3 entries while catching up")
+	}
+
+	// and are written oldest first
+	if err := b.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	var got []string
+	for _, e := range store.getEntries() {
+		got = append(got, e.Message)
+	}
+	if want := []string{"a", "b", "c"}; !slices.Equal(got, want) {
+		t.Errorf("written %v, want %v", got, want)
+	}
+
+	catchingUp = false
+	add("d", 0)
+	if !b.full(now) {
+		t.Error("full() = false with 1 entry after catching up")
+	}
+}
+
+// flakyStore fails writes until healthy is set.
+type flakyStore struct {
+	mockStore
+	healthy bool
+}
+
+func (s *flakyStore) Write(ctx context.Context, entries storage.LogBatch) (int, error) {
+	if !s.healthy {
+		return 0, errors.New("unavailable")
+	}
+	return s.mockStore.Write(ctx, entries)
+}
+
+func TestBatcher_Spool(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := OpenSpool(dir, 0)
+	if err != nil {
+		t.Fatalf("OpenSpool: %v", err)
+	}
+	store := &flakyStore{}
+	b := NewBatcher(store, nil, 1, time.Hour)
+	b.SetSpool(spool)
+	ctx := context.Background()
+
+	for _, msg := range []string{"first", "second"} {
+		b.Add(LogLine{Container: ContainerRef{Namespace: "default", PodName: "api"}, Message: msg})
+		b.Flush(ctx)
+	}
+	if stats := b.Stats(); stats.RetryQueueSize != 0 || stats.Spool.Batches != 2 {
+		t.Fatalf("retry queue %d, spool %d, want 0, 2", stats.RetryQueueSize, stats.Spool.Batches)
+	}
+
+	// A new batcher, as after a restart, replays the spool oldest first
+	spool, err = OpenSpool(dir, 0)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	store.healthy = true
+	b = NewBatcher(store, nil, 1, time.Hour)
+	b.SetSpool(spool)
+	b.processRetryQueue(ctx)
+	b.processRetryQueue(ctx)
+	b.processRetryQueue(ctx)
+
+	entries := store.getEntries()
+	if len(entries) != 2 || entries[0].Message != "first" || entries[1].Message != "second" {
+		t.Errorf("stored %+v, want first and second", entries)
+	}
+	if stats := b.Stats(); stats.Spool.Batches != 0 || stats.RetriedBatches != 2 {
+		t.Errorf("spool %d, retried %d, want 0, 2", stats.Spool.Batches, stats.RetriedBatches)
+	}
 }
@@ -2,7 +2,12 @@ package collector
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -12,23 +17,38 @@ import (
 	"github.com/kubelogs/kubelogs/internal/storage"
 )
 
-// Collector watches pods and streams container logs to storage.
+// Collector watches pods and streams container logs to storage, or
+// tails log files on the node if configured to.
 type Collector struct {
 	config    Config
 	clientset kubernetes.Interface
 	store     storage.Store
 
-	discovery     *PodDiscovery
+	discovery     *PodDiscovery // Nil when tailing files
 	streamManager *StreamManager
+	tailer        *FileTailer // Nil unless LogFiles is set
+	merger        *Merger     // Nil unless multi-line merging is configured
+	limiter       *RateLimiter
 	batcher       *Batcher
 
 	ctx    context.Context
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 
+	// started is set once the components above exist, so Stats can be
+	// called concurrently with Start
+	started atomic.Bool
+
+	startedAt time.Time
+
+	// Drop counters at the last drop summary, and the pods that lost
+	// lines since
+	dropsMu       sync.Mutex
+	reportedDrops drops
+	ledger        dropLedger
+
 	// Metrics
-	totalLinesRead atomic.Int64
-	totalErrors    atomic.Int64
+	totalErrors atomic.Int64
 }
 
 // CollectorStats contains collector statistics.
@@ -40,7 +60,7 @@ type CollectorStats struct {
 	StreamStats    []StreamStats
 }
 
-// New creates a new Collector.
+// New creates a new Collector. clientset may be nil when tailing files.
 func New(clientset kubernetes.Interface, store storage.Store, cfg Config) (*Collector, error) {
 	if err := cfg.Validate(); err != nil {
 		return nil, err
@@ -55,6 +75,28 @@ func New(clientset kubernetes.Interface, store storage.Store, cfg Config) (*Coll
 
 // Start begins collecting logs. Blocks until ctx is canceled.
 func (c *Collector) Start(ctx context.Context) error {
+	var spool *Spool
+	if c.config.SpoolDir != "" {
+		var err error
+		spool, err = OpenSpool(c.config.SpoolDir, c.config.SpoolMaxBytes)
+		if err != nil {
+			return fmt.Errorf("open spool: %w", err)
+		}
+	}
+
+	rules := c.config.SeverityRules
+	if c.config.SeverityRulesFile != "" {
+		fileRules, err := ReadSeverityRules(c.config.SeverityRulesFile)
+		if err != nil {
+			return fmt.Errorf("read severity rules: %w", err)
+		}
+		rules = append(slices.Clip(rules), fileRules...)
+	}
+	severityRules, err := NewSeverityRules(rules)
+	if err != nil {
+		return fmt.Errorf("severity rules: %w", err)
+	}
+
 	c.ctx, c.cancel = context.WithCancel(ctx)
 
 	// Create components
@@ -65,16 +107,92 @@ func (c *Collector) Start(ctx context.Context) error {
 		c.config.SinceTime,
 		c.config.StreamIdleTimeout,
 	)
+	c.streamManager.SetStartupSmoothing(c.config.StreamStartJitter, c.config.StreamRampUp)
+	c.streamManager.SetCatchUpLag(c.config.CatchUpLag)
+	c.streamManager.SetAdaptiveLimit(c.config.AdaptiveStreams)
 	c.streamManager.Start(c.ctx)
 
+	lines := c.streamManager.Output()
+	if len(c.config.LogFiles) > 0 {
+		c.tailer = NewFileTailer(
+			c.config.LogFiles,
+			c.config.FilePollInterval,
+			c.config.StreamBufferSize*10,
+			c.config.SinceTime,
+			c.config.NodeName,
+		)
+		c.tailer.collect = c.config.ShouldCollect
+		lines = c.tailer.Output()
+	}
+	if c.config.MultilineStart != "" {
+		c.merger = NewMerger(lines,
+			regexp.MustCompile(c.config.MultilineStart),
+			c.config.MultilineMaxLines,
+			c.config.MultilineMaxWait,
+		)
+		lines = c.merger.Output()
+	}
+	limits := LineLimits{Rate: c.config.RateLimit, SampleDebug: c.config.SampleDebug}
+	c.limiter = NewRateLimiter(lines, limits)
+	lines = c.limiter.Output()
+
 	c.batcher = NewBatcher(
 		c.store,
-		c.streamManager.Output(),
+		lines,
 		c.config.BatchSize,
 		c.config.BatchTimeout,
 	)
+	c.batcher.cluster = c.config.ClusterName
+	if len(rules) > 0 {
+		c.batcher.severityRules = severityRules
+	}
+	c.batcher.SetRetryPolicy(
+		c.config.RetryMinBackoff,
+		c.config.RetryMaxBackoff,
+		c.config.RetryQueueSize,
+		c.config.CircuitThreshold,
+		c.config.CircuitTimeout,
+	)
+	c.batcher.SetDropPolicy(c.config.RetryDropPolicy)
+	if spool != nil {
+		c.batcher.SetSpool(spool)
+	}
+	c.batcher.SetCatchUp(c.config.CatchUpBatchFactor, func() bool {
+		return c.streamManager.CatchingUp() > 0
+	})
 
-	c.discovery = NewPodDiscovery(c.clientset, c.config.NodeName)
+	if c.tailer == nil {
+		c.discovery = NewPodDiscovery(c.clientset, c.config.NodeName, c.config.DiscoveryResync, c.config.DiscoveryEventBuffer)
+		c.discovery.includeLabels = c.config.IncludeLabels
+		c.discovery.includeAnnotations = c.config.IncludeAnnotations
+		// Checked by cfg.Validate
+		c.discovery.excludeSelector, _ = parseSelector(c.config.ExcludePodSelector)
+		c.discovery.includeSelector, _ = parseSelector(c.config.IncludePodSelector)
+		c.discovery.limits = limits
+		c.batcher.podAttributes = c.discovery.PodAttributes
+		c.limiter.podLimits = c.discovery.PodLimits
+	}
+	if c.config.LifecycleEvents {
+		c.streamManager.onStreamStart = func(ref ContainerRef) {
+			c.batcher.Add(c.streamStartedLine(ref))
+		}
+		c.streamManager.onStreamEnd = func(ref ContainerRef, end StreamEnd) {
+			c.batcher.Add(c.streamStoppedLine(ref, end))
+		}
+		c.streamManager.onLineDropped = func(line LogLine) {
+			c.recordDrop(line, DropReasonBackpressure)
+		}
+		c.limiter.onLimited = func(line LogLine) {
+			c.recordDrop(line, DropReasonRateLimit)
+		}
+		c.batcher.onDrop = c.recordDroppedBatch
+	}
+	c.startedAt = time.Now()
+	c.reportedDrops.since = c.startedAt
+	c.started.Store(true)
+	if c.config.LifecycleEvents {
+		c.batcher.Add(c.collectorStartedLine())
+	}
 
 	// Start batcher (must be running before streams produce)
 	c.wg.Add(1)
@@ -85,25 +203,68 @@ func (c *Collector) Start(ctx context.Context) error {
 		}
 	}()
 
-	// Start pod discovery
+	if c.merger != nil {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.merger.Run(c.ctx)
+		}()
+	}
 	c.wg.Add(1)
 	go func() {
 		defer c.wg.Done()
-		if err := c.discovery.Start(c.ctx); err != nil && err != context.Canceled {
-			slog.Error("discovery error", "error", err)
-		}
+		c.limiter.Run(c.ctx)
 	}()
 
+	// Start pod discovery, or tail files instead
+	var events <-chan PodEvent
+	if c.tailer != nil {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			if err := c.tailer.Run(c.ctx); err != nil && err != context.Canceled {
+				slog.Error("file tailing error", "error", err)
+			}
+		}()
+	} else {
+		events = c.discovery.Events()
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			if err := c.discovery.Start(c.ctx); err != nil && err != context.Canceled {
+				slog.Error("discovery error", "error", err)
+			}
+		}()
+	}
+
+	if c.config.LifecycleEvents {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.reportDrops()
+		}()
+	}
+
+	if reporter, ok := c.store.(storage.StatusReporter); ok && c.config.StatusInterval > 0 {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.reportStatus(reporter)
+		}()
+	}
+
 	slog.Info("collector started",
 		"node", c.config.NodeName,
+		"cluster", c.config.ClusterName,
 		"maxStreams", c.config.MaxConcurrentStreams,
 		"batchSize", c.config.BatchSize,
+		"logFiles", c.config.LogFiles,
 	)
 
 	// Main loop: process pod events
 	for {
 		select {
-		case event := <-c.discovery.Events():
+		case event := <-events:
 			c.handlePodEvent(event)
 		case <-c.ctx.Done():
 			return c.shutdown()
@@ -117,6 +278,13 @@ func (c *Collector) handlePodEvent(event PodEvent) {
 		return
 	}
 
+	if event.Termination != nil && c.config.TerminationEvents {
+		c.batcher.Add(terminationLine(event.Container, event.Termination))
+	}
+	if event.Readiness != nil && c.config.ReadinessEvents {
+		c.batcher.Add(readinessLine(event.Container, event.Readiness))
+	}
+
 	switch event.Type {
 	case ContainerStarted:
 		slog.Debug("starting stream",
@@ -142,6 +310,72 @@ func (c *Collector) handlePodEvent(event PodEvent) {
 	}
 }
 
+// terminationLine builds the synthetic ERROR entry recording why a
+// container's run ended, timestamped when it finished.
+func terminationLine(ref ContainerRef, t *Termination) LogLine {
+	msg := fmt.Sprintf("container %s terminated: %s (exit code %d)", ref.ContainerName, t.Reason, t.ExitCode)
+	if t.Reason == "" {
+		msg = fmt.Sprintf("container %s terminated with exit code %d", ref.ContainerName, t.ExitCode)
+	}
+	if t.Message != "" {
+		msg += ": " + strings.TrimSpace(t.Message)
+	}
+
+	attrs := map[string]string{
+		"event":     "container_terminated",
+		"exit_code": strconv.Itoa(int(t.ExitCode)),
+	}
+	if t.Reason != "" {
+		attrs["reason"] = t.Reason
+	}
+	if t.Signal != 0 {
+		attrs["signal"] = strconv.Itoa(int(t.Signal))
+	}
+
+	return LogLine{
+		Container:  ref,
+		Timestamp:  t.FinishedAt,
+		Severity:   storage.SeverityError,
+		Message:    msg,
+		Attributes: attrs,
+	}
+}
+
+// readinessLine builds the synthetic entry recording a change of a pod's
+// Ready condition, timestamped at the transition: WARN when the pod
+// became unready and INFO when it recovered.
+func readinessLine(ref ContainerRef, r *Readiness) LogLine {
+	if r.Ready {
+		return LogLine{
+			Container:  ref,
+			Timestamp:  r.Since,
+			Severity:   storage.SeverityInfo,
+			Message:    fmt.Sprintf("pod %s ready", ref.PodName),
+			Attributes: map[string]string{"event": "pod_ready"},
+		}
+	}
+
+	msg := fmt.Sprintf("pod %s not ready", ref.PodName)
+	if r.Reason != "" {
+		msg += ": " + r.Reason
+	}
+	if r.Message != "" {
+		msg += ": " + strings.TrimSpace(r.Message)
+	}
+
+	attrs := map[string]string{"event": "pod_not_ready"}
+	if r.Reason != "" {
+		attrs["reason"] = r.Reason
+	}
+	return LogLine{
+		Container:  ref,
+		Timestamp:  r.Since,
+		Severity:   storage.SeverityWarn,
+		Message:    msg,
+		Attributes: attrs,
+	}
+}
+
 func (c *Collector) shutdown() error {
 	slog.Info("collector shutting down")
 
@@ -162,7 +396,19 @@ func (c *Collector) shutdown() error {
 		slog.Warn("collector shutdown timeout, some logs may be lost")
 	}
 
-	// Final flush
+	// Final flush, including records still waiting for continuation lines
+	for _, line := range c.limiter.Drain() {
+		c.batcher.Add(line)
+	}
+	if c.merger != nil {
+		for _, line := range c.merger.Drain() {
+			c.batcher.Add(line)
+		}
+	}
+	if c.config.LifecycleEvents {
+		c.summarizeDrops(true)
+		c.batcher.Add(c.collectorStoppedLine())
+	}
 	if err := c.batcher.Flush(context.Background()); err != nil {
 		slog.Error("final flush failed", "error", err)
 	}
@@ -183,20 +429,80 @@ func (c *Collector) Stats() CollectorStats {
 	var batcherStats BatcherStats
 	var streamStats []StreamStats
 	activeStreams := 0
+	var linesRead int64
 
-	if c.batcher != nil {
+	if c.started.Load() {
 		batcherStats = c.batcher.Stats()
-	}
-	if c.streamManager != nil {
 		streamStats = c.streamManager.Stats()
-		activeStreams = c.streamManager.ActiveStreams()
+		activeStreams = c.activeStreams()
+		linesRead = c.linesRead()
 	}
 
 	return CollectorStats{
 		ActiveStreams:  activeStreams,
-		TotalLinesRead: c.totalLinesRead.Load(),
+		TotalLinesRead: linesRead,
 		TotalErrors:    c.totalErrors.Load(),
 		BatcherStats:   batcherStats,
 		StreamStats:    streamStats,
 	}
 }
+
+// activeStreams returns the number of open streams, or of tailed files.
+// It must only be called once started.
+func (c *Collector) activeStreams() int {
+	if c.tailer != nil {
+		return c.tailer.ActiveFiles()
+	}
+	return c.streamManager.ActiveStreams()
+}
+
+// linesRead returns the number of lines read from streams or files.
+// It must only be called once started.
+func (c *Collector) linesRead() int64 {
+	if c.tailer != nil {
+		return c.tailer.LinesRead()
+	}
+	return c.streamManager.LinesRead()
+}
+
+// reportStatus sends the collector's status to the server every
+// StatusInterval until the collector stops.
+func (c *Collector) reportStatus(reporter storage.StatusReporter) {
+	ticker := time.NewTicker(c.config.StatusInterval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(c.ctx, c.config.StatusInterval)
+		err := reporter.ReportStatus(ctx, c.status())
+		cancel()
+		if err != nil && c.ctx.Err() == nil {
+			slog.Debug("collector status report failed", "error", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// status summarizes the collector's stats for a report to the server.
+func (c *Collector) status() storage.CollectorStatus {
+	stats := c.Stats()
+	return storage.CollectorStatus{
+		Node:              c.config.NodeName,
+		Cluster:           c.config.ClusterName,
+		StartedAt:         c.startedAt,
+		Interval:          c.config.StatusInterval,
+		ActiveStreams:     stats.ActiveStreams,
+		CatchingUpStreams: c.streamManager.CatchingUp(),
+		LinesRead:         stats.TotalLinesRead,
+		StreamErrors:      stats.TotalErrors,
+		EntriesWritten:    stats.BatcherStats.TotalEntries,
+		WriteErrors:       stats.BatcherStats.WriteErrors,
+		BufferedEntries:   stats.BatcherStats.BufferSize,
+		RetryQueueBatches: stats.BatcherStats.RetryQueueSize,
+		CircuitOpen:       stats.BatcherStats.CircuitOpen,
+	}
+}
@@ -1,11 +1,17 @@
 package collector
 
 import (
+	"fmt"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
 	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 // Config holds collector configuration.
@@ -14,6 +20,11 @@ type Config struct {
 	// Required for DaemonSet deployment. Uses NODE_NAME env var.
 	NodeName string
 
+	// ClusterName is stamped on every entry so a server receiving logs
+	// from several clusters can tell them apart. Uses KUBELOGS_CLUSTER_NAME.
+	// Default: empty.
+	ClusterName string
+
 	// MaxConcurrentStreams limits active log streams.
 	// Default: 100. Prevents memory exhaustion.
 	MaxConcurrentStreams int
@@ -26,6 +37,46 @@ type Config struct {
 	// Default: 5s. Ensures logs aren't delayed too long.
 	BatchTimeout time.Duration
 
+	// RetryMinBackoff is the wait before retrying a failed batch. It
+	// doubles with each failed retry, up to RetryMaxBackoff.
+	// Default: 1s.
+	RetryMinBackoff time.Duration
+
+	// RetryMaxBackoff caps the wait between retries.
+	// Default: 30s.
+	RetryMaxBackoff time.Duration
+
+	// RetryQueueSize is the number of failed batches kept for retry;
+	// beyond it the oldest are dropped.
+	// Default: 100.
+	RetryQueueSize int
+
+	// RetryDropPolicy chooses the batch dropped when the retry queue is
+	// full: DropOldest, DropNewest or DropLowestSeverity.
+	// Default: "oldest".
+	RetryDropPolicy DropPolicy
+
+	// SpoolDir, if set, is a directory where batches that failed to write
+	// are kept until they are retried, instead of the in-memory retry
+	// queue, so they survive a restart of the collector. Uses
+	// KUBELOGS_SPOOL_DIR.
+	// Default: empty (retry from memory).
+	SpoolDir string
+
+	// SpoolMaxBytes caps the size of the spool; beyond it the oldest
+	// batches are dropped. Uses KUBELOGS_SPOOL_MAX_BYTES.
+	// Default: 512MiB.
+	SpoolMaxBytes int64
+
+	// CircuitThreshold is the number of consecutive failed writes that
+	// open the circuit breaker, queueing batches without trying to write.
+	// Default: 5.
+	CircuitThreshold int
+
+	// CircuitTimeout is how long the circuit stays open.
+	// Default: 30s.
+	CircuitTimeout time.Duration
+
 	// StreamBufferSize is the channel buffer per stream.
 	// Default: 1000 lines. Provides backpressure relief.
 	StreamBufferSize int
@@ -35,14 +86,90 @@ type Config struct {
 	// Default: 15 minutes.
 	SinceTime time.Time
 
-	// ExcludeNamespaces skips these namespaces.
+	// ExcludeNamespaces skips these namespaces. Entries may be globs
+	// such as "ci-*".
 	// Default: ["kube-system"]. Reduces noise.
 	ExcludeNamespaces []string
 
-	// IncludeNamespaces only collects from these namespaces.
-	// Empty means all namespaces (except excluded).
+	// IncludeNamespaces only collects from these namespaces. Entries may
+	// be globs. Empty means all namespaces (except excluded).
 	IncludeNamespaces []string
 
+	// ExcludeNamespaceRegex skips namespaces matching this regular
+	// expression, e.g. `^ci-.*` for ephemeral CI namespaces. Uses
+	// KUBELOGS_EXCLUDE_NS_REGEX.
+	// Default: empty.
+	ExcludeNamespaceRegex string
+
+	// IncludeNamespaceRegex only collects from namespaces matching this
+	// regular expression, in addition to IncludeNamespaces. Uses
+	// KUBELOGS_INCLUDE_NS_REGEX.
+	// Default: empty.
+	IncludeNamespaceRegex string
+
+	// ExcludePodSelector skips pods matching this label selector, e.g.
+	// "logging.kubelogs.io/ignore=true". Pods relabeled to match stop
+	// being collected from. Uses KUBELOGS_EXCLUDE_POD_SELECTOR.
+	// Default: empty.
+	ExcludePodSelector string
+
+	// IncludePodSelector only collects from pods matching this label
+	// selector, e.g. "team in (payments,search)". Uses
+	// KUBELOGS_INCLUDE_POD_SELECTOR.
+	// Default: empty (all pods).
+	IncludePodSelector string
+
+	// IncludeLabels are pod labels added to each entry's attributes as
+	// label.<key>, e.g. label.app. Uses KUBELOGS_INCLUDE_LABELS.
+	// Default: none.
+	IncludeLabels []string
+
+	// IncludeAnnotations are pod annotations added to each entry's
+	// attributes as annotation.<key>. Uses KUBELOGS_INCLUDE_ANNOTATIONS.
+	// Default: none.
+	IncludeAnnotations []string
+
+	// MultilineStart is a regular expression matching the first line of
+	// a record, e.g. `^\d{4}-\d{2}-\d{2}` for lines starting with a date.
+	// Other lines, such as the frames of a stack trace, are appended to
+	// the record before them. Uses KUBELOGS_MULTILINE_START.
+	// Default: empty (merging disabled).
+	MultilineStart string
+
+	// MultilineMaxLines caps the lines merged into one entry.
+	// Default: 500.
+	MultilineMaxLines int
+
+	// MultilineMaxWait is how long a record waits for another
+	// continuation line before it is written.
+	// Default: 2s.
+	MultilineMaxWait time.Duration
+
+	// RateLimit is the lines per second kept from each container, with
+	// bursts of up to a second's worth; the rest are dropped and counted.
+	// Pods override it with the logging.kubelogs.io/rate-limit
+	// annotation. Uses KUBELOGS_RATE_LIMIT.
+	// Default: 0 (unlimited).
+	RateLimit float64
+
+	// SampleDebug keeps one in SampleDebug DEBUG and TRACE lines of each
+	// container, picked at random. Pods override it with the
+	// logging.kubelogs.io/sample-debug annotation. Uses
+	// KUBELOGS_SAMPLE_DEBUG.
+	// Default: 0 (all kept).
+	SampleDebug int
+
+	// SeverityRules set the severity of lines matching them, typically
+	// of apps that log without a level; see SeverityRule.
+	// Default: none.
+	SeverityRules []SeverityRule
+
+	// SeverityRulesFile is a YAML file of further severity rules, such
+	// as a mounted ConfigMap, read when the collector starts. Uses
+	// KUBELOGS_SEVERITY_RULES_FILE.
+	// Default: empty.
+	SeverityRulesFile string
+
 	// ShutdownTimeout is max time to drain logs on shutdown.
 	// Default: 30s.
 	ShutdownTimeout time.Duration
@@ -51,6 +178,99 @@ type Config struct {
 	// Detects stale connections that stop producing logs.
 	// Default: 5m.
 	StreamIdleTimeout time.Duration
+
+	// DiscoveryResync is how often the pod informer re-delivers every pod
+	// on the node. Unchanged pods are skipped, so resyncs only cost a
+	// pass over the cache; 0 disables them.
+	// Default: 30s.
+	DiscoveryResync time.Duration
+
+	// DiscoveryEventBuffer is the number of pod events (container starts
+	// and stops) queued for the collector. When it is full, discovery
+	// waits up to 5s before dropping an event.
+	// Default: 1000.
+	DiscoveryEventBuffer int
+
+	// StreamStartJitter is the longest random delay before opening the
+	// stream of a container found running at startup, so a restarted
+	// collector doesn't open them all at once.
+	// Default: 5s. 0 disables it.
+	StreamStartJitter time.Duration
+
+	// StreamRampUp is how long after startup the number of streams is
+	// ramped up from a tenth of MaxConcurrentStreams to all of them,
+	// smoothing the burst of backlog each new stream reads.
+	// Default: 1m. 0 disables it.
+	StreamRampUp time.Duration
+
+	// AdaptiveStreams lowers the number of streams while the kubelet or
+	// API server answer 429 or 5xx, halving it on each such response and
+	// raising it by one per stream opened without one.
+	// Default: true.
+	AdaptiveStreams bool
+
+	// CatchUpLag is how far a stream's cursor must trail now for it to
+	// catch up: read the backlog written while the collector was down in
+	// larger batches, oldest first, until it reads recent lines again.
+	// Default: 1m. 0 disables catch-up mode.
+	CatchUpLag time.Duration
+
+	// CatchUpBatchFactor is how many times larger batches are while any
+	// stream is catching up, at most 8.
+	// Default: 4.
+	CatchUpBatchFactor int
+
+	// TerminationEvents writes a synthetic ERROR entry when a container
+	// exits with a non-zero code or is OOM killed.
+	// Default: true.
+	TerminationEvents bool
+
+	// ReadinessEvents writes a synthetic entry when a pod's Ready
+	// condition changes, so readiness flapping shows up among its logs.
+	// Default: false.
+	ReadinessEvents bool
+
+	// LifecycleEvents writes entries about the collector itself to the
+	// storage.LifecycleNamespace namespace: when it starts and stops,
+	// when streams open and close and why, and a summary of dropped data
+	// each minute any was dropped.
+	// Default: true.
+	LifecycleEvents bool
+
+	// MetricsEnabled serves Prometheus metrics on /metrics at MetricsAddr.
+	// Default: true.
+	MetricsEnabled bool
+
+	// MetricsAddr is the listen address for the metrics endpoint.
+	// Default: ":9090".
+	MetricsAddr string
+
+	// HealthEnabled serves /healthz, /readyz and /debug/stats at
+	// HealthAddr, for liveness and readiness probes.
+	// Default: true.
+	HealthEnabled bool
+
+	// HealthAddr is the listen address for the health endpoints.
+	// Default: ":8081".
+	HealthAddr string
+
+	// StatusInterval is how often the collector reports its health to
+	// the server, which lists it on /api/collectors. Only remote storage
+	// accepts reports.
+	// Default: 30s. 0 disables reports.
+	StatusInterval time.Duration
+
+	// LogFiles are glob patterns of log files to tail instead of
+	// streaming logs from the API server, e.g. /var/log/containers/*.log.
+	// Pod discovery is off in this mode, so pod labels, annotations and
+	// container events aren't collected. Uses KUBELOGS_LOG_FILES.
+	// Default: empty (stream from the API server).
+	LogFiles []string
+
+	// FilePollInterval is how often tailed files are checked for new
+	// lines, new files and rotation.
+	// Default: 1s.
+	FilePollInterval time.Duration
 }
 
 // DefaultConfig returns sensible defaults for <256MB RAM constraint.
@@ -59,11 +279,35 @@ func DefaultConfig() Config {
 		MaxConcurrentStreams: 100,
 		BatchSize:            500,
 		BatchTimeout:         5 * time.Second,
+		RetryMinBackoff:      time.Second,
+		RetryMaxBackoff:      30 * time.Second,
+		RetryQueueSize:       100,
+		RetryDropPolicy:      DropOldest,
+		SpoolMaxBytes:        512 << 20,
+		CircuitThreshold:     5,
+		CircuitTimeout:       30 * time.Second,
 		StreamBufferSize:     1000,
 		ExcludeNamespaces:    []string{"kube-system"},
 		ShutdownTimeout:      30 * time.Second,
 		SinceTime:            time.Now().Add(-(15 * time.Minute)),
 		StreamIdleTimeout:    5 * time.Minute,
+		StreamStartJitter:    5 * time.Second,
+		StreamRampUp:         time.Minute,
+		AdaptiveStreams:      true,
+		CatchUpLag:           time.Minute,
+		CatchUpBatchFactor:   4,
+		DiscoveryResync:      30 * time.Second,
+		DiscoveryEventBuffer: 1000,
+		MultilineMaxLines:    500,
+		MultilineMaxWait:     2 * time.Second,
+		TerminationEvents:    true,
+		LifecycleEvents:      true,
+		MetricsEnabled:       true,
+		MetricsAddr:          ":9090",
+		HealthEnabled:        true,
+		HealthAddr:           ":8081",
+		StatusInterval:       30 * time.Second,
+		FilePollInterval:     time.Second,
 	}
 }
 
@@ -72,6 +316,7 @@ func ConfigFromEnv() Config {
 	cfg := DefaultConfig()
 
 	cfg.NodeName = os.Getenv("NODE_NAME")
+	cfg.ClusterName = strings.TrimSpace(os.Getenv("KUBELOGS_CLUSTER_NAME"))
 
 	if v := os.Getenv("KUBELOGS_MAX_STREAMS"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 {
@@ -91,6 +336,48 @@ func ConfigFromEnv() Config {
 		}
 	}
 
+	if v := os.Getenv("KUBELOGS_RETRY_MIN_BACKOFF"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.RetryMinBackoff = d
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_RETRY_MAX_BACKOFF"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.RetryMaxBackoff = d
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_RETRY_QUEUE_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.RetryQueueSize = n
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_RETRY_DROP_POLICY"); v != "" {
+		cfg.RetryDropPolicy = DropPolicy(strings.TrimSpace(v))
+	}
+
+	cfg.SpoolDir = strings.TrimSpace(os.Getenv("KUBELOGS_SPOOL_DIR"))
+
+	if v := os.Getenv("KUBELOGS_SPOOL_MAX_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			cfg.SpoolMaxBytes = n
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_CIRCUIT_THRESHOLD"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.CircuitThreshold = n
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_CIRCUIT_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.CircuitTimeout = d
+		}
+	}
+
 	if v := os.Getenv("KUBELOGS_STREAM_BUFFER"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 {
 			cfg.StreamBufferSize = n
@@ -111,6 +398,47 @@ func ConfigFromEnv() Config {
 		cfg.IncludeNamespaces = splitTrim(v, ",")
 	}
 
+	cfg.ExcludeNamespaceRegex = strings.TrimSpace(os.Getenv("KUBELOGS_EXCLUDE_NS_REGEX"))
+	cfg.IncludeNamespaceRegex = strings.TrimSpace(os.Getenv("KUBELOGS_INCLUDE_NS_REGEX"))
+	cfg.ExcludePodSelector = strings.TrimSpace(os.Getenv("KUBELOGS_EXCLUDE_POD_SELECTOR"))
+	cfg.IncludePodSelector = strings.TrimSpace(os.Getenv("KUBELOGS_INCLUDE_POD_SELECTOR"))
+
+	if v := os.Getenv("KUBELOGS_INCLUDE_LABELS"); v != "" {
+		cfg.IncludeLabels = splitTrim(v, ",")
+	}
+
+	if v := os.Getenv("KUBELOGS_INCLUDE_ANNOTATIONS"); v != "" {
+		cfg.IncludeAnnotations = splitTrim(v, ",")
+	}
+
+	cfg.MultilineStart = os.Getenv("KUBELOGS_MULTILINE_START")
+
+	if v := os.Getenv("KUBELOGS_MULTILINE_MAX_LINES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.MultilineMaxLines = n
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_MULTILINE_MAX_WAIT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.MultilineMaxWait = d
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_RATE_LIMIT"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 {
+			cfg.RateLimit = f
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_SAMPLE_DEBUG"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.SampleDebug = n
+		}
+	}
+
+	cfg.SeverityRulesFile = strings.TrimSpace(os.Getenv("KUBELOGS_SEVERITY_RULES_FILE"))
+
 	if v := os.Getenv("KUBELOGS_SHUTDOWN_TIMEOUT"); v != "" {
 		if d, err := time.ParseDuration(v); err == nil && d > 0 {
 			cfg.ShutdownTimeout = d
@@ -123,6 +451,94 @@ func ConfigFromEnv() Config {
 		}
 	}
 
+	if v := os.Getenv("KUBELOGS_STREAM_START_JITTER"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.StreamStartJitter = d
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_STREAM_RAMP_UP"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.StreamRampUp = d
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_ADAPTIVE_STREAMS"); v == "false" {
+		cfg.AdaptiveStreams = false
+	}
+
+	if v := os.Getenv("KUBELOGS_CATCH_UP_LAG"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.CatchUpLag = d
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_CATCH_UP_BATCH_FACTOR"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.CatchUpBatchFactor = n
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_DISCOVERY_RESYNC"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.DiscoveryResync = d
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_DISCOVERY_EVENT_BUFFER"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.DiscoveryEventBuffer = n
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_TERMINATION_EVENTS"); v == "false" {
+		cfg.TerminationEvents = false
+	}
+
+	if v := os.Getenv("KUBELOGS_READINESS_EVENTS"); v == "true" {
+		cfg.ReadinessEvents = true
+	}
+
+	if v := os.Getenv("KUBELOGS_LIFECYCLE_EVENTS"); v == "false" {
+		cfg.LifecycleEvents = false
+	}
+
+	if v := os.Getenv("KUBELOGS_METRICS_ENABLED"); v == "false" {
+		cfg.MetricsEnabled = false
+	}
+
+	if v := os.Getenv("KUBELOGS_METRICS_ADDR"); v != "" {
+		cfg.MetricsAddr = v
+	}
+
+	if v := os.Getenv("KUBELOGS_HEALTH_ENABLED"); v == "false" {
+		cfg.HealthEnabled = false
+	}
+
+	if v := os.Getenv("KUBELOGS_HEALTH_ADDR"); v != "" {
+		cfg.HealthAddr = v
+	}
+
+	if v := os.Getenv("KUBELOGS_STATUS_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.StatusInterval = d
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_LOG_FILES"); v != "" {
+		cfg.LogFiles = splitTrim(v, ",")
+		// Outside Kubernetes there may be no node name to pass in
+		if cfg.NodeName == "" {
+			cfg.NodeName, _ = os.Hostname()
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_FILE_POLL_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.FilePollInterval = d
+		}
+	}
+
 	return cfg
 }
 
@@ -140,6 +556,27 @@ func (c Config) Validate() error {
 	if c.BatchTimeout <= 0 {
 		return &ConfigError{Field: "BatchTimeout", Message: "must be positive"}
 	}
+	if c.RetryMinBackoff <= 0 {
+		return &ConfigError{Field: "RetryMinBackoff", Message: "must be positive"}
+	}
+	if c.RetryMaxBackoff < c.RetryMinBackoff {
+		return &ConfigError{Field: "RetryMaxBackoff", Message: "must be at least RetryMinBackoff"}
+	}
+	if c.RetryQueueSize <= 0 {
+		return &ConfigError{Field: "RetryQueueSize", Message: "must be positive"}
+	}
+	if !c.RetryDropPolicy.Valid() {
+		return &ConfigError{Field: "RetryDropPolicy", Message: "must be oldest, newest or severity"}
+	}
+	if c.SpoolDir != "" && c.SpoolMaxBytes <= 0 {
+		return &ConfigError{Field: "SpoolMaxBytes", Message: "must be positive"}
+	}
+	if c.CircuitThreshold <= 0 {
+		return &ConfigError{Field: "CircuitThreshold", Message: "must be positive"}
+	}
+	if c.CircuitTimeout <= 0 {
+		return &ConfigError{Field: "CircuitTimeout", Message: "must be positive"}
+	}
 	if c.StreamBufferSize <= 0 {
 		return &ConfigError{Field: "StreamBufferSize", Message: "must be positive"}
 	}
@@ -149,23 +586,116 @@ func (c Config) Validate() error {
 	if c.StreamIdleTimeout <= 0 {
 		return &ConfigError{Field: "StreamIdleTimeout", Message: "must be positive"}
 	}
+	if c.StreamStartJitter < 0 {
+		return &ConfigError{Field: "StreamStartJitter", Message: "must not be negative"}
+	}
+	if c.StreamRampUp < 0 {
+		return &ConfigError{Field: "StreamRampUp", Message: "must not be negative"}
+	}
+	if c.CatchUpLag < 0 {
+		return &ConfigError{Field: "CatchUpLag", Message: "must not be negative"}
+	}
+	if c.CatchUpBatchFactor < 1 || c.CatchUpBatchFactor > maxSlowdown {
+		return &ConfigError{Field: "CatchUpBatchFactor", Message: fmt.Sprintf("must be between 1 and %d", maxSlowdown)}
+	}
+	if c.StatusInterval < 0 {
+		return &ConfigError{Field: "StatusInterval", Message: "must not be negative"}
+	}
+	if c.DiscoveryResync < 0 {
+		return &ConfigError{Field: "DiscoveryResync", Message: "must not be negative"}
+	}
+	if c.DiscoveryEventBuffer <= 0 {
+		return &ConfigError{Field: "DiscoveryEventBuffer", Message: "must be positive"}
+	}
+	for _, p := range c.LogFiles {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return &ConfigError{Field: "LogFiles", Message: fmt.Sprintf("invalid pattern %q", p)}
+		}
+	}
+	if len(c.LogFiles) > 0 && c.FilePollInterval <= 0 {
+		return &ConfigError{Field: "FilePollInterval", Message: "must be positive"}
+	}
+	for _, ns := range slices.Concat(c.ExcludeNamespaces, c.IncludeNamespaces) {
+		if _, err := path.Match(ns, ""); err != nil {
+			return &ConfigError{Field: "Namespaces", Message: fmt.Sprintf("invalid pattern %q", ns)}
+		}
+	}
+	if _, err := regexp.Compile(c.ExcludeNamespaceRegex); err != nil {
+		return &ConfigError{Field: "ExcludeNamespaceRegex", Message: err.Error()}
+	}
+	if _, err := regexp.Compile(c.IncludeNamespaceRegex); err != nil {
+		return &ConfigError{Field: "IncludeNamespaceRegex", Message: err.Error()}
+	}
+	if _, err := parseSelector(c.ExcludePodSelector); err != nil {
+		return &ConfigError{Field: "ExcludePodSelector", Message: err.Error()}
+	}
+	if _, err := parseSelector(c.IncludePodSelector); err != nil {
+		return &ConfigError{Field: "IncludePodSelector", Message: err.Error()}
+	}
+	if c.MultilineStart != "" {
+		if _, err := regexp.Compile(c.MultilineStart); err != nil {
+			return &ConfigError{Field: "MultilineStart", Message: err.Error()}
+		}
+		if c.MultilineMaxLines <= 0 {
+			return &ConfigError{Field: "MultilineMaxLines", Message: "must be positive"}
+		}
+		if c.MultilineMaxWait <= 0 {
+			return &ConfigError{Field: "MultilineMaxWait", Message: "must be positive"}
+		}
+	}
+	if c.RateLimit < 0 {
+		return &ConfigError{Field: "RateLimit", Message: "must not be negative"}
+	}
+	if c.SampleDebug < 0 {
+		return &ConfigError{Field: "SampleDebug", Message: "must not be negative"}
+	}
+	if _, err := NewSeverityRules(c.SeverityRules); err != nil {
+		return &ConfigError{Field: "SeverityRules", Message: err.Error()}
+	}
 	return nil
 }
 
 // ShouldCollect returns true if logs from the given namespace should be collected.
+// It is called once per container event, so the patterns aren't cached.
 func (c Config) ShouldCollect(namespace string) bool {
 	// Check exclusions first
-	if slices.Contains(c.ExcludeNamespaces, namespace) {
+	if matchNamespace(c.ExcludeNamespaces, c.ExcludeNamespaceRegex, namespace) {
 		return false
 	}
 
-	// If include list is empty, collect all (except excluded)
-	if len(c.IncludeNamespaces) == 0 {
+	// If there's nothing to include, collect all (except excluded)
+	if len(c.IncludeNamespaces) == 0 && c.IncludeNamespaceRegex == "" {
 		return true
 	}
 
-	// Check if in include list
-	return slices.Contains(c.IncludeNamespaces, namespace)
+	return matchNamespace(c.IncludeNamespaces, c.IncludeNamespaceRegex, namespace)
+}
+
+// matchNamespace reports whether namespace is one of names (which may be
+// globs) or matches expr, if not empty. Invalid patterns match nothing;
+// Validate rejects them.
+func matchNamespace(names []string, expr, namespace string) bool {
+	for _, name := range names {
+		if name == namespace {
+			return true
+		}
+		if ok, _ := path.Match(name, namespace); ok {
+			return true
+		}
+	}
+	if expr == "" {
+		return false
+	}
+	ok, _ := regexp.MatchString(expr, namespace)
+	return ok
+}
+
+// parseSelector parses a pod label selector, or returns nil if s is empty.
+func parseSelector(s string) (labels.Selector, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return labels.Parse(s)
 }
 
 // ConfigError represents a configuration validation error.
@@ -26,9 +26,45 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.StreamIdleTimeout != 5*time.Minute {
 		t.Errorf("StreamIdleTimeout = %v, want 5m", cfg.StreamIdleTimeout)
 	}
+	if cfg.RetryMinBackoff != time.Second || cfg.RetryMaxBackoff != 30*time.Second || cfg.RetryQueueSize != 100 {
+		t.Errorf("Retry = %v, %v, %d, want 1s, 30s, 100", cfg.RetryMinBackoff, cfg.RetryMaxBackoff, cfg.RetryQueueSize)
+	}
+	if cfg.RetryDropPolicy != DropOldest {
+		t.Errorf("RetryDropPolicy = %q, want oldest", cfg.RetryDropPolicy)
+	}
+	if cfg.CircuitThreshold != 5 || cfg.CircuitTimeout != 30*time.Second {
+		t.Errorf("Circuit = %d, %v, want 5, 30s", cfg.CircuitThreshold, cfg.CircuitTimeout)
+	}
+	if cfg.StreamStartJitter != 5*time.Second || cfg.StreamRampUp != time.Minute {
+		t.Errorf("Stream start = %v, %v, want 5s, 1m", cfg.StreamStartJitter, cfg.StreamRampUp)
+	}
+	if cfg.DiscoveryResync != 30*time.Second || cfg.DiscoveryEventBuffer != 1000 {
+		t.Errorf("Discovery = %v, %d, want 30s, 1000", cfg.DiscoveryResync, cfg.DiscoveryEventBuffer)
+	}
 	if len(cfg.ExcludeNamespaces) != 1 || cfg.ExcludeNamespaces[0] != "kube-system" {
 		t.Errorf("ExcludeNamespaces = %v, want [kube-system]", cfg.ExcludeNamespaces)
 	}
+	if !cfg.TerminationEvents {
+		t.Errorf("TerminationEvents = false, want true")
+	}
+	if cfg.ReadinessEvents {
+		t.Errorf("ReadinessEvents = true, want false")
+	}
+	if !cfg.LifecycleEvents {
+		t.Errorf("LifecycleEvents = false, want true")
+	}
+	if cfg.MultilineStart != "" || cfg.MultilineMaxLines != 500 || cfg.MultilineMaxWait != 2*time.Second {
+		t.Errorf("Multiline = %q, %d, %v, want disabled, 500, 2s", cfg.MultilineStart, cfg.MultilineMaxLines, cfg.MultilineMaxWait)
+	}
+	if !cfg.MetricsEnabled || cfg.MetricsAddr != ":9090" {
+		t.Errorf("MetricsEnabled, MetricsAddr = %v, %q, want true, :9090", cfg.MetricsEnabled, cfg.MetricsAddr)
+	}
+	if len(cfg.LogFiles) != 0 || cfg.FilePollInterval != time.Second {
+		t.Errorf("LogFiles, FilePollInterval = %v, %v, want none, 1s", cfg.LogFiles, cfg.FilePollInterval)
+	}
+	if cfg.SpoolDir != "" || cfg.SpoolMaxBytes != 512<<20 {
+		t.Errorf("SpoolDir, SpoolMaxBytes = %q, %d, want none, 512MiB", cfg.SpoolDir, cfg.SpoolMaxBytes)
+	}
 }
 
 func TestConfig_Validate(t *testing.T) {
@@ -44,9 +80,17 @@ func TestConfig_Validate(t *testing.T) {
 				MaxConcurrentStreams: 100,
 				BatchSize:            500,
 				BatchTimeout:         5 * time.Second,
+				RetryMinBackoff:      time.Second,
+				RetryMaxBackoff:      30 * time.Second,
+				RetryQueueSize:       100,
+				RetryDropPolicy:      DropOldest,
+				CircuitThreshold:     5,
+				CircuitTimeout:       30 * time.Second,
 				StreamBufferSize:     1000,
 				ShutdownTimeout:      30 * time.Second,
 				StreamIdleTimeout:    5 * time.Minute,
+				DiscoveryEventBuffer: 1000,
+				CatchUpBatchFactor:   4,
 			},
 			wantErr: false,
 		},
@@ -59,6 +103,8 @@ func TestConfig_Validate(t *testing.T) {
 				StreamBufferSize:     1000,
 				ShutdownTimeout:      30 * time.Second,
 				StreamIdleTimeout:    5 * time.Minute,
+				DiscoveryEventBuffer: 1000,
+				CatchUpBatchFactor:   4,
 			},
 			wantErr: true,
 		},
@@ -72,6 +118,8 @@ func TestConfig_Validate(t *testing.T) {
 				StreamBufferSize:     1000,
 				ShutdownTimeout:      30 * time.Second,
 				StreamIdleTimeout:    5 * time.Minute,
+				DiscoveryEventBuffer: 1000,
+				CatchUpBatchFactor:   4,
 			},
 			wantErr: true,
 		},
@@ -85,6 +133,237 @@ func TestConfig_Validate(t *testing.T) {
 				StreamBufferSize:     1000,
 				ShutdownTimeout:      30 * time.Second,
 				StreamIdleTimeout:    5 * time.Minute,
+				DiscoveryEventBuffer: 1000,
+				CatchUpBatchFactor:   4,
+			},
+			wantErr: true,
+		},
+		{
+			name: "max backoff below min backoff",
+			cfg: Config{
+				NodeName:             "node-1",
+				MaxConcurrentStreams: 100,
+				BatchSize:            500,
+				BatchTimeout:         5 * time.Second,
+				RetryMinBackoff:      time.Minute,
+				RetryMaxBackoff:      30 * time.Second,
+				RetryQueueSize:       100,
+				RetryDropPolicy:      DropOldest,
+				CircuitThreshold:     5,
+				CircuitTimeout:       30 * time.Second,
+				StreamBufferSize:     1000,
+				ShutdownTimeout:      30 * time.Second,
+				StreamIdleTimeout:    5 * time.Minute,
+				DiscoveryEventBuffer: 1000,
+				CatchUpBatchFactor:   4,
+			},
+			wantErr: true,
+		},
+		{
+			name: "zero circuit threshold",
+			cfg: Config{
+				NodeName:             "node-1",
+				MaxConcurrentStreams: 100,
+				BatchSize:            500,
+				BatchTimeout:         5 * time.Second,
+				RetryMinBackoff:      time.Second,
+				RetryMaxBackoff:      30 * time.Second,
+				RetryQueueSize:       100,
+				RetryDropPolicy:      DropOldest,
+				CircuitTimeout:       30 * time.Second,
+				StreamBufferSize:     1000,
+				ShutdownTimeout:      30 * time.Second,
+				StreamIdleTimeout:    5 * time.Minute,
+				DiscoveryEventBuffer: 1000,
+				CatchUpBatchFactor:   4,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown drop policy",
+			cfg: Config{
+				NodeName:             "node-1",
+				MaxConcurrentStreams: 100,
+				BatchSize:            500,
+				BatchTimeout:         5 * time.Second,
+				RetryMinBackoff:      time.Second,
+				RetryMaxBackoff:      30 * time.Second,
+				RetryQueueSize:       100,
+				RetryDropPolicy:      "random",
+				CircuitThreshold:     5,
+				CircuitTimeout:       30 * time.Second,
+				StreamBufferSize:     1000,
+				ShutdownTimeout:      30 * time.Second,
+				StreamIdleTimeout:    5 * time.Minute,
+				DiscoveryEventBuffer: 1000,
+				CatchUpBatchFactor:   4,
+			},
+			wantErr: true,
+		},
+		{
+			name: "zero discovery event buffer",
+			cfg: Config{
+				NodeName:             "node-1",
+				MaxConcurrentStreams: 100,
+				BatchSize:            500,
+				BatchTimeout:         5 * time.Second,
+				RetryMinBackoff:      time.Second,
+				RetryMaxBackoff:      30 * time.Second,
+				RetryQueueSize:       100,
+				RetryDropPolicy:      DropOldest,
+				CircuitThreshold:     5,
+				CircuitTimeout:       30 * time.Second,
+				StreamBufferSize:     1000,
+				ShutdownTimeout:      30 * time.Second,
+				StreamIdleTimeout:    5 * time.Minute,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid namespace regex",
+			cfg: Config{
+				NodeName:              "node-1",
+				MaxConcurrentStreams:  100,
+				BatchSize:             500,
+				BatchTimeout:          5 * time.Second,
+				RetryMinBackoff:       time.Second,
+				RetryMaxBackoff:       30 * time.Second,
+				RetryQueueSize:        100,
+				RetryDropPolicy:       DropOldest,
+				CircuitThreshold:      5,
+				CircuitTimeout:        30 * time.Second,
+				StreamBufferSize:      1000,
+				ShutdownTimeout:       30 * time.Second,
+				StreamIdleTimeout:     5 * time.Minute,
+				DiscoveryEventBuffer:  1000,
+				ExcludeNamespaceRegex: "^ci-(",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid pod selector",
+			cfg: Config{
+				NodeName:             "node-1",
+				MaxConcurrentStreams: 100,
+				BatchSize:            500,
+				BatchTimeout:         5 * time.Second,
+				RetryMinBackoff:      time.Second,
+				RetryMaxBackoff:      30 * time.Second,
+				RetryQueueSize:       100,
+				RetryDropPolicy:      DropOldest,
+				CircuitThreshold:     5,
+				CircuitTimeout:       30 * time.Second,
+				StreamBufferSize:     1000,
+				ShutdownTimeout:      30 * time.Second,
+				StreamIdleTimeout:    5 * time.Minute,
+				DiscoveryEventBuffer: 1000,
+				ExcludePodSelector:   "team in (a",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid namespace glob",
+			cfg: Config{
+				NodeName:             "node-1",
+				MaxConcurrentStreams: 100,
+				BatchSize:            500,
+				BatchTimeout:         5 * time.Second,
+				RetryMinBackoff:      time.Second,
+				RetryMaxBackoff:      30 * time.Second,
+				RetryQueueSize:       100,
+				RetryDropPolicy:      DropOldest,
+				CircuitThreshold:     5,
+				CircuitTimeout:       30 * time.Second,
+				StreamBufferSize:     1000,
+				ShutdownTimeout:      30 * time.Second,
+				StreamIdleTimeout:    5 * time.Minute,
+				DiscoveryEventBuffer: 1000,
+				CatchUpBatchFactor:   4,
+				IncludeNamespaces:    []string{"team-[a"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "catch-up batch factor too large",
+			cfg: Config{
+				NodeName:             "node-1",
+				MaxConcurrentStreams: 100,
+				BatchSize:            500,
+				BatchTimeout:         5 * time.Second,
+				StreamBufferSize:     1000,
+				ShutdownTimeout:      30 * time.Second,
+				StreamIdleTimeout:    5 * time.Minute,
+				DiscoveryEventBuffer: 1000,
+				CatchUpBatchFactor:   16,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid multiline pattern",
+			cfg: Config{
+				NodeName:             "node-1",
+				MaxConcurrentStreams: 100,
+				BatchSize:            500,
+				BatchTimeout:         5 * time.Second,
+				RetryMinBackoff:      time.Second,
+				RetryMaxBackoff:      30 * time.Second,
+				RetryQueueSize:       100,
+				RetryDropPolicy:      DropOldest,
+				CircuitThreshold:     5,
+				CircuitTimeout:       30 * time.Second,
+				StreamBufferSize:     1000,
+				ShutdownTimeout:      30 * time.Second,
+				StreamIdleTimeout:    5 * time.Minute,
+				DiscoveryEventBuffer: 1000,
+				CatchUpBatchFactor:   4,
+				MultilineStart:       "^(",
+				MultilineMaxLines:    500,
+				MultilineMaxWait:     2 * time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid log file pattern",
+			cfg: Config{
+				NodeName:             "node-1",
+				MaxConcurrentStreams: 100,
+				BatchSize:            500,
+				BatchTimeout:         5 * time.Second,
+				RetryMinBackoff:      time.Second,
+				RetryMaxBackoff:      30 * time.Second,
+				RetryQueueSize:       100,
+				RetryDropPolicy:      DropOldest,
+				CircuitThreshold:     5,
+				CircuitTimeout:       30 * time.Second,
+				StreamBufferSize:     1000,
+				ShutdownTimeout:      30 * time.Second,
+				StreamIdleTimeout:    5 * time.Minute,
+				DiscoveryEventBuffer: 1000,
+				CatchUpBatchFactor:   4,
+				LogFiles:             []string{"/var/log/containers/[a.log"},
+				FilePollInterval:     time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid severity rule",
+			cfg: Config{
+				NodeName:             "node-1",
+				MaxConcurrentStreams: 100,
+				BatchSize:            500,
+				BatchTimeout:         5 * time.Second,
+				RetryMinBackoff:      time.Second,
+				RetryMaxBackoff:      30 * time.Second,
+				RetryQueueSize:       100,
+				RetryDropPolicy:      DropOldest,
+				CircuitThreshold:     5,
+				CircuitTimeout:       30 * time.Second,
+				StreamBufferSize:     1000,
+				ShutdownTimeout:      30 * time.Second,
+				StreamIdleTimeout:    5 * time.Minute,
+				DiscoveryEventBuffer: 1000,
+				CatchUpBatchFactor:   4,
+				SeverityRules:        []SeverityRule{{Match: "5xx", Severity: "bad"}},
 			},
 			wantErr: true,
 		},
@@ -148,6 +427,64 @@ func TestConfig_ShouldCollect(t *testing.T) {
 			namespace: "kube-system",
 			want:      false,
 		},
+		{
+			name: "exclude glob",
+			cfg: Config{
+				ExcludeNamespaces: []string{"kube-system", "ci-*"},
+			},
+			namespace: "ci-build-4711",
+			want:      false,
+		},
+		{
+			name: "exclude regex",
+			cfg: Config{
+				ExcludeNamespaceRegex: "^ci-.*",
+			},
+			namespace: "ci-build-4711",
+			want:      false,
+		},
+		{
+			name: "exclude regex allows others",
+			cfg: Config{
+				ExcludeNamespaceRegex: "^ci-.*",
+			},
+			namespace: "prod-ci",
+			want:      true,
+		},
+		{
+			name: "include regex",
+			cfg: Config{
+				IncludeNamespaceRegex: "^team-(a|b)$",
+			},
+			namespace: "team-b",
+			want:      true,
+		},
+		{
+			name: "include regex excludes other",
+			cfg: Config{
+				IncludeNamespaceRegex: "^team-(a|b)$",
+			},
+			namespace: "team-c",
+			want:      false,
+		},
+		{
+			name: "include list or regex",
+			cfg: Config{
+				IncludeNamespaces:     []string{"default"},
+				IncludeNamespaceRegex: "^team-",
+			},
+			namespace: "default",
+			want:      true,
+		},
+		{
+			name: "exclude regex takes precedence over include glob",
+			cfg: Config{
+				IncludeNamespaces:     []string{"team-*"},
+				ExcludeNamespaceRegex: "-scratch$",
+			},
+			namespace: "team-a-scratch",
+			want:      false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -3,15 +3,21 @@ package collector
 import (
 	"context"
 	"log/slog"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
 )
 
 // PodEventType represents the type of pod lifecycle event.
@@ -20,28 +26,119 @@ type PodEventType int
 const (
 	ContainerStarted PodEventType = iota
 	ContainerStopped
+	PodReadinessChanged
 )
 
 // PodEvent represents a pod lifecycle event.
 type PodEvent struct {
 	Type      PodEventType
 	Container ContainerRef
+
+	// Termination is set on ContainerStopped (or ContainerStarted after a
+	// restart that was never seen stopped) when the previous run of the
+	// container failed.
+	Termination *Termination
+
+	// Readiness is set on PodReadinessChanged. Container is then the
+	// first unready container, or the pod's first container.
+	Readiness *Readiness
+}
+
+// Readiness describes a change of a pod's Ready condition.
+type Readiness struct {
+	Ready   bool
+	Reason  string // e.g. "ContainersNotReady"
+	Message string
+	Since   time.Time
+}
+
+// Termination describes a container that exited with a non-zero code or
+// was OOM killed.
+type Termination struct {
+	ExitCode   int32
+	Signal     int32
+	Reason     string // e.g. "Error", "OOMKilled"
+	Message    string // Termination message written by the container
+	FinishedAt time.Time
+}
+
+// failedTermination returns the termination of the container's run with
+// the given ID if it failed. Terminations during pod deletion are
+// expected and not reported, unless the container ran out of memory.
+func failedTermination(pod *corev1.Pod, cs corev1.ContainerStatus, containerID string) *Termination {
+	t := cs.State.Terminated
+	if t == nil || t.ContainerID != containerID {
+		t = cs.LastTerminationState.Terminated
+	}
+	if t == nil || t.ContainerID != containerID {
+		return nil
+	}
+	if t.Reason != "OOMKilled" && (t.ExitCode == 0 || pod.DeletionTimestamp != nil) {
+		return nil
+	}
+
+	finishedAt := t.FinishedAt.Time
+	if finishedAt.IsZero() {
+		finishedAt = time.Now()
+	}
+	return &Termination{
+		ExitCode:   t.ExitCode,
+		Signal:     t.Signal,
+		Reason:     t.Reason,
+		Message:    t.Message,
+		FinishedAt: finishedAt,
+	}
 }
 
 // PodDiscovery watches for pod changes on the current node.
 type PodDiscovery struct {
-	nodeName  string
-	clientset kubernetes.Interface
-	events    chan PodEvent
+	nodeName     string
+	clientset    kubernetes.Interface
+	resyncPeriod time.Duration // 0 disables resyncs
+	events       chan PodEvent
 
 	// Track container states to detect restarts
 	containerStates map[string]containerState
 	mu              sync.RWMutex
 
+	// Ready condition of each pod by UID, to detect changes
+	podReady map[string]bool
+
+	// Pod labels and annotations copied into entry attributes, as
+	// label.<key> and annotation.<key>, along with the pod's workload
+	includeLabels      []string
+	includeAnnotations []string
+	podAttrs           map[string]map[string]string // By pod UID
+
+	// Line limits of the collector, and of the pods overriding them with
+	// annotations, by pod UID
+	limits    LineLimits
+	podLimits map[string]LineLimits
+
+	// Label selectors of the pods to skip and, if set, the only pods to
+	// collect from. Nil selects nothing to skip, or every pod
+	excludeSelector labels.Selector
+	includeSelector labels.Selector
+
 	factory  informers.SharedInformerFactory
 	informer cache.SharedIndexInformer
 
-	ctx context.Context
+	ctx    context.Context
+	synced atomic.Bool // Set once the informer's cache has synced
+
+	// Metrics
+	eventsBlocked  atomic.Int64 // Events that found the channel full
+	eventsDropped  atomic.Int64 // Events given up on after waiting
+	resyncsSkipped atomic.Int64 // Resync updates of unchanged pods
+}
+
+// DiscoveryStats contains pod discovery statistics.
+type DiscoveryStats struct {
+	QueuedEvents   int // Events waiting to be handled
+	QueueCapacity  int
+	BlockedEvents  int64
+	DroppedEvents  int64
+	SkippedResyncs int64
 }
 
 // containerState tracks a container's running state.
@@ -51,13 +148,19 @@ type containerState struct {
 	containerID  string
 }
 
-// NewPodDiscovery creates a pod watcher for the given node.
-func NewPodDiscovery(clientset kubernetes.Interface, nodeName string) *PodDiscovery {
+// NewPodDiscovery creates a pod watcher for the given node. The informer
+// re-delivers every pod each resyncPeriod (0 disables this), and up to
+// eventBuffer events are queued for the collector.
+func NewPodDiscovery(clientset kubernetes.Interface, nodeName string, resyncPeriod time.Duration, eventBuffer int) *PodDiscovery {
 	return &PodDiscovery{
 		nodeName:        nodeName,
 		clientset:       clientset,
-		events:          make(chan PodEvent, 1000), // Increased from 100 to handle high pod churn
+		resyncPeriod:    resyncPeriod,
+		events:          make(chan PodEvent, eventBuffer),
 		containerStates: make(map[string]containerState),
+		podReady:        make(map[string]bool),
+		podAttrs:        make(map[string]map[string]string),
+		podLimits:       make(map[string]LineLimits),
 	}
 }
 
@@ -77,7 +180,7 @@ func (d *PodDiscovery) Start(ctx context.Context) error {
 
 	d.factory = informers.NewSharedInformerFactoryWithOptions(
 		d.clientset,
-		30*time.Second, // Resync period
+		d.resyncPeriod,
 		informers.WithTweakListOptions(tweakListOptions),
 	)
 
@@ -103,6 +206,7 @@ func (d *PodDiscovery) Start(ctx context.Context) error {
 	if !cache.WaitForCacheSync(syncCtx.Done(), d.informer.HasSynced) {
 		return &DiscoveryError{Message: "failed to sync pod cache"}
 	}
+	d.synced.Store(true)
 
 	slog.Info("pod discovery started", "node", d.nodeName)
 
@@ -117,7 +221,13 @@ func (d *PodDiscovery) onPodAdd(obj interface{}) {
 		return
 	}
 
+	if !d.selected(pod) {
+		return
+	}
+
+	d.processMetadata(pod)
 	d.processContainerStatuses(pod)
+	d.processReadiness(pod)
 }
 
 func (d *PodDiscovery) onPodUpdate(oldObj, newObj interface{}) {
@@ -126,7 +236,24 @@ func (d *PodDiscovery) onPodUpdate(oldObj, newObj interface{}) {
 		return
 	}
 
+	// Resyncs re-deliver pods that haven't changed. The tracked states
+	// already match them, so skip the work (and the lock) for every pod
+	// on the node each period
+	if old, ok := oldObj.(*corev1.Pod); ok && old.ResourceVersion != "" && old.ResourceVersion == pod.ResourceVersion {
+		d.resyncsSkipped.Add(1)
+		return
+	}
+
+	// A pod relabeled out of the selection stops being collected from,
+	// and one relabeled into it starts
+	if !d.selected(pod) {
+		d.forgetPod(pod)
+		return
+	}
+
+	d.processMetadata(pod)
 	d.processContainerStatuses(pod)
+	d.processReadiness(pod)
 }
 
 func (d *PodDiscovery) onPodDelete(obj interface{}) {
@@ -143,6 +270,12 @@ func (d *PodDiscovery) onPodDelete(obj interface{}) {
 		}
 	}
 
+	d.mu.Lock()
+	delete(d.podReady, string(pod.UID))
+	delete(d.podAttrs, string(pod.UID))
+	delete(d.podLimits, string(pod.UID))
+	d.mu.Unlock()
+
 	// Emit stopped events for all containers
 	for _, cs := range pod.Status.ContainerStatuses {
 		ref := ContainerRef{
@@ -163,6 +296,45 @@ func (d *PodDiscovery) onPodDelete(obj interface{}) {
 	}
 }
 
+// selected reports whether pod's labels match the include selector, if
+// any, and not the exclude selector.
+func (d *PodDiscovery) selected(pod *corev1.Pod) bool {
+	set := labels.Set(pod.Labels)
+	if d.excludeSelector != nil && d.excludeSelector.Matches(set) {
+		return false
+	}
+	return d.includeSelector == nil || d.includeSelector.Matches(set)
+}
+
+// forgetPod stops the running containers of a pod no longer selected and
+// drops its tracked state, so it is seen as new if selected again.
+func (d *PodDiscovery) forgetPod(pod *corev1.Pod) {
+	d.mu.Lock()
+	delete(d.podReady, string(pod.UID))
+	delete(d.podAttrs, string(pod.UID))
+	delete(d.podLimits, string(pod.UID))
+	var stopped []ContainerRef
+	for _, cs := range pod.Status.ContainerStatuses {
+		ref := ContainerRef{
+			Namespace:     pod.Namespace,
+			PodName:       pod.Name,
+			PodUID:        string(pod.UID),
+			ContainerName: cs.Name,
+		}
+		if prev, ok := d.containerStates[ref.Key()]; ok {
+			delete(d.containerStates, ref.Key())
+			if prev.running {
+				stopped = append(stopped, ref)
+			}
+		}
+	}
+	d.mu.Unlock()
+
+	for _, ref := range stopped {
+		d.emitEvent(PodEvent{Type: ContainerStopped, Container: ref})
+	}
+}
+
 func (d *PodDiscovery) processContainerStatuses(pod *corev1.Pod) {
 	for _, cs := range pod.Status.ContainerStatuses {
 		ref := ContainerRef{
@@ -188,9 +360,15 @@ func (d *PodDiscovery) processContainerStatuses(pod *corev1.Pod) {
 			}
 			d.mu.Unlock()
 
+			// A restart between two updates skips the stopped state
+			var termination *Termination
+			if exists && prev.running {
+				termination = failedTermination(pod, cs, prev.containerID)
+			}
 			d.emitEvent(PodEvent{
-				Type:      ContainerStarted,
-				Container: ref,
+				Type:        ContainerStarted,
+				Container:   ref,
+				Termination: termination,
 			})
 		} else if !isRunning && exists && prev.running {
 			// Container stopped
@@ -202,8 +380,9 @@ func (d *PodDiscovery) processContainerStatuses(pod *corev1.Pod) {
 			d.mu.Unlock()
 
 			d.emitEvent(PodEvent{
-				Type:      ContainerStopped,
-				Container: ref,
+				Type:        ContainerStopped,
+				Container:   ref,
+				Termination: failedTermination(pod, cs, prev.containerID),
 			})
 		} else {
 			// No state change or initial non-running state
@@ -219,6 +398,185 @@ func (d *PodDiscovery) processContainerStatuses(pod *corev1.Pod) {
 	}
 }
 
+// processMetadata records the pod's workload and included labels and
+// annotations. They are looked up as lines arrive, so changes to a
+// running pod's labels apply to its later lines.
+func (d *PodDiscovery) processMetadata(pod *corev1.Pod) {
+	attrs := make(map[string]string)
+	if w := podWorkload(pod); w != "" {
+		attrs[storage.WorkloadAttribute] = w
+	}
+	addJobAttributes(pod, attrs)
+	for _, k := range d.includeLabels {
+		if v, ok := pod.Labels[k]; ok {
+			attrs["label."+k] = v
+		}
+	}
+	for _, k := range d.includeAnnotations {
+		if v, ok := pod.Annotations[k]; ok {
+			attrs["annotation."+k] = v
+		}
+	}
+
+	limits, override := d.annotatedLimits(pod)
+
+	d.mu.Lock()
+	if len(attrs) == 0 {
+		delete(d.podAttrs, string(pod.UID))
+	} else {
+		d.podAttrs[string(pod.UID)] = attrs
+	}
+	if override {
+		d.podLimits[string(pod.UID)] = limits
+	} else {
+		delete(d.podLimits, string(pod.UID))
+	}
+	d.mu.Unlock()
+}
+
+// annotatedLimits returns the line limits of pod, and whether its
+// annotations override the collector's. Invalid values are ignored.
+func (d *PodDiscovery) annotatedLimits(pod *corev1.Pod) (LineLimits, bool) {
+	limits := d.limits
+	override := false
+	if v, ok := pod.Annotations[RateLimitAnnotation]; ok {
+		if rate, err := strconv.ParseFloat(v, 64); err == nil && rate >= 0 {
+			limits.Rate = rate
+			override = true
+		} else {
+			slog.Warn("ignoring invalid annotation", "pod", pod.Namespace+"/"+pod.Name, "annotation", RateLimitAnnotation, "value", v)
+		}
+	}
+	if v, ok := pod.Annotations[SampleDebugAnnotation]; ok {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			limits.SampleDebug = n
+			override = true
+		} else {
+			slog.Warn("ignoring invalid annotation", "pod", pod.Namespace+"/"+pod.Name, "annotation", SampleDebugAnnotation, "value", v)
+		}
+	}
+	return limits, override
+}
+
+// PodLimits returns the line limits of the pod with the given UID if
+// its annotations override the collector's.
+func (d *PodDiscovery) PodLimits(podUID string) (LineLimits, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	limits, ok := d.podLimits[podUID]
+	return limits, ok
+}
+
+// PodAttributes returns the workload and included labels and annotations
+// of the pod with the given UID, or nil if it has none. The map must not
+// be modified.
+func (d *PodDiscovery) PodAttributes(podUID string) map[string]string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.podAttrs[podUID]
+}
+
+// podWorkload returns the workload attribute of pod from its controller
+// owner reference, or "" for pods without one and static pods. Pods of a
+// Deployment's ReplicaSet are attributed to the Deployment, recognized
+// by the pod-template-hash the Deployment appends to the ReplicaSet's
+// name, and pods of a CronJob's Job to the CronJob, recognized by the
+// Job's name, so no owners need to be looked up.
+func podWorkload(pod *corev1.Pod) string {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil || owner.Kind == "Node" {
+		return ""
+	}
+	switch owner.Kind {
+	case "ReplicaSet":
+		if hash := pod.Labels["pod-template-hash"]; hash != "" {
+			if name, ok := strings.CutSuffix(owner.Name, "-"+hash); ok && name != "" {
+				return "deployment/" + name
+			}
+		}
+	case "Job":
+		if cronjob, _, ok := storage.CronJobRun(owner.Name); ok {
+			return "cronjob/" + cronjob
+		}
+	}
+	return strings.ToLower(owner.Kind) + "/" + owner.Name
+}
+
+// addJobAttributes adds the Job of a Job's pod to attrs, and the
+// CronJob that created the Job, if any. Retries of a run are pods of the
+// same Job, so the job attribute gathers all of them.
+func addJobAttributes(pod *corev1.Pod, attrs map[string]string) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil || owner.Kind != "Job" {
+		return
+	}
+	attrs[storage.JobAttribute] = owner.Name
+	if cronjob, _, ok := storage.CronJobRun(owner.Name); ok {
+		attrs[storage.CronJobAttribute] = cronjob
+	}
+}
+
+// processReadiness emits PodReadinessChanged when the pod's Ready
+// condition changes. The first state seen is not reported, since every
+// pod starts out not ready, and neither are pods being deleted.
+func (d *PodDiscovery) processReadiness(pod *corev1.Pod) {
+	var cond *corev1.PodCondition
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type == corev1.PodReady {
+			cond = &pod.Status.Conditions[i]
+			break
+		}
+	}
+	if cond == nil || pod.DeletionTimestamp != nil {
+		return
+	}
+
+	ready := cond.Status == corev1.ConditionTrue
+	uid := string(pod.UID)
+
+	d.mu.Lock()
+	prev, seen := d.podReady[uid]
+	d.podReady[uid] = ready
+	d.mu.Unlock()
+
+	if !seen || prev == ready {
+		return
+	}
+
+	container := ""
+	if len(pod.Spec.Containers) > 0 {
+		container = pod.Spec.Containers[0].Name
+	}
+	if !ready {
+		for _, cs := range pod.Status.ContainerStatuses {
+			if !cs.Ready {
+				container = cs.Name
+				break
+			}
+		}
+	}
+
+	since := cond.LastTransitionTime.Time
+	if since.IsZero() {
+		since = time.Now()
+	}
+	d.emitEvent(PodEvent{
+		Type: PodReadinessChanged,
+		Container: ContainerRef{
+			Namespace:     pod.Namespace,
+			PodName:       pod.Name,
+			PodUID:        uid,
+			ContainerName: container,
+		},
+		Readiness: &Readiness{
+			Ready:   ready,
+			Reason:  cond.Reason,
+			Message: cond.Message,
+			Since:   since,
+		},
+	})
+}
+
 func (d *PodDiscovery) emitEvent(event PodEvent) {
 	// Try non-blocking first
 	select {
@@ -228,6 +586,7 @@ func (d *PodDiscovery) emitEvent(event PodEvent) {
 	}
 
 	// Channel is full - block with timeout to avoid silent drops
+	d.eventsBlocked.Add(1)
 	slog.Warn("pod event channel full, waiting to emit",
 		"type", event.Type,
 		"container", event.Container.Key(),
@@ -237,11 +596,13 @@ func (d *PodDiscovery) emitEvent(event PodEvent) {
 	select {
 	case d.events <- event:
 	case <-d.ctx.Done():
+		d.eventsDropped.Add(1)
 		slog.Error("failed to emit pod event - context cancelled",
 			"type", event.Type,
 			"container", event.Container.Key(),
 		)
 	case <-time.After(5 * time.Second):
+		d.eventsDropped.Add(1)
 		slog.Error("failed to emit pod event - timeout after 5s",
 			"type", event.Type,
 			"container", event.Container.Key(),
@@ -249,6 +610,23 @@ func (d *PodDiscovery) emitEvent(event PodEvent) {
 	}
 }
 
+// Synced reports whether the pods on the node have been listed, so
+// events for all of them were emitted.
+func (d *PodDiscovery) Synced() bool {
+	return d.synced.Load()
+}
+
+// Stats returns current discovery statistics.
+func (d *PodDiscovery) Stats() DiscoveryStats {
+	return DiscoveryStats{
+		QueuedEvents:   len(d.events),
+		QueueCapacity:  cap(d.events),
+		BlockedEvents:  d.eventsBlocked.Load(),
+		DroppedEvents:  d.eventsDropped.Load(),
+		SkippedResyncs: d.resyncsSkipped.Load(),
+	}
+}
+
 // DiscoveryError represents a pod discovery error.
 type DiscoveryError struct {
 	Message string
@@ -0,0 +1,396 @@
+package collector
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+func testPod(statuses ...corev1.ContainerStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "uid-1"},
+		Status:     corev1.PodStatus{ContainerStatuses: statuses},
+	}
+}
+
+func runningStatus(id string) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name:        "app",
+		ContainerID: id,
+		State:       corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+	}
+}
+
+func TestPodDiscovery_Termination(t *testing.T) {
+	finished := metav1.NewTime(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	terminated := func(id string, code int32, reason string) *corev1.ContainerStateTerminated {
+		return &corev1.ContainerStateTerminated{ContainerID: id, ExitCode: code, Reason: reason, FinishedAt: finished}
+	}
+
+	tests := []struct {
+		name     string
+		next     corev1.ContainerStatus
+		deleting bool
+		wantType PodEventType
+		want     *Termination
+	}{
+		{
+			name:     "clean exit",
+			next:     corev1.ContainerStatus{Name: "app", ContainerID: "c1", State: corev1.ContainerState{Terminated: terminated("c1", 0, "Completed")}},
+			wantType: ContainerStopped,
+		},
+		{
+			name:     "error exit",
+			next:     corev1.ContainerStatus{Name: "app", ContainerID: "c1", State: corev1.ContainerState{Terminated: terminated("c1", 2, "Error")}},
+			wantType: ContainerStopped,
+			want:     &Termination{ExitCode: 2, Reason: "Error", FinishedAt: finished.Time},
+		},
+		{
+			name: "crash loop backoff",
+			next: corev1.ContainerStatus{Name: "app", ContainerID: "c1",
+				State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+				LastTerminationState: corev1.ContainerState{Terminated: terminated("c1", 1, "Error")}},
+			wantType: ContainerStopped,
+			want:     &Termination{ExitCode: 1, Reason: "Error", FinishedAt: finished.Time},
+		},
+		{
+			name: "restart between updates",
+			next: corev1.ContainerStatus{Name: "app", ContainerID: "c2",
+				State:                corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+				LastTerminationState: corev1.ContainerState{Terminated: terminated("c1", 137, "OOMKilled")}},
+			wantType: ContainerStarted,
+			want:     &Termination{ExitCode: 137, Reason: "OOMKilled", FinishedAt: finished.Time},
+		},
+		{
+			name:     "killed during deletion",
+			next:     corev1.ContainerStatus{Name: "app", ContainerID: "c1", State: corev1.ContainerState{Terminated: terminated("c1", 143, "Error")}},
+			deleting: true,
+			wantType: ContainerStopped,
+		},
+		{
+			name:     "oom during deletion",
+			next:     corev1.ContainerStatus{Name: "app", ContainerID: "c1", State: corev1.ContainerState{Terminated: terminated("c1", 137, "OOMKilled")}},
+			deleting: true,
+			wantType: ContainerStopped,
+			want:     &Termination{ExitCode: 137, Reason: "OOMKilled", FinishedAt: finished.Time},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewPodDiscovery(nil, "node", 0, 1000)
+			d.processContainerStatuses(testPod(runningStatus("c1")))
+			if ev := <-d.Events(); ev.Type != ContainerStarted || ev.Termination != nil {
+				t.Fatalf("unexpected first event %+v", ev)
+			}
+
+			pod := testPod(tt.next)
+			if tt.deleting {
+				now := metav1.Now()
+				pod.DeletionTimestamp = &now
+			}
+			d.processContainerStatuses(pod)
+
+			ev := <-d.Events()
+			if ev.Type != tt.wantType {
+				t.Errorf("event type = %v, want %v", ev.Type, tt.wantType)
+			}
+			switch {
+			case tt.want == nil && ev.Termination != nil:
+				t.Errorf("unexpected termination %+v", ev.Termination)
+			case tt.want != nil && (ev.Termination == nil || *ev.Termination != *tt.want):
+				t.Errorf("termination = %+v, want %+v", ev.Termination, tt.want)
+			}
+
+			// Resyncs of the same state report nothing more
+			d.processContainerStatuses(pod)
+			if len(d.Events()) != 0 {
+				t.Errorf("resync emitted %d more events", len(d.Events()))
+			}
+		})
+	}
+}
+
+func TestTerminationLine(t *testing.T) {
+	ref := ContainerRef{Namespace: "default", PodName: "web", PodUID: "uid-1", ContainerName: "app"}
+	finished := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	line := terminationLine(ref, &Termination{ExitCode: 137, Signal: 9, Reason: "OOMKilled", FinishedAt: finished})
+	if line.Severity != storage.SeverityError || !line.Timestamp.Equal(finished) {
+		t.Errorf("unexpected line %+v", line)
+	}
+	if line.Message != "container app terminated: OOMKilled (exit code 137)" {
+		t.Errorf("Message = %q", line.Message)
+	}
+	want := map[string]string{"event": "container_terminated", "exit_code": "137", "reason": "OOMKilled", "signal": "9"}
+	for k, v := range want {
+		if line.Attributes[k] != v {
+			t.Errorf("attribute %s = %q, want %q", k, line.Attributes[k], v)
+		}
+	}
+
+	line = terminationLine(ref, &Termination{ExitCode: 1, Message: "panic: boom\n", FinishedAt: finished})
+	if line.Message != "container app terminated with exit code 1: panic: boom" {
+		t.Errorf("Message = %q", line.Message)
+	}
+	if _, ok := line.Attributes["reason"]; ok {
+		t.Errorf("unexpected reason attribute")
+	}
+}
+
+func TestPodDiscovery_Readiness(t *testing.T) {
+	changed := metav1.NewTime(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	readyPod := func(status corev1.ConditionStatus, reason string, appReady bool) *corev1.Pod {
+		st := runningStatus("c1")
+		st.Ready = appReady
+		sidecar := runningStatus("c2")
+		sidecar.Name, sidecar.Ready = "sidecar", true
+		pod := testPod(sidecar, st)
+		pod.Spec.Containers = []corev1.Container{{Name: "sidecar"}, {Name: "app"}}
+		pod.Status.Conditions = []corev1.PodCondition{{
+			Type:               corev1.PodReady,
+			Status:             status,
+			Reason:             reason,
+			Message:            "containers with unready status: [app]",
+			LastTransitionTime: changed,
+		}}
+		return pod
+	}
+	readiness := func(d *PodDiscovery) []PodEvent {
+		var events []PodEvent
+		for len(d.Events()) > 0 {
+			if ev := <-d.Events(); ev.Type == PodReadinessChanged {
+				events = append(events, ev)
+			}
+		}
+		return events
+	}
+
+	d := NewPodDiscovery(nil, "node", 0, 1000)
+
+	// Starting out not ready is not a change
+	d.onPodAdd(readyPod(corev1.ConditionFalse, "ContainersNotReady", false))
+	if evs := readiness(d); len(evs) != 0 {
+		t.Fatalf("initial state emitted %+v", evs)
+	}
+
+	d.onPodUpdate(nil, readyPod(corev1.ConditionTrue, "", true))
+	evs := readiness(d)
+	if len(evs) != 1 || !evs[0].Readiness.Ready || evs[0].Container.ContainerName != "sidecar" {
+		t.Fatalf("ready events = %+v", evs)
+	}
+
+	d.onPodUpdate(nil, readyPod(corev1.ConditionFalse, "ContainersNotReady", false))
+	evs = readiness(d)
+	want := Readiness{Reason: "ContainersNotReady", Message: "containers with unready status: [app]", Since: changed.Time}
+	if len(evs) != 1 || *evs[0].Readiness != want || evs[0].Container.ContainerName != "app" {
+		t.Fatalf("not ready events = %+v", evs)
+	}
+
+	// Resyncs of the same state report nothing more
+	d.onPodUpdate(nil, readyPod(corev1.ConditionFalse, "ContainersNotReady", false))
+	if evs := readiness(d); len(evs) != 0 {
+		t.Errorf("resync emitted %+v", evs)
+	}
+
+	// Pods being deleted become unready as expected
+	d.onPodUpdate(nil, readyPod(corev1.ConditionTrue, "", true))
+	readiness(d)
+	deleting := readyPod(corev1.ConditionFalse, "ContainersNotReady", false)
+	now := metav1.Now()
+	deleting.DeletionTimestamp = &now
+	d.onPodUpdate(nil, deleting)
+	if evs := readiness(d); len(evs) != 0 {
+		t.Errorf("deletion emitted %+v", evs)
+	}
+}
+
+func TestReadinessLine(t *testing.T) {
+	ref := ContainerRef{Namespace: "default", PodName: "web", PodUID: "uid-1", ContainerName: "app"}
+	since := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	line := readinessLine(ref, &Readiness{Reason: "ContainersNotReady", Message: "containers with unready status: [app]", Since: since})
+	if line.Severity != storage.SeverityWarn || !line.Timestamp.Equal(since) {
+		t.Errorf("unexpected line %+v", line)
+	}
+	if line.Message != "pod web not ready: ContainersNotReady: containers with unready status: [app]" {
+		t.Errorf("Message = %q", line.Message)
+	}
+	if line.Attributes["event"] != "pod_not_ready" || line.Attributes["reason"] != "ContainersNotReady" {
+		t.Errorf("Attributes = %v", line.Attributes)
+	}
+
+	line = readinessLine(ref, &Readiness{Ready: true, Since: since})
+	if line.Severity != storage.SeverityInfo || line.Message != "pod web ready" || line.Attributes["event"] != "pod_ready" {
+		t.Errorf("unexpected line %+v", line)
+	}
+}
+
+func TestPodDiscovery_Metadata(t *testing.T) {
+	d := NewPodDiscovery(nil, "node", 0, 1000)
+	d.includeLabels = []string{"app", "team"}
+	d.includeAnnotations = []string{"example.com/owner"}
+
+	pod := testPod(runningStatus("c1"))
+	pod.Labels = map[string]string{"app": "web", "pod-template-hash": "abc"}
+	pod.Annotations = map[string]string{"example.com/owner": "payments", "other": "x"}
+	d.onPodAdd(pod)
+
+	want := map[string]string{"label.app": "web", "annotation.example.com/owner": "payments"}
+	if got := d.PodAttributes("uid-1"); !maps.Equal(got, want) {
+		t.Errorf("PodAttributes = %v, want %v", got, want)
+	}
+
+	b := NewBatcher(nil, nil, 10, time.Second)
+	b.podAttributes = d.PodAttributes
+	entry := b.convertToEntry(LogLine{
+		Container:  ContainerRef{Namespace: "default", PodName: "web", PodUID: "uid-1", ContainerName: "app"},
+		Message:    "hello",
+		Attributes: map[string]string{"trace_id": "t1"},
+	})
+	want = map[string]string{"label.app": "web", "annotation.example.com/owner": "payments", "pod_uid": "uid-1", "trace_id": "t1"}
+	if !maps.Equal(entry.Attributes, want) {
+		t.Errorf("entry attributes = %v, want %v", entry.Attributes, want)
+	}
+
+	// Relabeling applies to later lines
+	relabeled := pod.DeepCopy()
+	relabeled.Labels = map[string]string{"team": "core"}
+	d.onPodUpdate(pod, relabeled)
+	if got := d.PodAttributes("uid-1")["label.team"]; got != "core" {
+		t.Errorf("label.team = %q after relabel, want core", got)
+	}
+
+	d.onPodDelete(relabeled)
+	if got := d.PodAttributes("uid-1"); got != nil {
+		t.Errorf("PodAttributes after delete = %v", got)
+	}
+}
+
+func TestPodDiscovery_Selectors(t *testing.T) {
+	d := NewPodDiscovery(nil, "node", 0, 1000)
+	d.excludeSelector, _ = parseSelector("logging.kubelogs.io/ignore=true")
+	d.includeSelector, _ = parseSelector("team in (core,payments)")
+	d.includeLabels = []string{"team"}
+
+	ignored := testPod(runningStatus("c1"))
+	ignored.Labels = map[string]string{"team": "core", "logging.kubelogs.io/ignore": "true"}
+	d.onPodAdd(ignored)
+	other := testPod(runningStatus("c1"))
+	other.UID = "uid-2"
+	other.Labels = map[string]string{"team": "search"}
+	d.onPodAdd(other)
+	if n := len(d.events); n != 0 {
+		t.Fatalf("%d events for unselected pods, want 0", n)
+	}
+
+	// Relabeling into the selection starts the pod's containers
+	selected := ignored.DeepCopy()
+	selected.ResourceVersion = "2"
+	delete(selected.Labels, "logging.kubelogs.io/ignore")
+	d.onPodUpdate(ignored, selected)
+	if ev := <-d.events; ev.Type != ContainerStarted || ev.Container.PodUID != "uid-1" {
+		t.Errorf("event = %+v, want ContainerStarted of uid-1", ev)
+	}
+	if got := d.PodAttributes("uid-1")["label.team"]; got != "core" {
+		t.Errorf("label.team = %q, want core", got)
+	}
+
+	// Relabeling out of it stops them
+	d.onPodUpdate(selected, ignored)
+	if ev := <-d.events; ev.Type != ContainerStopped || ev.Container.PodUID != "uid-1" {
+		t.Errorf("event = %+v, want ContainerStopped of uid-1", ev)
+	}
+	if got := d.PodAttributes("uid-1"); got != nil {
+		t.Errorf("PodAttributes of excluded pod = %v", got)
+	}
+	if n := len(d.events); n != 0 {
+		t.Errorf("%d more events, want 0", n)
+	}
+}
+
+func TestPodWorkload(t *testing.T) {
+	owner := func(kind, name string) []metav1.OwnerReference {
+		controller := true
+		return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+	}
+
+	tests := []struct {
+		name   string
+		owners []metav1.OwnerReference
+		hash   string
+		want   string
+	}{
+		{"deployment", owner("ReplicaSet", "api-7d9f8b6c5"), "7d9f8b6c5", "deployment/api"},
+		{"bare replicaset", owner("ReplicaSet", "api"), "", "replicaset/api"},
+		{"replicaset with foreign hash", owner("ReplicaSet", "api-1"), "2", "replicaset/api-1"},
+		{"statefulset", owner("StatefulSet", "db"), "", "statefulset/db"},
+		{"daemonset", owner("DaemonSet", "agent"), "", "daemonset/agent"},
+		{"cronjob", owner("Job", "backup-28512345"), "", "cronjob/backup"},
+		{"job", owner("Job", "migrate"), "", "job/migrate"},
+		{"job with a date", owner("Job", "migrate-20240301"), "", "job/migrate-20240301"},
+		{"static pod", owner("Node", "node-1"), "", ""},
+		{"no owner", nil, "", ""},
+		{"not controller", []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "api"}}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := testPod(runningStatus("c1"))
+			pod.OwnerReferences = tt.owners
+			if tt.hash != "" {
+				pod.Labels = map[string]string{"pod-template-hash": tt.hash}
+			}
+			if got := podWorkload(pod); got != tt.want {
+				t.Errorf("podWorkload() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	d := NewPodDiscovery(nil, "node", 0, 1000)
+	pod := testPod(runningStatus("c1"))
+	pod.OwnerReferences = owner("StatefulSet", "db")
+	d.onPodAdd(pod)
+	if got := d.PodAttributes("uid-1")[storage.WorkloadAttribute]; got != "statefulset/db" {
+		t.Errorf("workload attribute = %q, want statefulset/db", got)
+	}
+
+	// Pods of a CronJob's run are tagged with the Job and CronJob
+	pod.OwnerReferences = owner("Job", "backup-28512345")
+	d.onPodUpdate(testPod(runningStatus("c1")), pod)
+	want := map[string]string{"workload": "cronjob/backup", "job": "backup-28512345", "cronjob": "backup"}
+	if got := d.PodAttributes("uid-1"); !maps.Equal(got, want) {
+		t.Errorf("PodAttributes = %v, want %v", got, want)
+	}
+}
+
+func TestPodDiscovery_ResyncAndSaturation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d := NewPodDiscovery(nil, "node", 0, 1)
+	d.ctx = ctx
+
+	pod := testPod(runningStatus("c1"))
+	pod.ResourceVersion = "1"
+	d.onPodAdd(pod)
+
+	// A resync re-delivers the pod unchanged
+	d.onPodUpdate(pod, pod.DeepCopy())
+	if s := d.Stats(); s.SkippedResyncs != 1 || s.QueuedEvents != 1 || s.QueueCapacity != 1 {
+		t.Errorf("after resync stats = %+v", s)
+	}
+
+	// A new container doesn't fit the full queue
+	restarted := testPod(runningStatus("c2"))
+	restarted.ResourceVersion = "2"
+	d.onPodUpdate(pod, restarted)
+	if s := d.Stats(); s.SkippedResyncs != 1 || s.BlockedEvents != 1 || s.DroppedEvents != 1 {
+		t.Errorf("after restart stats = %+v", s)
+	}
+}
@@ -0,0 +1,213 @@
+//go:build e2e
+
+package collector
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+// noisyScript emits one JSON, one logfmt and one plain line per iteration,
+// then exits non-zero so the kubelet restarts the container.
+const noisyScript = `
+echo "e2e container starting"
+i=0
+while [ $i -lt 5 ]; do
+  echo '{"level":"error","msg":"e2e json line","trace_id":"abc123"}'
+  echo 'level=warn msg="e2e logfmt line" component=e2e'
+  echo '[INFO] e2e plain line'
+  i=$((i+1))
+  sleep 1
+done
+exit 1
+`
+
+// TestCollector_E2E runs the collector against a real cluster (kind or similar)
+// and asserts parsed lines, severities and attributes end-to-end, including
+// logs from a restarted container.
+//
+// Run with: make test-e2e
+func TestCollector_E2E(t *testing.T) {
+	clientset := e2eClientset(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	nodeName := e2eNodeName(ctx, t, clientset)
+
+	ns := fmt.Sprintf("kubelogs-e2e-%d", time.Now().Unix())
+	_, err := clientset.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: ns},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("create namespace: %v", err)
+	}
+	defer clientset.CoreV1().Namespaces().Delete(context.Background(), ns, metav1.DeleteOptions{})
+
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:", WriteBufferSize: 1})
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	defer store.Close()
+
+	cfg := DefaultConfig()
+	cfg.NodeName = nodeName
+	cfg.IncludeNamespaces = []string{ns}
+	cfg.ExcludeNamespaces = nil
+	cfg.SinceTime = time.Time{}
+	cfg.BatchTimeout = 500 * time.Millisecond
+	cfg.ShutdownTimeout = 5 * time.Second
+
+	c, err := New(clientset, store, cfg)
+	if err != nil {
+		t.Fatalf("create collector: %v", err)
+	}
+
+	collectorCtx, stopCollector := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Start(collectorCtx)
+	}()
+	defer func() {
+		stopCollector()
+		<-done
+	}()
+
+	_, err = clientset.CoreV1().Pods(ns).Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "noisy"},
+		Spec: corev1.PodSpec{
+			NodeName:      nodeName,
+			RestartPolicy: corev1.RestartPolicyAlways,
+			Containers: []corev1.Container{{
+				Name:    "app",
+				Image:   "busybox:1.36",
+				Command: []string{"sh", "-c", noisyScript},
+			}},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("create pod: %v", err)
+	}
+
+	// Wait until both container runs have been collected.
+	var entries []storage.LogEntry
+	deadline := time.Now().Add(3 * time.Minute)
+	for {
+		result, err := store.Query(ctx, storage.Query{
+			Namespace:  ns,
+			Pagination: storage.Pagination{Limit: 1000, Order: storage.OrderAsc},
+		})
+		if err != nil {
+			t.Fatalf("query: %v", err)
+		}
+		entries = result.Entries
+		if countMessages(entries, "e2e container starting") >= 2 && countMessages(entries, "e2e plain line") >= 10 &&
+			countMessages(entries, "container app terminated") >= 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for logs, got %d entries", len(entries))
+		}
+		time.Sleep(2 * time.Second)
+	}
+
+	for _, e := range entries {
+		if e.Pod != "noisy" || e.Container != "app" {
+			t.Errorf("unexpected source %s/%s", e.Pod, e.Container)
+		}
+		if e.Attributes["pod_uid"] == "" {
+			t.Errorf("entry %d missing pod_uid attribute", e.ID)
+		}
+
+		switch e.Message {
+		case "e2e json line":
+			if e.Severity != storage.SeverityError {
+				t.Errorf("json line severity = %v, want ERROR", e.Severity)
+			}
+			if e.Attributes["trace_id"] != "abc123" {
+				t.Errorf("json line trace_id = %q, want abc123", e.Attributes["trace_id"])
+			}
+		case "e2e logfmt line":
+			if e.Severity != storage.SeverityWarn {
+				t.Errorf("logfmt line severity = %v, want WARN", e.Severity)
+			}
+			if e.Attributes["component"] != "e2e" {
+				t.Errorf("logfmt line component = %q, want e2e", e.Attributes["component"])
+			}
+		case "[INFO] e2e plain line":
+			if e.Severity != storage.SeverityInfo {
+				t.Errorf("plain line severity = %v, want INFO", e.Severity)
+			}
+		case "container app terminated: Error (exit code 1)":
+			if e.Severity != storage.SeverityError || e.Attributes["exit_code"] != "1" {
+				t.Errorf("termination entry = %+v, want ERROR with exit_code 1", e)
+			}
+		}
+	}
+
+	// No duplicates across the restart: each run emits exactly 5 of each line.
+	starts := countMessages(entries, "e2e container starting")
+	if got := countMessages(entries, "e2e json line"); got > starts*5 {
+		t.Errorf("got %d json lines for %d runs, want at most %d", got, starts, starts*5)
+	}
+}
+
+// e2eClientset builds a client from KUBECONFIG (or ~/.kube/config).
+func e2eClientset(t *testing.T) kubernetes.Interface {
+	t.Helper()
+
+	kubeconfig := os.Getenv("KUBECONFIG")
+	if kubeconfig == "" {
+		kubeconfig = os.Getenv("HOME") + "/.kube/config"
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		t.Fatalf("load kubeconfig: %v", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatalf("create clientset: %v", err)
+	}
+	return clientset
+}
+
+// e2eNodeName returns the first schedulable node in the cluster.
+func e2eNodeName(ctx context.Context, t *testing.T, clientset kubernetes.Interface) string {
+	t.Helper()
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("list nodes: %v", err)
+	}
+	for _, n := range nodes.Items {
+		if !n.Spec.Unschedulable {
+			return n.Name
+		}
+	}
+	t.Fatal("no schedulable nodes found")
+	return ""
+}
+
+// countMessages counts entries whose message contains substr.
+func countMessages(entries []storage.LogEntry, substr string) int {
+	n := 0
+	for _, e := range entries {
+		if strings.Contains(e.Message, substr) {
+			n++
+		}
+	}
+	return n
+}
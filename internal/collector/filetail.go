@@ -0,0 +1,318 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxFileLine caps the bytes kept of one line read from a file, the same
+// as the scanner buffer of API streams.
+const maxFileLine = 1024 * 1024
+
+var (
+	// containerLogName matches the names of the kubelet's symlinks in
+	// /var/log/containers: <pod>_<namespace>_<container>-<container id>.log
+	containerLogName = regexp.MustCompile(`^([^_]+)_([^_]+)_(.+)-[0-9a-f]{64}\.log$`)
+
+	// podLogPath matches the kubelet's files in /var/log/pods:
+	// <namespace>_<pod>_<pod uid>/<container>/<restart count>.log
+	podLogPath = regexp.MustCompile(`([^/_]+)_([^/_]+)_([0-9a-f-]+)/([^/]+)/\d+\.log$`)
+)
+
+// FileTailer reads container logs from files on the node instead of the
+// API server, for nodes where the kubelet's log endpoint is unavailable
+// or to spare the API server. It polls the files matching its patterns
+// for new lines, following the kubelet's log rotation.
+type FileTailer struct {
+	patterns  []string
+	interval  time.Duration
+	sinceTime time.Time
+	node      string
+	parser    *Parser
+	output    chan LogLine
+
+	// collect, if set, filters files by namespace
+	collect func(namespace string) bool
+
+	mu    sync.Mutex
+	files map[string]*tailedFile // By path
+
+	linesRead atomic.Int64
+}
+
+// tailedFile is an open file being tailed.
+type tailedFile struct {
+	path   string
+	ref    ContainerRef
+	f      *os.File
+	info   os.FileInfo // Of the open file, to notice when path is replaced
+	r      *bufio.Reader
+	offset int64 // Bytes read
+
+	pending []byte // Start of a line not yet terminated by a newline
+	partial string // CRI partial lines waiting for their last part
+}
+
+// NewFileTailer creates a FileTailer for the files matching patterns
+// (filepath.Glob syntax), checked every interval. Lines older than
+// sinceTime are skipped. Files that don't follow the kubelet's naming
+// are attributed to a container named after the file, in a pod named
+// after node.
+func NewFileTailer(patterns []string, interval time.Duration, bufferSize int, sinceTime time.Time, node string) *FileTailer {
+	return &FileTailer{
+		patterns:  patterns,
+		interval:  interval,
+		sinceTime: sinceTime,
+		node:      node,
+		parser:    NewParser(),
+		output:    make(chan LogLine, bufferSize),
+		files:     make(map[string]*tailedFile),
+	}
+}
+
+// Output returns the channel where all log lines are sent.
+func (t *FileTailer) Output() <-chan LogLine {
+	return t.output
+}
+
+// ActiveFiles returns the number of files being tailed.
+func (t *FileTailer) ActiveFiles() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.files)
+}
+
+// LinesRead returns the number of lines read from all files.
+func (t *FileTailer) LinesRead() int64 {
+	return t.linesRead.Load()
+}
+
+// Run tails the files until ctx is canceled.
+func (t *FileTailer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	defer t.closeAll()
+
+	slog.Info("file tailing started", "patterns", t.patterns)
+	for {
+		t.poll(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// poll opens new files matching the patterns, reads what was appended to
+// each file, and closes files that were removed or replaced.
+func (t *FileTailer) poll(ctx context.Context) {
+	matches := make(map[string]bool)
+	for _, p := range t.patterns {
+		paths, _ := filepath.Glob(p) // Validate rejects malformed patterns
+		for _, path := range paths {
+			matches[path] = true
+		}
+	}
+
+	t.mu.Lock()
+	for path := range matches {
+		if _, ok := t.files[path]; !ok {
+			if tf := t.open(path); tf != nil {
+				t.files[path] = tf
+			}
+		}
+	}
+	files := make([]*tailedFile, 0, len(t.files))
+	for _, tf := range t.files {
+		files = append(files, tf)
+	}
+	t.mu.Unlock()
+
+	for _, tf := range files {
+		if ctx.Err() != nil {
+			return
+		}
+		// Read to the end first, so a rotated file is drained before
+		// the one replacing it is opened
+		t.read(ctx, tf)
+
+		info, err := os.Stat(tf.path)
+		switch {
+		case err != nil || !matches[tf.path] || !os.SameFile(info, tf.info):
+			t.close(tf)
+		case info.Size() < tf.offset:
+			slog.Info("log file truncated, reading from the start", "path", tf.path)
+			if _, err := tf.f.Seek(0, io.SeekStart); err != nil {
+				t.close(tf)
+				continue
+			}
+			tf.r.Reset(tf.f)
+			tf.offset = 0
+			tf.pending = tf.pending[:0]
+			t.read(ctx, tf)
+		}
+	}
+}
+
+// open opens path for tailing from its start. It returns nil if the file
+// can't be read or its namespace isn't collected.
+func (t *FileTailer) open(path string) *tailedFile {
+	ref := fileContainerRef(path, t.node)
+	if ref.Namespace != "" && t.collect != nil && !t.collect(ref.Namespace) {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		slog.Warn("failed to open log file", "path", path, "error", err)
+		return nil
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		slog.Warn("failed to open log file", "path", path, "error", err)
+		return nil
+	}
+
+	slog.Debug("tailing log file", "path", path, "container", ref.Key())
+	return &tailedFile{
+		path: path,
+		ref:  ref,
+		f:    f,
+		info: info,
+		r:    bufio.NewReaderSize(f, 64*1024),
+	}
+}
+
+func (t *FileTailer) close(tf *tailedFile) {
+	tf.f.Close()
+	t.mu.Lock()
+	delete(t.files, tf.path)
+	t.mu.Unlock()
+}
+
+func (t *FileTailer) closeAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for path, tf := range t.files {
+		tf.f.Close()
+		delete(t.files, path)
+	}
+}
+
+// read sends the complete lines appended to tf since the last read.
+func (t *FileTailer) read(ctx context.Context, tf *tailedFile) {
+	for {
+		chunk, err := tf.r.ReadSlice('\n')
+		tf.offset += int64(len(chunk))
+		if room := maxFileLine - len(tf.pending); room > 0 {
+			tf.pending = append(tf.pending, chunk[:min(len(chunk), room)]...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			// The rest of the line, if any, is read next time
+			if err != io.EOF {
+				slog.Warn("failed to read log file", "path", tf.path, "error", err)
+			}
+			return
+		}
+
+		line := strings.TrimRight(string(tf.pending), "\r\n")
+		tf.pending = tf.pending[:0]
+		if !t.send(ctx, tf, line) {
+			return
+		}
+	}
+}
+
+// send parses a line of tf and sends it, unless it's the start of a
+// CRI partial line or older than sinceTime. It returns false if ctx was
+// canceled.
+func (t *FileTailer) send(ctx context.Context, tf *tailedFile, line string) bool {
+	if ts, msg, partial, ok := parseCRILine(line); ok {
+		if partial {
+			if len(tf.partial) < maxFileLine {
+				tf.partial += msg
+			}
+			return true
+		}
+		line = ts + " " + tf.partial + msg
+		tf.partial = ""
+	}
+
+	parsed := t.parser.Parse(line)
+	if !t.sinceTime.IsZero() && parsed.Timestamp.Before(t.sinceTime) {
+		return true
+	}
+
+	select {
+	case t.output <- LogLine{
+		Container:  tf.ref,
+		Timestamp:  parsed.Timestamp,
+		Severity:   parsed.Severity,
+		Message:    parsed.Message,
+		Attributes: parsed.Attributes,
+	}:
+		t.linesRead.Add(1)
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// parseCRILine splits a line in the CRI log format,
+// "<RFC 3339 timestamp> <stdout|stderr> <F|P> <message>", where P marks
+// a line the runtime split because it was too long, continued by the
+// next one.
+func parseCRILine(line string) (ts, msg string, partial, ok bool) {
+	ts, rest, ok := strings.Cut(line, " ")
+	if !ok {
+		return "", "", false, false
+	}
+	stream, rest, ok := strings.Cut(rest, " ")
+	if !ok || stream != "stdout" && stream != "stderr" {
+		return "", "", false, false
+	}
+	tag, msg, _ := strings.Cut(rest, " ")
+	switch tag {
+	case "F":
+	case "P":
+		partial = true
+	default:
+		return "", "", false, false
+	}
+	if _, err := time.Parse(time.RFC3339Nano, ts); err != nil {
+		return "", "", false, false
+	}
+	return ts, msg, partial, true
+}
+
+// fileContainerRef identifies the container whose logs are in path, from
+// the kubelet's naming of /var/log/containers or /var/log/pods files.
+// Other files are attributed to a container named after the file, in a
+// pod named after node.
+func fileContainerRef(path, node string) ContainerRef {
+	if m := containerLogName.FindStringSubmatch(filepath.Base(path)); m != nil {
+		return ContainerRef{Namespace: m[2], PodName: m[1], ContainerName: m[3]}
+	}
+	if m := podLogPath.FindStringSubmatch(filepath.ToSlash(path)); m != nil {
+		return ContainerRef{Namespace: m[1], PodName: m[2], PodUID: m[3], ContainerName: m[4]}
+	}
+	return ContainerRef{
+		PodName:       node,
+		ContainerName: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+	}
+}
@@ -0,0 +1,158 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseCRILine(t *testing.T) {
+	tests := []struct {
+		line        string
+		wantMsg     string
+		wantPartial bool
+		wantOK      bool
+	}{
+		{"2024-01-15T10:30:00.123456789Z stdout F hello world", "hello world", false, true},
+		{"2024-01-15T10:30:00Z stderr P first half", "first half", true, true},
+		{"2024-01-15T10:30:00Z stdout F", "", false, true},
+		{"2024-01-15T10:30:00Z stdin F hello", "", false, false},
+		{"2024-01-15T10:30:00Z stdout X hello", "", false, false},
+		{"yesterday stdout F hello", "", false, false},
+		{"plain line", "", false, false},
+	}
+	for _, tt := range tests {
+		_, msg, partial, ok := parseCRILine(tt.line)
+		if msg != tt.wantMsg || partial != tt.wantPartial || ok != tt.wantOK {
+			t.Errorf("parseCRILine(%q) = %q, %v, %v, want %q, %v, %v",
+				tt.line, msg, partial, ok, tt.wantMsg, tt.wantPartial, tt.wantOK)
+		}
+	}
+}
+
+func TestFileContainerRef(t *testing.T) {
+	id := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		path string
+		want ContainerRef
+	}{
+		{
+			"/var/log/containers/api-7d9f_default_app-" + id + ".log",
+			ContainerRef{Namespace: "default", PodName: "api-7d9f", ContainerName: "app"},
+		},
+		{
+			"/var/log/pods/default_api-7d9f_5f1c2a3e-1111-2222-3333-444455556666/app/0.log",
+			ContainerRef{Namespace: "default", PodName: "api-7d9f", PodUID: "5f1c2a3e-1111-2222-3333-444455556666", ContainerName: "app"},
+		},
+		{
+			"/var/log/syslog.log",
+			ContainerRef{PodName: "node-1", ContainerName: "syslog"},
+		},
+	}
+	for _, tt := range tests {
+		if got := fileContainerRef(tt.path, "node-1"); got != tt.want {
+			t.Errorf("fileContainerRef(%q) = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFileTailer(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	write := func(flag int, s string) {
+		t.Helper()
+		f, err := os.OpenFile(path, flag|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ft := NewFileTailer([]string{filepath.Join(dir, "*.log")}, time.Hour, 100, time.Time{}, "node-1")
+	ctx := context.Background()
+	next := func() []string {
+		t.Helper()
+		ft.poll(ctx)
+		var msgs []string
+		for len(ft.output) > 0 {
+			msgs = append(msgs, (<-ft.output).Message)
+		}
+		return msgs
+	}
+	expect := func(want ...string) {
+		t.Helper()
+		got := next()
+		if len(got) != len(want) {
+			t.Fatalf("messages = %q, want %q", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("messages = %q, want %q", got, want)
+			}
+		}
+	}
+
+	// A partial CRI line is held until its last part, and an unterminated
+	// line until its newline
+	write(os.O_CREATE, "2024-01-15T10:30:00Z stdout F one\n"+
+		"2024-01-15T10:30:01Z stdout P tw\n"+
+		"2024-01-15T10:30:01Z stdout F o\n"+
+		"2024-01-15T10:30:02Z stdout F thr")
+	expect("one", "two")
+	write(os.O_APPEND, "ee\n")
+	expect("three")
+	if ft.ActiveFiles() != 1 || ft.LinesRead() != 3 {
+		t.Errorf("ActiveFiles, LinesRead = %d, %d, want 1, 3", ft.ActiveFiles(), ft.LinesRead())
+	}
+
+	// Truncation rereads the file from the start
+	write(os.O_TRUNC, "2024-01-15T10:30:03Z stdout F four\n")
+	expect("four")
+
+	// Rotation drains the old file, then follows the new one
+	write(os.O_APPEND, "2024-01-15T10:30:04Z stdout F five\n")
+	if err := os.Rename(path, filepath.Join(dir, "app.log.1")); err != nil {
+		t.Fatal(err)
+	}
+	write(os.O_CREATE, "2024-01-15T10:30:05Z stdout F six\n")
+	expect("five")
+	expect("six")
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	expect()
+	if ft.ActiveFiles() != 0 {
+		t.Errorf("ActiveFiles = %d after removal, want 0", ft.ActiveFiles())
+	}
+}
+
+func TestFileTailer_SinceAndCollect(t *testing.T) {
+	dir := t.TempDir()
+	id := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	for _, name := range []string{"api_default_app-" + id + ".log", "dns_kube-system_coredns-" + id + ".log"} {
+		content := "2024-01-15T10:30:00Z stdout F old\n2024-01-15T10:31:00Z stdout F new\n"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	since := time.Date(2024, 1, 15, 10, 30, 30, 0, time.UTC)
+	ft := NewFileTailer([]string{filepath.Join(dir, "*.log")}, time.Hour, 100, since, "node-1")
+	ft.collect = func(ns string) bool { return ns != "kube-system" }
+	ft.poll(context.Background())
+
+	if len(ft.output) != 1 {
+		t.Fatalf("got %d lines, want 1", len(ft.output))
+	}
+	line := <-ft.output
+	want := ContainerRef{Namespace: "default", PodName: "api", ContainerName: "app"}
+	if line.Message != "new" || line.Container != want {
+		t.Errorf("line = %q from %+v, want %q from %+v", line.Message, line.Container, "new", want)
+	}
+}
@@ -0,0 +1,76 @@
+package collector
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// HealthHandler returns the handler of the collector's health endpoints,
+// for Kubernetes probes and debugging:
+//
+//	GET /healthz      200 while the process serves requests
+//	GET /readyz       200 once ready (see NotReady), 503 and the reasons otherwise
+//	GET /debug/stats  Stats as JSON
+func (c *Collector) HealthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("GET /readyz", c.handleReady)
+	mux.HandleFunc("GET /debug/stats", c.handleStats)
+	return mux
+}
+
+// NotReady returns why the collector isn't ready to collect, or nil once
+// it has started, synced its pod cache (unless tailing files) and the
+// store accepts writes, i.e. the circuit breaker is closed.
+func (c *Collector) NotReady() []string {
+	if !c.started.Load() {
+		return []string{"not started"}
+	}
+	var reasons []string
+	if c.discovery != nil && !c.discovery.Synced() {
+		reasons = append(reasons, "pod cache not synced")
+	}
+	if c.batcher.Stats().CircuitOpen {
+		reasons = append(reasons, "storage unreachable, circuit breaker open")
+	}
+	return reasons
+}
+
+func (c *Collector) handleReady(w http.ResponseWriter, r *http.Request) {
+	if reasons := c.NotReady(); len(reasons) > 0 {
+		http.Error(w, strings.Join(reasons, "\n"), http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
+
+// streamStatsJSON is StreamStats with the error as text, which the
+// error value itself doesn't marshal to.
+type streamStatsJSON struct {
+	StreamStats
+	LastError string `json:",omitempty"`
+}
+
+func (c *Collector) handleStats(w http.ResponseWriter, r *http.Request) {
+	stats := c.Stats()
+	streams := make([]streamStatsJSON, len(stats.StreamStats))
+	for i, s := range stats.StreamStats {
+		streams[i].StreamStats = s
+		if s.LastError != nil {
+			streams[i].LastError = s.LastError.Error()
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(struct {
+		CollectorStats
+		StreamStats []streamStatsJSON
+	}{stats, streams})
+	if err != nil {
+		slog.Error("json encode error", "error", err)
+	}
+}
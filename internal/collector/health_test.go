@@ -0,0 +1,68 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestCollector_HealthEndpoints(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	defer store.Close()
+
+	cfg := DefaultConfig()
+	cfg.NodeName = "node-1"
+	cfg.LifecycleEvents = false
+	c, err := New(fake.NewSimpleClientset(), store, cfg)
+	if err != nil {
+		t.Fatalf("create collector: %v", err)
+	}
+	h := c.HealthHandler()
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := get("/healthz"); rec.Code != http.StatusOK {
+		t.Errorf("/healthz = %d, want 200", rec.Code)
+	}
+	if rec := get("/readyz"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz before start = %d, want 503", rec.Code)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.Start(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for get("/readyz").Code != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatalf("not ready: %v", c.NotReady())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	rec := get("/debug/stats")
+	var stats CollectorStats
+	if err := json.NewDecoder(rec.Body).Decode(&stats); rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("/debug/stats = %d, %v", rec.Code, err)
+	}
+	if stats.ActiveStreams != 0 || stats.BatcherStats.CircuitOpen {
+		t.Errorf("stats = %+v", stats)
+	}
+}
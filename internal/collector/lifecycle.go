@@ -0,0 +1,329 @@
+package collector
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// dropSummaryInterval is how often the collector writes a summary of the
+// data it dropped, if it dropped any.
+const dropSummaryInterval = time.Minute
+
+// lifecycleContainer is the container of lifecycle entries, in the pod
+// named after the collector's node.
+const lifecycleContainer = "collector"
+
+// maxPodDropLines bounds the pod_drops entries of one drop summary; the
+// remaining pods wait for the next one.
+const maxPodDropLines = 100
+
+// Reasons the lines of a pod were dropped, in pod_drops entries.
+const (
+	DropReasonBackpressure = "backpressure"     // Stream output full
+	DropReasonRateLimit    = "rate_limit"       // Over the container's rate limit
+	DropReasonRetryQueue   = "retry_queue_full" // In a batch dropped from the retry queue
+)
+
+// drops counts the data the collector dropped so far.
+type drops struct {
+	lines     int64     // Stream output full
+	limited   int64     // Over a container's rate limit
+	entries   int64     // Retry queue full
+	spooled   int64     // Spooled batches dropped
+	podEvents int64     // Pod events dropped
+	since     time.Time // When they were counted
+}
+
+// sub returns the drops counted in d but not in prev.
+func (d drops) sub(prev drops) drops {
+	return drops{
+		lines:     d.lines - prev.lines,
+		limited:   d.limited - prev.limited,
+		entries:   d.entries - prev.entries,
+		spooled:   d.spooled - prev.spooled,
+		podEvents: d.podEvents - prev.podEvents,
+	}
+}
+
+func (d drops) any() bool {
+	return d.lines > 0 || d.limited > 0 || d.entries > 0 || d.spooled > 0 || d.podEvents > 0
+}
+
+// podDropKey identifies the drops of a pod for one reason.
+type podDropKey struct {
+	namespace string
+	pod       string
+	reason    string
+}
+
+// podDrops counts the lines of a pod dropped for one reason, with the
+// timestamps of the first and last of them.
+type podDrops struct {
+	podDropKey
+	lines int64
+	first time.Time
+	last  time.Time
+}
+
+// dropLedger records which pods lost lines, so drop summaries can name
+// them. The zero value is ready to use.
+type dropLedger struct {
+	mu   sync.Mutex
+	pods map[podDropKey]*podDrops
+}
+
+// add records n lines of namespace/pod dropped for reason, logged at.
+func (l *dropLedger) add(namespace, pod, reason string, n int64, at time.Time) {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	key := podDropKey{namespace: namespace, pod: pod, reason: reason}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.pods == nil {
+		l.pods = make(map[podDropKey]*podDrops)
+	}
+	d, ok := l.pods[key]
+	if !ok {
+		l.pods[key] = &podDrops{podDropKey: key, lines: n, first: at, last: at}
+		return
+	}
+	d.lines += n
+	if at.Before(d.first) {
+		d.first = at
+	}
+	if at.After(d.last) {
+		d.last = at
+	}
+}
+
+// take removes and returns up to limit recorded drops, those with the
+// oldest lines first. A limit of 0 or less takes all of them.
+func (l *dropLedger) take(limit int) []podDrops {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	all := make([]podDrops, 0, len(l.pods))
+	for _, d := range l.pods {
+		all = append(all, *d)
+	}
+	slices.SortFunc(all, func(a, b podDrops) int {
+		return cmp.Or(a.first.Compare(b.first),
+			cmp.Compare(a.namespace, b.namespace),
+			cmp.Compare(a.pod, b.pod),
+			cmp.Compare(a.reason, b.reason))
+	})
+	if limit > 0 && len(all) > limit {
+		all = all[:limit]
+	}
+	for _, d := range all {
+		delete(l.pods, d.podDropKey)
+	}
+	return all
+}
+
+// lifecycleLine builds an entry of the collector's own lifecycle.
+func (c *Collector) lifecycleLine(severity storage.Severity, msg string, attrs map[string]string) LogLine {
+	return LogLine{
+		Container: ContainerRef{
+			Namespace:     storage.LifecycleNamespace,
+			PodName:       c.config.NodeName,
+			ContainerName: lifecycleContainer,
+		},
+		Timestamp:  time.Now(),
+		Severity:   severity,
+		Message:    msg,
+		Attributes: attrs,
+	}
+}
+
+// collectorStartedLine records that the collector started.
+func (c *Collector) collectorStartedLine() LogLine {
+	return c.lifecycleLine(storage.SeverityInfo,
+		fmt.Sprintf("collector started on node %s", c.config.NodeName),
+		map[string]string{"event": "collector_started", "node": c.config.NodeName})
+}
+
+// collectorStoppedLine records that the collector is stopping, with what
+// it read and wrote since it started.
+func (c *Collector) collectorStoppedLine() LogLine {
+	uptime := time.Since(c.startedAt).Round(time.Second)
+	return c.lifecycleLine(storage.SeverityInfo,
+		fmt.Sprintf("collector stopped on node %s after %s", c.config.NodeName, uptime),
+		map[string]string{
+			"event":           "collector_stopped",
+			"node":            c.config.NodeName,
+			"uptime_seconds":  strconv.FormatInt(int64(uptime/time.Second), 10),
+			"lines_read":      strconv.FormatInt(c.linesRead(), 10),
+			"entries_written": strconv.FormatInt(c.batcher.Stats().TotalEntries, 10),
+		})
+}
+
+// streamAttributes returns the attributes naming the container of a
+// stream event.
+func streamAttributes(event string, ref ContainerRef) map[string]string {
+	return map[string]string{
+		"event":            event,
+		"stream_namespace": ref.Namespace,
+		"stream_pod":       ref.PodName,
+		"stream_container": ref.ContainerName,
+	}
+}
+
+// streamStartedLine records that the stream of ref was opened.
+func (c *Collector) streamStartedLine(ref ContainerRef) LogLine {
+	return c.lifecycleLine(storage.SeverityInfo,
+		fmt.Sprintf("stream started for %s/%s/%s", ref.Namespace, ref.PodName, ref.ContainerName),
+		streamAttributes("stream_started", ref))
+}
+
+// streamStoppedLine records why the stream of ref ended. Streams that
+// failed are warnings.
+func (c *Collector) streamStoppedLine(ref ContainerRef, end StreamEnd) LogLine {
+	msg := fmt.Sprintf("stream stopped for %s/%s/%s: %s", ref.Namespace, ref.PodName, ref.ContainerName, end.Reason)
+	attrs := streamAttributes("stream_stopped", ref)
+	attrs["reason"] = end.Reason
+	attrs["lines_read"] = strconv.FormatInt(end.LinesRead, 10)
+	severity := storage.SeverityInfo
+	if end.Err != nil {
+		msg += ": " + end.Err.Error()
+		attrs["error"] = end.Err.Error()
+		severity = storage.SeverityWarn
+	}
+	return c.lifecycleLine(severity, msg, attrs)
+}
+
+// countDrops reads the drop counters of the collector's components.
+func (c *Collector) countDrops() drops {
+	stats := c.batcher.Stats()
+	d := drops{
+		lines:   c.streamManager.LinesDropped(),
+		limited: c.limiter.LimitedLines(),
+		entries: stats.DroppedEntries,
+		spooled: stats.Spool.DroppedBatches,
+	}
+	if c.discovery != nil {
+		d.podEvents = c.discovery.Stats().DroppedEvents
+	}
+	return d
+}
+
+// dropsLine summarizes the data dropped since the last summary, at
+// since.
+func (c *Collector) dropsLine(d drops, since time.Time) LogLine {
+	var parts []string
+	for _, n := range []struct {
+		count int64
+		what  string
+	}{
+		{d.lines, "log lines"},
+		{d.limited, "rate limited log lines"},
+		{d.entries, "entries of failed batches"},
+		{d.spooled, "spooled batches"},
+		{d.podEvents, "pod events"},
+	} {
+		if n.count > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n.count, n.what))
+		}
+	}
+	return c.lifecycleLine(storage.SeverityWarn,
+		fmt.Sprintf("collector dropped %s since %s", strings.Join(parts, ", "), since.UTC().Format(time.RFC3339)),
+		map[string]string{
+			"event":                 "drops",
+			"node":                  c.config.NodeName,
+			"since":                 since.UTC().Format(time.RFC3339Nano),
+			"dropped_lines":         strconv.FormatInt(d.lines, 10),
+			"rate_limited_lines":    strconv.FormatInt(d.limited, 10),
+			"dropped_entries":       strconv.FormatInt(d.entries, 10),
+			"dropped_spool_batches": strconv.FormatInt(d.spooled, 10),
+			"dropped_pod_events":    strconv.FormatInt(d.podEvents, 10),
+		})
+}
+
+// podDropsLine records the lines of one pod dropped for one reason.
+func (c *Collector) podDropsLine(d podDrops) LogLine {
+	first := d.first.UTC().Format(time.RFC3339)
+	last := d.last.UTC().Format(time.RFC3339)
+	return c.lifecycleLine(storage.SeverityWarn,
+		fmt.Sprintf("dropped %d lines from %s/%s between %s and %s, reason=%s",
+			d.lines, d.namespace, d.pod, first, last, d.reason),
+		map[string]string{
+			"event":            "pod_drops",
+			"node":             c.config.NodeName,
+			"stream_namespace": d.namespace,
+			"stream_pod":       d.pod,
+			"reason":           d.reason,
+			"dropped_lines":    strconv.FormatInt(d.lines, 10),
+			"first":            d.first.UTC().Format(time.RFC3339Nano),
+			"last":             d.last.UTC().Format(time.RFC3339Nano),
+		})
+}
+
+// storageReachable reports whether the last writes to storage succeeded,
+// so entries written now aren't stuck behind, or dropped with, failed
+// batches.
+func (c *Collector) storageReachable() bool {
+	stats := c.batcher.Stats()
+	return !stats.CircuitOpen && stats.RetryQueueSize == 0 && stats.Spool.Batches == 0
+}
+
+// summarizeDrops writes a summary of the data dropped since the last
+// one, if any was, followed by the pods that lost lines. While storage
+// is unreachable summaries are held back, and the next one covers the
+// whole outage, unless final is set at shutdown.
+func (c *Collector) summarizeDrops(final bool) {
+	c.dropsMu.Lock()
+	defer c.dropsMu.Unlock()
+	if !final && !c.storageReachable() {
+		return
+	}
+	cur := c.countDrops()
+	cur.since = time.Now()
+	if d := cur.sub(c.reportedDrops); d.any() {
+		c.batcher.Add(c.dropsLine(d, c.reportedDrops.since))
+	}
+	c.reportedDrops = cur
+
+	limit := maxPodDropLines
+	if final {
+		limit = 0
+	}
+	for _, d := range c.ledger.take(limit) {
+		c.batcher.Add(c.podDropsLine(d))
+	}
+}
+
+// recordDrop adds a dropped line to the drop ledger.
+func (c *Collector) recordDrop(line LogLine, reason string) {
+	c.ledger.add(line.Container.Namespace, line.Container.PodName, reason, 1, line.Timestamp)
+}
+
+// recordDroppedBatch adds the entries of a dropped batch to the drop
+// ledger.
+func (c *Collector) recordDroppedBatch(batch storage.LogBatch) {
+	for _, e := range batch {
+		c.ledger.add(e.Namespace, e.Pod, DropReasonRetryQueue, 1, e.Timestamp)
+	}
+}
+
+// reportDrops writes drop summaries every dropSummaryInterval until the
+// collector stops.
+func (c *Collector) reportDrops() {
+	ticker := time.NewTicker(dropSummaryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.summarizeDrops(false)
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+func TestStreamManager_StreamEnd(t *testing.T) {
+	m := NewStreamManager(nil, 10, 10, time.Time{}, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	m.Start(ctx)
+
+	failed := errors.New("connection reset")
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, StreamEndCompleted},
+		{context.Canceled, StreamEndStopped},
+		{fmt.Errorf("read: %w", context.Canceled), StreamEndStopped},
+		{failed, StreamEndError},
+	}
+	for _, tt := range tests {
+		end := m.streamEnd(tt.err, 7)
+		if end.Reason != tt.want || end.LinesRead != 7 {
+			t.Errorf("streamEnd(%v) = %+v, want reason %s", tt.err, end, tt.want)
+		}
+		if (end.Err != nil) != (tt.want == StreamEndError) {
+			t.Errorf("streamEnd(%v).Err = %v", tt.err, end.Err)
+		}
+	}
+
+	cancel()
+	if end := m.streamEnd(context.Canceled, 0); end.Reason != StreamEndShutdown {
+		t.Errorf("reason after shutdown = %s, want %s", end.Reason, StreamEndShutdown)
+	}
+}
+
+func TestCollector_LifecycleLines(t *testing.T) {
+	c := &Collector{config: Config{NodeName: "node-1"}}
+	ref := ContainerRef{Namespace: "default", PodName: "api", ContainerName: "app"}
+
+	line := c.streamStoppedLine(ref, StreamEnd{Reason: StreamEndError, Err: errors.New("EOF"), LinesRead: 3})
+	if line.Container.Namespace != storage.LifecycleNamespace || line.Container.PodName != "node-1" {
+		t.Errorf("source = %+v, want %s/node-1", line.Container, storage.LifecycleNamespace)
+	}
+	if line.Severity != storage.SeverityWarn {
+		t.Errorf("severity = %v, want WARN", line.Severity)
+	}
+	want := map[string]string{
+		"event":            "stream_stopped",
+		"stream_namespace": "default",
+		"stream_pod":       "api",
+		"stream_container": "app",
+		"reason":           StreamEndError,
+		"lines_read":       "3",
+		"error":            "EOF",
+	}
+	for k, v := range want {
+		if line.Attributes[k] != v {
+			t.Errorf("attribute %s = %q, want %q", k, line.Attributes[k], v)
+		}
+	}
+
+	if line := c.streamStoppedLine(ref, StreamEnd{Reason: StreamEndCompleted}); line.Severity != storage.SeverityInfo {
+		t.Errorf("completed stream severity = %v, want INFO", line.Severity)
+	}
+}
+
+func TestCollector_SummarizeDrops(t *testing.T) {
+	c := &Collector{
+		config:        Config{NodeName: "node-1"},
+		batcher:       NewBatcher(&mockStore{}, nil, 100, time.Hour),
+		streamManager: NewStreamManager(nil, 10, 10, time.Time{}, time.Minute),
+		limiter:       NewRateLimiter(nil, LineLimits{}),
+	}
+	c.reportedDrops.since = time.Now()
+
+	summaries := func() []LogLine {
+		var lines []LogLine
+		for _, e := range c.batcher.buffer {
+			if e.Attributes["event"] == "drops" {
+				lines = append(lines, LogLine{Message: e.Message, Attributes: e.Attributes})
+			}
+		}
+		return lines
+	}
+
+	// Nothing dropped, nothing written
+	c.summarizeDrops(false)
+	if got := summaries(); len(got) != 0 {
+		t.Fatalf("got %d summaries without drops, want 0", len(got))
+	}
+
+	c.streamManager.linesDropped.Add(5)
+	c.batcher.droppedEntries.Add(2)
+	c.limiter.limitedLines.Add(3)
+	c.summarizeDrops(false)
+	got := summaries()
+	if len(got) != 1 {
+		t.Fatalf("got %d summaries, want 1", len(got))
+	}
+	if got[0].Attributes["dropped_lines"] != "5" || got[0].Attributes["dropped_entries"] != "2" ||
+		got[0].Attributes["rate_limited_lines"] != "3" {
+		t.Errorf("summary attributes = %v, want 5 lines, 2 entries and 3 rate limited lines", got[0].Attributes)
+	}
+
+	// Only new drops are summarized
+	c.streamManager.linesDropped.Add(1)
+	c.summarizeDrops(false)
+	got = summaries()
+	if len(got) != 2 {
+		t.Fatalf("got %d summaries, want 2", len(got))
+	}
+	if got[1].Attributes["dropped_lines"] != "1" || got[1].Attributes["dropped_entries"] != "0" {
+		t.Errorf("second summary attributes = %v, want 1 line", got[1].Attributes)
+	}
+}
+
+func TestCollector_PodDrops(t *testing.T) {
+	c := &Collector{
+		config:        Config{NodeName: "node-1"},
+		batcher:       NewBatcher(&mockStore{}, nil, 100, time.Hour),
+		streamManager: NewStreamManager(nil, 10, 10, time.Time{}, time.Minute),
+		limiter:       NewRateLimiter(nil, LineLimits{}),
+	}
+	c.reportedDrops.since = time.Now()
+
+	podDrops := func() []storage.LogEntry {
+		var entries []storage.LogEntry
+		for _, e := range c.batcher.buffer {
+			if e.Attributes["event"] == "pod_drops" {
+				entries = append(entries, e)
+			}
+		}
+		return entries
+	}
+
+	t0 := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	api := ContainerRef{Namespace: "default", PodName: "api", ContainerName: "app"}
+	for i := range 3 {
+		c.recordDrop(LogLine{Container: api, Timestamp: t0.Add(time.Duration(i) * time.Second)}, DropReasonBackpressure)
+	}
+	c.recordDrop(LogLine{Container: api, Timestamp: t0}, DropReasonRateLimit)
+	c.recordDroppedBatch(storage.LogBatch{
+		{Namespace: "jobs", Pod: "worker", Timestamp: t0.Add(-time.Minute)},
+		{Namespace: "jobs", Pod: "worker", Timestamp: t0.Add(-time.Second)},
+	})
+	c.streamManager.linesDropped.Add(3)
+
+	// Held back while storage is unreachable
+	c.batcher.circuitOpen = true
+	c.summarizeDrops(false)
+	if got := podDrops(); len(got) != 0 {
+		t.Fatalf("got %d pod drops while storage was unreachable, want 0", len(got))
+	}
+
+	c.batcher.circuitOpen = false
+	c.summarizeDrops(false)
+	got := podDrops()
+	if len(got) != 3 {
+		t.Fatalf("got %d pod drops, want 3", len(got))
+	}
+	worker := got[0]
+	want := map[string]string{
+		"stream_namespace": "jobs",
+		"stream_pod":       "worker",
+		"reason":           DropReasonRetryQueue,
+		"dropped_lines":    "2",
+		"first":            t0.Add(-time.Minute).Format(time.RFC3339Nano),
+		"last":             t0.Add(-time.Second).Format(time.RFC3339Nano),
+	}
+	for k, v := range want {
+		if worker.Attributes[k] != v {
+			t.Errorf("attribute %s = %q, want %q", k, worker.Attributes[k], v)
+		}
+	}
+	wantMsg := "dropped 2 lines from jobs/worker between 2024-05-01T09:59:00Z and 2024-05-01T09:59:59Z, reason=retry_queue_full"
+	if worker.Message != wantMsg {
+		t.Errorf("message = %q, want %q", worker.Message, wantMsg)
+	}
+	if got[1].Attributes["reason"] != DropReasonBackpressure || got[1].Attributes["dropped_lines"] != "3" {
+		t.Errorf("second pod drops = %v, want 3 lines dropped for backpressure", got[1].Attributes)
+	}
+
+	// Reported drops are not reported again
+	c.summarizeDrops(false)
+	if got := podDrops(); len(got) != 3 {
+		t.Errorf("got %d pod drops after another summary, want 3", len(got))
+	}
+}
+
+func TestDropLedger_TakeLimit(t *testing.T) {
+	var l dropLedger
+	t0 := time.Now()
+	for i := range 5 {
+		l.add("default", fmt.Sprintf("pod-%d", i), DropReasonRateLimit, 1, t0.Add(time.Duration(i)*time.Second))
+	}
+	first := l.take(2)
+	if len(first) != 2 || first[0].pod != "pod-0" || first[1].pod != "pod-1" {
+		t.Fatalf("take(2) = %+v, want pod-0 and pod-1", first)
+	}
+	if rest := l.take(0); len(rest) != 3 || rest[0].pod != "pod-2" {
+		t.Errorf("take(0) = %+v, want the 3 remaining pods from pod-2", rest)
+	}
+	if rest := l.take(0); len(rest) != 0 {
+		t.Errorf("take(0) on an empty ledger = %+v, want none", rest)
+	}
+}
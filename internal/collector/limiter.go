@@ -0,0 +1,192 @@
+package collector
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// Pod annotations overriding the collector's LineLimits for the pod's
+// containers.
+const (
+	RateLimitAnnotation   = "logging.kubelogs.io/rate-limit"
+	SampleDebugAnnotation = "logging.kubelogs.io/sample-debug"
+)
+
+// bucketIdle is how long a container's token bucket is kept after its
+// last line. Idle buckets are full again, so dropping them loses nothing.
+const bucketIdle = time.Minute
+
+// LineLimits bound the lines kept from a container.
+type LineLimits struct {
+	// Rate is the lines per second kept, with bursts of up to a second's
+	// worth. 0 is unlimited.
+	Rate float64
+
+	// SampleDebug keeps one in SampleDebug DEBUG and TRACE lines, picked
+	// at random. 0 and 1 keep all.
+	SampleDebug int
+}
+
+// RateLimiter drops the lines of containers logging faster than their
+// rate limit and samples their DEBUG and TRACE lines, so one
+// crash-looping pod can't flood storage. It sits between the stream
+// manager (or the merger) and the batcher.
+type RateLimiter struct {
+	limits LineLimits
+
+	// podLimits, if set, returns the limits of a pod that overrides them
+	// with annotations
+	podLimits func(podUID string) (LineLimits, bool)
+
+	// onLimited, if set, is told of each line dropped over a rate limit
+	onLimited func(line LogLine)
+
+	input  <-chan LogLine
+	output chan LogLine
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket // By container key
+	unsent  []LogLine               // Lines not sent before shutdown
+
+	// Metrics
+	limitedLines atomic.Int64
+	sampledLines atomic.Int64
+}
+
+// tokenBucket holds the lines a container may still send right away.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a rate limiter reading from input and applying
+// limits to every container.
+func NewRateLimiter(input <-chan LogLine, limits LineLimits) *RateLimiter {
+	return &RateLimiter{
+		limits:  limits,
+		input:   input,
+		output:  make(chan LogLine),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// Output returns the channel of lines kept. It is closed when Run
+// returns.
+func (l *RateLimiter) Output() <-chan LogLine {
+	return l.output
+}
+
+// LimitedLines returns the number of lines dropped over a rate limit.
+func (l *RateLimiter) LimitedLines() int64 {
+	return l.limitedLines.Load()
+}
+
+// SampledLines returns the number of DEBUG and TRACE lines sampled out.
+func (l *RateLimiter) SampledLines() int64 {
+	return l.sampledLines.Load()
+}
+
+// Run forwards the lines within their container's limits until input is
+// closed or ctx is canceled. A line not sent on cancellation is left
+// for Drain.
+func (l *RateLimiter) Run(ctx context.Context) {
+	defer close(l.output)
+
+	ticker := time.NewTicker(bucketIdle)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case line, ok := <-l.input:
+			if !ok {
+				return
+			}
+			if !l.allow(line, time.Now()) {
+				continue
+			}
+			select {
+			case l.output <- line:
+			case <-ctx.Done():
+				l.mu.Lock()
+				l.unsent = append(l.unsent, line)
+				l.mu.Unlock()
+				return
+			}
+
+		case now := <-ticker.C:
+			l.prune(now)
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Drain removes and returns the lines not yet sent, for a final flush on
+// shutdown.
+func (l *RateLimiter) Drain() []LogLine {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lines := l.unsent
+	l.unsent = nil
+	return lines
+}
+
+// allow reports whether line is kept. Sampled out lines don't count
+// against the rate limit.
+func (l *RateLimiter) allow(line LogLine, now time.Time) bool {
+	limits := l.limits
+	if l.podLimits != nil && line.Container.PodUID != "" {
+		if pod, ok := l.podLimits(line.Container.PodUID); ok {
+			limits = pod
+		}
+	}
+
+	if limits.SampleDebug > 1 && line.Severity >= storage.SeverityTrace && line.Severity <= storage.SeverityDebug {
+		if rand.IntN(limits.SampleDebug) != 0 {
+			l.sampledLines.Add(1)
+			return false
+		}
+	}
+	if limits.Rate <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	burst := max(limits.Rate, 1)
+	key := line.Container.Key()
+	b := l.buckets[key]
+	if b == nil {
+		b = &tokenBucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*limits.Rate)
+	b.last = now
+	if b.tokens < 1 {
+		l.limitedLines.Add(1)
+		if l.onLimited != nil {
+			l.onLimited(line)
+		}
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// prune forgets the buckets of containers idle for bucketIdle.
+func (l *RateLimiter) prune(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= bucketIdle {
+			delete(l.buckets, key)
+		}
+	}
+}
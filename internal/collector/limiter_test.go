@@ -0,0 +1,130 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+func TestRateLimiter_Rate(t *testing.T) {
+	l := NewRateLimiter(nil, LineLimits{Rate: 10})
+	noisy := LogLine{Container: ContainerRef{Namespace: "default", PodName: "crash", PodUID: "uid-1", ContainerName: "app"}}
+	quiet := LogLine{Container: ContainerRef{Namespace: "default", PodName: "web", PodUID: "uid-2", ContainerName: "app"}}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// A second's worth of lines passes at once, then the rest are dropped
+	kept := 0
+	for range 25 {
+		if l.allow(noisy, now) {
+			kept++
+		}
+	}
+	if kept != 10 || l.LimitedLines() != 15 {
+		t.Errorf("kept %d, limited %d of 25 lines, want 10 and 15", kept, l.LimitedLines())
+	}
+	if !l.allow(quiet, now) {
+		t.Error("line of another container dropped")
+	}
+
+	// Tokens refill at the rate
+	if !l.allow(noisy, now.Add(100*time.Millisecond)) {
+		t.Error("line dropped after refill")
+	}
+	if l.allow(noisy, now.Add(100*time.Millisecond)) {
+		t.Error("second line kept after refilling one")
+	}
+
+	l.prune(now.Add(bucketIdle))
+	if len(l.buckets) != 1 {
+		t.Errorf("%d buckets after pruning, want the one used 100ms later", len(l.buckets))
+	}
+}
+
+func TestRateLimiter_SampleDebug(t *testing.T) {
+	l := NewRateLimiter(nil, LineLimits{SampleDebug: 10})
+	now := time.Now()
+	ref := ContainerRef{Namespace: "default", PodName: "web", PodUID: "uid-1", ContainerName: "app"}
+
+	debug := 0
+	for range 1000 {
+		if l.allow(LogLine{Container: ref, Severity: storage.SeverityDebug}, now) {
+			debug++
+		}
+		if !l.allow(LogLine{Container: ref, Severity: storage.SeverityInfo}, now) {
+			t.Fatal("INFO line sampled out")
+		}
+	}
+	if debug < 50 || debug > 150 {
+		t.Errorf("kept %d of 1000 DEBUG lines, want about 100", debug)
+	}
+	if l.SampledLines() != int64(1000-debug) {
+		t.Errorf("SampledLines = %d, want %d", l.SampledLines(), 1000-debug)
+	}
+}
+
+func TestRateLimiter_PodAnnotations(t *testing.T) {
+	d := NewPodDiscovery(nil, "node", 0, 1000)
+	d.limits = LineLimits{Rate: 1, SampleDebug: 10}
+
+	pod := testPod(runningStatus("c1"))
+	pod.Annotations = map[string]string{RateLimitAnnotation: "0"}
+	d.onPodAdd(pod)
+	if got, ok := d.PodLimits("uid-1"); !ok || got != (LineLimits{Rate: 0, SampleDebug: 10}) {
+		t.Errorf("PodLimits = %+v, %v, want unlimited rate and the sampling of the collector", got, ok)
+	}
+
+	invalid := testPod(runningStatus("c1"))
+	invalid.UID = "uid-2"
+	invalid.Annotations = map[string]string{SampleDebugAnnotation: "often"}
+	d.onPodAdd(invalid)
+	if got, ok := d.PodLimits("uid-2"); ok {
+		t.Errorf("PodLimits of invalid annotation = %+v, want none", got)
+	}
+
+	// The annotated pod's lines pass the collector's rate
+	l := NewRateLimiter(nil, d.limits)
+	l.podLimits = d.PodLimits
+	now := time.Now()
+	for i := range 5 {
+		if !l.allow(LogLine{Container: ContainerRef{PodUID: "uid-1"}, Severity: storage.SeverityInfo}, now) {
+			t.Fatalf("line %d of unlimited pod dropped", i)
+		}
+	}
+	l.allow(LogLine{Container: ContainerRef{PodUID: "uid-2"}, Severity: storage.SeverityInfo}, now)
+	if l.allow(LogLine{Container: ContainerRef{PodUID: "uid-2"}, Severity: storage.SeverityInfo}, now) {
+		t.Error("second line of pod at 1 line/s kept")
+	}
+
+	d.onPodDelete(pod)
+	if _, ok := d.PodLimits("uid-1"); ok {
+		t.Error("PodLimits after delete")
+	}
+}
+
+func TestRateLimiter_Run(t *testing.T) {
+	input := make(chan LogLine)
+	l := NewRateLimiter(input, LineLimits{Rate: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		l.Run(ctx)
+		close(done)
+	}()
+
+	ref := ContainerRef{PodUID: "uid-1"}
+	input <- LogLine{Container: ref, Message: "first"}
+	if line := <-l.Output(); line.Message != "first" {
+		t.Errorf("got %q, want first", line.Message)
+	}
+	input <- LogLine{Container: ref, Message: "limited"}
+
+	// A line not sent before shutdown is left for Drain
+	input <- LogLine{Container: ContainerRef{PodUID: "uid-2"}, Message: "unsent"}
+	cancel()
+	<-done
+	if lines := l.Drain(); len(lines) != 1 || lines[0].Message != "unsent" {
+		t.Errorf("Drain = %v, want the unsent line", lines)
+	}
+}
@@ -0,0 +1,137 @@
+package collector
+
+import (
+	"strings"
+
+	"github.com/kubelogs/kubelogs/internal/metrics"
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// RegisterMetrics exposes the collector's stream and batcher statistics
+// on r. Values are read at scrape time, so it may be called before Start.
+func (c *Collector) RegisterMetrics(r *metrics.Registry) {
+	batcher := func(f func(BatcherStats) float64) func() float64 {
+		return func() float64 {
+			if !c.started.Load() {
+				return 0
+			}
+			return f(c.batcher.Stats())
+		}
+	}
+
+	r.GaugeFunc("kubelogs_collector_active_streams", "Container log streams, or tailed files, currently open.", func() float64 {
+		if !c.started.Load() {
+			return 0
+		}
+		return float64(c.activeStreams())
+	})
+	r.GaugeFunc("kubelogs_collector_stream_limit", "Streams currently allowed by the startup ramp-up and kubelet pressure.", func() float64 {
+		if !c.started.Load() {
+			return 0
+		}
+		return float64(c.streamManager.StreamLimit())
+	})
+	r.CounterFunc("kubelogs_collector_api_throttled_total", "429 and 5xx responses from the kubelet and API server to streams.", func() float64 {
+		if !c.started.Load() {
+			return 0
+		}
+		return float64(c.streamManager.Throttled())
+	})
+	r.GaugeFunc("kubelogs_collector_catching_up_streams", "Streams reading the backlog written while the collector was down.", func() float64 {
+		if !c.started.Load() {
+			return 0
+		}
+		return float64(c.streamManager.CatchingUp())
+	})
+	r.CounterFunc("kubelogs_collector_lines_read_total", "Log lines read from containers.",
+		func() float64 {
+			if !c.started.Load() {
+				return 0
+			}
+			return float64(c.linesRead())
+		})
+	r.CounterFunc("kubelogs_collector_dropped_lines_total", "Log lines dropped because the batcher fell behind.",
+		func() float64 {
+			if !c.started.Load() {
+				return 0
+			}
+			return float64(c.streamManager.LinesDropped())
+		})
+	r.CounterFunc("kubelogs_collector_rate_limited_lines_total", "Log lines dropped over their container's rate limit.",
+		func() float64 {
+			if !c.started.Load() {
+				return 0
+			}
+			return float64(c.limiter.LimitedLines())
+		})
+	r.CounterFunc("kubelogs_collector_sampled_lines_total", "DEBUG and TRACE lines dropped by sampling.",
+		func() float64 {
+			if !c.started.Load() {
+				return 0
+			}
+			return float64(c.limiter.SampledLines())
+		})
+	r.CounterFunc("kubelogs_collector_errors_total", "Stream errors.",
+		func() float64 { return float64(c.totalErrors.Load()) })
+	r.CounterFunc("kubelogs_collector_merged_lines_total", "Continuation lines merged into the entry before them.",
+		func() float64 {
+			if !c.started.Load() || c.merger == nil {
+				return 0
+			}
+			return float64(c.merger.MergedLines())
+		})
+
+	discovery := func(f func(DiscoveryStats) float64) func() float64 {
+		return func() float64 {
+			if !c.started.Load() || c.discovery == nil {
+				return 0
+			}
+			return f(c.discovery.Stats())
+		}
+	}
+	r.GaugeFunc("kubelogs_collector_pod_events_queued", "Pod events waiting to be handled.",
+		discovery(func(s DiscoveryStats) float64 { return float64(s.QueuedEvents) }))
+	r.GaugeFunc("kubelogs_collector_pod_events_queue_capacity", "Pod events that can be queued before discovery blocks.",
+		discovery(func(s DiscoveryStats) float64 { return float64(s.QueueCapacity) }))
+	r.CounterFunc("kubelogs_collector_pod_events_blocked_total", "Pod events that found the queue full and had to wait.",
+		discovery(func(s DiscoveryStats) float64 { return float64(s.BlockedEvents) }))
+	r.CounterFunc("kubelogs_collector_pod_events_dropped_total", "Pod events dropped after waiting for a full queue.",
+		discovery(func(s DiscoveryStats) float64 { return float64(s.DroppedEvents) }))
+	r.CounterFunc("kubelogs_collector_pod_resyncs_skipped_total", "Informer resyncs of unchanged pods that were skipped.",
+		discovery(func(s DiscoveryStats) float64 { return float64(s.SkippedResyncs) }))
+
+	r.CounterFunc("kubelogs_collector_batch_writes_total", "Batches written to storage.",
+		batcher(func(s BatcherStats) float64 { return float64(s.TotalWrites) }))
+	r.CounterFunc("kubelogs_collector_written_entries_total", "Log entries written to storage.",
+		batcher(func(s BatcherStats) float64 { return float64(s.TotalEntries) }))
+	for sev := storage.SeverityUnknown; sev <= storage.SeverityFatal; sev++ {
+		r.CounterFunc("kubelogs_collector_written_entries_by_severity_total", "Log entries written to storage, by severity.",
+			batcher(func(s BatcherStats) float64 { return float64(s.WrittenSeverities[sev]) }),
+			"severity", strings.ToLower(sev.String()))
+	}
+	r.CounterFunc("kubelogs_collector_batch_write_errors_total", "Batch flushes that failed.",
+		batcher(func(s BatcherStats) float64 { return float64(s.WriteErrors) }))
+	r.CounterFunc("kubelogs_collector_retried_batches_total", "Batches written after being queued for retry.",
+		batcher(func(s BatcherStats) float64 { return float64(s.RetriedBatches) }))
+	r.CounterFunc("kubelogs_collector_dropped_entries_total", "Entries of batches dropped because the retry queue was full.",
+		batcher(func(s BatcherStats) float64 { return float64(s.DroppedEntries) }))
+	r.GaugeFunc("kubelogs_collector_buffered_entries", "Entries waiting for the next flush.",
+		batcher(func(s BatcherStats) float64 { return float64(s.BufferSize) }))
+	r.GaugeFunc("kubelogs_collector_retry_queue_batches", "Failed batches waiting to be retried.",
+		batcher(func(s BatcherStats) float64 { return float64(s.RetryQueueSize) }))
+	r.GaugeFunc("kubelogs_collector_spool_batches", "Failed batches kept on disk to be retried.",
+		batcher(func(s BatcherStats) float64 { return float64(s.Spool.Batches) }))
+	r.GaugeFunc("kubelogs_collector_spool_bytes", "Size of the batches kept on disk.",
+		batcher(func(s BatcherStats) float64 { return float64(s.Spool.Bytes) }))
+	r.CounterFunc("kubelogs_collector_spool_dropped_batches_total", "Spooled batches dropped because the spool was full or unreadable.",
+		batcher(func(s BatcherStats) float64 { return float64(s.Spool.DroppedBatches) }))
+	r.GaugeFunc("kubelogs_collector_write_slowdown", "Factor batch sizes and intervals are scaled by under server backpressure.",
+		batcher(func(s BatcherStats) float64 { return float64(s.Slowdown) }))
+	r.GaugeFunc("kubelogs_collector_circuit_open", "1 while writes are paused after repeated failures.",
+		batcher(func(s BatcherStats) float64 {
+			if s.CircuitOpen {
+				return 1
+			}
+			return 0
+		}))
+}
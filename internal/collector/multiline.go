@@ -0,0 +1,194 @@
+package collector
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Merger joins continuation lines, such as the frames of a stack trace,
+// to the line that starts their record, so each record is stored as one
+// entry. It sits between the stream manager and the batcher and keeps
+// one pending record per container.
+type Merger struct {
+	start    *regexp.Regexp
+	maxLines int
+	maxWait  time.Duration
+
+	input  <-chan LogLine
+	output chan LogLine
+
+	mu      sync.Mutex
+	pending map[string]*pendingRecord // By container key
+	unsent  []LogLine                 // Records not sent before shutdown
+
+	// Metrics
+	mergedLines atomic.Int64
+}
+
+// pendingRecord is a record waiting for more continuation lines.
+type pendingRecord struct {
+	line    LogLine
+	lines   []string
+	arrived time.Time
+}
+
+// NewMerger creates a merger reading from input. Lines matching start
+// begin a new record, as do lines the parser extracted structured fields
+// from; other lines are appended to the container's previous record. A
+// record is sent once it has maxLines lines or no line was added to it
+// for maxWait.
+func NewMerger(input <-chan LogLine, start *regexp.Regexp, maxLines int, maxWait time.Duration) *Merger {
+	return &Merger{
+		start:    start,
+		maxLines: maxLines,
+		maxWait:  maxWait,
+		input:    input,
+		output:   make(chan LogLine),
+		pending:  make(map[string]*pendingRecord),
+	}
+}
+
+// Output returns the channel of merged lines. It is closed when Run
+// returns.
+func (m *Merger) Output() <-chan LogLine {
+	return m.output
+}
+
+// MergedLines returns the number of lines appended to an earlier line.
+func (m *Merger) MergedLines() int64 {
+	return m.mergedLines.Load()
+}
+
+// Run merges lines until input is closed or ctx is canceled. Records
+// still pending on cancellation are left for Drain.
+func (m *Merger) Run(ctx context.Context) {
+	defer close(m.output)
+
+	ticker := time.NewTicker(max(m.maxWait/2, 10*time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case line, ok := <-m.input:
+			if !ok {
+				for _, rec := range m.Drain() {
+					if !m.send(ctx, rec) {
+						return
+					}
+				}
+				return
+			}
+			for _, rec := range m.add(line, time.Now()) {
+				if !m.send(ctx, rec) {
+					return
+				}
+			}
+
+		case now := <-ticker.C:
+			for _, rec := range m.expired(now) {
+				if !m.send(ctx, rec) {
+					return
+				}
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Drain removes and returns the records not yet sent, for a final flush
+// on shutdown.
+func (m *Merger) Drain() []LogLine {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	records := m.unsent
+	m.unsent = nil
+	pending := make([]*pendingRecord, 0, len(m.pending))
+	for key, p := range m.pending {
+		pending = append(pending, p)
+		delete(m.pending, key)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].arrived.Before(pending[j].arrived) })
+	for _, p := range pending {
+		records = append(records, p.record())
+	}
+	return records
+}
+
+// send delivers a record, keeping it for Drain if ctx is canceled first.
+func (m *Merger) send(ctx context.Context, line LogLine) bool {
+	select {
+	case m.output <- line:
+		return true
+	case <-ctx.Done():
+		m.mu.Lock()
+		m.unsent = append(m.unsent, line)
+		m.mu.Unlock()
+		return false
+	}
+}
+
+// add merges line into its container's pending record and returns the
+// records that are complete.
+func (m *Merger) add(line LogLine, now time.Time) []LogLine {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := line.Container.Key()
+	p := m.pending[key]
+
+	if p != nil && !m.startsRecord(line) {
+		p.lines = append(p.lines, line.Message)
+		p.arrived = now
+		m.mergedLines.Add(1)
+		if len(p.lines) < m.maxLines {
+			return nil
+		}
+		delete(m.pending, key)
+		return []LogLine{p.record()}
+	}
+
+	var done []LogLine
+	if p != nil {
+		done = append(done, p.record())
+	}
+	m.pending[key] = &pendingRecord{line: line, lines: []string{line.Message}, arrived: now}
+	return done
+}
+
+// expired removes and returns the records that waited maxWait for
+// another line.
+func (m *Merger) expired(now time.Time) []LogLine {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var done []LogLine
+	for key, p := range m.pending {
+		if now.Sub(p.arrived) >= m.maxWait {
+			done = append(done, p.record())
+			delete(m.pending, key)
+		}
+	}
+	return done
+}
+
+func (m *Merger) startsRecord(line LogLine) bool {
+	return line.Attributes != nil || m.start.MatchString(line.Message)
+}
+
+// record returns the merged line: the first line's timestamp, severity
+// and attributes with the messages of all lines.
+func (p *pendingRecord) record() LogLine {
+	line := p.line
+	if len(p.lines) > 1 {
+		line.Message = strings.Join(p.lines, "\n")
+	}
+	return line
+}
@@ -0,0 +1,127 @@
+package collector
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+func TestMerger(t *testing.T) {
+	app := ContainerRef{Namespace: "default", PodName: "web", PodUID: "uid-1", ContainerName: "app"}
+	sidecar := app
+	sidecar.ContainerName = "sidecar"
+	start := regexp.MustCompile(`^\d{4}-\d{2}-\d{2}`)
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	line := func(ref ContainerRef, msg string) LogLine {
+		return LogLine{Container: ref, Timestamp: t0, Message: msg}
+	}
+
+	tests := []struct {
+		name     string
+		maxLines int
+		lines    []LogLine
+		want     []string // Messages of records completed by the lines
+		pending  []string // Messages left for Drain
+	}{
+		{
+			name:     "stack trace",
+			maxLines: 10,
+			lines: []LogLine{
+				line(app, "2024-01-01 ERROR request failed"),
+				line(app, "java.lang.IllegalStateException: boom"),
+				line(app, "\tat com.example.Handler.run(Handler.java:42)"),
+				line(app, "2024-01-01 INFO next request"),
+			},
+			want:    []string{"2024-01-01 ERROR request failed\njava.lang.IllegalStateException: boom\n\tat com.example.Handler.run(Handler.java:42)"},
+			pending: []string{"2024-01-01 INFO next request"},
+		},
+		{
+			name:     "containers merge separately",
+			maxLines: 10,
+			lines: []LogLine{
+				line(app, "2024-01-01 app"),
+				line(sidecar, "2024-01-01 sidecar"),
+				line(app, "  app continued"),
+				line(sidecar, "  sidecar continued"),
+			},
+			pending: []string{"2024-01-01 app\n  app continued", "2024-01-01 sidecar\n  sidecar continued"},
+		},
+		{
+			name:     "max lines",
+			maxLines: 2,
+			lines: []LogLine{
+				line(app, "2024-01-01 start"),
+				line(app, "one"),
+				line(app, "two"),
+			},
+			want:    []string{"2024-01-01 start\none"},
+			pending: []string{"two"},
+		},
+		{
+			name:     "structured lines start records",
+			maxLines: 10,
+			lines: []LogLine{
+				line(app, "2024-01-01 start"),
+				{Container: app, Message: "json", Attributes: map[string]string{"level": "info"}},
+			},
+			want:    []string{"2024-01-01 start"},
+			pending: []string{"json"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMerger(nil, start, tt.maxLines, time.Second)
+			var got []string
+			for i, l := range tt.lines {
+				for _, rec := range m.add(l, t0.Add(time.Duration(i)*time.Millisecond)) {
+					got = append(got, rec.Message)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("records = %q, want %q", got, tt.want)
+			}
+			var pending []string
+			for _, rec := range m.Drain() {
+				pending = append(pending, rec.Message)
+			}
+			if !slices.Equal(pending, tt.pending) {
+				t.Errorf("pending = %q, want %q", pending, tt.pending)
+			}
+		})
+	}
+}
+
+func TestMerger_Run(t *testing.T) {
+	input := make(chan LogLine, 10)
+	m := NewMerger(input, regexp.MustCompile(`^\S`), 100, 20*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+
+	ref := ContainerRef{Namespace: "default", PodName: "web", PodUID: "uid-1", ContainerName: "app"}
+	input <- LogLine{Container: ref, Severity: storage.SeverityError, Message: "Traceback (most recent call last):"}
+	input <- LogLine{Container: ref, Message: `  File "app.py", line 1`}
+
+	// Sent once no continuation line arrives within the wait
+	select {
+	case rec := <-m.Output():
+		if rec.Message != "Traceback (most recent call last):\n  File \"app.py\", line 1" || rec.Severity != storage.SeverityError {
+			t.Errorf("record = %+v", rec)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("record not sent after max wait")
+	}
+	if m.MergedLines() != 1 {
+		t.Errorf("MergedLines() = %d, want 1", m.MergedLines())
+	}
+
+	close(input)
+	if _, ok := <-m.Output(); ok {
+		t.Error("output not closed after input")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -39,6 +40,10 @@ var jsonFieldAliases = map[string][]string{
 	"user_id":    {"user_id", "userId", "user"},
 }
 
+// severityFields are the field names checked (in order) for a log level.
+// They are always kept as attributes, even when maxAttributes is reached.
+var severityFields = []string{"level", "severity", "lvl"}
+
 // reverseAliases maps field aliases back to their canonical names for quick lookup.
 var reverseAliases = buildReverseAliases()
 
@@ -162,7 +167,7 @@ func (p *Parser) parseJSON(message string) (storage.Severity, map[string]string)
 
 	// Extract severity from common field names
 	severity := storage.SeverityUnknown
-	for _, key := range []string{"level", "severity", "lvl"} {
+	for _, key := range severityFields {
 		if val, ok := data[key]; ok {
 			if str, ok := val.(string); ok && str != "" {
 				severity = storage.ParseSeverity(str)
@@ -185,26 +190,41 @@ func (p *Parser) parseJSON(message string) (storage.Severity, map[string]string)
 func extractJSONFields(data map[string]any) map[string]string {
 	attrs := make(map[string]string)
 
-	// Extract all scalar fields, normalizing known aliases
-	for key, val := range data {
+	// Normalize known aliases first so the first listed alias wins
+	// regardless of map iteration order
+	for canonical, aliases := range jsonFieldAliases {
+		for _, alias := range aliases {
+			if str := stringifyValue(data[alias]); str != "" {
+				attrs[canonical] = str
+				break
+			}
+		}
+	}
+
+	for _, key := range severityFields {
+		if str := stringifyValue(data[key]); str != "" {
+			attrs[key] = str
+		}
+	}
+
+	// Extract remaining scalar fields in key order so the maxAttributes
+	// cutoff is deterministic
+	for _, key := range sortedKeys(data) {
 		if len(attrs) >= maxAttributes {
 			break
 		}
+		if _, ok := attrs[key]; ok {
+			continue
+		}
+		if _, ok := reverseAliases[key]; ok {
+			continue // Already normalized above
+		}
 
-		str := stringifyValue(val)
+		str := stringifyValue(data[key])
 		if str == "" {
 			continue // Skip non-scalar values
 		}
-
-		// Normalize known aliases to canonical names
-		if canonical, ok := reverseAliases[key]; ok {
-			// Only set if not already present (first alias wins)
-			if _, exists := attrs[canonical]; !exists {
-				attrs[canonical] = str
-			}
-		} else {
-			attrs[key] = str
-		}
+		attrs[key] = str
 	}
 
 	// Return nil if no fields extracted (saves memory)
@@ -238,6 +258,16 @@ func stringifyValue(val any) string {
 	}
 }
 
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // buildReverseAliases creates a map from field aliases to their canonical names.
 func buildReverseAliases() map[string]string {
 	reverse := make(map[string]string)
@@ -265,7 +295,7 @@ func (p *Parser) parseLogfmt(message string) (storage.Severity, map[string]strin
 
 	// Extract severity from common field names
 	severity := storage.SeverityUnknown
-	for _, key := range []string{"level", "severity", "lvl"} {
+	for _, key := range severityFields {
 		if val, ok := fields[key]; ok && val != "" {
 			severity = storage.ParseSeverity(val)
 			if severity != storage.SeverityUnknown {
@@ -399,23 +429,38 @@ func unescapeLogfmtValue(s string) string {
 func extractLogfmtAttrs(fields map[string]string) map[string]string {
 	attrs := make(map[string]string)
 
-	for key, val := range fields {
+	// Normalize known aliases first so the first listed alias wins
+	// regardless of map iteration order
+	for canonical, aliases := range jsonFieldAliases {
+		for _, alias := range aliases {
+			if val := fields[alias]; val != "" {
+				attrs[canonical] = val
+				break
+			}
+		}
+	}
+
+	for _, key := range severityFields {
+		if val := fields[key]; val != "" {
+			attrs[key] = val
+		}
+	}
+
+	for _, key := range sortedKeys(fields) {
 		if len(attrs) >= maxAttributes {
 			break
 		}
+		val := fields[key]
 		if val == "" {
 			continue
 		}
-
-		// Normalize known aliases to canonical names
-		if canonical, ok := reverseAliases[key]; ok {
-			// Only set if not already present (first alias wins)
-			if _, exists := attrs[canonical]; !exists {
-				attrs[canonical] = val
-			}
-		} else {
-			attrs[key] = val
+		if _, ok := attrs[key]; ok {
+			continue
+		}
+		if _, ok := reverseAliases[key]; ok {
+			continue // Already normalized above
 		}
+		attrs[key] = val
 	}
 
 	// Return nil if no fields extracted (saves memory)
@@ -1,12 +1,22 @@
 package collector
 
 import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/kubelogs/kubelogs/internal/fixtures"
 	"github.com/kubelogs/kubelogs/internal/storage"
 )
 
+var updateGolden = flag.Bool("update", false, "update golden files in testdata/")
+
 func TestParser_KubernetesTimestamp(t *testing.T) {
 	parser := NewParser()
 
@@ -601,6 +611,73 @@ func TestParser_MaxAttributesLimit(t *testing.T) {
 	}
 }
 
+func TestParser_AliasPrecedence(t *testing.T) {
+	parser := NewParser()
+
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"json", `2024-01-15T10:30:00Z {"level":"info","msg":"m","traceID":"d","trace-id":"c","traceId":"b","trace_id":"a"}`},
+		{"logfmt", `2024-01-15T10:30:00Z level=info msg=m traceID=d trace-id=c traceId=b trace_id=a`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The first alias listed wins, whatever the map order
+			for range 20 {
+				result := parser.Parse(tt.line)
+				if got := result.Attributes["trace_id"]; got != "a" {
+					t.Fatalf("trace_id = %q, want %q from the first listed alias", got, "a")
+				}
+				for _, alias := range []string{"traceID", "trace-id", "traceId"} {
+					if _, ok := result.Attributes[alias]; ok {
+						t.Errorf("alias %q kept as an attribute", alias)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParser_MaxAttributesDeterministic(t *testing.T) {
+	parser := NewParser()
+
+	// 25 fields sorting before "level", which would push it past the cap
+	var jsonFields, logfmtFields []string
+	for i := range 25 {
+		jsonFields = append(jsonFields, fmt.Sprintf(`"a%02d":"v"`, i))
+		logfmtFields = append(logfmtFields, fmt.Sprintf("a%02d=v", i))
+	}
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"json", `2024-01-15T10:30:00Z {"msg":"m",` + strings.Join(jsonFields, ",") + `,"level":"warn"}`},
+		{"logfmt", `2024-01-15T10:30:00Z msg=m ` + strings.Join(logfmtFields, " ") + ` level=warn`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Aliases and the level are kept first, then the other fields
+			// in key order up to the cap; msg counts until it becomes the
+			// message
+			want := map[string]string{"msg": "m", "level": "warn"}
+			for i := 0; len(want) < maxAttributes; i++ {
+				want[fmt.Sprintf("a%02d", i)] = "v"
+			}
+			delete(want, "msg")
+			for range 20 {
+				result := parser.Parse(tt.line)
+				if result.Severity != storage.SeverityWarn {
+					t.Errorf("severity = %v, want warn", result.Severity)
+				}
+				if !maps.Equal(result.Attributes, want) {
+					t.Fatalf("attributes = %v, want %v", result.Attributes, want)
+				}
+			}
+		})
+	}
+}
+
 func TestParser_ExtractsAllScalarFields(t *testing.T) {
 	parser := NewParser()
 
@@ -637,3 +714,76 @@ func TestParser_ExtractsAllScalarFields(t *testing.T) {
 		t.Errorf("should not extract arrays")
 	}
 }
+
+// goldenParse is the golden-file representation of a parsed line.
+type goldenParse struct {
+	Line       string            `json:"line"`
+	Severity   string            `json:"severity"`
+	Message    string            `json:"message"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// TestParser_Corpora checks parser output for every fixture corpus against
+// golden files in testdata/parser. Run with -update to regenerate them.
+func TestParser_Corpora(t *testing.T) {
+	parser := NewParser()
+
+	for _, corpus := range fixtures.All() {
+		t.Run(corpus.Name, func(t *testing.T) {
+			got := make([]goldenParse, 0, len(corpus.Lines))
+			for _, line := range corpus.Lines {
+				result := parser.Parse("2024-01-15T10:30:00.123456789Z " + line)
+				got = append(got, goldenParse{
+					Line:       line,
+					Severity:   result.Severity.String(),
+					Message:    result.Message,
+					Attributes: result.Attributes,
+				})
+			}
+
+			data, err := json.MarshalIndent(got, "", "  ")
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			data = append(data, '\n')
+
+			path := filepath.Join("testdata", "parser", corpus.Name+".golden.json")
+			if *updateGolden {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatalf("mkdir: %v", err)
+				}
+				if err := os.WriteFile(path, data, 0o644); err != nil {
+					t.Fatalf("write golden: %v", err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read golden (run with -update to create): %v", err)
+			}
+
+			var wantParsed []goldenParse
+			if err := json.Unmarshal(want, &wantParsed); err != nil {
+				t.Fatalf("unmarshal golden: %v", err)
+			}
+			if len(wantParsed) != len(got) {
+				t.Fatalf("golden has %d lines, corpus has %d (run with -update)", len(wantParsed), len(got))
+			}
+			for i := range got {
+				if string(mustJSON(t, got[i])) != string(mustJSON(t, wantParsed[i])) {
+					t.Errorf("line %d mismatch\n got: %s\nwant: %s", i+1, mustJSON(t, got[i]), mustJSON(t, wantParsed[i]))
+				}
+			}
+		})
+	}
+}
+
+func mustJSON(t *testing.T, v any) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return b
+}
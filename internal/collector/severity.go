@@ -0,0 +1,118 @@
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// SeverityRule sets the severity of the lines of matching containers
+// whose message matches a regular expression, e.g. 5xx responses in an
+// nginx access log, which has no level.
+type SeverityRule struct {
+	// Namespace and Container are globs the line's namespace and
+	// container must match. Empty matches any.
+	Namespace string `json:"namespace,omitempty"`
+	Container string `json:"container,omitempty"`
+
+	// Match is a regular expression the message must match.
+	Match string `json:"match"`
+
+	// Severity is the severity set, by name, e.g. "error".
+	Severity string `json:"severity"`
+
+	// Override also applies the rule to lines the parser found a
+	// severity in. By default only lines of unknown severity change.
+	Override bool `json:"override,omitempty"`
+}
+
+// severityRulesFile is the YAML document of a severity rules file.
+type severityRulesFile struct {
+	Rules []SeverityRule `json:"rules"`
+}
+
+// ReadSeverityRules reads the rules of the YAML file at name, such as
+// a mounted ConfigMap:
+//
+//	rules:
+//	  - container: nginx
+//	    match: '" 5\d\d '
+//	    severity: error
+func ReadSeverityRules(name string) ([]SeverityRule, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var f severityRulesFile
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", name, err)
+	}
+	return f.Rules, nil
+}
+
+// SeverityRules applies severity rules to lines after parsing. The
+// first matching rule wins.
+type SeverityRules struct {
+	rules []compiledSeverityRule
+}
+
+type compiledSeverityRule struct {
+	SeverityRule
+	match    *regexp.Regexp
+	severity storage.Severity
+}
+
+// NewSeverityRules compiles rules, checking their patterns and
+// severities.
+func NewSeverityRules(rules []SeverityRule) (*SeverityRules, error) {
+	s := &SeverityRules{rules: make([]compiledSeverityRule, 0, len(rules))}
+	for i, r := range rules {
+		re, err := regexp.Compile(r.Match)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		sev := storage.ParseSeverity(r.Severity)
+		if sev == storage.SeverityUnknown {
+			return nil, fmt.Errorf("rule %d: invalid severity %q", i+1, r.Severity)
+		}
+		for _, glob := range []string{r.Namespace, r.Container} {
+			if _, err := path.Match(glob, ""); err != nil {
+				return nil, fmt.Errorf("rule %d: invalid pattern %q", i+1, glob)
+			}
+		}
+		s.rules = append(s.rules, compiledSeverityRule{SeverityRule: r, match: re, severity: sev})
+	}
+	return s, nil
+}
+
+// Apply returns the severity of line under the rules: that of the first
+// rule matching it, or its own.
+func (s *SeverityRules) Apply(line LogLine) storage.Severity {
+	for _, r := range s.rules {
+		if line.Severity != storage.SeverityUnknown && !r.Override {
+			continue
+		}
+		if !matchGlob(r.Namespace, line.Container.Namespace) || !matchGlob(r.Container, line.Container.ContainerName) {
+			continue
+		}
+		if r.match.MatchString(line.Message) {
+			return r.severity
+		}
+	}
+	return line.Severity
+}
+
+// matchGlob reports whether name matches glob; an empty glob matches
+// any name.
+func matchGlob(glob, name string) bool {
+	if glob == "" {
+		return true
+	}
+	ok, _ := path.Match(glob, name)
+	return ok
+}
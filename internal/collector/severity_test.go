@@ -0,0 +1,88 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+func TestSeverityRules(t *testing.T) {
+	rules, err := NewSeverityRules([]SeverityRule{
+		{Container: "nginx", Match: `" 5\d\d `, Severity: "error"},
+		{Container: "nginx", Match: `" 4\d\d `, Severity: "warn"},
+		{Namespace: "batch-*", Match: `^Traceback`, Severity: "ERROR", Override: true},
+	})
+	if err != nil {
+		t.Fatalf("NewSeverityRules: %v", err)
+	}
+
+	nginx := ContainerRef{Namespace: "web", PodName: "web-1", ContainerName: "nginx"}
+	job := ContainerRef{Namespace: "batch-nightly", PodName: "job-1", ContainerName: "main"}
+	tests := []struct {
+		name string
+		line LogLine
+		want storage.Severity
+	}{
+		{"5xx", LogLine{Container: nginx, Message: `10.0.0.1 - - "GET / HTTP/1.1" 502 157`}, storage.SeverityError},
+		{"4xx", LogLine{Container: nginx, Message: `10.0.0.1 - - "GET /x HTTP/1.1" 404 0`}, storage.SeverityWarn},
+		{"2xx", LogLine{Container: nginx, Message: `10.0.0.1 - - "GET / HTTP/1.1" 200 612`}, storage.SeverityUnknown},
+		{"parsed severity kept", LogLine{Container: nginx, Severity: storage.SeverityInfo, Message: `"GET / HTTP/1.1" 503 0`}, storage.SeverityInfo},
+		{"other container", LogLine{Container: job, Message: `"GET / HTTP/1.1" 503 0`}, storage.SeverityUnknown},
+		{"override", LogLine{Container: job, Severity: storage.SeverityInfo, Message: "Traceback (most recent call last):"}, storage.SeverityError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rules.Apply(tt.line); got != tt.want {
+				t.Errorf("Apply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, bad := range []SeverityRule{
+		{Match: "(", Severity: "error"},
+		{Match: "x", Severity: "loud"},
+		{Container: "[", Match: "x", Severity: "error"},
+	} {
+		if _, err := NewSeverityRules([]SeverityRule{bad}); err == nil {
+			t.Errorf("NewSeverityRules(%+v) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestReadSeverityRules(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "rules.yaml")
+	data := `rules:
+  - container: nginx
+    match: '" 5\d\d '
+    severity: error
+  - namespace: batch-*
+    match: ^Traceback
+    severity: error
+    override: true
+`
+	if err := os.WriteFile(name, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := ReadSeverityRules(name)
+	if err != nil {
+		t.Fatalf("ReadSeverityRules: %v", err)
+	}
+	want := []SeverityRule{
+		{Container: "nginx", Match: `" 5\d\d `, Severity: "error"},
+		{Namespace: "batch-*", Match: "^Traceback", Severity: "error", Override: true},
+	}
+	if len(rules) != len(want) || rules[0] != want[0] || rules[1] != want[1] {
+		t.Errorf("rules = %+v, want %+v", rules, want)
+	}
+
+	// Misspelled fields are reported rather than ignored
+	if err := os.WriteFile(name, []byte("rules:\n  - containr: nginx\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadSeverityRules(name); err == nil {
+		t.Error("ReadSeverityRules with an unknown field succeeded, want error")
+	}
+}
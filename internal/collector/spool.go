@@ -0,0 +1,215 @@
+package collector
+
+import (
+	"cmp"
+	"encoding/gob"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// spoolExt is the extension of segment files; files being written carry
+// a further ".tmp" until they are complete.
+const spoolExt = ".batch"
+
+// Spool keeps batches that failed to write in segment files on disk, so
+// they survive a collector restart or a storage outage longer than the
+// in-memory retry queue lasts. Each segment holds one gob-encoded batch
+// and is named after its sequence number, so the oldest sorts first.
+type Spool struct {
+	dir      string
+	maxBytes int64
+
+	mu       sync.Mutex
+	segments []spoolSegment // Oldest first
+	size     int64          // Bytes of all segments
+	next     uint64         // Sequence number of the next segment
+
+	droppedBatches atomic.Int64
+}
+
+// spoolSegment is a segment file.
+type spoolSegment struct {
+	seq  uint64
+	size int64
+}
+
+// OpenSpool opens the spool in dir, creating it if needed, and picks up
+// the segments left by a previous run. When segments add up to more
+// than maxBytes the oldest are dropped; 0 means no limit.
+func OpenSpool(dir string, maxBytes int64) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Spool{dir: dir, maxBytes: maxBytes}
+	for _, f := range files {
+		name := f.Name()
+		if strings.HasSuffix(name, spoolExt+".tmp") {
+			// Interrupted while writing
+			os.Remove(filepath.Join(dir, name))
+			continue
+		}
+		base, ok := strings.CutSuffix(name, spoolExt)
+		if !ok {
+			continue
+		}
+		seq, err := strconv.ParseUint(base, 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			return nil, err
+		}
+		s.segments = append(s.segments, spoolSegment{seq: seq, size: info.Size()})
+		s.size += info.Size()
+		s.next = max(s.next, seq+1)
+	}
+	slices.SortFunc(s.segments, func(a, b spoolSegment) int {
+		return cmp.Compare(a.seq, b.seq)
+	})
+
+	if len(s.segments) > 0 {
+		slog.Info("spool has batches from a previous run", "dir", dir, "batches", len(s.segments), "bytes", s.size)
+	}
+	return s, nil
+}
+
+func (s *Spool) path(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", seq, spoolExt))
+}
+
+// Put writes batch to a new segment, synced to disk before it returns,
+// then drops the oldest segments while the spool exceeds its size.
+func (s *Spool) Put(batch storage.LogBatch) error {
+	s.mu.Lock()
+	seq := s.next
+	s.next++
+	s.mu.Unlock()
+
+	path := s.path(seq)
+	size, err := writeSegment(path, batch)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.segments = append(s.segments, spoolSegment{seq: seq, size: size})
+	s.size += size
+	for s.maxBytes > 0 && s.size > s.maxBytes && len(s.segments) > 1 {
+		old := s.segments[0]
+		s.segments = s.segments[1:]
+		s.size -= old.size
+		os.Remove(s.path(old.seq))
+		s.droppedBatches.Add(1)
+		slog.Warn("spool full, dropping oldest batch", "dir", s.dir, "bytes", old.size)
+	}
+	return nil
+}
+
+// writeSegment writes batch to path through a temporary file, so a
+// segment is either complete or absent. It returns the file's size.
+func writeSegment(path string, batch storage.LogBatch) (int64, error) {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+	err = gob.NewEncoder(f).Encode(batch)
+	if err == nil {
+		err = f.Sync()
+	}
+	var size int64
+	if err == nil {
+		var info os.FileInfo
+		if info, err = f.Stat(); err == nil {
+			size = info.Size()
+		}
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+	return size, nil
+}
+
+// Oldest returns the oldest batch and its sequence number, for Remove
+// once it's written. ok is false if the spool is empty. Segments that
+// can't be read are dropped.
+func (s *Spool) Oldest() (batch storage.LogBatch, seq uint64, ok bool) {
+	for {
+		s.mu.Lock()
+		if len(s.segments) == 0 {
+			s.mu.Unlock()
+			return nil, 0, false
+		}
+		seq = s.segments[0].seq
+		s.mu.Unlock()
+
+		f, err := os.Open(s.path(seq))
+		if err == nil {
+			err = gob.NewDecoder(f).Decode(&batch)
+			f.Close()
+		}
+		if err == nil {
+			return batch, seq, true
+		}
+		slog.Error("dropping unreadable spool segment", "path", s.path(seq), "error", err)
+		s.Remove(seq)
+		s.droppedBatches.Add(1)
+	}
+}
+
+// Remove deletes the segment with sequence number seq, if it's still
+// spooled.
+func (s *Spool) Remove(seq uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.segments, func(sg spoolSegment) bool { return sg.seq == seq })
+	if i < 0 {
+		return
+	}
+	s.size -= s.segments[i].size
+	s.segments = slices.Delete(s.segments, i, i+1)
+	if err := os.Remove(s.path(seq)); err != nil && !os.IsNotExist(err) {
+		slog.Warn("failed to remove spool segment", "path", s.path(seq), "error", err)
+	}
+}
+
+// SpoolStats contains spool statistics.
+type SpoolStats struct {
+	Batches        int
+	Bytes          int64
+	DroppedBatches int64 // Dropped to stay under the size limit, or unreadable
+}
+
+// Stats returns spool statistics.
+func (s *Spool) Stats() SpoolStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SpoolStats{
+		Batches:        len(s.segments),
+		Bytes:          s.size,
+		DroppedBatches: s.droppedBatches.Load(),
+	}
+}
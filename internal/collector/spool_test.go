@@ -0,0 +1,98 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+func TestSpool(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenSpool(dir, 0)
+	if err != nil {
+		t.Fatalf("OpenSpool: %v", err)
+	}
+
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, msg := range []string{"first", "second", "third"} {
+		err := s.Put(storage.LogBatch{{
+			Timestamp:  ts,
+			Namespace:  "shop",
+			Message:    msg,
+			Severity:   storage.SeverityError,
+			Attributes: map[string]string{"pod_uid": "abc"},
+		}})
+		if err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	if stats := s.Stats(); stats.Batches != 3 || stats.Bytes == 0 {
+		t.Fatalf("stats = %+v, want 3 batches", stats)
+	}
+
+	batch, seq, ok := s.Oldest()
+	if !ok || len(batch) != 1 || batch[0].Message != "first" || !batch[0].Timestamp.Equal(ts) ||
+		batch[0].Severity != storage.SeverityError || batch[0].Attributes["pod_uid"] != "abc" {
+		t.Fatalf("Oldest() = %+v, %v, want the first batch", batch, ok)
+	}
+	s.Remove(seq)
+
+	// A restart picks up the remaining segments, and discards one that
+	// was being written
+	os.WriteFile(filepath.Join(dir, "00000000000000000099.batch.tmp"), []byte("partial"), 0o644)
+	s, err = OpenSpool(dir, 0)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if stats := s.Stats(); stats.Batches != 2 {
+		t.Errorf("after reopen: %d batches, want 2", stats.Batches)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "00000000000000000099.batch.tmp")); !os.IsNotExist(err) {
+		t.Errorf("temporary segment kept: %v", err)
+	}
+	s.Put(storage.LogBatch{{Message: "fourth"}})
+
+	// An unreadable segment is dropped
+	os.WriteFile(s.path(s.segments[0].seq), []byte("garbage"), 0o644)
+	var got []string
+	for {
+		batch, seq, ok := s.Oldest()
+		if !ok {
+			break
+		}
+		got = append(got, batch[0].Message)
+		s.Remove(seq)
+	}
+	if len(got) != 2 || got[0] != "third" || got[1] != "fourth" {
+		t.Errorf("replayed %v, want [third fourth]", got)
+	}
+	if stats := s.Stats(); stats.Batches != 0 || stats.Bytes != 0 || stats.DroppedBatches != 1 {
+		t.Errorf("stats = %+v, want empty with 1 dropped", stats)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("%d files left in the spool", len(files))
+	}
+}
+
+func TestSpool_MaxBytes(t *testing.T) {
+	s, err := OpenSpool(t.TempDir(), 1)
+	if err != nil {
+		t.Fatalf("OpenSpool: %v", err)
+	}
+
+	// The newest batch is kept even if it alone exceeds the limit
+	for _, msg := range []string{"first", "second", "third"} {
+		if err := s.Put(storage.LogBatch{{Message: msg}}); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	if stats := s.Stats(); stats.Batches != 1 || stats.DroppedBatches != 2 {
+		t.Errorf("stats = %+v, want 1 batch and 2 dropped", stats)
+	}
+	if batch, _, _ := s.Oldest(); batch[0].Message != "third" {
+		t.Errorf("kept %q, want third", batch[0].Message)
+	}
+}
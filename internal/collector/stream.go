@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -51,24 +54,64 @@ type Stream struct {
 	sinceTime   time.Time
 	idleTimeout time.Duration
 
+	// throttle, if set, is told how the kubelet and API server respond
+	throttle *streamThrottle
+
+	// totalLines, if set, counts the lines of every stream of the manager,
+	// and droppedLines those dropped because its output was full
+	totalLines   *atomic.Int64
+	droppedLines *atomic.Int64
+
+	// onDrop, if set, is told of each line dropped because the output was
+	// full
+	onDrop func(line LogLine)
+
+	// catchUpLag, if set, is how far the cursor must trail now for the
+	// stream to catch up; catchingUp counts the streams that are, shared
+	// by the stream manager
+	catchUpLag time.Duration
+	catchingUp *atomic.Int64
+
 	mu           sync.Mutex
 	running      bool
 	linesRead    int64
 	errors       int
+	gaps         int // Rotation gaps found on reconnect
 	lastError    error
 	startedAt    time.Time
 	lastSentTime time.Time // Cursor: timestamp of last successfully sent log
+	catchUpFrom  time.Time // Cursor when catch-up began; zero unless catching up
 }
 
+const (
+	// gapProbeBytes is how much of a container's log is read to find
+	// the oldest line the kubelet still has.
+	gapProbeBytes = 4096
+
+	// minLogGap is the shortest rotation gap reported.
+	minLogGap = time.Second
+)
+
+// caughtUpIdle is how long a catching-up stream may wait for a line
+// before its backlog is considered read.
+const caughtUpIdle = time.Second
+
 // StreamStats contains stream statistics.
 type StreamStats struct {
 	Container    ContainerRef
 	Running      bool
 	LinesRead    int64
 	Errors       int
+	Gaps         int // Logs rotated away before they were read
 	LastError    error
 	StartedAt    time.Time
 	LastSentTime time.Time // Cursor position for debugging
+
+	// Catch-up progress: the share of the gap between CatchUpFrom and
+	// now that has been read, from 0 to 1
+	CatchingUp      bool
+	CatchUpFrom     time.Time
+	CatchUpProgress float64
 }
 
 // NewStream creates a stream for the given container.
@@ -101,6 +144,7 @@ func (s *Stream) Start(ctx context.Context) error {
 	defer func() {
 		s.mu.Lock()
 		s.running = false
+		s.endCatchUp()
 		s.mu.Unlock()
 	}()
 
@@ -110,12 +154,19 @@ func (s *Stream) Start(ctx context.Context) error {
 	for {
 		// Update sinceTime from cursor before each run attempt
 		s.mu.Lock()
-		if !s.lastSentTime.IsZero() {
+		resuming := !s.lastSentTime.IsZero()
+		if resuming {
 			// Add 1ns to exclude the last sent log (SinceTime is inclusive)
 			s.sinceTime = s.lastSentTime.Add(time.Nanosecond)
 		}
+		since := s.sinceTime
+		s.beginCatchUp(time.Now())
 		s.mu.Unlock()
 
+		if resuming {
+			s.reportGap(ctx, since)
+		}
+
 		err := s.run(ctx)
 		if err == nil {
 			return nil // Normal termination (pod finished)
@@ -160,6 +211,9 @@ func (s *Stream) run(ctx context.Context) error {
 
 	req := s.clientset.CoreV1().Pods(s.ref.Namespace).GetLogs(s.ref.PodName, opts)
 	stream, err := req.Stream(ctx)
+	if ctx.Err() == nil {
+		s.observe(err)
+	}
 	if err != nil {
 		return fmt.Errorf("open log stream: %w", err)
 	}
@@ -190,6 +244,12 @@ func (s *Stream) run(ctx context.Context) error {
 	go scanNext()
 
 	for {
+		// While catching up, a pause in the backlog means it has been read
+		var caughtUp <-chan time.Time
+		if s.isCatchingUp() {
+			caughtUp = time.After(caughtUpIdle)
+		}
+
 		select {
 		case result := <-scanCh:
 			if !result.hasNext {
@@ -220,11 +280,17 @@ func (s *Stream) run(ctx context.Context) error {
 
 			select {
 			case s.output <- logLine:
+				if s.totalLines != nil {
+					s.totalLines.Add(1)
+				}
 				s.mu.Lock()
 				s.linesRead++
 				if logLine.Timestamp.After(s.lastSentTime) {
 					s.lastSentTime = logLine.Timestamp
 				}
+				if !s.catchUpFrom.IsZero() && time.Since(s.lastSentTime) <= s.catchUpLag {
+					s.endCatchUp()
+				}
 				s.mu.Unlock()
 			case <-ctx.Done():
 				return ctx.Err()
@@ -233,6 +299,12 @@ func (s *Stream) run(ctx context.Context) error {
 				slog.Warn("output channel full, dropping log line",
 					"container", s.ref.Key(),
 				)
+				if s.droppedLines != nil {
+					s.droppedLines.Add(1)
+				}
+				if s.onDrop != nil {
+					s.onDrop(logLine)
+				}
 				// Still update cursor to avoid re-sending dropped logs on reconnect
 				s.mu.Lock()
 				if logLine.Timestamp.After(s.lastSentTime) {
@@ -244,6 +316,11 @@ func (s *Stream) run(ctx context.Context) error {
 			// Start next scan
 			go scanNext()
 
+		case <-caughtUp:
+			s.mu.Lock()
+			s.endCatchUp()
+			s.mu.Unlock()
+
 		case <-time.After(s.idleTimeout):
 			// No log line received within idle timeout - connection may be stale
 			slog.Warn("stream idle timeout, reconnecting",
@@ -258,20 +335,161 @@ func (s *Stream) run(ctx context.Context) error {
 	}
 }
 
+// reportGap writes a synthetic entry if the kubelet has rotated away
+// logs written after since, the cursor a reconnecting stream resumes
+// from: its oldest retained line is newer, so the lines in between, if
+// any, can't be read any more.
+func (s *Stream) reportGap(ctx context.Context, since time.Time) {
+	oldest, ok := s.oldestRetained(ctx)
+	if !ok || oldest.Sub(since) < minLogGap {
+		return
+	}
+
+	slog.Warn("logs rotated away before they were read",
+		"container", s.ref.Key(),
+		"from", since,
+		"to", oldest,
+	)
+	s.mu.Lock()
+	s.gaps++
+	s.mu.Unlock()
+
+	select {
+	case s.output <- gapLine(s.ref, since, oldest):
+	case <-ctx.Done():
+	}
+}
+
+// oldestRetained returns the timestamp of the oldest log line the
+// kubelet still has for the container, or false if it can't tell.
+func (s *Stream) oldestRetained(ctx context.Context) (time.Time, bool) {
+	limit := int64(gapProbeBytes)
+	opts := &corev1.PodLogOptions{
+		Container:  s.ref.ContainerName,
+		Timestamps: true,
+		LimitBytes: &limit,
+	}
+	stream, err := s.clientset.CoreV1().Pods(s.ref.Namespace).GetLogs(s.ref.PodName, opts).Stream(ctx)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer stream.Close()
+
+	// A long first line is cut off by the limit, but starts with its timestamp
+	line, err := bufio.NewReader(stream).ReadString('\n')
+	if err != nil && line == "" {
+		return time.Time{}, false
+	}
+	return lineTimestamp(line)
+}
+
+// lineTimestamp parses the timestamp the kubelet prefixes log lines with.
+func lineTimestamp(line string) (time.Time, bool) {
+	ts, _, ok := strings.Cut(line, " ")
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	return t, err == nil
+}
+
+// gapLine builds the synthetic WARN entry recording that the container's
+// logs from from to to were rotated away before they were read,
+// timestamped at the start of the gap.
+func gapLine(ref ContainerRef, from, to time.Time) LogLine {
+	seconds := int64(to.Sub(from).Round(time.Second) / time.Second)
+	return LogLine{
+		Container: ref,
+		Timestamp: from,
+		Severity:  storage.SeverityWarn,
+		Message:   fmt.Sprintf("gap: ~%d seconds of logs unavailable, rotated by the kubelet before they were read", seconds),
+		Attributes: map[string]string{
+			"event":       "log_gap",
+			"gap_start":   from.UTC().Format(time.RFC3339Nano),
+			"gap_end":     to.UTC().Format(time.RFC3339Nano),
+			"gap_seconds": strconv.FormatInt(seconds, 10),
+		},
+	}
+}
+
+// beginCatchUp enters catch-up mode if the stream's cursor trails now
+// by more than catchUpLag. Callers hold s.mu.
+func (s *Stream) beginCatchUp(now time.Time) {
+	if s.catchUpLag <= 0 || !s.catchUpFrom.IsZero() || s.sinceTime.IsZero() || now.Sub(s.sinceTime) <= s.catchUpLag {
+		return
+	}
+	s.catchUpFrom = s.sinceTime
+	if s.catchingUp != nil {
+		s.catchingUp.Add(1)
+	}
+	slog.Info("stream catching up",
+		"container", s.ref.Key(),
+		"since", s.sinceTime,
+		"lag", now.Sub(s.sinceTime).Round(time.Second),
+	)
+}
+
+// endCatchUp leaves catch-up mode. Callers hold s.mu.
+func (s *Stream) endCatchUp() {
+	if s.catchUpFrom.IsZero() {
+		return
+	}
+	s.catchUpFrom = time.Time{}
+	if s.catchingUp != nil {
+		s.catchingUp.Add(-1)
+	}
+	slog.Info("stream caught up",
+		"container", s.ref.Key(),
+		"linesRead", s.linesRead,
+	)
+}
+
+// isCatchingUp reports whether the stream is in catch-up mode.
+func (s *Stream) isCatchingUp() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.catchUpFrom.IsZero()
+}
+
+// catchUpProgress returns the share of the gap between from and now
+// covered by cursor, from 0 to 1.
+func catchUpProgress(from, cursor, now time.Time) float64 {
+	gap := now.Sub(from)
+	if gap <= 0 || !cursor.After(from) {
+		return 0
+	}
+	return min(float64(cursor.Sub(from))/float64(gap), 1)
+}
+
 // Stats returns stream statistics.
 func (s *Stream) Stats() StreamStats {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	return StreamStats{
+	stats := StreamStats{
 		Container:    s.ref,
 		Running:      s.running,
 		LinesRead:    s.linesRead,
 		Errors:       s.errors,
+		Gaps:         s.gaps,
 		LastError:    s.lastError,
 		StartedAt:    s.startedAt,
 		LastSentTime: s.lastSentTime,
 	}
+	if !s.catchUpFrom.IsZero() {
+		stats.CatchingUp = true
+		stats.CatchUpFrom = s.catchUpFrom
+		stats.CatchUpProgress = catchUpProgress(s.catchUpFrom, s.lastSentTime, time.Now())
+	}
+	return stats
+}
+
+// observe reports the result of a call to the kubelet or API server to
+// the throttle, if any.
+func (s *Stream) observe(err error) {
+	if s.throttle != nil {
+		s.throttle.observe(err, time.Now())
+	}
 }
 
 // isContainerRunning checks if the container is still running in the cluster.
@@ -279,6 +497,7 @@ func (s *Stream) Stats() StreamStats {
 func (s *Stream) isContainerRunning(ctx context.Context) bool {
 	pod, err := s.clientset.CoreV1().Pods(s.ref.Namespace).Get(ctx, s.ref.PodName, metav1.GetOptions{})
 	if err != nil {
+		s.observe(err)
 		// Can't reach API server or pod doesn't exist - assume not running
 		return false
 	}
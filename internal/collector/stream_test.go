@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+func TestLineTimestamp(t *testing.T) {
+	tests := []struct {
+		line   string
+		want   time.Time
+		wantOK bool
+	}{
+		{"2024-01-15T10:30:00.123456789Z hello\n", time.Date(2024, 1, 15, 10, 30, 0, 123456789, time.UTC), true},
+		{"2024-01-15T10:30:00Z truncated li", time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC), true},
+		{"fake logs", time.Time{}, false},
+		{"", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := lineTimestamp(tt.line)
+		if ok != tt.wantOK || !got.Equal(tt.want) {
+			t.Errorf("lineTimestamp(%q) = %v, %v, want %v, %v", tt.line, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestGapLine(t *testing.T) {
+	ref := ContainerRef{Namespace: "default", PodName: "api-0", ContainerName: "app"}
+	from := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	line := gapLine(ref, from, from.Add(90*time.Second+400*time.Millisecond))
+
+	if line.Container != ref || !line.Timestamp.Equal(from) || line.Severity != storage.SeverityWarn {
+		t.Errorf("gapLine = %+v", line)
+	}
+	if want := "gap: ~90 seconds of logs unavailable, rotated by the kubelet before they were read"; line.Message != want {
+		t.Errorf("Message = %q, want %q", line.Message, want)
+	}
+	want := map[string]string{
+		"event":       "log_gap",
+		"gap_start":   "2024-01-15T10:00:00Z",
+		"gap_end":     "2024-01-15T10:01:30.4Z",
+		"gap_seconds": "90",
+	}
+	for k, v := range want {
+		if line.Attributes[k] != v {
+			t.Errorf("Attributes[%s] = %q, want %q", k, line.Attributes[k], v)
+		}
+	}
+}
+
+func TestStream_ReportGapWithoutTimestamps(t *testing.T) {
+	// The fake clientset serves "fake logs", which has no timestamp, so no
+	// gap can be measured and none is reported
+	output := make(chan LogLine, 1)
+	s := NewStream(fake.NewSimpleClientset(), ContainerRef{Namespace: "default", PodName: "api-0", ContainerName: "app"},
+		output, NewParser(), time.Time{}, time.Minute)
+
+	s.reportGap(context.Background(), time.Now().Add(-time.Hour))
+	if len(output) != 0 || s.Stats().Gaps != 0 {
+		t.Errorf("reported a gap without timestamps: %d lines, %d gaps", len(output), s.Stats().Gaps)
+	}
+}
@@ -2,8 +2,11 @@ package collector
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"math/rand/v2"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"k8s.io/client-go/kubernetes"
@@ -19,6 +22,34 @@ type StreamManager struct {
 	idleTimeout time.Duration
 	parser      *Parser
 
+	// Startup smoothing: streams opened in the first rampUp (or
+	// startJitter) after Start wait up to startJitter, and the number of
+	// streams grows linearly to maxStreams over rampUp
+	startJitter time.Duration
+	rampUp      time.Duration
+	startedAt   time.Time
+
+	// throttle, if set, lowers the number of streams while the kubelet
+	// or API server answer 429 or 5xx
+	throttle *streamThrottle
+
+	// Streams whose cursor trails now by more than catchUpLag catch up;
+	// catchingUp counts them
+	catchUpLag time.Duration
+	catchingUp atomic.Int64
+
+	linesRead    atomic.Int64 // By all streams, including ended ones
+	linesDropped atomic.Int64 // Because the output was full
+
+	// onStreamStart and onStreamEnd, if set, are told when a stream is
+	// opened and why it ended
+	onStreamStart func(ref ContainerRef)
+	onStreamEnd   func(ref ContainerRef, end StreamEnd)
+
+	// onLineDropped, if set, is told of each line a stream dropped
+	// because the output was full
+	onLineDropped func(line LogLine)
+
 	mu      sync.RWMutex
 	streams map[string]*managedStream
 
@@ -62,9 +93,103 @@ func (m *StreamManager) Output() <-chan LogLine {
 	return m.output
 }
 
+// SetStartupSmoothing spreads out the streams of the containers already
+// running when the collector starts, so they don't all hit the kubelet
+// and the server at once: streams opened within rampUp (or startJitter,
+// if longer) of Start wait a random delay of up to startJitter, and the
+// number of streams is ramped up from a tenth of maxStreams to all of
+// them over rampUp. Zero disables either. Must be called before Start.
+func (m *StreamManager) SetStartupSmoothing(startJitter, rampUp time.Duration) {
+	m.startJitter = startJitter
+	m.rampUp = rampUp
+}
+
+// SetCatchUpLag puts streams whose cursor trails now by more than lag
+// in catch-up mode until they read recent lines. Zero disables it. Must
+// be called before Start.
+func (m *StreamManager) SetCatchUpLag(lag time.Duration) {
+	m.catchUpLag = lag
+}
+
+// SetAdaptiveLimit makes the number of streams adapt to kubelet and API
+// server pressure: halved on every 429 or 5xx response, and raised by
+// one per stream opened without one, up to maxStreams. Streams already
+// open are kept; new ones wait. Must be called before Start.
+func (m *StreamManager) SetAdaptiveLimit(enabled bool) {
+	m.throttle = nil
+	if enabled {
+		m.throttle = newStreamThrottle(m.maxStreams)
+	}
+}
+
 // Start initializes the stream manager.
 func (m *StreamManager) Start(ctx context.Context) {
 	m.ctx, m.cancel = context.WithCancel(ctx)
+	m.startedAt = time.Now()
+}
+
+// rampLimit returns the number of streams allowed elapsed into a ramp-up
+// of rampUp to maxStreams, starting from a tenth of them.
+func rampLimit(maxStreams int, elapsed, rampUp time.Duration) int {
+	if rampUp <= 0 || elapsed >= rampUp {
+		return maxStreams
+	}
+	initial := max(maxStreams/10, 1)
+	return min(initial+int(int64(maxStreams-initial)*int64(elapsed)/int64(rampUp)), maxStreams)
+}
+
+// streamLimit returns the number of streams currently allowed, by the
+// ramp-up and the adaptive limit.
+func (m *StreamManager) streamLimit(elapsed time.Duration) int {
+	limit := rampLimit(m.maxStreams, elapsed, m.rampUp)
+	if m.throttle != nil {
+		limit = min(limit, m.throttle.Limit())
+	}
+	return limit
+}
+
+// StreamLimit returns the number of streams currently allowed.
+func (m *StreamManager) StreamLimit() int {
+	return m.streamLimit(time.Since(m.startedAt))
+}
+
+// Throttled returns the number of 429 and 5xx responses from the kubelet
+// and API server seen by streams, if the limit is adaptive.
+func (m *StreamManager) Throttled() int64 {
+	if m.throttle == nil {
+		return 0
+	}
+	return m.throttle.Throttled()
+}
+
+// waitForSlot blocks while the ramp-up or adaptive limit allow no more
+// streams. Below them, the semaphore alone limits streams.
+func (m *StreamManager) waitForSlot() error {
+	for {
+		elapsed := time.Since(m.startedAt)
+		if (m.throttle == nil && elapsed >= m.rampUp) || m.ActiveStreams() < m.streamLimit(elapsed) {
+			return nil
+		}
+
+		// Check again when the limit grows, or sooner if a stream ends
+		wait := 100 * time.Millisecond
+		if elapsed < m.rampUp {
+			wait = min(max(m.rampUp/time.Duration(m.maxStreams), 10*time.Millisecond), time.Second)
+		}
+		select {
+		case <-time.After(wait):
+		case <-m.ctx.Done():
+			return m.ctx.Err()
+		}
+	}
+}
+
+// startDelay returns the random delay before a new stream is opened.
+func (m *StreamManager) startDelay() time.Duration {
+	if m.startJitter <= 0 || time.Since(m.startedAt) >= max(m.rampUp, m.startJitter) {
+		return 0
+	}
+	return rand.N(m.startJitter)
 }
 
 // StartStream begins streaming logs for a container.
@@ -80,6 +205,10 @@ func (m *StreamManager) StartStream(ref ContainerRef) error {
 	}
 	m.mu.Unlock()
 
+	if err := m.waitForSlot(); err != nil {
+		return err
+	}
+
 	// Acquire semaphore slot (may block)
 	select {
 	case m.streamSem <- struct{}{}:
@@ -91,6 +220,12 @@ func (m *StreamManager) StartStream(ref ContainerRef) error {
 	streamCtx, streamCancel := context.WithCancel(m.ctx)
 
 	stream := NewStream(m.clientset, ref, m.output, m.parser, m.sinceTime, m.idleTimeout)
+	stream.catchUpLag = m.catchUpLag
+	stream.catchingUp = &m.catchingUp
+	stream.totalLines = &m.linesRead
+	stream.droppedLines = &m.linesDropped
+	stream.onDrop = m.onLineDropped
+	stream.throttle = m.throttle
 
 	m.mu.Lock()
 	// Double-check after acquiring semaphore
@@ -116,7 +251,21 @@ func (m *StreamManager) StartStream(ref ContainerRef) error {
 			<-m.streamSem // Release slot
 		}()
 
+		if delay := m.startDelay(); delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-streamCtx.Done():
+				return
+			}
+		}
+
+		if m.onStreamStart != nil {
+			m.onStreamStart(ref)
+		}
 		err := stream.Start(streamCtx)
+		if m.onStreamEnd != nil {
+			m.onStreamEnd(ref, m.streamEnd(err, stream.Stats().LinesRead))
+		}
 		if err != nil && err != context.Canceled {
 			slog.Warn("stream ended with error",
 				"container", key,
@@ -163,6 +312,53 @@ func (m *StreamManager) ActiveStreams() int {
 	return len(m.streams)
 }
 
+// StreamEnd describes why a stream ended.
+type StreamEnd struct {
+	Reason    string // One of the StreamEnd reasons
+	Err       error  // Set if Reason is StreamEndError
+	LinesRead int64
+}
+
+// Reasons a stream ends.
+const (
+	StreamEndCompleted = "completed"         // The container terminated
+	StreamEndStopped   = "container_stopped" // Discovery reported the container stopped
+	StreamEndShutdown  = "shutdown"          // The collector is stopping
+	StreamEndError     = "error"             // Reading failed and wasn't retried
+)
+
+// streamEnd classifies the error a stream ended with.
+func (m *StreamManager) streamEnd(err error, linesRead int64) StreamEnd {
+	end := StreamEnd{LinesRead: linesRead}
+	switch {
+	case err == nil:
+		end.Reason = StreamEndCompleted
+	case m.ctx.Err() != nil:
+		end.Reason = StreamEndShutdown
+	case errors.Is(err, context.Canceled):
+		end.Reason = StreamEndStopped
+	default:
+		end.Reason, end.Err = StreamEndError, err
+	}
+	return end
+}
+
+// LinesRead returns the number of lines read by all streams so far.
+func (m *StreamManager) LinesRead() int64 {
+	return m.linesRead.Load()
+}
+
+// LinesDropped returns the number of lines streams dropped because the
+// output stayed full.
+func (m *StreamManager) LinesDropped() int64 {
+	return m.linesDropped.Load()
+}
+
+// CatchingUp returns the number of streams catching up.
+func (m *StreamManager) CatchingUp() int {
+	return int(m.catchingUp.Load())
+}
+
 // Stats returns statistics for all active streams.
 func (m *StreamManager) Stats() []StreamStats {
 	m.mu.RLock()
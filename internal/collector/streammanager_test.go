@@ -0,0 +1,131 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func TestRampLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxStreams int
+		elapsed    time.Duration
+		rampUp     time.Duration
+		want       int
+	}{
+		{"disabled", 100, 0, 0, 100},
+		{"start", 100, 0, time.Minute, 10},
+		{"halfway", 100, 30 * time.Second, time.Minute, 55},
+		{"done", 100, time.Minute, time.Minute, 100},
+		{"after", 100, time.Hour, time.Minute, 100},
+		{"few streams start with one", 5, 0, time.Minute, 1},
+		{"few streams halfway", 5, 30 * time.Second, time.Minute, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rampLimit(tt.maxStreams, tt.elapsed, tt.rampUp); got != tt.want {
+				t.Errorf("rampLimit(%d, %v, %v) = %d, want %d", tt.maxStreams, tt.elapsed, tt.rampUp, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamManager_StartDelay(t *testing.T) {
+	m := NewStreamManager(nil, 10, 10, time.Time{}, time.Minute)
+	m.SetStartupSmoothing(time.Second, time.Minute)
+	m.startedAt = time.Now()
+	for range 100 {
+		if d := m.startDelay(); d < 0 || d >= time.Second {
+			t.Fatalf("startDelay() = %v, want [0, 1s)", d)
+		}
+	}
+
+	// Containers started later are streamed right away
+	m.startedAt = time.Now().Add(-2 * time.Minute)
+	if d := m.startDelay(); d != 0 {
+		t.Errorf("startDelay() after ramp-up = %v, want 0", d)
+	}
+}
+
+func TestStream_CatchUp(t *testing.T) {
+	m := NewStreamManager(nil, 10, 10, time.Time{}, time.Minute)
+	m.SetCatchUpLag(time.Minute)
+
+	now := time.Now()
+	tests := []struct {
+		name      string
+		sinceTime time.Time
+		want      bool
+	}{
+		{"from pod start", time.Time{}, false},
+		{"recent cursor", now.Add(-30 * time.Second), false},
+		{"old cursor", now.Add(-time.Hour), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStream(nil, ContainerRef{PodName: tt.name}, nil, nil, tt.sinceTime, time.Minute)
+			s.catchUpLag = m.catchUpLag
+			s.catchingUp = &m.catchingUp
+
+			s.beginCatchUp(now)
+			if got := s.Stats().CatchingUp; got != tt.want {
+				t.Fatalf("CatchingUp = %v, want %v", got, tt.want)
+			}
+			if got := m.CatchingUp(); got != btoi(tt.want) {
+				t.Errorf("manager CatchingUp() = %d, want %d", got, btoi(tt.want))
+			}
+
+			s.endCatchUp()
+			if s.Stats().CatchingUp || m.CatchingUp() != 0 {
+				t.Errorf("still catching up after endCatchUp")
+			}
+		})
+	}
+}
+
+func TestCatchUpProgress(t *testing.T) {
+	now := time.Now()
+	from := now.Add(-time.Hour)
+	tests := []struct {
+		cursor time.Time
+		want   float64
+	}{
+		{time.Time{}, 0},
+		{from, 0},
+		{from.Add(15 * time.Minute), 0.25},
+		{now, 1},
+		{now.Add(time.Minute), 1},
+	}
+	for _, tt := range tests {
+		if got := catchUpProgress(from, tt.cursor, now); got != tt.want {
+			t.Errorf("catchUpProgress(cursor %v) = %v, want %v", tt.cursor.Sub(from), got, tt.want)
+		}
+	}
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestStreamManager_StreamLimit(t *testing.T) {
+	m := NewStreamManager(nil, 100, 10, time.Time{}, time.Minute)
+	m.SetStartupSmoothing(0, time.Minute)
+	m.SetAdaptiveLimit(true)
+	m.startedAt = time.Now().Add(-30 * time.Second)
+
+	if got := m.StreamLimit(); got != 55 {
+		t.Errorf("StreamLimit() halfway through ramp-up = %d, want 55", got)
+	}
+
+	// The lower of the ramp-up and the adaptive limit applies
+	m.throttle.observe(apierrors.NewTooManyRequests("slow down", 1), time.Now())
+	m.throttle.observe(apierrors.NewTooManyRequests("slow down", 1), time.Now().Add(time.Second))
+	if got := m.StreamLimit(); got != 25 {
+		t.Errorf("StreamLimit() after two 429s = %d, want 25", got)
+	}
+}
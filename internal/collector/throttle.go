@@ -0,0 +1,87 @@
+package collector
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// throttleCooldown is the shortest time between two limit decreases, so
+// a burst of errors from one overloaded moment halves the limit once.
+const throttleCooldown = time.Second
+
+// streamThrottle adapts the number of streams to the load the kubelet and
+// API server can take, AIMD style: every 429 or 5xx response halves the
+// limit, and every log stream opened without one raises it by one, back
+// up to the maximum.
+type streamThrottle struct {
+	max int
+
+	mu           sync.Mutex
+	limit        int
+	lastDecrease time.Time
+
+	throttled atomic.Int64 // Responses that reduced, or would have reduced, the limit
+}
+
+func newStreamThrottle(max int) *streamThrottle {
+	return &streamThrottle{max: max, limit: max}
+}
+
+// Limit returns the number of streams currently allowed.
+func (t *streamThrottle) Limit() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.limit
+}
+
+// Throttled returns the number of pressure responses seen.
+func (t *streamThrottle) Throttled() int64 {
+	return t.throttled.Load()
+}
+
+// observe adjusts the limit after a call to the kubelet or API server
+// that returned err.
+func (t *streamThrottle) observe(err error, now time.Time) {
+	if err != nil && !isAPIPressure(err) {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err == nil {
+		t.limit = min(t.limit+1, t.max)
+		return
+	}
+
+	t.throttled.Add(1)
+	if now.Sub(t.lastDecrease) < throttleCooldown {
+		return
+	}
+	prev := t.limit
+	t.limit = max(t.limit/2, 1)
+	t.lastDecrease = now
+	if t.limit != prev {
+		slog.Warn("kubelet or API server under pressure, reducing streams",
+			"limit", t.limit,
+			"error", err,
+		)
+	}
+}
+
+// isAPIPressure reports whether err is a response of an overloaded
+// kubelet or API server: 429 Too Many Requests or a 5xx status.
+func isAPIPressure(err error) bool {
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) {
+		return false
+	}
+	code := status.Status().Code
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
@@ -0,0 +1,80 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestIsAPIPressure(t *testing.T) {
+	pods := schema.GroupResource{Resource: "pods"}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"too many requests", apierrors.NewTooManyRequests("slow down", 1), true},
+		{"internal error", apierrors.NewInternalError(errors.New("boom")), true},
+		{"service unavailable", apierrors.NewServiceUnavailable("kubelet restarting"), true},
+		{"wrapped", fmt.Errorf("open log stream: %w", apierrors.NewTooManyRequests("slow down", 1)), true},
+		{"not found", apierrors.NewNotFound(pods, "api-0"), false},
+		{"bad request", apierrors.NewBadRequest("container not running"), false},
+		{"connection reset", errors.New("connection reset by peer"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isAPIPressure(tt.err); got != tt.want {
+				t.Errorf("isAPIPressure(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamThrottle(t *testing.T) {
+	throttle := newStreamThrottle(100)
+	now := time.Now()
+	pressure := apierrors.NewTooManyRequests("slow down", 1)
+
+	throttle.observe(pressure, now)
+	if got := throttle.Limit(); got != 50 {
+		t.Fatalf("limit after 429 = %d, want 50", got)
+	}
+
+	// A burst of errors within the cooldown halves the limit once
+	throttle.observe(pressure, now.Add(100*time.Millisecond))
+	if got := throttle.Limit(); got != 50 {
+		t.Errorf("limit after second 429 in cooldown = %d, want 50", got)
+	}
+	throttle.observe(pressure, now.Add(2*time.Second))
+	if got := throttle.Limit(); got != 25 {
+		t.Errorf("limit after 429 past cooldown = %d, want 25", got)
+	}
+	if got := throttle.Throttled(); got != 3 {
+		t.Errorf("Throttled() = %d, want 3", got)
+	}
+
+	// Other errors don't change it; successes raise it by one, up to max
+	throttle.observe(errors.New("connection reset by peer"), now.Add(3*time.Second))
+	throttle.observe(nil, now.Add(3*time.Second))
+	if got := throttle.Limit(); got != 26 {
+		t.Errorf("limit after success = %d, want 26", got)
+	}
+	for range 200 {
+		throttle.observe(nil, now)
+	}
+	if got := throttle.Limit(); got != 100 {
+		t.Errorf("limit after many successes = %d, want 100", got)
+	}
+
+	// but never drops below one
+	for i := range 20 {
+		throttle.observe(pressure, now.Add(time.Duration(10+2*i)*time.Second))
+	}
+	if got := throttle.Limit(); got != 1 {
+		t.Errorf("limit after many 429s = %d, want 1", got)
+	}
+}
@@ -0,0 +1,68 @@
+// Package fixtures provides anonymized real-world log corpora for tests.
+//
+// Each corpus is a file under corpus/ containing raw container output
+// (without the Kubernetes timestamp prefix), one line per log line.
+// Multi-line records such as stack traces are kept as separate lines,
+// exactly as the kubelet would stream them.
+package fixtures
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed corpus/*.log
+var corpusFS embed.FS
+
+// Corpus is a named set of sample log lines.
+type Corpus struct {
+	// Name is the corpus file name without extension (e.g. "nginx").
+	Name string
+
+	// Lines are the raw log lines in file order.
+	Lines []string
+}
+
+// Names returns the names of all available corpora, sorted.
+func Names() []string {
+	files, err := fs.Glob(corpusFS, "corpus/*.log")
+	if err != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, strings.TrimSuffix(path.Base(f), ".log"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load returns the corpus with the given name.
+func Load(name string) (Corpus, error) {
+	data, err := corpusFS.ReadFile("corpus/" + name + ".log")
+	if err != nil {
+		return Corpus{}, fmt.Errorf("fixtures: unknown corpus %q", name)
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	return Corpus{Name: name, Lines: lines}, nil
+}
+
+// All returns every available corpus, sorted by name.
+func All() []Corpus {
+	names := Names()
+	corpora := make([]Corpus, 0, len(names))
+	for _, name := range names {
+		c, err := Load(name)
+		if err != nil {
+			continue
+		}
+		corpora = append(corpora, c)
+	}
+	return corpora
+}
@@ -0,0 +1,282 @@
+// Package incident stores incident workspaces: shared reports that group
+// pinned queries, log entries, timeline markers and free-form notes.
+package incident
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+var (
+	ErrNotFound    = errors.New("incident: not found")
+	ErrInvalidItem = errors.New("incident: invalid item")
+)
+
+// ItemKind identifies what an incident item pins.
+type ItemKind string
+
+const (
+	// KindEntry pins a log entry by ID.
+	KindEntry ItemKind = "entry"
+	// KindQuery pins a UI/API query string (e.g. "namespace=prod&search=timeout").
+	KindQuery ItemKind = "query"
+	// KindMarker marks a point in time (e.g. "deploy v1.4.2").
+	KindMarker ItemKind = "marker"
+)
+
+// Incident groups pinned items and notes for a postmortem.
+type Incident struct {
+	ID        int64
+	Title     string
+	Notes     string
+	CreatedBy string // Username, empty when auth is disabled
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Items     []Item // Populated by Get, not List
+}
+
+// Item is a single pinned element of an incident.
+type Item struct {
+	ID        int64
+	Kind      ItemKind
+	EntryID   int64     // KindEntry
+	Query     string    // KindQuery
+	Timestamp time.Time // KindMarker
+	Label     string    // Optional caption for any kind
+	CreatedAt time.Time
+}
+
+// Validate checks that the fields required by the item's kind are set.
+func (it Item) Validate() error {
+	switch it.Kind {
+	case KindEntry:
+		if it.EntryID <= 0 {
+			return fmt.Errorf("%w: entry requires an entry id", ErrInvalidItem)
+		}
+	case KindQuery:
+		if it.Query == "" {
+			return fmt.Errorf("%w: query requires a query string", ErrInvalidItem)
+		}
+		if _, err := url.ParseQuery(it.Query); err != nil {
+			return fmt.Errorf("%w: malformed query: %v", ErrInvalidItem, err)
+		}
+	case KindMarker:
+		if it.Timestamp.IsZero() || it.Label == "" {
+			return fmt.Errorf("%w: marker requires a timestamp and label", ErrInvalidItem)
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidItem, it.Kind)
+	}
+	return nil
+}
+
+// Store manages incident persistence.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a Store with the given database connection.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Create creates a new incident.
+func (s *Store) Create(ctx context.Context, title, notes, createdBy string) (*Incident, error) {
+	now := time.Now()
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO incidents (title, notes, created_by, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`,
+		title, notes, createdBy, now.UnixNano(), now.UnixNano(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	id, _ := result.LastInsertId()
+	return &Incident{
+		ID:        id,
+		Title:     title,
+		Notes:     notes,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// Update replaces an incident's title and notes.
+func (s *Store) Update(ctx context.Context, id int64, title, notes string) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE incidents SET title = ?, notes = ?, updated_at = ? WHERE id = ?`,
+		title, notes, time.Now().UnixNano(), id,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes an incident and its items.
+func (s *Store) Delete(ctx context.Context, id int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM incidents WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	// Foreign keys aren't enforced, so cascade by hand
+	if _, err := tx.ExecContext(ctx, `DELETE FROM incident_items WHERE incident_id = ?`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Get retrieves an incident with its items, in the order they were added.
+func (s *Store) Get(ctx context.Context, id int64) (*Incident, error) {
+	inc := Incident{ID: id}
+	var createdAt, updatedAt int64
+
+	err := s.db.QueryRowContext(ctx,
+		`SELECT title, notes, created_by, created_at, updated_at FROM incidents WHERE id = ?`,
+		id,
+	).Scan(&inc.Title, &inc.Notes, &inc.CreatedBy, &createdAt, &updatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	inc.CreatedAt = time.Unix(0, createdAt)
+	inc.UpdatedAt = time.Unix(0, updatedAt)
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, kind, entry_id, query, timestamp, label, created_at
+		 FROM incident_items WHERE incident_id = ? ORDER BY id`,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var it Item
+		var ts, created int64
+		if err := rows.Scan(&it.ID, &it.Kind, &it.EntryID, &it.Query, &ts, &it.Label, &created); err != nil {
+			return nil, err
+		}
+		if ts != 0 {
+			it.Timestamp = time.Unix(0, ts)
+		}
+		it.CreatedAt = time.Unix(0, created)
+		inc.Items = append(inc.Items, it)
+	}
+
+	return &inc, rows.Err()
+}
+
+// List returns all incidents without their items, newest first.
+func (s *Store) List(ctx context.Context) ([]Incident, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, title, notes, created_by, created_at, updated_at FROM incidents ORDER BY created_at DESC, id DESC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var incidents []Incident
+	for rows.Next() {
+		var inc Incident
+		var createdAt, updatedAt int64
+		if err := rows.Scan(&inc.ID, &inc.Title, &inc.Notes, &inc.CreatedBy, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		inc.CreatedAt = time.Unix(0, createdAt)
+		inc.UpdatedAt = time.Unix(0, updatedAt)
+		incidents = append(incidents, inc)
+	}
+	return incidents, rows.Err()
+}
+
+// AddItem pins an item to an incident.
+func (s *Store) AddItem(ctx context.Context, incidentID int64, it Item) (*Item, error) {
+	if err := it.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx,
+		`UPDATE incidents SET updated_at = ? WHERE id = ?`,
+		now.UnixNano(), incidentID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrNotFound
+	}
+
+	var ts int64
+	if !it.Timestamp.IsZero() {
+		ts = it.Timestamp.UnixNano()
+	}
+	result, err = tx.ExecContext(ctx,
+		`INSERT INTO incident_items (incident_id, kind, entry_id, query, timestamp, label, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		incidentID, it.Kind, it.EntryID, it.Query, ts, it.Label, now.UnixNano(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	it.ID, _ = result.LastInsertId()
+	it.CreatedAt = now
+	return &it, nil
+}
+
+// RemoveItem unpins an item from an incident.
+func (s *Store) RemoveItem(ctx context.Context, incidentID, itemID int64) error {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM incident_items WHERE id = ? AND incident_id = ?`,
+		itemID, incidentID,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package incident
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	db, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewStore(db.DB())
+}
+
+func TestItem_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		item    Item
+		wantErr bool
+	}{
+		{"entry", Item{Kind: KindEntry, EntryID: 1}, false},
+		{"entry without id", Item{Kind: KindEntry}, true},
+		{"query", Item{Kind: KindQuery, Query: "namespace=prod&search=timeout"}, false},
+		{"empty query", Item{Kind: KindQuery}, true},
+		{"malformed query", Item{Kind: KindQuery, Query: "search=%zz"}, true},
+		{"marker", Item{Kind: KindMarker, Timestamp: time.Now(), Label: "deploy"}, false},
+		{"marker without label", Item{Kind: KindMarker, Timestamp: time.Now()}, true},
+		{"unknown kind", Item{Kind: "chart"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.item.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidItem) {
+				t.Errorf("Validate() error = %v, want ErrInvalidItem", err)
+			}
+		})
+	}
+}
+
+func TestStore_Lifecycle(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	inc, err := s.Create(ctx, "API outage", "", "alice")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if _, err := s.AddItem(ctx, inc.ID, Item{Kind: KindQuery, Query: "namespace=prod"}); err != nil {
+		t.Fatalf("AddItem failed: %v", err)
+	}
+	entry, err := s.AddItem(ctx, inc.ID, Item{Kind: KindEntry, EntryID: 42, Label: "first error"})
+	if err != nil {
+		t.Fatalf("AddItem failed: %v", err)
+	}
+	if _, err := s.AddItem(ctx, 9999, Item{Kind: KindEntry, EntryID: 1}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("AddItem to unknown incident error = %v, want ErrNotFound", err)
+	}
+
+	if err := s.Update(ctx, inc.ID, "API outage 2024-01-01", "Root cause: bad config"); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	got, err := s.Get(ctx, inc.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got.Title != "API outage 2024-01-01" || got.Notes != "Root cause: bad config" || got.CreatedBy != "alice" {
+		t.Errorf("unexpected incident %+v", got)
+	}
+	if len(got.Items) != 2 || got.Items[1].EntryID != 42 || got.Items[1].Label != "first error" {
+		t.Fatalf("unexpected items %+v", got.Items)
+	}
+
+	if err := s.RemoveItem(ctx, inc.ID, entry.ID); err != nil {
+		t.Fatalf("RemoveItem failed: %v", err)
+	}
+	if err := s.RemoveItem(ctx, inc.ID, entry.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second RemoveItem error = %v, want ErrNotFound", err)
+	}
+
+	list, err := s.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != inc.ID {
+		t.Errorf("List = %+v, want one incident", list)
+	}
+
+	if err := s.Delete(ctx, inc.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := s.Get(ctx, inc.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	inc := &Incident{
+		Title:     "Checkout errors",
+		Notes:     "Payments returned 502s for **15 minutes**.",
+		CreatedBy: "alice",
+		CreatedAt: base,
+		UpdatedAt: base,
+		Items: []Item{
+			{Kind: KindEntry, EntryID: 2, Label: "first failure"},
+			{Kind: KindMarker, Timestamp: base.Add(-time.Minute), Label: "deploy v1.4.2"},
+			{Kind: KindQuery, Query: "namespace=shop&minSeverity=5", Label: "all errors"},
+			{Kind: KindEntry, EntryID: 3},
+		},
+	}
+	entries := map[int64]storage.LogEntry{
+		2: {
+			ID:        2,
+			Timestamp: base.Add(time.Second),
+			Namespace: "shop",
+			Pod:       "payments-0",
+			Container: "app",
+			Severity:  storage.SeverityError,
+			Message:   "upstream returned 502 | retrying *now*",
+		},
+	}
+
+	var b strings.Builder
+	if err := WriteMarkdown(&b, inc, entries); err != nil {
+		t.Fatalf("WriteMarkdown failed: %v", err)
+	}
+
+	want := "# Checkout errors\n" +
+		"\n" +
+		"- Created: 2024-03-01T12:00:00Z by alice\n" +
+		"- Updated: 2024-03-01T12:00:00Z\n" +
+		"\n" +
+		"## Notes\n" +
+		"\n" +
+		"Payments returned 502s for **15 minutes**.\n" +
+		"\n" +
+		"## Queries\n" +
+		"\n" +
+		"- `namespace=shop&minSeverity=5` - all errors\n" +
+		"\n" +
+		"## Timeline\n" +
+		"\n" +
+		"- `2024-03-01 11:59:00.000Z` **deploy v1.4.2**\n" +
+		"- `2024-03-01 12:00:01.000Z` ERROR `shop/payments-0/app` upstream returned 502 \\| retrying \\*now\\*\n" +
+		"  > first failure\n" +
+		"\n" +
+		"_Pinned entries no longer in storage: 3._\n"
+
+	if got := b.String(); got != want {
+		t.Errorf("WriteMarkdown mismatch\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
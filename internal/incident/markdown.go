@@ -0,0 +1,108 @@
+package incident
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// mdEscaper escapes characters that would otherwise be read as Markdown.
+var mdEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "|", `\|`, "#", `\#`,
+	"\r\n", " ", "\n", " ",
+)
+
+// timelineEvent is an entry or marker placed on the incident timeline.
+type timelineEvent struct {
+	at   time.Time
+	line string
+}
+
+// WriteMarkdown renders inc as a postmortem document. Entries are looked up
+// in entries by ID; pinned entries that no longer exist are listed as missing.
+func WriteMarkdown(w io.Writer, inc *Incident, entries map[int64]storage.LogEntry) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", mdEscaper.Replace(inc.Title))
+	fmt.Fprintf(&b, "- Created: %s", inc.CreatedAt.UTC().Format(time.RFC3339))
+	if inc.CreatedBy != "" {
+		fmt.Fprintf(&b, " by %s", mdEscaper.Replace(inc.CreatedBy))
+	}
+	fmt.Fprintf(&b, "\n- Updated: %s\n\n", inc.UpdatedAt.UTC().Format(time.RFC3339))
+
+	// Notes are written by the user in Markdown, so they're kept as-is
+	if notes := strings.TrimSpace(inc.Notes); notes != "" {
+		fmt.Fprintf(&b, "## Notes\n\n%s\n\n", notes)
+	}
+
+	var queries []string
+	var timeline []timelineEvent
+	var missing []int64
+
+	for _, it := range inc.Items {
+		switch it.Kind {
+		case KindQuery:
+			line := fmt.Sprintf("- `%s`", strings.ReplaceAll(it.Query, "`", "'"))
+			if it.Label != "" {
+				line += " - " + mdEscaper.Replace(it.Label)
+			}
+			queries = append(queries, line)
+
+		case KindMarker:
+			timeline = append(timeline, timelineEvent{
+				at:   it.Timestamp,
+				line: fmt.Sprintf("- `%s` **%s**", formatTime(it.Timestamp), mdEscaper.Replace(it.Label)),
+			})
+
+		case KindEntry:
+			e, ok := entries[it.EntryID]
+			if !ok {
+				missing = append(missing, it.EntryID)
+				continue
+			}
+			line := fmt.Sprintf("- `%s` %s `%s/%s/%s` %s",
+				formatTime(e.Timestamp), e.Severity, e.Namespace, e.Pod, e.Container,
+				mdEscaper.Replace(e.Message))
+			if it.Label != "" {
+				line += "\n  > " + mdEscaper.Replace(it.Label)
+			}
+			timeline = append(timeline, timelineEvent{at: e.Timestamp, line: line})
+		}
+	}
+
+	if len(queries) > 0 {
+		fmt.Fprintf(&b, "## Queries\n\n%s\n\n", strings.Join(queries, "\n"))
+	}
+
+	if len(timeline) > 0 {
+		sort.SliceStable(timeline, func(i, j int) bool {
+			return timeline[i].at.Before(timeline[j].at)
+		})
+		b.WriteString("## Timeline\n\n")
+		for _, ev := range timeline {
+			b.WriteString(ev.line)
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
+	if len(missing) > 0 {
+		ids := make([]string, len(missing))
+		for i, id := range missing {
+			ids[i] = fmt.Sprint(id)
+		}
+		fmt.Fprintf(&b, "_Pinned entries no longer in storage: %s._\n", strings.Join(ids, ", "))
+	}
+
+	_, err := io.WriteString(w, strings.TrimRight(b.String(), "\n")+"\n")
+	return err
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05.000Z")
+}
@@ -3,7 +3,10 @@ package loadgen
 import (
 	"errors"
 	"flag"
+	"strings"
 	"time"
+
+	"github.com/kubelogs/kubelogs/internal/fixtures"
 )
 
 // Config holds load generator configuration.
@@ -11,6 +14,24 @@ type Config struct {
 	// Addr is the gRPC server address.
 	Addr string
 
+	// TLS connects over TLS, verifying the server against TLSCAFile or
+	// the system roots. TLSCertFile and TLSKeyFile add a client
+	// certificate for servers requiring mutual TLS.
+	TLS         bool
+	TLSCAFile   string
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// Token is sent to servers requiring gRPC token authentication.
+	Token string
+
+	// Mode is "write", generating logs, or "query", issuing concurrent
+	// reads and reporting their latencies.
+	Mode string
+
+	// Concurrency is the number of concurrent callers in query mode.
+	Concurrency int
+
 	// Rate is the number of logs per second to generate.
 	Rate int
 
@@ -26,9 +47,21 @@ type Config struct {
 	// Pods is the number of unique pods to generate.
 	Pods int
 
+	// Clusters are cluster names set on generated entries, with pods
+	// spread evenly across them. Empty leaves entries without a cluster.
+	Clusters []string
+
 	// ErrorRate is the percentage of logs that should be errors (0-100).
 	ErrorRate int
 
+	// Corpus replays lines from the named fixture corpus instead of
+	// generating messages from templates. Empty means use templates.
+	Corpus string
+
+	// Scenario is a YAML scenario file (see ReadScenario) shaping the
+	// traffic instead of Rate, Duration, Namespaces and Pods.
+	Scenario string
+
 	// Verbose enables debug logging.
 	Verbose bool
 }
@@ -36,14 +69,16 @@ type Config struct {
 // DefaultConfig returns sensible defaults.
 func DefaultConfig() Config {
 	return Config{
-		Addr:       ":50051",
-		Rate:       100,
-		Duration:   time.Minute,
-		BatchSize:  100,
-		Namespaces: 5,
-		Pods:       20,
-		ErrorRate:  5,
-		Verbose:    false,
+		Addr:        ":50051",
+		Mode:        "write",
+		Concurrency: 4,
+		Rate:        100,
+		Duration:    time.Minute,
+		BatchSize:   100,
+		Namespaces:  5,
+		Pods:        20,
+		ErrorRate:   5,
+		Verbose:     false,
 	}
 }
 
@@ -52,15 +87,35 @@ func ParseFlags() Config {
 	cfg := DefaultConfig()
 
 	flag.StringVar(&cfg.Addr, "addr", cfg.Addr, "gRPC server address")
+	flag.BoolVar(&cfg.TLS, "tls", cfg.TLS, "connect over TLS")
+	flag.StringVar(&cfg.TLSCAFile, "tls-ca", cfg.TLSCAFile, "CA certificates to verify the server with (implies -tls)")
+	flag.StringVar(&cfg.TLSCertFile, "tls-cert", cfg.TLSCertFile, "client certificate for mutual TLS (implies -tls)")
+	flag.StringVar(&cfg.TLSKeyFile, "tls-key", cfg.TLSKeyFile, "client key for mutual TLS")
+	flag.StringVar(&cfg.Token, "token", cfg.Token, "token for servers requiring gRPC authentication")
+	flag.StringVar(&cfg.Mode, "mode", cfg.Mode, "write logs, or query them and report latencies (write, query)")
+	flag.IntVar(&cfg.Concurrency, "concurrency", cfg.Concurrency, "concurrent callers in query mode")
 	flag.IntVar(&cfg.Rate, "rate", cfg.Rate, "logs per second")
 	flag.DurationVar(&cfg.Duration, "duration", cfg.Duration, "how long to run")
 	flag.IntVar(&cfg.BatchSize, "batch-size", cfg.BatchSize, "logs per batch")
 	flag.IntVar(&cfg.Namespaces, "namespaces", cfg.Namespaces, "number of unique namespaces")
 	flag.IntVar(&cfg.Pods, "pods", cfg.Pods, "number of unique pods")
+	clusters := flag.String("clusters", "", "comma-separated cluster names to spread pods across")
 	flag.IntVar(&cfg.ErrorRate, "error-rate", cfg.ErrorRate, "percentage of error logs (0-100)")
+	flag.StringVar(&cfg.Corpus, "corpus", cfg.Corpus, "replay a fixture corpus ("+strings.Join(fixtures.Names(), ", ")+")")
+	flag.StringVar(&cfg.Scenario, "scenario", cfg.Scenario, "YAML scenario file of phases, namespace rates, line sizes and formats; overrides -rate, -duration, -namespaces and -pods")
 	flag.BoolVar(&cfg.Verbose, "v", cfg.Verbose, "enable verbose logging")
 
 	flag.Parse()
+
+	if cfg.TLSCAFile != "" || cfg.TLSCertFile != "" {
+		cfg.TLS = true
+	}
+
+	for _, c := range strings.Split(*clusters, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			cfg.Clusters = append(cfg.Clusters, c)
+		}
+	}
 	return cfg
 }
 
@@ -69,6 +124,12 @@ func (c Config) Validate() error {
 	if c.Addr == "" {
 		return errors.New("addr cannot be empty")
 	}
+	if c.Mode != "write" && c.Mode != "query" {
+		return errors.New("mode must be write or query")
+	}
+	if c.Concurrency <= 0 {
+		return errors.New("concurrency must be positive")
+	}
 	if c.Rate <= 0 {
 		return errors.New("rate must be positive")
 	}
@@ -87,5 +148,21 @@ func (c Config) Validate() error {
 	if c.ErrorRate < 0 || c.ErrorRate > 100 {
 		return errors.New("error-rate must be between 0 and 100")
 	}
+	if c.Corpus != "" {
+		if _, err := fixtures.Load(c.Corpus); err != nil {
+			return err
+		}
+	}
+	if c.Scenario != "" {
+		if c.Corpus != "" {
+			return errors.New("corpus and scenario are exclusive")
+		}
+		if c.Mode == "query" {
+			return errors.New("scenario applies to write mode only")
+		}
+		if _, err := ReadScenario(c.Scenario); err != nil {
+			return err
+		}
+	}
 	return nil
 }
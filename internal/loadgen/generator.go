@@ -1,11 +1,17 @@
 package loadgen
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/rand"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/kubelogs/kubelogs/api/storagepb"
+	"github.com/kubelogs/kubelogs/internal/collector"
+	"github.com/kubelogs/kubelogs/internal/fixtures"
+	"github.com/kubelogs/kubelogs/internal/storage"
 )
 
 // Predefined realistic Kubernetes namespaces
@@ -100,9 +106,21 @@ type Generator struct {
 	rng  *rand.Rand
 	cfg  Config
 	pods []podInfo
+
+	// Corpus replay (nil when generating from templates)
+	corpus    []string
+	corpusPos int
+	parser    *collector.Parser
+
+	// Scenario replay (nil without a scenario): pods by namespace, and
+	// the cumulative rates picking a namespace
+	scenario  *Scenario
+	nsPods    [][]podInfo
+	nsWeights []float64
 }
 
 type podInfo struct {
+	cluster    string
 	namespace  string
 	name       string
 	containers []string
@@ -125,6 +143,12 @@ func NewGenerator(cfg Config) *Generator {
 	pods := make([]podInfo, 0, cfg.Pods)
 	for i := 0; i < cfg.Pods; i++ {
 		ns := namespaces[i%len(namespaces)]
+		// Each round of namespaces goes to the next cluster, so the same
+		// namespaces exist in every cluster
+		var cluster string
+		if len(cfg.Clusters) > 0 {
+			cluster = cfg.Clusters[(i/len(namespaces))%len(cfg.Clusters)]
+		}
 		prefix := deploymentPrefixes[rng.Intn(len(deploymentPrefixes))]
 
 		// Generate Kubernetes-style pod name: deployment-xxxxx-xxxxx
@@ -143,25 +167,92 @@ func NewGenerator(cfg Config) *Generator {
 		}
 
 		pods = append(pods, podInfo{
+			cluster:    cluster,
 			namespace:  ns,
 			name:       podName,
 			containers: containers,
 		})
 	}
 
-	return &Generator{
+	g := &Generator{
 		rng:  rng,
 		cfg:  cfg,
 		pods: pods,
 	}
+
+	if cfg.Corpus != "" {
+		if c, err := fixtures.Load(cfg.Corpus); err == nil {
+			g.corpus = c.Lines
+			g.parser = collector.NewParser()
+		}
+	}
+
+	if cfg.Scenario != "" {
+		if sc, err := ReadScenario(cfg.Scenario); err == nil {
+			g.useScenario(sc)
+		}
+	}
+
+	return g
+}
+
+// useScenario replaces the pods of g by those of the namespaces of sc.
+func (g *Generator) useScenario(sc *Scenario) {
+	g.scenario = sc
+	g.parser = collector.NewParser()
+	if sc.ErrorRate != nil {
+		g.cfg.ErrorRate = *sc.ErrorRate
+	}
+
+	g.pods = nil
+	var total float64
+	for i, ns := range sc.Namespaces {
+		n := ns.Pods
+		if n == 0 {
+			n = 5
+		}
+		pods := make([]podInfo, n)
+		for j := range pods {
+			var cluster string
+			if len(g.cfg.Clusters) > 0 {
+				cluster = g.cfg.Clusters[j%len(g.cfg.Clusters)]
+			}
+			pods[j] = podInfo{
+				cluster:   cluster,
+				namespace: ns.Name,
+				name: fmt.Sprintf("%s-%s-%s",
+					deploymentPrefixes[(i+j)%len(deploymentPrefixes)],
+					randomString(g.rng, 5),
+					randomString(g.rng, 5)),
+				containers: []string{"main"},
+			}
+		}
+		g.pods = append(g.pods, pods...)
+		g.nsPods = append(g.nsPods, pods)
+		total += ns.Rate
+		g.nsWeights = append(g.nsWeights, total)
+	}
+}
+
+// Scenario returns the scenario replayed, or nil.
+func (g *Generator) Scenario() *Scenario {
+	return g.scenario
 }
 
 // Next generates the next log entry.
 func (g *Generator) Next() *storagepb.LogEntry {
+	if g.scenario != nil {
+		return g.nextFromScenario()
+	}
+
 	// Select random pod
 	pod := g.pods[g.rng.Intn(len(g.pods))]
 	container := pod.containers[g.rng.Intn(len(pod.containers))]
 
+	if g.corpus != nil {
+		return g.nextFromCorpus(pod, container)
+	}
+
 	// Determine severity based on error rate
 	severity := g.randomSeverity()
 
@@ -170,6 +261,7 @@ func (g *Generator) Next() *storagepb.LogEntry {
 
 	return &storagepb.LogEntry{
 		TimestampNanos: time.Now().UnixNano(),
+		Cluster:        pod.cluster,
 		Namespace:      pod.namespace,
 		Pod:            pod.name,
 		Container:      container,
@@ -182,6 +274,166 @@ func (g *Generator) Next() *storagepb.LogEntry {
 	}
 }
 
+// nextFromCorpus replays the next corpus line, parsed the same way the
+// collector would parse it.
+func (g *Generator) nextFromCorpus(pod podInfo, container string) *storagepb.LogEntry {
+	line := g.corpus[g.corpusPos]
+	g.corpusPos = (g.corpusPos + 1) % len(g.corpus)
+
+	parsed := g.parser.Parse(line)
+	attrs := map[string]string{
+		"generator": "kubelogs-loadgen",
+		"node":      "loadgen-node",
+	}
+	for k, v := range parsed.Attributes {
+		attrs[k] = v
+	}
+
+	return &storagepb.LogEntry{
+		TimestampNanos: time.Now().UnixNano(),
+		Cluster:        pod.cluster,
+		Namespace:      pod.namespace,
+		Pod:            pod.name,
+		Container:      container,
+		Severity:       uint32(parsed.Severity),
+		Message:        parsed.Message,
+		Attributes:     attrs,
+	}
+}
+
+// nextFromScenario generates a line of a namespace picked by rate, in a
+// format and of a size picked from the scenario's distributions, parsed
+// the same way the collector would parse it.
+func (g *Generator) nextFromScenario() *storagepb.LogEntry {
+	ns := len(g.nsWeights) - 1
+	if total := g.nsWeights[ns]; total > 0 {
+		roll := g.rng.Float64() * total
+		for i, w := range g.nsWeights {
+			if roll < w {
+				ns = i
+				break
+			}
+		}
+	} else {
+		ns = g.rng.Intn(len(g.nsPods))
+	}
+	pods := g.nsPods[ns]
+	pod := pods[g.rng.Intn(len(pods))]
+
+	severity := g.randomSeverity()
+	line := g.formatLine(g.randomFormat(), severity, g.randomMessage(severity), g.randomSize())
+
+	parsed := g.parser.Parse(line)
+	attrs := map[string]string{
+		"generator": "kubelogs-loadgen",
+		"node":      "loadgen-node",
+	}
+	for k, v := range parsed.Attributes {
+		attrs[k] = v
+	}
+	if parsed.Severity == storage.SeverityUnknown {
+		parsed.Severity = storage.Severity(severity)
+	}
+
+	return &storagepb.LogEntry{
+		TimestampNanos: time.Now().UnixNano(),
+		Cluster:        pod.cluster,
+		Namespace:      pod.namespace,
+		Pod:            pod.name,
+		Container:      pod.containers[0],
+		Severity:       uint32(parsed.Severity),
+		Message:        parsed.Message,
+		Attributes:     attrs,
+	}
+}
+
+// Line formats of scenarios.
+const (
+	formatText = iota
+	formatJSON
+	formatLogfmt
+)
+
+// randomFormat picks a line format by the scenario's weights.
+func (g *Generator) randomFormat() int {
+	f := g.scenario.Formats
+	total := f.JSON + f.Logfmt + f.Text
+	if total == 0 {
+		return formatText
+	}
+	roll := g.rng.Intn(total)
+	switch {
+	case roll < f.JSON:
+		return formatJSON
+	case roll < f.JSON+f.Logfmt:
+		return formatLogfmt
+	default:
+		return formatText
+	}
+}
+
+// randomSize picks a line length by the scenario's size distribution,
+// or 0 to keep lines as long as their template.
+func (g *Generator) randomSize() int {
+	var total int
+	for _, b := range g.scenario.MessageSizes {
+		total += b.Weight
+	}
+	if total == 0 {
+		return 0
+	}
+	roll := g.rng.Intn(total)
+	for _, b := range g.scenario.MessageSizes {
+		if roll < b.Weight {
+			return b.Min + g.rng.Intn(b.Max-b.Min+1)
+		}
+		roll -= b.Weight
+	}
+	return 0
+}
+
+// formatLine writes message as a line in format, with a request ID as
+// structured lines usually carry, padded with words to about size
+// bytes.
+func (g *Generator) formatLine(format int, severity uint32, message string, size int) string {
+	level := storage.Severity(severity).String()
+	requestID := randomString(g.rng, 16)
+	build := func(msg string) string {
+		switch format {
+		case formatJSON:
+			b, _ := json.Marshal(struct {
+				Level     string `json:"level"`
+				Msg       string `json:"msg"`
+				RequestID string `json:"request_id"`
+			}{strings.ToLower(level), msg, requestID})
+			return string(b)
+		case formatLogfmt:
+			return "level=" + strings.ToLower(level) + " msg=" + strconv.Quote(msg) + " request_id=" + requestID
+		default:
+			return "[" + level + "] " + msg
+		}
+	}
+
+	line := build(message)
+	if pad := size - len(line); pad > 1 {
+		line = build(message + " " + g.filler(pad-1))
+	}
+	return line
+}
+
+// filler returns n bytes of random words.
+func (g *Generator) filler(n int) string {
+	var b strings.Builder
+	b.Grow(n)
+	for b.Len() < n {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(randomString(g.rng, min(3+g.rng.Intn(8), n-b.Len())))
+	}
+	return b.String()
+}
+
 func (g *Generator) randomSeverity() uint32 {
 	roll := g.rng.Intn(100)
 
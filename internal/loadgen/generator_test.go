@@ -3,6 +3,8 @@ package loadgen
 import (
 	"testing"
 	"time"
+
+	"github.com/kubelogs/kubelogs/internal/fixtures"
 )
 
 func TestGenerator_Next(t *testing.T) {
@@ -81,6 +83,68 @@ func TestGenerator_UniqueNamespaces(t *testing.T) {
 	}
 }
 
+func TestGenerator_Clusters(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Namespaces = 3
+	cfg.Pods = 12
+	cfg.Clusters = []string{"east", "west"}
+
+	gen := NewGenerator(cfg)
+
+	// Every namespace should show up in both clusters
+	seen := make(map[string]bool)
+	for _, pod := range gen.pods {
+		seen[pod.cluster+"/"+pod.namespace] = true
+	}
+	if len(seen) != 6 {
+		t.Errorf("expected 6 cluster/namespace pairs, got %v", seen)
+	}
+
+	for i := 0; i < 100; i++ {
+		if c := gen.Next().Cluster; c != "east" && c != "west" {
+			t.Fatalf("unexpected cluster %q", c)
+		}
+	}
+}
+
+func TestGenerator_Corpus(t *testing.T) {
+	for _, corpus := range fixtures.All() {
+		t.Run(corpus.Name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Corpus = corpus.Name
+
+			gen := NewGenerator(cfg)
+
+			// Two passes over the corpus should replay it in order and wrap
+			for i := 0; i < 2*len(corpus.Lines); i++ {
+				entry := gen.Next()
+				if entry.Severity > 6 {
+					t.Errorf("invalid severity: %d", entry.Severity)
+				}
+				if entry.Attributes["generator"] != "kubelogs-loadgen" {
+					t.Errorf("missing generator attribute")
+				}
+			}
+		})
+	}
+
+	// Structured lines keep their extracted fields
+	cfg := DefaultConfig()
+	cfg.Corpus = "json"
+	gen := NewGenerator(cfg)
+
+	first := gen.Next()
+	if first.Message != "starting HTTP server" {
+		t.Errorf("message = %q, want %q", first.Message, "starting HTTP server")
+	}
+	if first.Severity != 3 {
+		t.Errorf("severity = %d, want 3 (INFO)", first.Severity)
+	}
+	if first.Attributes["addr"] != ":8080" {
+		t.Errorf("addr attribute = %q, want :8080", first.Attributes["addr"])
+	}
+}
+
 func TestConfig_Validate(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -99,6 +163,8 @@ func TestConfig_Validate(t *testing.T) {
 		{"error rate < 0", func(c *Config) { c.ErrorRate = -1 }, true},
 		{"valid high rate", func(c *Config) { c.Rate = 100000 }, false},
 		{"valid long duration", func(c *Config) { c.Duration = 24 * time.Hour }, false},
+		{"valid corpus", func(c *Config) { c.Corpus = "nginx" }, false},
+		{"unknown corpus", func(c *Config) { c.Corpus = "does-not-exist" }, true},
 	}
 
 	for _, tt := range tests {
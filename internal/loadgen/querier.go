@@ -0,0 +1,192 @@
+package loadgen
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kubelogs/kubelogs/api/storagepb"
+)
+
+// Query workload operations.
+const (
+	opBrowse = "browse" // Newest entries of a namespace
+	opErrors = "errors" // Errors across namespaces
+	opSearch = "search" // Full-text search
+	opStats  = "stats"  // Storage statistics
+)
+
+// queryOps are the operations of the query workload with their weights,
+// roughly the mix of people investigating in the UI.
+var queryOps = []struct {
+	op     string
+	weight int
+}{
+	{opBrowse, 40},
+	{opSearch, 30},
+	{opErrors, 20},
+	{opStats, 10},
+}
+
+// searchTerms are words of the generated messages, so searches match.
+var searchTerms = []string{
+	"timeout", "connection", "database", "failed", "request",
+	"cache", "retry", "authenticated", "certificate", "memory",
+}
+
+// queryWindows are the time ranges queries look back over.
+var queryWindows = []time.Duration{15 * time.Minute, time.Hour, 24 * time.Hour}
+
+// OpLatency summarizes the calls of one operation.
+type OpLatency struct {
+	Op     string
+	Calls  int64 // Successful calls
+	Errors int64
+	P50    time.Duration
+	P90    time.Duration
+	P99    time.Duration
+	Max    time.Duration
+}
+
+// Querier issues concurrent read calls to the gRPC server and records
+// their latencies, to benchmark read paths.
+type Querier struct {
+	client      storagepb.StorageServiceClient
+	concurrency int
+	namespaces  []string
+
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+	errors    map[string]int64
+}
+
+// NewQuerier creates a querier running cfg.Concurrency workers.
+// Namespaces are those of cfg until Run finds the server's.
+func NewQuerier(client storagepb.StorageServiceClient, cfg Config) *Querier {
+	namespaces := make([]string, 0, cfg.Namespaces)
+	for i := 0; i < cfg.Namespaces && i < len(defaultNamespaces); i++ {
+		namespaces = append(namespaces, defaultNamespaces[i])
+	}
+	return &Querier{
+		client:      client,
+		concurrency: cfg.Concurrency,
+		namespaces:  namespaces,
+		latencies:   make(map[string][]time.Duration),
+		errors:      make(map[string]int64),
+	}
+}
+
+// Run issues calls from each worker, one after another, until ctx is
+// done. Calls cut short by ctx aren't recorded.
+func (q *Querier) Run(ctx context.Context) {
+	// Query the namespaces that have entries, if the server says
+	if resp, err := q.client.Stats(ctx, &storagepb.StatsRequest{}); err == nil && len(resp.Namespaces) > 0 {
+		q.namespaces = q.namespaces[:0]
+		for _, ns := range resp.Namespaces {
+			q.namespaces = append(q.namespaces, ns.Namespace)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := range q.concurrency {
+		rng := rand.New(rand.NewSource(time.Now().UnixNano() + int64(i)))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				op := randomOp(rng)
+				start := time.Now()
+				err := q.call(ctx, rng, op)
+				d := time.Since(start)
+				if ctx.Err() != nil {
+					return
+				}
+				q.record(op, d, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// randomOp picks an operation by weight.
+func randomOp(rng *rand.Rand) string {
+	roll := rng.Intn(100)
+	for _, o := range queryOps {
+		if roll < o.weight {
+			return o.op
+		}
+		roll -= o.weight
+	}
+	return opBrowse
+}
+
+// call issues one call of op.
+func (q *Querier) call(ctx context.Context, rng *rand.Rand, op string) error {
+	if op == opStats {
+		_, err := q.client.Stats(ctx, &storagepb.StatsRequest{})
+		return err
+	}
+
+	now := time.Now()
+	req := &storagepb.QueryRequest{
+		StartTimeNanos: now.Add(-queryWindows[rng.Intn(len(queryWindows))]).UnixNano(),
+		Limit:          100,
+		OrderBy:        storagepb.OrderBy_ORDER_BY_TIMESTAMP,
+	}
+	switch op {
+	case opBrowse:
+		if len(q.namespaces) > 0 {
+			req.Namespace = q.namespaces[rng.Intn(len(q.namespaces))]
+		}
+	case opErrors:
+		req.MinSeverity = 5
+	case opSearch:
+		req.Search = searchTerms[rng.Intn(len(searchTerms))]
+	}
+	_, err := q.client.Query(ctx, req)
+	return err
+}
+
+// record adds a call's outcome.
+func (q *Querier) record(op string, d time.Duration, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err != nil {
+		q.errors[op]++
+		return
+	}
+	q.latencies[op] = append(q.latencies[op], d)
+}
+
+// Stats returns the latency percentiles of each operation called.
+func (q *Querier) Stats() []OpLatency {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var stats []OpLatency
+	for _, o := range queryOps {
+		lat := slices.Clone(q.latencies[o.op])
+		if len(lat) == 0 && q.errors[o.op] == 0 {
+			continue
+		}
+		slices.Sort(lat)
+		s := OpLatency{Op: o.op, Calls: int64(len(lat)), Errors: q.errors[o.op]}
+		if len(lat) > 0 {
+			s.P50 = percentile(lat, 0.5)
+			s.P90 = percentile(lat, 0.9)
+			s.P99 = percentile(lat, 0.99)
+			s.Max = lat[len(lat)-1]
+		}
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// percentile returns the p-th quantile of sorted, by the nearest rank.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(i, 0)]
+}
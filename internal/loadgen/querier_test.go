@@ -0,0 +1,92 @@
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/kubelogs/kubelogs/api/storagepb"
+)
+
+// stubClient answers Query and Stats, failing searches.
+type stubClient struct {
+	storagepb.StorageServiceClient
+
+	mu         sync.Mutex
+	namespaces map[string]bool
+}
+
+func (c *stubClient) Query(ctx context.Context, req *storagepb.QueryRequest, _ ...grpc.CallOption) (*storagepb.QueryResponse, error) {
+	if req.Search != "" {
+		return nil, errors.New("search failed")
+	}
+	c.mu.Lock()
+	if req.Namespace != "" {
+		c.namespaces[req.Namespace] = true
+	}
+	c.mu.Unlock()
+	time.Sleep(time.Millisecond)
+	return &storagepb.QueryResponse{}, nil
+}
+
+func (c *stubClient) Stats(ctx context.Context, _ *storagepb.StatsRequest, _ ...grpc.CallOption) (*storagepb.StatsResponse, error) {
+	return &storagepb.StatsResponse{Namespaces: []*storagepb.NamespaceUsage{{Namespace: "shop"}, {Namespace: "payments"}}}, nil
+}
+
+func TestQuerier(t *testing.T) {
+	client := &stubClient{namespaces: make(map[string]bool)}
+	cfg := DefaultConfig()
+	cfg.Mode = "query"
+	cfg.Concurrency = 3
+	q := NewQuerier(client, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	q.Run(ctx)
+
+	ops := make(map[string]OpLatency)
+	for _, s := range q.Stats() {
+		ops[s.Op] = s
+	}
+	for _, op := range []string{opBrowse, opErrors, opStats} {
+		s := ops[op]
+		if s.Calls == 0 || s.Errors != 0 {
+			t.Errorf("%s: %d calls, %d errors; want calls and no errors", op, s.Calls, s.Errors)
+		}
+		if s.P50 > s.P90 || s.P90 > s.P99 || s.P99 > s.Max {
+			t.Errorf("%s: percentiles out of order: %+v", op, s)
+		}
+	}
+	if s := ops[opBrowse]; s.P50 < time.Millisecond {
+		t.Errorf("browse p50 = %v, want at least the stub's 1ms", s.P50)
+	}
+	if s := ops[opSearch]; s.Calls != 0 || s.Errors == 0 {
+		t.Errorf("search: %d calls, %d errors; want only errors", s.Calls, s.Errors)
+	}
+
+	// Browsing covers the namespaces the server reported
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if len(client.namespaces) != 2 || !client.namespaces["shop"] || !client.namespaces["payments"] {
+		t.Errorf("browsed namespaces = %v, want shop and payments", client.namespaces)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i))
+	}
+	for _, tt := range []struct {
+		p    float64
+		want time.Duration
+	}{{0.5, 50}, {0.9, 90}, {0.99, 99}, {1, 100}, {0, 1}} {
+		if got := percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
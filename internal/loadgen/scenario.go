@@ -0,0 +1,218 @@
+package loadgen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Scenario describes a traffic shape to replay: the namespaces logging
+// and their rates, phases scaling those rates over time, and the mix of
+// line sizes and formats.
+type Scenario struct {
+	// Namespaces log at their rate times the current phase's scale.
+	Namespaces []ScenarioNamespace `json:"namespaces"`
+
+	// Phases run in order; the scenario ends with the last one.
+	Phases []Phase `json:"phases"`
+
+	// MessageSizes is the distribution of line lengths, in bytes.
+	// Default: lines as long as their template.
+	MessageSizes []SizeBucket `json:"messageSizes,omitempty"`
+
+	// Formats weighs the line formats against each other. Default: all
+	// plain text.
+	Formats Formats `json:"formats,omitempty"`
+
+	// ErrorRate is the percentage of lines at error or fatal, as the
+	// -error-rate flag, which it overrides when set.
+	ErrorRate *int `json:"errorRate,omitempty"`
+}
+
+// ScenarioNamespace is a namespace's share of a scenario's traffic.
+type ScenarioNamespace struct {
+	Name string `json:"name"`
+
+	// Rate is the lines per second of the namespace at scale 1.
+	Rate float64 `json:"rate"`
+
+	// Pods is the number of pods logging. Default: 5.
+	Pods int `json:"pods,omitempty"`
+}
+
+// Phase is a stretch of a scenario with its own rate, e.g. a ramp-up,
+// a steady state or a burst.
+type Phase struct {
+	Name     string   `json:"name,omitempty"`
+	Duration Duration `json:"duration"`
+
+	// Scale multiplies the namespace rates. Default: 1.
+	Scale *float64 `json:"scale,omitempty"`
+
+	// From, if set, ramps the scale linearly from it to Scale over the
+	// phase.
+	From *float64 `json:"from,omitempty"`
+}
+
+// SizeBucket is a range of line lengths and the weight of lines in it.
+// Lengths are picked uniformly within the range.
+type SizeBucket struct {
+	Min    int `json:"min"`
+	Max    int `json:"max"`
+	Weight int `json:"weight"`
+}
+
+// Formats weighs line formats, e.g. 60, 30 and 10 for 60% JSON, 30%
+// logfmt and 10% plain text lines.
+type Formats struct {
+	JSON   int `json:"json,omitempty"`
+	Logfmt int `json:"logfmt,omitempty"`
+	Text   int `json:"text,omitempty"`
+}
+
+// Duration is a time.Duration written as a Go duration string, e.g.
+// "2m30s".
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"5m\"")
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// ReadScenario reads and validates the scenario in the YAML file name:
+//
+//	namespaces:
+//	  - name: production
+//	    rate: 400
+//	    pods: 12
+//	  - name: batch
+//	    rate: 50
+//	phases:
+//	  - name: ramp-up
+//	    duration: 2m
+//	    from: 0.1
+//	  - name: steady
+//	    duration: 10m
+//	  - name: burst
+//	    duration: 30s
+//	    scale: 5
+//	messageSizes:
+//	  - {min: 40, max: 160, weight: 80}
+//	  - {min: 1000, max: 8000, weight: 20}
+//	formats:
+//	  json: 60
+//	  logfmt: 30
+//	  text: 10
+func ReadScenario(name string) (*Scenario, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var s Scenario
+	if err := yaml.UnmarshalStrict(data, &s); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", name, err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return &s, nil
+}
+
+// Validate checks the scenario.
+func (s *Scenario) Validate() error {
+	if len(s.Namespaces) == 0 {
+		return errors.New("no namespaces")
+	}
+	for i, ns := range s.Namespaces {
+		if ns.Name == "" {
+			return fmt.Errorf("namespace %d: name cannot be empty", i+1)
+		}
+		if ns.Rate < 0 {
+			return fmt.Errorf("namespace %s: rate cannot be negative", ns.Name)
+		}
+		if ns.Pods < 0 {
+			return fmt.Errorf("namespace %s: pods cannot be negative", ns.Name)
+		}
+	}
+	if len(s.Phases) == 0 {
+		return errors.New("no phases")
+	}
+	for i, p := range s.Phases {
+		if p.Duration <= 0 {
+			return fmt.Errorf("phase %d: duration must be positive", i+1)
+		}
+		if (p.Scale != nil && *p.Scale < 0) || (p.From != nil && *p.From < 0) {
+			return fmt.Errorf("phase %d: scale cannot be negative", i+1)
+		}
+	}
+	for i, b := range s.MessageSizes {
+		if b.Min <= 0 || b.Max < b.Min || b.Weight < 0 {
+			return fmt.Errorf("message size %d: need 0 < min <= max and a weight of at least 0", i+1)
+		}
+	}
+	if s.Formats.JSON < 0 || s.Formats.Logfmt < 0 || s.Formats.Text < 0 {
+		return errors.New("format weights cannot be negative")
+	}
+	if s.ErrorRate != nil && (*s.ErrorRate < 0 || *s.ErrorRate > 100) {
+		return errors.New("errorRate must be between 0 and 100")
+	}
+	return nil
+}
+
+// Duration returns the total duration of the phases.
+func (s *Scenario) Duration() time.Duration {
+	var d time.Duration
+	for _, p := range s.Phases {
+		d += time.Duration(p.Duration)
+	}
+	return d
+}
+
+// Phase returns the index of the phase running at elapsed since the
+// start, or -1 once the scenario is over.
+func (s *Scenario) Phase(elapsed time.Duration) int {
+	for i, p := range s.Phases {
+		if elapsed < time.Duration(p.Duration) {
+			return i
+		}
+		elapsed -= time.Duration(p.Duration)
+	}
+	return -1
+}
+
+// Rate returns the total lines per second at elapsed since the start.
+func (s *Scenario) Rate(elapsed time.Duration) float64 {
+	var base float64
+	for _, ns := range s.Namespaces {
+		base += ns.Rate
+	}
+	for _, p := range s.Phases {
+		d := time.Duration(p.Duration)
+		if elapsed >= d {
+			elapsed -= d
+			continue
+		}
+		scale := 1.0
+		if p.Scale != nil {
+			scale = *p.Scale
+		}
+		if p.From != nil {
+			scale = *p.From + (scale-*p.From)*float64(elapsed)/float64(d)
+		}
+		return base * scale
+	}
+	return 0
+}
@@ -0,0 +1,129 @@
+package loadgen
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testScenario = `
+namespaces:
+  - name: production
+    rate: 300
+    pods: 4
+  - name: batch
+    rate: 100
+phases:
+  - name: ramp-up
+    duration: 10s
+    from: 0
+  - name: steady
+    duration: 1m
+  - name: burst
+    duration: 5s
+    scale: 4
+messageSizes:
+  - {min: 200, max: 300, weight: 1}
+formats:
+  json: 50
+  logfmt: 50
+errorRate: 0
+`
+
+func writeScenario(t *testing.T, content string) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "scenario.yaml")
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestReadScenario(t *testing.T) {
+	sc, err := ReadScenario(writeScenario(t, testScenario))
+	if err != nil {
+		t.Fatalf("ReadScenario: %v", err)
+	}
+	if d := sc.Duration(); d != 75*time.Second {
+		t.Errorf("Duration = %v, want 1m15s", d)
+	}
+
+	tests := []struct {
+		elapsed time.Duration
+		phase   int
+		rate    float64
+	}{
+		{0, 0, 0},
+		{5 * time.Second, 0, 200},
+		{10 * time.Second, 1, 400},
+		{time.Minute, 1, 400},
+		{72 * time.Second, 2, 1600},
+		{75 * time.Second, -1, 0},
+	}
+	for _, tt := range tests {
+		if p := sc.Phase(tt.elapsed); p != tt.phase {
+			t.Errorf("Phase(%v) = %d, want %d", tt.elapsed, p, tt.phase)
+		}
+		if r := sc.Rate(tt.elapsed); math.Abs(r-tt.rate) > 1e-9 {
+			t.Errorf("Rate(%v) = %v, want %v", tt.elapsed, r, tt.rate)
+		}
+	}
+
+	for _, bad := range []string{
+		"phases: [{duration: 1m}]",
+		"namespaces: [{name: a, rate: 1}]",
+		"namespaces: [{name: a, rate: 1}]\nphases: [{duration: 60}]",
+		"namespaces: [{name: a, rate: 1}]\nphases: [{duration: 1m}]\nmessageSizes: [{min: 10, max: 5, weight: 1}]",
+		"namespaces: [{name: a, rate: 1}]\nphases: [{duration: 1m, speed: 2}]",
+	} {
+		if _, err := ReadScenario(writeScenario(t, bad)); err == nil {
+			t.Errorf("ReadScenario(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestGenerator_Scenario(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Scenario = writeScenario(t, testScenario)
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	gen := NewGenerator(cfg)
+	if gen.Scenario() == nil {
+		t.Fatal("scenario not loaded")
+	}
+
+	namespaces := make(map[string]int)
+	pods := make(map[string]bool)
+	var structured int
+	const iterations = 4000
+	for range iterations {
+		e := gen.Next()
+		namespaces[e.Namespace]++
+		pods[e.Pod] = true
+		if e.Severity >= 5 {
+			t.Errorf("severity %d with errorRate 0", e.Severity)
+		}
+		// Both formats are parsed into attributes, leaving the message
+		if e.Attributes["request_id"] != "" {
+			structured++
+		}
+		if strings.Contains(e.Message, "request_id") || len(e.Message) < 100 {
+			t.Errorf("message %q not parsed or not padded", e.Message)
+		}
+	}
+
+	// Namespaces log in proportion to their rates, 3:1
+	if share := float64(namespaces["production"]) / iterations; share < 0.7 || share > 0.8 {
+		t.Errorf("production share = %.2f, want about 0.75", share)
+	}
+	if len(pods) != 9 {
+		t.Errorf("pods = %d, want 4 + the default 5", len(pods))
+	}
+	if structured != iterations {
+		t.Errorf("structured lines = %d, want all %d", structured, iterations)
+	}
+}
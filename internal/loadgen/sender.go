@@ -3,6 +3,7 @@ package loadgen
 import (
 	"context"
 	"log/slog"
+	"maps"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -16,6 +17,28 @@ type SenderStats struct {
 	TotalBatches int64
 	Errors       int64
 	StartTime    time.Time
+
+	// Entries of the written batches per severity, as generated and as
+	// counted by the server. Written is empty for servers that don't
+	// count severities.
+	SentSeverities    map[uint32]int64
+	WrittenSeverities map[uint32]int64
+}
+
+// SeverityMismatches returns the severities whose count by the server
+// differs from the count generated, or nil if the server didn't count
+// them.
+func (s SenderStats) SeverityMismatches() []uint32 {
+	if len(s.WrittenSeverities) == 0 {
+		return nil
+	}
+	var diff []uint32
+	for sev := range uint32(7) {
+		if s.SentSeverities[sev] != s.WrittenSeverities[sev] {
+			diff = append(diff, sev)
+		}
+	}
+	return diff
 }
 
 // Sender batches and sends logs to the gRPC server.
@@ -26,6 +49,8 @@ type Sender struct {
 	mu        sync.Mutex
 	buffer    []*storagepb.LogEntry
 	startTime time.Time
+	sent      map[uint32]int64 // Guarded by mu
+	written   map[uint32]int64 // Guarded by mu
 
 	// Metrics
 	totalLogs    atomic.Int64
@@ -40,6 +65,8 @@ func NewSender(client storagepb.StorageServiceClient, batchSize int) *Sender {
 		batchSize: batchSize,
 		buffer:    make([]*storagepb.LogEntry, 0, batchSize),
 		startTime: time.Now(),
+		sent:      make(map[uint32]int64),
+		written:   make(map[uint32]int64),
 	}
 }
 
@@ -85,6 +112,15 @@ func (s *Sender) Flush(ctx context.Context) error {
 	s.totalLogs.Add(int64(resp.Count))
 	s.totalBatches.Add(1)
 
+	s.mu.Lock()
+	for _, e := range batch {
+		s.sent[e.Severity]++
+	}
+	for sev, n := range resp.SeverityCounts {
+		s.written[sev] += n
+	}
+	s.mu.Unlock()
+
 	slog.Debug("batch sent",
 		"entries", resp.Count,
 		"total", s.totalLogs.Load(),
@@ -95,10 +131,14 @@ func (s *Sender) Flush(ctx context.Context) error {
 
 // Stats returns sender statistics.
 func (s *Sender) Stats() SenderStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return SenderStats{
-		TotalLogs:    s.totalLogs.Load(),
-		TotalBatches: s.totalBatches.Load(),
-		Errors:       s.errors.Load(),
-		StartTime:    s.startTime,
+		TotalLogs:         s.totalLogs.Load(),
+		TotalBatches:      s.totalBatches.Load(),
+		Errors:            s.errors.Load(),
+		StartTime:         s.startTime,
+		SentSeverities:    maps.Clone(s.sent),
+		WrittenSeverities: maps.Clone(s.written),
 	}
 }
@@ -0,0 +1,51 @@
+package loadgen
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/kubelogs/kubelogs/api/storagepb"
+)
+
+// countingClient accepts every batch but its ERROR entries, counting
+// the severities it accepted.
+type countingClient struct {
+	storagepb.StorageServiceClient
+}
+
+func (c *countingClient) Write(ctx context.Context, req *storagepb.WriteRequest, _ ...grpc.CallOption) (*storagepb.WriteResponse, error) {
+	resp := &storagepb.WriteResponse{SeverityCounts: make(map[uint32]int64)}
+	for _, e := range req.Entries {
+		if e.Severity != 5 {
+			resp.Count++
+			resp.SeverityCounts[e.Severity]++
+		}
+	}
+	return resp, nil
+}
+
+func TestSender_SeverityCounts(t *testing.T) {
+	s := NewSender(&countingClient{}, 2)
+	ctx := context.Background()
+	for _, sev := range []uint32{3, 3, 4, 5} {
+		if err := s.Send(ctx, &storagepb.LogEntry{Severity: sev}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+
+	stats := s.Stats()
+	if stats.SentSeverities[3] != 2 || stats.WrittenSeverities[3] != 2 {
+		t.Errorf("INFO sent %d, counted %d, want 2 and 2", stats.SentSeverities[3], stats.WrittenSeverities[3])
+	}
+	if got := stats.SeverityMismatches(); !slices.Equal(got, []uint32{5}) {
+		t.Errorf("SeverityMismatches = %v, want [5]", got)
+	}
+
+	stats.WrittenSeverities = nil
+	if got := stats.SeverityMismatches(); got != nil {
+		t.Errorf("SeverityMismatches without server counts = %v, want nil", got)
+	}
+}
@@ -0,0 +1,249 @@
+// Package logging sets up the structured logger of the kubelogs binaries
+// from the environment, and lets operators raise its level to debug at
+// runtime, with SIGUSR1 or over HTTP, while troubleshooting.
+package logging
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultDebugDuration is how long a level raised at runtime lasts before
+// reverting, unless told otherwise.
+const DefaultDebugDuration = 15 * time.Minute
+
+// MaxDebugDuration caps how long a level changed over HTTP lasts, so a
+// forgotten change can't log at debug forever.
+const MaxDebugDuration = 24 * time.Hour
+
+// Config configures the logger.
+type Config struct {
+	// Level is the minimum level logged. Default: info.
+	Level slog.Level
+
+	// Format is "json" or "text". Default: "json".
+	Format string
+
+	// Token is the bearer token the metrics listener requires to change
+	// the level over HTTP. Default: "" (the level is read-only there).
+	Token string
+}
+
+// ConfigFromEnv reads KUBELOGS_LOG_LEVEL (debug, info, warn or error),
+// KUBELOGS_LOG_FORMAT (json or text) and KUBELOGS_LOG_LEVEL_TOKEN,
+// shared by all binaries.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{Level: slog.LevelInfo, Format: "json"}
+	if v := os.Getenv("KUBELOGS_LOG_LEVEL"); v != "" {
+		if err := cfg.Level.UnmarshalText([]byte(v)); err != nil {
+			return cfg, fmt.Errorf("KUBELOGS_LOG_LEVEL: invalid level %q", v)
+		}
+	}
+	if v := os.Getenv("KUBELOGS_LOG_FORMAT"); v != "" {
+		cfg.Format = strings.ToLower(v)
+	}
+	if cfg.Format != "json" && cfg.Format != "text" {
+		return cfg, fmt.Errorf("KUBELOGS_LOG_FORMAT: must be json or text, got %q", cfg.Format)
+	}
+	cfg.Token = os.Getenv("KUBELOGS_LOG_LEVEL_TOKEN")
+	return cfg, nil
+}
+
+// Setup makes a logger writing to w as configured the default, and
+// returns its level for changes at runtime.
+func Setup(w io.Writer, cfg Config) *Level {
+	level := NewLevel(cfg.Level)
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	if cfg.Format == "text" {
+		h = slog.NewTextHandler(w, opts)
+	} else {
+		h = slog.NewJSONHandler(w, opts)
+	}
+	slog.SetDefault(slog.New(h))
+	return level
+}
+
+// Level is a slog.Leveler that can be changed for a while, after which
+// it reverts to its configured level.
+type Level struct {
+	v    slog.LevelVar
+	base slog.Level
+
+	mu     sync.Mutex
+	until  time.Time // Zero unless a change is due to revert
+	revert *time.Timer
+}
+
+// NewLevel returns a Level at base.
+func NewLevel(base slog.Level) *Level {
+	l := &Level{base: base}
+	l.v.Set(base)
+	return l
+}
+
+// Level implements slog.Leveler.
+func (l *Level) Level() slog.Level {
+	return l.v.Level()
+}
+
+// Set changes the level to level for d, then reverts it to the
+// configured level. A d of 0 keeps the change until the next one.
+func (l *Level) Set(level slog.Level, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.revert != nil {
+		l.revert.Stop()
+		l.revert = nil
+	}
+	l.until = time.Time{}
+	l.v.Set(level)
+	if d > 0 && level != l.base {
+		l.until = time.Now().Add(d)
+		var t *time.Timer
+		t = time.AfterFunc(d, func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			// A later change replaced this one
+			if l.revert == t {
+				l.reset()
+			}
+		})
+		l.revert = t
+	}
+	slog.Log(context.Background(), max(level, slog.LevelInfo), "log level changed", "level", level, "duration", d)
+}
+
+// Reset reverts the level to the configured one.
+func (l *Level) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.reset()
+}
+
+// reset reverts the level. Callers hold l.mu.
+func (l *Level) reset() {
+	if l.revert != nil {
+		l.revert.Stop()
+		l.revert = nil
+	}
+	l.until = time.Time{}
+	if l.v.Level() != l.base {
+		l.v.Set(l.base)
+		slog.Info("log level reverted", "level", l.base)
+	}
+}
+
+// toggleDebug switches to debug for d, or back to the configured level
+// if it was changed.
+func (l *Level) toggleDebug(d time.Duration) {
+	if l.Level() != l.base {
+		l.Reset()
+		return
+	}
+	l.Set(slog.LevelDebug, d)
+}
+
+// NotifyDebug toggles debug logging for DefaultDebugDuration on each
+// SIGUSR1 until ctx is done: the first signal raises the level, the
+// next reverts it early.
+func (l *Level) NotifyDebug(ctx context.Context) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGUSR1)
+	go func() {
+		defer signal.Stop(sigCh)
+		for {
+			select {
+			case <-sigCh:
+				l.toggleDebug(DefaultDebugDuration)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// levelJSON is the state of a Level.
+type levelJSON struct {
+	Level string `json:"level"`
+	Base  string `json:"base"`            // The configured level
+	Until int64  `json:"until,omitempty"` // Unix nanoseconds the level reverts at
+}
+
+// ServeHTTP reports the level on GET, and changes it on PUT or POST to
+// the level parameter, for duration (default 15m, at most
+// MaxDebugDuration). level "reset" reverts to the configured level.
+// Callers authorize changes; see Handler for an unauthenticated listener.
+func (l *Level) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPut, http.MethodPost:
+		params := r.URL.Query()
+		v := params.Get("level")
+		if v == "reset" {
+			l.Reset()
+			break
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(v)); err != nil {
+			http.Error(w, fmt.Sprintf("invalid level %q", v), http.StatusBadRequest)
+			return
+		}
+		d := DefaultDebugDuration
+		if v := params.Get("duration"); v != "" {
+			var err error
+			if d, err = time.ParseDuration(v); err != nil || d <= 0 || d > MaxDebugDuration {
+				http.Error(w, fmt.Sprintf("invalid duration %q: must be between 0 and %s", v, MaxDebugDuration), http.StatusBadRequest)
+				return
+			}
+		}
+		l.Set(level, d)
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	l.mu.Lock()
+	resp := levelJSON{Level: l.Level().String(), Base: l.base.String()}
+	if !l.until.IsZero() {
+		resp.Until = l.until.UnixNano()
+	}
+	l.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Handler serves the level like ServeHTTP, but only changes it for
+// requests bearing token as "Authorization: Bearer <token>". With no
+// token, it only reports the level.
+func (l *Level) Handler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			l.ServeHTTP(w, r)
+			return
+		}
+		if token == "" {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		l.ServeHTTP(w, r)
+	})
+}
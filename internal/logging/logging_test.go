@@ -0,0 +1,141 @@
+package logging
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		level, format string
+		want          Config
+		wantErr       bool
+	}{
+		{"", "", Config{Level: slog.LevelInfo, Format: "json"}, false},
+		{"debug", "text", Config{Level: slog.LevelDebug, Format: "text"}, false},
+		{"WARN", "JSON", Config{Level: slog.LevelWarn, Format: "json"}, false},
+		{"verbose", "", Config{}, true},
+		{"", "xml", Config{}, true},
+	}
+	for _, tt := range tests {
+		t.Setenv("KUBELOGS_LOG_LEVEL", tt.level)
+		t.Setenv("KUBELOGS_LOG_FORMAT", tt.format)
+		got, err := ConfigFromEnv()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ConfigFromEnv(%q, %q) error = %v, wantErr %v", tt.level, tt.format, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ConfigFromEnv(%q, %q) = %+v, want %+v", tt.level, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestLevel_Revert(t *testing.T) {
+	l := NewLevel(slog.LevelInfo)
+
+	l.Set(slog.LevelDebug, 20*time.Millisecond)
+	if l.Level() != slog.LevelDebug {
+		t.Fatalf("level = %v, want DEBUG", l.Level())
+	}
+	deadline := time.Now().Add(time.Second)
+	for l.Level() != slog.LevelInfo {
+		if time.Now().After(deadline) {
+			t.Fatal("level did not revert")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// A later change outlives the earlier one's revert
+	l.Set(slog.LevelDebug, 20*time.Millisecond)
+	l.Set(slog.LevelWarn, 0)
+	time.Sleep(50 * time.Millisecond)
+	if l.Level() != slog.LevelWarn {
+		t.Errorf("level = %v, want WARN", l.Level())
+	}
+
+	l.toggleDebug(time.Minute)
+	if l.Level() != slog.LevelInfo {
+		t.Errorf("level after toggle = %v, want INFO", l.Level())
+	}
+	l.toggleDebug(time.Minute)
+	if l.Level() != slog.LevelDebug {
+		t.Errorf("level after second toggle = %v, want DEBUG", l.Level())
+	}
+	l.Reset()
+}
+
+func TestLevel_ServeHTTP(t *testing.T) {
+	l := NewLevel(slog.LevelInfo)
+
+	tests := []struct {
+		method, target string
+		wantStatus     int
+		wantLevel      string
+		wantUntil      bool
+	}{
+		{"GET", "/loglevel", http.StatusOK, "INFO", false},
+		{"PUT", "/loglevel?level=debug", http.StatusOK, "DEBUG", true},
+		{"PUT", "/loglevel?level=warn&duration=1h", http.StatusOK, "WARN", true},
+		{"POST", "/loglevel?level=reset", http.StatusOK, "INFO", false},
+		{"PUT", "/loglevel?level=loud", http.StatusBadRequest, "", false},
+		{"PUT", "/loglevel?level=debug&duration=soon", http.StatusBadRequest, "", false},
+		{"PUT", "/loglevel?level=debug&duration=0", http.StatusBadRequest, "", false},
+		{"PUT", "/loglevel?level=debug&duration=25h", http.StatusBadRequest, "", false},
+		{"DELETE", "/loglevel", http.StatusMethodNotAllowed, "", false},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		l.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s %s status = %d, want %d", tt.method, tt.target, w.Code, tt.wantStatus)
+			continue
+		}
+		if tt.wantStatus != http.StatusOK {
+			continue
+		}
+		var resp levelJSON
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if resp.Level != tt.wantLevel || resp.Base != "INFO" || (resp.Until != 0) != tt.wantUntil {
+			t.Errorf("%s %s = %+v, want level %s", tt.method, tt.target, resp, tt.wantLevel)
+		}
+	}
+}
+
+func TestLevel_Handler(t *testing.T) {
+	tests := []struct {
+		name, token, method, auth string
+		wantStatus                int
+	}{
+		{"get without token", "", "GET", "", http.StatusOK},
+		{"put without token", "", "PUT", "", http.StatusMethodNotAllowed},
+		{"put with token configured but missing", "s3cret", "PUT", "", http.StatusUnauthorized},
+		{"put with wrong token", "s3cret", "PUT", "Bearer nope", http.StatusUnauthorized},
+		{"put with token", "s3cret", "PUT", "Bearer s3cret", http.StatusOK},
+		{"get with token configured", "s3cret", "GET", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLevel(slog.LevelInfo)
+			req := httptest.NewRequest(tt.method, "/loglevel?level=debug", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			l.Handler(tt.token).ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			changed := l.Level() == slog.LevelDebug
+			if want := tt.method == "PUT" && tt.wantStatus == http.StatusOK; changed != want {
+				t.Errorf("level changed = %v, want %v", changed, want)
+			}
+		})
+	}
+}
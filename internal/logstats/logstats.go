@@ -0,0 +1,108 @@
+// Package logstats stores hourly entry counts per namespace and
+// severity, so charts over weeks read a small table instead of scanning
+// log entries.
+package logstats
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// Interval is the width of the time buckets counts are kept in.
+const Interval = time.Hour
+
+// Count is the number of entries of one namespace and severity in the
+// hour starting at Hour.
+type Count struct {
+	Hour      time.Time
+	Namespace string
+	Severity  storage.Severity
+	Count     int64
+}
+
+// Store manages hourly count persistence.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a Store with the given database connection.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Replace sets the counts of the hours in [start, end) to counts, so
+// hours without counts are cleared.
+func (s *Store) Replace(ctx context.Context, start, end time.Time, counts []Count) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM log_stats WHERE hour >= ? AND hour < ?`,
+		start.UnixNano(), end.UnixNano()); err != nil {
+		return err
+	}
+	for _, c := range counts {
+		_, err := tx.ExecContext(ctx, `INSERT INTO log_stats (hour, namespace, severity, count) VALUES (?, ?, ?, ?)
+			ON CONFLICT (hour, namespace, severity) DO UPDATE SET count = count + excluded.count`,
+			c.Hour.UnixNano(), c.Namespace, c.Severity, c.Count)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Latest returns the start of the latest hour with counts, and false if
+// there are none.
+func (s *Store) Latest(ctx context.Context) (time.Time, bool, error) {
+	var hour sql.NullInt64
+	if err := s.db.QueryRowContext(ctx, `SELECT MAX(hour) FROM log_stats`).Scan(&hour); err != nil {
+		return time.Time{}, false, err
+	}
+	if !hour.Valid {
+		return time.Time{}, false, nil
+	}
+	return time.Unix(0, hour.Int64), true, nil
+}
+
+// List returns the counts of the hours in [start, end), of namespace
+// only unless it's empty, sorted by hour, namespace and severity.
+func (s *Store) List(ctx context.Context, start, end time.Time, namespace string) ([]Count, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT hour, namespace, severity, count FROM log_stats
+		 WHERE hour >= ? AND hour < ? AND ? IN ('', namespace)
+		 ORDER BY hour, namespace, severity`,
+		start.UnixNano(), end.UnixNano(), namespace,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []Count
+	for rows.Next() {
+		var c Count
+		var hour int64
+		if err := rows.Scan(&hour, &c.Namespace, &c.Severity, &c.Count); err != nil {
+			return nil, err
+		}
+		c.Hour = time.Unix(0, hour)
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// DeleteBefore removes the counts of hours starting before t and returns
+// the number of rows deleted.
+func (s *Store) DeleteBefore(ctx context.Context, t time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM log_stats WHERE hour < ?`, t.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,212 @@
+// Package metrics exposes counters, gauges and histograms in the
+// Prometheus text format, without the client library's dependencies.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefBuckets are latency buckets in seconds, from 5ms to 10s.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry holds metrics and serves them on /metrics. Series with the
+// same name but different labels form one family.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+type family struct {
+	name, help, typ string
+	series          []series
+}
+
+// series writes its sample lines; labels are rendered, e.g. `api="http"`.
+type series struct {
+	labels string
+	write  func(w io.Writer, name, labels string)
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// Counter is a monotonically increasing count. A nil Counter ignores
+// updates, so instrumented code works without a registry.
+type Counter struct {
+	v atomic.Int64
+}
+
+// Inc adds one.
+func (c *Counter) Inc() { c.Add(1) }
+
+// Add adds n, which must not be negative.
+func (c *Counter) Add(n int64) {
+	if c != nil {
+		c.v.Add(n)
+	}
+}
+
+// Value returns the current count.
+func (c *Counter) Value() int64 {
+	if c == nil {
+		return 0
+	}
+	return c.v.Load()
+}
+
+// Histogram counts observations in cumulative buckets. A nil Histogram
+// ignores observations.
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64 // Per bucket, not cumulative; last is +Inf
+	sum     float64
+	count   uint64
+}
+
+// Observe records a value.
+func (h *Histogram) Observe(v float64) {
+	if h == nil {
+		return
+	}
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.mu.Lock()
+	h.counts[i]++
+	h.sum += v
+	h.count++
+	h.mu.Unlock()
+}
+
+// Counter registers a counter. labels are name/value pairs.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	c := &Counter{}
+	r.CounterFunc(name, help, func() float64 { return float64(c.Value()) }, labels...)
+	return c
+}
+
+// CounterFunc registers a counter whose value fn reports at scrape time,
+// for counts kept elsewhere.
+func (r *Registry) CounterFunc(name, help string, fn func() float64, labels ...string) {
+	r.add(name, help, "counter", labels, func(w io.Writer, name, labels string) {
+		writeSample(w, name, labels, fn())
+	})
+}
+
+// GaugeFunc registers a gauge whose value fn reports at scrape time.
+func (r *Registry) GaugeFunc(name, help string, fn func() float64, labels ...string) {
+	r.add(name, help, "gauge", labels, func(w io.Writer, name, labels string) {
+		writeSample(w, name, labels, fn())
+	})
+}
+
+// Histogram registers a histogram with the given upper bounds, sorted
+// ascending.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{buckets: buckets, counts: make([]uint64, len(buckets)+1)}
+	r.add(name, help, "histogram", labels, func(w io.Writer, name, labels string) {
+		h.mu.Lock()
+		counts := append([]uint64(nil), h.counts...)
+		sum, count := h.sum, h.count
+		h.mu.Unlock()
+
+		var cum uint64
+		for i, le := range h.buckets {
+			cum += counts[i]
+			writeSample(w, name+"_bucket", joinLabels(labels, `le="`+formatFloat(le)+`"`), float64(cum))
+		}
+		writeSample(w, name+"_bucket", joinLabels(labels, `le="+Inf"`), float64(count))
+		writeSample(w, name+"_sum", labels, sum)
+		writeSample(w, name+"_count", labels, float64(count))
+	})
+	return h
+}
+
+func (r *Registry) add(name, help, typ string, labels []string, write func(io.Writer, string, string)) {
+	if len(labels)%2 != 0 {
+		panic("metrics: labels must be name/value pairs")
+	}
+	var pairs []string
+	for i := 0; i < len(labels); i += 2 {
+		pairs = append(pairs, labels[i]+`="`+escapeLabel(labels[i+1])+`"`)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{name: name, help: help, typ: typ}
+		r.families[name] = f
+	} else if f.typ != typ {
+		panic(fmt.Sprintf("metrics: %s registered as %s and %s", name, f.typ, typ))
+	}
+	f.series = append(f.series, series{labels: strings.Join(pairs, ","), write: write})
+}
+
+// WriteText writes all metrics in the Prometheus text format, sorted by
+// name.
+func (r *Registry) WriteText(w io.Writer) {
+	r.mu.Lock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.Unlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	for _, f := range families {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, escapeHelp(f.help), f.name, f.typ)
+		for _, s := range f.series {
+			s.write(w, f.name, s.labels)
+		}
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteText(w)
+}
+
+func writeSample(w io.Writer, name, labels string, v float64) {
+	if labels != "" {
+		fmt.Fprintf(w, "%s{%s} %s\n", name, labels, formatFloat(v))
+	} else {
+		fmt.Fprintf(w, "%s %s\n", name, formatFloat(v))
+	}
+}
+
+func joinLabels(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "," + b
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+func escapeHelp(s string) string { return helpEscaper.Replace(s) }
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+
+	writes := r.Counter("app_writes_total", "Writes.")
+	writes.Add(3)
+	writes.Inc()
+	r.GaugeFunc("app_streams", "Active\nstreams.", func() float64 { return 7 })
+	r.CounterFunc("app_lines_total", "Lines read.", func() float64 { return 1.5e6 })
+	grpc := r.Histogram("app_query_seconds", "Query latency.", []float64{0.1, 1}, "api", "grpc")
+	http := r.Histogram("app_query_seconds", "Query latency.", []float64{0.1, 1}, "api", `h"t`)
+	grpc.Observe(0.05)
+	grpc.Observe(0.5)
+	grpc.Observe(5)
+	http.Observe(0.1)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	want := `# HELP app_lines_total Lines read.
+# TYPE app_lines_total counter
+app_lines_total 1.5e+06
+# HELP app_query_seconds Query latency.
+# TYPE app_query_seconds histogram
+app_query_seconds_bucket{api="grpc",le="0.1"} 1
+app_query_seconds_bucket{api="grpc",le="1"} 2
+app_query_seconds_bucket{api="grpc",le="+Inf"} 3
+app_query_seconds_sum{api="grpc"} 5.55
+app_query_seconds_count{api="grpc"} 3
+app_query_seconds_bucket{api="h\"t",le="0.1"} 1
+app_query_seconds_bucket{api="h\"t",le="1"} 1
+app_query_seconds_bucket{api="h\"t",le="+Inf"} 1
+app_query_seconds_sum{api="h\"t"} 0.1
+app_query_seconds_count{api="h\"t"} 1
+# HELP app_streams Active\nstreams.
+# TYPE app_streams gauge
+app_streams 7
+# HELP app_writes_total Writes.
+# TYPE app_writes_total counter
+app_writes_total 4
+`
+	if got := rec.Body.String(); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
+
+func TestNilInstruments(t *testing.T) {
+	var c *Counter
+	c.Inc()
+	if c.Value() != 0 {
+		t.Error("nil counter has a value")
+	}
+	var h *Histogram
+	h.Observe(1)
+}
+
+func TestRegistryTypeConflict(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("x", "")
+	defer func() {
+		if recover() == nil {
+			t.Error("registering x as a gauge should panic")
+		}
+	}()
+	r.GaugeFunc("x", "", func() float64 { return 0 })
+}
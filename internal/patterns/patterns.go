@@ -0,0 +1,167 @@
+// Package patterns reduces log messages to templates by masking variable
+// tokens (numbers, IDs, addresses), so that similar lines can be counted
+// and compared as one pattern.
+package patterns
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxPatternLen bounds the size of a template; longer messages are cut.
+const maxPatternLen = 256
+
+// maskers are applied in order; earlier ones take precedence over
+// the generic number rule.
+var maskers = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), "<uuid>"},
+	{regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b`), "<ip>"},
+	{regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})?`), "<ts>"},
+	{regexp.MustCompile(`\b(?:0x[0-9a-fA-F]+|[0-9a-f]*\d[0-9a-f]*[a-f][0-9a-f]*|[0-9a-f]*[a-f][0-9a-f]*\d[0-9a-f]*)\b`), "<hex>"},
+	{regexp.MustCompile(`\b\d+(?:\.\d+)?(?:[a-zµ]{1,2})?\b`), "<num>"},
+	{regexp.MustCompile(`"[^"]{32,}"`), `"<str>"`},
+}
+
+var spaces = regexp.MustCompile(`\s+`)
+
+// Template returns the pattern for message.
+func Template(message string) string {
+	t := message
+	for _, m := range maskers {
+		t = m.re.ReplaceAllString(t, m.repl)
+	}
+	t = strings.TrimSpace(spaces.ReplaceAllString(t, " "))
+	if len(t) > maxPatternLen {
+		t = t[:maxPatternLen]
+	}
+	return t
+}
+
+// Count tracks how often a pattern occurred, with one example message.
+type Count struct {
+	Pattern string
+	Example string
+	N       int
+}
+
+// Counts maps patterns to their counts.
+type Counts map[string]*Count
+
+// Add records message under its template.
+func (c Counts) Add(message string) {
+	t := Template(message)
+	if pc, ok := c[t]; ok {
+		pc.N++
+		return
+	}
+	c[t] = &Count{Pattern: t, Example: message, N: 1}
+}
+
+// ChangeKind classifies how a pattern differs between two windows.
+type ChangeKind string
+
+const (
+	ChangeNew       ChangeKind = "new"
+	ChangeMissing   ChangeKind = "missing"
+	ChangeIncreased ChangeKind = "increased"
+)
+
+// Change describes a pattern whose frequency differs between windows.
+type Change struct {
+	Kind          ChangeKind
+	Pattern       string
+	Example       string
+	BaselineCount int
+	CompareCount  int
+	// Ratio is the compare/baseline rate ratio (0 for new or missing patterns).
+	Ratio float64
+}
+
+// DiffOptions tunes which differences are reported.
+type DiffOptions struct {
+	// BaselineWeight and CompareWeight normalize counts, typically the
+	// window durations, so windows of different length compare by rate.
+	// Zero means 1.
+	BaselineWeight float64
+	CompareWeight  float64
+
+	// MinRatio is the rate increase needed to report a pattern as increased.
+	MinRatio float64
+
+	// MinCount ignores patterns seen fewer times than this in both windows.
+	MinCount int
+}
+
+// Diff compares pattern counts and returns new, missing and increased patterns.
+// New patterns come first (most frequent first), then increased (largest
+// ratio first), then missing (most frequent first).
+func Diff(baseline, compare Counts, opts DiffOptions) []Change {
+	bw, cw := opts.BaselineWeight, opts.CompareWeight
+	if bw <= 0 {
+		bw = 1
+	}
+	if cw <= 0 {
+		cw = 1
+	}
+
+	var added, increased, missing []Change
+
+	for p, c := range compare {
+		b, seen := baseline[p]
+		if !seen {
+			if c.N >= opts.MinCount {
+				added = append(added, Change{Kind: ChangeNew, Pattern: p, Example: c.Example, CompareCount: c.N})
+			}
+			continue
+		}
+		if max(b.N, c.N) < opts.MinCount {
+			continue
+		}
+		ratio := (float64(c.N) / cw) / (float64(b.N) / bw)
+		if ratio >= opts.MinRatio {
+			increased = append(increased, Change{
+				Kind:          ChangeIncreased,
+				Pattern:       p,
+				Example:       c.Example,
+				BaselineCount: b.N,
+				CompareCount:  c.N,
+				Ratio:         ratio,
+			})
+		}
+	}
+
+	for p, b := range baseline {
+		if _, seen := compare[p]; !seen && b.N >= opts.MinCount {
+			missing = append(missing, Change{Kind: ChangeMissing, Pattern: p, Example: b.Example, BaselineCount: b.N})
+		}
+	}
+
+	// Pattern breaks ties so output is stable
+	sort.Slice(added, func(i, j int) bool {
+		if added[i].CompareCount != added[j].CompareCount {
+			return added[i].CompareCount > added[j].CompareCount
+		}
+		return added[i].Pattern < added[j].Pattern
+	})
+	sort.Slice(increased, func(i, j int) bool {
+		if increased[i].Ratio != increased[j].Ratio {
+			return increased[i].Ratio > increased[j].Ratio
+		}
+		return increased[i].Pattern < increased[j].Pattern
+	})
+	sort.Slice(missing, func(i, j int) bool {
+		if missing[i].BaselineCount != missing[j].BaselineCount {
+			return missing[i].BaselineCount > missing[j].BaselineCount
+		}
+		return missing[i].Pattern < missing[j].Pattern
+	})
+
+	changes := make([]Change, 0, len(added)+len(increased)+len(missing))
+	changes = append(changes, added...)
+	changes = append(changes, increased...)
+	return append(changes, missing...)
+}
@@ -0,0 +1,81 @@
+package patterns
+
+import (
+	"testing"
+)
+
+func TestTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{"plain", "server started", "server started"},
+		{"numbers", "processed 42 items in 13ms", "processed <num> items in <num>"},
+		{"float", "load average 0.75", "load average <num>"},
+		{"ip with port", "dial tcp 10.0.3.17:5432: connection refused", "dial tcp <ip>: connection refused"},
+		{"uuid", "request 3f2b8c1e-9a4d-4e6f-8b2a-1c3d5e7f9a0b failed", "request <uuid> failed"},
+		{"hex", "commit 9f86d081884c7d65 pushed", "commit <hex> pushed"},
+		{"key value", "user_id=1234 status=500", "user_id=<num> status=<num>"},
+		{"timestamp", "token expired at 2024-03-01T12:00:00Z", "token expired at <ts>"},
+		{"words kept", "cafe deadbeef added", "cafe deadbeef added"},
+		{"whitespace", "  too   many\tspaces ", "too many spaces"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Template(tt.message); got != tt.want {
+				t.Errorf("Template(%q) = %q, want %q", tt.message, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	baseline := Counts{}
+	compare := Counts{}
+	add := func(c Counts, msg string, n int) {
+		for i := 0; i < n; i++ {
+			c.Add(msg)
+		}
+	}
+
+	add(baseline, "request took 12ms", 10)
+	add(baseline, "cache warmed", 5)
+	add(baseline, "healthcheck ok", 100)
+
+	add(compare, "request took 90ms", 40)
+	add(compare, "connection refused to 10.0.0.1:80", 7)
+	add(compare, "healthcheck ok", 110)
+	add(compare, "rare thing", 1)
+
+	changes := Diff(baseline, compare, DiffOptions{MinRatio: 2, MinCount: 2})
+
+	want := []struct {
+		kind    ChangeKind
+		pattern string
+	}{
+		{ChangeNew, "connection refused to <ip>"},
+		{ChangeIncreased, "request took <num>"},
+		{ChangeMissing, "cache warmed"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes %+v, want %d", len(changes), changes, len(want))
+	}
+	for i, w := range want {
+		if changes[i].Kind != w.kind || changes[i].Pattern != w.pattern {
+			t.Errorf("change %d = %s %q, want %s %q", i, changes[i].Kind, changes[i].Pattern, w.kind, w.pattern)
+		}
+	}
+	if changes[1].Ratio != 4 {
+		t.Errorf("ratio = %v, want 4", changes[1].Ratio)
+	}
+
+	// Weights normalize windows of different length
+	changes = Diff(baseline, compare, DiffOptions{BaselineWeight: 1, CompareWeight: 4, MinRatio: 2, MinCount: 2})
+	for _, c := range changes {
+		if c.Kind == ChangeIncreased {
+			t.Errorf("unexpected increase %q with 4x longer compare window", c.Pattern)
+		}
+	}
+}
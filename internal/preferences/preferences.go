@@ -0,0 +1,74 @@
+// Package preferences stores per-user UI preferences, such as the theme
+// and visible columns. Preferences are a JSON object owned by the UI;
+// the server only checks its size and shape.
+package preferences
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalid is returned for preferences that aren't a JSON object or are
+// too large.
+var ErrInvalid = errors.New("preferences: invalid")
+
+// MaxLength bounds the size of a user's preferences in bytes.
+const MaxLength = 4096
+
+// Validate checks that data is a JSON object of at most MaxLength bytes.
+func Validate(data []byte) error {
+	if len(data) > MaxLength {
+		return fmt.Errorf("%w: longer than %d bytes", ErrInvalid, MaxLength)
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil || obj == nil {
+		return fmt.Errorf("%w: not a JSON object", ErrInvalid)
+	}
+	return nil
+}
+
+// Store manages preference persistence, one JSON object per user.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a Store with the given database connection.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Get returns a user's preferences, or an empty object if they have
+// saved none.
+func (s *Store) Get(ctx context.Context, userID int64) (json.RawMessage, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT data FROM user_preferences WHERE user_id = ?`,
+		userID,
+	).Scan(&data)
+
+	if err == sql.ErrNoRows {
+		return json.RawMessage("{}"), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(data), nil
+}
+
+// Put replaces a user's preferences.
+func (s *Store) Put(ctx context.Context, userID int64, data json.RawMessage) error {
+	if err := Validate(data); err != nil {
+		return err
+	}
+
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO user_preferences (user_id, data, updated_at) VALUES (?, ?, ?)
+		 ON CONFLICT (user_id) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`,
+		userID, string(data), time.Now().UnixNano(),
+	)
+	return err
+}
@@ -0,0 +1,422 @@
+// Package queue buffers ingest between collectors and the storage server
+// in a Redis stream. Collectors append encoded write batches; servers
+// read them through a consumer group and acknowledge each batch once it
+// is stored, so batches survive server restarts and ingest spikes wait
+// in Redis instead of pushing back on collectors.
+package queue
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultStream = "kubelogs"
+	defaultGroup  = "kubelogs"
+	defaultMaxLen = 100000
+
+	dialTimeout = 5 * time.Second
+
+	// commandTimeout bounds a command, on top of any time it asks
+	// Redis to block.
+	commandTimeout = 10 * time.Second
+
+	// dataField is the stream entry field holding a batch.
+	dataField = "d"
+
+	// doneTTL is how long stored batches are remembered.
+	doneTTL = 24 * time.Hour
+)
+
+// Config configures a Redis stream.
+type Config struct {
+	// Addr is the Redis server "host:port".
+	Addr string
+
+	// Username and Password authenticate with AUTH, if set.
+	Username string
+	Password string
+
+	// DB selects a logical database. Default: 0.
+	DB int
+
+	// Stream is the stream key. Default: "kubelogs".
+	Stream string
+
+	// Group is the consumer group servers read through. Default:
+	// "kubelogs".
+	Group string
+
+	// Consumer names this reader within the group. A restarted reader
+	// with the same name resumes the batches it hadn't acknowledged.
+	Consumer string
+
+	// MaxLen caps the stream at roughly this many batches; the oldest
+	// are trimmed, even if unread. Default: 100000.
+	MaxLen int64
+}
+
+// ConfigFromEnv reads the KUBELOGS_QUEUE_* variables shared by
+// collectors and servers. Addr is empty if no queue is configured.
+// Consumer defaults to the host name, which is the pod name.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Addr:     os.Getenv("KUBELOGS_QUEUE_ADDR"),
+		Username: os.Getenv("KUBELOGS_QUEUE_USERNAME"),
+		Password: os.Getenv("KUBELOGS_QUEUE_PASSWORD"),
+		Stream:   os.Getenv("KUBELOGS_QUEUE_STREAM"),
+		Group:    os.Getenv("KUBELOGS_QUEUE_GROUP"),
+		Consumer: os.Getenv("KUBELOGS_QUEUE_CONSUMER"),
+	}
+	if cfg.Consumer == "" {
+		cfg.Consumer, _ = os.Hostname()
+	}
+
+	if v := os.Getenv("KUBELOGS_QUEUE_DB"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.DB = n
+		}
+	}
+	if v := os.Getenv("KUBELOGS_QUEUE_MAX_LEN"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			cfg.MaxLen = n
+		}
+	}
+
+	return cfg
+}
+
+// Message is a batch read from the stream.
+type Message struct {
+	ID   string
+	Data []byte
+}
+
+// Error is an error reply from Redis.
+type Error string
+
+func (e Error) Error() string { return "redis: " + string(e) }
+
+// Stream is a Redis stream connection, safe for concurrent use. Commands
+// share one connection, which is redialed after network errors.
+type Stream struct {
+	cfg Config
+
+	mu   sync.Mutex
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// New returns a stream for cfg. It connects on first use.
+func New(cfg Config) (*Stream, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("queue: address is required")
+	}
+	if cfg.Stream == "" {
+		cfg.Stream = defaultStream
+	}
+	if cfg.Group == "" {
+		cfg.Group = defaultGroup
+	}
+	if cfg.MaxLen <= 0 {
+		cfg.MaxLen = defaultMaxLen
+	}
+	if cfg.Consumer == "" {
+		cfg.Consumer = "kubelogs"
+	}
+	return &Stream{cfg: cfg}, nil
+}
+
+// Publish appends a batch to the stream.
+func (s *Stream) Publish(ctx context.Context, data []byte) error {
+	_, err := s.do(ctx, 0, "XADD", s.cfg.Stream, "MAXLEN", "~", strconv.FormatInt(s.cfg.MaxLen, 10), "*", dataField, string(data))
+	return err
+}
+
+// CreateGroup creates the consumer group, and the stream if needed. A new
+// group starts at the beginning of the stream, so batches published
+// before any server ran are not skipped.
+func (s *Stream) CreateGroup(ctx context.Context) error {
+	_, err := s.do(ctx, 0, "XGROUP", "CREATE", s.cfg.Stream, s.cfg.Group, "0", "MKSTREAM")
+	var rerr Error
+	if errors.As(err, &rerr) && strings.HasPrefix(string(rerr), "BUSYGROUP") {
+		return nil
+	}
+	return err
+}
+
+// Read returns up to count batches for this consumer. With pending, it
+// returns batches delivered earlier but not acknowledged, without
+// blocking; otherwise it waits up to block for new ones.
+func (s *Stream) Read(ctx context.Context, count int, block time.Duration, pending bool) ([]Message, error) {
+	id := ">"
+	if pending {
+		id, block = "0", 0
+	}
+	args := []string{"XREADGROUP", "GROUP", s.cfg.Group, s.cfg.Consumer, "COUNT", strconv.Itoa(count)}
+	if !pending {
+		args = append(args, "BLOCK", strconv.FormatInt(block.Milliseconds(), 10))
+	}
+	args = append(args, "STREAMS", s.cfg.Stream, id)
+
+	reply, err := s.do(ctx, block, args...)
+	if err != nil || reply == nil {
+		return nil, err
+	}
+	return parseEntries(reply)
+}
+
+// Claim takes over batches other consumers read but left unacknowledged
+// for at least minIdle, e.g. those of a server that was replaced, so
+// pending reads return them. It returns how many were claimed.
+func (s *Stream) Claim(ctx context.Context, minIdle time.Duration) (int, error) {
+	malformed := errors.New("queue: malformed XAUTOCLAIM reply")
+	claimed := 0
+	for start := "0-0"; ; {
+		reply, err := s.do(ctx, 0, "XAUTOCLAIM", s.cfg.Stream, s.cfg.Group, s.cfg.Consumer,
+			strconv.FormatInt(minIdle.Milliseconds(), 10), start, "COUNT", "100", "JUSTID")
+		if err != nil {
+			return claimed, err
+		}
+		items, ok := reply.([]any)
+		if !ok || len(items) < 2 {
+			return claimed, malformed
+		}
+		next, _ := items[0].([]byte)
+		ids, _ := items[1].([]any)
+		claimed += len(ids)
+		if next == nil || string(next) == "0-0" {
+			return claimed, nil
+		}
+		start = string(next)
+	}
+}
+
+// Done reports whether a batch with this ID was marked stored, by any
+// consumer in the last day.
+func (s *Stream) Done(ctx context.Context, batchID string) (bool, error) {
+	reply, err := s.do(ctx, 0, "EXISTS", s.doneKey(batchID))
+	if err != nil {
+		return false, err
+	}
+	n, _ := reply.(int64)
+	return n > 0, nil
+}
+
+// MarkDone records that a batch was stored, so redeliveries and copies
+// published by collector retries are skipped.
+func (s *Stream) MarkDone(ctx context.Context, batchID string) error {
+	_, err := s.do(ctx, 0, "SET", s.doneKey(batchID), "1", "EX", strconv.Itoa(int(doneTTL.Seconds())))
+	return err
+}
+
+// doneKey is the key marking a batch stored.
+func (s *Stream) doneKey(batchID string) string {
+	return s.cfg.Stream + ":done:" + batchID
+}
+
+// Ack acknowledges a batch, removing it from the consumer's pending list.
+func (s *Stream) Ack(ctx context.Context, id string) error {
+	_, err := s.do(ctx, 0, "XACK", s.cfg.Stream, s.cfg.Group, id)
+	return err
+}
+
+// Close closes the connection.
+func (s *Stream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// do sends a command and reads its reply. block is how long the command
+// may wait in Redis.
+func (s *Stream) do(ctx context.Context, block time.Duration, args ...string) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		if err := s.dial(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := s.roundTrip(ctx, block, args)
+	var rerr Error
+	if err != nil && !errors.As(err, &rerr) {
+		// The connection is in an unknown state
+		s.conn.Close()
+		s.conn = nil
+	}
+	return reply, err
+}
+
+// dial connects and authenticates. Called with mu held.
+func (s *Stream) dial(ctx context.Context) error {
+	d := net.Dialer{Timeout: dialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", s.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("queue: dial %s: %w", s.cfg.Addr, err)
+	}
+	s.conn, s.br = conn, bufio.NewReader(conn)
+
+	var setup [][]string
+	if s.cfg.Password != "" {
+		if s.cfg.Username != "" {
+			setup = append(setup, []string{"AUTH", s.cfg.Username, s.cfg.Password})
+		} else {
+			setup = append(setup, []string{"AUTH", s.cfg.Password})
+		}
+	}
+	if s.cfg.DB != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(s.cfg.DB)})
+	}
+	for _, args := range setup {
+		if _, err := s.roundTrip(ctx, 0, args); err != nil {
+			conn.Close()
+			s.conn = nil
+			return fmt.Errorf("queue: %s: %w", args[0], err)
+		}
+	}
+	return nil
+}
+
+// roundTrip writes a command on the connection and reads the reply,
+// giving up when ctx is done.
+func (s *Stream) roundTrip(ctx context.Context, block time.Duration, args []string) (any, error) {
+	conn := s.conn
+	conn.SetDeadline(time.Now().Add(commandTimeout + block))
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	if _, err := conn.Write(encodeCommand(args)); err != nil {
+		return nil, ctxErr(ctx, err)
+	}
+	reply, err := readReply(s.br)
+	return reply, ctxErr(ctx, err)
+}
+
+// ctxErr prefers the context's error over the timeout it caused.
+func ctxErr(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// encodeCommand encodes a command as a RESP array of bulk strings.
+func encodeCommand(args []string) []byte {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, "\r\n"...)
+	for _, a := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(a)), 10)
+		buf = append(buf, "\r\n"...)
+		buf = append(buf, a...)
+		buf = append(buf, "\r\n"...)
+	}
+	return buf
+}
+
+// readReply reads one RESP2 reply: a string, Error, int64, []byte, []any
+// or nil.
+func readReply(br *bufio.Reader) (any, error) {
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("queue: malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, Error(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readReply(br); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("queue: unknown reply type %q", kind)
+}
+
+// parseEntries extracts messages from an XREADGROUP reply:
+// [[stream, [[id, [field, value, ...]], ...]]].
+func parseEntries(reply any) ([]Message, error) {
+	malformed := errors.New("queue: malformed XREADGROUP reply")
+
+	streams, ok := reply.([]any)
+	if !ok || len(streams) == 0 {
+		return nil, malformed
+	}
+	stream, ok := streams[0].([]any)
+	if !ok || len(stream) != 2 {
+		return nil, malformed
+	}
+	entries, ok := stream[1].([]any)
+	if !ok {
+		return nil, malformed
+	}
+
+	msgs := make([]Message, 0, len(entries))
+	for _, e := range entries {
+		entry, ok := e.([]any)
+		if !ok || len(entry) != 2 {
+			return nil, malformed
+		}
+		id, ok := entry[0].([]byte)
+		if !ok {
+			return nil, malformed
+		}
+		msg := Message{ID: string(id)}
+		// Fields are nil for entries trimmed from the stream while pending
+		fields, _ := entry[1].([]any)
+		for i := 0; i+1 < len(fields); i += 2 {
+			if k, _ := fields[i].([]byte); string(k) == dataField {
+				msg.Data, _ = fields[i+1].([]byte)
+			}
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
@@ -0,0 +1,285 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis serves the stream commands Stream uses, for one stream and
+// one consumer.
+type fakeRedis struct {
+	mu       sync.Mutex
+	password string
+	group    bool
+	entries  []Message
+	next     int             // First entry not yet delivered
+	pending  map[string]bool // Delivered, not acknowledged
+	keys     map[string]string
+	commands []string
+}
+
+func startFakeRedis(t *testing.T, password string) (*fakeRedis, string) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { lis.Close() })
+
+	f := &fakeRedis{password: password, pending: make(map[string]bool), keys: make(map[string]string)}
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f, lis.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		reply, err := readReply(br)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, a := range reply.([]any) {
+			args = append(args, string(a.([]byte)))
+		}
+
+		f.mu.Lock()
+		f.commands = append(f.commands, args[0])
+		var out string
+		switch {
+		case args[0] == "AUTH":
+			authed = args[len(args)-1] == f.password
+			out = "+OK\r\n"
+			if !authed {
+				out = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			out = "-NOAUTH Authentication required.\r\n"
+		default:
+			out = f.handle(args)
+		}
+		f.mu.Unlock()
+
+		if _, err := conn.Write([]byte(out)); err != nil {
+			return
+		}
+	}
+}
+
+// handle runs a command with mu held and returns the encoded reply.
+func (f *fakeRedis) handle(args []string) string {
+	switch args[0] {
+	case "XGROUP":
+		if f.group {
+			return "-BUSYGROUP Consumer Group name already exists\r\n"
+		}
+		f.group = true
+		return "+OK\r\n"
+	case "XADD":
+		id := fmt.Sprintf("%d-0", len(f.entries)+1)
+		f.entries = append(f.entries, Message{ID: id, Data: []byte(args[len(args)-1])})
+		return bulk(id)
+	case "XREADGROUP":
+		count, _ := strconv.Atoi(args[5])
+		var msgs []Message
+		if args[len(args)-1] == "0" {
+			for _, m := range f.entries {
+				if f.pending[m.ID] && len(msgs) < count {
+					msgs = append(msgs, m)
+				}
+			}
+		} else {
+			for ; f.next < len(f.entries) && len(msgs) < count; f.next++ {
+				m := f.entries[f.next]
+				f.pending[m.ID] = true
+				msgs = append(msgs, m)
+			}
+			if len(msgs) == 0 {
+				return "*-1\r\n"
+			}
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "*1\r\n*2\r\n%s*%d\r\n", bulk("kubelogs"), len(msgs))
+		for _, m := range msgs {
+			fmt.Fprintf(&b, "*2\r\n%s*2\r\n%s%s", bulk(m.ID), bulk(dataField), bulk(string(m.Data)))
+		}
+		return b.String()
+	case "XAUTOCLAIM":
+		// One consumer: everything pending is already its own
+		var b strings.Builder
+		fmt.Fprintf(&b, "*3\r\n%s*%d\r\n", bulk("0-0"), len(f.pending))
+		for id := range f.pending {
+			b.WriteString(bulk(id))
+		}
+		b.WriteString("*0\r\n")
+		return b.String()
+	case "SET":
+		f.keys[args[1]] = args[2]
+		return "+OK\r\n"
+	case "EXISTS":
+		if _, ok := f.keys[args[1]]; ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	case "XACK":
+		delete(f.pending, args[3])
+		return ":1\r\n"
+	}
+	return "-ERR unknown command\r\n"
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func TestStream(t *testing.T) {
+	f, addr := startFakeRedis(t, "secret")
+	s, err := New(Config{Addr: addr, Password: "secret", Consumer: "server-0"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	for _, data := range []string{"a", "b\r\nwith newline", "c"} {
+		if err := s.Publish(ctx, []byte(data)); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+	for range 2 {
+		if err := s.CreateGroup(ctx); err != nil {
+			t.Fatalf("CreateGroup: %v", err)
+		}
+	}
+
+	read := func(pending bool) []string {
+		t.Helper()
+		msgs, err := s.Read(ctx, 2, time.Millisecond, pending)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		var data []string
+		for _, m := range msgs {
+			data = append(data, string(m.Data))
+		}
+		return data
+	}
+
+	if got := read(true); got != nil {
+		t.Errorf("initial pending = %q, want none", got)
+	}
+	if got, want := read(false), []string{"a", "b\r\nwith newline"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("read = %q, want %q", got, want)
+	}
+	if err := s.Ack(ctx, "1-0"); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+	if got, want := read(true), []string{"b\r\nwith newline"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pending = %q, want %q", got, want)
+	}
+	if n, err := s.Claim(ctx, time.Minute); err != nil || n != 1 {
+		t.Errorf("Claim = %d, %v, want 1", n, err)
+	}
+	if got, want := read(false), []string{"c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("read = %q, want %q", got, want)
+	}
+	if got := read(false); got != nil {
+		t.Errorf("read after end = %q, want none", got)
+	}
+
+	if done, err := s.Done(ctx, "b1"); err != nil || done {
+		t.Errorf("Done before MarkDone = %v, %v", done, err)
+	}
+	if err := s.MarkDone(ctx, "b1"); err != nil {
+		t.Fatalf("MarkDone: %v", err)
+	}
+	if done, err := s.Done(ctx, "b1"); err != nil || !done {
+		t.Errorf("Done after MarkDone = %v, %v", done, err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.keys["kubelogs:done:b1"] == "" {
+		t.Errorf("keys = %v, want kubelogs:done:b1", f.keys)
+	}
+	if f.commands[0] != "AUTH" {
+		t.Errorf("first command = %s, want AUTH", f.commands[0])
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	_, addr := startFakeRedis(t, "secret")
+	ctx := context.Background()
+
+	s, _ := New(Config{Addr: addr, Password: "wrong"})
+	err := s.Publish(ctx, []byte("a"))
+	var rerr Error
+	if !errors.As(err, &rerr) || !strings.HasPrefix(string(rerr), "WRONGPASS") {
+		t.Errorf("Publish with wrong password = %v, want WRONGPASS", err)
+	}
+
+	s, _ = New(Config{Addr: addr, Password: "secret"})
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := s.Publish(canceled, []byte("a")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Publish with canceled context = %v, want context.Canceled", err)
+	}
+	// The stream reconnects after the aborted command
+	if err := s.Publish(ctx, []byte("a")); err != nil {
+		t.Errorf("Publish after cancel: %v", err)
+	}
+
+	if _, err := New(Config{}); err == nil {
+		t.Error("New without address should fail")
+	}
+}
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		in   string
+		want any
+	}{
+		{"+OK\r\n", "OK"},
+		{":42\r\n", int64(42)},
+		{"$3\r\nfoo\r\n", []byte("foo")},
+		{"$-1\r\n", nil},
+		{"*-1\r\n", nil},
+		{"*2\r\n$1\r\na\r\n:1\r\n", []any{[]byte("a"), int64(1)}},
+	}
+	for _, tt := range tests {
+		t.Run(strings.TrimSpace(tt.in), func(t *testing.T) {
+			got, err := readReply(bufio.NewReader(strings.NewReader(tt.in)))
+			if err != nil {
+				t.Fatalf("readReply: %v", err)
+			}
+			if tt.want == nil {
+				if got != nil && !reflect.ValueOf(got).IsNil() {
+					t.Errorf("got %#v, want nil", got)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
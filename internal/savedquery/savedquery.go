@@ -0,0 +1,194 @@
+// Package savedquery stores per-user saved searches: named filter
+// combinations that can be recalled from the UI.
+package savedquery
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+var (
+	ErrNotFound     = errors.New("saved query: not found")
+	ErrNameTaken    = errors.New("saved query: name already in use")
+	ErrInvalidQuery = errors.New("saved query: invalid query")
+)
+
+const (
+	// MaxNameLength bounds the size of a saved query name in bytes.
+	MaxNameLength = 200
+
+	// MaxQueryLength bounds the size of a saved query string in bytes.
+	MaxQueryLength = 8192
+)
+
+// Query is a named UI/API query string (e.g.
+// "namespace=prod&minSeverity=4&search=timeout") saved by a user.
+type Query struct {
+	ID        int64
+	UserID    int64
+	Name      string
+	Query     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Validate checks the name and query string of a saved query.
+func Validate(name, query string) error {
+	if name == "" || len(name) > MaxNameLength {
+		return fmt.Errorf("%w: name must be 1-%d bytes", ErrInvalidQuery, MaxNameLength)
+	}
+	if len(query) > MaxQueryLength {
+		return fmt.Errorf("%w: query longer than %d bytes", ErrInvalidQuery, MaxQueryLength)
+	}
+	if _, err := url.ParseQuery(query); err != nil {
+		return fmt.Errorf("%w: malformed query: %v", ErrInvalidQuery, err)
+	}
+	return nil
+}
+
+// Store manages saved query persistence. Every method is scoped to a
+// user, so one user's queries are invisible to the others.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a Store with the given database connection.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Create saves a query for a user. Names are unique per user.
+func (s *Store) Create(ctx context.Context, userID int64, name, query string) (*Query, error) {
+	if err := Validate(name, query); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO saved_queries (user_id, name, query, created_at, updated_at) VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT (user_id, name) DO NOTHING`,
+		userID, name, query, now.UnixNano(), now.UnixNano(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrNameTaken
+	}
+
+	id, _ := result.LastInsertId()
+	return &Query{
+		ID:        id,
+		UserID:    userID,
+		Name:      name,
+		Query:     query,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// Update replaces the name and query string of a user's saved query.
+func (s *Store) Update(ctx context.Context, userID, id int64, name, query string) error {
+	if err := Validate(name, query); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var other int64
+	err = tx.QueryRowContext(ctx,
+		`SELECT id FROM saved_queries WHERE user_id = ? AND name = ? AND id != ?`,
+		userID, name, id,
+	).Scan(&other)
+	if err == nil {
+		return ErrNameTaken
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE saved_queries SET name = ?, query = ?, updated_at = ? WHERE id = ? AND user_id = ?`,
+		name, query, time.Now().UnixNano(), id, userID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes a user's saved query.
+func (s *Store) Delete(ctx context.Context, userID, id int64) error {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM saved_queries WHERE id = ? AND user_id = ?`,
+		id, userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Get retrieves one of a user's saved queries.
+func (s *Store) Get(ctx context.Context, userID, id int64) (*Query, error) {
+	q := Query{ID: id, UserID: userID}
+	var createdAt, updatedAt int64
+
+	err := s.db.QueryRowContext(ctx,
+		`SELECT name, query, created_at, updated_at FROM saved_queries WHERE id = ? AND user_id = ?`,
+		id, userID,
+	).Scan(&q.Name, &q.Query, &createdAt, &updatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	q.CreatedAt = time.Unix(0, createdAt)
+	q.UpdatedAt = time.Unix(0, updatedAt)
+	return &q, nil
+}
+
+// List returns a user's saved queries ordered by name.
+func (s *Store) List(ctx context.Context, userID int64) ([]Query, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, name, query, created_at, updated_at FROM saved_queries WHERE user_id = ? ORDER BY name, id`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var queries []Query
+	for rows.Next() {
+		q := Query{UserID: userID}
+		var createdAt, updatedAt int64
+		if err := rows.Scan(&q.ID, &q.Name, &q.Query, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		q.CreatedAt = time.Unix(0, createdAt)
+		q.UpdatedAt = time.Unix(0, updatedAt)
+		queries = append(queries, q)
+	}
+	return queries, rows.Err()
+}
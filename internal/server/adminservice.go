@@ -0,0 +1,171 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kubelogs/kubelogs/api/storagepb"
+	"github.com/kubelogs/kubelogs/internal/audit"
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// grpcAdmin is who AdminService calls are recorded as: the holder of the
+// admin token.
+const grpcAdmin = "grpc-admin"
+
+// AdminServer implements the AdminService gRPC server, the destructive
+// operations kept out of StorageService so collectors can't call them.
+// Like the web UI's, its deletes need the token of a preview.
+type AdminServer struct {
+	storagepb.UnimplementedAdminServiceServer
+	store    storage.Store
+	confirms *Confirmations
+}
+
+// NewAdminServer creates an admin server wrapping the given store, whose
+// deletes confirms confirms.
+func NewAdminServer(store storage.Store, confirms *Confirmations) *AdminServer {
+	return &AdminServer{store: store, confirms: confirms}
+}
+
+// PreviewDelete counts the entries older than the given timestamp and
+// issues the token Delete needs.
+func (s *AdminServer) PreviewDelete(ctx context.Context, req *storagepb.DeleteRequest) (*storagepb.PreviewDeleteResponse, error) {
+	olderThan := time.Unix(0, req.OlderThanNanos)
+	return s.preview(ctx, deleteOp(olderThan), storage.Query{EndTime: olderThan})
+}
+
+// Delete removes entries older than the given timestamp.
+func (s *AdminServer) Delete(ctx context.Context, req *storagepb.DeleteRequest) (*storagepb.DeleteResponse, error) {
+	olderThan := time.Unix(0, req.OlderThanNanos)
+	preview, err := s.redeem(req.Confirm, deleteOp(olderThan))
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.store.Delete(ctx, olderThan)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "delete failed: %v", err)
+	}
+
+	filter := deleteFilter(olderThan)
+	slog.Info("deleted entries", "user", grpcAdmin, "requested_by", preview.requester, "filter", filter, "deleted", count)
+	s.confirms.record(ctx, audit.Record{
+		Operation: audit.OpDelete,
+		Filter:    filter,
+		Requester: preview.requester,
+		Approver:  grpcAdmin,
+		Count:     count,
+	})
+	return &storagepb.DeleteResponse{DeletedCount: count}, nil
+}
+
+// PreviewDeleteByQuery counts the entries matching a query's filters and
+// issues the token DeleteByQuery needs.
+func (s *AdminServer) PreviewDeleteByQuery(ctx context.Context, req *storagepb.DeleteByQueryRequest) (*storagepb.PreviewDeleteResponse, error) {
+	q, err := s.purgeQuery(req)
+	if err != nil {
+		return nil, err
+	}
+	return s.preview(ctx, purgeOp(q), q)
+}
+
+// DeleteByQuery removes entries matching a query's filters.
+func (s *AdminServer) DeleteByQuery(ctx context.Context, req *storagepb.DeleteByQueryRequest) (*storagepb.DeleteResponse, error) {
+	q, err := s.purgeQuery(req)
+	if err != nil {
+		return nil, err
+	}
+	preview, err := s.redeem(req.Confirm, purgeOp(q))
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.store.(storage.QueryDeleter).DeleteByQuery(ctx, q)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "delete failed: %v", err)
+	}
+
+	filter := queryFilter(q)
+	slog.Info("deleted entries by query", "user", grpcAdmin, "requested_by", preview.requester, "filter", filter, "deleted", count)
+	s.confirms.record(ctx, audit.Record{
+		Operation: audit.OpPurge,
+		Filter:    filter,
+		Requester: preview.requester,
+		Approver:  grpcAdmin,
+		Count:     count,
+	})
+	return &storagepb.DeleteResponse{DeletedCount: count}, nil
+}
+
+// purgeQuery returns the filters of a delete by query, failing if the
+// store can't delete by query or there are none.
+func (s *AdminServer) purgeQuery(req *storagepb.DeleteByQueryRequest) (storage.Query, error) {
+	if _, ok := s.store.(storage.QueryDeleter); !ok {
+		return storage.Query{}, status.Error(codes.Unimplemented, "store does not support deleting by query")
+	}
+	if req.Query == nil {
+		return storage.Query{}, status.Error(codes.InvalidArgument, "query has no filter")
+	}
+	q, err := fromProtoQuery(req.Query)
+	if err != nil {
+		return q, err
+	}
+	if !q.HasFilter() {
+		return q, status.Error(codes.InvalidArgument, "query has no filter")
+	}
+	return q, nil
+}
+
+// preview counts the entries matching q and issues a token for op.
+func (s *AdminServer) preview(ctx context.Context, op string, q storage.Query) (*storagepb.PreviewDeleteResponse, error) {
+	matched, err := countMatches(ctx, s.store, q)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "count failed: %v", err)
+	}
+	token, expires, err := s.confirms.issue(op, grpcAdmin, matched, time.Now())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "issue token: %v", err)
+	}
+	slog.Info("previewed delete", "user", grpcAdmin, "operation", op, "matched", matched)
+	return &storagepb.PreviewDeleteResponse{
+		Matched:        matched,
+		Token:          token,
+		ExpiresAtNanos: expires.UnixNano(),
+		SecondApprover: s.confirms.secondApprover,
+	}, nil
+}
+
+// redeem uses up the confirmation token of op.
+func (s *AdminServer) redeem(token, op string) (pendingConfirmation, error) {
+	if token == "" {
+		return pendingConfirmation{}, status.Error(codes.FailedPrecondition, "a confirm token from a preview of the same delete is required")
+	}
+	preview, err := s.confirms.redeem(token, op, grpcAdmin, time.Now())
+	switch {
+	case errors.Is(err, errConfirmSelf):
+		return preview, status.Error(codes.PermissionDenied, err.Error())
+	case err != nil:
+		return preview, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return preview, nil
+}
+
+// deleteOp returns the operation key confirmation tokens of a delete of
+// the entries before olderThan are bound to.
+func deleteOp(olderThan time.Time) string {
+	return "delete " + deleteFilter(olderThan)
+}
+
+// deleteFilter describes a delete of the entries before olderThan as a
+// JSON object, for logs and audit records.
+func deleteFilter(olderThan time.Time) string {
+	b, _ := json.Marshal(map[string]string{"before": olderThan.UTC().Format(time.RFC3339Nano)})
+	return string(b)
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kubelogs/kubelogs/api/storagepb"
+	"github.com/kubelogs/kubelogs/internal/audit"
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/remote"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestAdminServer_Confirm(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	store.Write(ctx, storage.LogBatch{
+		{Timestamp: now.Add(-time.Hour), Namespace: "a", Pod: "pod", Container: "c", Message: "old"},
+		{Timestamp: now, Namespace: "a", Pod: "pod", Container: "c", Message: "leak"},
+		{Timestamp: now, Namespace: "b", Pod: "pod", Container: "c", Message: "ok"},
+	})
+	store.Flush(ctx)
+
+	s := NewAdminServer(store, NewConfirmations(false, audit.NewStore(store.DB())))
+	byQuery := &storagepb.DeleteByQueryRequest{Query: &storagepb.QueryRequest{Search: "leak"}}
+	if _, err := s.DeleteByQuery(ctx, byQuery); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("DeleteByQuery without token: %v, want FailedPrecondition", err)
+	}
+	if _, err := s.PreviewDeleteByQuery(ctx, &storagepb.DeleteByQueryRequest{Query: &storagepb.QueryRequest{}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("PreviewDeleteByQuery without filter: %v, want InvalidArgument", err)
+	}
+
+	preview, err := s.PreviewDeleteByQuery(ctx, byQuery)
+	if err != nil || preview.Matched != 1 {
+		t.Fatalf("PreviewDeleteByQuery = %v, %v, want 1 match", preview, err)
+	}
+	other := &storagepb.DeleteByQueryRequest{Query: &storagepb.QueryRequest{Namespace: "b"}, Confirm: preview.Token}
+	if _, err := s.DeleteByQuery(ctx, other); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("DeleteByQuery with another query's token: %v, want FailedPrecondition", err)
+	}
+	byQuery.Confirm = preview.Token
+	resp, err := s.DeleteByQuery(ctx, byQuery)
+	if err != nil || resp.DeletedCount != 1 {
+		t.Fatalf("DeleteByQuery = %v, %v, want 1 deleted", resp, err)
+	}
+
+	before := &storagepb.DeleteRequest{OlderThanNanos: now.Add(-time.Minute).UnixNano()}
+	if preview, err = s.PreviewDelete(ctx, before); err != nil || preview.Matched != 1 {
+		t.Fatalf("PreviewDelete = %v, %v, want 1 match", preview, err)
+	}
+	before.Confirm = preview.Token
+	if resp, err = s.Delete(ctx, before); err != nil || resp.DeletedCount != 1 {
+		t.Fatalf("Delete = %v, %v, want 1 deleted", resp, err)
+	}
+
+	records, err := audit.NewStore(store.DB()).List(ctx, 0)
+	if err != nil || len(records) != 2 {
+		t.Fatalf("audit records = %v, %v, want 2", records, err)
+	}
+	if r := records[0]; r.Operation != audit.OpDelete || r.Requester != grpcAdmin || r.Count != 1 ||
+		r.Filter != deleteFilter(time.Unix(0, before.OlderThanNanos)) {
+		t.Errorf("delete record = %+v", r)
+	}
+	if r := records[1]; r.Operation != audit.OpPurge || r.Filter != `{"search":"leak"}` || r.Count != 1 {
+		t.Errorf("purge record = %+v", r)
+	}
+}
+
+func TestRemoteClient_ConfirmedDelete(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:", WriteBufferSize: 1})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	storagepb.RegisterStorageServiceServer(grpcServer, New(store))
+	storagepb.RegisterAdminServiceServer(grpcServer, NewAdminServer(store, NewConfirmations(false, audit.NewStore(store.DB()))))
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	client, err := remote.NewClient(lis.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	client.Write(ctx, storage.LogBatch{
+		{Timestamp: now.Add(-time.Hour), Namespace: "a", Pod: "pod", Container: "c", Message: "old"},
+		{Timestamp: now, Namespace: "a", Pod: "pod", Container: "c", Message: "new"},
+	})
+
+	// Deletes need a confirmed preview, so the plain Store and
+	// QueryDeleter methods aren't offered
+	if _, err := client.Delete(ctx, now); !errors.Is(err, storage.ErrConfirmationRequired) {
+		t.Errorf("Delete error = %v, want ErrConfirmationRequired", err)
+	}
+	if _, ok := storage.Store(client).(storage.QueryDeleter); ok {
+		t.Error("remote client implements storage.QueryDeleter")
+	}
+
+	before := now.Add(-time.Minute)
+	preview, err := client.PreviewDelete(ctx, before)
+	if err != nil || preview.Matched != 1 {
+		t.Fatalf("PreviewDelete = %+v, %v, want 1 match", preview, err)
+	}
+	if deleted, err := client.ConfirmDelete(ctx, before, preview.Token); err != nil || deleted != 1 {
+		t.Errorf("ConfirmDelete = %d, %v, want 1 deleted", deleted, err)
+	}
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/kubelogs/kubelogs/api/storagepb"
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/remote"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestServer_AggregateGroups(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	storagepb.RegisterStorageServiceServer(grpcServer, New(store))
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	client, err := remote.NewClient(lis.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	if _, err := store.Write(ctx, storage.LogBatch{
+		{Timestamp: base, Namespace: "shop", Severity: storage.SeverityInfo, Message: "a", Attributes: map[string]string{"latency_ms": "100"}},
+		{Timestamp: base.Add(time.Second), Namespace: "shop", Severity: storage.SeverityError, Message: "b", Attributes: map[string]string{"latency_ms": "300"}},
+		{Timestamp: base.Add(time.Minute), Namespace: "infra", Severity: storage.SeverityInfo, Message: "c"},
+	}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	store.Flush(ctx)
+
+	groups, err := client.Aggregate(ctx, storage.Query{}, storage.Aggregation{GroupBy: []string{"namespace"}, Value: "latency_ms"})
+	if err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+	if len(groups) != 2 || groups[0].Keys[0] != "infra" || groups[0].Count != 1 || groups[0].ValueCount != 0 ||
+		groups[1].Keys[0] != "shop" || groups[1].Count != 2 || groups[1].Avg() != 200 || groups[1].Max != 300 {
+		t.Errorf("groups = %+v", groups)
+	}
+
+	// Without fields or a value, one group per bucket
+	groups, err = client.Aggregate(ctx, storage.Query{}, storage.Aggregation{Interval: time.Minute})
+	if err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+	if len(groups) != 2 || !groups[0].Start.Equal(base) || groups[0].Count != 2 || len(groups[0].Keys) != 0 || groups[1].Count != 1 {
+		t.Errorf("bucket groups = %+v", groups)
+	}
+
+	groups, err = client.Aggregate(ctx, storage.Query{}, storage.Aggregation{})
+	if err != nil || len(groups) != 1 || groups[0].Count != 3 {
+		t.Errorf("total = %+v, %v", groups, err)
+	}
+
+	// Requests without group_by or value_attribute still get a histogram
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+	pb := storagepb.NewStorageServiceClient(conn)
+	if _, err := pb.Aggregate(ctx, &storagepb.AggregateRequest{GroupBy: []string{"message"}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("group by message: code = %v, want InvalidArgument", status.Code(err))
+	}
+	hist, err := pb.Aggregate(ctx, &storagepb.AggregateRequest{IntervalNanos: int64(time.Hour)})
+	if err != nil || len(hist.Buckets) != 2 || len(hist.Groups) != 0 {
+		t.Errorf("histogram = %v, %v", hist, err)
+	}
+}
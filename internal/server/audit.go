@@ -0,0 +1,61 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/audit"
+)
+
+// maxAuditLimit bounds the records one request lists.
+const maxAuditLimit = 1000
+
+// auditRecordJSON is the JSON representation of an audit record.
+type auditRecordJSON struct {
+	ID        int64     `json:"id"`
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	Filter    string    `json:"filter"`
+	Requester string    `json:"requester"`
+	Approver  string    `json:"approver"`
+	Count     int64     `json:"count"`
+}
+
+// handleListAudit lists the destructive operations confirmed, newest
+// first, up to the limit parameter (default 100).
+func (s *HTTPServer) handleListAudit(w http.ResponseWriter, r *http.Request) {
+	limit := audit.DefaultLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAuditLimit {
+			http.Error(w, fmt.Sprintf("invalid limit %q: must be between 1 and %d", v, maxAuditLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	resp := make([]auditRecordJSON, 0)
+	if s.confirms.audit != nil {
+		records, err := s.confirms.audit.List(r.Context(), limit)
+		if err != nil {
+			slog.Error("list audit records error", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		for _, rec := range records {
+			resp = append(resp, auditRecordJSON{
+				ID:        rec.ID,
+				Time:      rec.Time,
+				Operation: rec.Operation,
+				Filter:    rec.Filter,
+				Requester: rec.Requester,
+				Approver:  rec.Approver,
+				Count:     rec.Count,
+			})
+		}
+	}
+	writeJSON(w, resp)
+}
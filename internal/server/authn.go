@@ -0,0 +1,131 @@
+package server
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/auth"
+)
+
+// oidcTimeout bounds each request to the OpenID Connect provider.
+const oidcTimeout = 10 * time.Second
+
+// setupAuth creates the authenticators named in cfg.AuthMethods and the
+// middleware trying them in order. The "local" and "oidc" methods also
+// set up the session store their users are then identified by.
+func (s *HTTPServer) setupAuth(db *sql.DB, cfg Config) error {
+	if len(cfg.AuthMethods) == 0 {
+		return fmt.Errorf("no auth methods configured")
+	}
+
+	var chain auth.Chain
+	loginPath := ""
+	seen := make(map[string]bool)
+	s.userStore = auth.NewUserStore(db)
+	// Local and OIDC users share the session cookie, so one session
+	// authenticator serves both
+	sessions := func() {
+		if s.sessionStore == nil {
+			s.sessionStore = auth.NewSessionStore(db, cfg.SessionDuration)
+			chain = append(chain, auth.NewSessionAuthenticator(s.userStore, s.sessionStore, cfg.SessionCookieName))
+		}
+	}
+	for _, name := range cfg.AuthMethods {
+		if seen[name] {
+			return fmt.Errorf("auth method %q listed twice", name)
+		}
+		seen[name] = true
+
+		switch name {
+		case "local":
+			sessions()
+			s.localLogin = true
+			loginPath = "/login"
+		case "oidc":
+			if cfg.OIDCIssuer == "" || cfg.OIDCClientID == "" || cfg.OIDCRedirectURL == "" {
+				return fmt.Errorf("auth method %q needs KUBELOGS_OIDC_ISSUER, KUBELOGS_OIDC_CLIENT_ID and KUBELOGS_OIDC_REDIRECT_URL", name)
+			}
+			if !slices.Contains(cfg.OIDCScopes, "openid") {
+				return fmt.Errorf("auth method %q needs the openid scope", name)
+			}
+			sessions()
+			s.oidc = auth.NewOIDCLogin(auth.OIDCConfig{
+				Issuer:        cfg.OIDCIssuer,
+				ClientID:      cfg.OIDCClientID,
+				ClientSecret:  cfg.OIDCClientSecret,
+				RedirectURL:   cfg.OIDCRedirectURL,
+				Scopes:        cfg.OIDCScopes,
+				UsernameClaim: cfg.OIDCUsernameClaim,
+			}, s.userStore, &http.Client{Timeout: oidcTimeout}, cfg.SessionCookieName+"_oidc", cfg.SessionCookieSecure)
+			if loginPath == "" {
+				loginPath = "/auth/oidc/login"
+			}
+		case "proxy":
+			if len(cfg.AuthProxyCIDRs) == 0 {
+				return fmt.Errorf("auth method %q needs the proxy's addresses (KUBELOGS_AUTH_PROXY_CIDRS)", name)
+			}
+			if len(cfg.AuthProxyHeaders) == 0 {
+				return fmt.Errorf("auth method %q needs a user header", name)
+			}
+			if cfg.ProxyProtocol && len(cfg.TrustedProxies) == 0 {
+				return fmt.Errorf("auth method %q can't be used with PROXY protocol headers from any peer (KUBELOGS_TRUSTED_PROXIES)", name)
+			}
+			// The socket peer is checked: the addresses in PROXY and
+			// X-Forwarded-For headers are the client's claims
+			trusted := cfg.AuthProxyCIDRs
+			chain = append(chain, auth.NewHeaderAuthenticator(s.userStore, cfg.AuthProxyHeaders, func(r *http.Request) bool {
+				peer, ok := addrIP(socketAddr(r))
+				return ok && isTrusted(trusted, peer)
+			}))
+		default:
+			return fmt.Errorf("unknown auth method %q", name)
+		}
+	}
+
+	s.authMiddleware = auth.NewMiddleware(chain, loginPath, cfg.SessionCookieName, cfg.SessionCookieSecure)
+	return nil
+}
+
+// sessionAuth reports whether users log in on this server and carry a
+// session, so they can log out.
+func (s *HTTPServer) sessionAuth() bool {
+	return s.sessionStore != nil
+}
+
+// handleOIDCLogin sends the browser to the OpenID Connect provider to
+// log in.
+func (s *HTTPServer) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if err := s.oidc.Start(w, r); err != nil {
+		slog.Error("oidc login error", "error", err)
+		http.Error(w, "Login provider unavailable", http.StatusBadGateway)
+	}
+}
+
+// handleOIDCCallback completes a login when the provider sends the
+// browser back, and starts a session.
+func (s *HTTPServer) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	user, err := s.oidc.Finish(w, r)
+	if err != nil {
+		slog.Warn("oidc login failed", "error", err, "remote", r.RemoteAddr)
+		if s.localLogin {
+			http.Redirect(w, r, "/login?error=oidc", http.StatusSeeOther)
+			return
+		}
+		http.Error(w, "Login failed", http.StatusUnauthorized)
+		return
+	}
+
+	session, err := s.sessionStore.Create(r.Context(), user.ID)
+	if err != nil {
+		slog.Error("session create error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	slog.Info("oidc login", "username", user.Username)
+	s.authMiddleware.SetSessionCookie(w, session.ID, int(s.sessionDuration.Seconds()))
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
@@ -0,0 +1,384 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestHTTPServer_LocalAuth(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	cfg := DefaultConfig()
+	cfg.AuthEnabled = true
+	s, err := NewHTTPServer(store, store.DB(), cfg)
+	if err != nil {
+		t.Fatalf("NewHTTPServer: %v", err)
+	}
+	h := s.Routes()
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/api/logs", nil)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("API without session = %d, want 401", rec.Code)
+	}
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/", nil)); rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/login" {
+		t.Errorf("page without session = %d to %q, want a redirect to /login", rec.Code, rec.Header().Get("Location"))
+	}
+
+	form := url.Values{"username": {"alice"}, "password": {"password1"}, "confirm_password": {"password1"}}
+	req := httptest.NewRequest(http.MethodPost, "/setup", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := serve(req)
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != cfg.SessionCookieName {
+		t.Fatalf("setup cookies = %v, want the session cookie", cookies)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/logs", nil)
+	req.AddCookie(cookies[0])
+	if rec := serve(req); rec.Code != http.StatusOK {
+		t.Errorf("API with session = %d, want 200", rec.Code)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/login", nil)
+	req.AddCookie(cookies[0])
+	if rec := serve(req); rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/" {
+		t.Errorf("login page with session = %d to %q, want a redirect to /", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestHTTPServer_AuthMethods(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	for _, methods := range [][]string{nil, {"kerberos"}, {"local", "local"}, {"proxy"}, {"oidc"}} {
+		cfg := DefaultConfig()
+		cfg.AuthEnabled = true
+		cfg.AuthMethods = methods
+		if _, err := NewHTTPServer(store, store.DB(), cfg); err == nil {
+			t.Errorf("methods %q: want an error", methods)
+		}
+	}
+}
+
+func TestHTTPServer_ProxyAuth(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	cfg := DefaultConfig()
+	cfg.AuthEnabled = true
+	cfg.AuthMethods = []string{"proxy"}
+	cfg.AuthProxyCIDRs = parsePrefixes("10.0.0.5")
+	cfg.TrustedProxies = parsePrefixes("10.0.0.0/8")
+	s, err := NewHTTPServer(store, store.DB(), cfg)
+	if err != nil {
+		t.Fatalf("NewHTTPServer: %v", err)
+	}
+	h := s.Routes()
+	get := func(path, remote, user string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remote
+		req.Header.Set("X-Forwarded-For", "192.0.2.7")
+		if user != "" {
+			req.Header.Set("X-Forwarded-User", user)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := get("/api/logs", "10.0.0.5:4180", "alice"); code != http.StatusOK {
+		t.Errorf("API from the auth proxy = %d, want 200", code)
+	}
+	if code := get("/api/logs", "10.0.0.6:4180", "alice"); code != http.StatusUnauthorized {
+		t.Errorf("API with a user header from another proxy = %d, want 401", code)
+	}
+	if code := get("/api/logs", "10.0.0.5:4180", ""); code != http.StatusUnauthorized {
+		t.Errorf("API from the auth proxy without a user = %d, want 401", code)
+	}
+	if code := get("/", "10.0.0.5:4180", ""); code != http.StatusUnauthorized {
+		t.Errorf("page without a user = %d, want 401 without a login page", code)
+	}
+	if code := get("/login", "10.0.0.5:4180", "alice"); code != http.StatusNotFound {
+		t.Errorf("login page = %d, want 404", code)
+	}
+
+	// The user was created on first sight, and is found again
+	ctx := context.Background()
+	if has, _ := s.userStore.HasUsers(ctx); !has {
+		t.Fatal("no users after proxy login")
+	}
+	first, err := s.userStore.EnsureUser(ctx, "alice")
+	if err != nil || first.ID != 1 {
+		t.Fatalf("EnsureUser = %+v, %v, want the user with ID 1", first, err)
+	}
+	if _, err := s.userStore.Authenticate(ctx, "alice", ""); err == nil {
+		t.Error("proxy user logged in with an empty password")
+	}
+}
+
+func TestHTTPServer_ProxyAuthSpoofedPROXYHeader(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	cfg := DefaultConfig()
+	cfg.AuthEnabled = true
+	cfg.AuthMethods = []string{"proxy"}
+	cfg.AuthProxyCIDRs = parsePrefixes("10.0.0.5")
+	cfg.ProxyProtocol = true
+	if _, err := NewHTTPServer(store, store.DB(), cfg); err == nil {
+		t.Error("proxy auth with PROXY headers from any peer: want an error")
+	}
+
+	// A client allowed to send PROXY headers claims the auth proxy's
+	// address, then a user
+	get := func(authProxies string) int {
+		t.Helper()
+		cfg.TrustedProxies = parsePrefixes("127.0.0.0/8")
+		cfg.AuthProxyCIDRs = parsePrefixes(authProxies)
+		s, err := NewHTTPServer(store, store.DB(), cfg)
+		if err != nil {
+			t.Fatalf("NewHTTPServer: %v", err)
+		}
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Listen failed: %v", err)
+		}
+		plis, err := NewProxyListener(lis, cfg.TrustedProxies)
+		if err != nil {
+			t.Fatalf("NewProxyListener failed: %v", err)
+		}
+		go s.Serve(plis)
+		defer s.Shutdown(context.Background())
+
+		conn, err := net.Dial("tcp", lis.Addr().String())
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		defer conn.Close()
+		fmt.Fprint(conn, "PROXY TCP4 10.0.0.5 10.0.0.1 56324 80\r\nGET /api/logs HTTP/1.1\r\nHost: x\r\nX-Forwarded-User: admin\r\n\r\n")
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Fatalf("ReadResponse failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := get("10.0.0.5"); code != http.StatusUnauthorized {
+		t.Errorf("user header with a spoofed PROXY address = %d, want 401", code)
+	}
+	if code := get("127.0.0.1"); code != http.StatusOK {
+		t.Errorf("user header from the auth proxy's socket = %d, want 200", code)
+	}
+}
+
+// fakeOIDCProvider is an OpenID Connect provider issuing ID tokens with
+// the claims set in claims for the code "good".
+type fakeOIDCProvider struct {
+	*httptest.Server
+	key       *rsa.PrivateKey
+	signer    *rsa.PrivateKey // Signs tokens; key unless testing a forgery
+	claims    map[string]any
+	challenge string // PKCE challenge of the last authorization
+	nonce     string
+}
+
+func newFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	p := &fakeOIDCProvider{key: key, signer: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+			"jwks_uri":               p.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		b64 := base64.RawURLEncoding.EncodeToString
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "use": "sig",
+			"n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if id != "kubelogs" || secret != "s3cret" || r.FormValue("code") != "good" ||
+			base64.RawURLEncoding.EncodeToString(verifier[:]) != p.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		claims := map[string]any{"iss": p.URL, "aud": "kubelogs", "exp": time.Now().Add(time.Hour).Unix(), "nonce": p.nonce}
+		for k, v := range p.claims {
+			claims[k] = v
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": p.sign(t, claims)})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// sign returns an RS256 ID token of claims.
+func (p *fakeOIDCProvider) sign(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	enc := func(v any) string {
+		b, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signed := enc(map[string]string{"alg": "RS256", "kid": "k1"}) + "." + enc(claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.signer, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("SignPKCS1v15: %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestHTTPServer_OIDCAuth(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	provider := newFakeOIDCProvider(t)
+	cfg := DefaultConfig()
+	cfg.AuthEnabled = true
+	cfg.AuthMethods = []string{"oidc"}
+	cfg.OIDCIssuer = provider.URL
+	cfg.OIDCClientID = "kubelogs"
+	cfg.OIDCClientSecret = "s3cret"
+	cfg.OIDCRedirectURL = "https://logs.example.com/auth/oidc/callback"
+	s, err := NewHTTPServer(store, store.DB(), cfg)
+	if err != nil {
+		t.Fatalf("NewHTTPServer: %v", err)
+	}
+	h := s.Routes()
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/", nil)); rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/auth/oidc/login" {
+		t.Errorf("page without session = %d to %q, want a redirect to /auth/oidc/login", rec.Code, rec.Header().Get("Location"))
+	}
+
+	// login starts a flow at the provider, and the callback completes it
+	// with the state the provider hands back, returning its response
+	login := func(t *testing.T, state func(string) string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := serve(httptest.NewRequest(http.MethodGet, "/auth/oidc/login", nil))
+		authURL, err := url.Parse(rec.Header().Get("Location"))
+		if rec.Code != http.StatusSeeOther || err != nil || !strings.HasPrefix(authURL.String(), provider.URL+"/authorize") {
+			t.Fatalf("login = %d to %q, want a redirect to the provider", rec.Code, authURL)
+		}
+		q := authURL.Query()
+		if q.Get("client_id") != "kubelogs" || q.Get("redirect_uri") != cfg.OIDCRedirectURL || q.Get("code_challenge_method") != "S256" {
+			t.Errorf("authorization request = %v", q)
+		}
+		provider.challenge, provider.nonce = q.Get("code_challenge"), q.Get("nonce")
+
+		callback := "/auth/oidc/callback?code=good&state=" + url.QueryEscape(state(q.Get("state")))
+		req := httptest.NewRequest(http.MethodGet, callback, nil)
+		for _, c := range rec.Result().Cookies() {
+			req.AddCookie(c)
+		}
+		return serve(req)
+	}
+	same := func(state string) string { return state }
+
+	provider.claims = map[string]any{"email": "alice@example.com", "email_verified": true}
+	rec := login(t, same)
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/" {
+		t.Fatalf("callback = %d to %q: %s", rec.Code, rec.Header().Get("Location"), rec.Body)
+	}
+	var session *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == cfg.SessionCookieName && c.Value != "" {
+			session = c
+		}
+	}
+	if session == nil {
+		t.Fatalf("callback cookies = %v, want a session", rec.Result().Cookies())
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/logs", nil)
+	req.AddCookie(session)
+	if rec := serve(req); rec.Code != http.StatusOK {
+		t.Errorf("API with session = %d, want 200", rec.Code)
+	}
+	if _, err := s.userStore.Authenticate(context.Background(), "alice@example.com", ""); err == nil {
+		t.Error("OIDC user logged in with an empty password")
+	}
+
+	t.Run("state mismatch", func(t *testing.T) {
+		if rec := login(t, func(string) string { return "forged" }); rec.Code != http.StatusUnauthorized {
+			t.Errorf("callback = %d, want 401", rec.Code)
+		}
+	})
+	t.Run("unverified email", func(t *testing.T) {
+		provider.claims = map[string]any{"email": "bob@example.com", "email_verified": false}
+		if rec := login(t, same); rec.Code != http.StatusUnauthorized {
+			t.Errorf("callback = %d, want 401", rec.Code)
+		}
+	})
+	t.Run("email not asserted verified", func(t *testing.T) {
+		provider.claims = map[string]any{"email": "root@example.com"}
+		if rec := login(t, same); rec.Code != http.StatusUnauthorized {
+			t.Errorf("callback = %d, want 401", rec.Code)
+		}
+	})
+	t.Run("forged signature", func(t *testing.T) {
+		forger, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("GenerateKey: %v", err)
+		}
+		provider.signer = forger
+		defer func() { provider.signer = provider.key }()
+		provider.claims = map[string]any{"email": "mallory@example.com"}
+		if rec := login(t, same); rec.Code != http.StatusUnauthorized {
+			t.Errorf("callback = %d, want 401", rec.Code)
+		}
+	})
+}
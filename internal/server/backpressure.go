@@ -0,0 +1,96 @@
+package server
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// diskRetryAfter is how long writers rejected for lack of disk space
+	// are asked to wait.
+	diskRetryAfter = 30 * time.Second
+
+	// diskCheckInterval bounds how often free space is read.
+	diskCheckInterval = 10 * time.Second
+
+	// maxWriteDelay caps the delay asked of writers when writes are slow.
+	maxWriteDelay = 30 * time.Second
+)
+
+// backpressure decides when writers are asked to slow down: while
+// writes to the store are slow, replies carry a delay, and while the
+// disk is nearly full, writes are rejected with RESOURCE_EXHAUSTED.
+type backpressure struct {
+	latency time.Duration // Average write latency above which writers are slowed
+	minFree uint64        // Free bytes below which writes are rejected
+	path    string        // Filesystem checked for minFree
+
+	mu        sync.Mutex
+	avg       time.Duration // Moving average of write latency
+	free      uint64
+	checkedAt time.Time
+}
+
+// observe records the latency of a write to the store.
+func (b *backpressure) observe(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.avg == 0 {
+		b.avg = d
+		return
+	}
+	b.avg += (d - b.avg) / 8
+}
+
+// delay returns how long writers should wait before their next write,
+// or 0 while writes keep up.
+func (b *backpressure) delay() time.Duration {
+	if b.latency <= 0 {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.avg <= b.latency {
+		return 0
+	}
+	return min(b.avg, maxWriteDelay)
+}
+
+// diskFull reports whether free space on the store's filesystem is
+// below the minimum. Free space that can't be read counts as enough.
+func (b *backpressure) diskFull(now time.Time) bool {
+	if b.minFree == 0 || b.path == "" {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if now.Sub(b.checkedAt) >= diskCheckInterval {
+		b.checkedAt = now
+		free, err := diskFree(b.path)
+		if err != nil {
+			slog.Warn("failed to read free disk space", "path", b.path, "error", err)
+			free = b.minFree
+		}
+		if free < b.minFree && b.free >= b.minFree {
+			slog.Warn("disk nearly full, rejecting writes", "path", b.path, "free_bytes", free, "min_free_bytes", b.minFree)
+		}
+		b.free = free
+	}
+	return b.free < b.minFree
+}
+
+// errDiskFull is returned to writers while the disk is nearly full,
+// with a RetryInfo detail telling them when to try again.
+func errDiskFull() error {
+	st := status.New(codes.ResourceExhausted, "storage disk nearly full")
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(diskRetryAfter)}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
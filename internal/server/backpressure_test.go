@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kubelogs/kubelogs/api/storagepb"
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+// slowStore delays every write.
+type slowStore struct {
+	storage.Store
+	delay time.Duration
+}
+
+func (s *slowStore) Write(ctx context.Context, entries storage.LogBatch) (int, error) {
+	time.Sleep(s.delay)
+	return s.Store.Write(ctx, entries)
+}
+
+func TestServer_Backpressure(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:", WriteBufferSize: 1})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	req := &storagepb.WriteRequest{Entries: []*storagepb.LogEntry{
+		{TimestampNanos: time.Now().UnixNano(), Namespace: "default", Pod: "a", Container: "app", Message: "one"},
+	}}
+
+	srv := New(&slowStore{Store: store, delay: 20 * time.Millisecond})
+	srv.SetBackpressure(time.Hour, 0, "")
+	resp, err := srv.Write(ctx, req)
+	if err != nil || resp.RetryAfterMillis != 0 {
+		t.Errorf("Write under the latency limit = %v, %v, want no delay", resp, err)
+	}
+
+	srv.SetBackpressure(time.Millisecond, 0, "")
+	resp, err = srv.Write(ctx, req)
+	if err != nil || resp.Count != 1 || resp.RetryAfterMillis < 20 {
+		t.Errorf("Write over the latency limit = %v, %v, want written with a delay", resp, err)
+	}
+
+	// Far more free space than any disk has
+	srv.SetBackpressure(0, 1<<62, filepath.Join(t.TempDir(), "kubelogs.db"))
+	_, err = srv.Write(ctx, req)
+	st, _ := status.FromError(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("Write with a full disk = %v, want ResourceExhausted", err)
+	}
+	var retry *errdetails.RetryInfo
+	for _, d := range st.Details() {
+		retry, _ = d.(*errdetails.RetryInfo)
+	}
+	if retry == nil || retry.RetryDelay.AsDuration() != diskRetryAfter {
+		t.Errorf("details = %v, want a RetryInfo of %v", st.Details(), diskRetryAfter)
+	}
+}
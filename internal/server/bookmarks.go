@@ -0,0 +1,172 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/kubelogs/kubelogs/internal/auth"
+	"github.com/kubelogs/kubelogs/internal/bookmark"
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// bookmarkJSON is the JSON representation of a bookmark.
+type bookmarkJSON struct {
+	EntryID   int64         `json:"entryId"`
+	Note      string        `json:"note"`
+	CreatedAt int64         `json:"createdAt"` // Unix nanoseconds
+	UpdatedAt int64         `json:"updatedAt"` // Unix nanoseconds
+	Entry     *logEntryJSON `json:"entry"`     // nil once retention has removed the entry
+}
+
+// bookmarksResponse is the JSON response for listing bookmarks.
+type bookmarksResponse struct {
+	Bookmarks []bookmarkJSON `json:"bookmarks"`
+}
+
+// putBookmarkRequest is the JSON body for creating or updating a bookmark.
+type putBookmarkRequest struct {
+	Note string `json:"note"`
+}
+
+func toBookmarkJSON(b bookmark.Bookmark) bookmarkJSON {
+	return bookmarkJSON{
+		EntryID:   b.EntryID,
+		Note:      b.Note,
+		CreatedAt: b.CreatedAt.UnixNano(),
+		UpdatedAt: b.UpdatedAt.UnixNano(),
+	}
+}
+
+// handleListBookmarks returns the current user's bookmarks with their entries,
+// ordered by entry time so they read as an incident timeline.
+func (s *HTTPServer) handleListBookmarks(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	bookmarks, err := s.bookmarkStore.List(r.Context(), user.ID)
+	if err != nil {
+		slog.Error("list bookmarks error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	ids := make([]int64, len(bookmarks))
+	for i, b := range bookmarks {
+		ids[i] = b.EntryID
+	}
+
+	entries, err := s.entriesByID(r, ids)
+	if err != nil {
+		slog.Error("get bookmark entries error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := bookmarksResponse{Bookmarks: make([]bookmarkJSON, 0, len(bookmarks))}
+	for _, b := range bookmarks {
+		bj := toBookmarkJSON(b)
+		if e, ok := entries[b.EntryID]; ok {
+			ej := s.toJSON(e)
+			bj.Entry = &ej
+		}
+		resp.Bookmarks = append(resp.Bookmarks, bj)
+	}
+
+	// Entries first by timestamp, then bookmarks whose entry is gone
+	sort.SliceStable(resp.Bookmarks, func(i, j int) bool {
+		a, b := resp.Bookmarks[i].Entry, resp.Bookmarks[j].Entry
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return a.Timestamp < b.Timestamp
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("json encode error", "error", err)
+	}
+}
+
+// handlePutBookmark bookmarks an entry for the current user, replacing any note.
+func (s *HTTPServer) handlePutBookmark(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	entryID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || entryID <= 0 {
+		http.Error(w, "Invalid entry id", http.StatusBadRequest)
+		return
+	}
+
+	var req putBookmarkRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 2*bookmark.MaxNoteLength)).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if len(req.Note) > bookmark.MaxNoteLength {
+		http.Error(w, "Note too long", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.store.GetByID(r.Context(), entryID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "Entry not found", http.StatusNotFound)
+			return
+		}
+		slog.Error("get entry error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	b, err := s.bookmarkStore.Put(r.Context(), user.ID, entryID, req.Note)
+	if err != nil {
+		slog.Error("put bookmark error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(toBookmarkJSON(*b)); err != nil {
+		slog.Error("json encode error", "error", err)
+	}
+}
+
+// handleDeleteBookmark removes the current user's bookmark on an entry.
+func (s *HTTPServer) handleDeleteBookmark(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	entryID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || entryID <= 0 {
+		http.Error(w, "Invalid entry id", http.StatusBadRequest)
+		return
+	}
+
+	err = s.bookmarkStore.Delete(r.Context(), user.ID, entryID)
+	if errors.Is(err, bookmark.ErrNotFound) {
+		http.Error(w, "Bookmark not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("delete bookmark error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/auth"
+	"github.com/kubelogs/kubelogs/internal/bookmark"
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestBookmarks(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	store.Write(ctx, storage.LogBatch{
+		{Timestamp: now, Namespace: "ns", Pod: "pod", Container: "c", Message: "earlier"},
+		{Timestamp: now.Add(time.Minute), Namespace: "ns", Pod: "pod", Container: "c", Message: "later"},
+	})
+	store.Flush(ctx)
+
+	result, err := store.Query(ctx, storage.Query{Pagination: storage.Pagination{Order: storage.OrderAsc}})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	earlier, later := result.Entries[0].ID, result.Entries[1].ID
+
+	users := auth.NewUserStore(store.DB())
+	alice, err := users.CreateUser(ctx, "alice", "password123")
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	bob, err := users.CreateUser(ctx, "bob", "password123")
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	s := &HTTPServer{store: store, bookmarkStore: bookmark.NewStore(store.DB())}
+
+	do := func(user *auth.User, method string, id int64, body string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, fmt.Sprintf("/api/bookmarks/%d", id), strings.NewReader(body))
+		req.SetPathValue("id", fmt.Sprint(id))
+		req = req.WithContext(auth.ContextWithUser(req.Context(), user))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+	list := func(user *auth.User) []bookmarkJSON {
+		rec := do(user, http.MethodGet, 0, "", s.handleListBookmarks)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("list status = %d", rec.Code)
+		}
+		var resp bookmarksResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return resp.Bookmarks
+	}
+
+	// Bookmark the later entry first; the listing is ordered by entry time
+	if rec := do(alice, http.MethodPut, later, `{"note":"error spike starts"}`, s.handlePutBookmark); rec.Code != http.StatusOK {
+		t.Fatalf("put status = %d", rec.Code)
+	}
+	if rec := do(alice, http.MethodPut, earlier, "", s.handlePutBookmark); rec.Code != http.StatusOK {
+		t.Fatalf("put without body status = %d", rec.Code)
+	}
+	if rec := do(alice, http.MethodPut, 99999, "", s.handlePutBookmark); rec.Code != http.StatusNotFound {
+		t.Errorf("put unknown entry status = %d, want 404", rec.Code)
+	}
+
+	got := list(alice)
+	if len(got) != 2 {
+		t.Fatalf("got %d bookmarks, want 2", len(got))
+	}
+	if got[0].EntryID != earlier || got[1].EntryID != later {
+		t.Errorf("order = [%d, %d], want [%d, %d]", got[0].EntryID, got[1].EntryID, earlier, later)
+	}
+	if got[1].Note != "error spike starts" || got[1].Entry == nil || got[1].Entry.Message != "later" {
+		t.Errorf("unexpected bookmark %+v", got[1])
+	}
+
+	// Updating replaces the note
+	do(alice, http.MethodPut, later, `{"note":"root cause"}`, s.handlePutBookmark)
+	if got := list(alice); got[1].Note != "root cause" {
+		t.Errorf("note = %q, want %q", got[1].Note, "root cause")
+	}
+
+	// Bookmarks are per user
+	if got := list(bob); len(got) != 0 {
+		t.Errorf("bob sees %d bookmarks, want 0", len(got))
+	}
+	if rec := do(bob, http.MethodDelete, later, "", s.handleDeleteBookmark); rec.Code != http.StatusNotFound {
+		t.Errorf("bob delete status = %d, want 404", rec.Code)
+	}
+
+	if rec := do(alice, http.MethodDelete, later, "", s.handleDeleteBookmark); rec.Code != http.StatusNoContent {
+		t.Errorf("delete status = %d, want 204", rec.Code)
+	}
+	if got := list(alice); len(got) != 1 || got[0].EntryID != earlier {
+		t.Errorf("after delete got %+v, want only %d", got, earlier)
+	}
+}
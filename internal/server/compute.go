@@ -0,0 +1,150 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxComputedFields caps the computed fields of one query.
+	maxComputedFields = 5
+
+	// maxComputePattern caps the length of a computed field's regexp.
+	maxComputePattern = 256
+)
+
+// computeNameRe is the syntax of computed field names.
+var computeNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// computedField extracts a number from each entry's message, such as
+// the latency in "request done in 231ms".
+type computedField struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// parseComputedFields parses the compute parameters of a query, each
+// name:regexp. The regexp's first group, or its whole match if it has
+// none, is the field's value.
+func parseComputedFields(params url.Values) ([]computedField, error) {
+	specs := params["compute"]
+	if len(specs) > maxComputedFields {
+		return nil, fmt.Errorf("at most %d computed fields", maxComputedFields)
+	}
+
+	fields := make([]computedField, 0, len(specs))
+	for _, spec := range specs {
+		name, pattern, ok := strings.Cut(spec, ":")
+		if !ok || !computeNameRe.MatchString(name) {
+			return nil, fmt.Errorf("invalid computed field %q: want name:regexp", spec)
+		}
+		if slices.ContainsFunc(fields, func(f computedField) bool { return f.name == name }) {
+			return nil, fmt.Errorf("duplicate computed field %q", name)
+		}
+		if len(pattern) > maxComputePattern {
+			return nil, fmt.Errorf("computed field %q: regexp longer than %d bytes", name, maxComputePattern)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("computed field %q: %v", name, err)
+		}
+		fields = append(fields, computedField{name: name, re: re})
+	}
+	return fields, nil
+}
+
+// eval returns the field's value in message, if the regexp matches and
+// the match is a number or a duration.
+func (f computedField) eval(message string) (float64, bool) {
+	m := f.re.FindStringSubmatch(message)
+	if m == nil {
+		return 0, false
+	}
+	if len(m) > 1 {
+		return computeValue(m[1])
+	}
+	return computeValue(m[0])
+}
+
+// computeValue parses s as a number, or as a Go duration such as "1.5s",
+// which it returns in milliseconds so durations of different units
+// compare.
+func computeValue(s string) (float64, bool) {
+	if v, err := strconv.ParseFloat(s, 64); err == nil && !math.IsNaN(v) && !math.IsInf(v, 0) {
+		return v, true
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return float64(d) / float64(time.Millisecond), true
+	}
+	return 0, false
+}
+
+// computeEntry evaluates fields on message. It returns nil if none
+// matched.
+func computeEntry(fields []computedField, message string) map[string]float64 {
+	var values map[string]float64
+	for _, f := range fields {
+		if v, ok := f.eval(message); ok {
+			if values == nil {
+				values = make(map[string]float64, len(fields))
+			}
+			values[f.name] = v
+		}
+	}
+	return values
+}
+
+// computedSummaryJSON aggregates a computed field over the entries of
+// one page.
+type computedSummaryJSON struct {
+	Count int     `json:"count"` // Entries the field was found in
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Avg   float64 `json:"avg"`
+	P50   float64 `json:"p50"`
+	P95   float64 `json:"p95"`
+}
+
+// summarizeComputed aggregates each computed field over entries. Fields
+// found in no entry are left out.
+func summarizeComputed(fields []computedField, entries []logEntryJSON) map[string]computedSummaryJSON {
+	summary := make(map[string]computedSummaryJSON, len(fields))
+	for _, f := range fields {
+		var values []float64
+		for _, e := range entries {
+			if v, ok := e.Computed[f.name]; ok {
+				values = append(values, v)
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		slices.Sort(values)
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		summary[f.name] = computedSummaryJSON{
+			Count: len(values),
+			Min:   values[0],
+			Max:   values[len(values)-1],
+			Avg:   sum / float64(len(values)),
+			P50:   percentile(values, 0.50),
+			P95:   percentile(values, 0.95),
+		}
+	}
+	return summary
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(i, 0)]
+}
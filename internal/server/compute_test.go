@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestComputeValue(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"42", 42, true},
+		{"-1.5", -1.5, true},
+		{"231ms", 231, true},
+		{"1.5s", 1500, true},
+		{"2m3s", 123000, true},
+		{"250µs", 0.25, true},
+		{"NaN", 0, false},
+		{"fast", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := computeValue(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("computeValue(%q) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestComputedFields(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	var batch storage.LogBatch
+	for i, msg := range []string{
+		"GET /orders took 120ms status=200",
+		"GET /orders took 1.5s status=500",
+		"GET /orders took 30ms status=200",
+		"cache warmed",
+	} {
+		batch = append(batch, storage.LogEntry{Timestamp: now.Add(time.Duration(i) * time.Second), Namespace: "ns", Message: msg})
+	}
+	store.Write(ctx, batch)
+	store.Flush(ctx)
+
+	s := &HTTPServer{store: store}
+	get := func(params url.Values) (*httptest.ResponseRecorder, queryResponse) {
+		req := httptest.NewRequest(http.MethodGet, "/api/logs?"+params.Encode(), nil)
+		rec := httptest.NewRecorder()
+		s.handleQueryLogs(rec, req)
+		var resp queryResponse
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+		}
+		return rec, resp
+	}
+
+	rec, resp := get(url.Values{"order": {"asc"}, "compute": {`latency:took (\S+)`, `status:status=\d+`, `code:status=(\d+)`}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	want := []map[string]float64{
+		{"latency": 120, "code": 200},
+		{"latency": 1500, "code": 500},
+		{"latency": 30, "code": 200},
+		nil,
+	}
+	for i, e := range resp.Entries {
+		if len(e.Computed) != len(want[i]) {
+			t.Errorf("entry %d computed = %v, want %v", i, e.Computed, want[i])
+			continue
+		}
+		for k, v := range want[i] {
+			if e.Computed[k] != v {
+				t.Errorf("entry %d computed = %v, want %v", i, e.Computed, want[i])
+			}
+		}
+	}
+
+	// "status=200" isn't a number, so status is never found
+	if _, ok := resp.Computed["status"]; ok {
+		t.Errorf("summary has status: %v", resp.Computed)
+	}
+	got := resp.Computed["latency"]
+	if got.Count != 3 || got.Min != 30 || got.Max != 1500 || got.Avg != 550 || got.P50 != 120 || got.P95 != 1500 {
+		t.Errorf("latency summary = %+v", got)
+	}
+
+	for _, compute := range [][]string{
+		{"latency"},
+		{"9lives:(\\d+)"},
+		{"latency:(", "other:x"},
+		{"a:x", "a:y"},
+		{"a:1", "b:2", "c:3", "d:4", "e:5", "f:6"},
+	} {
+		if rec, _ := get(url.Values{"compute": compute}); rec.Code != http.StatusBadRequest {
+			t.Errorf("compute %q: status = %d, want 400", compute, rec.Code)
+		}
+	}
+}
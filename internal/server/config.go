@@ -1,42 +1,292 @@
 package server
 
 import (
+	"net/netip"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // Config holds server configuration.
 type Config struct {
-	// ListenAddr is the gRPC server listen address.
+	// ListenAddr is the gRPC server listen address. The host may be a
+	// network interface name, e.g. "eth0:50051", to bind to its address.
 	// Default: ":50051"
 	ListenAddr string
 
-	// HTTPListenAddr is the HTTP server listen address for the web UI.
+	// HTTPListenAddr is the HTTP server listen address for the web UI,
+	// e.g. "127.0.0.1:8080" behind an ingress sidecar. Interface names
+	// are accepted as for ListenAddr.
 	// Default: ":8080"
 	HTTPListenAddr string
 
+	// TLSCertFile and TLSKeyFile enable TLS on the gRPC listener with
+	// this PEM certificate and key.
+	// Default: "" (plaintext)
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// TLSClientCAFile requires gRPC clients to present a certificate
+	// signed by one of the CAs in this PEM file (mutual TLS).
+	// Default: "" (client certificates not checked)
+	TLSClientCAFile string
+
+	// GRPCToken requires gRPC clients to send this token as
+	// "authorization: Bearer <token>" metadata. The health service is
+	// exempt so probes keep working.
+	// Default: "" (calls aren't authenticated)
+	GRPCToken string
+
+	// GRPCCollectorTokens are tokens issued to individual collectors,
+	// keyed by collector name, accepted alongside GRPCToken.
+	// Default: none
+	GRPCCollectorTokens map[string]string
+
+	// GRPCAdminToken is the only token accepted for the AdminService,
+	// whose calls delete logs. It is accepted for every other call too.
+	// Default: "" (the AdminService isn't served)
+	GRPCAdminToken string
+
+	// GRPCHealth registers the standard gRPC health service.
+	// Default: true
+	GRPCHealth bool
+
+	// GRPCReflection registers the gRPC reflection service, which lets
+	// clients such as grpcurl discover the API.
+	// Default: true
+	GRPCReflection bool
+
+	// OTLPReceiver registers the OpenTelemetry logs service, so OTLP/gRPC
+	// exporters can send logs to the gRPC port.
+	// Default: true
+	OTLPReceiver bool
+
+	// TrustedProxies are the addresses of load balancers and ingress
+	// proxies in front of the HTTP server. Requests from them have their
+	// client address taken from X-Forwarded-For.
+	// Default: none (X-Forwarded-For is ignored)
+	TrustedProxies []netip.Prefix
+
+	// ProxyProtocol reads a PROXY protocol header from each HTTP
+	// connection from TrustedProxies, which must be set.
+	// Default: false
+	ProxyProtocol bool
+
+	// TraceURL links trace IDs in messages to a trace viewer, e.g.
+	// "https://jaeger.example.com/trace/{traceId}".
+	// Default: "" (trace IDs filter the UI instead)
+	TraceURL string
+
 	// HTTPEnabled controls whether the HTTP server is started.
 	// Default: true
 	HTTPEnabled bool
 
-	// DBPath is the path to the SQLite database file.
+	// MetricsEnabled serves Prometheus metrics on /metrics at
+	// MetricsListenAddr, separately from the web UI so it needs no auth.
+	// Default: true
+	MetricsEnabled bool
+
+	// MetricsListenAddr is the address for the metrics endpoint.
+	// Default: ":9090"
+	MetricsListenAddr string
+
+	// DBPath is the path to the SQLite database file. With the "s3"
+	// storage backend it holds only users, sessions and other metadata.
 	// Default: "kubelogs.db"
 	DBPath string
 
+	// SQLitePreset sizes the page cache and memory map of SQLite
+	// databases for the server's memory: "small", "medium" or "large"
+	// (see sqlite.Config). Per-store options override it.
+	// Default: "small"
+	SQLitePreset string
+
+	// SQLiteIndexedAttributes are attribute keys, e.g. "trace_id", that
+	// SQLite stores keep in indexed columns, so filters on them are fast.
+	// Default: none
+	SQLiteIndexedAttributes []string
+
+	// SQLiteFTSShedBacklog is the number of entries waiting to be
+	// written above which SQLite stores stop full-text indexing new
+	// entries, catching up in the background once ingest calms down
+	// (see sqlite.Config). 0 always indexes on write.
+	// Default: 0
+	SQLiteFTSShedBacklog int
+
+	// SQLiteHashChain makes SQLite stores record a hash chain over the
+	// entries of each flush, checked by GET /api/admin/ledger/verify
+	// (see sqlite.Config).
+	// Default: false
+	SQLiteHashChain bool
+
+	// StorageBackend selects where logs are stored: "sqlite", "s3"
+	// (chunks written directly to an S3-compatible bucket), "postgres"
+	// (a shared PostgreSQL database) or another backend registered with
+	// storage.Register.
+	// Default: "sqlite"
+	StorageBackend string
+
+	// StorageOptions are passed to the backend's storage.Factory, on top
+	// of the options set by the fields below, e.g. "write_buffer=5000".
+	// Default: none
+	StorageOptions map[string]string
+
+	// PostgresDSN is the connection string for the "postgres" storage
+	// backend.
+	// Default: "" (required for "postgres")
+	PostgresDSN string
+
+	// S3Endpoint, S3Region, S3Bucket, S3Prefix and S3PathStyle locate
+	// the bucket for the "s3" storage backend. Credentials are read from
+	// the standard AWS_* environment variables.
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3Prefix    string
+	S3PathStyle bool
+
+	// S3CacheDir caches fetched chunks on local disk. Empty caches only
+	// a few chunks in memory.
+	// Default: "" (memory only)
+	S3CacheDir string
+
+	// S3CacheMaxBytes bounds the chunk cache directory.
+	// Default: 1 GiB
+	S3CacheMaxBytes int64
+
+	// ArchiveBucket, when set, makes retention export entries to this
+	// S3-compatible bucket before deleting them. ArchiveEndpoint,
+	// ArchiveRegion and ArchivePathStyle locate it as for the "s3"
+	// backend; credentials are read from the standard AWS_* environment
+	// variables.
+	// Default: "" (disabled)
+	ArchiveBucket    string
+	ArchiveEndpoint  string
+	ArchiveRegion    string
+	ArchivePathStyle bool
+
+	// ArchivePrefix is prepended to the keys of archived objects.
+	// Default: "archive/"
+	ArchivePrefix string
+
+	// StorageRoutesFile is a JSON routing spec (see router.Spec) sending
+	// namespaces to different stores. When set, StorageBackend is ignored.
+	// Default: "" (disabled)
+	StorageRoutesFile string
+
 	// RetentionDays is the number of days to retain logs.
 	// 0 means disabled (no automatic deletion).
 	// Default: 0 (disabled)
 	RetentionDays int
 
+	// ClusterRetentionDays overrides RetentionDays for individual
+	// clusters; 0 keeps a cluster's logs forever. The key "" selects
+	// entries without a cluster name.
+	// Default: none
+	ClusterRetentionDays map[string]int
+
+	// ClusterQuotas caps the entries each cluster may ingest over gRPC
+	// per UTC day; entries over the quota are dropped. The key "*"
+	// applies to clusters not listed.
+	// Default: none (unlimited)
+	ClusterQuotas map[string]int64
+
+	// BackpressureLatency asks collectors to slow down, with a delay in
+	// write replies, while the average write to the store takes longer.
+	// 0 disables it.
+	// Default: 2s
+	BackpressureLatency time.Duration
+
+	// MinFreeDiskBytes rejects gRPC writes with RESOURCE_EXHAUSTED while
+	// the filesystem holding DBPath has less space free. 0 disables it.
+	// Default: 0 (disabled)
+	MinFreeDiskBytes uint64
+
+	// Enrichers names the enrichers applied to gRPC writes, in order:
+	// "environment" (EnvironmentTags) and "geoip" (GeoIPFile).
+	// Default: none
+	Enrichers []string
+
+	// EnvironmentTags are attributes the "environment" enricher adds to
+	// every entry, e.g. env=prod.
+	// Default: none
+	EnvironmentTags map[string]string
+
+	// GeoIPFile is the network table of the "geoip" enricher (see
+	// LoadGeoIP).
+	// Default: "" (required for "geoip")
+	GeoIPFile string
+
+	// GeoIPAttribute is the attribute holding the address the "geoip"
+	// enricher looks up.
+	// Default: "client_ip"
+	GeoIPAttribute string
+
 	// RetentionInterval is how often the retention cleanup runs.
 	// Default: 1 hour
 	RetentionInterval time.Duration
 
+	// LogStatsInterval is how often hourly entry counts per namespace
+	// and severity are brought up to date for /api/stats/timeseries. 0
+	// disables them.
+	// Default: 5 minutes
+	LogStatsInterval time.Duration
+
+	// LogStatsRetentionDays is the number of days hourly counts are
+	// kept, independently of the entries they count. 0 keeps them
+	// forever.
+	// Default: 90
+	LogStatsRetentionDays int
+
 	// AuthEnabled enables authentication when true.
 	// Default: false (disabled)
 	AuthEnabled bool
 
+	// AuthMethods names the ways users authenticate, tried in order:
+	// "local" for users with a password stored in the database, who log
+	// in on /login, "oidc" for users who log in with an OpenID Connect
+	// provider, and "proxy" for users named in a header by an
+	// authenticating reverse proxy. Requires AuthEnabled.
+	// Default: ["local"]
+	AuthMethods []string
+
+	// AuthProxyHeaders are the headers the "proxy" auth method reads the
+	// username from, the first one set winning.
+	// Default: ["X-Auth-Request-User", "X-Forwarded-User"]
+	AuthProxyHeaders []string
+
+	// AuthProxyCIDRs are the addresses of the authenticating proxies
+	// whose user headers are believed. Required by the "proxy" method.
+	// Default: none
+	AuthProxyCIDRs []netip.Prefix
+
+	// OIDCIssuer is the URL of the OpenID Connect provider the "oidc"
+	// auth method logs users in with, discovered from its
+	// /.well-known/openid-configuration.
+	// Default: "" (required by "oidc")
+	OIDCIssuer string
+
+	// OIDCClientID and OIDCClientSecret identify the server at the
+	// provider. The secret may be empty for public clients.
+	// Default: "" (the client ID is required by "oidc")
+	OIDCClientID     string
+	OIDCClientSecret string
+
+	// OIDCRedirectURL is where the provider sends users back, the
+	// server's external URL followed by /auth/oidc/callback.
+	// Default: "" (required by "oidc")
+	OIDCRedirectURL string
+
+	// OIDCScopes are the scopes requested from the provider.
+	// Default: ["openid", "profile", "email"]
+	OIDCScopes []string
+
+	// OIDCUsernameClaim is the ID token claim naming the user. With
+	// "email", addresses the provider doesn't mark verified are refused.
+	// Default: "email"
+	OIDCUsernameClaim string
+
 	// SessionDuration is how long sessions remain valid.
 	// Default: 24 hours
 	SessionDuration time.Duration
@@ -48,21 +298,100 @@ type Config struct {
 	// SessionCookieSecure sets the Secure flag on session cookies.
 	// Default: true
 	SessionCookieSecure bool
+
+	// AdminUsers are the usernames allowed to use admin endpoints such
+	// as the SQL console. Requires AuthEnabled.
+	// Default: none
+	AdminUsers []string
+
+	// RoutePolicy sets the role each route requires when auth is
+	// enabled, by path: "public" for anyone, "viewer" for any user and
+	// "admin" for AdminUsers. A path ending in "*" matches the paths
+	// starting with the rest; exact paths win over these, and longer
+	// ones over shorter. Routes not covered need "viewer", and those
+	// under /api/admin/ "admin". The login routes are always public.
+	// Default: none
+	RoutePolicy map[string]string
+
+	// RequireSecondApprover makes destructive admin operations, such as
+	// deleting by query, need a confirmation from an admin other than the
+	// one who previewed them. Needs two AdminUsers.
+	// Default: false
+	RequireSecondApprover bool
+
+	// SQLConsoleTimeout bounds each SQL console query.
+	// Default: 10 seconds
+	SQLConsoleTimeout time.Duration
+
+	// SQLConsoleMaxRows caps the rows a SQL console query returns.
+	// Default: 1000
+	SQLConsoleMaxRows int
+
+	// QueryTimeout cancels log queries over gRPC and /api/logs still
+	// running after it, so a pathological full-text search can't hold
+	// the database connection indefinitely. 0 disables the limit.
+	// Default: 30 seconds
+	QueryTimeout time.Duration
+
+	// HTTPReadTimeout bounds reading an HTTP request, headers and body.
+	// 0 disables the limit.
+	// Default: 30 seconds
+	HTTPReadTimeout time.Duration
+
+	// HTTPWriteTimeout bounds handling an HTTP request and writing its
+	// response. Live tails, long polls and exports are exempt. 0
+	// disables the limit.
+	// Default: 60 seconds
+	HTTPWriteTimeout time.Duration
+
+	// HTTPIdleTimeout closes keep-alive connections idle for longer.
+	// Default: 2 minutes
+	HTTPIdleTimeout time.Duration
+
+	// HTTPShutdownTimeout is how long in-flight HTTP requests and gRPC
+	// calls may run after a shutdown signal before their connections are
+	// closed. Live tails, gRPC tails and write streams end right away.
+	// Default: 15 seconds
+	HTTPShutdownTimeout time.Duration
 }
 
 // DefaultConfig returns sensible defaults.
 func DefaultConfig() Config {
 	return Config{
-		ListenAddr:          ":50051",
-		HTTPListenAddr:      ":8080",
-		HTTPEnabled:         true,
-		DBPath:              "kubelogs.db",
-		RetentionDays:       0,
-		RetentionInterval:   time.Hour,
-		AuthEnabled:         false,
-		SessionDuration:     24 * time.Hour,
-		SessionCookieName:   "kubelogs_session",
-		SessionCookieSecure: true,
+		ListenAddr:            ":50051",
+		HTTPListenAddr:        ":8080",
+		GRPCHealth:            true,
+		GRPCReflection:        true,
+		OTLPReceiver:          true,
+		HTTPEnabled:           true,
+		MetricsEnabled:        true,
+		MetricsListenAddr:     ":9090",
+		DBPath:                "kubelogs.db",
+		SQLitePreset:          "small",
+		StorageBackend:        "sqlite",
+		S3CacheMaxBytes:       1 << 30,
+		ArchivePrefix:         "archive/",
+		RetentionDays:         0,
+		RetentionInterval:     time.Hour,
+		LogStatsInterval:      5 * time.Minute,
+		LogStatsRetentionDays: 90,
+		AuthEnabled:           false,
+		AuthMethods:           []string{"local"},
+		AuthProxyHeaders:      []string{"X-Auth-Request-User", "X-Forwarded-User"},
+		OIDCScopes:            []string{"openid", "profile", "email"},
+		OIDCUsernameClaim:     "email",
+		SessionDuration:       24 * time.Hour,
+		SessionCookieName:     "kubelogs_session",
+		SessionCookieSecure:   true,
+		SQLConsoleTimeout:     10 * time.Second,
+		SQLConsoleMaxRows:     1000,
+		QueryTimeout:          30 * time.Second,
+		HTTPReadTimeout:       30 * time.Second,
+		HTTPWriteTimeout:      60 * time.Second,
+		HTTPIdleTimeout:       2 * time.Minute,
+		HTTPShutdownTimeout:   15 * time.Second,
+		GeoIPAttribute:        "client_ip",
+		BackpressureLatency:   2 * time.Second,
 	}
 }
 
@@ -78,24 +407,226 @@ func ConfigFromEnv() Config {
 		cfg.HTTPListenAddr = v
 	}
 
+	cfg.TLSCertFile = os.Getenv("KUBELOGS_TLS_CERT_FILE")
+	cfg.TLSKeyFile = os.Getenv("KUBELOGS_TLS_KEY_FILE")
+	cfg.TLSClientCAFile = os.Getenv("KUBELOGS_TLS_CLIENT_CA_FILE")
+
+	cfg.GRPCToken = os.Getenv("KUBELOGS_GRPC_TOKEN")
+
+	if v := os.Getenv("KUBELOGS_GRPC_COLLECTOR_TOKENS"); v != "" {
+		cfg.GRPCCollectorTokens = parseKeyValues(v)
+	}
+
+	cfg.GRPCAdminToken = os.Getenv("KUBELOGS_GRPC_ADMIN_TOKEN")
+
+	if v := os.Getenv("KUBELOGS_GRPC_HEALTH"); v == "false" {
+		cfg.GRPCHealth = false
+	}
+
+	if v := os.Getenv("KUBELOGS_GRPC_REFLECTION"); v == "false" {
+		cfg.GRPCReflection = false
+	}
+
+	if v := os.Getenv("KUBELOGS_OTLP_RECEIVER"); v == "false" {
+		cfg.OTLPReceiver = false
+	}
+
+	if v := os.Getenv("KUBELOGS_TRUSTED_PROXIES"); v != "" {
+		cfg.TrustedProxies = parsePrefixes(v)
+	}
+
+	if v := os.Getenv("KUBELOGS_PROXY_PROTOCOL"); v == "true" {
+		cfg.ProxyProtocol = true
+	}
+
+	cfg.TraceURL = os.Getenv("KUBELOGS_TRACE_URL")
+
 	if v := os.Getenv("KUBELOGS_HTTP_ENABLED"); v == "false" {
 		cfg.HTTPEnabled = false
 	}
 
+	if v := os.Getenv("KUBELOGS_METRICS_ENABLED"); v == "false" {
+		cfg.MetricsEnabled = false
+	}
+
+	if v := os.Getenv("KUBELOGS_METRICS_ADDR"); v != "" {
+		cfg.MetricsListenAddr = v
+	}
+
 	if v := os.Getenv("KUBELOGS_DB_PATH"); v != "" {
 		cfg.DBPath = v
 	}
 
+	if v := os.Getenv("KUBELOGS_SQLITE_PRESET"); v != "" {
+		cfg.SQLitePreset = v
+	}
+
+	for _, key := range strings.Split(os.Getenv("KUBELOGS_SQLITE_INDEXED_ATTRIBUTES"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			cfg.SQLiteIndexedAttributes = append(cfg.SQLiteIndexedAttributes, key)
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_SQLITE_FTS_SHED_BACKLOG"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.SQLiteFTSShedBacklog = n
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_SQLITE_HASH_CHAIN"); v == "true" {
+		cfg.SQLiteHashChain = true
+	}
+
+	if v := os.Getenv("KUBELOGS_STORAGE_BACKEND"); v != "" {
+		cfg.StorageBackend = v
+	}
+
+	if v := os.Getenv("KUBELOGS_STORAGE_OPTIONS"); v != "" {
+		cfg.StorageOptions = parseKeyValues(v)
+	}
+
+	cfg.PostgresDSN = os.Getenv("KUBELOGS_POSTGRES_DSN")
+
+	cfg.S3Endpoint = os.Getenv("KUBELOGS_S3_ENDPOINT")
+	cfg.S3Region = os.Getenv("KUBELOGS_S3_REGION")
+	cfg.S3Bucket = os.Getenv("KUBELOGS_S3_BUCKET")
+	cfg.S3Prefix = os.Getenv("KUBELOGS_S3_PREFIX")
+	cfg.S3CacheDir = os.Getenv("KUBELOGS_S3_CACHE_DIR")
+
+	if v := os.Getenv("KUBELOGS_S3_PATH_STYLE"); v == "true" {
+		cfg.S3PathStyle = true
+	}
+
+	if v := os.Getenv("KUBELOGS_S3_CACHE_MAX_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			cfg.S3CacheMaxBytes = n
+		}
+	}
+
+	cfg.ArchiveBucket = os.Getenv("KUBELOGS_ARCHIVE_BUCKET")
+	cfg.ArchiveEndpoint = os.Getenv("KUBELOGS_ARCHIVE_ENDPOINT")
+	cfg.ArchiveRegion = os.Getenv("KUBELOGS_ARCHIVE_REGION")
+
+	if v := os.Getenv("KUBELOGS_ARCHIVE_PREFIX"); v != "" {
+		cfg.ArchivePrefix = v
+	}
+
+	if v := os.Getenv("KUBELOGS_ARCHIVE_PATH_STYLE"); v == "true" {
+		cfg.ArchivePathStyle = true
+	}
+
+	cfg.StorageRoutesFile = os.Getenv("KUBELOGS_STORAGE_ROUTES")
+
 	if v := os.Getenv("KUBELOGS_RETENTION_DAYS"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
 			cfg.RetentionDays = n
 		}
 	}
 
+	if v := os.Getenv("KUBELOGS_CLUSTER_RETENTION_DAYS"); v != "" {
+		for cluster, days := range parseKeyValues(v) {
+			if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+				if cfg.ClusterRetentionDays == nil {
+					cfg.ClusterRetentionDays = make(map[string]int)
+				}
+				cfg.ClusterRetentionDays[cluster] = n
+			}
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_LOG_STATS_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.LogStatsInterval = d
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_LOG_STATS_RETENTION_DAYS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.LogStatsRetentionDays = n
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_CLUSTER_QUOTAS"); v != "" {
+		for cluster, limit := range parseKeyValues(v) {
+			if n, err := strconv.ParseInt(limit, 10, 64); err == nil && n >= 0 {
+				if cfg.ClusterQuotas == nil {
+					cfg.ClusterQuotas = make(map[string]int64)
+				}
+				cfg.ClusterQuotas[cluster] = n
+			}
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_BACKPRESSURE_LATENCY"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.BackpressureLatency = d
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_MIN_FREE_DISK_BYTES"); v != "" {
+		if n, err := strconv.ParseUint(v, 10, 64); err == nil {
+			cfg.MinFreeDiskBytes = n
+		}
+	}
+
+	for _, name := range strings.Split(os.Getenv("KUBELOGS_ENRICHERS"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			cfg.Enrichers = append(cfg.Enrichers, name)
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_ENVIRONMENT_TAGS"); v != "" {
+		cfg.EnvironmentTags = parseKeyValues(v)
+	}
+
+	cfg.GeoIPFile = os.Getenv("KUBELOGS_GEOIP_FILE")
+
+	if v := os.Getenv("KUBELOGS_GEOIP_ATTRIBUTE"); v != "" {
+		cfg.GeoIPAttribute = v
+	}
+
 	if v := os.Getenv("KUBELOGS_AUTH_ENABLED"); v == "true" {
 		cfg.AuthEnabled = true
 	}
 
+	if v := os.Getenv("KUBELOGS_AUTH_METHODS"); v != "" {
+		cfg.AuthMethods = nil
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				cfg.AuthMethods = append(cfg.AuthMethods, name)
+			}
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_AUTH_PROXY_HEADERS"); v != "" {
+		cfg.AuthProxyHeaders = nil
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				cfg.AuthProxyHeaders = append(cfg.AuthProxyHeaders, name)
+			}
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_AUTH_PROXY_CIDRS"); v != "" {
+		cfg.AuthProxyCIDRs = parsePrefixes(v)
+	}
+
+	cfg.OIDCIssuer = os.Getenv("KUBELOGS_OIDC_ISSUER")
+	cfg.OIDCClientID = os.Getenv("KUBELOGS_OIDC_CLIENT_ID")
+	cfg.OIDCClientSecret = os.Getenv("KUBELOGS_OIDC_CLIENT_SECRET")
+	cfg.OIDCRedirectURL = os.Getenv("KUBELOGS_OIDC_REDIRECT_URL")
+	if v := os.Getenv("KUBELOGS_OIDC_SCOPES"); v != "" {
+		cfg.OIDCScopes = nil
+		for _, scope := range strings.Split(v, ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				cfg.OIDCScopes = append(cfg.OIDCScopes, scope)
+			}
+		}
+	}
+	if v := os.Getenv("KUBELOGS_OIDC_USERNAME_CLAIM"); v != "" {
+		cfg.OIDCUsernameClaim = v
+	}
+
 	if v := os.Getenv("KUBELOGS_SESSION_DURATION"); v != "" {
 		if d, err := time.ParseDuration(v); err == nil {
 			cfg.SessionDuration = d
@@ -106,15 +637,121 @@ func ConfigFromEnv() Config {
 		cfg.SessionCookieSecure = false
 	}
 
+	for _, name := range strings.Split(os.Getenv("KUBELOGS_ADMIN_USERS"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			cfg.AdminUsers = append(cfg.AdminUsers, name)
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_ROUTE_POLICY"); v != "" {
+		cfg.RoutePolicy = parseKeyValues(v)
+	}
+
+	if v := os.Getenv("KUBELOGS_REQUIRE_SECOND_APPROVER"); v == "true" {
+		cfg.RequireSecondApprover = true
+	}
+
+	if v := os.Getenv("KUBELOGS_SQL_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.SQLConsoleTimeout = d
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_QUERY_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.QueryTimeout = d
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_HTTP_READ_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.HTTPReadTimeout = d
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_HTTP_WRITE_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.HTTPWriteTimeout = d
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_HTTP_IDLE_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.HTTPIdleTimeout = d
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_HTTP_SHUTDOWN_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.HTTPShutdownTimeout = d
+		}
+	}
+
+	if v := os.Getenv("KUBELOGS_SQL_MAX_ROWS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.SQLConsoleMaxRows = n
+		}
+	}
+
 	return cfg
 }
 
 // RetentionEnabled returns true if log retention is configured.
 func (c Config) RetentionEnabled() bool {
-	return c.RetentionDays > 0
+	if c.RetentionDays > 0 {
+		return true
+	}
+	for _, days := range c.ClusterRetentionDays {
+		if days > 0 {
+			return true
+		}
+	}
+	return false
 }
 
 // RetentionCutoff returns the time before which logs should be deleted.
 func (c Config) RetentionCutoff() time.Time {
-	return time.Now().Add(-time.Duration(c.RetentionDays) * 24 * time.Hour)
+	return retentionCutoff(c.RetentionDays)
+}
+
+// ClusterRetention returns the retention in days for a cluster, 0 if
+// its logs are kept forever.
+func (c Config) ClusterRetention(cluster string) int {
+	if days, ok := c.ClusterRetentionDays[cluster]; ok {
+		return days
+	}
+	return c.RetentionDays
+}
+
+func retentionCutoff(days int) time.Time {
+	return time.Now().Add(-time.Duration(days) * 24 * time.Hour)
+}
+
+// parseKeyValues parses "east=7,west=30" into a map. Malformed
+// pairs are skipped.
+func parseKeyValues(s string) map[string]string {
+	values := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return values
+}
+
+// parsePrefixes parses a comma-separated list of CIDR prefixes and
+// single IPs. Malformed entries are skipped.
+func parsePrefixes(s string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if p, err := netip.ParsePrefix(v); err == nil {
+			prefixes = append(prefixes, p.Masked())
+		} else if ip, err := netip.ParseAddr(v); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(ip, ip.BitLen()))
+		}
+	}
+	return prefixes
 }
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/audit"
+)
+
+// confirmTTL is how long a preview's confirmation token can be used.
+const confirmTTL = 10 * time.Minute
+
+var (
+	errConfirmInvalid = errors.New("confirmation token is unknown, expired or for another operation")
+	errConfirmSelf    = errors.New("operation must be confirmed by another admin")
+)
+
+// Confirmations holds the tokens issued by previews of destructive admin
+// operations, which the operation must present to run, and records the
+// operations confirmed in the audit log. A token is bound to the
+// operation and its parameters and can be used once. Tokens are kept in
+// memory, so they don't survive a restart. The web UI and the
+// AdminService share one, so either can confirm the other's previews.
+type Confirmations struct {
+	mu     sync.Mutex
+	tokens map[string]pendingConfirmation
+
+	secondApprover bool         // The approver must differ from the requester
+	audit          *audit.Store // nil = server log only
+}
+
+type pendingConfirmation struct {
+	op        string // Operation and its parameters, e.g. `purge {"Namespace":"a",...}`
+	requester string
+	matched   int64 // Entries the preview counted, -1 if unknown
+	expires   time.Time
+}
+
+// NewConfirmations creates the confirmations of a server. With
+// secondApprover, an operation must be confirmed by someone other than
+// who previewed it. Confirmed operations are added to auditLog unless
+// it is nil.
+func NewConfirmations(secondApprover bool, auditLog *audit.Store) *Confirmations {
+	return &Confirmations{
+		tokens:         make(map[string]pendingConfirmation),
+		secondApprover: secondApprover,
+		audit:          auditLog,
+	}
+}
+
+// issue returns a new token for op, previewed by requester with matched
+// entries, and when it expires.
+func (c *Confirmations) issue(op, requester string, matched int64, now time.Time) (string, time.Time, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(b)
+	expires := now.Add(confirmTTL)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for t, p := range c.tokens {
+		if now.After(p.expires) {
+			delete(c.tokens, t)
+		}
+	}
+	c.tokens[token] = pendingConfirmation{op: op, requester: requester, matched: matched, expires: expires}
+	return token, expires, nil
+}
+
+// redeem uses up token to run op on behalf of approver and returns its
+// preview. With a second approver required, the approver must be someone
+// else; the token is then kept for them.
+func (c *Confirmations) redeem(token, op, approver string, now time.Time) (pendingConfirmation, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.tokens[token]
+	if !ok || p.op != op || now.After(p.expires) {
+		return pendingConfirmation{}, errConfirmInvalid
+	}
+	if c.secondApprover && p.requester == approver {
+		return pendingConfirmation{}, errConfirmSelf
+	}
+	delete(c.tokens, token)
+	return p, nil
+}
+
+// record adds a confirmed operation to the audit log. The operation has
+// run by then, so a failure is only logged.
+func (c *Confirmations) record(ctx context.Context, r audit.Record) {
+	if c.audit == nil {
+		return
+	}
+	if err := c.audit.Add(context.WithoutCancel(ctx), r); err != nil {
+		slog.Error("failed to write audit record", "operation", r.Operation, "requester", r.Requester,
+			"approver", r.Approver, "count", r.Count, "error", err)
+	}
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/auth"
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// maxSQLQueryLength bounds the SQL accepted by the console.
+const maxSQLQueryLength = 64 << 10
+
+// sqlQueryRequest is the JSON body for a SQL console query.
+type sqlQueryRequest struct {
+	Query string `json:"query"`
+}
+
+// sqlQueryResponse is the JSON response for a SQL console query.
+type sqlQueryResponse struct {
+	Columns    []string `json:"columns"`
+	Rows       [][]any  `json:"rows"`
+	Truncated  bool     `json:"truncated"` // More rows matched than the row cap
+	DurationMs int64    `json:"durationMs"`
+}
+
+// schemaObjectJSON is the JSON representation of a schema object.
+type schemaObjectJSON struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Table string `json:"table"`
+	SQL   string `json:"sql"`
+}
+
+// requireAdmin wraps an authenticated handler to allow only AdminUsers.
+func (s *HTTPServer) requireAdmin(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !s.adminUsers[user.Username] {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// sqlConsole returns the store's SQL console, writing an error if the
+// storage backend has none.
+func (s *HTTPServer) sqlConsole(w http.ResponseWriter) (storage.SQLConsole, bool) {
+	console, ok := s.store.(storage.SQLConsole)
+	if !ok {
+		http.Error(w, "SQL console requires the SQLite storage backend", http.StatusNotImplemented)
+	}
+	return console, ok
+}
+
+// handleSQLQuery runs a read-only SQL query against the logs database.
+// The store rejects anything but reads; the query is cut off after the
+// configured timeout and row cap.
+func (s *HTTPServer) handleSQLQuery(w http.ResponseWriter, r *http.Request) {
+	console, ok := s.sqlConsole(w)
+	if !ok {
+		return
+	}
+
+	var req sqlQueryRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSQLQueryLength)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		http.Error(w, "Query is required", http.StatusBadRequest)
+		return
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	slog.Info("sql console query", "user", user.Username, "query", req.Query)
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.sqlTimeout)
+	defer cancel()
+
+	start := time.Now()
+	result, err := console.QueryReadOnly(ctx, req.Query, s.sqlMaxRows)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			http.Error(w, "Query timed out after "+s.sqlTimeout.String(), http.StatusGatewayTimeout)
+			return
+		}
+		if errors.Is(err, storage.ErrStorageClosed) {
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		// Mostly syntax errors and denied statements, which the admin
+		// needs to see
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, sqlQueryResponse{
+		Columns:    result.Columns,
+		Rows:       result.Rows,
+		Truncated:  result.Truncated,
+		DurationMs: time.Since(start).Milliseconds(),
+	})
+}
+
+// handleSchema exports the logs database schema as JSON, or as a SQL
+// script with ?format=sql.
+func (s *HTTPServer) handleSchema(w http.ResponseWriter, r *http.Request) {
+	console, ok := s.sqlConsole(w)
+	if !ok {
+		return
+	}
+
+	objects, err := console.Schema(r.Context())
+	if err != nil {
+		slog.Error("schema error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "sql" {
+		w.Header().Set("Content-Type", "application/sql; charset=utf-8")
+		for _, o := range objects {
+			if o.SQL != "" {
+				w.Write([]byte(o.SQL + ";\n\n"))
+			}
+		}
+		return
+	}
+
+	resp := make([]schemaObjectJSON, len(objects))
+	for i, o := range objects {
+		resp[i] = schemaObjectJSON{Type: o.Type, Name: o.Name, Table: o.Table, SQL: o.SQL}
+	}
+	writeJSON(w, resp)
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/auth"
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestSQLConsole(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:", WriteBufferSize: 1})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	store.Write(ctx, storage.LogBatch{
+		{Timestamp: now, Namespace: "a", Pod: "pod", Container: "c", Severity: storage.SeverityError, Message: "boom"},
+		{Timestamp: now, Namespace: "a", Pod: "pod", Container: "c", Message: "ok"},
+		{Timestamp: now, Namespace: "b", Pod: "pod", Container: "c", Message: "ok"},
+	})
+
+	s := &HTTPServer{
+		store:      store,
+		adminUsers: map[string]bool{"root": true},
+		sqlTimeout: time.Second,
+		sqlMaxRows: 1,
+	}
+	admin := &auth.User{ID: 1, Username: "root"}
+
+	do := func(user *auth.User, method, target, body string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req = req.WithContext(auth.ContextWithUser(req.Context(), user))
+		rec := httptest.NewRecorder()
+		s.requireAdmin(handler).ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(admin, http.MethodPost, "/api/admin/sql", `{"query":"SELECT namespace, COUNT(*) FROM logs GROUP BY namespace ORDER BY 2 DESC"}`, s.handleSQLQuery)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("query status = %d: %s", rec.Code, rec.Body)
+	}
+	var resp sqlQueryResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Columns) != 2 || len(resp.Rows) != 1 || resp.Rows[0][0] != "a" || !resp.Truncated {
+		t.Errorf("response = %+v, want the row for a, truncated", resp)
+	}
+
+	tests := []struct {
+		name  string
+		user  *auth.User
+		body  string
+		want  int
+		match string
+	}{
+		{"not an admin", &auth.User{ID: 2, Username: "alice"}, `{"query":"SELECT 1"}`, http.StatusForbidden, ""},
+		{"write", admin, `{"query":"DELETE FROM log_rollups"}`, http.StatusBadRequest, "not authorized"},
+		{"credentials", admin, `{"query":"SELECT password FROM users"}`, http.StatusBadRequest, "prohibited"},
+		{"syntax error", admin, `{"query":"SELEC 1"}`, http.StatusBadRequest, "syntax error"},
+		{"empty", admin, `{"query":" "}`, http.StatusBadRequest, ""},
+		{"timeout", admin, `{"query":"WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT COUNT(*) FROM n"}`, http.StatusGatewayTimeout, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(tt.user, http.MethodPost, "/api/admin/sql", tt.body, s.handleSQLQuery)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.match) {
+				t.Errorf("body %q doesn't mention %q", rec.Body, tt.match)
+			}
+		})
+	}
+
+	rec = do(admin, http.MethodGet, "/api/admin/schema?format=sql", "", s.handleSchema)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "CREATE TABLE log_shards") {
+		t.Errorf("schema export = %d %q", rec.Code, rec.Body)
+	}
+	rec = do(admin, http.MethodGet, "/api/admin/schema", "", s.handleSchema)
+	var objects []schemaObjectJSON
+	if err := json.NewDecoder(rec.Body).Decode(&objects); err != nil || len(objects) == 0 {
+		t.Errorf("schema = %v, %v", objects, err)
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+const (
+	defaultRunsWindow = 7 * 24 * time.Hour
+	defaultRunsLimit  = 20
+	maxRunsLimit      = 100
+)
+
+// cronJobRunJSON is one run of a CronJob: a Job and the entries of its
+// pods.
+type cronJobRunJSON struct {
+	Namespace string `json:"namespace"`
+	Job       string `json:"job"`
+	Scheduled int64  `json:"scheduled"` // Unix nanoseconds
+	Pods      int    `json:"pods"`      // More than one when the run was retried
+	Entries   int64  `json:"entries"`
+	Errors    int64  `json:"errors"` // Entries at error or fatal
+}
+
+// cronJobRunsResponse is the JSON response for CronJob runs.
+type cronJobRunsResponse struct {
+	CronJob string           `json:"cronjob"`
+	Runs    []cronJobRunJSON `json:"runs"`
+}
+
+// handleCronJobRuns lists the recent runs of the CronJob named in the
+// path, newest first, from the job and cronjob attributes collectors set.
+// Runs scheduled within window (default 7 days) are listed, narrowed by
+// the usual filters such as namespace; limit defaults to 20, up to 100.
+// The logs of a run are those with attr.job set to its job.
+func (s *HTTPServer) handleCronJobRuns(w http.ResponseWriter, r *http.Request) {
+	agg, ok := s.store.(storage.GroupAggregator)
+	if !ok {
+		http.Error(w, "Not supported", http.StatusNotImplemented)
+		return
+	}
+
+	name := r.PathValue("name")
+	params := r.URL.Query()
+
+	window := defaultRunsWindow
+	if v := params.Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, fmt.Sprintf("invalid window %q", v), http.StatusBadRequest)
+			return
+		}
+		window = d
+	}
+	limit := defaultRunsLimit
+	if v := params.Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= maxRunsLimit {
+			limit = n
+		}
+	}
+
+	q, err := s.parseQueryParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.Sample = 0
+	if q.Attributes == nil {
+		q.Attributes = make(map[string]string)
+	}
+	q.Attributes[storage.CronJobAttribute] = name
+	since := time.Now().Add(-window)
+	if q.StartTime.IsZero() {
+		q.StartTime = since
+	}
+
+	ctx, cancel := withQueryTimeout(r.Context(), s.queryTimeout)
+	defer cancel()
+
+	groups, err := agg.Aggregate(ctx, q, storage.Aggregation{
+		GroupBy: []string{storage.GroupByNamespace, "attr." + storage.JobAttribute, storage.GroupByPod, storage.GroupBySeverity},
+	})
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			s.queryTimeouts.Inc()
+			http.Error(w, "Query timed out after "+s.queryTimeout.String(), http.StatusGatewayTimeout)
+			return
+		}
+		slog.Error("cronjob runs error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	type runKey struct{ namespace, job string }
+	runs := make(map[runKey]*cronJobRunJSON)
+	pods := make(map[runKey]map[string]bool)
+	for _, g := range groups {
+		k := runKey{g.Keys[0], g.Keys[1]}
+		run, ok := runs[k]
+		if !ok {
+			_, scheduled, ok := storage.CronJobRun(k.job)
+			// Runs scheduled before the window are left out, even if
+			// they logged within it
+			if !ok || scheduled.Before(since) {
+				continue
+			}
+			run = &cronJobRunJSON{Namespace: k.namespace, Job: k.job, Scheduled: scheduled.UnixNano()}
+			runs[k] = run
+			pods[k] = make(map[string]bool)
+		}
+		run.Entries += g.Count
+		if storage.ParseSeverity(g.Keys[3]) >= storage.SeverityError {
+			run.Errors += g.Count
+		}
+		pods[k][g.Keys[2]] = true
+	}
+
+	resp := cronJobRunsResponse{CronJob: name, Runs: make([]cronJobRunJSON, 0, len(runs))}
+	for k, run := range runs {
+		run.Pods = len(pods[k])
+		resp.Runs = append(resp.Runs, *run)
+	}
+	slices.SortFunc(resp.Runs, func(a, b cronJobRunJSON) int {
+		return cmp.Or(cmp.Compare(b.Scheduled, a.Scheduled), cmp.Compare(a.Namespace, b.Namespace))
+	})
+	resp.Runs = resp.Runs[:min(len(resp.Runs), limit)]
+	writeJSON(w, resp)
+}
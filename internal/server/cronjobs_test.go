@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestHandleCronJobRuns(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now().Truncate(time.Minute)
+	entry := func(scheduled time.Time, pod string, sev storage.Severity, msg string) storage.LogEntry {
+		job := fmt.Sprintf("backup-%d", scheduled.Unix()/60)
+		return storage.LogEntry{
+			Timestamp: scheduled.Add(time.Second), Namespace: "ops", Pod: pod, Severity: sev, Message: msg,
+			Attributes: map[string]string{storage.JobAttribute: job, storage.CronJobAttribute: "backup"},
+		}
+	}
+	yesterday, today := now.Add(-24*time.Hour), now.Add(-time.Hour)
+	store.Write(context.Background(), storage.LogBatch{
+		entry(yesterday, "backup-a", storage.SeverityInfo, "done"),
+		// Today's run failed once and was retried
+		entry(today, "backup-b", storage.SeverityError, "failed"),
+		entry(today, "backup-c", storage.SeverityInfo, "started"),
+		entry(today, "backup-c", storage.SeverityInfo, "done"),
+		{Timestamp: today, Namespace: "ops", Pod: "other", Message: "x", Attributes: map[string]string{storage.CronJobAttribute: "cleanup"}},
+	})
+
+	s := &HTTPServer{store: store}
+	runs := func(query string) cronJobRunsResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/cronjobs/backup/runs"+query, nil)
+		req.SetPathValue("name", "backup")
+		rec := httptest.NewRecorder()
+		s.handleCronJobRuns(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp cronJobRunsResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return resp
+	}
+
+	resp := runs("")
+	want := []cronJobRunJSON{
+		{Namespace: "ops", Job: fmt.Sprintf("backup-%d", today.Unix()/60), Scheduled: today.UnixNano(), Pods: 2, Entries: 3, Errors: 1},
+		{Namespace: "ops", Job: fmt.Sprintf("backup-%d", yesterday.Unix()/60), Scheduled: yesterday.UnixNano(), Pods: 1, Entries: 1},
+	}
+	if fmt.Sprint(resp.Runs) != fmt.Sprint(want) {
+		t.Errorf("runs = %+v, want %+v", resp.Runs, want)
+	}
+
+	if resp := runs("?window=12h"); len(resp.Runs) != 1 || resp.Runs[0].Job != want[0].Job {
+		t.Errorf("runs in the last 12h = %+v, want today's", resp.Runs)
+	}
+	if resp := runs("?limit=1"); len(resp.Runs) != 1 {
+		t.Errorf("runs with limit=1 = %+v", resp.Runs)
+	}
+	if resp := runs("?namespace=prod"); len(resp.Runs) != 0 {
+		t.Errorf("runs in another namespace = %+v", resp.Runs)
+	}
+}
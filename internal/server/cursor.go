@@ -0,0 +1,138 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// cursorVersion is bumped whenever the token layout changes.
+const cursorVersion = 1
+
+// cursorLen is the encoded size: version, order, orderBy, timestamp, id, filter hash.
+const cursorLen = 1 + 1 + 1 + 8 + 8 + 8
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// cursor is the decoded form of an opaque pagination token.
+// It marks the last entry of a page; the next page continues after it
+// in the cursor's order.
+type cursor struct {
+	Timestamp  int64 // Unix nanoseconds
+	ID         int64
+	Order      storage.Order
+	OrderBy    storage.OrderBy
+	FilterHash uint64
+}
+
+// cursorFromEntry builds a cursor positioned at e for query q.
+func cursorFromEntry(e storage.LogEntry, q storage.Query) cursor {
+	return cursor{
+		Timestamp:  e.Timestamp.UnixNano(),
+		ID:         e.ID,
+		Order:      q.Pagination.Order,
+		OrderBy:    q.Pagination.OrderBy,
+		FilterHash: filterHash(q),
+	}
+}
+
+// encode returns the URL-safe token for c.
+func (c cursor) encode() string {
+	var buf [cursorLen]byte
+	buf[0] = cursorVersion
+	buf[1] = byte(c.Order)
+	buf[2] = byte(c.OrderBy)
+	binary.BigEndian.PutUint64(buf[3:], uint64(c.Timestamp))
+	binary.BigEndian.PutUint64(buf[11:], uint64(c.ID))
+	binary.BigEndian.PutUint64(buf[19:], c.FilterHash)
+	return base64.RawURLEncoding.EncodeToString(buf[:])
+}
+
+// decodeCursor parses a token produced by cursor.encode.
+func decodeCursor(token string) (cursor, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(buf) != cursorLen || buf[0] != cursorVersion {
+		return cursor{}, errInvalidCursor
+	}
+
+	c := cursor{
+		Order:      storage.Order(buf[1]),
+		OrderBy:    storage.OrderBy(buf[2]),
+		Timestamp:  int64(binary.BigEndian.Uint64(buf[3:])),
+		ID:         int64(binary.BigEndian.Uint64(buf[11:])),
+		FilterHash: binary.BigEndian.Uint64(buf[19:]),
+	}
+	if c.Order > storage.OrderAsc || c.OrderBy > storage.OrderByTimestamp || c.ID <= 0 {
+		return cursor{}, errInvalidCursor
+	}
+	return c, nil
+}
+
+// apply positions q after the cursor. Returns errInvalidCursor if the
+// cursor was issued for a different ordering or filter set.
+func (c cursor) apply(q *storage.Query) error {
+	if c.Order != q.Pagination.Order || c.OrderBy != q.Pagination.OrderBy || c.FilterHash != filterHash(*q) {
+		return errInvalidCursor
+	}
+
+	var ts time.Time
+	if c.OrderBy == storage.OrderByTimestamp {
+		ts = time.Unix(0, c.Timestamp)
+	}
+
+	if c.Order == storage.OrderAsc {
+		q.Pagination.AfterID = c.ID
+		q.Pagination.AfterTimestamp = ts
+	} else {
+		q.Pagination.BeforeID = c.ID
+		q.Pagination.BeforeTimestamp = ts
+	}
+	return nil
+}
+
+// filterHash fingerprints the filters of q so a cursor can't be reused
+// with different filters. Time bounds are excluded because relative
+// windows ("last 15 minutes") shift between page requests.
+func filterHash(q storage.Query) uint64 {
+	h := fnv.New64a()
+	write := func(s string) {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+
+	write(q.Cluster)
+	write(q.Namespace)
+	write(q.Pod)
+	write(q.Container)
+	write(q.Search)
+	write(strconv.Itoa(int(q.MinSeverity)))
+	if q.Sampled() {
+		write(strconv.FormatFloat(q.Sample, 'g', -1, 64))
+	}
+
+	keys := make([]string, 0, len(q.Attributes))
+	for k := range q.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		write(k)
+		write(q.Attributes[k])
+	}
+	for _, expr := range q.AttrExprs {
+		for _, t := range expr {
+			write(t.Key)
+			write(strconv.Itoa(int(t.Op)))
+			write(t.Value)
+		}
+		write("|")
+	}
+
+	return h.Sum64()
+}
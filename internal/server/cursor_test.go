@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	q := storage.Query{
+		Namespace:  "default",
+		Attributes: map[string]string{"app": "api"},
+		Pagination: storage.Pagination{Order: storage.OrderAsc, OrderBy: storage.OrderByTimestamp},
+	}
+	entry := storage.LogEntry{ID: 42, Timestamp: time.Unix(0, 1700000000123456789)}
+
+	token := cursorFromEntry(entry, q).encode()
+	c, err := decodeCursor(token)
+	if err != nil {
+		t.Fatalf("decodeCursor: %v", err)
+	}
+
+	next := q
+	if err := c.apply(&next); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if next.Pagination.AfterID != 42 {
+		t.Errorf("AfterID = %d, want 42", next.Pagination.AfterID)
+	}
+	if !next.Pagination.AfterTimestamp.Equal(entry.Timestamp) {
+		t.Errorf("AfterTimestamp = %v, want %v", next.Pagination.AfterTimestamp, entry.Timestamp)
+	}
+	if next.Pagination.BeforeID != 0 || !next.Pagination.BeforeTimestamp.IsZero() {
+		t.Error("ascending cursor should not set Before fields")
+	}
+}
+
+func TestCursor_Rejected(t *testing.T) {
+	base := storage.Query{
+		Namespace:  "default",
+		Attributes: map[string]string{"app": "api"},
+	}
+	token := cursorFromEntry(storage.LogEntry{ID: 7, Timestamp: time.Now()}, base).encode()
+
+	tests := []struct {
+		name   string
+		token  string
+		modify func(*storage.Query)
+	}{
+		{"garbage", "not-a-cursor!", func(q *storage.Query) {}},
+		{"truncated", token[:len(token)-4], func(q *storage.Query) {}},
+		{"different namespace", token, func(q *storage.Query) { q.Namespace = "kube-system" }},
+		{"different cluster", token, func(q *storage.Query) { q.Cluster = "east" }},
+		{"different search", token, func(q *storage.Query) { q.Search = "error" }},
+		{"different attributes", token, func(q *storage.Query) { q.Attributes = map[string]string{"app": "web"} }},
+		{"different order", token, func(q *storage.Query) { q.Pagination.Order = storage.OrderAsc }},
+		{"different order by", token, func(q *storage.Query) { q.Pagination.OrderBy = storage.OrderByTimestamp }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := base
+			tt.modify(&q)
+
+			c, err := decodeCursor(tt.token)
+			if err == nil {
+				err = c.apply(&q)
+			}
+			if err != errInvalidCursor {
+				t.Errorf("err = %v, want errInvalidCursor", err)
+			}
+		})
+	}
+
+	// Time bounds are not part of the fingerprint
+	q := base
+	q.StartTime = time.Now().Add(-time.Hour)
+	c, _ := decodeCursor(token)
+	if err := c.apply(&q); err != nil {
+		t.Errorf("cursor rejected after time bound change: %v", err)
+	}
+}
+
+func TestHandleQueryLogs_CursorPagination(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour)
+	var batch storage.LogBatch
+	for i := 0; i < 25; i++ {
+		batch = append(batch, storage.LogEntry{
+			Timestamp: base.Add(time.Duration(i) * time.Second),
+			Namespace: "ns",
+			Pod:       "pod",
+			Container: "c",
+			Message:   fmt.Sprintf("msg %d", i),
+		})
+	}
+	store.Write(ctx, batch)
+	store.Flush(ctx)
+
+	s := &HTTPServer{store: store}
+	get := func(params url.Values) (*httptest.ResponseRecorder, queryResponse) {
+		req := httptest.NewRequest(http.MethodGet, "/api/logs?"+params.Encode(), nil)
+		rec := httptest.NewRecorder()
+		s.handleQueryLogs(rec, req)
+
+		var resp queryResponse
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+		}
+		return rec, resp
+	}
+
+	params := url.Values{"namespace": {"ns"}, "limit": {"10"}}
+	seen := make(map[int64]bool)
+	pages := 0
+	for {
+		rec, resp := get(params)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("page %d: status %d", pages, rec.Code)
+		}
+		pages++
+		for _, e := range resp.Entries {
+			if seen[e.ID] {
+				t.Errorf("entry %d returned twice", e.ID)
+			}
+			seen[e.ID] = true
+		}
+		if !resp.HasMore {
+			break
+		}
+		params.Set("cursor", resp.NextCursor)
+	}
+
+	if pages != 3 {
+		t.Errorf("pages = %d, want 3", pages)
+	}
+	if len(seen) != 25 {
+		t.Errorf("saw %d entries, want 25", len(seen))
+	}
+
+	// Reusing the cursor with another filter is rejected
+	_, first := get(url.Values{"namespace": {"ns"}, "limit": {"10"}})
+	rec, _ := get(url.Values{"namespace": {"other"}, "limit": {"10"}, "cursor": {first.NextCursor}})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("mismatched cursor status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/patterns"
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+const (
+	// maxDiffScan bounds the entries read per window; windows with more
+	// entries are compared on their most recent maxDiffScan entries.
+	maxDiffScan = 50000
+
+	defaultDiffWindow   = time.Hour
+	defaultDiffMinRatio = 2.0
+	defaultDiffMinCount = 3
+	defaultDiffLimit    = 50
+)
+
+// diffWindowJSON describes one side of a diff.
+type diffWindowJSON struct {
+	Start     int64 `json:"start"` // Unix nanoseconds
+	End       int64 `json:"end"`   // Unix nanoseconds
+	Scanned   int   `json:"scanned"`
+	Truncated bool  `json:"truncated"` // More than maxDiffScan entries in the window
+	Patterns  int   `json:"patterns"`
+}
+
+// patternChangeJSON is a pattern that differs between the windows.
+type patternChangeJSON struct {
+	Kind          string  `json:"kind"` // new, missing or increased
+	Pattern       string  `json:"pattern"`
+	Example       string  `json:"example"`
+	BaselineCount int     `json:"baselineCount"`
+	CompareCount  int     `json:"compareCount"`
+	Ratio         float64 `json:"ratio,omitempty"`
+}
+
+// diffResponse is the JSON response for a window diff.
+type diffResponse struct {
+	Baseline diffWindowJSON      `json:"baseline"`
+	Compare  diffWindowJSON      `json:"compare"`
+	Changes  []patternChangeJSON `json:"changes"`
+}
+
+// handleDiff compares message patterns between a baseline and a compare
+// window, e.g. the hour before and after a deploy. Windows are given as
+// baselineStart/baselineEnd/compareStart/compareEnd (RFC3339), or as
+// at=<RFC3339>&window=<duration> for the window before vs after a point.
+// The usual filters (namespace, pod, search, ...) narrow both windows.
+func (s *HTTPServer) handleDiff(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	baseStart, baseEnd, cmpStart, cmpEnd, err := parseDiffWindows(params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts := patterns.DiffOptions{
+		BaselineWeight: baseEnd.Sub(baseStart).Seconds(),
+		CompareWeight:  cmpEnd.Sub(cmpStart).Seconds(),
+		MinRatio:       defaultDiffMinRatio,
+		MinCount:       defaultDiffMinCount,
+	}
+	if v := params.Get("minRatio"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 1 {
+			opts.MinRatio = f
+		}
+	}
+	if v := params.Get("minCount"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 1 {
+			opts.MinCount = n
+		}
+	}
+	limit := defaultDiffLimit
+	if v := params.Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 1000 {
+			limit = n
+		}
+	}
+
+	filter, err := s.parseQueryParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	baseline, baseWin, err := s.scanPatterns(r.Context(), filter, baseStart, baseEnd)
+	if err != nil {
+		slog.Error("diff query error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	compare, cmpWin, err := s.scanPatterns(r.Context(), filter, cmpStart, cmpEnd)
+	if err != nil {
+		slog.Error("diff query error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	changes := patterns.Diff(baseline, compare, opts)
+	if len(changes) > limit {
+		changes = changes[:limit]
+	}
+
+	resp := diffResponse{
+		Baseline: baseWin,
+		Compare:  cmpWin,
+		Changes:  make([]patternChangeJSON, 0, len(changes)),
+	}
+	for _, c := range changes {
+		resp.Changes = append(resp.Changes, patternChangeJSON{
+			Kind:          string(c.Kind),
+			Pattern:       c.Pattern,
+			Example:       c.Example,
+			BaselineCount: c.BaselineCount,
+			CompareCount:  c.CompareCount,
+			Ratio:         c.Ratio,
+		})
+	}
+	writeJSON(w, resp)
+}
+
+// parseDiffWindows reads the baseline and compare time ranges.
+func parseDiffWindows(params url.Values) (baseStart, baseEnd, cmpStart, cmpEnd time.Time, err error) {
+	if v := params.Get("at"); v != "" {
+		at, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return baseStart, baseEnd, cmpStart, cmpEnd, fmt.Errorf("invalid at: %v", err)
+		}
+		window := defaultDiffWindow
+		if v := params.Get("window"); v != "" {
+			window, err = time.ParseDuration(v)
+			if err != nil || window <= 0 {
+				return baseStart, baseEnd, cmpStart, cmpEnd, fmt.Errorf("invalid window %q", v)
+			}
+		}
+		return at.Add(-window), at, at, at.Add(window), nil
+	}
+
+	times := make([]time.Time, 4)
+	for i, name := range []string{"baselineStart", "baselineEnd", "compareStart", "compareEnd"} {
+		times[i], err = time.Parse(time.RFC3339, params.Get(name))
+		if err != nil {
+			return baseStart, baseEnd, cmpStart, cmpEnd, fmt.Errorf("%s is required (RFC3339) unless at is given", name)
+		}
+	}
+	baseStart, baseEnd, cmpStart, cmpEnd = times[0], times[1], times[2], times[3]
+	if !baseEnd.After(baseStart) || !cmpEnd.After(cmpStart) {
+		return baseStart, baseEnd, cmpStart, cmpEnd, fmt.Errorf("window end must be after start")
+	}
+	return baseStart, baseEnd, cmpStart, cmpEnd, nil
+}
+
+// scanPatterns counts message patterns for entries matching filter in [start, end).
+func (s *HTTPServer) scanPatterns(ctx context.Context, filter storage.Query, start, end time.Time) (patterns.Counts, diffWindowJSON, error) {
+	q := filter
+	q.StartTime = start
+	q.EndTime = end
+	q.Pagination = storage.Pagination{Limit: 1000, Order: storage.OrderDesc}
+
+	counts := patterns.Counts{}
+	win := diffWindowJSON{Start: start.UnixNano(), End: end.UnixNano()}
+
+	for {
+		result, err := s.store.Query(ctx, q)
+		if err != nil {
+			return nil, win, err
+		}
+		for _, e := range result.Entries {
+			counts.Add(e.Message)
+		}
+		win.Scanned += len(result.Entries)
+
+		if !result.HasMore || len(result.Entries) == 0 {
+			break
+		}
+		if win.Scanned >= maxDiffScan {
+			win.Truncated = true
+			break
+		}
+		q.Pagination.BeforeID = result.Entries[len(result.Entries)-1].ID
+	}
+
+	win.Patterns = len(counts)
+	return counts, win, nil
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestParseDiffWindows(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  url.Values
+		wantErr bool
+	}{
+		{"at with default window", url.Values{"at": {"2024-03-01T12:00:00Z"}}, false},
+		{"at with window", url.Values{"at": {"2024-03-01T12:00:00Z"}, "window": {"15m"}}, false},
+		{"bad window", url.Values{"at": {"2024-03-01T12:00:00Z"}, "window": {"-1h"}}, true},
+		{"explicit", url.Values{
+			"baselineStart": {"2024-03-01T10:00:00Z"}, "baselineEnd": {"2024-03-01T11:00:00Z"},
+			"compareStart": {"2024-03-01T11:00:00Z"}, "compareEnd": {"2024-03-01T12:00:00Z"},
+		}, false},
+		{"missing compare", url.Values{
+			"baselineStart": {"2024-03-01T10:00:00Z"}, "baselineEnd": {"2024-03-01T11:00:00Z"},
+		}, true},
+		{"inverted", url.Values{
+			"baselineStart": {"2024-03-01T11:00:00Z"}, "baselineEnd": {"2024-03-01T10:00:00Z"},
+			"compareStart": {"2024-03-01T11:00:00Z"}, "compareEnd": {"2024-03-01T12:00:00Z"},
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, _, err := parseDiffWindows(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseDiffWindows() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHandleDiff(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	deploy := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	var batch storage.LogBatch
+	add := func(at time.Time, format string, n int) {
+		for i := 0; i < n; i++ {
+			batch = append(batch, storage.LogEntry{
+				Timestamp: at.Add(time.Duration(i) * time.Second),
+				Namespace: "shop",
+				Pod:       "api-0",
+				Container: "app",
+				Message:   fmt.Sprintf(format, i),
+			})
+		}
+	}
+	add(deploy.Add(-30*time.Minute), "served request %d", 20)
+	add(deploy.Add(-30*time.Minute), "legacy endpoint hit by client %d", 5)
+	add(deploy.Add(10*time.Minute), "served request %d", 20)
+	add(deploy.Add(10*time.Minute), "db timeout after %dms", 8)
+	store.Write(context.Background(), batch)
+	store.Flush(context.Background())
+
+	s := &HTTPServer{store: store}
+	req := httptest.NewRequest(http.MethodGet, "/api/diff?namespace=shop&at="+deploy.Format(time.RFC3339), nil)
+	rec := httptest.NewRecorder()
+	s.handleDiff(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp diffResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	if resp.Baseline.Scanned != 25 || resp.Compare.Scanned != 28 {
+		t.Errorf("scanned = %d/%d, want 25/28", resp.Baseline.Scanned, resp.Compare.Scanned)
+	}
+	if len(resp.Changes) != 2 {
+		t.Fatalf("got %d changes %+v, want 2", len(resp.Changes), resp.Changes)
+	}
+	if c := resp.Changes[0]; c.Kind != "new" || c.Pattern != "db timeout after <num>" || c.CompareCount != 8 {
+		t.Errorf("first change = %+v, want new db timeout pattern", c)
+	}
+	if c := resp.Changes[1]; c.Kind != "missing" || c.Pattern != "legacy endpoint hit by client <num>" {
+		t.Errorf("second change = %+v, want missing legacy pattern", c)
+	}
+}
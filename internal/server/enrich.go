@@ -0,0 +1,165 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// Enricher adds data only the server has to entries before they are
+// written. Enrich modifies entries in place; it is called once per
+// batch, after quotas are applied, and must be safe for concurrent use.
+type Enricher interface {
+	Enrich(ctx context.Context, entries storage.LogBatch)
+}
+
+// NewEnrichers returns the enrichers named in cfg.Enrichers, in order.
+func NewEnrichers(cfg Config) ([]Enricher, error) {
+	var enrichers []Enricher
+	for _, name := range cfg.Enrichers {
+		switch name {
+		case "environment":
+			if len(cfg.EnvironmentTags) == 0 {
+				return nil, fmt.Errorf("enricher environment: no tags configured")
+			}
+			enrichers = append(enrichers, EnvironmentTags(cfg.EnvironmentTags))
+		case "geoip":
+			g, err := LoadGeoIP(cfg.GeoIPFile, cfg.GeoIPAttribute)
+			if err != nil {
+				return nil, fmt.Errorf("enricher geoip: %w", err)
+			}
+			enrichers = append(enrichers, g)
+		default:
+			return nil, fmt.Errorf("unknown enricher %q", name)
+		}
+	}
+	return enrichers, nil
+}
+
+// setAttr sets an attribute of e, allocating its map if needed.
+func setAttr(e *storage.LogEntry, key, value string) {
+	if e.Attributes == nil {
+		e.Attributes = make(map[string]string)
+	}
+	e.Attributes[key] = value
+}
+
+// EnvironmentTags adds fixed attributes, such as env=prod, to every
+// entry. Attributes an entry already has are kept.
+type EnvironmentTags map[string]string
+
+// Enrich implements Enricher.
+func (t EnvironmentTags) Enrich(_ context.Context, entries storage.LogBatch) {
+	for i := range entries {
+		for k, v := range t {
+			if _, ok := entries[i].Attributes[k]; !ok {
+				setAttr(&entries[i], k, v)
+			}
+		}
+	}
+}
+
+// GeoIP adds geo.country and geo.city attributes to entries whose
+// source attribute holds an IP address in its table.
+type GeoIP struct {
+	attribute string
+	bits      []int // Prefix lengths in the table, longest first
+	networks  map[netip.Prefix]geoLocation
+}
+
+type geoLocation struct {
+	country string
+	city    string
+}
+
+// LoadGeoIP reads a table of networks from a CSV file with lines of
+// "network,country[,city]", e.g. "81.2.69.0/24,GB,London". A header
+// line and lines starting with # are skipped. attribute names the entry
+// attribute holding the address to look up.
+func LoadGeoIP(path, attribute string) (*GeoIP, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	g := &GeoIP{attribute: attribute, networks: make(map[netip.Prefix]geoLocation)}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || (n == 1 && strings.HasPrefix(line, "network")) {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: want network,country[,city]", path, n)
+		}
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		loc := geoLocation{country: strings.TrimSpace(fields[1])}
+		if len(fields) > 2 {
+			loc.city = strings.TrimSpace(fields[2])
+		}
+		g.add(prefix, loc)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g *GeoIP) add(prefix netip.Prefix, loc geoLocation) {
+	prefix = prefix.Masked()
+	g.networks[prefix] = loc
+	if !slices.Contains(g.bits, prefix.Bits()) {
+		g.bits = append(g.bits, prefix.Bits())
+		slices.SortFunc(g.bits, func(a, b int) int { return b - a })
+	}
+}
+
+// lookup returns the location of the longest network containing addr.
+func (g *GeoIP) lookup(addr netip.Addr) (geoLocation, bool) {
+	addr = addr.Unmap()
+	for _, bits := range g.bits {
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			continue // Longer than the address
+		}
+		if loc, ok := g.networks[prefix]; ok {
+			return loc, true
+		}
+	}
+	return geoLocation{}, false
+}
+
+// Enrich implements Enricher.
+func (g *GeoIP) Enrich(_ context.Context, entries storage.LogBatch) {
+	for i := range entries {
+		ip, ok := entries[i].Attributes[g.attribute]
+		if !ok {
+			continue
+		}
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			continue
+		}
+		loc, ok := g.lookup(addr)
+		if !ok {
+			continue
+		}
+		if loc.country != "" {
+			setAttr(&entries[i], "geo.country", loc.country)
+		}
+		if loc.city != "" {
+			setAttr(&entries[i], "geo.city", loc.city)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/api/storagepb"
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func writeGeoIPFile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "geoip.csv")
+	data := "network,country,city\n" +
+		"# test networks\n" +
+		"81.2.0.0/16,GB\n" +
+		"81.2.69.0/24,GB,London\n" +
+		"2001:db8::/32,DE,Berlin\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestGeoIP(t *testing.T) {
+	g, err := LoadGeoIP(writeGeoIPFile(t), "client_ip")
+	if err != nil {
+		t.Fatalf("LoadGeoIP: %v", err)
+	}
+
+	tests := []struct {
+		ip          string
+		country     string
+		city        string
+		wantNoMatch bool
+	}{
+		{ip: "81.2.69.160", country: "GB", city: "London"},
+		{ip: "81.2.1.1", country: "GB"},
+		{ip: "::ffff:81.2.69.1", country: "GB", city: "London"},
+		{ip: "2001:db8::1", country: "DE", city: "Berlin"},
+		{ip: "10.0.0.1", wantNoMatch: true},
+		{ip: "not an ip", wantNoMatch: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			entries := storage.LogBatch{{Attributes: map[string]string{"client_ip": tt.ip}}}
+			g.Enrich(context.Background(), entries)
+			attrs := entries[0].Attributes
+			if tt.wantNoMatch {
+				if len(attrs) != 1 {
+					t.Errorf("attributes = %v, want unchanged", attrs)
+				}
+				return
+			}
+			if attrs["geo.country"] != tt.country || attrs["geo.city"] != tt.city {
+				t.Errorf("geo = %q, %q, want %q, %q", attrs["geo.country"], attrs["geo.city"], tt.country, tt.city)
+			}
+		})
+	}
+}
+
+func TestNewEnrichers(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Enrichers = []string{"nope"}
+	if _, err := NewEnrichers(cfg); err == nil {
+		t.Error("unknown enricher accepted")
+	}
+	cfg.Enrichers = []string{"geoip"}
+	if _, err := NewEnrichers(cfg); err == nil {
+		t.Error("geoip without a file accepted")
+	}
+	cfg.Enrichers = []string{"environment", "geoip"}
+	cfg.EnvironmentTags = map[string]string{"env": "prod"}
+	cfg.GeoIPFile = writeGeoIPFile(t)
+	enrichers, err := NewEnrichers(cfg)
+	if err != nil || len(enrichers) != 2 {
+		t.Fatalf("NewEnrichers = %v, %v", enrichers, err)
+	}
+}
+
+func TestServer_Enrichers(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:", WriteBufferSize: 1})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	g, err := LoadGeoIP(writeGeoIPFile(t), "client_ip")
+	if err != nil {
+		t.Fatalf("LoadGeoIP: %v", err)
+	}
+	srv := New(store)
+	srv.SetEnrichers(EnvironmentTags{"env": "prod", "region": "eu"}, g)
+
+	ctx := context.Background()
+	_, err = srv.Write(ctx, &storagepb.WriteRequest{Entries: []*storagepb.LogEntry{
+		{TimestampNanos: time.Now().UnixNano(), Namespace: "default", Pod: "a", Container: "app", Message: "GET /",
+			Attributes: map[string]string{"client_ip": "81.2.69.160", "region": "us"}},
+		{TimestampNanos: time.Now().UnixNano(), Namespace: "default", Pod: "a", Container: "app", Message: "tick"},
+	}})
+	if err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	result, err := store.Query(ctx, storage.Query{Pagination: storage.Pagination{Order: storage.OrderAsc}})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(result.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(result.Entries))
+	}
+	first, second := result.Entries[0].Attributes, result.Entries[1].Attributes
+	if first["env"] != "prod" || first["region"] != "us" || first["geo.city"] != "London" {
+		t.Errorf("first entry attributes = %v", first)
+	}
+	if second["env"] != "prod" || second["region"] != "eu" {
+		t.Errorf("second entry attributes = %v", second)
+	}
+}
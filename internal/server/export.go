@@ -0,0 +1,323 @@
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// exportPageSize is the number of entries fetched per store query while
+// exporting.
+const exportPageSize = 1000
+
+// exportColumns is the CSV header row. Attributes are one JSON object.
+var exportColumns = []string{"id", "timestamp", "cluster", "namespace", "pod", "container", "severity", "message", "attributes"}
+
+// exportFormat describes one format=... of the export endpoint.
+type exportFormat struct {
+	contentType string
+	extension   string
+	newWriter   func(w http.ResponseWriter) exportWriter
+}
+
+var exportFormats = map[string]exportFormat{
+	"csv": {"text/csv; charset=utf-8", "csv", func(w http.ResponseWriter) exportWriter {
+		return newCSVExport(w)
+	}},
+	"ndjson": {"application/x-ndjson", "ndjson", func(w http.ResponseWriter) exportWriter {
+		return &ndjsonExport{w: w, enc: json.NewEncoder(w)}
+	}},
+	"text": {"text/plain; charset=utf-8", "log", func(w http.ResponseWriter) exportWriter {
+		return &lineExport{w: w, format: appendText}
+	}},
+	"logfmt": {"text/plain; charset=utf-8", "logfmt", func(w http.ResponseWriter) exportWriter {
+		return &lineExport{w: w, format: appendLogfmt}
+	}},
+}
+
+// exportWriter writes entries in one export format.
+type exportWriter interface {
+	write(e storage.LogEntry) error
+	// flush sends buffered output to the client.
+	flush() error
+}
+
+// handleExportLogs streams every entry matching the query parameters in
+// one of exportFormats, paging through the store so clients needn't. The result is the matching entries stored
+// when the export started. The limit and cursor parameters are ignored.
+func (s *HTTPServer) handleExportLogs(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		name = "csv"
+	}
+	format, ok := exportFormats[name]
+	if !ok {
+		http.Error(w, "Invalid format: want csv, ndjson, text or logfmt", http.StatusBadRequest)
+		return
+	}
+
+	keepWriting(w)
+	q, err := s.parseQueryParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.Pagination.Limit = exportPageSize
+
+	// Entries stored while the export runs are left out, so pages don't
+	// skip or repeat entries as the result set changes under them. This
+	// also happens first, so a failing query still gets an error status.
+	snapshot, err := s.latestID(r)
+	var result *storage.QueryResult
+	if err == nil {
+		if snapshot == 0 {
+			result = &storage.QueryResult{} // Nothing stored yet
+		} else {
+			q.Pagination.MaxID = snapshot
+			result, err = s.exportPage(r, q)
+		}
+	}
+	if err != nil {
+		slog.Error("export query error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	out := format.newWriter(w)
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="logs.`+format.extension+`"`)
+	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx buffering
+
+	for {
+		for _, e := range result.Entries {
+			if err := out.write(e); err != nil {
+				slog.Debug("export write error", "error", err)
+				return
+			}
+		}
+		if err := out.flush(); err != nil {
+			slog.Debug("export write error", "error", err)
+			return
+		}
+		if !result.HasMore || len(result.Entries) == 0 {
+			return
+		}
+
+		last := result.Entries[len(result.Entries)-1]
+		if err := cursorFromEntry(last, q).apply(&q); err != nil {
+			slog.Error("export cursor error", "error", err)
+			return
+		}
+		if result, err = s.exportPage(r, q); err != nil {
+			// Headers are sent; a truncated body is all we can signal.
+			slog.Error("export query error", "error", err)
+			return
+		}
+	}
+}
+
+// latestID returns the ID of the newest stored entry, or 0 if there is
+// none.
+func (s *HTTPServer) latestID(r *http.Request) (int64, error) {
+	result, err := s.store.Query(r.Context(), storage.Query{Pagination: storage.Pagination{Limit: 1}})
+	if err != nil || len(result.Entries) == 0 {
+		return 0, err
+	}
+	return result.Entries[0].ID, nil
+}
+
+func (s *HTTPServer) exportPage(r *http.Request, q storage.Query) (*storage.QueryResult, error) {
+	start := time.Now()
+	result, err := s.store.Query(r.Context(), q)
+	s.queryDuration.Observe(since(start))
+	return result, err
+}
+
+// csvExport writes entries as CSV rows under a header of exportColumns.
+type csvExport struct {
+	w      http.ResponseWriter
+	cw     *csv.Writer
+	header bool
+}
+
+func newCSVExport(w http.ResponseWriter) *csvExport {
+	return &csvExport{w: w, cw: csv.NewWriter(w)}
+}
+
+// writeHeader writes the header row once.
+func (c *csvExport) writeHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+	return c.cw.Write(exportColumns)
+}
+
+func (c *csvExport) write(e storage.LogEntry) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	attrs := ""
+	if len(e.Attributes) > 0 {
+		b, err := json.Marshal(e.Attributes)
+		if err != nil {
+			return err
+		}
+		attrs = string(b)
+	}
+	return c.cw.Write([]string{
+		strconv.FormatInt(e.ID, 10),
+		e.Timestamp.UTC().Format(time.RFC3339Nano),
+		e.Cluster,
+		e.Namespace,
+		e.Pod,
+		e.Container,
+		e.Severity.String(),
+		e.Message,
+		attrs,
+	})
+}
+
+func (c *csvExport) flush() error {
+	// Sent even when nothing matched
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.cw.Flush()
+	if err := c.cw.Error(); err != nil {
+		return err
+	}
+	return flushResponse(c.w)
+}
+
+// ndjsonExport writes entries as one JSON object per line, in the form
+// the query API returns them.
+type ndjsonExport struct {
+	w   http.ResponseWriter
+	enc *json.Encoder
+}
+
+func (n *ndjsonExport) write(e storage.LogEntry) error {
+	return n.enc.Encode(logEntryJSON{
+		ID:        e.ID,
+		Timestamp: e.Timestamp.UnixNano(),
+		Cluster:   e.Cluster,
+		Namespace: e.Namespace,
+		Pod:       e.Pod,
+		Container: e.Container,
+		Severity:  int(e.Severity),
+		Message:   e.Message,
+		Attrs:     e.Attributes,
+	})
+}
+
+func (n *ndjsonExport) flush() error {
+	return flushResponse(n.w)
+}
+
+// lineExport writes each entry as one line built by format.
+type lineExport struct {
+	w      http.ResponseWriter
+	format func(buf []byte, e storage.LogEntry) []byte
+	buf    []byte
+}
+
+func (l *lineExport) write(e storage.LogEntry) error {
+	l.buf = append(l.format(l.buf[:0], e), '\n')
+	_, err := l.w.Write(l.buf)
+	return err
+}
+
+func (l *lineExport) flush() error {
+	return flushResponse(l.w)
+}
+
+// appendText formats e like a container log line prefixed with its
+// source, e.g. "2024-01-01T12:00:00Z shop/api-0 connection refused", for
+// grep-style tools. Multi-line messages are kept as they are.
+func appendText(buf []byte, e storage.LogEntry) []byte {
+	buf = e.Timestamp.UTC().AppendFormat(buf, time.RFC3339Nano)
+	buf = append(buf, ' ')
+	buf = append(buf, e.Namespace...)
+	buf = append(buf, '/')
+	buf = append(buf, e.Pod...)
+	buf = append(buf, ' ')
+	return append(buf, e.Message...)
+}
+
+// appendLogfmt formats e as logfmt: ts, level, the source fields, msg,
+// then the attributes sorted by key.
+func appendLogfmt(buf []byte, e storage.LogEntry) []byte {
+	buf = append(buf, "ts="...)
+	buf = e.Timestamp.UTC().AppendFormat(buf, time.RFC3339Nano)
+	buf = appendLogfmtPair(buf, "level", strings.ToLower(e.Severity.String()))
+	if e.Cluster != "" {
+		buf = appendLogfmtPair(buf, "cluster", e.Cluster)
+	}
+	buf = appendLogfmtPair(buf, "namespace", e.Namespace)
+	buf = appendLogfmtPair(buf, "pod", e.Pod)
+	buf = appendLogfmtPair(buf, "container", e.Container)
+	buf = appendLogfmtPair(buf, "msg", e.Message)
+	for _, k := range slices.Sorted(maps.Keys(e.Attributes)) {
+		buf = appendLogfmtPair(buf, logfmtKey(k), e.Attributes[k])
+	}
+	return buf
+}
+
+// appendLogfmtPair appends " key=value", quoting value if needed.
+func appendLogfmtPair(buf []byte, key, value string) []byte {
+	buf = append(buf, ' ')
+	buf = append(buf, key...)
+	buf = append(buf, '=')
+	if value != "" && !strings.ContainsFunc(value, func(r rune) bool {
+		return r <= ' ' || r == '=' || r == '"' || r == '\\' || r == 0x7f
+	}) {
+		return append(buf, value...)
+	}
+
+	// Escapes the collector's logfmt parser understands
+	buf = append(buf, '"')
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '"', '\\':
+			buf = append(buf, '\\', c)
+		case '\n':
+			buf = append(buf, '\\', 'n')
+		case '\r':
+			buf = append(buf, '\\', 'r')
+		case '\t':
+			buf = append(buf, '\\', 't')
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return append(buf, '"')
+}
+
+// logfmtKey replaces characters not allowed in logfmt keys with '_'.
+func logfmtKey(k string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, k)
+}
+
+// flushResponse sends buffered response data as a chunk, if w supports it.
+func flushResponse(w http.ResponseWriter) error {
+	err := http.NewResponseController(w).Flush()
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
+	return err
+}
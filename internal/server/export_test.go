@@ -0,0 +1,220 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestHandleExportLogs(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	// More than one page, so the export has to follow the cursor
+	const n = exportPageSize + 250
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	batch := make(storage.LogBatch, 0, n+1)
+	for i := range n {
+		batch = append(batch, storage.LogEntry{
+			Timestamp: base.Add(time.Duration(i) * time.Millisecond),
+			Namespace: "app",
+			Pod:       "pod",
+			Container: "c",
+			Severity:  storage.SeverityInfo,
+			Message:   fmt.Sprintf("line %d", i),
+		})
+	}
+	batch = append(batch, storage.LogEntry{
+		Timestamp:  base,
+		Namespace:  "other",
+		Pod:        "pod",
+		Container:  "c",
+		Severity:   storage.SeverityError,
+		Message:    "with \"quotes\", commas\nand newlines",
+		Attributes: map[string]string{"k": "v"},
+	})
+	if _, err := store.Write(ctx, batch); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	store.Flush(ctx)
+
+	s := &HTTPServer{store: store}
+	export := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/logs/export?"+query, nil)
+		rec := httptest.NewRecorder()
+		s.handleExportLogs(rec, req)
+		return rec
+	}
+
+	t.Run("ndjson pages through all entries", func(t *testing.T) {
+		rec := export("format=ndjson&namespace=app&order=asc&limit=10")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		scanner := bufio.NewScanner(rec.Body)
+		var i int
+		for ; scanner.Scan(); i++ {
+			var e logEntryJSON
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				t.Fatalf("line %d: %v", i, err)
+			}
+			if want := fmt.Sprintf("line %d", i); e.Message != want {
+				t.Fatalf("line %d message = %q, want %q", i, e.Message, want)
+			}
+		}
+		if i != n {
+			t.Errorf("exported %d entries, want %d", i, n)
+		}
+	})
+
+	t.Run("entries stored during the export are left out", func(t *testing.T) {
+		// Each page query stores a newer entry and a late one that sorts first
+		var run string
+		s := &HTTPServer{store: &writeOnQueryStore{Store: store, write: func() {
+			store.Write(ctx, storage.LogBatch{
+				{Timestamp: time.Now(), Namespace: "app", Pod: "pod", Container: "c", Message: "new " + run},
+				{Timestamp: base.Add(-time.Hour), Namespace: "app", Pod: "pod", Container: "c", Message: "late " + run},
+			})
+			store.Flush(ctx)
+		}}}
+		for _, run = range []string{"order=asc", "order=asc&orderBy=timestamp", "orderBy=timestamp"} {
+			req := httptest.NewRequest(http.MethodGet, "/api/logs/export?format=ndjson&namespace=app&"+run, nil)
+			rec := httptest.NewRecorder()
+			s.handleExportLogs(rec, req)
+
+			scanner := bufio.NewScanner(rec.Body)
+			var lines int
+			for scanner.Scan() {
+				var e logEntryJSON
+				if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+					t.Fatalf("%s: %v", run, err)
+				}
+				if e.Message == "new "+run || e.Message == "late "+run {
+					t.Fatalf("%s: exported %q, stored after the export started", run, e.Message)
+				}
+				if strings.HasPrefix(e.Message, "line ") {
+					lines++
+				}
+			}
+			if lines != n {
+				t.Errorf("%s: exported %d lines, want %d", run, lines, n)
+			}
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		rec := export("format=csv&namespace=other")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", rec.Code)
+		}
+		records, err := csv.NewReader(rec.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("parse csv: %v", err)
+		}
+		if len(records) != 2 {
+			t.Fatalf("got %d records, want header and 1 row", len(records))
+		}
+		if records[0][0] != "id" {
+			t.Errorf("header = %v", records[0])
+		}
+		row := records[1]
+		if row[1] != "2024-01-01T00:00:00Z" || row[3] != "other" || row[6] != "ERROR" {
+			t.Errorf("row = %v", row)
+		}
+		if row[7] != "with \"quotes\", commas\nand newlines" {
+			t.Errorf("message = %q", row[7])
+		}
+		if row[8] != `{"k":"v"}` {
+			t.Errorf("attributes = %q", row[8])
+		}
+	})
+
+	t.Run("csv with no matches has a header", func(t *testing.T) {
+		rec := export("namespace=missing")
+		records, err := csv.NewReader(rec.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("parse csv: %v", err)
+		}
+		if len(records) != 1 || len(records[0]) != len(exportColumns) {
+			t.Errorf("records = %v, want header only", records)
+		}
+	})
+
+	t.Run("text and logfmt", func(t *testing.T) {
+		tests := []struct {
+			format string
+			want   string
+		}{
+			{"text", "2024-01-01T00:00:00Z other/pod with \"quotes\", commas\nand newlines\n"},
+			{"logfmt", `ts=2024-01-01T00:00:00Z level=error namespace=other pod=pod container=c msg="with \"quotes\", commas\nand newlines" k=v` + "\n"},
+		}
+		for _, tt := range tests {
+			rec := export("namespace=other&format=" + tt.format)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s: status = %d, want 200", tt.format, rec.Code)
+			}
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("%s export = %q, want %q", tt.format, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if rec := export("format=xml"); rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", rec.Code)
+		}
+	})
+}
+
+// writeOnQueryStore calls write after every query, like a store taking
+// writes while an export pages through it.
+type writeOnQueryStore struct {
+	storage.Store
+	write func()
+}
+
+func (s *writeOnQueryStore) Query(ctx context.Context, q storage.Query) (*storage.QueryResult, error) {
+	result, err := s.Store.Query(ctx, q)
+	s.write()
+	return result, err
+}
+
+func TestAppendLogfmt(t *testing.T) {
+	e := storage.LogEntry{
+		Timestamp: time.Date(2024, 1, 1, 12, 0, 0, 500, time.UTC),
+		Cluster:   "east",
+		Namespace: "shop",
+		Pod:       "api-0",
+		Container: "app",
+		Severity:  storage.SeverityWarn,
+		Message:   "slow",
+		Attributes: map[string]string{
+			"user":       "bob smith",
+			"label.app":  "api",
+			"weird key=": "",
+			"path":       `C:\tmp`,
+		},
+	}
+	want := `ts=2024-01-01T12:00:00.0000005Z level=warn cluster=east namespace=shop pod=api-0 container=app msg=slow ` +
+		`label.app=api path="C:\\tmp" user="bob smith" weird_key_=""`
+	if got := string(appendLogfmt(nil, e)); got != want {
+		t.Errorf("appendLogfmt() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+const (
+	// defaultReportInterval is assumed for collectors that don't say
+	// when they'll report next.
+	defaultReportInterval = 30 * time.Second
+
+	// staleReports is how many report intervals may pass without a
+	// report before a collector is stale.
+	staleReports = 3
+
+	// fleetForget is how long a collector that stopped reporting is
+	// still listed, so collectors of removed nodes eventually go away.
+	fleetForget = 24 * time.Hour
+)
+
+// Collector health, from the latest report.
+const (
+	healthHealthy  = "healthy"
+	healthDegraded = "degraded" // Writes are failing or waiting for retry
+	healthStale    = "stale"    // No report for several intervals
+)
+
+// Fleet keeps the latest status report of every collector in memory.
+// It's shared by the gRPC server, which receives reports, and the HTTP
+// server, which lists them.
+type Fleet struct {
+	mu      sync.Mutex
+	reports map[fleetKey]collectorReport
+}
+
+type fleetKey struct {
+	cluster string
+	node    string
+}
+
+type collectorReport struct {
+	storage.CollectorStatus
+	reportedAt time.Time
+}
+
+// NewFleet creates an empty Fleet.
+func NewFleet() *Fleet {
+	return &Fleet{reports: make(map[fleetKey]collectorReport)}
+}
+
+// Report records the status of a collector, replacing its previous one.
+func (f *Fleet) Report(s storage.CollectorStatus, now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reports[fleetKey{s.Cluster, s.Node}] = collectorReport{CollectorStatus: s, reportedAt: now}
+}
+
+// collectors returns the latest reports sorted by cluster and node,
+// forgetting collectors that haven't reported for fleetForget.
+func (f *Fleet) collectors(now time.Time) []collectorReport {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reports := make([]collectorReport, 0, len(f.reports))
+	for k, r := range f.reports {
+		if now.Sub(r.reportedAt) > fleetForget {
+			delete(f.reports, k)
+			continue
+		}
+		reports = append(reports, r)
+	}
+	slices.SortFunc(reports, func(a, b collectorReport) int {
+		return cmp.Or(cmp.Compare(a.Cluster, b.Cluster), cmp.Compare(a.Node, b.Node))
+	})
+	return reports
+}
+
+// health classifies a collector by its latest report.
+func (r collectorReport) health(now time.Time) string {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = defaultReportInterval
+	}
+	switch {
+	case now.Sub(r.reportedAt) > staleReports*interval:
+		return healthStale
+	case r.CircuitOpen || r.RetryQueueBatches > 0:
+		return healthDegraded
+	default:
+		return healthHealthy
+	}
+}
+
+// collectorJSON is the JSON representation of a collector's status.
+type collectorJSON struct {
+	Node              string `json:"node"`
+	Cluster           string `json:"cluster,omitempty"`
+	Health            string `json:"health"`
+	ReportedAt        int64  `json:"reportedAt"` // Unix nanoseconds
+	StartedAt         int64  `json:"startedAt"`  // Unix nanoseconds
+	ActiveStreams     int    `json:"activeStreams"`
+	CatchingUpStreams int    `json:"catchingUpStreams"`
+	LinesRead         int64  `json:"linesRead"`
+	StreamErrors      int64  `json:"streamErrors"`
+	EntriesWritten    int64  `json:"entriesWritten"`
+	WriteErrors       int64  `json:"writeErrors"`
+	BufferedEntries   int    `json:"bufferedEntries"`
+	RetryQueueBatches int    `json:"retryQueueBatches"`
+	CircuitOpen       bool   `json:"circuitOpen"`
+}
+
+// handleListCollectors returns the latest status of every collector
+// that reported in the last day, with a health summary.
+func (s *HTTPServer) handleListCollectors(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	summary := map[string]int{healthHealthy: 0, healthDegraded: 0, healthStale: 0}
+	collectors := []collectorJSON{}
+
+	if s.fleet != nil {
+		for _, c := range s.fleet.collectors(now) {
+			health := c.health(now)
+			summary[health]++
+			collectors = append(collectors, collectorJSON{
+				Node:              c.Node,
+				Cluster:           c.Cluster,
+				Health:            health,
+				ReportedAt:        c.reportedAt.UnixNano(),
+				StartedAt:         c.StartedAt.UnixNano(),
+				ActiveStreams:     c.ActiveStreams,
+				CatchingUpStreams: c.CatchingUpStreams,
+				LinesRead:         c.LinesRead,
+				StreamErrors:      c.StreamErrors,
+				EntriesWritten:    c.EntriesWritten,
+				WriteErrors:       c.WriteErrors,
+				BufferedEntries:   c.BufferedEntries,
+				RetryQueueBatches: c.RetryQueueBatches,
+				CircuitOpen:       c.CircuitOpen,
+			})
+		}
+	}
+
+	writeJSON(w, map[string]any{"collectors": collectors, "summary": summary})
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kubelogs/kubelogs/api/storagepb"
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/remote"
+)
+
+func TestReportCollectorStatus(t *testing.T) {
+	fleet := NewFleet()
+	srv := New(&blockingStore{})
+	srv.SetFleet(fleet)
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	storagepb.RegisterStorageServiceServer(grpcServer, srv)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	client, err := remote.NewClient(lis.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	started := time.Now().Add(-time.Hour)
+	reports := []storage.CollectorStatus{
+		{Node: "node-b", Cluster: "prod", StartedAt: started, Interval: time.Minute, ActiveStreams: 12, LinesRead: 5000, EntriesWritten: 4990},
+		{Node: "node-a", Cluster: "prod", StartedAt: started, Interval: time.Minute, RetryQueueBatches: 3, WriteErrors: 7},
+	}
+	for _, r := range reports {
+		if err := client.ReportStatus(ctx, r); err != nil {
+			t.Fatalf("ReportStatus(%s): %v", r.Node, err)
+		}
+	}
+	if err := client.ReportStatus(ctx, storage.CollectorStatus{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("ReportStatus without node: code = %v, want InvalidArgument", status.Code(err))
+	}
+
+	// A collector that stopped reporting a while ago
+	fleet.Report(storage.CollectorStatus{Node: "node-c", Cluster: "prod", Interval: time.Minute}, time.Now().Add(-10*time.Minute))
+	// and one long gone
+	fleet.Report(storage.CollectorStatus{Node: "node-d", Cluster: "prod"}, time.Now().Add(-48*time.Hour))
+
+	h := &HTTPServer{fleet: fleet}
+	rec := httptest.NewRecorder()
+	h.handleListCollectors(rec, httptest.NewRequest("GET", "/api/collectors", nil))
+
+	var resp struct {
+		Collectors []collectorJSON `json:"collectors"`
+		Summary    map[string]int  `json:"summary"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	want := []struct {
+		node   string
+		health string
+	}{
+		{"node-a", healthDegraded},
+		{"node-b", healthHealthy},
+		{"node-c", healthStale},
+	}
+	if len(resp.Collectors) != len(want) {
+		t.Fatalf("got %d collectors, want %d: %+v", len(resp.Collectors), len(want), resp.Collectors)
+	}
+	for i, w := range want {
+		if got := resp.Collectors[i]; got.Node != w.node || got.Health != w.health {
+			t.Errorf("collector %d = %s (%s), want %s (%s)", i, got.Node, got.Health, w.node, w.health)
+		}
+	}
+	if got := resp.Collectors[1]; got.ActiveStreams != 12 || got.LinesRead != 5000 || got.StartedAt != started.UnixNano() {
+		t.Errorf("node-b = %+v", got)
+	}
+	if resp.Summary[healthHealthy] != 1 || resp.Summary[healthDegraded] != 1 || resp.Summary[healthStale] != 1 {
+		t.Errorf("summary = %v", resp.Summary)
+	}
+}
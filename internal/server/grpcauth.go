@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// healthServicePrefix starts the methods of the gRPC health service,
+// which Kubernetes probes call without credentials.
+const healthServicePrefix = "/grpc.health.v1.Health/"
+
+// adminServicePrefix starts the methods of the AdminService, which only
+// the admin token may call.
+const adminServicePrefix = "/kubelogs.storage.v1.AdminService/"
+
+// TokenAuth rejects gRPC calls that don't carry a known token as
+// "authorization: Bearer <token>" metadata with UNAUTHENTICATED. Tokens
+// are either shared by every collector or issued to one collector each,
+// so a single collector's credential can be revoked. Calls to the
+// AdminService need the admin token and fail with PERMISSION_DENIED
+// with any other.
+type TokenAuth struct {
+	tokens [][]byte
+	admin  []byte // nil if the AdminService can't be called
+}
+
+// NewTokenAuth accepts shared (if not empty), the tokens in collectors,
+// keyed by collector name, and admin (if not empty), which is also
+// accepted for every other call. It returns nil if there are no tokens,
+// meaning calls aren't authenticated.
+func NewTokenAuth(shared string, collectors map[string]string, admin string) *TokenAuth {
+	a := &TokenAuth{}
+	if admin != "" {
+		a.admin = []byte(admin)
+		a.tokens = append(a.tokens, a.admin)
+	}
+	if shared != "" {
+		a.tokens = append(a.tokens, []byte(shared))
+	}
+	for _, token := range collectors {
+		if token != "" {
+			a.tokens = append(a.tokens, []byte(token))
+		}
+	}
+	if len(a.tokens) == 0 {
+		return nil
+	}
+	return a
+}
+
+// UnaryInterceptor authenticates unary calls.
+func (a *TokenAuth) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := a.authenticate(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor authenticates streaming calls such as Tail.
+func (a *TokenAuth) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := a.authenticate(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// authenticate checks the token of a call to method.
+func (a *TokenAuth) authenticate(ctx context.Context, method string) error {
+	if strings.HasPrefix(method, healthServicePrefix) {
+		return nil
+	}
+
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, v := range md.Get("authorization") {
+			if t, ok := strings.CutPrefix(v, "Bearer "); ok {
+				token = t
+				break
+			}
+		}
+	}
+	if token == "" {
+		a.reject(ctx, method, "missing token")
+		return status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+	if !a.match(token) {
+		a.reject(ctx, method, "unknown token")
+		return status.Error(codes.Unauthenticated, "invalid token")
+	}
+	if strings.HasPrefix(method, adminServicePrefix) &&
+		(a.admin == nil || subtle.ConstantTimeCompare(a.admin, []byte(token)) != 1) {
+		a.reject(ctx, method, "not the admin token")
+		return status.Error(codes.PermissionDenied, "admin token required")
+	}
+	return nil
+}
+
+// match reports whether token is accepted. Every token is compared in
+// constant time, so timing doesn't reveal which one is close.
+func (a *TokenAuth) match(token string) bool {
+	var ok bool
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare(t, []byte(token)) == 1 {
+			ok = true
+		}
+	}
+	return ok
+}
+
+// reject logs a refused call.
+func (a *TokenAuth) reject(ctx context.Context, method, reason string) {
+	var addr string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr = p.Addr.String()
+	}
+	slog.Warn("rejected gRPC call", "method", method, "peer", addr, "reason", reason)
+}
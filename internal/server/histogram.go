@@ -0,0 +1,176 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+const (
+	// defaultHistogramRange is the time range counted when the request
+	// has no startTime.
+	defaultHistogramRange = time.Hour
+
+	// defaultHistogramBuckets is the number of buckets the time range is
+	// split into when the request has no interval.
+	defaultHistogramBuckets = 60
+
+	// maxHistogramBuckets caps the buckets one request may return.
+	maxHistogramBuckets = 1000
+)
+
+// histogramResponse is the JSON response for volume histograms.
+type histogramResponse struct {
+	Interval int64                 `json:"interval"` // Bucket width in milliseconds
+	Buckets  []histogramBucketJSON `json:"buckets"`
+}
+
+// histogramBucketJSON is the volume of one bucket.
+type histogramBucketJSON struct {
+	Timestamp int64                            `json:"timestamp"` // Bucket start, Unix nanoseconds
+	Counts    [storage.SeverityFatal + 1]int64 `json:"counts"`    // Indexed by severity
+	Total     int64                            `json:"total"`
+}
+
+// handleHistogram counts the entries matching the query parameters per
+// severity over time, for the log volume chart. The range defaults to
+// the hour before endTime (or now) and is split into interval-wide
+// buckets (a Go duration), 60 by default. Buckets are aligned to the
+// Unix epoch, or with tz (an IANA time zone) to local midnight, so daily
+// buckets follow the operator's days. Every bucket in the range is
+// returned, including empty ones.
+func (s *HTTPServer) handleHistogram(w http.ResponseWriter, r *http.Request) {
+	agg, ok := s.store.(storage.Aggregator)
+	if !ok {
+		http.Error(w, "Not supported", http.StatusNotImplemented)
+		return
+	}
+
+	q, err := s.parseQueryParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.EndTime.IsZero() {
+		q.EndTime = time.Now()
+	}
+	if q.StartTime.IsZero() {
+		q.StartTime = q.EndTime.Add(-defaultHistogramRange)
+	}
+	span := q.EndTime.Sub(q.StartTime)
+	if span <= 0 {
+		http.Error(w, "startTime must be before endTime", http.StatusBadRequest)
+		return
+	}
+
+	interval := max((span / defaultHistogramBuckets).Truncate(time.Second), time.Second)
+	if v := r.URL.Query().Get("interval"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, fmt.Sprintf("invalid interval %q", v), http.StatusBadRequest)
+			return
+		}
+		interval = d
+	}
+	loc, err := parseTimezone(r.URL.Query().Get("tz"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	starts := histogramStarts(q.StartTime, q.EndTime, interval, loc)
+	if len(starts) > maxHistogramBuckets {
+		http.Error(w, fmt.Sprintf("interval %v gives more than %d buckets", interval, maxHistogramBuckets), http.StatusBadRequest)
+		return
+	}
+
+	// Local buckets are uneven across DST changes and needn't be aligned
+	// to the epoch, so count in the largest width that divides every
+	// bucket start and add those up
+	width := int64(interval)
+	for _, ts := range starts {
+		width = gcd(width, ts)
+	}
+
+	start := time.Now()
+	buckets, err := agg.Histogram(r.Context(), q, time.Duration(width))
+	s.queryDuration.Observe(since(start))
+	if err != nil {
+		slog.Error("histogram error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := histogramResponse{
+		Interval: interval.Milliseconds(),
+		Buckets:  make([]histogramBucketJSON, len(starts)),
+	}
+	for i, ts := range starts {
+		resp.Buckets[i].Timestamp = ts
+	}
+	for _, b := range buckets {
+		ts := b.Start.UnixNano()
+		i := sort.Search(len(starts), func(i int) bool { return starts[i] > ts }) - 1
+		if i < 0 || b.Severity > storage.SeverityFatal {
+			continue
+		}
+		resp.Buckets[i].Counts[b.Severity] += b.Count
+		resp.Buckets[i].Total += b.Count
+	}
+	writeJSON(w, resp)
+}
+
+// histogramStarts returns the start (Unix nanoseconds) of every bucket
+// overlapping [start, end), stopping after maxHistogramBuckets+1.
+//
+// Without a location buckets are interval-wide and aligned to the epoch.
+// With one they restart at every local midnight: intervals of whole days
+// step by calendar day, so a bucket can be 23 or 25 hours long, and
+// shorter intervals are laid out from midnight, the last one of a day
+// ending early if the interval doesn't divide the day.
+func histogramStarts(start, end time.Time, interval time.Duration, loc *time.Location) []int64 {
+	var starts []int64
+	if loc == nil {
+		w := int64(interval)
+		first := start.UnixNano() / w * w
+		for ts := first; ts < end.UnixNano() && len(starts) <= maxHistogramBuckets; ts += w {
+			starts = append(starts, ts)
+		}
+		return starts
+	}
+
+	day := localMidnight(start, loc)
+	if interval%(24*time.Hour) == 0 {
+		days := int(interval / (24 * time.Hour))
+		for t := day; t.Before(end) && len(starts) <= maxHistogramBuckets; t = t.AddDate(0, 0, days) {
+			if next := t.AddDate(0, 0, days); next.After(start) {
+				starts = append(starts, t.UnixNano())
+			}
+		}
+		return starts
+	}
+	for day.Before(end) && len(starts) <= maxHistogramBuckets {
+		next := day.AddDate(0, 0, 1)
+		for t := day; t.Before(next) && t.Before(end) && len(starts) <= maxHistogramBuckets; t = t.Add(interval) {
+			if t.Add(interval).After(start) && next.After(start) {
+				starts = append(starts, t.UnixNano())
+			}
+		}
+		day = next
+	}
+	return starts
+}
+
+// gcd returns the greatest common divisor of a and b.
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	if a < 0 {
+		return -a
+	}
+	return a
+}
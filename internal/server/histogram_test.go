@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestHandleHistogram(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.Write(ctx, storage.LogBatch{
+		{Timestamp: base.Add(30 * time.Second), Namespace: "app", Pod: "p", Container: "c", Severity: storage.SeverityInfo, Message: "a"},
+		{Timestamp: base.Add(40 * time.Second), Namespace: "app", Pod: "p", Container: "c", Severity: storage.SeverityError, Message: "b"},
+		{Timestamp: base.Add(3 * time.Minute), Namespace: "app", Pod: "p", Container: "c", Severity: storage.SeverityInfo, Message: "c"},
+		{Timestamp: base.Add(3 * time.Minute), Namespace: "other", Pod: "p", Container: "c", Severity: storage.SeverityInfo, Message: "d"},
+		// 23:30 and 00:30 in Berlin
+		{Timestamp: base.Add(10*time.Hour + 30*time.Minute), Namespace: "tz", Pod: "p", Container: "c", Message: "e"},
+		{Timestamp: base.Add(11*time.Hour + 30*time.Minute), Namespace: "tz", Pod: "p", Container: "c", Message: "f"},
+	})
+	store.Flush(ctx)
+
+	s := &HTTPServer{store: store}
+	histogram := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/logs/histogram?"+query, nil)
+		rec := httptest.NewRecorder()
+		s.handleHistogram(rec, req)
+		return rec
+	}
+
+	rec := histogram("namespace=app&startTime=2024-01-01T12:00:00Z&endTime=2024-01-01T12:05:00Z&interval=1m")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var resp histogramResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Interval != time.Minute.Milliseconds() {
+		t.Errorf("interval = %d, want %d", resp.Interval, time.Minute.Milliseconds())
+	}
+	wantTotals := []int64{2, 0, 0, 1, 0}
+	if len(resp.Buckets) != len(wantTotals) {
+		t.Fatalf("got %d buckets, want %d: %+v", len(resp.Buckets), len(wantTotals), resp.Buckets)
+	}
+	for i, b := range resp.Buckets {
+		if want := base.Add(time.Duration(i) * time.Minute).UnixNano(); b.Timestamp != want {
+			t.Errorf("bucket %d timestamp = %d, want %d", i, b.Timestamp, want)
+		}
+		if b.Total != wantTotals[i] {
+			t.Errorf("bucket %d total = %d, want %d", i, b.Total, wantTotals[i])
+		}
+	}
+	if c := resp.Buckets[0].Counts; c[storage.SeverityInfo] != 1 || c[storage.SeverityError] != 1 {
+		t.Errorf("bucket 0 counts = %v", c)
+	}
+
+	// The default interval splits the range into 60 buckets
+	rec = histogram("startTime=2024-01-01T11:00:00Z&endTime=2024-01-01T12:00:00Z")
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Interval != time.Minute.Milliseconds() || len(resp.Buckets) != 60 {
+		t.Errorf("default interval = %dms with %d buckets, want 1m with 60", resp.Interval, len(resp.Buckets))
+	}
+
+	// Daily buckets follow local midnight
+	rec = histogram("namespace=tz&startTime=2024-01-01T00:00:00Z&endTime=2024-01-03T00:00:00Z&interval=24h&tz=Europe/Berlin")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	wantStarts := []string{"2023-12-31T23:00:00Z", "2024-01-01T23:00:00Z", "2024-01-02T23:00:00Z"}
+	wantTotals = []int64{1, 1, 0}
+	if len(resp.Buckets) != len(wantStarts) {
+		t.Fatalf("got %d buckets, want %d: %+v", len(resp.Buckets), len(wantStarts), resp.Buckets)
+	}
+	for i, b := range resp.Buckets {
+		if got := time.Unix(0, b.Timestamp).UTC().Format(time.RFC3339); got != wantStarts[i] {
+			t.Errorf("bucket %d start = %s, want %s", i, got, wantStarts[i])
+		}
+		if b.Total != wantTotals[i] {
+			t.Errorf("bucket %d total = %d, want %d", i, b.Total, wantTotals[i])
+		}
+	}
+
+	for _, query := range []string{
+		"interval=bogus",
+		"tz=Mars/Olympus",
+		"interval=1ms",
+		"startTime=2024-01-01T12:00:00Z&endTime=2024-01-01T11:00:00Z",
+	} {
+		if rec := histogram(query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestHistogramStarts(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	kathmandu, err := time.LoadLocation("Asia/Kathmandu")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		start, end string
+		interval   time.Duration
+		loc        *time.Location
+		want       []string
+	}{
+		{
+			name:  "epoch aligned",
+			start: "2024-03-10T00:10:00Z", end: "2024-03-10T00:30:00Z",
+			interval: 15 * time.Minute,
+			want:     []string{"2024-03-10T00:00:00Z", "2024-03-10T00:15:00Z"},
+		},
+		{
+			name:  "days across a DST change",
+			start: "2024-03-09T12:00:00Z", end: "2024-03-12T00:00:00Z",
+			interval: 24 * time.Hour, loc: ny,
+			// The 10th has 23 hours
+			want: []string{"2024-03-09T05:00:00Z", "2024-03-10T05:00:00Z", "2024-03-11T04:00:00Z"},
+		},
+		{
+			name:  "hours from local midnight",
+			start: "2024-01-01T18:00:00Z", end: "2024-01-01T20:00:00Z",
+			interval: time.Hour, loc: kathmandu,
+			want: []string{"2024-01-01T17:15:00Z", "2024-01-01T18:15:00Z", "2024-01-01T19:15:00Z"},
+		},
+		{
+			name:  "interval not dividing the day restarts at midnight",
+			start: "2024-01-01T17:00:00Z", end: "2024-01-01T20:00:00Z",
+			interval: 7 * time.Hour, loc: kathmandu,
+			want: []string{"2024-01-01T15:15:00Z", "2024-01-01T18:15:00Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, _ := time.Parse(time.RFC3339, tt.start)
+			end, _ := time.Parse(time.RFC3339, tt.end)
+			var got []string
+			for _, ts := range histogramStarts(start, end, tt.interval, tt.loc) {
+				got = append(got, time.Unix(0, ts).UTC().Format(time.RFC3339))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("histogramStarts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
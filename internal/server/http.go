@@ -4,31 +4,76 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
 	"io/fs"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/netip"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/kubelogs/kubelogs/internal/audit"
 	"github.com/kubelogs/kubelogs/internal/auth"
+	"github.com/kubelogs/kubelogs/internal/bookmark"
+	"github.com/kubelogs/kubelogs/internal/incident"
+	"github.com/kubelogs/kubelogs/internal/logstats"
+	"github.com/kubelogs/kubelogs/internal/metrics"
+	"github.com/kubelogs/kubelogs/internal/preferences"
+	"github.com/kubelogs/kubelogs/internal/savedquery"
 	"github.com/kubelogs/kubelogs/internal/storage"
 	"github.com/kubelogs/kubelogs/internal/web"
 )
 
 // HTTPServer serves the web UI.
 type HTTPServer struct {
-	store     storage.Store
-	templates *template.Template
-	staticFS  fs.FS
-
-	// Auth components (nil when auth disabled)
+	store          storage.Store
+	incidentStore  *incident.Store
+	logStats       *logstats.Store // nil when hourly stats are disabled
+	fleet          *Fleet          // Collector status reports (nil = none received)
+	logLevel       http.Handler    // Runtime log level for admins (nil = not served)
+	retentionDays  int             // Configured retention, for forecasts (0 = disabled)
+	trustedProxies []netip.Prefix
+	traceURL       string // Trace viewer URL with a {traceId} placeholder
+	queryDuration  *metrics.Histogram
+	queryTimeouts  *metrics.Counter
+	queryTimeout   time.Duration // 0 = unlimited
+	templates      *template.Template
+	staticFS       fs.FS
+
+	// server serves Routes once Serve is called; stopping is closed when
+	// it shuts down, ending live tails and long polls
+	server   *http.Server
+	stopping chan struct{}
+
+	// Auth components (nil when auth disabled; the session store also
+	// without the "local" and "oidc" methods)
 	authMiddleware  *auth.Middleware
 	userStore       *auth.UserStore
 	sessionStore    *auth.SessionStore
+	localLogin      bool            // Users log in with a password on /login
+	oidc            *auth.OIDCLogin // nil without the "oidc" method
+	bookmarkStore   *bookmark.Store
+	queryStore      *savedquery.Store
+	prefStore       *preferences.Store
 	authEnabled     bool
 	sessionDuration time.Duration
+
+	// Role each route requires, and the users with the admin role
+	policy     *routePolicy
+	adminUsers map[string]bool
+
+	// SQL console limits
+	sqlTimeout time.Duration
+	sqlMaxRows int
+
+	// Tokens confirming destructive admin operations, and the retention
+	// whose shrinkage they confirm (nil = not served)
+	confirms  *Confirmations
+	retention *RetentionWorker
 }
 
 // NewHTTPServer creates a new HTTP server for the web UI.
@@ -45,21 +90,48 @@ func NewHTTPServer(store storage.Store, db *sql.DB, cfg Config) (*HTTPServer, er
 
 	s := &HTTPServer{
 		store:           store,
+		incidentStore:   incident.NewStore(db),
+		retentionDays:   cfg.RetentionDays,
+		trustedProxies:  cfg.TrustedProxies,
+		traceURL:        cfg.TraceURL,
+		queryTimeout:    cfg.QueryTimeout,
 		templates:       tmpl,
 		staticFS:        staticFS,
 		authEnabled:     cfg.AuthEnabled,
 		sessionDuration: cfg.SessionDuration,
+		adminUsers:      make(map[string]bool),
+		sqlTimeout:      cfg.SQLConsoleTimeout,
+		sqlMaxRows:      cfg.SQLConsoleMaxRows,
+		confirms:        NewConfirmations(cfg.RequireSecondApprover, audit.NewStore(db)),
+		stopping:        make(chan struct{}),
+	}
+	s.server = &http.Server{
+		ReadTimeout:  cfg.HTTPReadTimeout,
+		WriteTimeout: cfg.HTTPWriteTimeout,
+		IdleTimeout:  cfg.HTTPIdleTimeout,
+		ConnContext:  proxyConnContext,
+	}
+	s.server.RegisterOnShutdown(func() { close(s.stopping) })
+	if cfg.LogStatsInterval > 0 {
+		s.logStats = logstats.NewStore(db)
+	}
+	for _, name := range cfg.AdminUsers {
+		s.adminUsers[name] = true
+	}
+	if s.policy, err = newRoutePolicy(cfg.RoutePolicy); err != nil {
+		return nil, err
+	}
+	if cfg.RequireSecondApprover && len(s.adminUsers) < 2 {
+		slog.Warn("a second approver is required but fewer than two admin users are configured, destructive operations can't be confirmed")
 	}
 
 	if cfg.AuthEnabled {
-		s.userStore = auth.NewUserStore(db)
-		s.sessionStore = auth.NewSessionStore(db, cfg.SessionDuration)
-		s.authMiddleware = auth.NewMiddleware(
-			s.userStore,
-			s.sessionStore,
-			cfg.SessionCookieName,
-			cfg.SessionCookieSecure,
-		)
+		if err := s.setupAuth(db, cfg); err != nil {
+			return nil, err
+		}
+		s.bookmarkStore = bookmark.NewStore(db)
+		s.queryStore = savedquery.NewStore(db)
+		s.prefStore = preferences.NewStore(db)
 	}
 
 	return s, nil
@@ -72,34 +144,120 @@ func (s *HTTPServer) Routes() http.Handler {
 	// Static files - always public
 	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServer(http.FS(s.staticFS))))
 
-	if s.authEnabled {
-		// Public routes (no auth required)
+	// Public routes, see fixedPolicy
+	if s.localLogin {
 		mux.HandleFunc("GET /login", s.handleLoginPage)
 		mux.HandleFunc("POST /login", s.handleLogin)
 		mux.HandleFunc("GET /setup", s.handleSetupPage)
 		mux.HandleFunc("POST /setup", s.handleSetup)
+	}
+	if s.oidc != nil {
+		mux.HandleFunc("GET /auth/oidc/login", s.handleOIDCLogin)
+		mux.HandleFunc("GET /auth/oidc/callback", s.handleOIDCCallback)
+	}
+	if s.sessionAuth() {
 		mux.HandleFunc("POST /logout", s.handleLogout)
+	}
 
-		// Protected page routes
-		mux.Handle("GET /", s.authMiddleware.RequireAuth(http.HandlerFunc(s.handleIndex)))
+	// Page and API routes, which need the role the route policy gives
+	// them when auth is enabled
+	mux.HandleFunc("GET /", s.handleIndex)
+	mux.HandleFunc("GET /api/logs", s.handleQueryLogs)
+	mux.HandleFunc("GET /api/logs/stream", s.handleLogStream)
+	mux.HandleFunc("GET /api/logs/poll", s.handleLogPoll)
+	mux.HandleFunc("GET /api/logs/entries", s.handleGetEntries)
+	mux.HandleFunc("GET /api/logs/{id}/context", s.handleGetContext)
+	mux.HandleFunc("GET /api/logs/export", s.handleExportLogs)
+	mux.HandleFunc("GET /api/logs/histogram", s.handleHistogram)
+	mux.HandleFunc("GET /api/stats", s.handleStats)
+	mux.HandleFunc("GET /api/stats/top", s.handleTopSources)
+	mux.HandleFunc("GET /api/stats/forecast", s.handleForecast)
+	mux.HandleFunc("GET /api/stats/timeseries", s.handleTimeseries)
+	mux.HandleFunc("GET /api/collectors", s.handleListCollectors)
+	mux.HandleFunc("GET /api/filters/clusters", s.handleListClusters)
+	mux.HandleFunc("GET /api/filters/namespaces", s.handleListNamespaces)
+	mux.HandleFunc("GET /api/filters/containers", s.handleListContainers)
+	mux.HandleFunc("GET /api/filters/pods", s.handleListPods)
+	mux.HandleFunc("GET /api/filters/workloads", s.handleListWorkloads)
+	mux.HandleFunc("GET /api/cronjobs/{name}/runs", s.handleCronJobRuns)
+	mux.HandleFunc("GET /api/traces/{traceId}/logs", s.handleTraceLogs)
+
+	mux.HandleFunc("GET /api/diff", s.handleDiff)
+	mux.HandleFunc("GET /api/errors/overview", s.handleErrorOverview)
+	mux.HandleFunc("GET /api/patterns/new", s.handleNewPatterns)
+
+	mux.HandleFunc("GET /api/incidents", s.handleListIncidents)
+	mux.HandleFunc("POST /api/incidents", s.handleCreateIncident)
+	mux.HandleFunc("GET /api/incidents/{id}", s.handleGetIncident)
+	mux.HandleFunc("PUT /api/incidents/{id}", s.handleUpdateIncident)
+	mux.HandleFunc("DELETE /api/incidents/{id}", s.handleDeleteIncident)
+	mux.HandleFunc("POST /api/incidents/{id}/items", s.handleAddIncidentItem)
+	mux.HandleFunc("DELETE /api/incidents/{id}/items/{itemId}", s.handleRemoveIncidentItem)
+	mux.HandleFunc("GET /api/incidents/{id}/export", s.handleExportIncident)
 
-		// Protected API routes
-		mux.Handle("GET /api/logs", s.authMiddleware.RequireAuthAPI(http.HandlerFunc(s.handleQueryLogs)))
-		mux.Handle("GET /api/logs/stream", s.authMiddleware.RequireAuthAPI(http.HandlerFunc(s.handleLogStream)))
-		mux.Handle("GET /api/stats", s.authMiddleware.RequireAuthAPI(http.HandlerFunc(s.handleStats)))
-		mux.Handle("GET /api/filters/namespaces", s.authMiddleware.RequireAuthAPI(http.HandlerFunc(s.handleListNamespaces)))
-		mux.Handle("GET /api/filters/containers", s.authMiddleware.RequireAuthAPI(http.HandlerFunc(s.handleListContainers)))
-	} else {
-		// No auth - all routes public (current behavior)
-		mux.HandleFunc("GET /", s.handleIndex)
-		mux.HandleFunc("GET /api/logs", s.handleQueryLogs)
-		mux.HandleFunc("GET /api/logs/stream", s.handleLogStream)
-		mux.HandleFunc("GET /api/stats", s.handleStats)
-		mux.HandleFunc("GET /api/filters/namespaces", s.handleListNamespaces)
-		mux.HandleFunc("GET /api/filters/containers", s.handleListContainers)
+	if s.authEnabled {
+		// Bookmarks are per user, so they're only available with auth
+		mux.HandleFunc("GET /api/bookmarks", s.handleListBookmarks)
+		mux.HandleFunc("PUT /api/bookmarks/{id}", s.handlePutBookmark)
+		mux.HandleFunc("DELETE /api/bookmarks/{id}", s.handleDeleteBookmark)
+
+		// Saved queries are per user as well
+		mux.HandleFunc("GET /api/queries", s.handleListSavedQueries)
+		mux.HandleFunc("POST /api/queries", s.handleCreateSavedQuery)
+		mux.HandleFunc("GET /api/queries/{id}", s.handleGetSavedQuery)
+		mux.HandleFunc("PUT /api/queries/{id}", s.handleUpdateSavedQuery)
+		mux.HandleFunc("DELETE /api/queries/{id}", s.handleDeleteSavedQuery)
+
+		mux.HandleFunc("GET /api/preferences", s.handleGetPreferences)
+		mux.HandleFunc("PUT /api/preferences", s.handlePutPreferences)
+
+		// The SQL console, deletes, retention changes, the audit log,
+		// index rebuilds, ledger checks and log level changes are limited
+		// to AdminUsers by default, so they need auth too
+		mux.HandleFunc("GET /api/admin/schema", s.handleSchema)
+		mux.HandleFunc("POST /api/admin/sql", s.handleSQLQuery)
+		mux.HandleFunc("POST /api/admin/logs/preview", s.handlePreviewDeleteLogs)
+		mux.HandleFunc("DELETE /api/admin/logs", s.handleDeleteLogs)
+		if s.retention != nil {
+			mux.HandleFunc("POST /api/admin/retention/preview", s.handlePreviewRetention)
+			mux.HandleFunc("POST /api/admin/retention/apply", s.handleApplyRetention)
+		}
+		mux.HandleFunc("GET /api/admin/audit", s.handleListAudit)
+		mux.HandleFunc("POST /api/admin/search-index/rebuild", s.handleRebuildSearchIndex)
+		mux.HandleFunc("GET /api/admin/ledger/verify", s.handleVerifyLedger)
+		if s.logLevel != nil {
+			mux.Handle("GET /api/admin/loglevel", s.logLevel)
+			mux.Handle("PUT /api/admin/loglevel", s.logLevel)
+			mux.Handle("POST /api/admin/loglevel", s.logLevel)
+		}
 	}
 
-	return s.withLogging(mux)
+	return s.withClientIP(s.withLogging(s.authorize(mux)))
+}
+
+// Serve serves Routes on lis until Shutdown, when it returns
+// http.ErrServerClosed.
+func (s *HTTPServer) Serve(lis net.Listener) error {
+	s.server.Handler = s.Routes()
+	return s.server.Serve(lis)
+}
+
+// Shutdown stops accepting connections, ends live tails and long polls,
+// and waits for other in-flight requests to finish. Connections still
+// busy when ctx is done are closed.
+func (s *HTTPServer) Shutdown(ctx context.Context) error {
+	err := s.server.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		s.server.Close()
+	}
+	return err
+}
+
+// keepWriting lifts the server's write timeout for a response that
+// streams, or waits for entries, longer than it.
+func keepWriting(w http.ResponseWriter) {
+	// Fails only for writers without deadlines, such as test recorders
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
 }
 
 // withLogging wraps a handler with request logging.
@@ -110,6 +268,7 @@ func (s *HTTPServer) withLogging(next http.Handler) http.Handler {
 		slog.Debug("http request",
 			"method", r.Method,
 			"path", r.URL.Path,
+			"remote", r.RemoteAddr,
 			"duration", time.Since(start),
 		)
 	})
@@ -124,6 +283,7 @@ func (s *HTTPServer) handleIndex(w http.ResponseWriter, r *http.Request) {
 
 	data := map[string]any{
 		"AuthEnabled": s.authEnabled,
+		"SessionAuth": s.sessionAuth(),
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -136,11 +296,9 @@ func (s *HTTPServer) handleIndex(w http.ResponseWriter, r *http.Request) {
 // handleLoginPage renders the login form.
 func (s *HTTPServer) handleLoginPage(w http.ResponseWriter, r *http.Request) {
 	// Check if user already authenticated
-	if cookie, err := r.Cookie(s.authMiddleware.CookieName()); err == nil {
-		if _, err := s.sessionStore.Get(r.Context(), cookie.Value); err == nil {
-			http.Redirect(w, r, "/", http.StatusSeeOther)
-			return
-		}
+	if _, err := s.authMiddleware.Authenticate(r); err == nil {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
 	}
 
 	// Check if setup needed
@@ -152,6 +310,7 @@ func (s *HTTPServer) handleLoginPage(w http.ResponseWriter, r *http.Request) {
 
 	data := map[string]any{
 		"Error": r.URL.Query().Get("error"),
+		"OIDC":  s.oidc != nil,
 	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if err := s.templates.ExecuteTemplate(w, "login.html", data); err != nil {
@@ -167,6 +326,7 @@ func (s *HTTPServer) handleLogin(w http.ResponseWriter, r *http.Request) {
 
 	user, err := s.userStore.Authenticate(r.Context(), username, password)
 	if err != nil {
+		slog.Warn("login failed", "username", username, "remote", r.RemoteAddr)
 		http.Redirect(w, r, "/login?error=invalid", http.StatusSeeOther)
 		return
 	}
@@ -253,9 +413,39 @@ func (s *HTTPServer) handleLogout(w http.ResponseWriter, r *http.Request) {
 		s.sessionStore.Delete(r.Context(), cookie.Value)
 	}
 	s.authMiddleware.SetSessionCookie(w, "", -1)
+	if !s.localLogin {
+		// The index sends users to the provider to log in again, which
+		// may not ask for credentials while its own session lasts
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
 	http.Redirect(w, r, "/login", http.StatusSeeOther)
 }
 
+// SetFleet lists the collectors reported to fleet on /api/collectors.
+// Call before serving.
+func (s *HTTPServer) SetFleet(fleet *Fleet) {
+	s.fleet = fleet
+}
+
+// SetConfirmations shares c, which confirms destructive operations, with
+// another API such as the AdminService. Call before serving.
+func (s *HTTPServer) SetConfirmations(c *Confirmations) {
+	s.confirms = c
+}
+
+// SetRetention lets admins confirm a shrinkage of w's retention on
+// /api/admin/retention. Call before serving.
+func (s *HTTPServer) SetRetention(w *RetentionWorker) {
+	s.retention = w
+}
+
+// SetLogLevel serves h, which reports and changes the log level, to
+// admins on /api/admin/loglevel. Call before serving.
+func (s *HTTPServer) SetLogLevel(h http.Handler) {
+	s.logLevel = h
+}
+
 // SessionStore returns the session store for cleanup.
 func (s *HTTPServer) SessionStore() *auth.SessionStore {
 	return s.sessionStore
@@ -265,42 +455,85 @@ func (s *HTTPServer) SessionStore() *auth.SessionStore {
 type logEntryJSON struct {
 	ID        int64             `json:"id"`
 	Timestamp int64             `json:"timestamp"` // Unix nanoseconds
+	Cluster   string            `json:"cluster,omitempty"`
 	Namespace string            `json:"namespace"`
 	Pod       string            `json:"pod"`
 	Container string            `json:"container"`
 	Severity  int               `json:"severity"`
 	Message   string            `json:"message"`
 	Attrs     map[string]string `json:"attrs,omitempty"`
+	Links     []messageLink     `json:"links,omitempty"` // Spans of Message to render as links
+
+	Computed map[string]float64 `json:"computed,omitempty"` // Values of the query's computed fields
 }
 
 // queryResponse is the JSON response for log queries.
 type queryResponse struct {
 	Entries    []logEntryJSON `json:"entries"`
 	HasMore    bool           `json:"hasMore"`
-	NextCursor int64          `json:"nextCursor,omitempty"`
-	Total      int64          `json:"total,omitempty"`
+	NextCursor string         `json:"nextCursor,omitempty"`  // Opaque token for the next page
+	Total      int64          `json:"total"`                 // Matches counted with count=true, or -1
+	Capped     bool           `json:"totalCapped,omitempty"` // Counting stopped at Total; there are more
+	SampleRate float64        `json:"sampleRate,omitempty"`  // Fraction of matches sampled
+
+	// Computed aggregates each computed field over this page's entries
+	Computed map[string]computedSummaryJSON `json:"computed,omitempty"`
 }
 
 // toJSON converts a storage LogEntry to JSON representation.
-func toJSON(e storage.LogEntry) logEntryJSON {
+func (s *HTTPServer) toJSON(e storage.LogEntry) logEntryJSON {
 	return logEntryJSON{
 		ID:        e.ID,
 		Timestamp: e.Timestamp.UnixNano(),
+		Cluster:   e.Cluster,
 		Namespace: e.Namespace,
 		Pod:       e.Pod,
 		Container: e.Container,
 		Severity:  int(e.Severity),
 		Message:   e.Message,
 		Attrs:     e.Attributes,
+		Links:     findLinks(e.Message, e.Attributes, s.traceURL),
 	}
 }
 
 // handleQueryLogs returns log entries matching the query parameters.
 func (s *HTTPServer) handleQueryLogs(w http.ResponseWriter, r *http.Request) {
-	q := s.parseQueryParams(r)
+	q, err := s.parseQueryParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fields, err := parseComputedFields(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	result, err := s.store.Query(r.Context(), q)
+	if token := r.URL.Query().Get("cursor"); token != "" {
+		c, err := decodeCursor(token)
+		if err == nil {
+			err = c.apply(&q)
+		}
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := withQueryTimeout(r.Context(), s.queryTimeout)
+	defer cancel()
+
+	start := time.Now()
+	result, err := s.store.Query(ctx, q)
+	s.queryDuration.Observe(since(start))
 	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			s.queryTimeouts.Inc()
+			slog.Warn("query timed out", "timeout", s.queryTimeout, "search", q.Search)
+			http.Error(w, "Query timed out after "+s.queryTimeout.String(), http.StatusGatewayTimeout)
+			return
+		}
 		slog.Error("query error", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
@@ -308,14 +541,24 @@ func (s *HTTPServer) handleQueryLogs(w http.ResponseWriter, r *http.Request) {
 
 	entries := make([]logEntryJSON, 0, len(result.Entries))
 	for _, e := range result.Entries {
-		entries = append(entries, toJSON(e))
+		j := s.toJSON(e)
+		j.Computed = computeEntry(fields, e.Message)
+		entries = append(entries, j)
 	}
 
 	resp := queryResponse{
 		Entries:    entries,
 		HasMore:    result.HasMore,
-		NextCursor: result.NextCursor,
 		Total:      result.TotalEstimate,
+		Capped:     result.TotalCapped,
+		SampleRate: result.SampleRate,
+	}
+	if len(fields) > 0 {
+		resp.Computed = summarizeComputed(fields, entries)
+	}
+	if result.HasMore && len(result.Entries) > 0 {
+		last := result.Entries[len(result.Entries)-1]
+		resp.NextCursor = cursorFromEntry(last, q).encode()
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -324,8 +567,76 @@ func (s *HTTPServer) handleQueryLogs(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// parseQueryParams extracts query parameters into a storage.Query.
-func (s *HTTPServer) parseQueryParams(r *http.Request) storage.Query {
+// entriesResponse is the JSON response for fetching entries by ID.
+type entriesResponse struct {
+	Entries []logEntryJSON `json:"entries"`
+}
+
+// handleGetEntries returns the entries listed in ids (comma-separated),
+// in request order. Unknown IDs are omitted.
+func (s *HTTPServer) handleGetEntries(w http.ResponseWriter, r *http.Request) {
+	var ids []int64
+	for _, v := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid id: "+v, http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, n)
+	}
+	if len(ids) > maxGetByIDs {
+		http.Error(w, fmt.Sprintf("Too many ids (max %d)", maxGetByIDs), http.StatusBadRequest)
+		return
+	}
+
+	found, err := s.store.GetByIDs(r.Context(), ids)
+	if err != nil {
+		slog.Error("get entries error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := entriesResponse{Entries: make([]logEntryJSON, 0, len(found))}
+	for _, e := range found {
+		resp.Entries = append(resp.Entries, s.toJSON(e))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("json encode error", "error", err)
+	}
+}
+
+// entriesByID fetches entries by ID, batching to stay within maxGetByIDs.
+func (s *HTTPServer) entriesByID(r *http.Request, ids []int64) (map[int64]storage.LogEntry, error) {
+	entries := make(map[int64]storage.LogEntry, len(ids))
+	for start := 0; start < len(ids); start += maxGetByIDs {
+		found, err := s.store.GetByIDs(r.Context(), ids[start:min(start+maxGetByIDs, len(ids))])
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range found {
+			entries[e.ID] = e
+		}
+	}
+	return entries, nil
+}
+
+// writeJSON encodes v as the JSON response body.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("json encode error", "error", err)
+	}
+}
+
+// parseQueryParams extracts query parameters into a storage.Query. Invalid
+// filters are an error, since ignoring them would widen the result;
+// invalid paging parameters fall back to their defaults.
+func (s *HTTPServer) parseQueryParams(r *http.Request) (storage.Query, error) {
 	q := storage.Query{
 		Pagination: storage.Pagination{
 			Limit: 100,
@@ -335,66 +646,32 @@ func (s *HTTPServer) parseQueryParams(r *http.Request) storage.Query {
 
 	params := r.URL.Query()
 
-	if v := params.Get("namespace"); v != "" {
-		q.Namespace = v
-	}
-	if v := params.Get("pod"); v != "" {
-		q.Pod = v
-	}
-	if v := params.Get("container"); v != "" {
-		q.Container = v
-	}
-	if v := params.Get("search"); v != "" {
-		q.Search = v
-	}
-	if v := params.Get("minSeverity"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n >= 0 && n <= 6 {
-			q.MinSeverity = storage.Severity(n)
-		}
+	if err := storage.ParseFilters(params, &q); err != nil {
+		return q, err
 	}
+
 	if v := params.Get("limit"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 1000 {
 			q.Pagination.Limit = n
 		}
 	}
-	if v := params.Get("afterId"); v != "" {
-		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
-			q.Pagination.AfterID = n
-		}
-	}
-	if v := params.Get("beforeId"); v != "" {
-		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
-			q.Pagination.BeforeID = n
-		}
-	}
 	if v := params.Get("order"); v == "asc" {
 		q.Pagination.Order = storage.OrderAsc
 	}
-
-	// Time range filtering
-	if v := params.Get("startTime"); v != "" {
-		if t, err := time.Parse(time.RFC3339, v); err == nil {
-			q.StartTime = t
-		}
+	if v := params.Get("orderBy"); v == "timestamp" {
+		q.Pagination.OrderBy = storage.OrderByTimestamp
 	}
-	if v := params.Get("endTime"); v != "" {
-		if t, err := time.Parse(time.RFC3339, v); err == nil {
-			q.EndTime = t
+	if v := params.Get("sample"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f > 0 && f <= 1 {
+			q.Sample = f
 		}
 	}
-
-	// Attribute filters (attr.key=value format)
-	for key, values := range params {
-		if strings.HasPrefix(key, "attr.") && len(values) > 0 {
-			if q.Attributes == nil {
-				q.Attributes = make(map[string]string)
-			}
-			attrKey := strings.TrimPrefix(key, "attr.")
-			q.Attributes[attrKey] = values[0]
-		}
+	if v := params.Get("consistency"); v == "eventual" {
+		q.Consistency = storage.ConsistencyEventual
 	}
+	q.Count = params.Get("count") == "true"
 
-	return q
+	return q, nil
 }
 
 // statsResponse is the JSON response for stats.
@@ -403,6 +680,18 @@ type statsResponse struct {
 	DiskSizeBytes int64  `json:"diskSizeBytes"`
 	OldestEntry   string `json:"oldestEntry,omitempty"`
 	NewestEntry   string `json:"newestEntry,omitempty"`
+
+	Namespaces []namespaceUsageJSON `json:"namespaces"`
+}
+
+// namespaceUsageJSON is the stored volume of one namespace.
+type namespaceUsageJSON struct {
+	Namespace string `json:"namespace"`
+	Entries   int64  `json:"entries"`
+	Bytes     int64  `json:"bytes"`
+	// DiskBytes apportions the database size by message bytes; it includes
+	// index and metadata overhead and is 0 for in-memory databases.
+	DiskBytes int64 `json:"diskBytes"`
 }
 
 // handleStats returns storage statistics.
@@ -417,6 +706,18 @@ func (s *HTTPServer) handleStats(w http.ResponseWriter, r *http.Request) {
 	resp := statsResponse{
 		TotalEntries:  stats.TotalEntries,
 		DiskSizeBytes: stats.DiskSizeBytes,
+		Namespaces:    make([]namespaceUsageJSON, 0, len(stats.Namespaces)),
+	}
+	var totalBytes int64
+	for _, u := range stats.Namespaces {
+		totalBytes += u.Bytes
+	}
+	for _, u := range stats.Namespaces {
+		nu := namespaceUsageJSON{Namespace: u.Namespace, Entries: u.Entries, Bytes: u.Bytes}
+		if totalBytes > 0 {
+			nu.DiskBytes = int64(float64(stats.DiskSizeBytes) * float64(u.Bytes) / float64(totalBytes))
+		}
+		resp.Namespaces = append(resp.Namespaces, nu)
 	}
 	if !stats.OldestEntry.IsZero() {
 		resp.OldestEntry = stats.OldestEntry.Format(time.RFC3339)
@@ -435,6 +736,32 @@ func (s *HTTPServer) handleStats(w http.ResponseWriter, r *http.Request) {
 type FilterLister interface {
 	ListNamespaces(ctx context.Context) ([]string, error)
 	ListContainers(ctx context.Context) ([]string, error)
+	// ListPods lists the pods in namespace, or in all namespaces if
+	// it's empty.
+	ListPods(ctx context.Context, namespace string) ([]string, error)
+}
+
+// ClusterLister is an interface for stores that can list cluster names.
+type ClusterLister interface {
+	ListClusters(ctx context.Context) ([]string, error)
+}
+
+// handleListClusters returns distinct non-empty cluster values.
+func (s *HTTPServer) handleListClusters(w http.ResponseWriter, r *http.Request) {
+	lister, ok := s.store.(ClusterLister)
+	if !ok {
+		http.Error(w, "Not supported", http.StatusNotImplemented)
+		return
+	}
+
+	clusters, err := lister.ListClusters(r.Context())
+	if err != nil {
+		slog.Error("list clusters error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, clusters)
 }
 
 // handleListNamespaces returns distinct namespace values.
@@ -452,10 +779,8 @@ func (s *HTTPServer) handleListNamespaces(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(namespaces); err != nil {
-		slog.Error("json encode error", "error", err)
-	}
+	s.writeFilterValues(w, r, namespaces, storage.RollupQuery{By: storage.RollupByNamespace},
+		func(ru storage.Rollup) string { return ru.Namespace })
 }
 
 // handleListContainers returns distinct container values.
@@ -473,8 +798,130 @@ func (s *HTTPServer) handleListContainers(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(containers); err != nil {
-		slog.Error("json encode error", "error", err)
+	s.writeFilterValues(w, r, containers, storage.RollupQuery{By: storage.RollupByContainer},
+		func(ru storage.Rollup) string { return ru.Container })
+}
+
+// handleListPods returns distinct pod values in the namespace parameter,
+// or in all namespaces without it.
+func (s *HTTPServer) handleListPods(w http.ResponseWriter, r *http.Request) {
+	lister, ok := s.store.(FilterLister)
+	if !ok {
+		http.Error(w, "Not supported", http.StatusNotImplemented)
+		return
+	}
+
+	namespace := r.URL.Query().Get("namespace")
+	pods, err := lister.ListPods(r.Context(), namespace)
+	if err != nil {
+		slog.Error("list pods error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	s.writeFilterValues(w, r, pods, storage.RollupQuery{By: storage.RollupByPod, Namespace: namespace},
+		func(ru storage.Rollup) string { return ru.Pod })
+}
+
+// filterValueJSON is a filter value with the volume of its entries.
+type filterValueJSON struct {
+	Value    string `json:"value"`
+	Entries  int64  `json:"entries,omitempty"`
+	LastSeen int64  `json:"lastSeen,omitempty"` // Unix nanoseconds
+}
+
+// writeFilterValues writes values as a list of strings or, with the
+// stats parameter set to true, as filterValueJSON with the entry count
+// and latest timestamp of each, summed from the rollups q selects by the
+// value key returns. Values are left without them if the store keeps no
+// rollups.
+func (s *HTTPServer) writeFilterValues(w http.ResponseWriter, r *http.Request, values []string, q storage.RollupQuery, key func(storage.Rollup) string) {
+	if r.URL.Query().Get("stats") != "true" {
+		writeJSON(w, values)
+		return
+	}
+
+	result := make([]filterValueJSON, len(values))
+	index := make(map[string]int, len(values))
+	for i, v := range values {
+		result[i].Value = v
+		index[v] = i
+	}
+
+	if reader, ok := s.store.(storage.RollupReader); ok {
+		rollups, err := reader.Rollups(r.Context(), q)
+		if err != nil {
+			slog.Error("rollups error", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		for _, ru := range rollups {
+			i, ok := index[key(ru)]
+			if !ok {
+				continue
+			}
+			result[i].Entries += ru.Lines
+			result[i].LastSeen = max(result[i].LastSeen, ru.LastSeen.UnixNano())
+		}
+	}
+	writeJSON(w, result)
+}
+
+// defaultWorkloadWindow is how far back workloads are listed without a
+// startTime.
+const defaultWorkloadWindow = 24 * time.Hour
+
+// workloadJSON is a workload and its entry count.
+type workloadJSON struct {
+	Namespace string `json:"namespace"`
+	Workload  string `json:"workload"`
+	Entries   int64  `json:"entries"`
+}
+
+// handleListWorkloads returns the workloads with entries matching the
+// usual filters, by default over the last 24 hours, with their counts,
+// sorted by namespace and workload. Entries of pods without a workload
+// are left out.
+func (s *HTTPServer) handleListWorkloads(w http.ResponseWriter, r *http.Request) {
+	agg, ok := s.store.(storage.GroupAggregator)
+	if !ok {
+		http.Error(w, "Not supported", http.StatusNotImplemented)
+		return
+	}
+
+	q, err := s.parseQueryParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.Sample = 0
+	if q.StartTime.IsZero() {
+		q.StartTime = time.Now().Add(-defaultWorkloadWindow)
+	}
+
+	ctx, cancel := withQueryTimeout(r.Context(), s.queryTimeout)
+	defer cancel()
+
+	groups, err := agg.Aggregate(ctx, q, storage.Aggregation{
+		GroupBy: []string{storage.GroupByNamespace, "attr." + storage.WorkloadAttribute},
+	})
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			s.queryTimeouts.Inc()
+			http.Error(w, "Query timed out after "+s.queryTimeout.String(), http.StatusGatewayTimeout)
+			return
+		}
+		slog.Error("list workloads error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	workloads := make([]workloadJSON, 0, len(groups))
+	for _, g := range groups {
+		if g.Keys[1] == "" {
+			continue
+		}
+		workloads = append(workloads, workloadJSON{Namespace: g.Keys[0], Workload: g.Keys[1], Entries: g.Count})
 	}
+	writeJSON(w, workloads)
 }
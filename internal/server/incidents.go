@@ -0,0 +1,305 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/auth"
+	"github.com/kubelogs/kubelogs/internal/incident"
+)
+
+const (
+	maxIncidentTitle = 200
+	maxIncidentNotes = 64 << 10
+	maxIncidentBody  = 128 << 10
+)
+
+// incidentJSON is the JSON representation of an incident.
+type incidentJSON struct {
+	ID        int64              `json:"id"`
+	Title     string             `json:"title"`
+	Notes     string             `json:"notes"`
+	CreatedBy string             `json:"createdBy,omitempty"`
+	CreatedAt int64              `json:"createdAt"` // Unix nanoseconds
+	UpdatedAt int64              `json:"updatedAt"` // Unix nanoseconds
+	Items     []incidentItemJSON `json:"items,omitempty"`
+}
+
+// incidentItemJSON is the JSON representation of an incident item.
+type incidentItemJSON struct {
+	ID        int64         `json:"id,omitempty"`
+	Kind      string        `json:"kind"`
+	EntryID   int64         `json:"entryId,omitempty"`
+	Query     string        `json:"query,omitempty"`
+	Timestamp int64         `json:"timestamp,omitempty"` // Unix nanoseconds, markers only
+	Label     string        `json:"label,omitempty"`
+	Entry     *logEntryJSON `json:"entry,omitempty"` // Hydrated for entry items still in storage
+}
+
+// incidentRequest is the JSON body for creating or updating an incident.
+type incidentRequest struct {
+	Title string `json:"title"`
+	Notes string `json:"notes"`
+}
+
+func toIncidentJSON(inc incident.Incident) incidentJSON {
+	return incidentJSON{
+		ID:        inc.ID,
+		Title:     inc.Title,
+		Notes:     inc.Notes,
+		CreatedBy: inc.CreatedBy,
+		CreatedAt: inc.CreatedAt.UnixNano(),
+		UpdatedAt: inc.UpdatedAt.UnixNano(),
+	}
+}
+
+func toIncidentItemJSON(it incident.Item) incidentItemJSON {
+	ij := incidentItemJSON{
+		ID:      it.ID,
+		Kind:    string(it.Kind),
+		EntryID: it.EntryID,
+		Query:   it.Query,
+		Label:   it.Label,
+	}
+	if !it.Timestamp.IsZero() {
+		ij.Timestamp = it.Timestamp.UnixNano()
+	}
+	return ij
+}
+
+// decodeIncidentRequest parses and validates an incident create/update body.
+func decodeIncidentRequest(w http.ResponseWriter, r *http.Request) (incidentRequest, bool) {
+	var req incidentRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxIncidentBody)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return req, false
+	}
+	if req.Title == "" || len(req.Title) > maxIncidentTitle {
+		http.Error(w, fmt.Sprintf("Title must be 1-%d bytes", maxIncidentTitle), http.StatusBadRequest)
+		return req, false
+	}
+	if len(req.Notes) > maxIncidentNotes {
+		http.Error(w, "Notes too long", http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+// pathID parses a positive integer path parameter, writing a 400 on failure.
+func pathID(w http.ResponseWriter, r *http.Request, name string) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "Invalid "+name, http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// writeIncidentError maps incident store errors to HTTP responses.
+func writeIncidentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, incident.ErrNotFound):
+		http.Error(w, "Incident not found", http.StatusNotFound)
+	case errors.Is(err, incident.ErrInvalidItem):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		slog.Error("incident error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleListIncidents returns all incidents, newest first.
+func (s *HTTPServer) handleListIncidents(w http.ResponseWriter, r *http.Request) {
+	incidents, err := s.incidentStore.List(r.Context())
+	if err != nil {
+		writeIncidentError(w, err)
+		return
+	}
+
+	resp := make([]incidentJSON, 0, len(incidents))
+	for _, inc := range incidents {
+		resp = append(resp, toIncidentJSON(inc))
+	}
+	writeJSON(w, map[string]any{"incidents": resp})
+}
+
+// handleCreateIncident creates a new incident owned by the current user.
+func (s *HTTPServer) handleCreateIncident(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeIncidentRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var createdBy string
+	if user, ok := auth.UserFromContext(r.Context()); ok {
+		createdBy = user.Username
+	}
+
+	inc, err := s.incidentStore.Create(r.Context(), req.Title, req.Notes, createdBy)
+	if err != nil {
+		writeIncidentError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, toIncidentJSON(*inc))
+}
+
+// handleGetIncident returns an incident with its items, hydrating pinned entries.
+func (s *HTTPServer) handleGetIncident(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	inc, err := s.incidentStore.Get(r.Context(), id)
+	if err != nil {
+		writeIncidentError(w, err)
+		return
+	}
+
+	var ids []int64
+	for _, it := range inc.Items {
+		if it.Kind == incident.KindEntry {
+			ids = append(ids, it.EntryID)
+		}
+	}
+	entries, err := s.entriesByID(r, ids)
+	if err != nil {
+		slog.Error("get incident entries error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := toIncidentJSON(*inc)
+	for _, it := range inc.Items {
+		ij := toIncidentItemJSON(it)
+		if e, ok := entries[it.EntryID]; ok && it.Kind == incident.KindEntry {
+			ej := s.toJSON(e)
+			ij.Entry = &ej
+		}
+		resp.Items = append(resp.Items, ij)
+	}
+	writeJSON(w, resp)
+}
+
+// handleUpdateIncident replaces an incident's title and notes.
+func (s *HTTPServer) handleUpdateIncident(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	req, ok := decodeIncidentRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.incidentStore.Update(r.Context(), id, req.Title, req.Notes); err != nil {
+		writeIncidentError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeleteIncident removes an incident and its items.
+func (s *HTTPServer) handleDeleteIncident(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := s.incidentStore.Delete(r.Context(), id); err != nil {
+		writeIncidentError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAddIncidentItem pins a query, entry or marker to an incident.
+func (s *HTTPServer) handleAddIncidentItem(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req incidentItemJSON
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxIncidentBody)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	it := incident.Item{
+		Kind:    incident.ItemKind(req.Kind),
+		EntryID: req.EntryID,
+		Query:   req.Query,
+		Label:   req.Label,
+	}
+	if req.Timestamp > 0 {
+		it.Timestamp = time.Unix(0, req.Timestamp)
+	}
+
+	added, err := s.incidentStore.AddItem(r.Context(), id, it)
+	if err != nil {
+		writeIncidentError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, toIncidentItemJSON(*added))
+}
+
+// handleRemoveIncidentItem unpins an item from an incident.
+func (s *HTTPServer) handleRemoveIncidentItem(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := pathID(w, r, "itemId")
+	if !ok {
+		return
+	}
+
+	if err := s.incidentStore.RemoveItem(r.Context(), id, itemID); err != nil {
+		writeIncidentError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleExportIncident renders an incident as a Markdown postmortem document.
+func (s *HTTPServer) handleExportIncident(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	inc, err := s.incidentStore.Get(r.Context(), id)
+	if err != nil {
+		writeIncidentError(w, err)
+		return
+	}
+
+	var ids []int64
+	for _, it := range inc.Items {
+		if it.Kind == incident.KindEntry {
+			ids = append(ids, it.EntryID)
+		}
+	}
+	entries, err := s.entriesByID(r, ids)
+	if err != nil {
+		slog.Error("get incident entries error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="incident-%d.md"`, id))
+	if err := incident.WriteMarkdown(w, inc, entries); err != nil {
+		slog.Error("incident export error", "error", err)
+	}
+}
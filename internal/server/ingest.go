@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/kubelogs/kubelogs/api/storagepb"
+	"github.com/kubelogs/kubelogs/internal/queue"
+)
+
+const (
+	// ingestReadCount is the most batches read from the queue at once.
+	ingestReadCount = 10
+
+	// ingestBlock is how long a read waits for new batches.
+	ingestBlock = 5 * time.Second
+
+	// ingestClaimIdle is how long another consumer's batch must go
+	// unacknowledged before this one takes it over, and how often to
+	// look for such batches.
+	ingestClaimIdle = 2 * time.Minute
+
+	// ingestDedupWindow is the number of recently stored entry hashes
+	// the consumer remembers.
+	ingestDedupWindow = 100000
+
+	// ingestRetryMax caps the wait between retries while the queue or
+	// the store fails.
+	ingestRetryMax = 30 * time.Second
+)
+
+// IngestQueue is a queue of encoded WriteRequests, read through a
+// consumer group. *queue.Stream implements it.
+type IngestQueue interface {
+	CreateGroup(ctx context.Context) error
+	Read(ctx context.Context, count int, block time.Duration, pending bool) ([]queue.Message, error)
+	Claim(ctx context.Context, minIdle time.Duration) (int, error)
+	Ack(ctx context.Context, id string) error
+
+	// Done and MarkDone track batch IDs already stored, across
+	// consumers.
+	Done(ctx context.Context, batchID string) (bool, error)
+	MarkDone(ctx context.Context, batchID string) error
+}
+
+// ConsumeQueue writes the batches collectors publish to q, as if they had
+// been sent to Write, acknowledging each once stored. Batches this
+// consumer read but hadn't acknowledged, e.g. before a restart, are
+// written first, as are batches other consumers abandoned; a batch that
+// fails to store is retried until it succeeds.
+//
+// Redelivered batches, and copies published by collector retries, are
+// skipped by batch ID; entries this consumer stored recently in other
+// batches are dropped by dedup hash. Blocks until ctx is canceled.
+func (s *Server) ConsumeQueue(ctx context.Context, q IngestQueue) {
+	retry := time.Second
+	wait := func(err error, msg string) {
+		slog.Error(msg, "error", err, "retry_in", retry)
+		select {
+		case <-ctx.Done():
+		case <-time.After(retry):
+		}
+		retry = min(2*retry, ingestRetryMax)
+	}
+
+	for ctx.Err() == nil {
+		if err := q.CreateGroup(ctx); err != nil {
+			wait(err, "failed to create ingest queue group")
+			continue
+		}
+		break
+	}
+	slog.Info("ingest queue consumer started")
+
+	seen := newHashWindow(ingestDedupWindow)
+	pending := true
+	var lastClaim time.Time
+	for ctx.Err() == nil {
+		if time.Since(lastClaim) >= ingestClaimIdle {
+			lastClaim = time.Now()
+			n, err := q.Claim(ctx, ingestClaimIdle)
+			if err != nil && ctx.Err() == nil {
+				slog.Warn("failed to claim abandoned queued batches", "error", err)
+			}
+			if n > 0 {
+				slog.Info("claimed abandoned queued batches", "count", n)
+				pending = true
+			}
+		}
+
+		msgs, err := q.Read(ctx, ingestReadCount, ingestBlock, pending)
+		if err != nil {
+			if ctx.Err() == nil {
+				wait(err, "failed to read ingest queue")
+			}
+			continue
+		}
+		if pending && len(msgs) == 0 {
+			pending = false
+			continue
+		}
+
+		for _, m := range msgs {
+			if err := s.ingest(ctx, q, m, seen); err != nil {
+				if ctx.Err() == nil {
+					wait(err, "failed to store queued batch")
+				}
+				// Start over from the failed batch
+				pending = true
+				break
+			}
+			if err := q.Ack(ctx, m.ID); err != nil {
+				// Rewriting the batch later is harmless: entries are
+				// deduplicated
+				slog.Warn("failed to acknowledge queued batch", "id", m.ID, "error", err)
+			}
+			retry = time.Second
+		}
+	}
+}
+
+// ingest stores one queued batch. Batches that can't be decoded, or were
+// trimmed from the queue before being read, are dropped.
+func (s *Server) ingest(ctx context.Context, q IngestQueue, m queue.Message, seen *hashWindow) error {
+	var req storagepb.WriteRequest
+	if err := proto.Unmarshal(m.Data, &req); err != nil {
+		slog.Warn("dropping undecodable queued batch", "id", m.ID, "error", err)
+		return nil
+	}
+
+	if req.BatchId != "" {
+		done, err := q.Done(ctx, req.BatchId)
+		if err != nil {
+			return err
+		}
+		if done {
+			slog.Debug("skipping stored batch", "id", m.ID, "batch", req.BatchId)
+			return nil
+		}
+	}
+
+	entries := req.Entries[:0]
+	for _, e := range req.Entries {
+		if e.DedupHash == 0 || !seen.contains(e.DedupHash) {
+			entries = append(entries, e)
+		}
+	}
+	req.Entries = entries
+	if len(entries) > 0 {
+		if _, err := s.Write(ctx, &req); err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.DedupHash != 0 {
+				seen.add(e.DedupHash)
+			}
+		}
+	}
+
+	if req.BatchId != "" {
+		if err := q.MarkDone(ctx, req.BatchId); err != nil {
+			// A redelivery is still caught by the entry hashes
+			slog.Warn("failed to mark queued batch stored", "batch", req.BatchId, "error", err)
+		}
+	}
+	return nil
+}
+
+// hashWindow remembers the most recent dedup hashes, up to a limit.
+type hashWindow struct {
+	seen map[int64]struct{}
+	ring []int64
+	pos  int
+}
+
+func newHashWindow(size int) *hashWindow {
+	return &hashWindow{
+		seen: make(map[int64]struct{}),
+		ring: make([]int64, 0, size),
+	}
+}
+
+func (w *hashWindow) contains(h int64) bool {
+	_, ok := w.seen[h]
+	return ok
+}
+
+// add records a hash, forgetting the oldest beyond the limit.
+func (w *hashWindow) add(h int64) {
+	if w.contains(h) {
+		return
+	}
+	if len(w.ring) < cap(w.ring) {
+		w.ring = append(w.ring, h)
+	} else {
+		delete(w.seen, w.ring[w.pos])
+		w.ring[w.pos] = h
+		w.pos = (w.pos + 1) % len(w.ring)
+	}
+	w.seen[h] = struct{}{}
+}
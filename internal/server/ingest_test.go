@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/kubelogs/kubelogs/api/storagepb"
+	"github.com/kubelogs/kubelogs/internal/queue"
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+// memQueue is an in-memory IngestQueue.
+type memQueue struct {
+	mu      sync.Mutex
+	msgs    []queue.Message
+	next    int
+	pending []queue.Message
+	acked   []string
+	done    map[string]bool
+}
+
+func (q *memQueue) CreateGroup(context.Context) error { return nil }
+
+func (q *memQueue) Claim(context.Context, time.Duration) (int, error) { return 0, nil }
+
+func (q *memQueue) Done(_ context.Context, batchID string) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.done[batchID], nil
+}
+
+func (q *memQueue) MarkDone(_ context.Context, batchID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.done == nil {
+		q.done = make(map[string]bool)
+	}
+	q.done[batchID] = true
+	return nil
+}
+
+func (q *memQueue) Read(ctx context.Context, count int, block time.Duration, pending bool) ([]queue.Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if pending {
+		return append([]queue.Message(nil), q.pending...), nil
+	}
+	var msgs []queue.Message
+	for ; q.next < len(q.msgs) && len(msgs) < count; q.next++ {
+		msgs = append(msgs, q.msgs[q.next])
+		q.pending = append(q.pending, q.msgs[q.next])
+	}
+	if len(msgs) == 0 {
+		q.mu.Unlock()
+		select {
+		case <-ctx.Done():
+		case <-time.After(10 * time.Millisecond):
+		}
+		q.mu.Lock()
+	}
+	return msgs, nil
+}
+
+func (q *memQueue) Ack(_ context.Context, id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.acked = append(q.acked, id)
+	for i, m := range q.pending {
+		if m.ID == id {
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (q *memQueue) ackedCount() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.acked)
+}
+
+// failingStore fails the first writes, then delegates. It counts the
+// entries it was asked to store.
+type failingStore struct {
+	storage.Store
+	mu       sync.Mutex
+	failures int
+	written  int
+}
+
+func (s *failingStore) Write(ctx context.Context, entries storage.LogBatch) (int, error) {
+	s.mu.Lock()
+	if s.failures > 0 {
+		s.failures--
+		s.mu.Unlock()
+		return 0, errors.New("disk full")
+	}
+	s.written += len(entries)
+	s.mu.Unlock()
+	return s.Store.Write(ctx, entries)
+}
+
+func TestConsumeQueue(t *testing.T) {
+	db, err := sqlite.New(sqlite.Config{Path: ":memory:", WriteBufferSize: 1})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer db.Close()
+
+	batch := func(msg string) []byte {
+		data, _ := proto.Marshal(&storagepb.WriteRequest{Entries: []*storagepb.LogEntry{{
+			TimestampNanos: time.Now().UnixNano(),
+			Namespace:      "default",
+			Pod:            "web",
+			Container:      "app",
+			Message:        msg,
+		}}})
+		return data
+	}
+	q := &memQueue{msgs: []queue.Message{
+		{ID: "1-0", Data: batch("first")},
+		{ID: "2-0", Data: []byte("not a protobuf \xff\xff")},
+		{ID: "3-0", Data: nil}, // Trimmed while pending
+		{ID: "4-0", Data: batch("second")},
+	}}
+
+	// The first write fails and is retried
+	srv := New(&failingStore{Store: db, failures: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		srv.ConsumeQueue(ctx, q)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for q.ackedCount() < 4 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if n := q.ackedCount(); n != 4 {
+		t.Fatalf("acked %d batches, want 4", n)
+	}
+	result, err := db.Query(context.Background(), storage.Query{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(result.Entries) != 2 {
+		t.Errorf("stored %d entries, want 2", len(result.Entries))
+	}
+}
+
+func TestConsumeQueue_Dedup(t *testing.T) {
+	db, err := sqlite.New(sqlite.Config{Path: ":memory:", WriteBufferSize: 1})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer db.Close()
+
+	entry := func(msg string, hash int64) *storagepb.LogEntry {
+		return &storagepb.LogEntry{TimestampNanos: 1, Namespace: "default", Pod: "web", Container: "app", Message: msg, DedupHash: hash}
+	}
+	batch := func(id string, entries ...*storagepb.LogEntry) []byte {
+		data, _ := proto.Marshal(&storagepb.WriteRequest{BatchId: id, Entries: entries})
+		return data
+	}
+	q := &memQueue{msgs: []queue.Message{
+		{ID: "1-0", Data: batch("b1", entry("a", 1), entry("b", 2))},
+		{ID: "2-0", Data: batch("b1", entry("a", 1), entry("b", 2))}, // Published twice
+		{ID: "3-0", Data: batch("b2", entry("b", 2), entry("c", 3))}, // Overlaps b1
+		{ID: "4-0", Data: batch("", entry("d", 0))},                  // No IDs
+	}}
+
+	store := &failingStore{Store: db}
+	srv := New(store)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		srv.ConsumeQueue(ctx, q)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for q.ackedCount() < 4 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.written != 4 {
+		t.Errorf("store asked to write %d entries, want 4 (a, b, c, d)", store.written)
+	}
+}
+
+func TestHashWindow(t *testing.T) {
+	w := newHashWindow(2)
+	w.add(1)
+	w.add(2)
+	w.add(2)
+	if !w.contains(1) || !w.contains(2) {
+		t.Fatal("window lost a hash within its size")
+	}
+	w.add(3)
+	if w.contains(1) || !w.contains(2) || !w.contains(3) {
+		t.Errorf("window = %v, want oldest evicted", w.seen)
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/auth"
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// ledgerFailureJSON is a ledger row failing verification.
+type ledgerFailureJSON struct {
+	Seq     int64  `json:"seq"`
+	FirstID int64  `json:"firstId"`
+	LastID  int64  `json:"lastId"`
+	Reason  string `json:"reason"`
+}
+
+// ledgerResponse is the JSON response of a ledger verification.
+type ledgerResponse struct {
+	Verified bool                `json:"verified"`
+	Flushes  int64               `json:"flushes"`
+	Deletes  int64               `json:"deletes"`
+	Entries  int64               `json:"entries"`
+	Deleted  int64               `json:"deleted"`
+	HeadSeq  int64               `json:"headSeq"`
+	HeadHash string              `json:"headHash,omitempty"`
+	Failed   int64               `json:"failed"`
+	Failures []ledgerFailureJSON `json:"failures"`
+}
+
+// handleVerifyLedger checks the store's hash chain against the stored
+// entries. It reads every entry the ledger covers, so it can take a
+// while on large stores.
+func (s *HTTPServer) handleVerifyLedger(w http.ResponseWriter, r *http.Request) {
+	lv, ok := s.store.(storage.LedgerVerifier)
+	if !ok {
+		http.Error(w, "Storage backend does not support ledger verification", http.StatusNotImplemented)
+		return
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	start := time.Now()
+	keepWriting(w)
+	report, err := lv.VerifyLedger(r.Context())
+	if err != nil {
+		slog.Error("ledger verification error", "user", user.Username, "error", err)
+		if errors.Is(err, storage.ErrStorageClosed) {
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	attrs := []any{"user", user.Username, "flushes", report.Flushes, "deletes", report.Deletes,
+		"entries", report.Entries, "deleted", report.Deleted, "failed", report.Failed, "duration", time.Since(start).Round(time.Millisecond)}
+	if report.Failed > 0 {
+		slog.Warn("ledger verification failed", attrs...)
+	} else {
+		slog.Info("ledger verified", attrs...)
+	}
+
+	resp := ledgerResponse{
+		Verified: report.Failed == 0,
+		Flushes:  report.Flushes,
+		Deletes:  report.Deletes,
+		Entries:  report.Entries,
+		Deleted:  report.Deleted,
+		HeadSeq:  report.HeadSeq,
+		HeadHash: report.HeadHash,
+		Failed:   report.Failed,
+		Failures: make([]ledgerFailureJSON, 0, len(report.Failures)),
+	}
+	for _, f := range report.Failures {
+		resp.Failures = append(resp.Failures, ledgerFailureJSON{Seq: f.Seq, FirstID: f.FirstID, LastID: f.LastID, Reason: f.Reason})
+	}
+	writeJSON(w, resp)
+}
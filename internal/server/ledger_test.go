@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/auth"
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestVerifyLedger(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:", HashChain: true})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	store.Write(ctx, storage.LogBatch{
+		{Timestamp: now, Namespace: "a", Pod: "pod", Container: "c", Message: "one"},
+		{Timestamp: now, Namespace: "a", Pod: "pod", Container: "c", Message: "two"},
+	})
+	store.Flush(ctx)
+
+	s := &HTTPServer{store: store, adminUsers: map[string]bool{"root": true}}
+	do := func(user *auth.User) (*httptest.ResponseRecorder, ledgerResponse) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/ledger/verify", nil)
+		req = req.WithContext(auth.ContextWithUser(req.Context(), user))
+		rec := httptest.NewRecorder()
+		s.requireAdmin(s.handleVerifyLedger).ServeHTTP(rec, req)
+		var resp ledgerResponse
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+		}
+		return rec, resp
+	}
+	root := &auth.User{ID: 1, Username: "root"}
+
+	if rec, _ := do(&auth.User{ID: 2, Username: "alice"}); rec.Code != http.StatusForbidden {
+		t.Errorf("non-admin status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	rec, resp := do(root)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	if !resp.Verified || resp.Flushes != 1 || resp.Entries != 2 || resp.HeadSeq != 1 || len(resp.HeadHash) != 64 {
+		t.Errorf("response = %+v, want 1 flush of 2 entries verified", resp)
+	}
+
+	if _, err := store.DB().Exec(`UPDATE log_ledger SET created_at = created_at + 1`); err != nil {
+		t.Fatalf("tamper: %v", err)
+	}
+	_, resp = do(root)
+	if resp.Verified || resp.Failed != 1 || len(resp.Failures) != 1 || resp.Failures[0].Reason != storage.LedgerChainMismatch {
+		t.Errorf("response after tampering = %+v, want a chain mismatch", resp)
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Link kinds found in messages.
+const (
+	linkURL   = "url"
+	linkTrace = "trace"
+)
+
+var (
+	// urlPattern matches http(s) URLs up to whitespace, quotes, angle
+	// brackets or control characters (including ANSI escapes).
+	urlPattern = regexp.MustCompile(`https?://[^\s"'<>` + "`" + `\x00-\x1f\x7f]+`)
+
+	// traceparentPattern matches W3C traceparent values; the trace ID is
+	// the first group.
+	traceparentPattern = regexp.MustCompile(`\b[0-9a-f]{2}-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}\b`)
+)
+
+// messageLink marks a span of a message the UI renders as a link. The
+// server finds links so the UI never has to run its own patterns over
+// untrusted text; it escapes the message and wraps only these spans.
+type messageLink struct {
+	// Start and End are offsets in UTF-16 code units, as JavaScript
+	// indexes strings.
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Kind  string `json:"kind"`
+
+	// Href is the link target: always http(s) for URLs, and for traces
+	// the configured trace viewer. Trace links without one filter the
+	// UI by trace ID instead.
+	Href string `json:"href,omitempty"`
+
+	// TraceID is set for trace links.
+	TraceID string `json:"traceId,omitempty"`
+}
+
+// byteSpan is a link with byte offsets, before conversion for the UI.
+type byteSpan struct {
+	start, end int
+	link       messageLink
+}
+
+// findLinks returns the URLs and trace IDs in a message, in order and
+// not overlapping. Trace IDs are the entry's trace_id attribute and IDs
+// in W3C traceparent values; traceURL, if set, is a URL with a
+// "{traceId}" placeholder they link to.
+func findLinks(msg string, attrs map[string]string, traceURL string) []messageLink {
+	var spans []byteSpan
+
+	for _, m := range urlPattern.FindAllStringIndex(msg, -1) {
+		end := m[0] + len(trimURL(msg[m[0]:m[1]]))
+		u, err := url.Parse(msg[m[0]:end])
+		if err != nil || u.Host == "" {
+			continue
+		}
+		spans = append(spans, byteSpan{m[0], end, messageLink{Kind: linkURL, Href: u.String()}})
+	}
+
+	traceLink := func(id string) messageLink {
+		l := messageLink{Kind: linkTrace, TraceID: id}
+		if traceURL != "" {
+			l.Href = strings.ReplaceAll(traceURL, "{traceId}", url.PathEscape(id))
+		}
+		return l
+	}
+	for _, m := range traceparentPattern.FindAllStringSubmatchIndex(msg, -1) {
+		spans = append(spans, byteSpan{m[2], m[3], traceLink(msg[m[2]:m[3]])})
+	}
+	if id := attrs["trace_id"]; len(id) >= 8 {
+		for off := 0; ; {
+			i := strings.Index(msg[off:], id)
+			if i < 0 {
+				break
+			}
+			start := off + i
+			spans = append(spans, byteSpan{start, start + len(id), traceLink(id)})
+			off = start + len(id)
+		}
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+
+	// Earlier spans win; URLs come first among spans at the same offset
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	links := make([]messageLink, 0, len(spans))
+	pos, units, last := 0, 0, -1
+	for _, sp := range spans {
+		if sp.start < last {
+			continue
+		}
+		units += utf16Len(msg[pos:sp.start])
+		sp.link.Start = units
+		units += utf16Len(msg[sp.start:sp.end])
+		sp.link.End = units
+		pos, last = sp.end, sp.end
+		links = append(links, sp.link)
+	}
+	return links
+}
+
+// trimURL drops trailing punctuation that more likely ends the sentence
+// than the URL, keeping closing brackets that have an opening match.
+func trimURL(s string) string {
+	for len(s) > 0 {
+		c := s[len(s)-1]
+		switch {
+		case strings.IndexByte(".,;:!?", c) >= 0:
+		case c == ')' && strings.Count(s, "(") < strings.Count(s, ")"):
+		case c == ']' && strings.Count(s, "[") < strings.Count(s, "]"):
+		case c == '}' && strings.Count(s, "{") < strings.Count(s, "}"):
+		default:
+			return s
+		}
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// utf16Len returns the length of s in UTF-16 code units.
+func utf16Len(s string) int {
+	n := 0
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		n += utf16.RuneLen(r)
+		s = s[size:]
+	}
+	return n
+}
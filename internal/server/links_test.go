@@ -0,0 +1,96 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFindLinks(t *testing.T) {
+	const trace = "4bf92f3577b34da6a3ce929d0e0736c1"
+
+	tests := []struct {
+		name     string
+		msg      string
+		attrs    map[string]string
+		traceURL string
+		want     string
+	}{
+		{
+			name: "no links",
+			msg:  "plain message",
+			want: "[]",
+		},
+		{
+			name: "url",
+			msg:  "GET https://example.com/a?b=1 done",
+			want: "[4-29 url https://example.com/a?b=1]",
+		},
+		{
+			name: "trailing punctuation",
+			msg:  "see http://example.com/x.",
+			want: "[4-24 url http://example.com/x]",
+		},
+		{
+			name: "balanced parens",
+			msg:  "(http://example.com/wiki/Go_(lang))",
+			want: "[1-34 url http://example.com/wiki/Go_(lang)]",
+		},
+		{
+			name: "quotes and markup end urls",
+			msg:  `<a href="http://example.com/">x</a>`,
+			want: "[9-28 url http://example.com/]",
+		},
+		{
+			name: "ansi escape ends url",
+			msg:  "\x1b[32mhttp://example.com\x1b[0m",
+			want: "[5-23 url http://example.com]",
+		},
+		{
+			name: "other schemes ignored",
+			msg:  "javascript:alert(1) ftp://example.com",
+			want: "[]",
+		},
+		{
+			name: "utf16 offsets",
+			msg:  "🚀 é http://example.com",
+			want: "[5-23 url http://example.com]",
+		},
+		{
+			name:  "trace attribute",
+			msg:   "trace_id=" + trace + " failed",
+			attrs: map[string]string{"trace_id": trace},
+			want:  "[9-41 trace  " + trace + "]",
+		},
+		{
+			name:     "traceparent with viewer",
+			msg:      "traceparent: 00-" + trace + "-00f067aa0ba902b7-01",
+			traceURL: "https://jaeger.example.com/trace/{traceId}",
+			want:     "[16-48 trace https://jaeger.example.com/trace/" + trace + " " + trace + "]",
+		},
+		{
+			name:  "trace inside url is not linked twice",
+			msg:   "http://jaeger/trace/" + trace,
+			attrs: map[string]string{"trace_id": trace},
+			want:  "[0-52 url http://jaeger/trace/" + trace + "]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := "["
+			for i, l := range findLinks(tt.msg, tt.attrs, tt.traceURL) {
+				if i > 0 {
+					got += " "
+				}
+				got += fmt.Sprintf("%d-%d %s %s", l.Start, l.End, l.Kind, l.Href)
+				if l.TraceID != "" {
+					got += " " + l.TraceID
+				}
+			}
+			got += "]"
+			if got != tt.want {
+				t.Errorf("findLinks(%q) = %s, want %s", tt.msg, got, tt.want)
+			}
+		})
+	}
+}
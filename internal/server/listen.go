@@ -0,0 +1,49 @@
+package server
+
+import (
+	"fmt"
+	"net"
+)
+
+// ResolveListenAddr returns addr with a network interface name in the
+// host part replaced by the interface's address, preferring IPv4, so
+// "eth0:50051" binds to that interface only. Other addresses are
+// returned unchanged.
+func ResolveListenAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid listen address %q: %w", addr, err)
+	}
+	if host == "" || net.ParseIP(host) != nil {
+		return addr, nil
+	}
+
+	iface, err := net.InterfaceByName(host)
+	if err != nil {
+		// Not an interface; let the listener resolve it as a host name
+		return addr, nil
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", fmt.Errorf("interface %s: %w", host, err)
+	}
+
+	var ip net.IP
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			ip = ipNet.IP
+			break
+		}
+		if ip == nil && !ipNet.IP.IsLinkLocalUnicast() {
+			ip = ipNet.IP
+		}
+	}
+	if ip == nil {
+		return "", fmt.Errorf("interface %s has no usable address", host)
+	}
+	return net.JoinHostPort(ip.String(), port), nil
+}
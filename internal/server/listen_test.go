@@ -0,0 +1,30 @@
+package server
+
+import "testing"
+
+func TestResolveListenAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		want    string
+		wantErr bool
+	}{
+		{addr: ":50051", want: ":50051"},
+		{addr: "0.0.0.0:8080", want: "0.0.0.0:8080"},
+		{addr: "[::1]:8080", want: "[::1]:8080"},
+		{addr: "localhost:8080", want: "localhost:8080"},
+		{addr: "lo:8080", want: "127.0.0.1:8080"},
+		{addr: "8080", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			got, err := ResolveListenAddr(tt.addr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveListenAddr(%q) error = %v, wantErr %v", tt.addr, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveListenAddr(%q) = %q, want %q", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
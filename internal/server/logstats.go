@@ -0,0 +1,274 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/logstats"
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+const (
+	// logStatsSettle is how far back each run recounts hours it already
+	// counted, so entries arriving late, e.g. from a collector catching
+	// up, are included.
+	logStatsSettle = time.Hour
+
+	// logStatsChunk bounds the time range counted per store query.
+	logStatsChunk = 24 * time.Hour
+
+	// logStatsBackfill is how far back the first run counts when counts
+	// are kept forever.
+	logStatsBackfill = 30 * 24 * time.Hour
+
+	// defaultTimeseriesRange is the time range returned when the request
+	// has no startTime.
+	defaultTimeseriesRange = 7 * 24 * time.Hour
+)
+
+// StatsAggregator keeps hourly entry counts per namespace and severity
+// (see package logstats) up to date from the log store, so that
+// /api/stats/timeseries doesn't scan entries.
+type StatsAggregator struct {
+	store         storage.Store
+	stats         *logstats.Store
+	interval      time.Duration
+	retentionDays int
+
+	// next is the start of the first hour not yet counted for good, zero
+	// before the first run
+	next time.Time
+}
+
+// NewStatsAggregator creates an aggregator counting the entries of store
+// into the log_stats table of db.
+func NewStatsAggregator(store storage.Store, db *sql.DB, cfg Config) *StatsAggregator {
+	return &StatsAggregator{
+		store:         store,
+		stats:         logstats.NewStore(db),
+		interval:      cfg.LogStatsInterval,
+		retentionDays: cfg.LogStatsRetentionDays,
+	}
+}
+
+// Run counts new entries every interval. Blocks until ctx is canceled.
+func (a *StatsAggregator) Run(ctx context.Context) {
+	ga, ok := a.store.(storage.GroupAggregator)
+	if !ok {
+		slog.Warn("log store can't aggregate entries, hourly stats disabled")
+		return
+	}
+
+	slog.Info("log stats aggregator starting",
+		"interval", a.interval,
+		"retention_days", a.retentionDays,
+	)
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		if err := a.runOnce(ctx, ga, time.Now()); err != nil && ctx.Err() == nil {
+			slog.Error("log stats aggregation failed", "error", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			slog.Info("log stats aggregator stopping")
+			return
+		}
+	}
+}
+
+// runOnce counts the entries of the hours since the last run, up to the
+// one in progress at now, and drops counts older than the retention.
+func (a *StatsAggregator) runOnce(ctx context.Context, ga storage.GroupAggregator, now time.Time) error {
+	end := now.Truncate(logstats.Interval).Add(logstats.Interval)
+
+	start := a.next.Add(-logStatsSettle)
+	if a.next.IsZero() {
+		latest, ok, err := a.stats.Latest(ctx)
+		if err != nil {
+			return fmt.Errorf("read latest stats: %w", err)
+		}
+		if ok {
+			start = latest.Add(-logStatsSettle)
+		} else {
+			start = end.Add(-a.backfill())
+		}
+	}
+
+	for start.Before(end) {
+		chunkEnd := start.Add(logStatsChunk)
+		if chunkEnd.After(end) {
+			chunkEnd = end
+		}
+		if err := a.count(ctx, ga, start, chunkEnd); err != nil {
+			return err
+		}
+		start = chunkEnd
+	}
+	// The hour in progress is counted again next time
+	a.next = end.Add(-logstats.Interval)
+
+	if a.retentionDays > 0 {
+		cutoff := now.Add(-time.Duration(a.retentionDays) * 24 * time.Hour).Truncate(logstats.Interval)
+		deleted, err := a.stats.DeleteBefore(ctx, cutoff)
+		if err != nil {
+			return fmt.Errorf("delete old stats: %w", err)
+		}
+		if deleted > 0 {
+			slog.Debug("deleted old log stats", "rows", deleted, "cutoff", cutoff.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// backfill returns how far back the first run counts.
+func (a *StatsAggregator) backfill() time.Duration {
+	if a.retentionDays > 0 {
+		return time.Duration(a.retentionDays) * 24 * time.Hour
+	}
+	return logStatsBackfill
+}
+
+// count replaces the counts of the hours in [start, end) with those of
+// the store.
+func (a *StatsAggregator) count(ctx context.Context, ga storage.GroupAggregator, start, end time.Time) error {
+	groups, err := ga.Aggregate(ctx,
+		storage.Query{StartTime: start, EndTime: end},
+		storage.Aggregation{
+			GroupBy:  []string{storage.GroupByNamespace, storage.GroupBySeverity},
+			Interval: logstats.Interval,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("aggregate entries: %w", err)
+	}
+
+	counts := make([]logstats.Count, len(groups))
+	for i, g := range groups {
+		counts[i] = logstats.Count{
+			Hour:      g.Start,
+			Namespace: g.Keys[0],
+			Severity:  storage.ParseSeverity(g.Keys[1]),
+			Count:     g.Count,
+		}
+	}
+	if err := a.stats.Replace(ctx, start, end, counts); err != nil {
+		return fmt.Errorf("write stats: %w", err)
+	}
+	return nil
+}
+
+// timeseriesResponse is the JSON response for namespace time series.
+type timeseriesResponse struct {
+	Interval int64            `json:"interval"` // Bucket width in milliseconds
+	Series   []timeseriesJSON `json:"series"`
+}
+
+// timeseriesJSON is the volume of one namespace over time.
+type timeseriesJSON struct {
+	Namespace string                `json:"namespace"`
+	Total     int64                 `json:"total"`
+	Buckets   []histogramBucketJSON `json:"buckets"`
+}
+
+// handleTimeseries returns entry counts per namespace and severity over
+// time from the hourly stats, for dashboards spanning more than raw
+// entries can be scanned for. The range defaults to the week before
+// endTime (or now) and is split into interval-wide buckets, a whole
+// number of hours, 1h by default; tz aligns them as for the histogram.
+// namespace limits the response to one namespace. Series are sorted by
+// total, largest first, and every bucket in the range is returned.
+func (s *HTTPServer) handleTimeseries(w http.ResponseWriter, r *http.Request) {
+	if s.logStats == nil {
+		http.Error(w, "Hourly stats are disabled", http.StatusNotImplemented)
+		return
+	}
+
+	q, err := s.parseQueryParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.EndTime.IsZero() {
+		q.EndTime = time.Now()
+	}
+	if q.StartTime.IsZero() {
+		q.StartTime = q.EndTime.Add(-defaultTimeseriesRange)
+	}
+	if !q.StartTime.Before(q.EndTime) {
+		http.Error(w, "startTime must be before endTime", http.StatusBadRequest)
+		return
+	}
+
+	interval := logstats.Interval
+	if v := r.URL.Query().Get("interval"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 || d%logstats.Interval != 0 {
+			http.Error(w, fmt.Sprintf("invalid interval %q: want a whole number of hours", v), http.StatusBadRequest)
+			return
+		}
+		interval = d
+	}
+	loc, err := parseTimezone(r.URL.Query().Get("tz"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	starts := histogramStarts(q.StartTime, q.EndTime, interval, loc)
+	if len(starts) > maxHistogramBuckets {
+		http.Error(w, fmt.Sprintf("interval %v gives more than %d buckets", interval, maxHistogramBuckets), http.StatusBadRequest)
+		return
+	}
+
+	// Hours partly in the range are counted whole
+	counts, err := s.logStats.List(r.Context(), q.StartTime.Truncate(logstats.Interval), q.EndTime, q.Namespace)
+	if err != nil {
+		slog.Error("timeseries error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	series := make(map[string]*timeseriesJSON)
+	for _, c := range counts {
+		ts := c.Hour.UnixNano()
+		i := sort.Search(len(starts), func(i int) bool { return starts[i] > ts }) - 1
+		if i < 0 || c.Severity > storage.SeverityFatal {
+			continue
+		}
+		sr, ok := series[c.Namespace]
+		if !ok {
+			sr = &timeseriesJSON{Namespace: c.Namespace, Buckets: make([]histogramBucketJSON, len(starts))}
+			for j, start := range starts {
+				sr.Buckets[j].Timestamp = start
+			}
+			series[c.Namespace] = sr
+		}
+		sr.Buckets[i].Counts[c.Severity] += c.Count
+		sr.Buckets[i].Total += c.Count
+		sr.Total += c.Count
+	}
+
+	resp := timeseriesResponse{
+		Interval: interval.Milliseconds(),
+		Series:   make([]timeseriesJSON, 0, len(series)),
+	}
+	for _, sr := range series {
+		resp.Series = append(resp.Series, *sr)
+	}
+	slices.SortFunc(resp.Series, func(a, b timeseriesJSON) int {
+		if c := cmp.Compare(b.Total, a.Total); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Namespace, b.Namespace)
+	})
+	writeJSON(w, resp)
+}
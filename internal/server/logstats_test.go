@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/logstats"
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestStatsAggregator(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	base := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	write := func(ts time.Time, ns string, sev storage.Severity) {
+		t.Helper()
+		store.Write(ctx, storage.LogBatch{{Timestamp: ts, Namespace: ns, Pod: "p", Container: "c", Severity: sev, Message: "m"}})
+		if err := store.Flush(ctx); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+	}
+	write(base.Add(-5*24*time.Hour), "old", storage.SeverityInfo) // Before the retention
+	write(base.Add(-2*time.Hour+15*time.Minute), "app", storage.SeverityInfo)
+	write(base.Add(-2*time.Hour+20*time.Minute), "app", storage.SeverityError)
+	write(base.Add(-time.Hour+5*time.Minute), "app", storage.SeverityInfo)
+	write(base.Add(10*time.Minute), "db", storage.SeverityWarn)
+
+	cfg := DefaultConfig()
+	cfg.LogStatsRetentionDays = 3
+	agg := NewStatsAggregator(store, store.DB(), cfg)
+	if err := agg.runOnce(ctx, store, base.Add(30*time.Minute)); err != nil {
+		t.Fatalf("runOnce: %v", err)
+	}
+
+	// A late entry in the previous hour and one in the current hour are
+	// counted by the next run
+	write(base.Add(-time.Hour+50*time.Minute), "app", storage.SeverityInfo)
+	write(base.Add(35*time.Minute), "db", storage.SeverityWarn)
+	if err := agg.runOnce(ctx, store, base.Add(40*time.Minute)); err != nil {
+		t.Fatalf("runOnce: %v", err)
+	}
+
+	counts, err := logstats.NewStore(store.DB()).List(ctx, time.Unix(0, 0), base.Add(24*time.Hour), "")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	want := []logstats.Count{
+		{Hour: base.Add(-2 * time.Hour), Namespace: "app", Severity: storage.SeverityInfo, Count: 1},
+		{Hour: base.Add(-2 * time.Hour), Namespace: "app", Severity: storage.SeverityError, Count: 1},
+		{Hour: base.Add(-time.Hour), Namespace: "app", Severity: storage.SeverityInfo, Count: 2},
+		{Hour: base, Namespace: "db", Severity: storage.SeverityWarn, Count: 2},
+	}
+	if len(counts) != len(want) {
+		t.Fatalf("counts = %+v, want %+v", counts, want)
+	}
+	for i := range want {
+		if !counts[i].Hour.Equal(want[i].Hour) || counts[i].Namespace != want[i].Namespace ||
+			counts[i].Severity != want[i].Severity || counts[i].Count != want[i].Count {
+			t.Errorf("counts[%d] = %+v, want %+v", i, counts[i], want[i])
+		}
+	}
+
+	// Counts beyond the retention are dropped
+	if err := agg.runOnce(ctx, store, base.Add(3*24*time.Hour-30*time.Minute)); err != nil {
+		t.Fatalf("runOnce: %v", err)
+	}
+	counts, _ = logstats.NewStore(store.DB()).List(ctx, time.Unix(0, 0), base.Add(24*time.Hour), "")
+	if len(counts) != 2 || !counts[0].Hour.Equal(base.Add(-time.Hour)) {
+		t.Errorf("counts after retention = %+v, want those from %v", counts, base.Add(-time.Hour))
+	}
+}
+
+func TestHandleTimeseries(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	base := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	stats := logstats.NewStore(store.DB())
+	err = stats.Replace(ctx, base, base.Add(48*time.Hour), []logstats.Count{
+		{Hour: base.Add(time.Hour), Namespace: "app", Severity: storage.SeverityInfo, Count: 5},
+		{Hour: base.Add(2 * time.Hour), Namespace: "app", Severity: storage.SeverityError, Count: 2},
+		{Hour: base.Add(25 * time.Hour), Namespace: "app", Severity: storage.SeverityInfo, Count: 1},
+		{Hour: base.Add(3 * time.Hour), Namespace: "db", Severity: storage.SeverityInfo, Count: 20},
+	})
+	if err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+
+	s := &HTTPServer{store: store, logStats: stats}
+	timeseries := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/stats/timeseries?"+query, nil)
+		rec := httptest.NewRecorder()
+		s.handleTimeseries(rec, req)
+		return rec
+	}
+
+	rec := timeseries("startTime=2024-01-10T00:00:00Z&endTime=2024-01-12T00:00:00Z&interval=24h")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var resp timeseriesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Interval != (24 * time.Hour).Milliseconds() {
+		t.Errorf("interval = %d, want a day", resp.Interval)
+	}
+	if len(resp.Series) != 2 || resp.Series[0].Namespace != "db" || resp.Series[1].Namespace != "app" {
+		t.Fatalf("series = %+v, want db then app", resp.Series)
+	}
+	app := resp.Series[1]
+	if app.Total != 8 || len(app.Buckets) != 2 {
+		t.Fatalf("app = %+v, want 8 entries in 2 buckets", app)
+	}
+	if b := app.Buckets[0]; b.Timestamp != base.UnixNano() || b.Total != 7 ||
+		b.Counts[storage.SeverityInfo] != 5 || b.Counts[storage.SeverityError] != 2 {
+		t.Errorf("app day 1 = %+v, want 5 info and 2 errors", b)
+	}
+	if b := app.Buckets[1]; b.Total != 1 {
+		t.Errorf("app day 2 total = %d, want 1", b.Total)
+	}
+
+	rec = timeseries("namespace=app&startTime=2024-01-10T00:00:00Z&endTime=2024-01-10T04:00:00Z")
+	resp = timeseriesResponse{}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp.Series) != 1 || len(resp.Series[0].Buckets) != 4 || resp.Series[0].Buckets[1].Total != 5 {
+		t.Errorf("hourly app series = %+v, want 4 hours with 5 in the second", resp.Series)
+	}
+
+	for _, query := range []string{"interval=30m", "interval=90m", "startTime=2024-01-11T00:00:00Z&endTime=2024-01-10T00:00:00Z"} {
+		if rec := timeseries(query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+
+	s.logStats = nil
+	if rec := timeseries(""); rec.Code != http.StatusNotImplemented {
+		t.Errorf("disabled: status = %d, want 501", rec.Code)
+	}
+}
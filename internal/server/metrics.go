@@ -0,0 +1,59 @@
+package server
+
+import (
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/metrics"
+)
+
+// serverMetrics holds the instruments Server updates. Unregistered
+// instruments are nil and record nothing.
+type serverMetrics struct {
+	writeRequests  *metrics.Counter
+	writeErrors    *metrics.Counter
+	writtenEntries *metrics.Counter
+	droppedEntries *metrics.Counter
+	throttled      *metrics.Counter
+	queryDuration  *metrics.Histogram
+	queryTimeouts  *metrics.Counter
+}
+
+// queryDurationMetric and queryTimeoutsMetric are shared by the gRPC and
+// HTTP query paths, which are told apart by the api label.
+const (
+	queryDurationMetric = "kubelogs_server_query_duration_seconds"
+	queryTimeoutsMetric = "kubelogs_server_query_timeouts_total"
+)
+
+// RegisterMetrics registers the server's write and query metrics with r.
+// Call before serving.
+func (s *Server) RegisterMetrics(r *metrics.Registry) {
+	s.metrics = serverMetrics{
+		writeRequests:  r.Counter("kubelogs_server_write_requests_total", "Write requests received over gRPC or the ingest queue."),
+		writeErrors:    r.Counter("kubelogs_server_write_errors_total", "Write requests that failed to store their entries."),
+		writtenEntries: r.Counter("kubelogs_server_written_entries_total", "Log entries stored."),
+		droppedEntries: r.Counter("kubelogs_server_quota_dropped_entries_total", "Log entries dropped by cluster quotas."),
+		throttled:      r.Counter("kubelogs_server_throttled_writes_total", "Write requests asked to slow down or rejected for lack of disk space."),
+		queryDuration:  r.Histogram(queryDurationMetric, "Time spent answering log queries.", metrics.DefBuckets, "api", "grpc"),
+		queryTimeouts:  r.Counter(queryTimeoutsMetric, "Log queries cancelled by the query timeout.", "api", "grpc"),
+	}
+}
+
+// RegisterMetrics registers the web UI's query latency and timeouts with r.
+func (s *HTTPServer) RegisterMetrics(r *metrics.Registry) {
+	s.queryDuration = r.Histogram(queryDurationMetric, "Time spent answering log queries.", metrics.DefBuckets, "api", "http")
+	s.queryTimeouts = r.Counter(queryTimeoutsMetric, "Log queries cancelled by the query timeout.", "api", "http")
+}
+
+// RegisterMetrics exposes the worker's run and deletion counts on r.
+func (w *RetentionWorker) RegisterMetrics(r *metrics.Registry) {
+	r.CounterFunc("kubelogs_server_retention_runs_total", "Retention passes completed.",
+		func() float64 { return float64(w.totalRuns.Load()) })
+	r.CounterFunc("kubelogs_server_retention_deleted_entries_total", "Log entries deleted by retention.",
+		func() float64 { return float64(w.totalDeleted.Load()) })
+}
+
+// since returns the seconds elapsed since start, for latency histograms.
+func since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/api/storagepb"
+	"github.com/kubelogs/kubelogs/internal/metrics"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestServer_Metrics(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:", WriteBufferSize: 1})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	reg := metrics.NewRegistry()
+	srv := New(store)
+	srv.SetClusterQuotas(map[string]int64{"small": 1})
+	srv.RegisterMetrics(reg)
+
+	ctx := context.Background()
+	now := time.Now().UnixNano()
+	_, err = srv.Write(ctx, &storagepb.WriteRequest{Entries: []*storagepb.LogEntry{
+		{TimestampNanos: now, Cluster: "small", Namespace: "default", Pod: "a", Container: "app", Message: "one"},
+		{TimestampNanos: now, Cluster: "small", Namespace: "default", Pod: "a", Container: "app", Message: "two"},
+		{TimestampNanos: now, Cluster: "big", Namespace: "default", Pod: "a", Container: "app", Message: "three"},
+	}})
+	if err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if _, err := srv.Query(ctx, &storagepb.QueryRequest{}); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		"kubelogs_server_write_requests_total 1\n",
+		"kubelogs_server_write_errors_total 0\n",
+		"kubelogs_server_written_entries_total 2\n",
+		"kubelogs_server_quota_dropped_entries_total 1\n",
+		`kubelogs_server_query_duration_seconds_count{api="grpc"} 1` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
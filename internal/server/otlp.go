@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/kubelogs/kubelogs/api/otlppb"
+	"github.com/kubelogs/kubelogs/api/storagepb"
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// Resource attributes that map to entry fields rather than attributes.
+const (
+	otlpCluster   = "k8s.cluster.name"
+	otlpNamespace = "k8s.namespace.name"
+	otlpPod       = "k8s.pod.name"
+	otlpPodUID    = "k8s.pod.uid"
+	otlpContainer = "k8s.container.name"
+	otlpService   = "service.name"
+)
+
+// OTLPReceiver implements the OpenTelemetry logs service, so applications
+// instrumented with an OpenTelemetry SDK can send logs to kubelogs
+// without a collector. Records are written through Server.Write and get
+// the same quotas, enrichment and backpressure as collector writes.
+type OTLPReceiver struct {
+	otlppb.UnimplementedLogsServiceServer
+	srv *Server
+}
+
+// NewOTLPReceiver creates a receiver writing through srv.
+func NewOTLPReceiver(srv *Server) *OTLPReceiver {
+	return &OTLPReceiver{srv: srv}
+}
+
+// Export writes the records in req.
+func (o *OTLPReceiver) Export(ctx context.Context, req *otlppb.ExportLogsServiceRequest) (*otlppb.ExportLogsServiceResponse, error) {
+	entries := fromOTLP(req, time.Now())
+	if len(entries) > 0 {
+		if _, err := o.srv.Write(ctx, &storagepb.WriteRequest{Entries: entries}); err != nil {
+			return nil, err
+		}
+	}
+	return &otlppb.ExportLogsServiceResponse{}, nil
+}
+
+// fromOTLP converts the records in req to entries. The namespace, pod,
+// container and cluster come from the k8s.* resource attributes, with
+// service.name standing in for a missing container; other resource
+// attributes are added to each record's attributes, which take
+// precedence. Records without a timestamp get their observed time, or
+// now.
+func fromOTLP(req *otlppb.ExportLogsServiceRequest, now time.Time) []*storagepb.LogEntry {
+	var entries []*storagepb.LogEntry
+	for _, rl := range req.ResourceLogs {
+		var base storagepb.LogEntry
+		resAttrs := make(map[string]string)
+		for _, kv := range rl.GetResource().GetAttributes() {
+			v := anyValueString(kv.Value)
+			switch kv.Key {
+			case otlpCluster:
+				base.Cluster = v
+			case otlpNamespace:
+				base.Namespace = v
+			case otlpPod:
+				base.Pod = v
+			case otlpContainer:
+				base.Container = v
+			case otlpPodUID:
+				resAttrs["pod_uid"] = v
+			default:
+				resAttrs[kv.Key] = v
+			}
+		}
+		if base.Container == "" {
+			base.Container = resAttrs[otlpService]
+		}
+
+		for _, sl := range rl.ScopeLogs {
+			for _, rec := range sl.LogRecords {
+				e := &storagepb.LogEntry{
+					TimestampNanos: recordTime(rec, now),
+					Cluster:        base.Cluster,
+					Namespace:      base.Namespace,
+					Pod:            base.Pod,
+					Container:      base.Container,
+					Severity:       uint32(otlpSeverity(rec.SeverityNumber, rec.SeverityText)),
+					Message:        anyValueString(rec.Body),
+				}
+				if n := len(resAttrs) + len(rec.Attributes); n > 0 || len(rec.TraceId) > 0 {
+					e.Attributes = make(map[string]string, n+2)
+				}
+				for k, v := range resAttrs {
+					e.Attributes[k] = v
+				}
+				for _, kv := range rec.Attributes {
+					e.Attributes[kv.Key] = anyValueString(kv.Value)
+				}
+				if len(rec.TraceId) > 0 {
+					e.Attributes["trace_id"] = hex.EncodeToString(rec.TraceId)
+					if len(rec.SpanId) > 0 {
+						e.Attributes["span_id"] = hex.EncodeToString(rec.SpanId)
+					}
+				}
+				entries = append(entries, e)
+			}
+		}
+	}
+	return entries
+}
+
+func recordTime(rec *otlppb.LogRecord, now time.Time) int64 {
+	switch {
+	case rec.TimeUnixNano != 0:
+		return int64(rec.TimeUnixNano)
+	case rec.ObservedTimeUnixNano != 0:
+		return int64(rec.ObservedTimeUnixNano)
+	default:
+		return now.UnixNano()
+	}
+}
+
+// otlpSeverity maps an OTLP severity number, whose ranges of four
+// correspond to our levels, falling back to parsing the severity text.
+func otlpSeverity(n otlppb.SeverityNumber, text string) storage.Severity {
+	if n <= otlppb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED || n > otlppb.SeverityNumber_SEVERITY_NUMBER_FATAL4 {
+		return storage.ParseSeverity(text)
+	}
+	return storage.SeverityTrace + storage.Severity((n-1)/4)
+}
+
+// anyValueString renders an OTLP value as a string: scalars as text,
+// bytes as base64 and arrays and maps as JSON.
+func anyValueString(v *otlppb.AnyValue) string {
+	switch v := v.GetValue().(type) {
+	case *otlppb.AnyValue_StringValue:
+		return v.StringValue
+	case *otlppb.AnyValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue)
+	case *otlppb.AnyValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10)
+	case *otlppb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+	case *otlppb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(v.BytesValue)
+	case *otlppb.AnyValue_ArrayValue, *otlppb.AnyValue_KvlistValue:
+		b, err := json.Marshal(anyValueJSON(&otlppb.AnyValue{Value: v}))
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	default:
+		return ""
+	}
+}
+
+// anyValueJSON converts an OTLP value to a value encoding/json can
+// marshal.
+func anyValueJSON(v *otlppb.AnyValue) any {
+	switch v := v.GetValue().(type) {
+	case *otlppb.AnyValue_StringValue:
+		return v.StringValue
+	case *otlppb.AnyValue_BoolValue:
+		return v.BoolValue
+	case *otlppb.AnyValue_IntValue:
+		return v.IntValue
+	case *otlppb.AnyValue_DoubleValue:
+		return v.DoubleValue
+	case *otlppb.AnyValue_BytesValue:
+		return v.BytesValue // Marshaled as base64
+	case *otlppb.AnyValue_ArrayValue:
+		values := make([]any, len(v.ArrayValue.GetValues()))
+		for i, av := range v.ArrayValue.GetValues() {
+			values[i] = anyValueJSON(av)
+		}
+		return values
+	case *otlppb.AnyValue_KvlistValue:
+		m := make(map[string]any, len(v.KvlistValue.GetValues()))
+		for _, kv := range v.KvlistValue.GetValues() {
+			m[kv.Key] = anyValueJSON(kv.Value)
+		}
+		return m
+	default:
+		return nil
+	}
+}
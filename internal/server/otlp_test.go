@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/kubelogs/kubelogs/api/otlppb"
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func strValue(s string) *otlppb.AnyValue {
+	return &otlppb.AnyValue{Value: &otlppb.AnyValue_StringValue{StringValue: s}}
+}
+
+func TestOTLPSeverity(t *testing.T) {
+	tests := []struct {
+		n    otlppb.SeverityNumber
+		text string
+		want storage.Severity
+	}{
+		{otlppb.SeverityNumber_SEVERITY_NUMBER_TRACE, "", storage.SeverityTrace},
+		{otlppb.SeverityNumber_SEVERITY_NUMBER_DEBUG4, "", storage.SeverityDebug},
+		{otlppb.SeverityNumber_SEVERITY_NUMBER_INFO, "", storage.SeverityInfo},
+		{otlppb.SeverityNumber_SEVERITY_NUMBER_WARN2, "", storage.SeverityWarn},
+		{otlppb.SeverityNumber_SEVERITY_NUMBER_ERROR3, "", storage.SeverityError},
+		{otlppb.SeverityNumber_SEVERITY_NUMBER_FATAL4, "", storage.SeverityFatal},
+		{otlppb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, "warning", storage.SeverityWarn},
+		{otlppb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, "", storage.SeverityUnknown},
+		{99, "error", storage.SeverityError},
+	}
+	for _, tt := range tests {
+		if got := otlpSeverity(tt.n, tt.text); got != tt.want {
+			t.Errorf("otlpSeverity(%d, %q) = %v, want %v", tt.n, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestAnyValueString(t *testing.T) {
+	tests := []struct {
+		v    *otlppb.AnyValue
+		want string
+	}{
+		{nil, ""},
+		{strValue("hello"), "hello"},
+		{&otlppb.AnyValue{Value: &otlppb.AnyValue_IntValue{IntValue: -3}}, "-3"},
+		{&otlppb.AnyValue{Value: &otlppb.AnyValue_DoubleValue{DoubleValue: 1.5}}, "1.5"},
+		{&otlppb.AnyValue{Value: &otlppb.AnyValue_BoolValue{BoolValue: true}}, "true"},
+		{&otlppb.AnyValue{Value: &otlppb.AnyValue_BytesValue{BytesValue: []byte("hi")}}, "aGk="},
+		{&otlppb.AnyValue{Value: &otlppb.AnyValue_KvlistValue{KvlistValue: &otlppb.KeyValueList{Values: []*otlppb.KeyValue{
+			{Key: "user", Value: strValue("bob")},
+			{Key: "tags", Value: &otlppb.AnyValue{Value: &otlppb.AnyValue_ArrayValue{ArrayValue: &otlppb.ArrayValue{Values: []*otlppb.AnyValue{
+				strValue("a"),
+				{Value: &otlppb.AnyValue_IntValue{IntValue: 1}},
+			}}}}},
+		}}}}, `{"tags":["a",1],"user":"bob"}`},
+	}
+	for _, tt := range tests {
+		if got := anyValueString(tt.v); got != tt.want {
+			t.Errorf("anyValueString(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestOTLPReceiver_Export(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:", WriteBufferSize: 1})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	otlppb.RegisterLogsServiceServer(grpcServer, NewOTLPReceiver(New(store)))
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+	client := otlppb.NewLogsServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	_, err = client.Export(ctx, &otlppb.ExportLogsServiceRequest{ResourceLogs: []*otlppb.ResourceLogs{{
+		Resource: &otlppb.Resource{Attributes: []*otlppb.KeyValue{
+			{Key: "k8s.namespace.name", Value: strValue("shop")},
+			{Key: "k8s.pod.name", Value: strValue("checkout-1")},
+			{Key: "k8s.pod.uid", Value: strValue("uid-1")},
+			{Key: "service.name", Value: strValue("checkout")},
+			{Key: "env", Value: strValue("prod")},
+		}},
+		ScopeLogs: []*otlppb.ScopeLogs{{LogRecords: []*otlppb.LogRecord{{
+			TimeUnixNano:   uint64(ts.UnixNano()),
+			SeverityNumber: otlppb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+			Body:           strValue("payment failed"),
+			Attributes: []*otlppb.KeyValue{
+				{Key: "env", Value: strValue("staging")},
+				{Key: "order", Value: &otlppb.AnyValue{Value: &otlppb.AnyValue_IntValue{IntValue: 42}}},
+			},
+			TraceId: []byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+			SpanId:  []byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		}}}},
+	}}})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	result, err := store.Query(ctx, storage.Query{Namespace: "shop", Pagination: storage.Pagination{Limit: 10}})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(result.Entries))
+	}
+	e := result.Entries[0]
+	if !e.Timestamp.Equal(ts) || e.Pod != "checkout-1" || e.Container != "checkout" ||
+		e.Severity != storage.SeverityError || e.Message != "payment failed" {
+		t.Errorf("entry = %+v", e)
+	}
+	want := map[string]string{
+		"pod_uid":      "uid-1",
+		"service.name": "checkout",
+		"env":          "staging",
+		"order":        "42",
+		"trace_id":     "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":      "00f067aa0ba902b7",
+	}
+	for k, v := range want {
+		if e.Attributes[k] != v {
+			t.Errorf("attribute %s = %q, want %q", k, e.Attributes[k], v)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/patterns"
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+const (
+	// maxOverviewScan bounds the error entries read for patterns; ranges
+	// with more are summarized from their oldest maxOverviewScan errors.
+	maxOverviewScan = 50000
+
+	defaultOverviewWindow = time.Hour
+	defaultOverviewLimit  = 10
+	maxOverviewLimit      = 100
+
+	defaultNewPatternsWindow = 24 * time.Hour
+	defaultNewPatternsLimit  = 50
+	maxNewPatternsLimit      = 1000
+)
+
+// overviewPatternJSON is an error pattern and where it first occurred.
+type overviewPatternJSON struct {
+	Pattern   string `json:"pattern"`
+	Example   string `json:"example"`
+	Count     int    `json:"count"`
+	FirstSeen int64  `json:"firstSeen"` // Unix nanoseconds
+	FirstID   int64  `json:"firstId"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+}
+
+// overviewPodJSON is a pod and its error count.
+type overviewPodJSON struct {
+	Cluster   string `json:"cluster,omitempty"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Errors    int64  `json:"errors"`
+}
+
+// overviewResponse is the JSON response for an error overview.
+type overviewResponse struct {
+	Start     int64 `json:"start"` // Unix nanoseconds
+	End       int64 `json:"end"`   // Unix nanoseconds
+	Errors    int64 `json:"errors"`
+	Fatals    int64 `json:"fatals"`
+	Scanned   int   `json:"scanned"`
+	Truncated bool  `json:"truncated"` // More than maxOverviewScan errors in the range
+
+	TopPatterns []overviewPatternJSON `json:"topPatterns"`
+	TopPods     []overviewPodJSON     `json:"topPods"`
+	// NewPatterns are the patterns absent from the window of the same
+	// length before start, in order of first occurrence.
+	NewPatterns []overviewPatternJSON `json:"newPatterns"`
+}
+
+// overviewPattern accumulates one pattern of the scan.
+type overviewPattern struct {
+	n     int
+	first storage.LogEntry
+}
+
+// handleErrorOverview answers the first questions of an incident in one
+// request: how many errors and fatals, which patterns and pods produce
+// them, and which patterns are new, with their first occurrence. The
+// range is startTime/endTime (RFC3339, default the last hour), narrowed
+// by the usual filters; minSeverity is raised to error. limit (default
+// 10, up to 100) caps each list.
+func (s *HTTPServer) handleErrorOverview(w http.ResponseWriter, r *http.Request) {
+	q, err := s.parseQueryParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.Sample = 0
+	q.MinSeverity = max(q.MinSeverity, storage.SeverityError)
+	if q.EndTime.IsZero() {
+		q.EndTime = time.Now()
+	}
+	if q.StartTime.IsZero() {
+		q.StartTime = q.EndTime.Add(-defaultOverviewWindow)
+	}
+	if !q.EndTime.After(q.StartTime) {
+		http.Error(w, "endTime must be after startTime", http.StatusBadRequest)
+		return
+	}
+	limit := defaultOverviewLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= maxOverviewLimit {
+			limit = n
+		}
+	}
+
+	ctx, cancel := withQueryTimeout(r.Context(), s.queryTimeout)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := s.errorOverview(ctx, q, limit)
+	s.queryDuration.Observe(since(start))
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			s.queryTimeouts.Inc()
+			http.Error(w, "Query timed out after "+s.queryTimeout.String(), http.StatusGatewayTimeout)
+			return
+		}
+		slog.Error("error overview error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, resp)
+}
+
+// errorOverview scans the errors matching q oldest first for their
+// patterns, and counts them by severity and pod. Counts come from the
+// store's Aggregate when it has one, so they stay exact past the scan
+// limit.
+func (s *HTTPServer) errorOverview(ctx context.Context, q storage.Query, limit int) (*overviewResponse, error) {
+	resp := &overviewResponse{
+		Start: q.StartTime.UnixNano(),
+		End:   q.EndTime.UnixNano(),
+	}
+
+	found := make(map[string]*overviewPattern)
+	pods := make(map[[3]string]int64)
+
+	scan := q
+	scan.Pagination = storage.Pagination{Limit: 1000, Order: storage.OrderAsc, OrderBy: storage.OrderByTimestamp}
+	for {
+		result, err := s.store.Query(ctx, scan)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range result.Entries {
+			t := patterns.Template(e.Message)
+			if p, ok := found[t]; ok {
+				p.n++
+			} else {
+				found[t] = &overviewPattern{n: 1, first: e}
+			}
+			if e.Severity == storage.SeverityFatal {
+				resp.Fatals++
+			} else {
+				resp.Errors++
+			}
+			pods[[3]string{e.Cluster, e.Namespace, e.Pod}]++
+		}
+		resp.Scanned += len(result.Entries)
+
+		if !result.HasMore || len(result.Entries) == 0 {
+			break
+		}
+		if resp.Scanned >= maxOverviewScan {
+			resp.Truncated = true
+			break
+		}
+		last := result.Entries[len(result.Entries)-1]
+		scan.Pagination.AfterTimestamp = last.Timestamp
+		scan.Pagination.AfterID = last.ID
+	}
+
+	if agg, ok := s.store.(storage.GroupAggregator); ok {
+		groups, err := agg.Aggregate(ctx, q, storage.Aggregation{
+			GroupBy: []string{storage.GroupBySeverity, storage.GroupByCluster, storage.GroupByNamespace, storage.GroupByPod},
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp.Errors, resp.Fatals = 0, 0
+		clear(pods)
+		for _, g := range groups {
+			if g.Keys[0] == storage.SeverityFatal.String() {
+				resp.Fatals += g.Count
+			} else {
+				resp.Errors += g.Count
+			}
+			pods[[3]string{g.Keys[1], g.Keys[2], g.Keys[3]}] += g.Count
+		}
+	}
+
+	// Patterns seen in the window before the range aren't new
+	baseline, _, err := s.scanPatterns(ctx, q, q.StartTime.Add(-q.EndTime.Sub(q.StartTime)), q.StartTime)
+	if err != nil {
+		return nil, err
+	}
+
+	all := make([]overviewPatternJSON, 0, len(found))
+	for t, p := range found {
+		e := p.first
+		all = append(all, overviewPatternJSON{
+			Pattern:   t,
+			Example:   e.Message,
+			Count:     p.n,
+			FirstSeen: e.Timestamp.UnixNano(),
+			FirstID:   e.ID,
+			Namespace: e.Namespace,
+			Pod:       e.Pod,
+		})
+	}
+
+	resp.NewPatterns = make([]overviewPatternJSON, 0)
+	for _, p := range all {
+		if _, seen := baseline[p.Pattern]; !seen {
+			resp.NewPatterns = append(resp.NewPatterns, p)
+		}
+	}
+	slices.SortFunc(resp.NewPatterns, func(a, b overviewPatternJSON) int {
+		return cmp.Or(cmp.Compare(a.FirstSeen, b.FirstSeen), cmp.Compare(a.FirstID, b.FirstID))
+	})
+	resp.NewPatterns = resp.NewPatterns[:min(len(resp.NewPatterns), limit)]
+
+	// Pattern and pod break ties so output is stable
+	slices.SortFunc(all, func(a, b overviewPatternJSON) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Pattern, b.Pattern))
+	})
+	resp.TopPatterns = all[:min(len(all), limit)]
+
+	resp.TopPods = make([]overviewPodJSON, 0, len(pods))
+	for k, n := range pods {
+		resp.TopPods = append(resp.TopPods, overviewPodJSON{Cluster: k[0], Namespace: k[1], Pod: k[2], Errors: n})
+	}
+	slices.SortFunc(resp.TopPods, func(a, b overviewPodJSON) int {
+		return cmp.Or(cmp.Compare(b.Errors, a.Errors), cmp.Compare(a.Cluster, b.Cluster),
+			cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Pod, b.Pod))
+	})
+	resp.TopPods = resp.TopPods[:min(len(resp.TopPods), limit)]
+
+	return resp, nil
+}
+
+// newPatternJSON is a pattern from the store's first-seen index.
+type newPatternJSON struct {
+	Pattern     string `json:"pattern"`
+	Example     string `json:"example"`
+	FirstSeen   int64  `json:"firstSeen"` // Unix nanoseconds
+	LastSeen    int64  `json:"lastSeen"`  // Unix nanoseconds
+	Count       int64  `json:"count"`
+	FirstID     int64  `json:"firstId"`
+	Namespace   string `json:"namespace"`
+	Pod         string `json:"pod"`
+	MaxSeverity int    `json:"maxSeverity"`
+}
+
+// newPatternsResponse is the JSON response for new patterns.
+type newPatternsResponse struct {
+	Since    int64            `json:"since"` // Unix nanoseconds
+	Patterns []newPatternJSON `json:"patterns"`
+}
+
+// handleNewPatterns returns the message patterns first seen within
+// window (default 24h), most recent first, from the store's first-seen
+// index. minSeverity keeps patterns with an entry at least that severe;
+// limit defaults to 50, up to 1000.
+func (s *HTTPServer) handleNewPatterns(w http.ResponseWriter, r *http.Request) {
+	reader, ok := s.store.(storage.PatternReader)
+	if !ok {
+		http.Error(w, "Not supported", http.StatusNotImplemented)
+		return
+	}
+
+	params := r.URL.Query()
+
+	window := defaultNewPatternsWindow
+	if v := params.Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, fmt.Sprintf("invalid window %q", v), http.StatusBadRequest)
+			return
+		}
+		window = d
+	}
+
+	var minSeverity storage.Severity
+	if v := params.Get("minSeverity"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > int(storage.SeverityFatal) {
+			http.Error(w, fmt.Sprintf("invalid minSeverity %q: must be 0 to %d", v, storage.SeverityFatal), http.StatusBadRequest)
+			return
+		}
+		minSeverity = storage.Severity(n)
+	}
+
+	limit := defaultNewPatternsLimit
+	if v := params.Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= maxNewPatternsLimit {
+			limit = n
+		}
+	}
+
+	since := time.Now().Add(-window)
+	sightings, err := reader.Patterns(r.Context(), storage.PatternQuery{
+		FirstSeenAfter: since,
+		MinSeverity:    minSeverity,
+		Limit:          limit,
+	})
+	if err != nil {
+		slog.Error("patterns error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := newPatternsResponse{
+		Since:    since.UnixNano(),
+		Patterns: make([]newPatternJSON, 0, len(sightings)),
+	}
+	for _, p := range sightings {
+		resp.Patterns = append(resp.Patterns, newPatternJSON{
+			Pattern:     p.Pattern,
+			Example:     p.Example,
+			FirstSeen:   p.FirstSeen.UnixNano(),
+			LastSeen:    p.LastSeen.UnixNano(),
+			Count:       p.Count,
+			FirstID:     p.FirstID,
+			Namespace:   p.Namespace,
+			Pod:         p.Pod,
+			MaxSeverity: int(p.MaxSeverity),
+		})
+	}
+	writeJSON(w, resp)
+}
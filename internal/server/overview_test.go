@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestHandleErrorOverview(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	var batch storage.LogBatch
+	add := func(at time.Time, pod string, sev storage.Severity, format string, n int) {
+		for i := 0; i < n; i++ {
+			batch = append(batch, storage.LogEntry{
+				Timestamp: at.Add(time.Duration(i) * time.Second),
+				Namespace: "shop",
+				Pod:       pod,
+				Container: "app",
+				Severity:  sev,
+				Message:   fmt.Sprintf(format, i),
+			})
+		}
+	}
+	add(start.Add(-30*time.Minute), "api-0", storage.SeverityError, "cache miss for key %d", 4)
+	add(start.Add(10*time.Minute), "api-0", storage.SeverityError, "cache miss for key %d", 3)
+	add(start.Add(20*time.Minute), "api-1", storage.SeverityError, "db timeout after %dms", 5)
+	add(start.Add(40*time.Minute), "api-1", storage.SeverityFatal, "out of memory at %d", 1)
+	add(start.Add(15*time.Minute), "api-0", storage.SeverityInfo, "served request %d", 20)
+	store.Write(context.Background(), batch)
+	store.Flush(context.Background())
+
+	s := &HTTPServer{store: store}
+	get := func(query string) (*httptest.ResponseRecorder, overviewResponse) {
+		req := httptest.NewRequest(http.MethodGet, "/api/errors/overview?"+query, nil)
+		rec := httptest.NewRecorder()
+		s.handleErrorOverview(rec, req)
+		var resp overviewResponse
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+		}
+		return rec, resp
+	}
+
+	rec, resp := get("namespace=shop&startTime=" + start.Format(time.RFC3339) + "&endTime=" + start.Add(time.Hour).Format(time.RFC3339))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	if resp.Errors != 8 || resp.Fatals != 1 || resp.Scanned != 9 {
+		t.Errorf("errors = %d, fatals = %d, scanned = %d, want 8, 1, 9", resp.Errors, resp.Fatals, resp.Scanned)
+	}
+
+	if len(resp.TopPatterns) != 3 {
+		t.Fatalf("got %d top patterns %+v, want 3", len(resp.TopPatterns), resp.TopPatterns)
+	}
+	if p := resp.TopPatterns[0]; p.Pattern != "db timeout after <num>" || p.Count != 5 {
+		t.Errorf("top pattern = %+v, want db timeout x5", p)
+	}
+
+	if len(resp.TopPods) != 2 || resp.TopPods[0].Pod != "api-1" || resp.TopPods[0].Errors != 6 || resp.TopPods[1].Errors != 3 {
+		t.Errorf("top pods = %+v, want api-1 (6), api-0 (3)", resp.TopPods)
+	}
+
+	// The cache miss was already failing before the range
+	if len(resp.NewPatterns) != 2 {
+		t.Fatalf("got %d new patterns %+v, want 2", len(resp.NewPatterns), resp.NewPatterns)
+	}
+	first := resp.NewPatterns[0]
+	if first.Pattern != "db timeout after <num>" || first.Pod != "api-1" || first.Example != "db timeout after 0ms" ||
+		first.FirstSeen != start.Add(20*time.Minute).UnixNano() || first.FirstID == 0 {
+		t.Errorf("first new pattern = %+v", first)
+	}
+	if resp.NewPatterns[1].Pattern != "out of memory at <num>" {
+		t.Errorf("second new pattern = %+v, want out of memory", resp.NewPatterns[1])
+	}
+
+	_, resp = get("limit=1&startTime=" + start.Format(time.RFC3339) + "&endTime=" + start.Add(time.Hour).Format(time.RFC3339))
+	if len(resp.TopPatterns) != 1 || len(resp.TopPods) != 1 || len(resp.NewPatterns) != 1 {
+		t.Errorf("limit=1 returned %d patterns, %d pods, %d new", len(resp.TopPatterns), len(resp.TopPods), len(resp.NewPatterns))
+	}
+
+	if rec, _ := get("startTime=" + start.Format(time.RFC3339) + "&endTime=" + start.Format(time.RFC3339)); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty range status = %d, want 400", rec.Code)
+	}
+}
+
+func TestHandleNewPatterns(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	store.Write(context.Background(), storage.LogBatch{
+		{Timestamp: now.Add(-48 * time.Hour), Namespace: "shop", Pod: "api-0", Severity: storage.SeverityInfo, Message: "served request 1"},
+		{Timestamp: now.Add(-time.Minute), Namespace: "shop", Pod: "api-0", Severity: storage.SeverityInfo, Message: "served request 2"},
+		{Timestamp: now.Add(-2 * time.Hour), Namespace: "shop", Pod: "api-1", Severity: storage.SeverityWarn, Message: "cache miss for key 7"},
+		{Timestamp: now.Add(-time.Hour), Namespace: "shop", Pod: "api-1", Severity: storage.SeverityError, Message: "upstream timed out"},
+	})
+
+	s := &HTTPServer{store: store}
+
+	tests := []struct {
+		name         string
+		query        string
+		wantStatus   int
+		wantPatterns []string
+	}{
+		{"default window", "", http.StatusOK, []string{"upstream timed out", "cache miss for key <num>"}},
+		{"wide window", "?window=72h", http.StatusOK, []string{"upstream timed out", "cache miss for key <num>", "served request <num>"}},
+		{"narrow window", "?window=90m", http.StatusOK, []string{"upstream timed out"}},
+		{"min severity", "?minSeverity=5", http.StatusOK, []string{"upstream timed out"}},
+		{"limit", "?limit=1", http.StatusOK, []string{"upstream timed out"}},
+		{"bad window", "?window=soon", http.StatusBadRequest, nil},
+		{"bad min severity", "?minSeverity=9", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleNewPatterns(rec, httptest.NewRequest(http.MethodGet, "/api/patterns/new"+tt.query, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp newPatternsResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			var got []string
+			for _, p := range resp.Patterns {
+				got = append(got, p.Pattern)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantPatterns, ",") {
+				t.Errorf("patterns = %v, want %v", got, tt.wantPatterns)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Roles a route can require, from the least to the most privileged.
+const (
+	RolePublic = "public" // Anyone, without logging in
+	RoleViewer = "viewer" // Any authenticated user
+	RoleAdmin  = "admin"  // AdminUsers only
+)
+
+// defaultPolicy applies to the routes RoutePolicy doesn't cover.
+var defaultPolicy = map[string]string{
+	"*":            RoleViewer,
+	"/api/admin/*": RoleAdmin,
+}
+
+// fixedPolicy covers the routes needed to log in, which RoutePolicy
+// can't change.
+var fixedPolicy = map[string]string{
+	"/static/*":    RolePublic,
+	"/login":       RolePublic,
+	"/setup":       RolePublic,
+	"/logout":      RolePublic,
+	"/auth/oidc/*": RolePublic,
+}
+
+// routePolicy maps request paths to the role they require.
+type routePolicy struct {
+	exact  map[string]string
+	prefix map[string]string // Without the trailing "*"
+}
+
+// newRoutePolicy builds the policy of the configured rules on top of
+// the defaults. Rules must name a known role and a path starting with
+// "/" (or be "*").
+func newRoutePolicy(rules map[string]string) (*routePolicy, error) {
+	p := &routePolicy{exact: make(map[string]string), prefix: make(map[string]string)}
+	for _, set := range []map[string]string{defaultPolicy, rules, fixedPolicy} {
+		for pattern, role := range set {
+			switch role {
+			case RolePublic, RoleViewer, RoleAdmin:
+			default:
+				return nil, fmt.Errorf("route policy %q: unknown role %q", pattern, role)
+			}
+			if pattern != "*" && !strings.HasPrefix(pattern, "/") {
+				return nil, fmt.Errorf("route policy %q: pattern must start with /", pattern)
+			}
+			// Admin handlers log and confirm operations by user
+			if role == RolePublic && strings.HasPrefix(pattern, "/api/admin/") {
+				return nil, fmt.Errorf("route policy %q: admin routes need a user", pattern)
+			}
+			if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+				p.prefix[prefix] = role
+			} else {
+				p.exact[pattern] = role
+			}
+		}
+	}
+	return p, nil
+}
+
+// role returns the role path requires: that of the exact rule for path,
+// or else of the longest prefix rule matching it.
+func (p *routePolicy) role(path string) string {
+	if role, ok := p.exact[path]; ok {
+		return role
+	}
+	role, longest := RoleViewer, -1
+	for prefix, r := range p.prefix {
+		if len(prefix) > longest && strings.HasPrefix(path, prefix) {
+			role, longest = r, len(prefix)
+		}
+	}
+	return role
+}
+
+// authorize wraps the routes to require the role the policy gives each
+// path, once auth is enabled. API routes answer 401 without a user;
+// pages redirect to the login page. Users who aren't admins get 403 on
+// admin routes.
+func (s *HTTPServer) authorize(next http.Handler) http.Handler {
+	if s.authMiddleware == nil {
+		return next
+	}
+	admin := s.requireAdmin(next.ServeHTTP)
+	pageViewer := s.authMiddleware.RequireAuth(next)
+	pageAdmin := s.authMiddleware.RequireAuth(admin)
+	apiViewer := s.authMiddleware.RequireAuthAPI(next)
+	apiAdmin := s.authMiddleware.RequireAuthAPI(admin)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api := strings.HasPrefix(r.URL.Path, "/api/")
+		switch s.policy.role(r.URL.Path) {
+		case RolePublic:
+			next.ServeHTTP(w, r)
+		case RoleAdmin:
+			if api {
+				apiAdmin.ServeHTTP(w, r)
+			} else {
+				pageAdmin.ServeHTTP(w, r)
+			}
+		default:
+			if api {
+				apiViewer.ServeHTTP(w, r)
+			} else {
+				pageViewer.ServeHTTP(w, r)
+			}
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kubelogs/kubelogs/internal/logging"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestRoutePolicy_Role(t *testing.T) {
+	p, err := newRoutePolicy(map[string]string{
+		"/api/stats":         RolePublic,
+		"/api/stats/*":       RolePublic,
+		"/api/logs/export":   RoleAdmin,
+		"/api/admin/schema":  RoleViewer,
+		"/api/diff*":         RoleAdmin,
+		"/login":             RoleAdmin, // Ignored, login stays public
+		"/api/admin/search*": RoleViewer,
+	})
+	if err != nil {
+		t.Fatalf("newRoutePolicy: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/", RoleViewer},
+		{"/api/logs", RoleViewer},
+		{"/api/stats", RolePublic},
+		{"/api/stats/top", RolePublic},
+		{"/api/logs/export", RoleAdmin},
+		{"/api/diff", RoleAdmin},
+		{"/api/admin/sql", RoleAdmin},
+		{"/api/admin/schema", RoleViewer},
+		{"/api/admin/search-index/rebuild", RoleViewer},
+		{"/login", RolePublic},
+		{"/static/app.js", RolePublic},
+	}
+	for _, tt := range tests {
+		if got := p.role(tt.path); got != tt.want {
+			t.Errorf("role(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	for _, rules := range []map[string]string{
+		{"/api/logs": "editor"},
+		{"api/logs": RoleViewer},
+		{"/api/admin/sql": RolePublic},
+	} {
+		if _, err := newRoutePolicy(rules); err == nil {
+			t.Errorf("newRoutePolicy(%v): want an error", rules)
+		}
+	}
+}
+
+func TestHTTPServer_RoutePolicy(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	cfg := DefaultConfig()
+	cfg.AuthEnabled = true
+	cfg.AuthMethods = []string{"proxy"}
+	cfg.AuthProxyCIDRs = parsePrefixes("192.0.2.1")
+	cfg.AdminUsers = []string{"root"}
+	cfg.RoutePolicy = map[string]string{"/api/stats": RolePublic, "/api/admin/schema": RoleViewer}
+	s, err := NewHTTPServer(store, store.DB(), cfg)
+	if err != nil {
+		t.Fatalf("NewHTTPServer: %v", err)
+	}
+	level := logging.NewLevel(slog.LevelInfo)
+	s.SetLogLevel(level)
+	h := s.Routes()
+	get := func(path, user string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if user != "" {
+			req.Header.Set("X-Forwarded-User", user)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	tests := []struct {
+		path string
+		user string
+		want int
+	}{
+		{"/api/stats", "", http.StatusOK},
+		{"/api/logs", "", http.StatusUnauthorized},
+		{"/api/logs", "alice", http.StatusOK},
+		{"/api/admin/schema", "alice", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if got := get(tt.path, tt.user); got != tt.want {
+			t.Errorf("GET %s as %q = %d, want %d", tt.path, tt.user, got, tt.want)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/search-index/rebuild", nil)
+	req.Header.Set("X-Forwarded-User", "alice")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("rebuild as a viewer = %d, want 403", rec.Code)
+	}
+
+	for _, tt := range []struct {
+		user string
+		want int
+	}{{"alice", http.StatusForbidden}, {"root", http.StatusOK}} {
+		req := httptest.NewRequest(http.MethodPut, "/api/admin/loglevel?level=debug&duration=5m", nil)
+		req.Header.Set("X-Forwarded-User", tt.user)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("PUT /api/admin/loglevel as %q = %d, want %d", tt.user, rec.Code, tt.want)
+		}
+	}
+	if level.Level() != slog.LevelDebug {
+		t.Errorf("level = %v after an admin change, want DEBUG", level.Level())
+	}
+
+	cfg.RoutePolicy = map[string]string{"/api/logs": "owner"}
+	if _, err := NewHTTPServer(store, store.DB(), cfg); err == nil {
+		t.Error("unknown role: want an error")
+	}
+}
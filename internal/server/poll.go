@@ -0,0 +1,143 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+const (
+	defaultPollWait = 30 * time.Second
+	maxPollWait     = 60 * time.Second
+
+	// pollInterval is how often long polls query stores that don't
+	// announce writes.
+	pollInterval = 500 * time.Millisecond
+
+	// pollFallbackInterval is how often long polls query stores that do,
+	// for writes by other servers sharing the database.
+	pollFallbackInterval = 5 * time.Second
+)
+
+// pollResponse is the JSON response for long-poll requests.
+type pollResponse struct {
+	Entries []logEntryJSON `json:"entries"`
+	HasMore bool           `json:"hasMore"`
+	LastID  int64          `json:"lastId"` // Pass as afterId on the next poll
+}
+
+// handleLogPoll returns entries newer than afterId, blocking until at least
+// one matching entry arrives or waitSeconds elapses. It is an alternative to
+// SSE for clients behind proxies that buffer streaming responses.
+func (s *HTTPServer) handleLogPoll(w http.ResponseWriter, r *http.Request) {
+	q, err := s.parseQueryParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.Pagination.Order = storage.OrderAsc
+	q.Pagination.OrderBy = storage.OrderByID
+
+	params := r.URL.Query()
+
+	wait := defaultPollWait
+	if v := params.Get("waitSeconds"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid waitSeconds", http.StatusBadRequest)
+			return
+		}
+		wait = min(time.Duration(n)*time.Second, maxPollWait)
+	}
+
+	if v := params.Get("afterId"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid afterId", http.StatusBadRequest)
+			return
+		}
+		q.Pagination.AfterID = n
+	} else {
+		// No position yet - start from the newest matching entry
+		latest := q
+		latest.Pagination = storage.Pagination{Limit: 1, Order: storage.OrderDesc}
+		result, err := s.store.Query(r.Context(), latest)
+		if err != nil {
+			slog.Error("poll query error", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if len(result.Entries) > 0 {
+			q.Pagination.AfterID = result.Entries[0].ID
+		}
+	}
+
+	keepWriting(w)
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+
+	// Stores that announce writes are queried when written to, others,
+	// such as remote stores, are polled
+	notifier, _ := s.store.(storage.Notifier)
+	interval := pollInterval
+	if notifier != nil {
+		interval = pollFallbackInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Subscribe before querying so a write in between still wakes us
+		var wake <-chan struct{}
+		if notifier != nil {
+			wake = notifier.Written()
+		}
+
+		result, err := s.store.Query(r.Context(), q)
+		if err != nil {
+			slog.Error("poll query error", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if len(result.Entries) > 0 || wait == 0 {
+			s.writePollResponse(w, result, q.Pagination.AfterID)
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-deadline.C:
+			s.writePollResponse(w, &storage.QueryResult{}, q.Pagination.AfterID)
+			return
+		case <-s.stopping:
+			s.writePollResponse(w, &storage.QueryResult{}, q.Pagination.AfterID)
+			return
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// writePollResponse encodes result, carrying afterID forward when it is empty.
+func (s *HTTPServer) writePollResponse(w http.ResponseWriter, result *storage.QueryResult, afterID int64) {
+	resp := pollResponse{
+		Entries: make([]logEntryJSON, 0, len(result.Entries)),
+		HasMore: result.HasMore,
+		LastID:  afterID,
+	}
+	for _, e := range result.Entries {
+		resp.Entries = append(resp.Entries, s.toJSON(e))
+		resp.LastID = e.ID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("json encode error", "error", err)
+	}
+}
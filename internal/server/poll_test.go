@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/api/storagepb"
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestHandleLogPoll(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	store.Write(ctx, storage.LogBatch{
+		{Timestamp: time.Now(), Namespace: "ns", Pod: "pod", Container: "c", Message: "existing"},
+	})
+	store.Flush(ctx)
+
+	grpcSrv := New(store)
+	s := &HTTPServer{store: store}
+
+	poll := func(query string) pollResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/logs/poll?"+query, nil)
+		rec := httptest.NewRecorder()
+		s.handleLogPoll(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", rec.Code)
+		}
+		var resp pollResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("returns existing entries immediately", func(t *testing.T) {
+		resp := poll("afterId=0&waitSeconds=5")
+		if len(resp.Entries) != 1 || resp.Entries[0].Message != "existing" {
+			t.Fatalf("entries = %+v, want the existing entry", resp.Entries)
+		}
+		if resp.LastID != resp.Entries[0].ID {
+			t.Errorf("lastId = %d, want %d", resp.LastID, resp.Entries[0].ID)
+		}
+	})
+
+	t.Run("times out with no new entries", func(t *testing.T) {
+		start := time.Now()
+		resp := poll("waitSeconds=1")
+		if len(resp.Entries) != 0 {
+			t.Errorf("got %d entries, want 0", len(resp.Entries))
+		}
+		if resp.LastID == 0 {
+			t.Error("lastId should point at the newest existing entry")
+		}
+		if elapsed := time.Since(start); elapsed < time.Second {
+			t.Errorf("returned after %v, want at least 1s", elapsed)
+		}
+	})
+
+	wakes := func(t *testing.T, message string, limit time.Duration) {
+		t.Helper()
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			grpcSrv.Write(ctx, &storagepb.WriteRequest{Entries: []*storagepb.LogEntry{
+				{TimestampNanos: time.Now().UnixNano(), Namespace: "ns", Pod: "pod", Container: "c", Message: message},
+			}})
+		}()
+
+		start := time.Now()
+		resp := poll("namespace=ns&waitSeconds=10")
+		if len(resp.Entries) != 1 || resp.Entries[0].Message != message {
+			t.Fatalf("entries = %+v, want the %s entry", resp.Entries, message)
+		}
+		if elapsed := time.Since(start); elapsed > limit {
+			t.Errorf("took %v to wake, want under %v", elapsed, limit)
+		}
+	}
+
+	t.Run("wakes on write", func(t *testing.T) {
+		// Well before the fallback interval, so the store's notification
+		// woke it
+		wakes(t, "fresh", pollFallbackInterval/2)
+	})
+
+	t.Run("polls stores that don't announce writes", func(t *testing.T) {
+		s.store = struct{ storage.Store }{store}
+		defer func() { s.store = store }()
+		wakes(t, "polled", 5*pollInterval)
+	})
+}
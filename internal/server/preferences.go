@@ -0,0 +1,56 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/kubelogs/kubelogs/internal/auth"
+	"github.com/kubelogs/kubelogs/internal/preferences"
+)
+
+// handleGetPreferences returns the current user's UI preferences, a JSON
+// object ({} if none are saved).
+func (s *HTTPServer) handleGetPreferences(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	data, err := s.prefStore.Get(r.Context(), user.ID)
+	if err != nil {
+		slog.Error("get preferences error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// handlePutPreferences replaces the current user's UI preferences.
+func (s *HTTPServer) handlePutPreferences(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, preferences.MaxLength+1))
+	if err != nil {
+		http.Error(w, "Preferences too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err := s.prefStore.Put(r.Context(), user.ID, data); err != nil {
+		if errors.Is(err, preferences.ErrInvalid) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		slog.Error("put preferences error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kubelogs/kubelogs/internal/auth"
+	"github.com/kubelogs/kubelogs/internal/preferences"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestPreferences(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	users := auth.NewUserStore(store.DB())
+	alice, err := users.CreateUser(ctx, "alice", "password123")
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	bob, err := users.CreateUser(ctx, "bob", "password123")
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	s := &HTTPServer{store: store, prefStore: preferences.NewStore(store.DB())}
+
+	do := func(user *auth.User, method, body string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/preferences", strings.NewReader(body))
+		req = req.WithContext(auth.ContextWithUser(req.Context(), user))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	if rec := do(alice, http.MethodGet, "", s.handleGetPreferences); rec.Code != http.StatusOK || rec.Body.String() != "{}" {
+		t.Errorf("initial preferences = %d %q, want 200 {}", rec.Code, rec.Body)
+	}
+
+	prefs := `{"theme":"light","columns":{"pod":true,"attrs":false},"wrap":false}`
+	if rec := do(alice, http.MethodPut, prefs, s.handlePutPreferences); rec.Code != http.StatusNoContent {
+		t.Fatalf("put status = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(alice, http.MethodGet, "", s.handleGetPreferences); rec.Body.String() != prefs {
+		t.Errorf("preferences = %q, want %q", rec.Body, prefs)
+	}
+
+	// Preferences are per user
+	if rec := do(bob, http.MethodGet, "", s.handleGetPreferences); rec.Body.String() != "{}" {
+		t.Errorf("bob's preferences = %q, want {}", rec.Body)
+	}
+
+	for _, body := range []string{`[1,2]`, `null`, `not json`, `{"x":"` + strings.Repeat("a", preferences.MaxLength) + `"}`} {
+		if rec := do(alice, http.MethodPut, body, s.handlePutPreferences); rec.Code == http.StatusNoContent {
+			t.Errorf("put %.20q accepted", body)
+		}
+	}
+	if rec := do(alice, http.MethodGet, "", s.handleGetPreferences); rec.Body.String() != prefs {
+		t.Errorf("preferences after rejected puts = %q, want %q", rec.Body, prefs)
+	}
+}
@@ -0,0 +1,269 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// proxyHeaderTimeout bounds how long a connection may take to send its
+// PROXY protocol header.
+const proxyHeaderTimeout = 5 * time.Second
+
+// proxyV2Signature starts every PROXY protocol v2 header.
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// isTrusted reports whether ip belongs to one of the trusted prefixes.
+func isTrusted(trusted []netip.Prefix, ip netip.Addr) bool {
+	ip = ip.Unmap()
+	for _, p := range trusted {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// addrIP returns the IP of a "host:port" address.
+func addrIP(addr string) (netip.Addr, bool) {
+	if ap, err := netip.ParseAddrPort(addr); err == nil {
+		return ap.Addr().Unmap(), true
+	}
+	ip, err := netip.ParseAddr(addr)
+	return ip.Unmap(), err == nil
+}
+
+// forwardedFor returns the client IP from the X-Forwarded-For headers of
+// a request sent by a trusted proxy: the rightmost address that isn't a
+// trusted proxy itself.
+func forwardedFor(r *http.Request, trusted []netip.Prefix) (netip.Addr, bool) {
+	var hops []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+
+	var client netip.Addr
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip, ok := addrIP(strings.TrimSpace(hops[i]))
+		if !ok {
+			break
+		}
+		client = ip
+		if !isTrusted(trusted, ip) {
+			break
+		}
+	}
+	return client, client.IsValid()
+}
+
+// withClientIP replaces the RemoteAddr of requests from trusted proxies
+// with the client address they forwarded, so logging and auth see the
+// real client.
+func (s *HTTPServer) withClientIP(next http.Handler) http.Handler {
+	if len(s.trustedProxies) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if peer, ok := addrIP(r.RemoteAddr); ok && isTrusted(s.trustedProxies, peer) {
+			if client, ok := forwardedFor(r, s.trustedProxies); ok {
+				r = r.WithContext(context.WithValue(r.Context(), peerAddrKey{}, r.RemoteAddr))
+				r.RemoteAddr = net.JoinHostPort(client.String(), "0")
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// peerAddrKey holds the RemoteAddr withClientIP replaced.
+type peerAddrKey struct{}
+
+// peerAddr returns the address of the host that sent r: the proxy that
+// forwarded it if withClientIP replaced its RemoteAddr by the client's.
+func peerAddr(r *http.Request) string {
+	if addr, ok := r.Context().Value(peerAddrKey{}).(string); ok {
+		return addr
+	}
+	return r.RemoteAddr
+}
+
+// socketAddrKey holds the address of the TCP peer of a connection whose
+// RemoteAddr a PROXY header replaced.
+type socketAddrKey struct{}
+
+// proxyConnContext records the TCP peer of PROXY protocol connections in
+// the context of their requests, for checks that must not believe the
+// header. It is the HTTP server's ConnContext.
+func proxyConnContext(ctx context.Context, c net.Conn) context.Context {
+	if pc, ok := c.(*proxyConn); ok {
+		return context.WithValue(ctx, socketAddrKey{}, pc.peer.String())
+	}
+	return ctx
+}
+
+// socketAddr returns the address of the TCP peer r came from, whatever
+// client address a PROXY header or X-Forwarded-For carried.
+func socketAddr(r *http.Request) string {
+	if addr, ok := r.Context().Value(socketAddrKey{}).(string); ok {
+		return addr
+	}
+	return peerAddr(r)
+}
+
+// NewProxyListener wraps l to read a PROXY protocol (v1 or v2) header
+// from each connection from the trusted prefixes and report the source
+// address it carries as the connection's RemoteAddr; other connections
+// are used as-is. trusted must not be empty: a header from any peer
+// would let every client claim any address.
+func NewProxyListener(l net.Listener, trusted []netip.Prefix) (net.Listener, error) {
+	if len(trusted) == 0 {
+		return nil, errors.New("PROXY protocol needs the addresses of the proxies sending it (KUBELOGS_TRUSTED_PROXIES)")
+	}
+	return &proxyListener{Listener: l, trusted: trusted}, nil
+}
+
+type proxyListener struct {
+	net.Listener
+	trusted []netip.Prefix
+}
+
+func (l *proxyListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if peer, ok := addrIP(conn.RemoteAddr().String()); !ok || !isTrusted(l.trusted, peer) {
+		return conn, nil
+	}
+	return &proxyConn{Conn: conn, br: bufio.NewReader(conn), peer: conn.RemoteAddr()}, nil
+}
+
+// proxyConn reads the PROXY header on first use, in the goroutine
+// serving the connection rather than the accept loop.
+type proxyConn struct {
+	net.Conn
+	br   *bufio.Reader
+	peer net.Addr // The proxy's own address
+
+	once   sync.Once
+	remote net.Addr
+	err    error
+}
+
+func (c *proxyConn) init() {
+	c.once.Do(func() {
+		c.Conn.SetReadDeadline(time.Now().Add(proxyHeaderTimeout))
+		c.remote, c.err = readProxyHeader(c.br)
+		c.Conn.SetReadDeadline(time.Time{})
+		if c.err != nil {
+			slog.Debug("invalid PROXY protocol header",
+				"remote", c.Conn.RemoteAddr(), "error", c.err)
+		}
+		if c.remote == nil {
+			c.remote = c.Conn.RemoteAddr()
+		}
+	})
+}
+
+func (c *proxyConn) Read(p []byte) (int, error) {
+	c.init()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.br.Read(p)
+}
+
+func (c *proxyConn) RemoteAddr() net.Addr {
+	c.init()
+	return c.remote
+}
+
+// readProxyHeader consumes a PROXY protocol header and returns the source
+// address it carries, nil for LOCAL and UNKNOWN connections.
+func readProxyHeader(br *bufio.Reader) (net.Addr, error) {
+	prefix, err := br.Peek(len(proxyV2Signature))
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	switch {
+	case bytes.Equal(prefix, proxyV2Signature):
+		return readProxyV2(br)
+	case bytes.HasPrefix(prefix, []byte("PROXY ")):
+		return readProxyV1(br)
+	}
+	return nil, errors.New("missing PROXY protocol header")
+}
+
+// readProxyV1 parses a text header, e.g.
+// "PROXY TCP4 192.0.2.1 10.0.0.1 56324 443\r\n".
+func readProxyV1(br *bufio.Reader) (net.Addr, error) {
+	line, err := br.ReadSlice('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read v1 header: %w", err)
+	}
+	if len(line) > 107 || !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errors.New("malformed v1 header")
+	}
+
+	fields := strings.Fields(string(line))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("malformed v1 header %q", strings.TrimSpace(string(line)))
+	}
+	ip, err := netip.ParseAddr(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("v1 source address: %w", err)
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("v1 source port: %w", err)
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(ip, uint16(port))), nil
+}
+
+// readProxyV2 parses a binary header.
+func readProxyV2(br *bufio.Reader) (net.Addr, error) {
+	hdr := make([]byte, 16)
+	if _, err := io.ReadFull(br, hdr); err != nil {
+		return nil, fmt.Errorf("read v2 header: %w", err)
+	}
+	if hdr[12]>>4 != 2 {
+		return nil, fmt.Errorf("unsupported v2 version %d", hdr[12]>>4)
+	}
+	body := make([]byte, binary.BigEndian.Uint16(hdr[14:16]))
+	if _, err := io.ReadFull(br, body); err != nil {
+		return nil, fmt.Errorf("read v2 addresses: %w", err)
+	}
+
+	// LOCAL connections (health checks by the proxy) carry no client
+	if hdr[12]&0xf == 0 {
+		return nil, nil
+	}
+	var ip netip.Addr
+	var port uint16
+	switch family := hdr[13] >> 4; {
+	case family == 1 && len(body) >= 12:
+		ip = netip.AddrFrom4([4]byte(body[0:4]))
+		port = binary.BigEndian.Uint16(body[8:10])
+	case family == 2 && len(body) >= 36:
+		ip = netip.AddrFrom16([16]byte(body[0:16]))
+		port = binary.BigEndian.Uint16(body[32:34])
+	default:
+		// Unix sockets and unspecified families: keep the peer address
+		return nil, nil
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(ip.Unmap(), port)), nil
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestWithClientIP(t *testing.T) {
+	s := &HTTPServer{trustedProxies: parsePrefixes("10.0.0.0/8, 192.168.1.5")}
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		want   string
+	}{
+		{"untrusted peer", "203.0.113.9:4000", []string{"198.51.100.1"}, "203.0.113.9:4000"},
+		{"trusted peer", "10.1.2.3:4000", []string{"198.51.100.1"}, "198.51.100.1:0"},
+		{"proxy chain", "10.1.2.3:4000", []string{"198.51.100.1, 192.168.1.5"}, "198.51.100.1:0"},
+		{"spoofed left", "10.1.2.3:4000", []string{"1.2.3.4, 198.51.100.1"}, "198.51.100.1:0"},
+		{"multiple headers", "10.1.2.3:4000", []string{"1.2.3.4", "198.51.100.1"}, "198.51.100.1:0"},
+		{"all trusted", "10.1.2.3:4000", []string{"10.9.9.9"}, "10.9.9.9:0"},
+		{"garbage", "10.1.2.3:4000", []string{"not-an-ip"}, "10.1.2.3:4000"},
+		{"no header", "10.1.2.3:4000", nil, "10.1.2.3:4000"},
+		{"ipv6", "[::ffff:10.1.2.3]:4000", []string{"2001:db8::1"}, "[2001:db8::1]:0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := s.withClientIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remote
+			for _, v := range tt.xff {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func proxyV2Header(cmd, family byte, addrs []byte) []byte {
+	h := append([]byte{}, proxyV2Signature...)
+	h = append(h, 0x20|cmd, family, byte(len(addrs)>>8), byte(len(addrs)))
+	return append(h, addrs...)
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	v4 := []byte{192, 0, 2, 1, 10, 0, 0, 1, 0xdc, 0x04, 0x01, 0xbb}
+	v6 := append(netip.MustParseAddr("2001:db8::1").AsSlice(), make([]byte, 16)...)
+	v6 = append(v6, 0x00, 0x50, 0x01, 0xbb)
+
+	tests := []struct {
+		name    string
+		header  []byte
+		want    string // "" for no address
+		wantErr bool
+	}{
+		{name: "v1 tcp4", header: []byte("PROXY TCP4 192.0.2.1 10.0.0.1 56324 443\r\n"), want: "192.0.2.1:56324"},
+		{name: "v1 tcp6", header: []byte("PROXY TCP6 2001:db8::1 2001:db8::2 80 443\r\n"), want: "[2001:db8::1]:80"},
+		{name: "v1 unknown", header: []byte("PROXY UNKNOWN\r\n")},
+		{name: "v1 malformed", header: []byte("PROXY TCP4 192.0.2.1\r\n"), wantErr: true},
+		{name: "v1 bad port", header: []byte("PROXY TCP4 192.0.2.1 10.0.0.1 99999 443\r\n"), wantErr: true},
+		{name: "v2 tcp4", header: proxyV2Header(1, 0x11, v4), want: "192.0.2.1:56324"},
+		{name: "v2 tcp6", header: proxyV2Header(1, 0x21, v6), want: "[2001:db8::1]:80"},
+		{name: "v2 local", header: proxyV2Header(0, 0x00, nil)},
+		{name: "v2 truncated", header: proxyV2Header(1, 0x11, v4)[:20], wantErr: true},
+		{name: "no header", header: []byte("GET / HTTP/1.1\r\n\r\n"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			br := bufio.NewReader(bytes.NewReader(append(tt.header, "GET /"...)))
+			addr, err := readProxyHeader(br)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readProxyHeader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := ""
+			if addr != nil {
+				got = addr.String()
+			}
+			if got != tt.want {
+				t.Errorf("address = %q, want %q", got, tt.want)
+			}
+			if rest, _ := br.ReadString(0); rest != "GET /" {
+				t.Errorf("header not fully consumed, rest %q", rest)
+			}
+		})
+	}
+}
+
+func TestProxyListener(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.RemoteAddr)
+	}))
+	if _, err := NewProxyListener(lis, nil); err == nil {
+		t.Errorf("NewProxyListener without trusted proxies: want an error")
+	}
+	plis, err := NewProxyListener(lis, []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")})
+	if err != nil {
+		t.Fatalf("NewProxyListener failed: %v", err)
+	}
+	srv.Listener = plis
+	srv.Start()
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "PROXY TCP4 192.0.2.1 10.0.0.1 56324 80\r\nGET / HTTP/1.1\r\nHost: x\r\n\r\n")
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("ReadResponse failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var body bytes.Buffer
+	body.ReadFrom(resp.Body)
+	if body.String() != "192.0.2.1:56324" {
+		t.Errorf("RemoteAddr = %q, want 192.0.2.1:56324", body.String())
+	}
+}
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/audit"
+	"github.com/kubelogs/kubelogs/internal/auth"
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// deleteLogsResponse is the JSON response for a delete by query.
+type deleteLogsResponse struct {
+	Deleted int64 `json:"deleted"`
+}
+
+// purgePreviewResponse is the JSON response for a delete by query
+// preview.
+type purgePreviewResponse struct {
+	Matched        int64     `json:"matched"` // -1 if the backend can't count
+	Token          string    `json:"token"`
+	ExpiresAt      time.Time `json:"expiresAt"`
+	SecondApprover bool      `json:"secondApprover"` // Another admin must confirm
+}
+
+// handlePreviewDeleteLogs counts the entries a delete by query with the
+// same filters would remove, and issues the token the delete must
+// present to run.
+func (s *HTTPServer) handlePreviewDeleteLogs(w http.ResponseWriter, r *http.Request) {
+	q, ok := s.parsePurge(w, r)
+	if !ok {
+		return
+	}
+
+	matched, err := countMatches(r.Context(), s.store, q)
+	if err != nil {
+		slog.Error("delete by query preview error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	token, expires, err := s.confirms.issue(purgeOp(q), user.Username, matched, time.Now())
+	if err != nil {
+		slog.Error("issue confirmation token", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	slog.Info("previewed delete by query", "user", user.Username, "filter", queryFilter(q), "matched", matched)
+	writeJSON(w, purgePreviewResponse{
+		Matched:        matched,
+		Token:          token,
+		ExpiresAt:      expires,
+		SecondApprover: s.confirms.secondApprover,
+	})
+}
+
+// handleDeleteLogs deletes the entries matching the filters in the query
+// string, in the syntax of GET /api/logs, e.g. to purge a leaked secret.
+// Unlike queries, invalid filters are rejected rather than ignored, as
+// is a request without filters. The confirm parameter must be the token
+// of a preview with the same filters.
+func (s *HTTPServer) handleDeleteLogs(w http.ResponseWriter, r *http.Request) {
+	q, ok := s.parsePurge(w, r)
+	if !ok {
+		return
+	}
+	qd := s.store.(storage.QueryDeleter)
+
+	token := r.URL.Query().Get("confirm")
+	if token == "" {
+		http.Error(w, "A confirm token from POST /api/admin/logs/preview is required", http.StatusPreconditionRequired)
+		return
+	}
+	user, _ := auth.UserFromContext(r.Context())
+	preview, err := s.confirms.redeem(token, purgeOp(q), user.Username, time.Now())
+	switch {
+	case errors.Is(err, errConfirmSelf):
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	deleted, err := qd.DeleteByQuery(r.Context(), q)
+	if err != nil {
+		if errors.Is(err, storage.ErrStorageClosed) {
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		slog.Error("delete by query error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	filter := queryFilter(q)
+	slog.Info("deleted entries by query",
+		"user", user.Username,
+		"requested_by", preview.requester,
+		"filter", filter,
+		"deleted", deleted,
+	)
+	s.confirms.record(r.Context(), audit.Record{
+		Operation: audit.OpPurge,
+		Filter:    filter,
+		Requester: preview.requester,
+		Approver:  user.Username,
+		Count:     deleted,
+	})
+	writeJSON(w, deleteLogsResponse{Deleted: deleted})
+}
+
+// parsePurge parses the filters of a delete by query, writing an error if
+// the store can't delete by query or the filters are invalid.
+func (s *HTTPServer) parsePurge(w http.ResponseWriter, r *http.Request) (storage.Query, bool) {
+	var q storage.Query
+	if _, ok := s.store.(storage.QueryDeleter); !ok {
+		http.Error(w, "Storage backend does not support deleting by query", http.StatusNotImplemented)
+		return q, false
+	}
+
+	params := r.URL.Query()
+	params.Del("confirm")
+	if err := storage.ParseFilters(params, &q); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return q, false
+	}
+	if !q.HasFilter() {
+		http.Error(w, "At least one filter is required", http.StatusBadRequest)
+		return q, false
+	}
+	return q, true
+}
+
+// countMatches counts the entries matching q's filters, or returns -1 if
+// the store can't count.
+func countMatches(ctx context.Context, store storage.Store, q storage.Query) (int64, error) {
+	ga, ok := store.(storage.GroupAggregator)
+	if !ok {
+		return -1, nil
+	}
+	groups, err := ga.Aggregate(ctx, q, storage.Aggregation{})
+	if err != nil {
+		return 0, err
+	}
+	var matched int64
+	for _, g := range groups {
+		matched += g.Count
+	}
+	return matched, nil
+}
+
+// purgeOp returns the operation key confirmation tokens of a delete by
+// query with q are bound to, the same whichever API previewed it.
+func purgeOp(q storage.Query) string {
+	return "purge " + queryFilter(q)
+}
+
+// queryFilter describes the filters of q as a JSON object, with the
+// names of the GET /api/logs parameters, for logs and audit records.
+// Keys are sorted, so the same filters always give the same string.
+func queryFilter(q storage.Query) string {
+	f := make(map[string]any)
+	if !q.StartTime.IsZero() {
+		f["startTime"] = q.StartTime.UTC().Format(time.RFC3339Nano)
+	}
+	if !q.EndTime.IsZero() {
+		f["endTime"] = q.EndTime.UTC().Format(time.RFC3339Nano)
+	}
+	for k, v := range map[string]string{"search": q.Search, "cluster": q.Cluster, "namespace": q.Namespace, "pod": q.Pod, "container": q.Container} {
+		if v != "" {
+			f[k] = v
+		}
+	}
+	if q.MinSeverity > storage.SeverityUnknown {
+		f["minSeverity"] = q.MinSeverity.String()
+	}
+	if len(q.Attributes) > 0 {
+		f["attributes"] = q.Attributes
+	}
+	if len(q.AttrExprs) > 0 {
+		f["attrExprs"] = q.AttrExprs
+	}
+	b, _ := json.Marshal(f)
+	return string(b)
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/audit"
+	"github.com/kubelogs/kubelogs/internal/auth"
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestDeleteLogs(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	store.Write(ctx, storage.LogBatch{
+		{Timestamp: now, Namespace: "a", Pod: "pod", Container: "c", Message: "password=hunter2", Attributes: map[string]string{"leak": "yes"}},
+		{Timestamp: now, Namespace: "a", Pod: "pod", Container: "c", Message: "ok"},
+		{Timestamp: now, Namespace: "b", Pod: "pod", Container: "c", Message: "ok", Attributes: map[string]string{"leak": "yes"}},
+	})
+	store.Flush(ctx)
+
+	s := &HTTPServer{store: store, adminUsers: map[string]bool{"root": true, "bob": true}, confirms: NewConfirmations(false, audit.NewStore(store.DB()))}
+	admin := &auth.User{ID: 1, Username: "root"}
+	serve := func(h http.HandlerFunc, method string, user *auth.User, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/admin/logs?"+query, nil)
+		req = req.WithContext(auth.ContextWithUser(req.Context(), user))
+		rec := httptest.NewRecorder()
+		s.requireAdmin(h).ServeHTTP(rec, req)
+		return rec
+	}
+	preview := func(user *auth.User, query string) purgePreviewResponse {
+		t.Helper()
+		rec := serve(s.handlePreviewDeleteLogs, http.MethodPost, user, query)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("preview status = %d: %s", rec.Code, rec.Body)
+		}
+		var resp purgePreviewResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode preview: %v", err)
+		}
+		return resp
+	}
+	do := func(user *auth.User, query string) *httptest.ResponseRecorder {
+		return serve(s.handleDeleteLogs, http.MethodDelete, user, query)
+	}
+
+	other := preview(admin, "namespace=b")
+	tests := []struct {
+		name  string
+		user  *auth.User
+		query string
+		want  int
+	}{
+		{"not an admin", &auth.User{ID: 2, Username: "alice"}, "namespace=a", http.StatusForbidden},
+		{"no filter", admin, "limit=10", http.StatusBadRequest},
+		{"invalid filter", admin, "namespace=a&minSeverity=loud", http.StatusBadRequest},
+		{"no token", admin, "namespace=a", http.StatusPreconditionRequired},
+		{"unknown token", admin, "namespace=a&confirm=123", http.StatusConflict},
+		{"other filters' token", admin, "namespace=a&confirm=" + other.Token, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := do(tt.user, tt.query); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+
+	p := preview(admin, "attr.leak=yes&namespace=a")
+	if p.Matched != 1 || p.SecondApprover {
+		t.Errorf("preview = %+v, want 1 match without second approver", p)
+	}
+	rec := do(admin, "namespace=a&attr.leak=yes&confirm="+p.Token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("delete status = %d: %s", rec.Code, rec.Body)
+	}
+	var resp deleteLogsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Deleted != 1 {
+		t.Errorf("deleted = %d, want 1", resp.Deleted)
+	}
+	if rec := do(admin, "namespace=a&attr.leak=yes&confirm="+p.Token); rec.Code != http.StatusConflict {
+		t.Errorf("reused token status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	result, err := store.Query(ctx, storage.Query{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entries) != 2 {
+		t.Errorf("%d entries left, want 2", len(result.Entries))
+	}
+
+	// With a second approver, the admin who previewed can't confirm
+	s.confirms.secondApprover = true
+	p = preview(admin, "namespace=b")
+	if rec := do(admin, "namespace=b&confirm="+p.Token); rec.Code != http.StatusForbidden {
+		t.Errorf("self-confirmed status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	rec = do(&auth.User{ID: 3, Username: "bob"}, "namespace=b&confirm="+p.Token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("second approver status = %d: %s", rec.Code, rec.Body)
+	}
+
+	// Both deletes are in the audit log, newest first
+	records, err := audit.NewStore(store.DB()).List(ctx, 0)
+	if err != nil {
+		t.Fatalf("List audit records: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("%d audit records, want 2", len(records))
+	}
+	if r := records[0]; r.Operation != audit.OpPurge || r.Requester != "root" || r.Approver != "bob" ||
+		r.Count != 1 || r.Filter != queryFilter(storage.Query{Namespace: "b"}) {
+		t.Errorf("second approver's record = %+v", r)
+	}
+	if r := records[1]; r.Requester != "root" || r.Approver != "root" || r.Count != 1 {
+		t.Errorf("first record = %+v", r)
+	}
+}
+
+func TestConfirmationsExpire(t *testing.T) {
+	c := NewConfirmations(false, nil)
+	now := time.Now()
+	token, expires, err := c.issue("purge namespace=a", "root", -1, now)
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	if !expires.Equal(now.Add(confirmTTL)) {
+		t.Errorf("expires = %v, want %v", expires, now.Add(confirmTTL))
+	}
+	if _, err := c.redeem(token, "purge namespace=a", "root", expires.Add(time.Second)); err != errConfirmInvalid {
+		t.Errorf("expired redeem error = %v, want %v", err, errConfirmInvalid)
+	}
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/kubelogs/kubelogs/internal/auth"
+	"github.com/kubelogs/kubelogs/internal/savedquery"
+)
+
+// maxSavedQueryBody bounds the JSON body of a saved query request.
+const maxSavedQueryBody = 2 * (savedquery.MaxNameLength + savedquery.MaxQueryLength)
+
+// savedQueryJSON is the JSON representation of a saved query.
+type savedQueryJSON struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Query     string `json:"query"`     // URL query string, as accepted by /api/logs
+	CreatedAt int64  `json:"createdAt"` // Unix nanoseconds
+	UpdatedAt int64  `json:"updatedAt"` // Unix nanoseconds
+}
+
+// savedQueryRequest is the JSON body for creating or updating a saved query.
+type savedQueryRequest struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+}
+
+func toSavedQueryJSON(q savedquery.Query) savedQueryJSON {
+	return savedQueryJSON{
+		ID:        q.ID,
+		Name:      q.Name,
+		Query:     q.Query,
+		CreatedAt: q.CreatedAt.UnixNano(),
+		UpdatedAt: q.UpdatedAt.UnixNano(),
+	}
+}
+
+func decodeSavedQueryRequest(w http.ResponseWriter, r *http.Request) (savedQueryRequest, bool) {
+	var req savedQueryRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSavedQueryBody)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+func writeSavedQueryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, savedquery.ErrNotFound):
+		http.Error(w, "Saved query not found", http.StatusNotFound)
+	case errors.Is(err, savedquery.ErrNameTaken):
+		http.Error(w, "A saved query with that name already exists", http.StatusConflict)
+	case errors.Is(err, savedquery.ErrInvalidQuery):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		slog.Error("saved query error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleListSavedQueries returns the current user's saved queries by name.
+func (s *HTTPServer) handleListSavedQueries(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	queries, err := s.queryStore.List(r.Context(), user.ID)
+	if err != nil {
+		writeSavedQueryError(w, err)
+		return
+	}
+
+	resp := make([]savedQueryJSON, 0, len(queries))
+	for _, q := range queries {
+		resp = append(resp, toSavedQueryJSON(q))
+	}
+	writeJSON(w, map[string]any{"queries": resp})
+}
+
+// handleCreateSavedQuery saves a query for the current user.
+func (s *HTTPServer) handleCreateSavedQuery(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	req, ok := decodeSavedQueryRequest(w, r)
+	if !ok {
+		return
+	}
+
+	q, err := s.queryStore.Create(r.Context(), user.ID, req.Name, req.Query)
+	if err != nil {
+		writeSavedQueryError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, toSavedQueryJSON(*q))
+}
+
+// handleGetSavedQuery returns one of the current user's saved queries.
+func (s *HTTPServer) handleGetSavedQuery(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	q, err := s.queryStore.Get(r.Context(), user.ID, id)
+	if err != nil {
+		writeSavedQueryError(w, err)
+		return
+	}
+	writeJSON(w, toSavedQueryJSON(*q))
+}
+
+// handleUpdateSavedQuery replaces the name and query of a saved query.
+func (s *HTTPServer) handleUpdateSavedQuery(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	req, ok := decodeSavedQueryRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.queryStore.Update(r.Context(), user.ID, id, req.Name, req.Query); err != nil {
+		writeSavedQueryError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeleteSavedQuery removes one of the current user's saved queries.
+func (s *HTTPServer) handleDeleteSavedQuery(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := s.queryStore.Delete(r.Context(), user.ID, id); err != nil {
+		writeSavedQueryError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
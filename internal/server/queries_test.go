@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kubelogs/kubelogs/internal/auth"
+	"github.com/kubelogs/kubelogs/internal/savedquery"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestSavedQueries(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	users := auth.NewUserStore(store.DB())
+	alice, err := users.CreateUser(ctx, "alice", "password123")
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	bob, err := users.CreateUser(ctx, "bob", "password123")
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	s := &HTTPServer{store: store, queryStore: savedquery.NewStore(store.DB())}
+
+	do := func(user *auth.User, method string, id int64, body string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, fmt.Sprintf("/api/queries/%d", id), strings.NewReader(body))
+		req.SetPathValue("id", fmt.Sprint(id))
+		req = req.WithContext(auth.ContextWithUser(req.Context(), user))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+	list := func(user *auth.User) []savedQueryJSON {
+		rec := do(user, http.MethodGet, 0, "", s.handleListSavedQueries)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("list status = %d", rec.Code)
+		}
+		var resp struct {
+			Queries []savedQueryJSON `json:"queries"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return resp.Queries
+	}
+
+	rec := do(alice, http.MethodPost, 0, `{"name":"prod errors","query":"namespace=prod&minSeverity=5&search=timeout"}`, s.handleCreateSavedQuery)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", rec.Code, rec.Body)
+	}
+	var created savedQueryJSON
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if created.ID == 0 || created.Name != "prod errors" {
+		t.Errorf("created = %+v", created)
+	}
+
+	for _, body := range []string{
+		`{"name":"","query":"namespace=prod"}`,
+		`{"name":"bad","query":"namespace=%zz"}`,
+		`not json`,
+	} {
+		if rec := do(alice, http.MethodPost, 0, body, s.handleCreateSavedQuery); rec.Code != http.StatusBadRequest {
+			t.Errorf("create %s status = %d, want 400", body, rec.Code)
+		}
+	}
+	if rec := do(alice, http.MethodPost, 0, `{"name":"prod errors","query":""}`, s.handleCreateSavedQuery); rec.Code != http.StatusConflict {
+		t.Errorf("duplicate name status = %d, want 409", rec.Code)
+	}
+
+	// Names are only unique per user, and queries are only visible to their owner
+	if rec := do(bob, http.MethodPost, 0, `{"name":"prod errors","query":"namespace=staging"}`, s.handleCreateSavedQuery); rec.Code != http.StatusCreated {
+		t.Errorf("bob create status = %d, want 201", rec.Code)
+	}
+	if got := list(bob); len(got) != 1 || got[0].Query != "namespace=staging" {
+		t.Errorf("bob sees %+v, want only his own query", got)
+	}
+	for _, tt := range []struct {
+		method  string
+		body    string
+		handler http.HandlerFunc
+	}{
+		{http.MethodGet, "", s.handleGetSavedQuery},
+		{http.MethodPut, `{"name":"mine","query":""}`, s.handleUpdateSavedQuery},
+		{http.MethodDelete, "", s.handleDeleteSavedQuery},
+	} {
+		if rec := do(bob, tt.method, created.ID, tt.body, tt.handler); rec.Code != http.StatusNotFound {
+			t.Errorf("bob %s status = %d, want 404", tt.method, rec.Code)
+		}
+	}
+
+	// Update, including a rename that clashes with another query
+	do(alice, http.MethodPost, 0, `{"name":"api","query":"pod=api"}`, s.handleCreateSavedQuery)
+	if rec := do(alice, http.MethodPut, created.ID, `{"name":"api","query":""}`, s.handleUpdateSavedQuery); rec.Code != http.StatusConflict {
+		t.Errorf("rename clash status = %d, want 409", rec.Code)
+	}
+	if rec := do(alice, http.MethodPut, created.ID, `{"name":"prod errors","query":"namespace=prod"}`, s.handleUpdateSavedQuery); rec.Code != http.StatusNoContent {
+		t.Errorf("update status = %d, want 204", rec.Code)
+	}
+	rec = do(alice, http.MethodGet, created.ID, "", s.handleGetSavedQuery)
+	var got savedQueryJSON
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got.Query != "namespace=prod" {
+		t.Errorf("query after update = %q", got.Query)
+	}
+
+	// Listed by name
+	if got := list(alice); len(got) != 2 || got[0].Name != "api" || got[1].Name != "prod errors" {
+		t.Errorf("alice sees %+v", got)
+	}
+
+	if rec := do(alice, http.MethodDelete, created.ID, "", s.handleDeleteSavedQuery); rec.Code != http.StatusNoContent {
+		t.Errorf("delete status = %d, want 204", rec.Code)
+	}
+	if rec := do(alice, http.MethodGet, created.ID, "", s.handleGetSavedQuery); rec.Code != http.StatusNotFound {
+		t.Errorf("get after delete status = %d, want 404", rec.Code)
+	}
+}
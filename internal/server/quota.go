@@ -0,0 +1,73 @@
+package server
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// clusterQuotas enforces daily ingest quotas per cluster. Counts reset
+// at midnight UTC and are not persisted, so a restart starts the day over.
+type clusterQuotas struct {
+	limits map[string]int64 // "*" applies to clusters not listed
+
+	mu   sync.Mutex
+	day  int64 // Days since the Unix epoch the counts belong to
+	used map[string]int64
+}
+
+func newClusterQuotas(limits map[string]int64) *clusterQuotas {
+	return &clusterQuotas{limits: limits, used: make(map[string]int64)}
+}
+
+// limit returns the quota of cluster and whether it has one.
+func (q *clusterQuotas) limit(cluster string) (int64, bool) {
+	if n, ok := q.limits[cluster]; ok {
+		return n, true
+	}
+	n, ok := q.limits["*"]
+	return n, ok
+}
+
+// admit returns the entries within their cluster's quota and counts them
+// as used, along with the number admitted per cluster.
+func (q *clusterQuotas) admit(entries storage.LogBatch, now time.Time) (storage.LogBatch, map[string]int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if day := now.Unix() / 86400; day != q.day {
+		q.day = day
+		clear(q.used)
+	}
+
+	admitted := make(map[string]int64)
+	dropped := make(map[string]int64)
+	kept := entries[:0:0]
+	for _, e := range entries {
+		if limit, ok := q.limit(e.Cluster); ok && q.used[e.Cluster] >= limit {
+			dropped[e.Cluster]++
+			continue
+		}
+		q.used[e.Cluster]++
+		admitted[e.Cluster]++
+		kept = append(kept, e)
+	}
+
+	for cluster, n := range dropped {
+		limit, _ := q.limit(cluster)
+		slog.Warn("cluster ingest quota exceeded, dropping entries",
+			"cluster", cluster, "dropped", n, "quota", limit)
+	}
+	return kept, admitted
+}
+
+// refund returns quota taken by admit for entries that weren't stored.
+func (q *clusterQuotas) refund(admitted map[string]int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for cluster, n := range admitted {
+		q.used[cluster] = max(q.used[cluster]-n, 0)
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/api/storagepb"
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestClusterQuotas(t *testing.T) {
+	q := newClusterQuotas(map[string]int64{"east": 2, "*": 1})
+	day := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	batch := func(clusters ...string) storage.LogBatch {
+		var b storage.LogBatch
+		for _, c := range clusters {
+			b = append(b, storage.LogEntry{Cluster: c})
+		}
+		return b
+	}
+
+	tests := []struct {
+		name    string
+		entries storage.LogBatch
+		now     time.Time
+		want    int
+	}{
+		{"within quota", batch("east", "west"), day, 2},
+		{"partly over", batch("east", "east", "west", "north"), day, 2},
+		{"all over", batch("east", "west", "north"), day, 0},
+		{"next day resets", batch("east", "east", "east", "west"), day.Add(12 * time.Hour), 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, _ := q.admit(tt.entries, tt.now)
+			if len(kept) != tt.want {
+				t.Errorf("admitted %d entries, want %d", len(kept), tt.want)
+			}
+		})
+	}
+
+	// Refunded quota can be used again
+	q.refund(map[string]int64{"east": 1})
+	if kept, _ := q.admit(batch("east", "east"), day.Add(12*time.Hour)); len(kept) != 1 {
+		t.Errorf("admitted %d entries after refund, want 1", len(kept))
+	}
+}
+
+func TestServer_ClusterQuotas(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:", WriteBufferSize: 1})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	srv := New(store)
+	srv.SetClusterQuotas(map[string]int64{"east": 1})
+	ctx := context.Background()
+
+	now := time.Now()
+	resp, err := srv.Write(ctx, &storagepb.WriteRequest{Entries: []*storagepb.LogEntry{
+		{TimestampNanos: now.UnixNano(), Cluster: "east", Namespace: "ns", Message: "a"},
+		{TimestampNanos: now.UnixNano() + 1, Cluster: "east", Namespace: "ns", Message: "b"},
+		{TimestampNanos: now.UnixNano() + 2, Cluster: "west", Namespace: "ns", Message: "c"},
+	}})
+	if err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if resp.Count != 2 {
+		t.Errorf("expected 2 entries written, got %d", resp.Count)
+	}
+}
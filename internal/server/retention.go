@@ -2,17 +2,33 @@ package server
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"log/slog"
 	"sync/atomic"
 	"time"
 
+	"github.com/kubelogs/kubelogs/internal/archive"
 	"github.com/kubelogs/kubelogs/internal/storage"
 )
 
-// RetentionWorker periodically deletes old log entries.
+// archiveLead is how far past the retention cutoff entries are archived,
+// so entries the run deletes moments later are covered.
+const archiveLead = time.Minute
+
+// RetentionWorker periodically deletes old log entries, and entries past
+// their own expiry (see storage.LogEntry.ExpiresAt).
 type RetentionWorker struct {
-	store  storage.Store
-	config Config
+	store    storage.Store
+	config   Config // Its retention is the policy in effect
+	archiver *archive.Archiver
+
+	// The retention configured, and where the one applied is recorded
+	// (nil = the configured one applies at once)
+	configured RetentionPolicy
+	policyDB   *sql.DB
+	wake       chan struct{}
 
 	totalRuns    atomic.Int64
 	totalDeleted atomic.Int64
@@ -31,20 +47,98 @@ type RetentionStats struct {
 // NewRetentionWorker creates a new retention worker.
 func NewRetentionWorker(store storage.Store, config Config) *RetentionWorker {
 	return &RetentionWorker{
-		store:  store,
-		config: config,
+		store:      store,
+		config:     config,
+		configured: config.RetentionPolicy(),
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// GuardShrinkage records the retention applied in db, and keeps to it
+// while the configured retention would delete some cluster's logs
+// sooner, until ApplyRetention confirms the change. Call before Run.
+func (w *RetentionWorker) GuardShrinkage(db *sql.DB) {
+	w.policyDB = db
+}
+
+// PendingShrinkage returns the retention applied and the configured one,
+// and whether applying the latter awaits confirmation.
+func (w *RetentionWorker) PendingShrinkage(ctx context.Context) (applied, configured RetentionPolicy, pending bool, err error) {
+	if w.policyDB == nil {
+		return w.configured, w.configured, false, nil
+	}
+	p, err := loadRetentionPolicy(ctx, w.policyDB)
+	if err != nil || p == nil {
+		return w.configured, w.configured, false, err
 	}
+	return *p, w.configured, p.shrinksTo(w.configured), nil
+}
+
+// ApplyRetention records the configured retention as applied, confirming
+// its shrinkage, and runs retention at once.
+func (w *RetentionWorker) ApplyRetention(ctx context.Context) error {
+	if w.policyDB == nil {
+		return nil
+	}
+	if err := saveRetentionPolicy(ctx, w.policyDB, w.configured); err != nil {
+		return err
+	}
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// usePolicy puts the retention to apply in effect: the configured one,
+// unless it shrinks the one applied and awaits confirmation, in which
+// case the longer of both for each cluster.
+func (w *RetentionWorker) usePolicy(ctx context.Context) error {
+	p := w.configured
+	if w.policyDB != nil {
+		applied, err := loadRetentionPolicy(ctx, w.policyDB)
+		if err != nil {
+			return err
+		}
+		switch {
+		case applied != nil && applied.shrinksTo(w.configured):
+			p = widest(*applied, w.configured)
+			slog.Warn("configured retention is shorter than the one applied, keeping the longer one until an admin applies it",
+				"change", RetentionChange(*applied, w.configured))
+		case applied == nil || !applied.equal(w.configured):
+			if err := saveRetentionPolicy(ctx, w.policyDB, w.configured); err != nil {
+				return err
+			}
+		}
+	}
+	w.config.RetentionDays = p.Days
+	w.config.ClusterRetentionDays = p.ClusterDays
+	return nil
+}
+
+// SetArchiver makes the worker archive entries before retention deletes
+// them. Entries deleted by their own expiry aren't archived.
+func (w *RetentionWorker) SetArchiver(a *archive.Archiver) {
+	w.archiver = a
 }
 
 // Run starts the retention worker. Blocks until ctx is canceled.
 func (w *RetentionWorker) Run(ctx context.Context) {
-	if !w.config.RetentionEnabled() {
+	_, expiring := w.store.(storage.ExpiryDeleter)
+	if !w.config.RetentionEnabled() && !expiring {
+		// Keeping logs forever is recorded too, so that enabling
+		// retention later needs confirming
+		if err := w.usePolicy(ctx); err != nil {
+			slog.Error("failed to record retention policy", "error", err)
+		}
 		slog.Info("retention disabled, worker not starting")
 		return
 	}
 
 	slog.Info("retention worker starting",
 		"retention_days", w.config.RetentionDays,
+		"cluster_retention_days", w.config.ClusterRetentionDays,
+		"entry_expiry", expiring,
 		"interval", w.config.RetentionInterval,
 	)
 
@@ -58,6 +152,8 @@ func (w *RetentionWorker) Run(ctx context.Context) {
 		select {
 		case <-ticker.C:
 			w.runOnce(ctx)
+		case <-w.wake:
+			w.runOnce(ctx)
 		case <-ctx.Done():
 			slog.Info("retention worker stopping")
 			return
@@ -67,13 +163,17 @@ func (w *RetentionWorker) Run(ctx context.Context) {
 
 // runOnce executes a single retention cycle.
 func (w *RetentionWorker) runOnce(ctx context.Context) {
+	err := w.usePolicy(ctx)
 	cutoff := w.config.RetentionCutoff()
 
 	slog.Debug("retention cleanup starting",
 		"cutoff", cutoff.Format(time.RFC3339),
 	)
 
-	deleted, err := w.store.Delete(ctx, cutoff)
+	var deleted int64
+	if err == nil {
+		deleted, err = w.deleteExpired(ctx)
+	}
 
 	w.totalRuns.Add(1)
 	now := time.Now()
@@ -103,6 +203,116 @@ func (w *RetentionWorker) runOnce(ctx context.Context) {
 	}
 }
 
+// deleteExpired deletes the entries past their own expiry, if the store
+// keeps it, then applies retention.
+func (w *RetentionWorker) deleteExpired(ctx context.Context) (int64, error) {
+	var deleted int64
+	if ed, ok := w.store.(storage.ExpiryDeleter); ok {
+		n, err := ed.DeleteExpired(ctx, time.Now())
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	if !w.config.RetentionEnabled() {
+		return deleted, nil
+	}
+	if w.archiver != nil {
+		// Nothing is deleted unless it's archived
+		cutoff := retentionCutoff(w.shortestRetention()).Add(archiveLead)
+		n, err := w.archiver.Archive(ctx, w.store, cutoff)
+		if err != nil {
+			return deleted, fmt.Errorf("archive: %w", err)
+		}
+		if n > 0 {
+			slog.Info("archived entries", "entries", n, "cutoff", cutoff.Format(time.RFC3339))
+		}
+	}
+	n, err := w.applyRetention(ctx)
+	return deleted + n, err
+}
+
+// shortestRetention returns the shortest retention in days across
+// clusters, leaving out those kept forever.
+func (w *RetentionWorker) shortestRetention() int {
+	shortest := w.config.RetentionDays
+	for _, days := range w.config.ClusterRetentionDays {
+		if days > 0 && (shortest == 0 || days < shortest) {
+			shortest = days
+		}
+	}
+	return shortest
+}
+
+// applyRetention applies the retention of every cluster. The store-wide
+// delete only removes entries expired for all clusters, so clusters kept
+// longer survive it; clusters with shorter retention are then trimmed
+// one by one.
+func (w *RetentionWorker) applyRetention(ctx context.Context) (int64, error) {
+	overrides := w.config.ClusterRetentionDays
+	if len(overrides) == 0 {
+		return w.store.Delete(ctx, w.config.RetentionCutoff())
+	}
+	cd, ok := w.store.(storage.ClusterDeleter)
+	if !ok {
+		return 0, errors.New("store does not support per-cluster retention")
+	}
+
+	// Longest retention across clusters; 0 means forever
+	longest := w.config.RetentionDays
+	for _, days := range overrides {
+		if longest > 0 && (days == 0 || days > longest) {
+			longest = days
+		}
+	}
+
+	var deleted int64
+	if longest > 0 {
+		n, err := w.store.Delete(ctx, retentionCutoff(longest))
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+
+	// Clusters without an override follow RetentionDays; they only need
+	// listing when that is shorter than the store-wide delete
+	clusters := []string{""}
+	for cluster := range overrides {
+		if cluster != "" {
+			clusters = append(clusters, cluster)
+		}
+	}
+	if w.config.RetentionDays > 0 && w.config.RetentionDays != longest {
+		lister, ok := w.store.(ClusterLister)
+		if !ok {
+			return deleted, errors.New("store cannot list clusters")
+		}
+		listed, err := lister.ListClusters(ctx)
+		if err != nil {
+			return deleted, err
+		}
+		for _, cluster := range listed {
+			if _, ok := overrides[cluster]; !ok {
+				clusters = append(clusters, cluster)
+			}
+		}
+	}
+
+	for _, cluster := range clusters {
+		days := w.config.ClusterRetention(cluster)
+		if days == 0 || days == longest {
+			continue
+		}
+		n, err := cd.DeleteCluster(ctx, cluster, retentionCutoff(days))
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
 // Stats returns retention worker statistics.
 func (w *RetentionWorker) Stats() RetentionStats {
 	var lastErr error
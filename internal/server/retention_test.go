@@ -2,9 +2,18 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/kubelogs/kubelogs/api/storagepb"
+	"github.com/kubelogs/kubelogs/internal/archive"
+	"github.com/kubelogs/kubelogs/internal/audit"
+	"github.com/kubelogs/kubelogs/internal/auth"
 	"github.com/kubelogs/kubelogs/internal/storage"
 	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
 )
@@ -54,6 +63,224 @@ func TestRetentionWorker_DeletesOldLogs(t *testing.T) {
 	}
 }
 
+func TestRetentionWorker_ClusterOverrides(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	var entries storage.LogBatch
+	for _, cluster := range []string{"", "east", "west", "north"} {
+		for _, age := range []int{0, 3, 10} {
+			entries = append(entries, storage.LogEntry{
+				Timestamp: now.Add(-time.Duration(age) * 24 * time.Hour),
+				Cluster:   cluster, Namespace: "ns", Pod: "pod", Container: "c",
+				Message: fmt.Sprintf("%s %d", cluster, age),
+			})
+		}
+	}
+	store.Write(ctx, entries)
+	store.Flush(ctx)
+
+	// Default 5 days; east kept 2 days, west 30, north forever
+	cfg := Config{
+		RetentionDays:        5,
+		ClusterRetentionDays: map[string]int{"east": 2, "west": 30, "north": 0},
+		RetentionInterval:    time.Hour,
+	}
+	worker := NewRetentionWorker(store, cfg)
+	worker.runOnce(ctx)
+
+	if stats := worker.Stats(); stats.LastRunError != nil {
+		t.Fatalf("retention failed: %v", stats.LastRunError)
+	}
+
+	result, err := store.Query(ctx, storage.Query{Pagination: storage.Pagination{Limit: 100}})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	remaining := make(map[string]bool)
+	for _, e := range result.Entries {
+		remaining[e.Message] = true
+	}
+	want := []string{" 0", " 3", "east 0", "west 0", "west 3", "west 10", "north 0", "north 3", "north 10"}
+	if len(remaining) != len(want) {
+		t.Errorf("remaining = %v, want %v", remaining, want)
+	}
+	for _, msg := range want {
+		if !remaining[msg] {
+			t.Errorf("%q was deleted", msg)
+		}
+	}
+}
+
+func TestRetentionWorker_EntryExpiry(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:", WriteBufferSize: 1})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	srv := New(store)
+	_, err = srv.Write(ctx, &storagepb.WriteRequest{Entries: []*storagepb.LogEntry{
+		{TimestampNanos: now.UnixNano(), Namespace: "ns", Pod: "pod", Container: "c", Message: "kept"},
+		{TimestampNanos: now.UnixNano(), Namespace: "ns", Pod: "pod", Container: "c", Message: "expired",
+			ExpiresAtNanos: now.Add(-time.Minute).UnixNano()},
+	}})
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	// The batch TTL applies to entries without their own expiry
+	_, err = srv.Write(ctx, &storagepb.WriteRequest{
+		Entries: []*storagepb.LogEntry{
+			{TimestampNanos: now.UnixNano(), Namespace: "ns", Pod: "pod", Container: "c", Message: "debug dump"},
+		},
+		TtlMillis: time.Hour.Milliseconds(),
+	})
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	// Entries expire even with retention disabled
+	worker := NewRetentionWorker(store, Config{RetentionInterval: time.Hour})
+	worker.runOnce(ctx)
+	if stats := worker.Stats(); stats.TotalDeleted != 1 || stats.LastRunError != nil {
+		t.Errorf("deleted %d, error %v; want 1 deleted", stats.TotalDeleted, stats.LastRunError)
+	}
+
+	deleted, err := store.DeleteExpired(ctx, now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteExpired failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("DeleteExpired after the batch TTL deleted %d, want 1", deleted)
+	}
+	result, err := store.Query(ctx, storage.Query{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entries) != 1 || result.Entries[0].Message != "kept" {
+		t.Errorf("remaining entries = %+v, want only kept", result.Entries)
+	}
+}
+
+// unavailableBucket is an objstore.Bucket whose requests all fail.
+type unavailableBucket struct{}
+
+var errBucketUnavailable = errors.New("bucket unavailable")
+
+func (unavailableBucket) Put(context.Context, string, []byte) error { return errBucketUnavailable }
+func (unavailableBucket) Get(context.Context, string) ([]byte, error) {
+	return nil, errBucketUnavailable
+}
+func (unavailableBucket) Delete(context.Context, string) error { return errBucketUnavailable }
+func (unavailableBucket) List(context.Context, string) ([]string, error) {
+	return nil, errBucketUnavailable
+}
+
+func TestRetentionWorker_KeepsEntriesWhenArchiveFails(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:", WriteBufferSize: 1})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	store.Write(ctx, storage.LogBatch{
+		{Timestamp: time.Now().Add(-48 * time.Hour), Namespace: "ns", Pod: "pod", Container: "c", Message: "old"},
+	})
+
+	worker := NewRetentionWorker(store, Config{RetentionDays: 1, RetentionInterval: time.Hour})
+	worker.SetArchiver(archive.New(unavailableBucket{}, "archive/"))
+	worker.runOnce(ctx)
+
+	if stats := worker.Stats(); !errors.Is(*worker.lastRunError.Load(), errBucketUnavailable) || stats.TotalDeleted != 0 {
+		t.Errorf("stats = %+v, want the bucket error and nothing deleted", stats)
+	}
+	result, err := store.Query(ctx, storage.Query{})
+	if err != nil || len(result.Entries) != 1 {
+		t.Fatalf("remaining entries = %d, %v, want 1", len(result.Entries), err)
+	}
+}
+
+func TestRetentionWorker_GuardsShrinkage(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	store.Write(ctx, storage.LogBatch{
+		{Timestamp: now.Add(-5 * 24 * time.Hour), Namespace: "ns", Pod: "pod", Container: "c", Message: "old"},
+		{Timestamp: now, Namespace: "ns", Pod: "pod", Container: "c", Message: "new"},
+	})
+	store.Flush(ctx)
+
+	// A week is applied, then a day configured
+	week := NewRetentionWorker(store, Config{RetentionDays: 7, RetentionInterval: time.Hour})
+	week.GuardShrinkage(store.DB())
+	week.runOnce(ctx)
+	worker := NewRetentionWorker(store, Config{RetentionDays: 1, RetentionInterval: time.Hour})
+	worker.GuardShrinkage(store.DB())
+	worker.runOnce(ctx)
+	if deleted := worker.Stats().TotalDeleted; deleted != 0 {
+		t.Fatalf("deleted %d entries before the shrinkage was confirmed", deleted)
+	}
+
+	s := &HTTPServer{
+		store:      store,
+		adminUsers: map[string]bool{"root": true},
+		confirms:   NewConfirmations(false, audit.NewStore(store.DB())),
+		retention:  worker,
+	}
+	serve := func(h http.HandlerFunc, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/retention?"+query, nil)
+		req = req.WithContext(auth.ContextWithUser(req.Context(), &auth.User{ID: 1, Username: "root"}))
+		rec := httptest.NewRecorder()
+		s.requireAdmin(h).ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := serve(s.handleApplyRetention, ""); rec.Code != http.StatusPreconditionRequired {
+		t.Errorf("apply without token status = %d, want %d", rec.Code, http.StatusPreconditionRequired)
+	}
+	rec := serve(s.handlePreviewRetention, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("preview status = %d: %s", rec.Code, rec.Body)
+	}
+	var preview retentionPreviewResponse
+	if err := json.NewDecoder(rec.Body).Decode(&preview); err != nil {
+		t.Fatalf("decode preview: %v", err)
+	}
+	if preview.Matched != 1 || preview.Applied.Days != 7 || preview.Configured.Days != 1 {
+		t.Errorf("preview = %+v, want 1 entry matched going from 7 days to 1", preview)
+	}
+	if rec := serve(s.handleApplyRetention, "confirm="+preview.Token); rec.Code != http.StatusOK {
+		t.Fatalf("apply status = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(s.handlePreviewRetention, ""); rec.Code != http.StatusConflict {
+		t.Errorf("preview after apply status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	worker.runOnce(ctx)
+	if deleted := worker.Stats().TotalDeleted; deleted != 1 {
+		t.Errorf("deleted %d entries after the shrinkage was confirmed, want 1", deleted)
+	}
+	records, err := audit.NewStore(store.DB()).List(ctx, 0)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("audit records = %v, %v, want 1", records, err)
+	}
+	if r := records[0]; r.Operation != audit.OpRetention || r.Approver != "root" || r.Count != 1 {
+		t.Errorf("audit record = %+v", r)
+	}
+}
+
 func TestRetentionWorker_DisabledWhenZeroDays(t *testing.T) {
 	cfg := Config{
 		RetentionDays:     0,
@@ -161,6 +388,9 @@ func TestConfigFromEnv(t *testing.T) {
 	if cfg.RetentionInterval != time.Hour {
 		t.Errorf("Default retention interval should be 1h, got %v", cfg.RetentionInterval)
 	}
+	if !cfg.GRPCHealth || !cfg.GRPCReflection {
+		t.Errorf("gRPC health and reflection should be enabled by default")
+	}
 
 	// Test with env var
 	t.Setenv("KUBELOGS_RETENTION_DAYS", "30")
@@ -176,6 +406,36 @@ func TestConfigFromEnv(t *testing.T) {
 		t.Errorf("Invalid retention days should default to 0, got %d", cfg.RetentionDays)
 	}
 
+	// gRPC services opt-out
+	t.Setenv("KUBELOGS_GRPC_HEALTH", "false")
+	t.Setenv("KUBELOGS_GRPC_REFLECTION", "false")
+	cfg = ConfigFromEnv()
+	if cfg.GRPCHealth || cfg.GRPCReflection {
+		t.Errorf("gRPC health and reflection should be disabled")
+	}
+
+	// Proxy settings
+	t.Setenv("KUBELOGS_TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.5,bogus")
+	t.Setenv("KUBELOGS_PROXY_PROTOCOL", "true")
+	cfg = ConfigFromEnv()
+	if fmt.Sprint(cfg.TrustedProxies) != "[10.0.0.0/8 192.168.1.5/32]" || !cfg.ProxyProtocol {
+		t.Errorf("unexpected proxy settings %v, %v", cfg.TrustedProxies, cfg.ProxyProtocol)
+	}
+
+	// Per-cluster settings
+	t.Setenv("KUBELOGS_CLUSTER_RETENTION_DAYS", "east=7, west=0,bad,south=x")
+	t.Setenv("KUBELOGS_CLUSTER_QUOTAS", "east=1000,*=50")
+	cfg = ConfigFromEnv()
+	if len(cfg.ClusterRetentionDays) != 2 || cfg.ClusterRetentionDays["east"] != 7 || cfg.ClusterRetentionDays["west"] != 0 {
+		t.Errorf("unexpected cluster retention %v", cfg.ClusterRetentionDays)
+	}
+	if cfg.ClusterRetention("east") != 7 || cfg.ClusterRetention("other") != cfg.RetentionDays {
+		t.Errorf("unexpected ClusterRetention results")
+	}
+	if len(cfg.ClusterQuotas) != 2 || cfg.ClusterQuotas["east"] != 1000 || cfg.ClusterQuotas["*"] != 50 {
+		t.Errorf("unexpected cluster quotas %v", cfg.ClusterQuotas)
+	}
+
 	// Test non-numeric value (should use default)
 	t.Setenv("KUBELOGS_RETENTION_DAYS", "abc")
 	cfg = ConfigFromEnv()
@@ -0,0 +1,286 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/audit"
+	"github.com/kubelogs/kubelogs/internal/auth"
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// RetentionPolicy is how long logs are kept, in days, 0 meaning forever:
+// Days for clusters without an entry in ClusterDays.
+type RetentionPolicy struct {
+	Days        int            `json:"days"`
+	ClusterDays map[string]int `json:"clusterDays,omitempty"`
+}
+
+// RetentionPolicy returns the retention configured in c.
+func (c Config) RetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{Days: c.RetentionDays, ClusterDays: c.ClusterRetentionDays}
+}
+
+// days returns the retention of cluster.
+func (p RetentionPolicy) days(cluster string) int {
+	if days, ok := p.ClusterDays[cluster]; ok {
+		return days
+	}
+	return p.Days
+}
+
+// equal reports whether p and q keep every cluster's logs as long.
+func (p RetentionPolicy) equal(q RetentionPolicy) bool {
+	return p.Days == q.Days && maps.Equal(p.ClusterDays, q.ClusterDays)
+}
+
+// shrinksTo reports whether applying q instead of p deletes the logs of
+// some cluster sooner.
+func (p RetentionPolicy) shrinksTo(q RetentionPolicy) bool {
+	if keptLonger(p.Days, q.Days) {
+		return true
+	}
+	for _, cluster := range overriddenClusters(p, q) {
+		if keptLonger(p.days(cluster), q.days(cluster)) {
+			return true
+		}
+	}
+	return false
+}
+
+// widest returns the longer retention of p and q for every cluster.
+func widest(p, q RetentionPolicy) RetentionPolicy {
+	out := RetentionPolicy{Days: longerRetention(p.Days, q.Days)}
+	for _, cluster := range overriddenClusters(p, q) {
+		if days := longerRetention(p.days(cluster), q.days(cluster)); days != out.Days {
+			if out.ClusterDays == nil {
+				out.ClusterDays = make(map[string]int)
+			}
+			out.ClusterDays[cluster] = days
+		}
+	}
+	return out
+}
+
+// overriddenClusters returns the clusters with an override in p or q.
+func overriddenClusters(p, q RetentionPolicy) []string {
+	clusters := slices.Collect(maps.Keys(p.ClusterDays))
+	for cluster := range q.ClusterDays {
+		if _, ok := p.ClusterDays[cluster]; !ok {
+			clusters = append(clusters, cluster)
+		}
+	}
+	slices.Sort(clusters)
+	return clusters
+}
+
+// keptLonger reports whether a retention of a days keeps logs longer
+// than one of b days.
+func keptLonger(a, b int) bool {
+	return b != 0 && (a == 0 || a > b)
+}
+
+func longerRetention(a, b int) int {
+	if keptLonger(a, b) {
+		return a
+	}
+	return b
+}
+
+// retentionOp returns the operation key confirmation tokens of a
+// retention shrinkage from applied to configured are bound to.
+func retentionOp(applied, configured RetentionPolicy) string {
+	return "retention " + RetentionChange(applied, configured)
+}
+
+// RetentionChange describes a retention shrinkage as a JSON object, for
+// logs and audit records.
+func RetentionChange(applied, configured RetentionPolicy) string {
+	b, _ := json.Marshal(map[string]RetentionPolicy{"from": applied, "to": configured})
+	return string(b)
+}
+
+// loadRetentionPolicy reads the retention last applied, or nil if none
+// was recorded.
+func loadRetentionPolicy(ctx context.Context, db *sql.DB) (*RetentionPolicy, error) {
+	var data string
+	err := db.QueryRowContext(ctx, `SELECT policy FROM retention_policy WHERE id = 1`).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read retention policy: %w", err)
+	}
+	var p RetentionPolicy
+	if err := json.Unmarshal([]byte(data), &p); err != nil {
+		return nil, fmt.Errorf("decode retention policy: %w", err)
+	}
+	return &p, nil
+}
+
+// saveRetentionPolicy records p as the retention applied.
+func saveRetentionPolicy(ctx context.Context, db *sql.DB, p RetentionPolicy) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO retention_policy (id, policy, applied_at) VALUES (1, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET policy = excluded.policy, applied_at = excluded.applied_at
+	`, string(data), time.Now().UnixNano())
+	if err != nil {
+		return fmt.Errorf("save retention policy: %w", err)
+	}
+	return nil
+}
+
+// CountShrinkage counts the entries applying configured instead of
+// applied would delete now, or returns -1 if store can't count them.
+// With per-cluster retention, only entries of named clusters are
+// counted.
+func CountShrinkage(ctx context.Context, store storage.Store, applied, configured RetentionPolicy) (int64, error) {
+	count := func(cluster string) (int64, error) {
+		from, to := applied.days(cluster), configured.days(cluster)
+		if !keptLonger(from, to) {
+			return 0, nil
+		}
+		q := storage.Query{Cluster: cluster, EndTime: retentionCutoff(to)}
+		if from > 0 {
+			q.StartTime = retentionCutoff(from)
+		}
+		return countMatches(ctx, store, q)
+	}
+	if len(applied.ClusterDays) == 0 && len(configured.ClusterDays) == 0 {
+		return count("")
+	}
+
+	lister, ok := store.(ClusterLister)
+	if !ok {
+		return -1, nil
+	}
+	clusters, err := lister.ListClusters(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, cluster := range clusters {
+		if cluster == "" {
+			continue
+		}
+		n, err := count(cluster)
+		if n < 0 || err != nil {
+			return n, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// retentionPreviewResponse is the JSON response for a retention
+// shrinkage preview.
+type retentionPreviewResponse struct {
+	Applied        RetentionPolicy `json:"applied"`
+	Configured     RetentionPolicy `json:"configured"`
+	Matched        int64           `json:"matched"` // -1 if the backend can't count
+	Token          string          `json:"token"`
+	ExpiresAt      time.Time       `json:"expiresAt"`
+	SecondApprover bool            `json:"secondApprover"` // Another admin must confirm
+}
+
+// handlePreviewRetention counts the entries the configured retention
+// would delete sooner than the one applied, and issues the token
+// applying it must present.
+func (s *HTTPServer) handlePreviewRetention(w http.ResponseWriter, r *http.Request) {
+	applied, configured, ok := s.pendingRetention(w, r)
+	if !ok {
+		return
+	}
+	matched, err := CountShrinkage(r.Context(), s.store, applied, configured)
+	if err != nil {
+		slog.Error("retention preview error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	token, expires, err := s.confirms.issue(retentionOp(applied, configured), user.Username, matched, time.Now())
+	if err != nil {
+		slog.Error("issue confirmation token", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	slog.Info("previewed retention shrinkage", "user", user.Username, "change", RetentionChange(applied, configured), "matched", matched)
+	writeJSON(w, retentionPreviewResponse{
+		Applied:        applied,
+		Configured:     configured,
+		Matched:        matched,
+		Token:          token,
+		ExpiresAt:      expires,
+		SecondApprover: s.confirms.secondApprover,
+	})
+}
+
+// handleApplyRetention applies the configured retention in place of a
+// longer one. The confirm parameter must be the token of a preview of
+// the same change.
+func (s *HTTPServer) handleApplyRetention(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("confirm")
+	if token == "" {
+		http.Error(w, "A confirm token from POST /api/admin/retention/preview is required", http.StatusPreconditionRequired)
+		return
+	}
+	applied, configured, ok := s.pendingRetention(w, r)
+	if !ok {
+		return
+	}
+	user, _ := auth.UserFromContext(r.Context())
+	preview, err := s.confirms.redeem(token, retentionOp(applied, configured), user.Username, time.Now())
+	switch {
+	case errors.Is(err, errConfirmSelf):
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	if err := s.retention.ApplyRetention(r.Context()); err != nil {
+		slog.Error("apply retention error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	filter := RetentionChange(applied, configured)
+	slog.Info("applied shorter retention", "user", user.Username, "requested_by", preview.requester, "change", filter)
+	s.confirms.record(r.Context(), audit.Record{
+		Operation: audit.OpRetention,
+		Filter:    filter,
+		Requester: preview.requester,
+		Approver:  user.Username,
+		Count:     preview.matched,
+	})
+	writeJSON(w, map[string]RetentionPolicy{"applied": configured})
+}
+
+// pendingRetention returns the retention applied and the shorter one
+// configured, writing an error unless a shrinkage awaits confirmation.
+func (s *HTTPServer) pendingRetention(w http.ResponseWriter, r *http.Request) (applied, configured RetentionPolicy, ok bool) {
+	applied, configured, pending, err := s.retention.PendingShrinkage(r.Context())
+	if err != nil {
+		slog.Error("read retention policy error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return applied, configured, false
+	}
+	if !pending {
+		http.Error(w, "The configured retention doesn't shorten the one applied", http.StatusConflict)
+		return applied, configured, false
+	}
+	return applied, configured, true
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/auth"
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// rebuildProgress is one line of the response to a search index
+// rebuild.
+type rebuildProgress struct {
+	Indexed int64  `json:"indexed"`
+	Total   int64  `json:"total"`
+	Done    bool   `json:"done,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// handleRebuildSearchIndex rebuilds the full-text index from the stored
+// entries, e.g. after a tokenizer change, streaming progress as NDJSON
+// after each batch. Disconnecting stops the rebuild; entries already
+// queued for indexing are still indexed in the background.
+func (s *HTTPServer) handleRebuildSearchIndex(w http.ResponseWriter, r *http.Request) {
+	rb, ok := s.store.(storage.SearchIndexRebuilder)
+	if !ok {
+		http.Error(w, "Storage backend does not support rebuilding the search index", http.StatusNotImplemented)
+		return
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	slog.Info("rebuilding search index", "user", user.Username)
+	start := time.Now()
+
+	keepWriting(w)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	var last rebuildProgress
+	started := false
+	indexed, err := rb.RebuildSearchIndex(r.Context(), func(p storage.IndexProgress) {
+		started = true
+		last = rebuildProgress{Indexed: p.Indexed, Total: p.Total}
+		enc.Encode(last)
+		flushResponse(w)
+	})
+	if err != nil {
+		slog.Error("search index rebuild error", "user", user.Username, "indexed", indexed, "error", err)
+		// Once progress was sent, the status can't change
+		if started {
+			enc.Encode(rebuildProgress{Indexed: indexed, Total: last.Total, Error: err.Error()})
+			return
+		}
+		if errors.Is(err, storage.ErrStorageClosed) {
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("rebuilt search index", "user", user.Username, "indexed", indexed, "duration", time.Since(start).Round(time.Millisecond))
+	enc.Encode(rebuildProgress{Indexed: indexed, Total: max(last.Total, indexed), Done: true})
+}
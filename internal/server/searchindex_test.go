@@ -0,0 +1,74 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/auth"
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestRebuildSearchIndex(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	var batch storage.LogBatch
+	for i := range 20 {
+		batch = append(batch, storage.LogEntry{Timestamp: time.Now(), Namespace: "a", Pod: "pod", Container: "c", Message: fmt.Sprintf("line %d", i)})
+	}
+	store.Write(ctx, batch)
+	store.Flush(ctx)
+
+	s := &HTTPServer{store: store, adminUsers: map[string]bool{"root": true}}
+	do := func(user *auth.User) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/search-index/rebuild", nil)
+		req = req.WithContext(auth.ContextWithUser(req.Context(), user))
+		rec := httptest.NewRecorder()
+		s.requireAdmin(s.handleRebuildSearchIndex).ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(&auth.User{ID: 2, Username: "alice"}); rec.Code != http.StatusForbidden {
+		t.Errorf("non-admin status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	rec := do(&auth.User{ID: 1, Username: "root"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("rebuild status = %d: %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q, want application/x-ndjson", ct)
+	}
+	var lines []rebuildProgress
+	sc := bufio.NewScanner(rec.Body)
+	for sc.Scan() {
+		var p rebuildProgress
+		if err := json.Unmarshal(sc.Bytes(), &p); err != nil {
+			t.Fatalf("decode %q: %v", sc.Text(), err)
+		}
+		lines = append(lines, p)
+	}
+	want := []rebuildProgress{{Indexed: 20, Total: 20}, {Indexed: 20, Total: 20, Done: true}}
+	if len(lines) != len(want) || lines[0] != want[0] || lines[1] != want[1] {
+		t.Errorf("progress = %+v, want %+v", lines, want)
+	}
+
+	result, err := store.Query(ctx, storage.Query{Search: "line"})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entries) != 20 {
+		t.Errorf("search found %d entries after rebuild, want 20", len(result.Entries))
+	}
+}
@@ -1,11 +1,17 @@
 package server
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"io"
+	"maps"
+	"math"
+	"sync"
 	"time"
 
 	"google.golang.org/grpc/codes"
+	_ "google.golang.org/grpc/encoding/gzip" // Accepts gzip-compressed calls
 	"google.golang.org/grpc/status"
 
 	"github.com/kubelogs/kubelogs/api/storagepb"
@@ -15,56 +21,216 @@ import (
 // Server implements the StorageService gRPC server.
 type Server struct {
 	storagepb.UnimplementedStorageServiceServer
-	store storage.Store
+	store     storage.Store
+	quotas    *clusterQuotas
+	enrichers []Enricher
+	pressure  *backpressure
+	fleet     *Fleet
+	metrics   serverMetrics
+
+	queryTimeout time.Duration // 0 = unlimited
+
+	// stopping is closed by Stop, ending the streams that would
+	// otherwise stay open as long as their clients
+	stopping chan struct{}
+	stopOnce sync.Once
 }
 
 // New creates a new gRPC server wrapping the given store.
 func New(store storage.Store) *Server {
-	return &Server{store: store}
+	return &Server{store: store, stopping: make(chan struct{})}
+}
+
+// Stop ends open WriteStream calls, after the batch being written, and
+// Tail calls with UNAVAILABLE, so clients reconnect to another server
+// and grpc.Server.GracefulStop doesn't wait on them. Call it before
+// GracefulStop.
+func (s *Server) Stop() {
+	s.stopOnce.Do(func() { close(s.stopping) })
+}
+
+// errStopping is returned to streams ended by Stop.
+func errStopping() error {
+	return status.Error(codes.Unavailable, "server shutting down")
+}
+
+// SetClusterQuotas limits the entries each cluster may write per UTC day
+// (see Config.ClusterQuotas). Call before serving.
+func (s *Server) SetClusterQuotas(limits map[string]int64) {
+	if len(limits) == 0 {
+		s.quotas = nil
+		return
+	}
+	s.quotas = newClusterQuotas(limits)
+}
+
+// SetBackpressure asks writers to slow down while the average write to
+// the store takes longer than latency, and rejects writes while the
+// filesystem holding path has less than minFreeBytes free. Zero values
+// disable either check. Call before serving.
+func (s *Server) SetBackpressure(latency time.Duration, minFreeBytes uint64, path string) {
+	if latency <= 0 && minFreeBytes == 0 {
+		s.pressure = nil
+		return
+	}
+	s.pressure = &backpressure{latency: latency, minFree: minFreeBytes, path: path}
+}
+
+// SetQueryTimeout cancels queries still running after d (see
+// Config.QueryTimeout). Zero disables the limit. Call before serving.
+func (s *Server) SetQueryTimeout(d time.Duration) {
+	s.queryTimeout = d
+}
+
+// withQueryTimeout bounds ctx by d, if positive. Cancelling a query's
+// context interrupts the SQLite statement running it, so a slow query
+// gives up the database connection rather than holding it.
+func withQueryTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// SetFleet records collector status reports in fleet. Without one,
+// reports are rejected as UNIMPLEMENTED. Call before serving.
+func (s *Server) SetFleet(fleet *Fleet) {
+	s.fleet = fleet
+}
+
+// SetEnrichers sets the enrichers applied to every batch before it is
+// written, in order. Call before serving.
+func (s *Server) SetEnrichers(enrichers ...Enricher) {
+	s.enrichers = enrichers
 }
 
 // Write persists a batch of log entries.
 func (s *Server) Write(ctx context.Context, req *storagepb.WriteRequest) (*storagepb.WriteResponse, error) {
+	s.metrics.writeRequests.Inc()
+
+	if s.pressure != nil && s.pressure.diskFull(time.Now()) {
+		s.metrics.throttled.Inc()
+		return nil, errDiskFull()
+	}
+
+	// The batch TTL applies to entries without their own expiry
+	var expires time.Time
+	if req.TtlMillis > 0 {
+		expires = time.Now().Add(time.Duration(req.TtlMillis) * time.Millisecond)
+	}
 	entries := make(storage.LogBatch, len(req.Entries))
 	for i, e := range req.Entries {
 		entries[i] = fromProtoEntry(e)
+		if entries[i].ExpiresAt.IsZero() {
+			entries[i].ExpiresAt = expires
+		}
+	}
+
+	// Entries over quota are dropped rather than rejected, so collectors
+	// don't retry them
+	var admitted map[string]int64
+	if s.quotas != nil {
+		entries, admitted = s.quotas.admit(entries, time.Now())
+		s.metrics.droppedEntries.Add(int64(len(req.Entries) - len(entries)))
+	}
+
+	for _, e := range s.enrichers {
+		e.Enrich(ctx, entries)
 	}
 
+	start := time.Now()
 	n, err := s.store.Write(ctx, entries)
+	if s.pressure != nil {
+		s.pressure.observe(time.Since(start))
+	}
 	if err != nil {
+		if s.quotas != nil {
+			s.quotas.refund(admitted)
+		}
+		s.metrics.writeErrors.Inc()
 		return nil, status.Errorf(codes.Internal, "write failed: %v", err)
 	}
+	s.metrics.writtenEntries.Add(int64(n))
 
-	return &storagepb.WriteResponse{Count: int32(n)}, nil
+	resp := &storagepb.WriteResponse{Count: int32(n), SeverityCounts: make(map[uint32]int64)}
+	for _, e := range entries {
+		resp.SeverityCounts[uint32(e.Severity)]++
+	}
+	if s.pressure != nil {
+		if d := s.pressure.delay(); d > 0 {
+			s.metrics.throttled.Inc()
+			resp.RetryAfterMillis = d.Milliseconds()
+		}
+	}
+	return resp, nil
 }
 
-// Query searches for log entries matching the given criteria.
-func (s *Server) Query(ctx context.Context, req *storagepb.QueryRequest) (*storagepb.QueryResponse, error) {
-	q := storage.Query{
-		Search:      req.Search,
-		Namespace:   req.Namespace,
-		Pod:         req.Pod,
-		Container:   req.Container,
-		MinSeverity: storage.Severity(req.MinSeverity),
-		Attributes:  req.Attributes,
-		Pagination: storage.Pagination{
-			Limit:    int(req.Limit),
-			AfterID:  req.AfterId,
-			BeforeID: req.BeforeId,
-			Order:    fromProtoOrder(req.Order),
-		},
+// WriteStream writes each batch received as Write does and acknowledges
+// it, until the client closes the stream, a write fails or the server
+// stops.
+func (s *Server) WriteStream(stream storagepb.StorageService_WriteStreamServer) error {
+	// Receive in the background, so Stop ends a stream waiting for its
+	// next batch; returning cancels the stream and with it the receiver
+	type received struct {
+		req *storagepb.WriteRequest
+		err error
 	}
+	reqs := make(chan received)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			select {
+			case reqs <- received{req, err}:
+			case <-stream.Context().Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
 
-	// Only set time filters if non-zero (zero means no filter)
-	if req.StartTimeNanos != 0 {
-		q.StartTime = time.Unix(0, req.StartTimeNanos)
+	for {
+		var r received
+		select {
+		case <-s.stopping:
+			return errStopping()
+		case r = <-reqs:
+		}
+		if errors.Is(r.err, io.EOF) {
+			return nil
+		}
+		if r.err != nil {
+			return r.err
+		}
+		resp, err := s.Write(stream.Context(), r.req)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
 	}
-	if req.EndTimeNanos != 0 {
-		q.EndTime = time.Unix(0, req.EndTimeNanos)
+}
+
+// Query searches for log entries matching the given criteria.
+func (s *Server) Query(ctx context.Context, req *storagepb.QueryRequest) (*storagepb.QueryResponse, error) {
+	q, err := fromProtoQuery(req)
+	if err != nil {
+		return nil, err
 	}
 
+	ctx, cancel := withQueryTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	start := time.Now()
 	result, err := s.store.Query(ctx, q)
+	s.metrics.queryDuration.Observe(since(start))
 	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			s.metrics.queryTimeouts.Inc()
+			return nil, status.Error(codes.DeadlineExceeded, "query timed out")
+		}
 		return nil, status.Errorf(codes.Internal, "query failed: %v", err)
 	}
 
@@ -73,12 +239,19 @@ func (s *Server) Query(ctx context.Context, req *storagepb.QueryRequest) (*stora
 		pbEntries[i] = toProtoEntry(e)
 	}
 
-	return &storagepb.QueryResponse{
+	resp := &storagepb.QueryResponse{
 		Entries:       pbEntries,
 		HasMore:       result.HasMore,
 		NextCursor:    result.NextCursor,
 		TotalEstimate: result.TotalEstimate,
-	}, nil
+		SampleRate:    result.SampleRate,
+		TotalCapped:   result.TotalCapped,
+	}
+	if !result.NextCursorTimestamp.IsZero() {
+		resp.NextCursorTimestampNanos = result.NextCursorTimestamp.UnixNano()
+	}
+
+	return resp, nil
 }
 
 // GetByID retrieves a single entry by its ID.
@@ -94,16 +267,26 @@ func (s *Server) GetByID(ctx context.Context, req *storagepb.GetByIDRequest) (*s
 	return &storagepb.GetByIDResponse{Entry: toProtoEntry(*entry)}, nil
 }
 
-// Delete removes entries older than the given timestamp.
-func (s *Server) Delete(ctx context.Context, req *storagepb.DeleteRequest) (*storagepb.DeleteResponse, error) {
-	olderThan := time.Unix(0, req.OlderThanNanos)
+// maxGetByIDs bounds the number of IDs accepted in one GetByIDs call.
+const maxGetByIDs = 1000
+
+// GetByIDs retrieves multiple entries by ID in one call.
+func (s *Server) GetByIDs(ctx context.Context, req *storagepb.GetByIDsRequest) (*storagepb.GetByIDsResponse, error) {
+	if len(req.Ids) > maxGetByIDs {
+		return nil, status.Errorf(codes.InvalidArgument, "too many ids: %d (max %d)", len(req.Ids), maxGetByIDs)
+	}
 
-	count, err := s.store.Delete(ctx, olderThan)
+	entries, err := s.store.GetByIDs(ctx, req.Ids)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "delete failed: %v", err)
+		return nil, status.Errorf(codes.Internal, "get by ids failed: %v", err)
+	}
+
+	pbEntries := make([]*storagepb.LogEntry, len(entries))
+	for i, e := range entries {
+		pbEntries[i] = toProtoEntry(e)
 	}
 
-	return &storagepb.DeleteResponse{DeletedCount: count}, nil
+	return &storagepb.GetByIDsResponse{Entries: pbEntries}, nil
 }
 
 // Stats returns storage statistics.
@@ -113,12 +296,132 @@ func (s *Server) Stats(ctx context.Context, req *storagepb.StatsRequest) (*stora
 		return nil, status.Errorf(codes.Internal, "stats failed: %v", err)
 	}
 
-	return &storagepb.StatsResponse{
+	resp := &storagepb.StatsResponse{
 		TotalEntries:     stats.TotalEntries,
 		DiskSizeBytes:    stats.DiskSizeBytes,
 		OldestEntryNanos: stats.OldestEntry.UnixNano(),
 		NewestEntryNanos: stats.NewestEntry.UnixNano(),
-	}, nil
+	}
+	for _, u := range stats.Namespaces {
+		resp.Namespaces = append(resp.Namespaces, &storagepb.NamespaceUsage{
+			Namespace: u.Namespace,
+			Entries:   u.Entries,
+			Bytes:     u.Bytes,
+		})
+	}
+	return resp, nil
+}
+
+// Aggregate counts the entries matching a query in time buckets, or
+// summarizes them per group.
+func (s *Server) Aggregate(ctx context.Context, req *storagepb.AggregateRequest) (*storagepb.AggregateResponse, error) {
+	if len(req.GroupBy) > 0 || req.ValueAttribute != "" {
+		return s.aggregateGroups(ctx, req)
+	}
+
+	agg, ok := s.store.(storage.Aggregator)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "store does not support aggregation")
+	}
+	if req.IntervalNanos <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "interval_nanos must be positive")
+	}
+
+	q, err := fromProtoQuery(req.GetQuery())
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	buckets, err := agg.Histogram(ctx, q, time.Duration(req.IntervalNanos))
+	s.metrics.queryDuration.Observe(since(start))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "aggregate failed: %v", err)
+	}
+
+	resp := &storagepb.AggregateResponse{Buckets: make([]*storagepb.HistogramBucket, len(buckets))}
+	for i, b := range buckets {
+		resp.Buckets[i] = &storagepb.HistogramBucket{
+			StartNanos: b.Start.UnixNano(),
+			Severity:   uint32(b.Severity),
+			Count:      b.Count,
+		}
+	}
+	return resp, nil
+}
+
+// aggregateGroups answers an Aggregate call with group_by or
+// value_attribute.
+func (s *Server) aggregateGroups(ctx context.Context, req *storagepb.AggregateRequest) (*storagepb.AggregateResponse, error) {
+	agg, ok := s.store.(storage.GroupAggregator)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "store does not support grouped aggregation")
+	}
+	a := storage.Aggregation{
+		GroupBy:  req.GroupBy,
+		Interval: time.Duration(req.IntervalNanos),
+		Value:    req.ValueAttribute,
+	}
+	if err := a.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	q, err := fromProtoQuery(req.GetQuery())
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	groups, err := agg.Aggregate(ctx, q, a)
+	s.metrics.queryDuration.Observe(since(start))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "aggregate failed: %v", err)
+	}
+
+	resp := &storagepb.AggregateResponse{Groups: make([]*storagepb.AggregateGroup, len(groups))}
+	for i, g := range groups {
+		pg := &storagepb.AggregateGroup{
+			Keys:       g.Keys,
+			Count:      g.Count,
+			ValueCount: g.ValueCount,
+			Sum:        g.Sum,
+			Min:        g.Min,
+			Max:        g.Max,
+			Avg:        g.Avg(),
+		}
+		if !g.Start.IsZero() {
+			pg.StartNanos = g.Start.UnixNano()
+		}
+		resp.Groups[i] = pg
+	}
+	return resp, nil
+}
+
+// ReportCollectorStatus records a collector's health report.
+func (s *Server) ReportCollectorStatus(ctx context.Context, req *storagepb.ReportCollectorStatusRequest) (*storagepb.ReportCollectorStatusResponse, error) {
+	if s.fleet == nil {
+		return nil, status.Error(codes.Unimplemented, "collector status reports are not enabled")
+	}
+	if req.Node == "" {
+		return nil, status.Error(codes.InvalidArgument, "node is required")
+	}
+
+	s.fleet.Report(storage.CollectorStatus{
+		Node:              req.Node,
+		Cluster:           req.Cluster,
+		StartedAt:         time.Unix(0, req.StartedAtNanos),
+		Interval:          time.Duration(req.IntervalMillis) * time.Millisecond,
+		ActiveStreams:     int(req.ActiveStreams),
+		CatchingUpStreams: int(req.CatchingUpStreams),
+		LinesRead:         req.LinesRead,
+		StreamErrors:      req.StreamErrors,
+		EntriesWritten:    req.EntriesWritten,
+		WriteErrors:       req.WriteErrors,
+		BufferedEntries:   int(req.BufferedEntries),
+		RetryQueueBatches: int(req.RetryQueueBatches),
+		CircuitOpen:       req.CircuitOpen,
+	}, time.Now())
+	return &storagepb.ReportCollectorStatusResponse{}, nil
 }
 
 // toProtoEntry converts a storage.LogEntry to protobuf.
@@ -126,6 +429,7 @@ func toProtoEntry(e storage.LogEntry) *storagepb.LogEntry {
 	return &storagepb.LogEntry{
 		Id:             e.ID,
 		TimestampNanos: e.Timestamp.UnixNano(),
+		Cluster:        e.Cluster,
 		Namespace:      e.Namespace,
 		Pod:            e.Pod,
 		Container:      e.Container,
@@ -136,17 +440,22 @@ func toProtoEntry(e storage.LogEntry) *storagepb.LogEntry {
 }
 
 // fromProtoEntry converts a protobuf LogEntry to storage.LogEntry.
-func fromProtoEntry(e *storagepb.LogEntry) storage.LogEntry {
-	return storage.LogEntry{
-		ID:         e.Id,
-		Timestamp:  time.Unix(0, e.TimestampNanos),
-		Namespace:  e.Namespace,
-		Pod:        e.Pod,
-		Container:  e.Container,
-		Severity:   storage.Severity(e.Severity),
-		Message:    e.Message,
-		Attributes: e.Attributes,
+func fromProtoEntry(pe *storagepb.LogEntry) storage.LogEntry {
+	e := storage.LogEntry{
+		ID:         pe.Id,
+		Timestamp:  time.Unix(0, pe.TimestampNanos),
+		Cluster:    pe.Cluster,
+		Namespace:  pe.Namespace,
+		Pod:        pe.Pod,
+		Container:  pe.Container,
+		Severity:   storage.Severity(pe.Severity),
+		Message:    pe.Message,
+		Attributes: pe.Attributes,
 	}
+	if pe.ExpiresAtNanos != 0 {
+		e.ExpiresAt = time.Unix(0, pe.ExpiresAtNanos)
+	}
+	return e
 }
 
 // fromProtoOrder converts protobuf Order to storage.Order.
@@ -156,3 +465,75 @@ func fromProtoOrder(o storagepb.Order) storage.Order {
 	}
 	return storage.OrderDesc
 }
+
+// fromProtoOrderBy converts protobuf OrderBy to storage.OrderBy.
+func fromProtoOrderBy(o storagepb.OrderBy) storage.OrderBy {
+	if o == storagepb.OrderBy_ORDER_BY_TIMESTAMP {
+		return storage.OrderByTimestamp
+	}
+	return storage.OrderByID
+}
+
+// fromProtoQuery converts a protobuf QueryRequest to storage.Query.
+// Filters of the query string are combined with the structured fields,
+// which take precedence.
+func fromProtoQuery(req *storagepb.QueryRequest) (storage.Query, error) {
+	var q storage.Query
+	if req.GetQueryString() != "" {
+		var err error
+		if q, err = storage.ParseQueryString(req.GetQueryString()); err != nil {
+			return q, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	q.Search = cmp.Or(req.GetSearch(), q.Search)
+	q.Cluster = cmp.Or(req.GetCluster(), q.Cluster)
+	q.Namespace = cmp.Or(req.GetNamespace(), q.Namespace)
+	q.Pod = cmp.Or(req.GetPod(), q.Pod)
+	q.Container = cmp.Or(req.GetContainer(), q.Container)
+	q.MinSeverity = cmp.Or(storage.Severity(req.GetMinSeverity()), q.MinSeverity)
+	if q.Attributes == nil {
+		q.Attributes = req.GetAttributes()
+	} else {
+		maps.Copy(q.Attributes, req.GetAttributes())
+	}
+	for _, pb := range req.GetAttributeExprs() {
+		expr := make(storage.AttrExpr, len(pb.GetTerms()))
+		for i, t := range pb.GetTerms() {
+			// AttrOp and AttributeOp share their values
+			expr[i] = storage.AttrTerm{Key: t.GetKey(), Op: storage.AttrOp(t.GetOp()), Value: t.GetValue()}
+		}
+		q.AttrExprs = append(q.AttrExprs, expr)
+	}
+	if s := req.GetSample(); s < 0 || s > 1 || math.IsNaN(s) {
+		return q, status.Errorf(codes.InvalidArgument, "sample must be between 0 and 1, got %v", s)
+	}
+	q.Sample = req.GetSample()
+	// Consistency and storage.Consistency share their values
+	q.Consistency = storage.Consistency(req.GetConsistency())
+	q.Count = req.GetCount()
+	q.Pagination = storage.Pagination{
+		Limit:    int(req.GetLimit()),
+		AfterID:  req.GetAfterId(),
+		BeforeID: req.GetBeforeId(),
+		MaxID:    req.GetMaxId(),
+		Order:    fromProtoOrder(req.GetOrder()),
+		OrderBy:  fromProtoOrderBy(req.GetOrderBy()),
+	}
+
+	// Only set time filters if non-zero (zero means no filter)
+	if req.GetStartTimeNanos() != 0 {
+		q.StartTime = time.Unix(0, req.GetStartTimeNanos())
+	}
+	if req.GetEndTimeNanos() != 0 {
+		q.EndTime = time.Unix(0, req.GetEndTimeNanos())
+	}
+	if req.GetAfterTimestampNanos() != 0 {
+		q.Pagination.AfterTimestamp = time.Unix(0, req.GetAfterTimestampNanos())
+	}
+	if req.GetBeforeTimestampNanos() != 0 {
+		q.Pagination.BeforeTimestamp = time.Unix(0, req.GetBeforeTimestampNanos())
+	}
+
+	return q, nil
+}
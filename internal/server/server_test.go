@@ -2,15 +2,25 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"maps"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
 
 	"github.com/kubelogs/kubelogs/api/storagepb"
 	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/remote"
 	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
 )
 
@@ -62,6 +72,7 @@ func TestServer_WriteAndQuery(t *testing.T) {
 		},
 		{
 			TimestampNanos: now.Add(time.Second).UnixNano(),
+			Cluster:        "east",
 			Namespace:      "default",
 			Pod:            "test-pod-2",
 			Container:      "main",
@@ -78,6 +89,10 @@ func TestServer_WriteAndQuery(t *testing.T) {
 	if writeResp.Count != 2 {
 		t.Errorf("expected 2 entries written, got %d", writeResp.Count)
 	}
+	wantSeverities := map[uint32]int64{uint32(storage.SeverityInfo): 1, uint32(storage.SeverityError): 1}
+	if !maps.Equal(writeResp.SeverityCounts, wantSeverities) {
+		t.Errorf("SeverityCounts = %v, want %v", writeResp.SeverityCounts, wantSeverities)
+	}
 
 	// Query all entries
 	queryResp, err := client.Query(ctx, &storagepb.QueryRequest{
@@ -104,6 +119,19 @@ func TestServer_WriteAndQuery(t *testing.T) {
 		t.Errorf("expected 2 entries in namespace default, got %d", len(queryResp.Entries))
 	}
 
+	// Query by cluster
+	queryResp, err = client.Query(ctx, &storagepb.QueryRequest{
+		Cluster: "east",
+		Limit:   10,
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	if len(queryResp.Entries) != 1 || queryResp.Entries[0].Cluster != "east" {
+		t.Errorf("expected 1 entry in cluster east, got %v", queryResp.Entries)
+	}
+
 	// Query by severity
 	queryResp, err = client.Query(ctx, &storagepb.QueryRequest{
 		MinSeverity: uint32(storage.SeverityError),
@@ -116,6 +144,245 @@ func TestServer_WriteAndQuery(t *testing.T) {
 	if len(queryResp.Entries) != 1 {
 		t.Errorf("expected 1 error entry, got %d", len(queryResp.Entries))
 	}
+
+	// Query by query string, combined with structured fields
+	queryResp, err = client.Query(ctx, &storagepb.QueryRequest{
+		QueryString: "namespace=default&minSeverity=3&search=test",
+		Pod:         "test-pod-1",
+		Limit:       10,
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	if len(queryResp.Entries) != 1 || queryResp.Entries[0].Pod != "test-pod-1" {
+		t.Errorf("expected the entry of test-pod-1, got %v", queryResp.Entries)
+	}
+
+	for _, qs := range []string{"minSeverity=9", "startTime=yesterday", "namespace=%zz"} {
+		_, err = client.Query(ctx, &storagepb.QueryRequest{QueryString: qs})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("query string %q: code = %v, want InvalidArgument", qs, status.Code(err))
+		}
+	}
+}
+
+func TestServer_QueryConsistency(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:", WriteBufferSize: 100})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	storagepb.RegisterStorageServiceServer(grpcServer, New(store))
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	client, err := remote.NewClient(lis.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if _, err := client.Write(ctx, storage.LogBatch{
+		{Timestamp: time.Now(), Namespace: "default", Pod: "p", Container: "c", Message: "buffered"},
+	}); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	// The entry is still in the server's write buffer
+	result, err := client.Query(ctx, storage.Query{Consistency: storage.ConsistencyEventual})
+	if err != nil || len(result.Entries) != 0 {
+		t.Fatalf("eventual query = %v, %v, want no entries", result, err)
+	}
+	result, err = client.Query(ctx, storage.Query{})
+	if err != nil || len(result.Entries) != 1 {
+		t.Fatalf("strong query = %v, %v, want the acknowledged entry", result, err)
+	}
+}
+
+// unaryOnlyServer is a server predating WriteStream.
+type unaryOnlyServer struct {
+	*Server
+}
+
+func (unaryOnlyServer) WriteStream(storagepb.StorageService_WriteStreamServer) error {
+	return status.Error(codes.Unimplemented, "method WriteStream not implemented")
+}
+
+// headerStats is a stats.Handler calling itself with the headers of
+// every call.
+type headerStats func(*stats.InHeader)
+
+func (h headerStats) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (h headerStats) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (h headerStats) HandleConn(context.Context, stats.ConnStats) {}
+
+func (h headerStats) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if in, ok := s.(*stats.InHeader); ok {
+		h(in)
+	}
+}
+
+func TestServer_WriteStream(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		unaryOnly  bool
+		wantMethod string
+	}{
+		{"stream", false, storagepb.StorageService_WriteStream_FullMethodName},
+		{"fallback to unary", true, storagepb.StorageService_Write_FullMethodName},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := sqlite.New(sqlite.Config{Path: ":memory:", WriteBufferSize: 1})
+			if err != nil {
+				t.Fatalf("failed to create store: %v", err)
+			}
+			defer store.Close()
+
+			lis, err := net.Listen("tcp", "localhost:0")
+			if err != nil {
+				t.Fatalf("failed to listen: %v", err)
+			}
+			var methods, encodings []string
+			var mu sync.Mutex
+			record := func(method string) {
+				mu.Lock()
+				methods = append(methods, method)
+				mu.Unlock()
+			}
+			grpcServer := grpc.NewServer(
+				grpc.StatsHandler(headerStats(func(h *stats.InHeader) {
+					mu.Lock()
+					encodings = append(encodings, h.Compression)
+					mu.Unlock()
+				})),
+				grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+					record(info.FullMethod)
+					return handler(ctx, req)
+				}),
+				grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+					record(info.FullMethod)
+					return handler(srv, ss)
+				}),
+			)
+			var srv storagepb.StorageServiceServer = New(store)
+			if tt.unaryOnly {
+				srv = unaryOnlyServer{New(store)}
+			}
+			storagepb.RegisterStorageServiceServer(grpcServer, srv)
+			go grpcServer.Serve(lis)
+			defer grpcServer.Stop()
+
+			client, err := remote.NewClient(lis.Addr().String(), remote.WithCompression("gzip"))
+			if err != nil {
+				t.Fatalf("failed to connect: %v", err)
+			}
+			defer client.Close()
+
+			ctx := context.Background()
+			for i := range 3 {
+				n, err := client.Write(ctx, storage.LogBatch{
+					{Timestamp: time.Now(), Namespace: "default", Pod: "p", Container: "c", Message: "line"},
+					{Timestamp: time.Now(), Namespace: "default", Pod: "p", Container: "c", Message: "line"},
+				})
+				if err != nil || n != 2 {
+					t.Fatalf("write %d = %d, %v, want 2", i, n, err)
+				}
+			}
+			stats, err := store.Stats(ctx)
+			if err != nil || stats.TotalEntries != 6 {
+				t.Fatalf("stored %+v, %v, want 6 entries", stats, err)
+			}
+
+			// One stream carries every batch, or each is a Write after the
+			// stream was refused
+			mu.Lock()
+			defer mu.Unlock()
+			want := []string{tt.wantMethod}
+			if tt.unaryOnly {
+				want = []string{storagepb.StorageService_WriteStream_FullMethodName, tt.wantMethod, tt.wantMethod, tt.wantMethod}
+			}
+			if !slices.Equal(methods, want) {
+				t.Errorf("methods = %v, want %v", methods, want)
+			}
+			if len(encodings) != len(methods) || slices.ContainsFunc(encodings, func(e string) bool { return e != "gzip" }) {
+				t.Errorf("encodings = %v, want gzip for every call", encodings)
+			}
+		})
+	}
+
+	if _, err := remote.NewClient("localhost:0", remote.WithCompression("lz4")); err == nil {
+		t.Error("NewClient with an unregistered compressor: want an error")
+	}
+}
+
+func TestServer_WriteStreamStop(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:", WriteBufferSize: 1})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	srv := New(store)
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	storagepb.RegisterStorageServiceServer(grpcServer, srv)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := storagepb.NewStorageServiceClient(conn).WriteStream(ctx)
+	if err != nil {
+		t.Fatalf("WriteStream failed: %v", err)
+	}
+	err = stream.Send(&storagepb.WriteRequest{Entries: []*storagepb.LogEntry{{
+		TimestampNanos: time.Now().UnixNano(), Namespace: "default", Pod: "p", Container: "c", Message: "line",
+	}}})
+	if err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("recv failed: %v", err)
+	}
+
+	// The open stream would hold GracefulStop up until the client goes
+	srv.Stop()
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		t.Fatal("GracefulStop still waiting on the write stream")
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("recv after stop = %v, want Unavailable", err)
+	}
 }
 
 func TestServer_GetByID(t *testing.T) {
@@ -196,6 +463,21 @@ func TestServer_GetByID(t *testing.T) {
 	if err == nil {
 		t.Error("expected error for non-existent ID")
 	}
+
+	// Batch get skips missing IDs
+	batchResp, err := client.GetByIDs(ctx, &storagepb.GetByIDsRequest{Ids: []int64{99999, id}})
+	if err != nil {
+		t.Fatalf("get by ids failed: %v", err)
+	}
+	if len(batchResp.Entries) != 1 || batchResp.Entries[0].Id != id {
+		t.Errorf("expected only entry %d, got %v", id, batchResp.Entries)
+	}
+
+	// Oversized batches are rejected
+	_, err = client.GetByIDs(ctx, &storagepb.GetByIDsRequest{Ids: make([]int64, maxGetByIDs+1)})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for oversized batch, got %v", err)
+	}
 }
 
 func TestServer_Stats(t *testing.T) {
@@ -250,4 +532,162 @@ func TestServer_Stats(t *testing.T) {
 	if statsResp.TotalEntries != 3 {
 		t.Errorf("expected 3 total entries, got %d", statsResp.TotalEntries)
 	}
+	if len(statsResp.Namespaces) != 1 || statsResp.Namespaces[0].Entries != 3 || statsResp.Namespaces[0].Bytes != 3 {
+		t.Errorf("unexpected namespace usage %v", statsResp.Namespaces)
+	}
+}
+
+// blockingStore answers queries only once their context is done, like a
+// store stuck in a pathological query until it is interrupted.
+type blockingStore struct {
+	storage.Store
+}
+
+func (s blockingStore) Query(ctx context.Context, q storage.Query) (*storage.QueryResult, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestQueryTimeout(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+	slow := blockingStore{store}
+
+	t.Run("grpc", func(t *testing.T) {
+		srv := New(slow)
+		srv.SetQueryTimeout(50 * time.Millisecond)
+		_, err := srv.Query(context.Background(), &storagepb.QueryRequest{Search: "timeout"})
+		if got := status.Code(err); got != codes.DeadlineExceeded {
+			t.Errorf("code = %v, want DeadlineExceeded (%v)", got, err)
+		}
+	})
+
+	t.Run("http", func(t *testing.T) {
+		s := &HTTPServer{store: slow, queryTimeout: 50 * time.Millisecond}
+		rec := httptest.NewRecorder()
+		s.handleQueryLogs(rec, httptest.NewRequest(http.MethodGet, "/api/logs?search=timeout", nil))
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Errorf("status = %d, want 504", rec.Code)
+		}
+	})
+
+	t.Run("sqlite gives up interrupted queries", func(t *testing.T) {
+		store.Write(context.Background(), storage.LogBatch{{Timestamp: time.Now(), Namespace: "ns", Pod: "p", Container: "c", Message: "timeout"}})
+		store.Flush(context.Background())
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := store.Query(ctx, storage.Query{Search: "timeout"}); err == nil {
+			t.Error("Query with a cancelled context succeeded")
+		}
+	})
+}
+
+func TestHandleListFilterValues(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	store.Write(context.Background(), storage.LogBatch{
+		{Timestamp: base, Namespace: "shop", Pod: "api-0", Container: "app", Message: "a"},
+		{Timestamp: base.Add(time.Second), Namespace: "shop", Pod: "api-1", Container: "app", Message: "b"},
+		{Timestamp: base.Add(2 * time.Second), Namespace: "shop", Pod: "api-1", Container: "proxy", Message: "c"},
+		{Timestamp: base.Add(-time.Hour), Namespace: "infra", Pod: "dns-0", Container: "dns", Message: "d"},
+	})
+	store.Flush(context.Background())
+
+	s := &HTTPServer{store: store}
+	get := func(handler http.HandlerFunc, target string, v any) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d", target, rec.Code)
+		}
+		if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+			t.Fatalf("%s: decode response: %v", target, err)
+		}
+	}
+
+	var pods []string
+	get(s.handleListPods, "/api/filters/pods?namespace=shop", &pods)
+	if !slices.Equal(pods, []string{"api-0", "api-1"}) {
+		t.Errorf("pods in shop = %v, want [api-0 api-1]", pods)
+	}
+	get(s.handleListPods, "/api/filters/pods", &pods)
+	if !slices.Equal(pods, []string{"api-0", "api-1", "dns-0"}) {
+		t.Errorf("pods = %v, want [api-0 api-1 dns-0]", pods)
+	}
+
+	var namespaces []filterValueJSON
+	get(s.handleListNamespaces, "/api/filters/namespaces?stats=true", &namespaces)
+	wantNamespaces := []filterValueJSON{
+		{Value: "infra", Entries: 1, LastSeen: base.Add(-time.Hour).UnixNano()},
+		{Value: "shop", Entries: 3, LastSeen: base.Add(2 * time.Second).UnixNano()},
+	}
+	if !slices.Equal(namespaces, wantNamespaces) {
+		t.Errorf("namespaces = %+v, want %+v", namespaces, wantNamespaces)
+	}
+
+	// Containers of the same name in several pods are summed
+	var containers []filterValueJSON
+	get(s.handleListContainers, "/api/filters/containers?stats=true", &containers)
+	wantContainers := []filterValueJSON{
+		{Value: "app", Entries: 2, LastSeen: base.Add(time.Second).UnixNano()},
+		{Value: "dns", Entries: 1, LastSeen: base.Add(-time.Hour).UnixNano()},
+		{Value: "proxy", Entries: 1, LastSeen: base.Add(2 * time.Second).UnixNano()},
+	}
+	if !slices.Equal(containers, wantContainers) {
+		t.Errorf("containers = %+v, want %+v", containers, wantContainers)
+	}
+}
+
+func TestHandleListWorkloads(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	api := map[string]string{storage.WorkloadAttribute: "deployment/api"}
+	store.Write(context.Background(), storage.LogBatch{
+		{Timestamp: now, Namespace: "shop", Pod: "api-5d8f-a", Message: "a", Attributes: api},
+		{Timestamp: now, Namespace: "shop", Pod: "api-7c4b-b", Message: "b", Attributes: api},
+		{Timestamp: now, Namespace: "shop", Pod: "db-0", Message: "c", Attributes: map[string]string{storage.WorkloadAttribute: "statefulset/db"}},
+		{Timestamp: now, Namespace: "shop", Pod: "debug", Message: "d"},
+		{Timestamp: now.Add(-48 * time.Hour), Namespace: "infra", Pod: "dns-0", Message: "e", Attributes: map[string]string{storage.WorkloadAttribute: "deployment/dns"}},
+	})
+
+	s := &HTTPServer{store: store}
+
+	rec := httptest.NewRecorder()
+	s.handleListWorkloads(rec, httptest.NewRequest(http.MethodGet, "/api/filters/workloads", nil))
+	var workloads []workloadJSON
+	if err := json.NewDecoder(rec.Body).Decode(&workloads); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	want := []workloadJSON{
+		{Namespace: "shop", Workload: "deployment/api", Entries: 2},
+		{Namespace: "shop", Workload: "statefulset/db", Entries: 1},
+	}
+	if !slices.Equal(workloads, want) {
+		t.Errorf("workloads = %+v, want %+v", workloads, want)
+	}
+
+	// The workload filter spans the workload's pods
+	rec = httptest.NewRecorder()
+	s.handleQueryLogs(rec, httptest.NewRequest(http.MethodGet, "/api/logs?workload=deployment/api&limit=1&count=true", nil))
+	var resp queryResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Entries) != 1 || resp.Total != 2 {
+		t.Errorf("workload=deployment/api returned %d entries of %d, want 1 of 2", len(resp.Entries), resp.Total)
+	}
 }
@@ -6,14 +6,33 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/kubelogs/kubelogs/internal/storage"
 )
 
+const (
+	// ssePollInterval is how often live tails query stores that don't
+	// announce writes.
+	ssePollInterval = 500 * time.Millisecond
+
+	// sseFallbackInterval is how often live tails query stores that do,
+	// for writes by other servers sharing the database.
+	sseFallbackInterval = 5 * time.Second
+
+	// sseMinInterval is the least time between queries of a live tail
+	// woken by writes.
+	sseMinInterval = 250 * time.Millisecond
+)
+
 // handleLogStream streams log entries via Server-Sent Events.
 func (s *HTTPServer) handleLogStream(w http.ResponseWriter, r *http.Request) {
+	filters, err := parseSSEFilters(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Set SSE headers
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -25,31 +44,43 @@ func (s *HTTPServer) handleLogStream(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "SSE not supported", http.StatusInternalServerError)
 		return
 	}
+	keepWriting(w)
 
-	// Parse filter parameters
-	filters := s.parseSSEFilters(r)
+	// since narrows the stream like startTime does
+	if filters.since.After(filters.query.StartTime) {
+		filters.query.StartTime = filters.since
+	}
 
 	// Get initial cursor - start from the most recent entries
 	var lastID int64
 
-	// If client provided lastId (reconnection), skip initial batch and resume from that ID
-	if filters.lastId > 0 {
-		lastID = filters.lastId
+	// If client provided a position, skip initial batch and resume from there
+	if filters.afterID > 0 {
+		lastID = filters.afterID
+	} else if !filters.since.IsZero() {
+		// Start just before the first entry written at or after since
+		firstQuery := filters.query
+		firstQuery.Pagination = storage.Pagination{
+			Limit: 1,
+			Order: storage.OrderAsc,
+		}
+		first, err := s.store.Query(r.Context(), firstQuery)
+		if err != nil {
+			slog.Debug("sse since query error", "error", err)
+			return
+		}
+		// With no match, lastID stays 0 and the since bound does the filtering
+		if len(first.Entries) > 0 {
+			lastID = first.Entries[0].ID - 1
+		}
 	} else {
 		// New connection - fetch and send initial batch
-		initialResult, err := s.store.Query(r.Context(), storage.Query{
-			Namespace:   filters.namespace,
-			Pod:         filters.pod,
-			Container:   filters.container,
-			MinSeverity: filters.minSeverity,
-			Search:      filters.search,
-			StartTime:   filters.startTime,
-			Attributes:  filters.attributes,
-			Pagination: storage.Pagination{
-				Limit: 50,
-				Order: storage.OrderDesc,
-			},
-		})
+		initialQuery := filters.query
+		initialQuery.Pagination = storage.Pagination{
+			Limit: 50,
+			Order: storage.OrderDesc,
+		}
+		initialResult, err := s.store.Query(r.Context(), initialQuery)
 		if err == nil && len(initialResult.Entries) > 0 {
 			// Send initial batch in reverse order (oldest first)
 			for i := len(initialResult.Entries) - 1; i >= 0; i-- {
@@ -57,38 +88,48 @@ func (s *HTTPServer) handleLogStream(w http.ResponseWriter, r *http.Request) {
 				s.sendSSEEvent(w, entry)
 				lastID = entry.ID
 			}
+
+			// Hand the client a cursor for paging back past the initial batch
+			if initialResult.HasMore {
+				oldest := initialResult.Entries[len(initialResult.Entries)-1]
+				s.sendSSECursor(w, cursorFromEntry(oldest, initialQuery))
+			}
 			flusher.Flush()
 		}
 	}
 
-	// Poll for new entries
-	ticker := time.NewTicker(500 * time.Millisecond)
+	q := filters.query
+	q.Pagination = storage.Pagination{
+		Limit: 100,
+		Order: storage.OrderAsc,
+	}
+
+	// Stores that announce writes are queried when written to, and only
+	// rarely otherwise to pick up writes by other servers sharing the
+	// database. Others, such as remote stores, are polled.
+	notifier, _ := s.store.(storage.Notifier)
+	interval := ssePollInterval
+	if notifier != nil {
+		interval = sseFallbackInterval
+	}
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
-		select {
-		case <-r.Context().Done():
-			return
-		case <-ticker.C:
-			q := storage.Query{
-				Namespace:   filters.namespace,
-				Pod:         filters.pod,
-				Container:   filters.container,
-				MinSeverity: filters.minSeverity,
-				Search:      filters.search,
-				StartTime:   filters.startTime,
-				Attributes:  filters.attributes,
-				Pagination: storage.Pagination{
-					Limit:   100,
-					AfterID: lastID,
-					Order:   storage.OrderAsc,
-				},
-			}
+		// Wait before querying so a write in between isn't missed
+		var written <-chan struct{}
+		if notifier != nil {
+			written = notifier.Written()
+		}
 
+		// Drain full pages so catching up from an old position isn't
+		// limited to one page per wakeup
+		for {
+			q.Pagination.AfterID = lastID
 			result, err := s.store.Query(r.Context(), q)
 			if err != nil {
 				slog.Debug("sse query error", "error", err)
-				continue
+				break
 			}
 
 			for _, entry := range result.Entries {
@@ -99,70 +140,83 @@ func (s *HTTPServer) handleLogStream(w http.ResponseWriter, r *http.Request) {
 			if len(result.Entries) > 0 {
 				flusher.Flush()
 			}
+			if !result.HasMore || r.Context().Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.stopping:
+			// Clients reconnect, resuming with afterId
+			return
+		case <-ticker.C:
+			continue
+		case <-written:
+		}
+
+		// Writes come in bursts: wait a little so one query reads them
+		// together, rather than flushing buffering stores on every write
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.stopping:
+			return
+		case <-time.After(sseMinInterval):
 		}
 	}
 }
 
 // sseFilters holds parsed SSE filter parameters.
 type sseFilters struct {
-	namespace   string
-	pod         string
-	container   string
-	minSeverity storage.Severity
-	search      string
-	startTime   time.Time
-	attributes  map[string]string
-	lastId      int64 // Resume from this ID (skip initial batch if set)
+	query   storage.Query // Filters, as /api/logs parses them
+	afterID int64         // Resume after this ID (skip initial batch if set)
+	since   time.Time     // Start from entries at or after this time (skip initial batch if set)
 }
 
-// parseSSEFilters extracts filter parameters from the request.
-func (s *HTTPServer) parseSSEFilters(r *http.Request) sseFilters {
+// parseSSEFilters extracts filter parameters from the request, failing
+// on invalid filters.
+func parseSSEFilters(r *http.Request) (sseFilters, error) {
 	params := r.URL.Query()
-	filters := sseFilters{
-		attributes: make(map[string]string),
-	}
-
-	filters.namespace = params.Get("namespace")
-	filters.pod = params.Get("pod")
-	filters.container = params.Get("container")
-	filters.search = params.Get("search")
-
-	if v := params.Get("minSeverity"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n >= 0 && n <= 6 {
-			filters.minSeverity = storage.Severity(n)
-		}
+	var filters sseFilters
+	if err := storage.ParseFilters(params, &filters.query); err != nil {
+		return filters, err
 	}
 
-	if v := params.Get("startTime"); v != "" {
-		if t, err := time.Parse(time.RFC3339, v); err == nil {
-			filters.startTime = t
-		}
+	// Parse afterId to resume from a known position (skip initial batch if set).
+	// lastId is the older name used for reconnection.
+	v := params.Get("afterId")
+	if v == "" {
+		v = params.Get("lastId")
 	}
-
-	// Parse attribute filters (attr.key=value format)
-	for key, values := range params {
-		if strings.HasPrefix(key, "attr.") && len(values) > 0 {
-			attrKey := strings.TrimPrefix(key, "attr.")
-			filters.attributes[attrKey] = values[0]
+	if v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			filters.afterID = n
 		}
 	}
 
-	// Parse lastId for reconnection (skip initial batch if set)
-	if v := params.Get("lastId"); v != "" {
-		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
-			filters.lastId = n
+	if v := params.Get("since"); v != "" {
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			filters.since = t
 		}
 	}
 
-	return filters
+	return filters, nil
 }
 
 // sendSSEEvent sends a single log entry as an SSE event.
 func (s *HTTPServer) sendSSEEvent(w http.ResponseWriter, entry storage.LogEntry) {
-	data, err := json.Marshal(toJSON(entry))
+	data, err := json.Marshal(s.toJSON(entry))
 	if err != nil {
 		slog.Debug("sse marshal error", "error", err)
 		return
 	}
 	fmt.Fprintf(w, "data: %s\n\n", data)
 }
+
+// sendSSECursor sends a "cursor" event carrying a token for /api/logs
+// (order=desc) that continues before the oldest entry sent so far.
+func (s *HTTPServer) sendSSECursor(w http.ResponseWriter, c cursor) {
+	fmt.Fprintf(w, "event: cursor\ndata: {\"cursor\":%q}\n\n", c.encode())
+}
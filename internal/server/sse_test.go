@@ -0,0 +1,308 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+// streamMessages runs the SSE handler briefly and returns the streamed entry messages.
+func streamMessages(t *testing.T, s *HTTPServer, params url.Values) []string {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 800*time.Millisecond)
+	defer cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/logs/stream?"+params.Encode(), nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	s.handleLogStream(rec, req)
+
+	var messages []string
+	scanner := bufio.NewScanner(rec.Body)
+	event := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: ") && event == "":
+			var e logEntryJSON
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+				t.Fatalf("decode event: %v", err)
+			}
+			messages = append(messages, e.Message)
+		case line == "":
+			event = ""
+		}
+	}
+	return messages
+}
+
+func TestHandleLogStream_StartPosition(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour)
+	var batch storage.LogBatch
+	for i := 0; i < 120; i++ {
+		batch = append(batch, storage.LogEntry{
+			Timestamp: base.Add(time.Duration(i) * time.Second),
+			Namespace: "ns",
+			Pod:       "pod",
+			Container: "c",
+			Message:   fmt.Sprintf("msg %d", i),
+		})
+	}
+	store.Write(ctx, batch)
+	store.Flush(ctx)
+
+	all, err := store.Query(ctx, storage.Query{Pagination: storage.Pagination{Limit: 1000, Order: storage.OrderAsc}})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	s := &HTTPServer{store: store}
+
+	tests := []struct {
+		name      string
+		params    url.Values
+		wantFirst string
+		wantCount int
+	}{
+		{"latest batch by default", url.Values{}, "msg 70", 50},
+		{"after id", url.Values{"afterId": {fmt.Sprint(all.Entries[9].ID)}}, "msg 10", 110},
+		{"legacy last id", url.Values{"lastId": {fmt.Sprint(all.Entries[109].ID)}}, "msg 110", 10},
+		{"since", url.Values{"since": {base.Add(100 * time.Second).Format(time.RFC3339Nano)}}, "msg 100", 20},
+		{"since in the future", url.Values{"since": {time.Now().Add(time.Hour).Format(time.RFC3339)}}, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := streamMessages(t, s, tt.params)
+			if len(messages) != tt.wantCount {
+				t.Fatalf("got %d entries, want %d", len(messages), tt.wantCount)
+			}
+			if tt.wantCount > 0 && messages[0] != tt.wantFirst {
+				t.Errorf("first entry = %q, want %q", messages[0], tt.wantFirst)
+			}
+		})
+	}
+}
+
+func TestHandleLogStream_AttrExprs(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour)
+	var batch storage.LogBatch
+	for i := 0; i < 120; i++ {
+		region := []string{"us-east", "us-west", "eu"}[i%3]
+		batch = append(batch, storage.LogEntry{
+			Timestamp:  base.Add(time.Duration(i) * time.Second),
+			Namespace:  "ns",
+			Pod:        "pod",
+			Container:  "c",
+			Message:    region,
+			Attributes: map[string]string{"region": region},
+		})
+	}
+	store.Write(ctx, batch)
+	store.Flush(ctx)
+
+	s := &HTTPServer{store: store}
+	filter := url.Values{"attr.region": {"us-*"}}
+
+	// The stream filters as /api/logs does, and its cursor carries on
+	// there with the same filters
+	sctx, cancel := context.WithTimeout(ctx, 300*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	s.handleLogStream(rec, httptest.NewRequest(http.MethodGet, "/api/logs/stream?"+filter.Encode(), nil).WithContext(sctx))
+	var streamed int
+	var token string
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		var event struct {
+			Message string `json:"message"`
+			Cursor  string `json:"cursor"`
+		}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("decode event: %v", err)
+		}
+		if event.Cursor != "" {
+			token = event.Cursor
+			continue
+		}
+		streamed++
+		if !strings.HasPrefix(event.Message, "us-") {
+			t.Errorf("streamed entry from %q", event.Message)
+		}
+	}
+	if streamed != 50 || token == "" {
+		t.Fatalf("streamed %d entries and cursor %q, want 50 and a cursor", streamed, token)
+	}
+
+	params := url.Values{"attr.region": {"us-*"}, "cursor": {token}}
+	rec = httptest.NewRecorder()
+	s.handleQueryLogs(rec, httptest.NewRequest(http.MethodGet, "/api/logs?"+params.Encode(), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("query with stream cursor = %d: %s", rec.Code, rec.Body)
+	}
+	var resp queryResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Entries) != 30 {
+		t.Errorf("query with stream cursor returned %d entries, want the remaining 30", len(resp.Entries))
+	}
+
+	// Invalid filters are rejected rather than ignored
+	invalid := url.Values{"attr.": {"x"}}.Encode()
+	rec = httptest.NewRecorder()
+	s.handleLogStream(rec, httptest.NewRequest(http.MethodGet, "/api/logs/stream?"+invalid, nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("stream with invalid filter = %d, want 400", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	s.handleQueryLogs(rec, httptest.NewRequest(http.MethodGet, "/api/logs?"+invalid, nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("query with invalid filter = %d, want 400", rec.Code)
+	}
+}
+
+func TestHTTPServer_Shutdown(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	// An entry for the stream to start with, so its headers are sent
+	store.Write(context.Background(), storage.LogBatch{{Timestamp: time.Now(), Namespace: "ns", Message: "hello"}})
+	store.Flush(context.Background())
+
+	cfg := DefaultConfig()
+	cfg.HTTPWriteTimeout = 200 * time.Millisecond
+	s, err := NewHTTPServer(store, store.DB(), cfg)
+	if err != nil {
+		t.Fatalf("NewHTTPServer: %v", err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(lis) }()
+
+	resp, err := http.Get("http://" + lis.Addr().String() + "/api/logs/stream")
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// The stream outlives the write timeout
+	time.Sleep(400 * time.Millisecond)
+	read := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(io.Discard, resp.Body)
+		read <- err
+	}()
+	select {
+	case err := <-read:
+		t.Fatalf("stream ended before shutdown: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Shutdown ends it and returns without waiting for the timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown: %v", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("Shutdown took %v", d)
+	}
+	if err := <-read; err != nil {
+		t.Errorf("stream ended with %v, want EOF", err)
+	}
+	if err := <-served; err != http.ErrServerClosed {
+		t.Errorf("Serve returned %v, want ErrServerClosed", err)
+	}
+}
+
+// countingStore counts queries, keeping the store's optional interfaces.
+type countingStore struct {
+	*sqlite.Store
+	queries atomic.Int64
+}
+
+func (s *countingStore) Query(ctx context.Context, q storage.Query) (*storage.QueryResult, error) {
+	s.queries.Add(1)
+	return s.Store.Query(ctx, q)
+}
+
+func TestHandleLogStream_Notify(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	counted := &countingStore{Store: store}
+	s := &HTTPServer{store: counted}
+
+	ts := httptest.NewServer(http.HandlerFunc(s.handleLogStream))
+	defer ts.Close()
+
+	// Headers are sent with the first entry, so write it while connecting
+	written := make(chan time.Time, 1)
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		written <- time.Now()
+		store.Write(context.Background(), storage.LogBatch{{Timestamp: time.Now(), Namespace: "ns", Message: "hello"}})
+	}()
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || !strings.Contains(line, "hello") {
+		t.Fatalf("first line = %q, %v, want the entry", line, err)
+	}
+	// Well before the fallback query
+	if d := time.Since(<-written); d > 2*time.Second {
+		t.Errorf("entry arrived %v after it was written", d)
+	}
+
+	// An idle stream doesn't query
+	n := counted.queries.Load()
+	time.Sleep(time.Second)
+	if got := counted.queries.Load(); got != n {
+		t.Errorf("%d queries while idle, want none", got-n)
+	}
+}
@@ -0,0 +1,348 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+const (
+	defaultTopWindow = time.Hour
+	defaultTopLimit  = 10
+	maxTopLimit      = 1000
+
+	defaultForecastDays       = 30
+	maxForecastDays           = 365
+	defaultForecastRateWindow = 24 * time.Hour
+)
+
+// rollupDimensions maps the by parameter to rollup dimensions.
+var rollupDimensions = map[string]storage.RollupDimension{
+	"namespace": storage.RollupByNamespace,
+	"pod":       storage.RollupByPod,
+	"container": storage.RollupByContainer,
+}
+
+// topSourceJSON is one source in a top-K response.
+type topSourceJSON struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod,omitempty"`
+	Container string `json:"container,omitempty"`
+	Lines     int64  `json:"lines"`
+	Bytes     int64  `json:"bytes"`
+}
+
+// topResponse is the JSON response for top sources.
+type topResponse struct {
+	By      string          `json:"by"`
+	Start   int64           `json:"start"` // Unix nanoseconds
+	End     int64           `json:"end"`   // Unix nanoseconds
+	Sources []topSourceJSON `json:"sources"`
+}
+
+// handleTopSources returns the highest-volume namespaces, pods or
+// containers over a recent window, computed from rollups.
+// Parameters: by (namespace, pod or container; default pod), window
+// (duration, default 1h), sort (bytes or lines), limit and namespace.
+// Instead of window, day (YYYY-MM-DD or "today") selects a calendar day
+// in tz (an IANA time zone, default UTC).
+func (s *HTTPServer) handleTopSources(w http.ResponseWriter, r *http.Request) {
+	reader, ok := s.store.(storage.RollupReader)
+	if !ok {
+		http.Error(w, "Not supported", http.StatusNotImplemented)
+		return
+	}
+
+	params := r.URL.Query()
+
+	by := params.Get("by")
+	if by == "" {
+		by = "pod"
+	}
+	dim, ok := rollupDimensions[by]
+	if !ok {
+		http.Error(w, fmt.Sprintf("invalid by %q: want namespace, pod or container", by), http.StatusBadRequest)
+		return
+	}
+
+	window := defaultTopWindow
+	if v := params.Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, fmt.Sprintf("invalid window %q", v), http.StatusBadRequest)
+			return
+		}
+		window = d
+	}
+
+	limit := defaultTopLimit
+	if v := params.Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= maxTopLimit {
+			limit = n
+		}
+	}
+
+	end := time.Now()
+	startTime, endTime := end.Add(-window), time.Time{}
+	if v := params.Get("day"); v != "" {
+		loc, err := parseTimezone(params.Get("tz"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if loc == nil {
+			loc = time.UTC
+		}
+		startTime = localMidnight(end, loc)
+		if v != "today" {
+			startTime, err = time.ParseInLocation(time.DateOnly, v, loc)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid day %q: want YYYY-MM-DD or today", v), http.StatusBadRequest)
+				return
+			}
+		}
+		// Rollup buckets divide every UTC offset in use, so local days
+		// start and end on bucket boundaries
+		endTime = startTime.AddDate(0, 0, 1)
+		end = endTime
+	}
+
+	q := storage.RollupQuery{
+		StartTime:    startTime,
+		EndTime:      endTime,
+		By:           dim,
+		Namespace:    params.Get("namespace"),
+		OrderByLines: params.Get("sort") == "lines",
+		Limit:        limit,
+	}
+
+	rollups, err := reader.Rollups(r.Context(), q)
+	if err != nil {
+		slog.Error("rollups error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := topResponse{
+		By:      by,
+		Start:   q.StartTime.UnixNano(),
+		End:     end.UnixNano(),
+		Sources: make([]topSourceJSON, 0, len(rollups)),
+	}
+	for _, ru := range rollups {
+		resp.Sources = append(resp.Sources, topSourceJSON{
+			Namespace: ru.Namespace,
+			Pod:       ru.Pod,
+			Container: ru.Container,
+			Lines:     ru.Lines,
+			Bytes:     ru.Bytes,
+		})
+	}
+	writeJSON(w, resp)
+}
+
+// forecastPointJSON is the projected size at one point in time.
+type forecastPointJSON struct {
+	Day          int   `json:"day"`
+	Time         int64 `json:"time"` // Unix nanoseconds
+	MessageBytes int64 `json:"messageBytes"`
+	DiskBytes    int64 `json:"diskBytes"`
+}
+
+// forecastResponse is the JSON response for a size forecast.
+// Message bytes come from rollups; disk bytes scale them by the current
+// ratio of database size to message bytes and are 0 when that is unknown.
+type forecastResponse struct {
+	RetentionDays           int     `json:"retentionDays"` // Used for the forecast (0 = unlimited)
+	ConfiguredRetentionDays int     `json:"configuredRetentionDays"`
+	RateWindow              string  `json:"rateWindow"`
+	IngestLinesPerDay       int64   `json:"ingestLinesPerDay"`
+	IngestBytesPerDay       int64   `json:"ingestBytesPerDay"`
+	DiskBytesPerByte        float64 `json:"diskBytesPerByte"`
+
+	CurrentMessageBytes int64 `json:"currentMessageBytes"`
+	CurrentDiskBytes    int64 `json:"currentDiskBytes"`
+
+	// Reclaimable is what applying RetentionDays now would free.
+	ReclaimableMessageBytes int64 `json:"reclaimableMessageBytes"`
+	ReclaimableDiskBytes    int64 `json:"reclaimableDiskBytes"`
+
+	// SteadyState is the size once retention balances ingest (0 = unbounded).
+	SteadyStateMessageBytes int64 `json:"steadyStateMessageBytes"`
+	SteadyStateDiskBytes    int64 `json:"steadyStateDiskBytes"`
+
+	// FullAt is the first forecast point whose disk size exceeds the
+	// capacity parameter, if given and reached.
+	CapacityBytes int64 `json:"capacityBytes,omitempty"`
+	FullAt        int64 `json:"fullAt,omitempty"` // Unix nanoseconds
+
+	Points []forecastPointJSON `json:"points"`
+}
+
+// handleForecast projects database size over the coming days at the
+// current ingest rate, applying a retention policy.
+// Parameters: retentionDays (default: configured retention), days (horizon,
+// default 30), rateWindow (duration the ingest rate is measured over,
+// default 24h) and capacity (bytes, e.g. the volume size).
+func (s *HTTPServer) handleForecast(w http.ResponseWriter, r *http.Request) {
+	reader, ok := s.store.(storage.RollupReader)
+	if !ok {
+		http.Error(w, "Not supported", http.StatusNotImplemented)
+		return
+	}
+
+	params := r.URL.Query()
+
+	retentionDays := s.retentionDays
+	if v := params.Get("retentionDays"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, fmt.Sprintf("invalid retentionDays %q", v), http.StatusBadRequest)
+			return
+		}
+		retentionDays = n
+	}
+
+	days := defaultForecastDays
+	if v := params.Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxForecastDays {
+			http.Error(w, fmt.Sprintf("invalid days %q: want 1-%d", v, maxForecastDays), http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+
+	rateWindow := defaultForecastRateWindow
+	if v := params.Get("rateWindow"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, fmt.Sprintf("invalid rateWindow %q", v), http.StatusBadRequest)
+			return
+		}
+		rateWindow = d
+	}
+
+	var capacity int64
+	if v := params.Get("capacity"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			http.Error(w, fmt.Sprintf("invalid capacity %q", v), http.StatusBadRequest)
+			return
+		}
+		capacity = n
+	}
+
+	resp, err := s.forecast(r.Context(), reader, time.Now(), retentionDays, days, rateWindow)
+	if err != nil {
+		slog.Error("forecast error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if capacity > 0 {
+		resp.CapacityBytes = capacity
+		for _, p := range resp.Points {
+			if p.DiskBytes > capacity {
+				resp.FullAt = p.Time
+				break
+			}
+		}
+	}
+	writeJSON(w, resp)
+}
+
+// forecast computes a size projection starting at now. Data already
+// stored ages out of the retention window day by day, while new data
+// arrives at the rate measured over rateWindow.
+func (s *HTTPServer) forecast(ctx context.Context, reader storage.RollupReader, now time.Time, retentionDays, days int, rateWindow time.Duration) (*forecastResponse, error) {
+	stats, err := s.store.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, current, err := sumRollups(ctx, reader, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	lines, recent, err := sumRollups(ctx, reader, now.Add(-rateWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	perDay := 24 * time.Hour.Seconds() / rateWindow.Seconds()
+	resp := &forecastResponse{
+		RetentionDays:           retentionDays,
+		ConfiguredRetentionDays: s.retentionDays,
+		RateWindow:              rateWindow.String(),
+		IngestLinesPerDay:       int64(float64(lines) * perDay),
+		IngestBytesPerDay:       int64(float64(recent) * perDay),
+		CurrentMessageBytes:     current,
+		CurrentDiskBytes:        stats.DiskSizeBytes,
+		Points:                  make([]forecastPointJSON, 0, days+1),
+	}
+	if stats.DiskSizeBytes > 0 && current > 0 {
+		resp.DiskBytesPerByte = float64(stats.DiskSizeBytes) / float64(current)
+	}
+	toDisk := func(b int64) int64 {
+		return int64(float64(b) * resp.DiskBytesPerByte)
+	}
+
+	retention := time.Duration(retentionDays) * 24 * time.Hour
+	if retentionDays > 0 {
+		_, kept, err := sumRollups(ctx, reader, now.Add(-retention))
+		if err != nil {
+			return nil, err
+		}
+		resp.ReclaimableMessageBytes = current - kept
+		resp.ReclaimableDiskBytes = toDisk(resp.ReclaimableMessageBytes)
+		resp.SteadyStateMessageBytes = resp.IngestBytesPerDay * int64(retentionDays)
+		resp.SteadyStateDiskBytes = toDisk(resp.SteadyStateMessageBytes)
+	}
+
+	for day := 0; day <= days; day++ {
+		t := now.Add(time.Duration(day) * 24 * time.Hour)
+
+		var size int64
+		if retentionDays == 0 {
+			size = current + resp.IngestBytesPerDay*int64(day)
+		} else {
+			// Stored data still inside the window, plus new data
+			if cutoff := t.Add(-retention); cutoff.Before(now) {
+				_, kept, err := sumRollups(ctx, reader, cutoff)
+				if err != nil {
+					return nil, err
+				}
+				size = kept
+			}
+			size += resp.IngestBytesPerDay * int64(min(day, retentionDays))
+		}
+
+		resp.Points = append(resp.Points, forecastPointJSON{
+			Day:          day,
+			Time:         t.UnixNano(),
+			MessageBytes: size,
+			DiskBytes:    toDisk(size),
+		})
+	}
+
+	return resp, nil
+}
+
+// sumRollups totals lines and bytes of all sources since start.
+func sumRollups(ctx context.Context, reader storage.RollupReader, start time.Time) (lines, bytes int64, err error) {
+	rollups, err := reader.Rollups(ctx, storage.RollupQuery{StartTime: start, By: storage.RollupByNamespace})
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, ru := range rollups {
+		lines += ru.Lines
+		bytes += ru.Bytes
+	}
+	return lines, bytes, nil
+}
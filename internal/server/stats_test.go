@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestHandleTopSources(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	var batch storage.LogBatch
+	for i := 0; i < 50; i++ {
+		batch = append(batch, storage.LogEntry{
+			Timestamp: now.Add(-time.Duration(i) * time.Second),
+			Namespace: "shop", Pod: "api-0", Container: "app",
+			Message: strings.Repeat("x", 100),
+		})
+	}
+	batch = append(batch,
+		storage.LogEntry{Timestamp: now, Namespace: "infra", Pod: "dns-0", Container: "dns", Message: "ok"},
+		storage.LogEntry{Timestamp: now.Add(-3 * time.Hour), Namespace: "batch", Pod: "job-0", Container: "main", Message: strings.Repeat("y", 10000)},
+		// 08:00 on June 2nd in Tokyo
+		storage.LogEntry{Timestamp: time.Date(2024, 6, 1, 23, 0, 0, 0, time.UTC), Namespace: "batch", Pod: "old-0", Container: "main", Message: "z"},
+	)
+	store.Write(context.Background(), batch)
+	store.Flush(context.Background())
+
+	s := &HTTPServer{store: store}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantPods   []string
+	}{
+		{"default window", "", http.StatusOK, []string{"api-0", "dns-0"}},
+		{"wide window", "?window=24h", http.StatusOK, []string{"job-0", "api-0", "dns-0"}},
+		{"limit", "?limit=1&sort=lines", http.StatusOK, []string{"api-0"}},
+		{"namespace filter", "?namespace=infra", http.StatusOK, []string{"dns-0"}},
+		{"bad by", "?by=node", http.StatusBadRequest, nil},
+		{"bad window", "?window=soon", http.StatusBadRequest, nil},
+		{"today", "?day=today&tz=UTC&sort=lines&limit=1", http.StatusOK, []string{"api-0"}},
+		{"local day", "?day=2024-06-02&tz=Asia/Tokyo", http.StatusOK, []string{"old-0"}},
+		{"utc day", "?day=2024-06-01", http.StatusOK, []string{"old-0"}},
+		{"previous local day", "?day=2024-06-01&tz=Asia/Tokyo", http.StatusOK, nil},
+		{"bad day", "?day=yesterday", http.StatusBadRequest, nil},
+		{"bad tz", "?day=today&tz=Nowhere", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleTopSources(rec, httptest.NewRequest(http.MethodGet, "/api/stats/top"+tt.query, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp topResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			var pods []string
+			for _, src := range resp.Sources {
+				pods = append(pods, src.Pod)
+			}
+			if strings.Join(pods, ",") != strings.Join(tt.wantPods, ",") {
+				t.Errorf("pods = %v, want %v", pods, tt.wantPods)
+			}
+		})
+	}
+}
+
+func TestHandleForecast(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	// 1000 bytes over the last day, 100 bytes older than that
+	now := time.Now()
+	batch := storage.LogBatch{
+		{Timestamp: now.Add(-36 * time.Hour), Namespace: "ns", Pod: "p", Container: "c", Message: strings.Repeat("o", 100)},
+	}
+	for i := 1; i <= 10; i++ {
+		batch = append(batch, storage.LogEntry{
+			Timestamp: now.Add(-time.Duration(i) * time.Hour),
+			Namespace: "ns", Pod: "p", Container: "c",
+			Message: strings.Repeat("n", 100),
+		})
+	}
+	store.Write(context.Background(), batch)
+	store.Flush(context.Background())
+
+	s := &HTTPServer{store: store, retentionDays: 1}
+
+	get := func(t *testing.T, query string) forecastResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		s.handleForecast(rec, httptest.NewRequest(http.MethodGet, "/api/stats/forecast"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp forecastResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("configured retention", func(t *testing.T) {
+		resp := get(t, "?days=3")
+		if resp.RetentionDays != 1 || resp.IngestBytesPerDay != 1000 || resp.CurrentMessageBytes != 1100 {
+			t.Fatalf("unexpected forecast %+v", resp)
+		}
+		if resp.ReclaimableMessageBytes != 100 || resp.SteadyStateMessageBytes != 1000 {
+			t.Errorf("reclaimable/steady = %d/%d, want 100/1000", resp.ReclaimableMessageBytes, resp.SteadyStateMessageBytes)
+		}
+		var sizes []int64
+		for _, p := range resp.Points {
+			sizes = append(sizes, p.MessageBytes)
+		}
+		if fmt.Sprint(sizes) != "[1000 1000 1000 1000]" {
+			t.Errorf("sizes = %v", sizes)
+		}
+	})
+
+	t.Run("unlimited retention", func(t *testing.T) {
+		resp := get(t, "?retentionDays=0&days=2")
+		var sizes []int64
+		for _, p := range resp.Points {
+			sizes = append(sizes, p.MessageBytes)
+		}
+		if fmt.Sprint(sizes) != "[1100 2100 3100]" {
+			t.Errorf("sizes = %v", sizes)
+		}
+		if resp.SteadyStateMessageBytes != 0 {
+			t.Errorf("steady state = %d, want 0", resp.SteadyStateMessageBytes)
+		}
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		for _, q := range []string{"?days=0", "?retentionDays=-1", "?rateWindow=x", "?capacity=big"} {
+			rec := httptest.NewRecorder()
+			s.handleForecast(rec, httptest.NewRequest(http.MethodGet, "/api/stats/forecast"+q, nil))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: status = %d, want 400", q, rec.Code)
+			}
+		}
+	})
+}
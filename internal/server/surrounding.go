@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+const (
+	// defaultContextLines is the number of entries returned on each side
+	// of the entry when the request doesn't say.
+	defaultContextLines = 50
+
+	// maxContextLines caps the entries returned on each side.
+	maxContextLines = 500
+)
+
+// contextResponse is the JSON response for an entry's context.
+type contextResponse struct {
+	Before        []logEntryJSON `json:"before"` // Oldest first
+	Entry         logEntryJSON   `json:"entry"`
+	After         []logEntryJSON `json:"after"` // Oldest first
+	HasMoreBefore bool           `json:"hasMoreBefore"`
+	HasMoreAfter  bool           `json:"hasMoreAfter"`
+}
+
+// handleGetContext returns the entries of the same container written
+// before and after an entry, by timestamp, so a search hit can be read
+// in context. before and after default to 50, up to 500.
+func (s *HTTPServer) handleGetContext(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	before, err := contextLines(r, "before")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	after, err := contextLines(r, "after")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := withQueryTimeout(r.Context(), s.queryTimeout)
+	defer cancel()
+
+	start := time.Now()
+	sur, err := storage.GetSurrounding(ctx, s.store, id, before, after)
+	s.queryDuration.Observe(since(start))
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			http.Error(w, "Entry not found", http.StatusNotFound)
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			s.queryTimeouts.Inc()
+			http.Error(w, "Query timed out after "+s.queryTimeout.String(), http.StatusGatewayTimeout)
+		default:
+			slog.Error("context query error", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	resp := contextResponse{
+		Before:        make([]logEntryJSON, 0, len(sur.Before)),
+		Entry:         s.toJSON(sur.Entry),
+		After:         make([]logEntryJSON, 0, len(sur.After)),
+		HasMoreBefore: sur.HasMoreBefore,
+		HasMoreAfter:  sur.HasMoreAfter,
+	}
+	for _, e := range sur.Before {
+		resp.Before = append(resp.Before, s.toJSON(e))
+	}
+	for _, e := range sur.After {
+		resp.After = append(resp.After, s.toJSON(e))
+	}
+	writeJSON(w, resp)
+}
+
+// contextLines parses the number of context entries in parameter name.
+func contextLines(r *http.Request, name string) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return defaultContextLines, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 || n > maxContextLines {
+		return 0, fmt.Errorf("invalid %s %q: must be 0 to %d", name, v, maxContextLines)
+	}
+	return n, nil
+}
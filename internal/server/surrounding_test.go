@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestGetContext(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	// Two containers interleaved, written out of timestamp order, with
+	// two entries sharing a timestamp
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour)
+	var batch storage.LogBatch
+	for _, i := range []int{5, 0, 3, 1, 4, 2, 6, 7} {
+		ts := base.Add(time.Duration(i) * time.Second)
+		if i == 4 {
+			ts = base.Add(3 * time.Second)
+		}
+		batch = append(batch,
+			storage.LogEntry{Timestamp: ts, Namespace: "ns", Pod: "api", Container: "app", Message: fmt.Sprint(i)},
+			storage.LogEntry{Timestamp: ts, Namespace: "ns", Pod: "api", Container: "sidecar", Message: fmt.Sprint("sidecar ", i)},
+		)
+	}
+	store.Write(ctx, batch)
+	store.Flush(ctx)
+
+	all, err := store.Query(ctx, storage.Query{Container: "app", Pagination: storage.Pagination{Limit: 100}})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	var target int64
+	for _, e := range all.Entries {
+		if e.Message == "3" {
+			target = e.ID
+		}
+	}
+
+	s := &HTTPServer{store: store}
+	get := func(id int64, query string) (*httptest.ResponseRecorder, contextResponse) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/logs/%d/context?%s", id, query), nil)
+		req.SetPathValue("id", fmt.Sprint(id))
+		rec := httptest.NewRecorder()
+		s.handleGetContext(rec, req)
+		var resp contextResponse
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+		}
+		return rec, resp
+	}
+	messages := func(entries []logEntryJSON) string {
+		var s string
+		for _, e := range entries {
+			s += e.Message
+		}
+		return s
+	}
+
+	// "3" and "4" share a timestamp; "3" was written first, so it comes first
+	rec, resp := get(target, "before=2&after=3")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	if resp.Entry.Message != "3" || messages(resp.Before) != "12" || messages(resp.After) != "456" {
+		t.Errorf("context = %s [%s] %s", messages(resp.Before), resp.Entry.Message, messages(resp.After))
+	}
+	if !resp.HasMoreBefore || !resp.HasMoreAfter {
+		t.Errorf("hasMoreBefore = %v, hasMoreAfter = %v, want both", resp.HasMoreBefore, resp.HasMoreAfter)
+	}
+
+	_, resp = get(target, "")
+	if messages(resp.Before) != "012" || messages(resp.After) != "4567" || resp.HasMoreBefore || resp.HasMoreAfter {
+		t.Errorf("default context = %s [%s] %s", messages(resp.Before), resp.Entry.Message, messages(resp.After))
+	}
+
+	_, resp = get(target, "before=0&after=0")
+	if len(resp.Before) != 0 || len(resp.After) != 0 {
+		t.Errorf("empty context = %s [%s] %s", messages(resp.Before), resp.Entry.Message, messages(resp.After))
+	}
+
+	if rec, _ := get(target, "before=501"); rec.Code != http.StatusBadRequest {
+		t.Errorf("before=501 status = %d, want 400", rec.Code)
+	}
+	if rec, _ := get(target, "after=-1"); rec.Code != http.StatusBadRequest {
+		t.Errorf("after=-1 status = %d, want 400", rec.Code)
+	}
+	if rec, _ := get(99999, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown entry status = %d, want 404", rec.Code)
+	}
+}
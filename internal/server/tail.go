@@ -0,0 +1,101 @@
+package server
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kubelogs/kubelogs/api/storagepb"
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+const (
+	// tailPageSize bounds the entries sent in one TailResponse.
+	tailPageSize = 500
+
+	// tailPollInterval is how often Tail queries stores that don't
+	// announce writes.
+	tailPollInterval = 500 * time.Millisecond
+
+	// tailFallbackInterval is how often Tail queries stores that do, for
+	// writes by other servers sharing the database.
+	tailFallbackInterval = 5 * time.Second
+)
+
+// Tail streams entries matching the query as they are written. Each write
+// the store announces (see storage.Notifier) triggers a query for entries
+// after the last one sent; other stores are polled. Tails end with
+// UNAVAILABLE when the server stops.
+func (s *Server) Tail(req *storagepb.QueryRequest, stream grpc.ServerStreamingServer[storagepb.TailResponse]) error {
+	ctx := stream.Context()
+
+	q, err := fromProtoQuery(req)
+	if err != nil {
+		return err
+	}
+	q.Pagination = storage.Pagination{Limit: tailPageSize, Order: storage.OrderAsc}
+
+	lastID := req.AfterId
+	if lastID <= 0 {
+		// Start after the newest entry, matching or not
+		newest, err := s.store.Query(ctx, storage.Query{Pagination: storage.Pagination{Limit: 1}})
+		if err != nil {
+			return status.Errorf(codes.Internal, "tail failed: %v", err)
+		}
+		if len(newest.Entries) > 0 {
+			lastID = newest.Entries[0].ID
+		}
+	}
+
+	notifier, _ := s.store.(storage.Notifier)
+	interval := tailPollInterval
+	if notifier != nil {
+		interval = tailFallbackInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Wait before querying so a write in between isn't missed
+		var written <-chan struct{}
+		if notifier != nil {
+			written = notifier.Written()
+		}
+
+		for {
+			q.Pagination.AfterID = lastID
+			result, err := s.store.Query(ctx, q)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return status.Errorf(codes.Internal, "tail failed: %v", err)
+			}
+
+			if len(result.Entries) > 0 {
+				pbEntries := make([]*storagepb.LogEntry, len(result.Entries))
+				for i, e := range result.Entries {
+					pbEntries[i] = toProtoEntry(e)
+				}
+				if err := stream.Send(&storagepb.TailResponse{Entries: pbEntries}); err != nil {
+					return err
+				}
+				lastID = result.Entries[len(result.Entries)-1].ID
+			}
+			if !result.HasMore {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.stopping:
+			return errStopping()
+		case <-written:
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/kubelogs/kubelogs/api/storagepb"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestServer_Tail(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:", WriteBufferSize: 1})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	srv := New(store)
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	storagepb.RegisterStorageServiceServer(grpcServer, srv)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+	client := storagepb.NewStorageServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	write := func(namespace, msg string) {
+		t.Helper()
+		_, err := client.Write(ctx, &storagepb.WriteRequest{Entries: []*storagepb.LogEntry{{
+			TimestampNanos: time.Now().UnixNano(),
+			Namespace:      namespace,
+			Pod:            "pod",
+			Container:      "app",
+			Message:        msg,
+		}}})
+		if err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	recv := func(stream grpc.ServerStreamingClient[storagepb.TailResponse]) []string {
+		t.Helper()
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("recv failed: %v", err)
+		}
+		var msgs []string
+		for _, e := range resp.Entries {
+			msgs = append(msgs, e.Message)
+		}
+		return msgs
+	}
+
+	write("default", "before")
+
+	stream, err := client.Tail(ctx, &storagepb.QueryRequest{Namespace: "default"})
+	if err != nil {
+		t.Fatalf("tail failed: %v", err)
+	}
+
+	// Entries written before the call aren't sent; other namespaces are
+	// filtered out. The stream starts once the server has run its first
+	// query, so retry the write until it arrives.
+	got := make(chan []string, 1)
+	go func() { got <- recv(stream) }()
+	var msgs []string
+	for msgs == nil {
+		write("other", "filtered")
+		write("default", "after")
+		select {
+		case msgs = <-got:
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	if msgs[0] != "after" {
+		t.Fatalf("first tailed entries = %q, want after", msgs)
+	}
+	for _, m := range msgs {
+		if m != "after" {
+			t.Errorf("unexpected tailed entry %q", m)
+		}
+	}
+
+	// Resuming from an ID sends what was written since
+	first, err := client.Query(ctx, &storagepb.QueryRequest{Limit: 1, Order: storagepb.Order_ORDER_ASC})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	resumed, err := client.Tail(ctx, &storagepb.QueryRequest{Namespace: "default", AfterId: first.Entries[0].Id})
+	if err != nil {
+		t.Fatalf("tail failed: %v", err)
+	}
+	if msgs := recv(resumed); len(msgs) == 0 || msgs[0] != "after" {
+		t.Errorf("resumed entries = %q, want after...", msgs)
+	}
+}
+
+func TestServer_TailStop(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:", WriteBufferSize: 1})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	srv := New(store)
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	storagepb.RegisterStorageServiceServer(grpcServer, srv)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client := storagepb.NewStorageServiceClient(conn)
+	for _, msg := range []string{"first", "second"} {
+		_, err := client.Write(ctx, &storagepb.WriteRequest{Entries: []*storagepb.LogEntry{{
+			TimestampNanos: time.Now().UnixNano(), Namespace: "default", Pod: "p", Container: "c", Message: msg,
+		}}})
+		if err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	// Receiving the entry after the first means the tail is running
+	stream, err := client.Tail(ctx, &storagepb.QueryRequest{AfterId: 1})
+	if err != nil {
+		t.Fatalf("tail failed: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("recv failed: %v", err)
+	}
+
+	srv.Stop()
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		t.Fatal("GracefulStop still waiting on the tail")
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("recv after stop = %v, want Unavailable", err)
+	}
+}
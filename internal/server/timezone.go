@@ -0,0 +1,26 @@
+package server
+
+import (
+	"fmt"
+	"time"
+)
+
+// parseTimezone loads the IANA time zone named by the tz parameter, such
+// as "Europe/Berlin". An empty name returns nil, meaning UTC-based
+// defaults apply.
+func parseTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return nil, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tz %q", name)
+	}
+	return loc, nil
+}
+
+// localMidnight returns the start of the day containing t in loc.
+func localMidnight(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
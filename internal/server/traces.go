@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+const (
+	defaultTraceLimit = 1000
+	maxTraceLimit     = 10000
+)
+
+// traceSourceJSON is the part of a trace logged by one container.
+type traceSourceJSON struct {
+	Cluster   string         `json:"cluster,omitempty"`
+	Namespace string         `json:"namespace"`
+	Pod       string         `json:"pod"`
+	Container string         `json:"container"`
+	Start     int64          `json:"start"` // Unix nanoseconds of the first entry
+	End       int64          `json:"end"`   // Unix nanoseconds of the last entry
+	Entries   []logEntryJSON `json:"entries"`
+}
+
+// traceResponse is the JSON response for a trace's logs.
+type traceResponse struct {
+	TraceID string            `json:"traceId"`
+	Sources []traceSourceJSON `json:"sources"` // By first entry
+	HasMore bool              `json:"hasMore"`
+}
+
+// handleTraceLogs returns the entries whose trace_id attribute is the
+// trace ID in the path, across all namespaces and pods, oldest first and
+// grouped by container, so a distributed request can be followed through
+// the services it reached. The usual filters, such as startTime and
+// endTime, narrow it; limit defaults to 1000, up to 10000.
+func (s *HTTPServer) handleTraceLogs(w http.ResponseWriter, r *http.Request) {
+	traceID := r.PathValue("traceId")
+	if traceID == "" {
+		http.Error(w, "Invalid trace ID", http.StatusBadRequest)
+		return
+	}
+	filter, err := s.parseQueryParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := defaultTraceLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= maxTraceLimit {
+			limit = n
+		}
+	}
+
+	ctx, cancel := withQueryTimeout(r.Context(), s.queryTimeout)
+	defer cancel()
+
+	start := time.Now()
+	trace, err := storage.GetTrace(ctx, s.store, traceID, filter, limit)
+	s.queryDuration.Observe(since(start))
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			s.queryTimeouts.Inc()
+			http.Error(w, "Query timed out after "+s.queryTimeout.String(), http.StatusGatewayTimeout)
+			return
+		}
+		slog.Error("trace query error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := traceResponse{
+		TraceID: trace.TraceID,
+		Sources: make([]traceSourceJSON, 0, len(trace.Sources)),
+		HasMore: trace.HasMore,
+	}
+	for _, src := range trace.Sources {
+		js := traceSourceJSON{
+			Cluster:   src.Cluster,
+			Namespace: src.Namespace,
+			Pod:       src.Pod,
+			Container: src.Container,
+			Start:     src.Entries[0].Timestamp.UnixNano(),
+			End:       src.Entries[len(src.Entries)-1].Timestamp.UnixNano(),
+			Entries:   make([]logEntryJSON, 0, len(src.Entries)),
+		}
+		for _, e := range src.Entries {
+			js.Entries = append(js.Entries, s.toJSON(e))
+		}
+		resp.Sources = append(resp.Sources, js)
+	}
+	writeJSON(w, resp)
+}
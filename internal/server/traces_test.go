@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func TestTraceLogs(t *testing.T) {
+	store, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	// A request through frontend and payments, interleaved, written out
+	// of order, plus an entry of another trace
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour)
+	entry := func(ns, pod, msg, trace string, offset time.Duration) storage.LogEntry {
+		return storage.LogEntry{
+			Timestamp:  base.Add(offset),
+			Namespace:  ns,
+			Pod:        pod,
+			Container:  "app",
+			Message:    msg,
+			Attributes: map[string]string{storage.TraceAttribute: trace},
+		}
+	}
+	store.Write(ctx, storage.LogBatch{
+		entry("payments", "api", "charge", "abc", 20*time.Millisecond),
+		entry("shop", "frontend", "checkout", "abc", 0),
+		entry("shop", "frontend", "done", "abc", 50*time.Millisecond),
+		entry("payments", "api", "charged", "abc", 30*time.Millisecond),
+		entry("shop", "frontend", "other", "def", 10*time.Millisecond),
+	})
+	store.Flush(ctx)
+
+	s := &HTTPServer{store: store}
+	get := func(target, traceID string) traceResponse {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.SetPathValue("traceId", traceID)
+		rec := httptest.NewRecorder()
+		s.handleTraceLogs(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d: %s", target, rec.Code, rec.Body)
+		}
+		var resp traceResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return resp
+	}
+	messages := func(entries []logEntryJSON) string {
+		var s string
+		for _, e := range entries {
+			s += e.Message + " "
+		}
+		return s
+	}
+
+	resp := get("/api/traces/abc/logs", "abc")
+	if len(resp.Sources) != 2 || resp.HasMore {
+		t.Fatalf("sources = %+v, hasMore = %v, want 2 sources", resp.Sources, resp.HasMore)
+	}
+	if src := resp.Sources[0]; src.Namespace != "shop" || messages(src.Entries) != "checkout done " ||
+		src.Start != base.UnixNano() || src.End != base.Add(50*time.Millisecond).UnixNano() {
+		t.Errorf("first source = %s/%s %q", src.Namespace, src.Pod, messages(src.Entries))
+	}
+	if src := resp.Sources[1]; src.Namespace != "payments" || messages(src.Entries) != "charge charged " {
+		t.Errorf("second source = %s/%s %q", src.Namespace, src.Pod, messages(src.Entries))
+	}
+
+	// The limit keeps the earliest entries
+	resp = get("/api/traces/abc/logs?limit=2", "abc")
+	if len(resp.Sources) != 2 || !resp.HasMore ||
+		messages(resp.Sources[0].Entries) != "checkout " || messages(resp.Sources[1].Entries) != "charge " {
+		t.Errorf("limited = %+v, hasMore = %v", resp.Sources, resp.HasMore)
+	}
+
+	// Filters narrow it
+	resp = get("/api/traces/abc/logs?namespace=payments", "abc")
+	if len(resp.Sources) != 1 || resp.Sources[0].Namespace != "payments" {
+		t.Errorf("filtered = %+v", resp.Sources)
+	}
+
+	resp = get("/api/traces/none/logs", "none")
+	if resp.TraceID != "none" || resp.Sources == nil || len(resp.Sources) != 0 {
+		t.Errorf("unknown trace = %+v", resp)
+	}
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Group-by fields of an Aggregation. Attributes are grouped by with
+// "attr.<key>".
+const (
+	GroupByCluster   = "cluster"
+	GroupByNamespace = "namespace"
+	GroupByPod       = "pod"
+	GroupByContainer = "container"
+	GroupBySeverity  = "severity"
+)
+
+// MaxGroupBy caps the number of group-by fields of an Aggregation.
+const MaxGroupBy = 4
+
+// GroupAggregator is an optional interface for stores that can
+// summarize matching entries per group of field values, so clients get
+// counts and statistics without fetching the entries.
+type GroupAggregator interface {
+	// Aggregate groups the entries matching q's filters by a's fields
+	// and, if a.Interval is set, time bucket. Pagination is ignored.
+	// Groups are sorted by start, then keys.
+	Aggregate(ctx context.Context, q Query, a Aggregation) ([]AggregateGroup, error)
+}
+
+// Aggregation describes how Aggregate groups and summarizes entries.
+type Aggregation struct {
+	// GroupBy lists the fields whose values form a group: GroupByCluster,
+	// GroupByNamespace, GroupByPod, GroupByContainer, GroupBySeverity or
+	// "attr.<key>". Empty puts all entries in one group per bucket.
+	GroupBy []string
+
+	// Interval is the width of time buckets, aligned to the Unix epoch.
+	// Zero doesn't bucket by time.
+	Interval time.Duration
+
+	// Value is an attribute key whose numeric values are summarized per
+	// group. Entries where it is absent or not a number are counted but
+	// not summarized.
+	Value string
+}
+
+// GroupAttr returns the attribute key of group-by field f, and whether
+// f is an attribute.
+func GroupAttr(f string) (string, bool) {
+	return strings.CutPrefix(f, "attr.")
+}
+
+// Validate checks the group-by fields, interval and value attribute.
+func (a Aggregation) Validate() error {
+	if len(a.GroupBy) > MaxGroupBy {
+		return fmt.Errorf("at most %d group-by fields allowed", MaxGroupBy)
+	}
+	for i, f := range a.GroupBy {
+		switch f {
+		case GroupByCluster, GroupByNamespace, GroupByPod, GroupByContainer, GroupBySeverity:
+		default:
+			if key, ok := GroupAttr(f); !ok || key == "" {
+				return fmt.Errorf("invalid group-by field %q", f)
+			}
+		}
+		if slices.Contains(a.GroupBy[:i], f) {
+			return fmt.Errorf("duplicate group-by field %q", f)
+		}
+	}
+	if a.Interval < 0 {
+		return errors.New("interval must not be negative")
+	}
+	return nil
+}
+
+// AggregateGroup summarizes the entries of one group.
+type AggregateGroup struct {
+	// Start is the start of the group's time bucket, or zero without an
+	// interval.
+	Start time.Time
+	// Keys holds the group's values of Aggregation.GroupBy, in order.
+	// Severities are named, and absent attributes are empty.
+	Keys []string
+	// Count is the number of entries in the group.
+	Count int64
+
+	// ValueCount is the number of entries whose value attribute is a
+	// number; Sum, Min and Max are over those values.
+	ValueCount int64
+	Sum        float64
+	Min        float64
+	Max        float64
+}
+
+// Avg returns the mean of the group's values, or 0 if it has none.
+func (g AggregateGroup) Avg() float64 {
+	if g.ValueCount == 0 {
+		return 0
+	}
+	return g.Sum / float64(g.ValueCount)
+}
+
+// MergeGroups combines groups with the same start and keys, e.g. from
+// several stores or shards, and sorts the result by start, then keys.
+func MergeGroups(groups []AggregateGroup) []AggregateGroup {
+	type key struct {
+		start int64
+		keys  string
+	}
+	index := make(map[key]int)
+	merged := make([]AggregateGroup, 0, len(groups))
+	for _, g := range groups {
+		k := key{g.Start.UnixNano(), strings.Join(g.Keys, "\x00")}
+		i, ok := index[k]
+		if !ok {
+			index[k] = len(merged)
+			merged = append(merged, g)
+			continue
+		}
+		m := &merged[i]
+		m.Count += g.Count
+		if g.ValueCount == 0 {
+			continue
+		}
+		if m.ValueCount == 0 {
+			m.Min, m.Max = g.Min, g.Max
+		} else {
+			m.Min, m.Max = min(m.Min, g.Min), max(m.Max, g.Max)
+		}
+		m.ValueCount += g.ValueCount
+		m.Sum += g.Sum
+	}
+	SortGroups(merged)
+	return merged
+}
+
+// SortGroups sorts groups by start, then keys.
+func SortGroups(groups []AggregateGroup) {
+	slices.SortFunc(groups, func(a, b AggregateGroup) int {
+		if c := a.Start.Compare(b.Start); c != 0 {
+			return c
+		}
+		return slices.Compare(a.Keys, b.Keys)
+	})
+}
@@ -0,0 +1,61 @@
+package storage
+
+import "strings"
+
+// Match reports whether attrs satisfies any of the terms of e, or e is
+// empty. Stores that can't push attribute expressions down to their
+// query language filter entries with it.
+func (e AttrExpr) Match(attrs map[string]string) bool {
+	if len(e) == 0 {
+		return true
+	}
+	for _, t := range e {
+		if t.Match(attrs) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether attrs satisfies t.
+func (t AttrTerm) Match(attrs map[string]string) bool {
+	v, ok := attrs[t.Key]
+	switch t.Op {
+	case AttrExists:
+		return ok
+	case AttrNotEqual:
+		return !ok || !globMatch(t.Value, v)
+	default:
+		return ok && globMatch(t.Value, v)
+	}
+}
+
+// IsGlob reports whether the term's value has wildcards.
+func (t AttrTerm) IsGlob() bool {
+	return strings.Contains(t.Value, "*")
+}
+
+// globMatch reports whether s matches pattern, in which * matches any
+// run of characters and everything else matches itself.
+func globMatch(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return s == pattern
+	}
+
+	// The first part anchors at the start, the last at the end, and
+	// the ones between match leftmost in order
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, p := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, p)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(p):]
+	}
+	return len(s) >= len(last) && strings.HasSuffix(s, last)
+}
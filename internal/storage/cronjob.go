@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Attributes collectors set on entries of Job pods, besides the workload.
+const (
+	JobAttribute     = "job"     // Name of the Job, one per run
+	CronJobAttribute = "cronjob" // Name of the CronJob that created the Job
+)
+
+// minCronJobRun bounds the scheduled times CronJobRun accepts, so that a
+// Job with a date or version suffix isn't taken for a CronJob's.
+var minCronJobRun = time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// CronJobRun splits the name of a Job a CronJob created into the
+// CronJob's name and the run's scheduled time. The CronJob controller
+// names Jobs <cronjob>-<scheduled time in minutes since the epoch>; ok
+// is false for names that don't fit, or whose time is before 2015 or
+// more than a day ahead.
+func CronJobRun(job string) (cronjob string, scheduled time.Time, ok bool) {
+	i := strings.LastIndexByte(job, '-')
+	if i <= 0 {
+		return "", time.Time{}, false
+	}
+	minutes, err := strconv.ParseInt(job[i+1:], 10, 64)
+	if err != nil || minutes <= 0 || job[i+1] == '+' {
+		return "", time.Time{}, false
+	}
+	scheduled = time.Unix(minutes*60, 0).UTC()
+	if scheduled.Before(minCronJobRun) || scheduled.After(time.Now().Add(24*time.Hour)) {
+		return "", time.Time{}, false
+	}
+	return job[:i], scheduled, true
+}
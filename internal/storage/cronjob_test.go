@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronJobRun(t *testing.T) {
+	tests := []struct {
+		job     string
+		cronjob string
+		want    time.Time
+		ok      bool
+	}{
+		{"backup-28512180", "backup", time.Date(2024, 3, 18, 3, 0, 0, 0, time.UTC), true},
+		{"nightly-report-28512360", "nightly-report", time.Date(2024, 3, 18, 6, 0, 0, 0, time.UTC), true},
+		{"migrate", "", time.Time{}, false},
+		{"migrate-v2", "", time.Time{}, false},
+		{"migrate-20240301", "", time.Time{}, false}, // 1978
+		{"backup-+28512345", "", time.Time{}, false},
+		{"backup-99999999999", "", time.Time{}, false}, // Far in the future
+		{"-28512345", "", time.Time{}, false},
+		{"backup-", "", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		cronjob, scheduled, ok := CronJobRun(tt.job)
+		if cronjob != tt.cronjob || !scheduled.Equal(tt.want) || ok != tt.ok {
+			t.Errorf("CronJobRun(%q) = %q, %v, %v, want %q, %v, %v", tt.job, cronjob, scheduled, ok, tt.cronjob, tt.want, tt.ok)
+		}
+	}
+}
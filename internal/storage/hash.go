@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// DedupHash identifies an entry by timestamp, source and message: a
+// 64-bit FNV-1a hash with null separators, the same as the SQLite store's.
+// An empty cluster is left out, so entries written before clusters
+// existed keep their hashes.
+func DedupHash(e *LogEntry) int64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(e.Timestamp.UnixNano()))
+	h.Write(buf[:])
+	if e.Cluster != "" {
+		h.Write([]byte(e.Cluster))
+		h.Write([]byte{0})
+	}
+	h.Write([]byte(e.Namespace))
+	h.Write([]byte{0})
+	h.Write([]byte(e.Pod))
+	h.Write([]byte{0})
+	h.Write([]byte(e.Container))
+	h.Write([]byte{0})
+	h.Write([]byte(e.Message))
+	return int64(h.Sum64())
+}
@@ -64,6 +64,10 @@ type LogEntry struct {
 	// Timestamp when the log was produced.
 	Timestamp time.Time
 
+	// Cluster names the Kubernetes cluster the entry came from.
+	// Empty for single-cluster setups.
+	Cluster string
+
 	// Kubernetes context fields - indexed for fast filtering.
 	Namespace string
 	Pod       string
@@ -78,8 +82,29 @@ type LogEntry struct {
 	// Attributes holds arbitrary structured fields.
 	// nil means no attributes.
 	Attributes map[string]string
+
+	// ExpiresAt, if set, is when the entry is deleted regardless of the
+	// server's retention, e.g. for debug dumps kept for a day only.
+	// Stores honoring it implement ExpiryDeleter; reads leave it zero.
+	ExpiresAt time.Time
 }
 
+// WorkloadAttribute is the attribute collectors set to the workload that
+// owns an entry's pod, as kind/name in kubectl's form, e.g.
+// "deployment/api". It stays the same as pods are replaced, so queries on
+// it cover every pod the workload ran.
+const WorkloadAttribute = "workload"
+
+// LifecycleNamespace is the namespace of the entries collectors write
+// about themselves, such as streams opening and closing, so the stored
+// data records its own gaps. Kubernetes namespaces can't contain an
+// underscore, so it never collides with one.
+const LifecycleNamespace = "_kubelogs"
+
+// MaxCount bounds the matches counted for Query.Count, so counting a
+// broad query costs no more than scanning this many entries.
+const MaxCount = 100_000
+
 // LogBatch is a slice of entries for bulk operations.
 type LogBatch []LogEntry
 
@@ -93,6 +118,9 @@ type Query struct {
 	// Full-text search on message body.
 	Search string
 
+	// Cluster filter (exact match).
+	Cluster string
+
 	// Kubernetes field filters (exact match).
 	Namespace string
 	Pod       string
@@ -104,10 +132,49 @@ type Query struct {
 	// Attribute filters (exact match, AND logic).
 	Attributes map[string]string
 
+	// Attribute expressions (AND logic between expressions and with
+	// Attributes, OR logic within one). Empty expressions are ignored.
+	AttrExprs []AttrExpr
+
+	// Sample, between 0 and 1, returns about that fraction of the
+	// matching entries, picked at random, for exploring huge ranges.
+	// 0 or 1 returns every match. Only Query samples; counting and
+	// deleting ignore it.
+	Sample float64
+
+	// Consistency is which acknowledged writes the results include.
+	Consistency Consistency
+
+	// Count asks for QueryResult.TotalEstimate: the matches of the
+	// filters across all pages, counted up to MaxCount. Counting scans
+	// the matches, so it's for a first page rather than every page.
+	Count bool
+
 	// Pagination controls.
 	Pagination Pagination
 }
 
+// Sampled reports whether q asks for a sample of the matches.
+func (q Query) Sampled() bool {
+	return q.Sample > 0 && q.Sample < 1
+}
+
+// HasFilter reports whether q selects fewer than all entries, ignoring
+// pagination.
+func (q Query) HasFilter() bool {
+	if !q.StartTime.IsZero() || !q.EndTime.IsZero() || q.Search != "" || q.Cluster != "" ||
+		q.Namespace != "" || q.Pod != "" || q.Container != "" || q.MinSeverity > SeverityUnknown ||
+		len(q.Attributes) > 0 {
+		return true
+	}
+	for _, expr := range q.AttrExprs {
+		if len(expr) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // Pagination defines how to page through results.
 type Pagination struct {
 	// Limit is the maximum number of entries to return.
@@ -120,8 +187,24 @@ type Pagination struct {
 	// BeforeID returns entries with ID before this value (for reverse pagination).
 	BeforeID int64
 
+	// AfterTimestamp and BeforeTimestamp combine with AfterID and BeforeID
+	// into a (timestamp, id) keyset cursor when OrderBy is OrderByTimestamp.
+	// Zero means the cursor falls back to ID only. A timestamp with a zero ID
+	// includes entries at exactly that timestamp.
+	AfterTimestamp  time.Time
+	BeforeTimestamp time.Time
+
+	// MaxID excludes entries with a higher ID, i.e. those stored after the
+	// entry with ID MaxID. Reads paging through a result set pass the
+	// newest ID at their start to see one consistent cut of it.
+	// Zero means no limit.
+	MaxID int64
+
 	// Order specifies result ordering.
 	Order Order
+
+	// OrderBy specifies the sort key.
+	OrderBy OrderBy
 }
 
 // Order defines sort order for query results.
@@ -134,6 +217,55 @@ const (
 	OrderAsc
 )
 
+// Consistency defines which acknowledged writes a query sees. Stores
+// that buffer writes (see WriteOptimizer) acknowledge them before they
+// are stored.
+type Consistency uint8
+
+const (
+	// ConsistencyStrong sees every write acknowledged before the query
+	// started, storing buffered writes first (default).
+	ConsistencyStrong Consistency = iota
+	// ConsistencyEventual may miss writes still buffered, sparing busy
+	// stores a flush per query, e.g. for dashboards refreshed anyway.
+	ConsistencyEventual
+)
+
+// OrderBy defines the sort key for query results.
+type OrderBy uint8
+
+const (
+	// OrderByID sorts by insertion order (default).
+	OrderByID OrderBy = iota
+	// OrderByTimestamp sorts by log timestamp, breaking ties by ID.
+	// Use this when entries may arrive out of order (late batches).
+	OrderByTimestamp
+)
+
+// AttrOp is the comparison of an attribute term.
+type AttrOp uint8
+
+const (
+	// AttrEqual matches entries with the attribute set to the value.
+	AttrEqual AttrOp = iota
+	// AttrNotEqual matches entries without the attribute, or with it set
+	// to another value.
+	AttrNotEqual
+	// AttrExists matches entries with the attribute, whatever its value.
+	AttrExists
+)
+
+// AttrTerm compares one attribute. A * in Value matches any run of
+// characters, so "us-*" matches "us-east" and "us-west".
+type AttrTerm struct {
+	Key   string
+	Op    AttrOp
+	Value string
+}
+
+// AttrExpr matches entries matching any of its terms.
+type AttrExpr []AttrTerm
+
 // QueryResult contains the results of a log query.
 type QueryResult struct {
 	// Entries contains the matching log entries.
@@ -145,7 +277,20 @@ type QueryResult struct {
 	// NextCursor is the ID to use for fetching the next page.
 	NextCursor int64
 
+	// NextCursorTimestamp is the timestamp paired with NextCursor.
+	// Only set when ordering by timestamp.
+	NextCursorTimestamp time.Time
+
 	// TotalEstimate is an approximate count of total matches.
-	// -1 means count is not available.
+	// -1 means count is not available, e.g. because Query.Count wasn't
+	// set.
 	TotalEstimate int64
+
+	// TotalCapped reports that counting stopped at MaxCount, which
+	// TotalEstimate is then set to: there are more matches.
+	TotalCapped bool
+
+	// SampleRate is the fraction of matches the store sampled, or 0 if
+	// it returned every match.
+	SampleRate float64
 }
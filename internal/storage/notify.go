@@ -0,0 +1,30 @@
+package storage
+
+import "sync"
+
+// WriteSignal implements Notifier for a store, which calls Publish after
+// each write. The zero value is ready to use.
+type WriteSignal struct {
+	mu sync.Mutex
+	ch chan struct{} // nil until someone waits
+}
+
+// Written implements Notifier.
+func (s *WriteSignal) Written() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ch == nil {
+		s.ch = make(chan struct{})
+	}
+	return s.ch
+}
+
+// Publish wakes everyone waiting on a channel from Written.
+func (s *WriteSignal) Publish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ch != nil {
+		close(s.ch)
+		s.ch = nil
+	}
+}
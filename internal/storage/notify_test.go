@@ -0,0 +1,24 @@
+package storage
+
+import "testing"
+
+func TestWriteSignal_WakesAllWaiters(t *testing.T) {
+	var s WriteSignal
+	a, b := s.Written(), s.Written()
+
+	s.Publish()
+
+	for i, ch := range []<-chan struct{}{a, b} {
+		select {
+		case <-ch:
+		default:
+			t.Errorf("waiter %d not woken", i)
+		}
+	}
+
+	select {
+	case <-s.Written():
+		t.Error("new waiter should not see a previous publish")
+	default:
+	}
+}
@@ -0,0 +1,175 @@
+package objstore
+
+import (
+	"container/list"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// decodedChunks is the number of parsed chunks kept in memory.
+const decodedChunks = 8
+
+// chunkCache keeps fetched chunks locally: compressed on disk (when a
+// directory is configured) bounded by size, and the most recently used
+// few parsed in memory. Chunks are immutable, so entries never go stale.
+type chunkCache struct {
+	dir      string
+	maxBytes int64
+
+	mu       sync.Mutex
+	files    *list.List // *cachedFile, most recently used first
+	byName   map[string]*list.Element
+	size     int64
+	decoded  *list.List // *decodedChunk, most recently used first
+	byDecode map[string]*list.Element
+}
+
+type cachedFile struct {
+	name string
+	size int64
+}
+
+type decodedChunk struct {
+	name    string
+	entries []storage.LogEntry
+}
+
+// newChunkCache creates a cache. Files left in dir by a previous run
+// are reused.
+func newChunkCache(dir string, maxBytes int64) (*chunkCache, error) {
+	c := &chunkCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		files:    list.New(),
+		byName:   make(map[string]*list.Element),
+		decoded:  list.New(),
+		byDecode: make(map[string]*list.Element),
+	}
+	if dir == "" {
+		return c, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, de := range dirEntries {
+		info, err := de.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		c.byName[de.Name()] = c.files.PushBack(&cachedFile{name: de.Name(), size: info.Size()})
+		c.size += info.Size()
+	}
+	c.evict()
+	return c, nil
+}
+
+// entries returns the parsed chunk if it is in memory.
+func (c *chunkCache) entries(name string) ([]storage.LogEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.byDecode[name]
+	if !ok {
+		return nil, false
+	}
+	c.decoded.MoveToFront(el)
+	return el.Value.(*decodedChunk).entries, true
+}
+
+// putEntries keeps a parsed chunk in memory.
+func (c *chunkCache) putEntries(name string, entries []storage.LogEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.byDecode[name]; ok {
+		c.decoded.MoveToFront(el)
+		return
+	}
+	c.byDecode[name] = c.decoded.PushFront(&decodedChunk{name: name, entries: entries})
+	for c.decoded.Len() > decodedChunks {
+		el := c.decoded.Back()
+		c.decoded.Remove(el)
+		delete(c.byDecode, el.Value.(*decodedChunk).name)
+	}
+}
+
+// data returns the compressed chunk from disk.
+func (c *chunkCache) data(name string) ([]byte, bool) {
+	if c.dir == "" {
+		return nil, false
+	}
+	c.mu.Lock()
+	el, ok := c.byName[name]
+	if ok {
+		c.files.MoveToFront(el)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(filepath.Join(c.dir, name))
+	if err != nil {
+		c.remove(name)
+		return nil, false
+	}
+	return data, true
+}
+
+// putData stores a compressed chunk on disk.
+func (c *chunkCache) putData(name string, data []byte) {
+	if c.dir == "" || int64(len(data)) > c.maxBytes {
+		return
+	}
+	if err := os.WriteFile(filepath.Join(c.dir, name), data, 0o644); err != nil {
+		slog.Warn("chunk cache write failed", "chunk", name, "error", err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.byName[name]; ok {
+		c.size -= el.Value.(*cachedFile).size
+		c.files.Remove(el)
+	}
+	c.byName[name] = c.files.PushFront(&cachedFile{name: name, size: int64(len(data))})
+	c.size += int64(len(data))
+	c.evict()
+}
+
+// remove drops a chunk, e.g. after it was deleted from the bucket.
+func (c *chunkCache) remove(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.byDecode[name]; ok {
+		c.decoded.Remove(el)
+		delete(c.byDecode, name)
+	}
+	if el, ok := c.byName[name]; ok {
+		c.removeFile(el)
+	}
+}
+
+// evict removes least recently used files over the size limit.
+// Callers hold c.mu.
+func (c *chunkCache) evict() {
+	for c.size > c.maxBytes && c.files.Len() > 0 {
+		c.removeFile(c.files.Back())
+	}
+}
+
+// removeFile deletes a cached file. Callers hold c.mu.
+func (c *chunkCache) removeFile(el *list.Element) {
+	f := el.Value.(*cachedFile)
+	c.files.Remove(el)
+	delete(c.byName, f.name)
+	c.size -= f.size
+	os.Remove(filepath.Join(c.dir, f.name))
+}
@@ -0,0 +1,251 @@
+package objstore
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// ErrObjectNotFound is returned by a Bucket when a key doesn't exist.
+var ErrObjectNotFound = errors.New("objstore: object not found")
+
+// Bucket is a flat key/value object store. Implementations must be safe
+// for concurrent use.
+type Bucket interface {
+	Put(ctx context.Context, key string, data []byte) error
+
+	// Get returns ErrObjectNotFound (possibly wrapped) for missing keys.
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Delete succeeds for missing keys.
+	Delete(ctx context.Context, key string) error
+
+	// List returns all keys starting with prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// Object layout under the configured prefix:
+//
+//	chunks/<name>.ndjson.gz  gzip-compressed entries, one JSON object per line, by ID
+//	index/<name>.json        chunkMeta describing the chunk
+//
+// name is "<minID>-<maxID>-<count>" with zero-padded IDs, so keys sort by
+// ID and a rewritten chunk never reuses the key of the one it replaces.
+const (
+	chunksDir = "chunks/"
+	indexDir  = "index/"
+)
+
+// chunkMeta is the index record of one chunk. Queries use it to skip
+// chunks that can't match without fetching them.
+type chunkMeta struct {
+	Name    string `json:"name"`
+	MinID   int64  `json:"minId"`
+	MaxID   int64  `json:"maxId"`
+	MinTime int64  `json:"minTime"` // Unix nanoseconds
+	MaxTime int64  `json:"maxTime"` // Unix nanoseconds
+	Entries int64  `json:"entries"`
+	Size    int64  `json:"size"` // Compressed object size
+
+	// Sorted distinct values, for filter pruning
+	Clusters   []string `json:"clusters,omitempty"`
+	Pods       []string `json:"pods"`
+	Containers []string `json:"containers"`
+
+	// Namespaces maps namespace to its entry count and message bytes.
+	Namespaces map[string]*namespaceCount `json:"namespaces"`
+}
+
+// namespaceCount is the volume of one namespace within a chunk.
+type namespaceCount struct {
+	Entries int64 `json:"entries"`
+	Bytes   int64 `json:"bytes"`
+}
+
+func chunkName(entries []storage.LogEntry) string {
+	return fmt.Sprintf("%020d-%020d-%d", entries[0].ID, entries[len(entries)-1].ID, len(entries))
+}
+
+func chunkKey(prefix, name string) string { return prefix + chunksDir + name + ".ndjson.gz" }
+func indexKey(prefix, name string) string { return prefix + indexDir + name + ".json" }
+
+// chunkRecord is the serialized form of an entry.
+type chunkRecord struct {
+	ID         int64             `json:"id"`
+	Timestamp  int64             `json:"ts"`
+	Cluster    string            `json:"cl,omitempty"`
+	Namespace  string            `json:"ns"`
+	Pod        string            `json:"pod"`
+	Container  string            `json:"c"`
+	Severity   storage.Severity  `json:"sev"`
+	Message    string            `json:"msg"`
+	Attributes map[string]string `json:"attrs,omitempty"`
+}
+
+// encodeChunk serializes entries, which must be sorted by ID, and
+// returns the compressed chunk with its index record.
+func encodeChunk(entries []storage.LogEntry) ([]byte, *chunkMeta, error) {
+	meta := &chunkMeta{
+		Name:       chunkName(entries),
+		MinID:      entries[0].ID,
+		MaxID:      entries[len(entries)-1].ID,
+		MinTime:    entries[0].Timestamp.UnixNano(),
+		MaxTime:    entries[0].Timestamp.UnixNano(),
+		Entries:    int64(len(entries)),
+		Namespaces: make(map[string]*namespaceCount),
+	}
+	clusters := make(map[string]bool)
+	pods := make(map[string]bool)
+	containers := make(map[string]bool)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(zw)
+	for _, e := range entries {
+		ts := e.Timestamp.UnixNano()
+		meta.MinTime = min(meta.MinTime, ts)
+		meta.MaxTime = max(meta.MaxTime, ts)
+		clusters[e.Cluster] = true
+		pods[e.Pod] = true
+		containers[e.Container] = true
+		nc, ok := meta.Namespaces[e.Namespace]
+		if !ok {
+			nc = &namespaceCount{}
+			meta.Namespaces[e.Namespace] = nc
+		}
+		nc.Entries++
+		nc.Bytes += int64(len(e.Message))
+
+		err := enc.Encode(chunkRecord{
+			ID:         e.ID,
+			Timestamp:  ts,
+			Cluster:    e.Cluster,
+			Namespace:  e.Namespace,
+			Pod:        e.Pod,
+			Container:  e.Container,
+			Severity:   e.Severity,
+			Message:    e.Message,
+			Attributes: e.Attributes,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("encode entry: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, nil, fmt.Errorf("compress chunk: %w", err)
+	}
+
+	meta.Clusters = sortedKeys(clusters)
+	meta.Pods = sortedKeys(pods)
+	meta.Containers = sortedKeys(containers)
+	meta.Size = int64(buf.Len())
+	return buf.Bytes(), meta, nil
+}
+
+// decodeChunk parses a compressed chunk.
+func decodeChunk(data []byte) ([]storage.LogEntry, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decompress chunk: %w", err)
+	}
+	defer zr.Close()
+
+	var entries []storage.LogEntry
+	sc := bufio.NewScanner(zr)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var r chunkRecord
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("decode entry: %w", err)
+		}
+		entries = append(entries, storage.LogEntry{
+			ID:         r.ID,
+			Timestamp:  time.Unix(0, r.Timestamp),
+			Cluster:    r.Cluster,
+			Namespace:  r.Namespace,
+			Pod:        r.Pod,
+			Container:  r.Container,
+			Severity:   r.Severity,
+			Message:    r.Message,
+			Attributes: r.Attributes,
+		})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read chunk: %w", err)
+	}
+	return entries, nil
+}
+
+// mayMatch reports whether the chunk can contain entries matching q.
+func (m *chunkMeta) mayMatch(q *storage.Query) bool {
+	if !q.StartTime.IsZero() && m.MaxTime < q.StartTime.UnixNano() {
+		return false
+	}
+	if !q.EndTime.IsZero() && m.MinTime >= q.EndTime.UnixNano() {
+		return false
+	}
+	if q.Cluster != "" && !containsSorted(chunkClusters(m), q.Cluster) {
+		return false
+	}
+	if q.Namespace != "" && m.Namespaces[q.Namespace] == nil {
+		return false
+	}
+	if q.Pod != "" && !containsSorted(m.Pods, q.Pod) {
+		return false
+	}
+	if q.Container != "" && !containsSorted(m.Containers, q.Container) {
+		return false
+	}
+
+	p := q.Pagination
+	byTimestamp := p.OrderBy == storage.OrderByTimestamp
+	if byTimestamp && !p.AfterTimestamp.IsZero() {
+		if m.MaxTime < p.AfterTimestamp.UnixNano() {
+			return false
+		}
+	} else if p.AfterID > 0 && m.MaxID <= p.AfterID {
+		return false
+	}
+	if byTimestamp && !p.BeforeTimestamp.IsZero() {
+		if m.MinTime > p.BeforeTimestamp.UnixNano() {
+			return false
+		}
+	} else if p.BeforeID > 0 && m.MinID >= p.BeforeID {
+		return false
+	}
+	if p.MaxID > 0 && m.MinID > p.MaxID {
+		return false
+	}
+	return true
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// chunkClusters returns the clusters of a chunk. Chunks written before
+// clusters existed hold only entries without one.
+func chunkClusters(cm *chunkMeta) []string {
+	if cm.Clusters == nil {
+		return []string{""}
+	}
+	return cm.Clusters
+}
+
+func containsSorted(values []string, v string) bool {
+	i := sort.SearchStrings(values, v)
+	return i < len(values) && values[i] == v
+}
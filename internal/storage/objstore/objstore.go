@@ -0,0 +1,967 @@
+// Package objstore implements storage.Store directly on object storage,
+// for clusters without persistent volumes.
+//
+// Writes are buffered in memory and uploaded as immutable compressed
+// chunks with a small index object each. Queries prune chunks by their
+// index, fetch the remaining ones and filter them in memory, keeping
+// recently used chunks in a local cache. This trades query latency for
+// needing no persistent disk; entries not yet uploaded are lost if the
+// process dies.
+//
+// Full-text search is a case-insensitive match of all search terms
+// rather than FTS5 syntax.
+package objstore
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+const (
+	defaultChunkEntries  = 10000
+	defaultFlushInterval = time.Minute
+	defaultCacheMaxBytes = 1 << 30
+	defaultQueryLimit    = 100
+
+	// dedupWindow is the number of recent entries checked for duplicates,
+	// e.g. batches resent by a collector after a timeout.
+	dedupWindow = 100000
+)
+
+// Config holds object store configuration.
+type Config struct {
+	// Bucket holds chunks and their index.
+	Bucket Bucket
+
+	// Prefix is prepended to all keys, e.g. "kubelogs/".
+	Prefix string
+
+	// CacheDir caches fetched chunks on local disk (e.g. an emptyDir).
+	// Empty keeps only a few recently used chunks in memory.
+	CacheDir string
+
+	// CacheMaxBytes bounds the disk cache. Default: 1 GiB.
+	CacheMaxBytes int64
+
+	// ChunkEntries is the number of entries buffered before a chunk is
+	// uploaded. Default: 10000.
+	ChunkEntries int
+
+	// FlushInterval uploads buffered entries at least this often, so a
+	// quiet cluster doesn't hold logs in memory indefinitely.
+	// Default: 1 minute. Negative disables periodic flushing.
+	FlushInterval time.Duration
+}
+
+// Store implements storage.Store on a Bucket.
+type Store struct {
+	bucket Bucket
+	prefix string
+	cache  *chunkCache
+
+	mu           sync.RWMutex
+	closed       bool
+	chunkEntries int
+	metas        []*chunkMeta       // Uploaded chunks, sorted by ID
+	flushing     []storage.LogEntry // Being uploaded, sorted by ID
+	head         []storage.LogEntry // Not yet uploaded, sorted by ID
+	nextID       int64
+	seen         map[uint64]struct{} // Dedup hashes of recent entries
+	seenRing     []uint64
+	seenPos      int
+
+	flushMu sync.Mutex // Serializes uploads and deletes
+
+	written storage.WriteSignal
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func init() {
+	// Options: "bucket", "endpoint", "region", "prefix", "path_style",
+	// "cache_dir" and "cache_max_bytes". Credentials come from the
+	// standard AWS environment variables.
+	storage.Register("s3", func(opts storage.Options) (storage.Store, error) {
+		var cacheMaxBytes int64
+		if v := opts["cache_max_bytes"]; v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("objstore: invalid cache_max_bytes %q", v)
+			}
+			cacheMaxBytes = n
+		}
+		bucket, err := NewS3Bucket(S3Config{
+			Endpoint:        opts["endpoint"],
+			Region:          opts["region"],
+			Bucket:          opts["bucket"],
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			PathStyle:       opts["path_style"] == "true",
+		})
+		if err != nil {
+			return nil, err
+		}
+		return New(Config{
+			Bucket:        bucket,
+			Prefix:        opts["prefix"],
+			CacheDir:      opts["cache_dir"],
+			CacheMaxBytes: cacheMaxBytes,
+		})
+	})
+}
+
+// New opens a store, loading the chunk index from the bucket.
+func New(cfg Config) (*Store, error) {
+	if cfg.Bucket == nil {
+		return nil, errors.New("objstore: bucket is required")
+	}
+	if cfg.ChunkEntries <= 0 {
+		cfg.ChunkEntries = defaultChunkEntries
+	}
+	if cfg.FlushInterval == 0 {
+		cfg.FlushInterval = defaultFlushInterval
+	}
+	if cfg.CacheMaxBytes <= 0 {
+		cfg.CacheMaxBytes = defaultCacheMaxBytes
+	}
+
+	cache, err := newChunkCache(cfg.CacheDir, cfg.CacheMaxBytes)
+	if err != nil {
+		return nil, fmt.Errorf("open chunk cache: %w", err)
+	}
+
+	s := &Store{
+		bucket:       cfg.Bucket,
+		prefix:       cfg.Prefix,
+		cache:        cache,
+		chunkEntries: cfg.ChunkEntries,
+		nextID:       1,
+		seen:         make(map[uint64]struct{}),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+
+	if err := s.loadIndex(context.Background()); err != nil {
+		return nil, err
+	}
+
+	if cfg.FlushInterval > 0 {
+		go s.flushLoop(cfg.FlushInterval)
+	} else {
+		close(s.done)
+	}
+	return s, nil
+}
+
+// loadIndex reads all chunk index objects.
+func (s *Store) loadIndex(ctx context.Context) error {
+	keys, err := s.bucket.List(ctx, s.prefix+indexDir)
+	if err != nil {
+		return fmt.Errorf("list index: %w", err)
+	}
+
+	metas := make([]*chunkMeta, 0, len(keys))
+	for _, key := range keys {
+		data, err := s.bucket.Get(ctx, key)
+		if errors.Is(err, ErrObjectNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("read index: %w", err)
+		}
+		var m chunkMeta
+		if err := json.Unmarshal(data, &m); err != nil {
+			return fmt.Errorf("decode index %s: %w", key, err)
+		}
+		metas = append(metas, &m)
+	}
+	sort.Slice(metas, func(i, j int) bool { return metas[i].MinID < metas[j].MinID })
+
+	// An interrupted chunk rewrite leaves the old and new chunk with
+	// overlapping IDs. The rewrite only drops entries, so the smaller
+	// chunk is the current one.
+	kept := metas[:0]
+	for _, m := range metas {
+		if n := len(kept); n > 0 && m.MinID <= kept[n-1].MaxID {
+			stale := m
+			if m.Entries < kept[n-1].Entries {
+				stale, kept[n-1] = kept[n-1], m
+			}
+			if err := s.deleteChunk(ctx, stale); err != nil {
+				slog.Warn("failed to delete stale chunk", "chunk", stale.Name, "error", err)
+			}
+			continue
+		}
+		kept = append(kept, m)
+	}
+
+	s.metas = kept
+	if n := len(kept); n > 0 {
+		s.nextID = kept[n-1].MaxID + 1
+	}
+	return nil
+}
+
+// flushLoop uploads buffered entries periodically until Close.
+func (s *Store) flushLoop(interval time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if err := s.flush(context.Background(), false); err != nil {
+				slog.Error("chunk upload failed", "error", err)
+			}
+		}
+	}
+}
+
+// Write implements storage.Store.
+func (s *Store) Write(ctx context.Context, entries storage.LogBatch) (int, error) {
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return 0, storage.ErrStorageClosed
+	}
+	for _, e := range entries {
+		h := dedupHash(&e)
+		if _, dup := s.seen[h]; dup {
+			continue
+		}
+		s.remember(h)
+
+		e.ID = s.nextID
+		s.nextID++
+		s.head = append(s.head, e)
+	}
+	needFlush := len(s.head) >= s.chunkEntries
+	s.mu.Unlock()
+	s.written.Publish()
+
+	if needFlush {
+		if err := s.flush(ctx, true); err != nil {
+			return 0, err
+		}
+	}
+	return len(entries), nil
+}
+
+// Written implements storage.Notifier.
+func (s *Store) Written() <-chan struct{} {
+	return s.written.Written()
+}
+
+// remember records a dedup hash, forgetting the oldest beyond dedupWindow.
+// Callers hold s.mu.
+func (s *Store) remember(h uint64) {
+	if len(s.seenRing) < dedupWindow {
+		s.seenRing = append(s.seenRing, h)
+	} else {
+		delete(s.seen, s.seenRing[s.seenPos])
+		s.seenRing[s.seenPos] = h
+		s.seenPos = (s.seenPos + 1) % dedupWindow
+	}
+	s.seen[h] = struct{}{}
+}
+
+// dedupHash identifies an entry by timestamp, source and message. An
+// empty cluster is left out, as in the SQLite store.
+func dedupHash(e *storage.LogEntry) uint64 {
+	h := fnv.New64a()
+	var ts [8]byte
+	binary.LittleEndian.PutUint64(ts[:], uint64(e.Timestamp.UnixNano()))
+	h.Write(ts[:])
+	if e.Cluster != "" {
+		h.Write([]byte(e.Cluster))
+		h.Write([]byte{0})
+	}
+	for _, field := range []string{e.Namespace, e.Pod, e.Container, e.Message} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
+// Flush implements storage.WriteOptimizer by uploading buffered entries.
+func (s *Store) Flush(ctx context.Context) error {
+	s.mu.RLock()
+	closed := s.closed
+	s.mu.RUnlock()
+	if closed {
+		return storage.ErrStorageClosed
+	}
+	return s.flush(ctx, false)
+}
+
+// SetWriteBuffer implements storage.WriteOptimizer by setting the chunk size.
+func (s *Store) SetWriteBuffer(entries int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entries > 0 {
+		s.chunkEntries = entries
+	}
+}
+
+// flush uploads the head as one or more chunks. With fullOnly, a
+// trailing partial chunk stays buffered. On failure the entries not
+// uploaded go back to the head.
+func (s *Store) flush(ctx context.Context, fullOnly bool) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	size := s.chunkEntries
+	n := len(s.head)
+	if fullOnly {
+		n -= n % size
+	}
+	if n == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	s.flushing = s.head[:n:n]
+	s.head = slices.Clone(s.head[n:])
+	s.mu.Unlock()
+
+	for {
+		s.mu.RLock()
+		part := s.flushing[:min(size, len(s.flushing))]
+		s.mu.RUnlock()
+		if len(part) == 0 {
+			return nil
+		}
+
+		meta, data, err := s.uploadChunk(ctx, part)
+		if err != nil {
+			s.mu.Lock()
+			s.head = slices.Concat(s.flushing, s.head)
+			s.flushing = nil
+			s.mu.Unlock()
+			return err
+		}
+
+		s.mu.Lock()
+		s.metas = append(s.metas, meta)
+		s.flushing = s.flushing[len(part):]
+		s.mu.Unlock()
+
+		s.cache.putData(meta.Name, data)
+		s.cache.putEntries(meta.Name, part)
+	}
+}
+
+// uploadChunk writes a chunk and then its index, so an index never
+// points at a missing chunk.
+func (s *Store) uploadChunk(ctx context.Context, entries []storage.LogEntry) (*chunkMeta, []byte, error) {
+	data, meta, err := encodeChunk(entries)
+	if err != nil {
+		return nil, nil, err
+	}
+	idx, err := json.Marshal(meta)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encode index: %w", err)
+	}
+
+	if err := s.bucket.Put(ctx, chunkKey(s.prefix, meta.Name), data); err != nil {
+		return nil, nil, fmt.Errorf("upload chunk: %w", err)
+	}
+	if err := s.bucket.Put(ctx, indexKey(s.prefix, meta.Name), idx); err != nil {
+		return nil, nil, fmt.Errorf("upload index: %w", err)
+	}
+	return meta, data, nil
+}
+
+// deleteChunk removes a chunk's index and then its data.
+func (s *Store) deleteChunk(ctx context.Context, m *chunkMeta) error {
+	s.cache.remove(m.Name)
+	if err := s.bucket.Delete(ctx, indexKey(s.prefix, m.Name)); err != nil {
+		return fmt.Errorf("delete index: %w", err)
+	}
+	if err := s.bucket.Delete(ctx, chunkKey(s.prefix, m.Name)); err != nil {
+		return fmt.Errorf("delete chunk: %w", err)
+	}
+	return nil
+}
+
+// loadChunk returns a chunk's entries from the cache or the bucket.
+func (s *Store) loadChunk(ctx context.Context, m *chunkMeta) ([]storage.LogEntry, error) {
+	if entries, ok := s.cache.entries(m.Name); ok {
+		return entries, nil
+	}
+
+	data, ok := s.cache.data(m.Name)
+	if !ok {
+		var err error
+		data, err = s.bucket.Get(ctx, chunkKey(s.prefix, m.Name))
+		if err != nil {
+			return nil, fmt.Errorf("fetch chunk %s: %w", m.Name, err)
+		}
+		s.cache.putData(m.Name, data)
+	}
+
+	entries, err := decodeChunk(data)
+	if err != nil {
+		return nil, fmt.Errorf("chunk %s: %w", m.Name, err)
+	}
+	s.cache.putEntries(m.Name, entries)
+	return entries, nil
+}
+
+// Query implements storage.Store.
+func (s *Store) Query(ctx context.Context, q storage.Query) (*storage.QueryResult, error) {
+	limit := q.Pagination.Limit
+	if limit <= 0 {
+		limit = defaultQueryLimit
+	}
+	m := newMatcher(&q)
+
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return nil, storage.ErrStorageClosed
+	}
+	var matches []storage.LogEntry
+	for _, buf := range [][]storage.LogEntry{s.flushing, s.head} {
+		for i := range buf {
+			if m.match(&buf[i]) && m.sample() {
+				matches = append(matches, buf[i])
+			}
+		}
+	}
+	var candidates []*chunkMeta
+	for _, cm := range s.metas {
+		if cm.mayMatch(&q) {
+			candidates = append(candidates, cm)
+		}
+	}
+	s.mu.RUnlock()
+
+	// Visit chunks by the best entry they could hold and stop once a
+	// chunk can't improve on the results collected so far.
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := m.bound(candidates[i]), m.bound(candidates[j])
+		return m.less(&a, &b)
+	})
+	for _, cm := range candidates {
+		if len(matches) > limit {
+			m.sort(matches)
+			matches = matches[:limit+1]
+			if bound := m.bound(cm); !m.less(&bound, &matches[limit]) {
+				break
+			}
+		}
+
+		entries, err := s.loadChunk(ctx, cm)
+		if errors.Is(err, ErrObjectNotFound) {
+			// Deleted by retention since the index was read
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i := range entries {
+			if m.match(&entries[i]) && m.sample() {
+				matches = append(matches, entries[i])
+			}
+		}
+	}
+	m.sort(matches)
+
+	result := &storage.QueryResult{
+		TotalEstimate: -1,
+	}
+	if q.Sampled() {
+		result.SampleRate = q.Sample
+	}
+	if len(matches) > limit {
+		result.HasMore = true
+		result.NextCursor = matches[limit].ID
+		if q.Pagination.OrderBy == storage.OrderByTimestamp {
+			result.NextCursorTimestamp = matches[limit].Timestamp
+		}
+		matches = matches[:limit]
+	}
+	if matches == nil {
+		matches = make([]storage.LogEntry, 0)
+	}
+	result.Entries = matches
+
+	return result, nil
+}
+
+// GetByID implements storage.Store.
+func (s *Store) GetByID(ctx context.Context, id int64) (*storage.LogEntry, error) {
+	entries, err := s.GetByIDs(ctx, []int64{id})
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, storage.ErrNotFound
+	}
+	return &entries[0], nil
+}
+
+// GetByIDs implements storage.Store.
+func (s *Store) GetByIDs(ctx context.Context, ids []int64) ([]storage.LogEntry, error) {
+	found := make(map[int64]storage.LogEntry, len(ids))
+	byChunk := make(map[*chunkMeta][]int64)
+
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return nil, storage.ErrStorageClosed
+	}
+	for _, id := range ids {
+		if e, ok := findEntry(s.flushing, id); ok {
+			found[id] = e
+		} else if e, ok := findEntry(s.head, id); ok {
+			found[id] = e
+		} else if cm := s.chunkFor(id); cm != nil {
+			byChunk[cm] = append(byChunk[cm], id)
+		}
+	}
+	s.mu.RUnlock()
+
+	for cm, chunkIDs := range byChunk {
+		entries, err := s.loadChunk(ctx, cm)
+		if errors.Is(err, ErrObjectNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range chunkIDs {
+			if e, ok := findEntry(entries, id); ok {
+				found[id] = e
+			}
+		}
+	}
+
+	entries := make([]storage.LogEntry, 0, len(found))
+	for _, id := range ids {
+		if e, ok := found[id]; ok {
+			entries = append(entries, e)
+			delete(found, id)
+		}
+	}
+	return entries, nil
+}
+
+// chunkFor returns the chunk whose ID range contains id. Callers hold s.mu.
+func (s *Store) chunkFor(id int64) *chunkMeta {
+	i := sort.Search(len(s.metas), func(i int) bool { return s.metas[i].MaxID >= id })
+	if i < len(s.metas) && s.metas[i].MinID <= id {
+		return s.metas[i]
+	}
+	return nil
+}
+
+// findEntry binary searches entries sorted by ID.
+func findEntry(entries []storage.LogEntry, id int64) (storage.LogEntry, bool) {
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].ID >= id })
+	if i < len(entries) && entries[i].ID == id {
+		return entries[i], true
+	}
+	return storage.LogEntry{}, false
+}
+
+// Delete implements storage.Store. Chunks entirely older than the cutoff
+// are removed; chunks straddling it are rewritten without the old entries.
+func (s *Store) Delete(ctx context.Context, olderThan time.Time) (int64, error) {
+	return s.deleteBefore(ctx, olderThan.UnixNano(), nil)
+}
+
+// DeleteCluster implements storage.ClusterDeleter. Chunks holding old
+// entries of the cluster are rewritten without them.
+func (s *Store) DeleteCluster(ctx context.Context, cluster string, olderThan time.Time) (int64, error) {
+	return s.deleteBefore(ctx, olderThan.UnixNano(), &cluster)
+}
+
+// deleteBefore removes entries older than cutoff, only those of cluster
+// if it is non-nil.
+func (s *Store) deleteBefore(ctx context.Context, cutoff int64, cluster *string) (int64, error) {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	drop := func(e storage.LogEntry) bool {
+		return e.Timestamp.UnixNano() < cutoff && (cluster == nil || e.Cluster == *cluster)
+	}
+	// A chunk is dropped without fetching it when every entry matches
+	wholly := func(cm *chunkMeta) bool {
+		if cm.MaxTime >= cutoff {
+			return false
+		}
+		if cluster == nil {
+			return true
+		}
+		clusters := chunkClusters(cm)
+		return len(clusters) == 1 && clusters[0] == *cluster
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return 0, storage.ErrStorageClosed
+	}
+	n := len(s.head)
+	s.head = slices.DeleteFunc(s.head, drop)
+	deleted := int64(n - len(s.head))
+
+	var expired []*chunkMeta
+	for _, cm := range s.metas {
+		if cm.MinTime < cutoff && (cluster == nil || containsSorted(chunkClusters(cm), *cluster)) {
+			expired = append(expired, cm)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, cm := range expired {
+		var replacement *chunkMeta
+		if !wholly(cm) {
+			entries, err := s.loadChunk(ctx, cm)
+			if err != nil {
+				return deleted, err
+			}
+			var kept []storage.LogEntry
+			for _, e := range entries {
+				if !drop(e) {
+					kept = append(kept, e)
+				}
+			}
+			if len(kept) == len(entries) {
+				continue
+			}
+			if len(kept) > 0 {
+				meta, data, err := s.uploadChunk(ctx, kept)
+				if err != nil {
+					return deleted, err
+				}
+				replacement = meta
+				s.cache.putData(meta.Name, data)
+				s.cache.putEntries(meta.Name, kept)
+			}
+		}
+
+		s.mu.Lock()
+		i := slices.Index(s.metas, cm)
+		if replacement != nil {
+			s.metas[i] = replacement
+			deleted += cm.Entries - replacement.Entries
+		} else {
+			s.metas = slices.Delete(s.metas, i, i+1)
+			deleted += cm.Entries
+		}
+		s.mu.Unlock()
+
+		if err := s.deleteChunk(ctx, cm); err != nil {
+			return deleted, err
+		}
+	}
+
+	return deleted, nil
+}
+
+// Stats implements storage.Store. Sizes are those of the uploaded chunks.
+func (s *Store) Stats(ctx context.Context) (*storage.Stats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return nil, storage.ErrStorageClosed
+	}
+
+	stats := &storage.Stats{}
+	usage := make(map[string]*storage.NamespaceUsage)
+	oldest, newest := int64(math.MaxInt64), int64(math.MinInt64)
+
+	addUsage := func(ns string, entries, bytes int64) {
+		u, ok := usage[ns]
+		if !ok {
+			u = &storage.NamespaceUsage{Namespace: ns}
+			usage[ns] = u
+		}
+		u.Entries += entries
+		u.Bytes += bytes
+	}
+
+	for _, cm := range s.metas {
+		stats.TotalEntries += cm.Entries
+		stats.DiskSizeBytes += cm.Size
+		oldest = min(oldest, cm.MinTime)
+		newest = max(newest, cm.MaxTime)
+		for ns, nc := range cm.Namespaces {
+			addUsage(ns, nc.Entries, nc.Bytes)
+		}
+	}
+	for _, buf := range [][]storage.LogEntry{s.flushing, s.head} {
+		for _, e := range buf {
+			stats.TotalEntries++
+			ts := e.Timestamp.UnixNano()
+			oldest = min(oldest, ts)
+			newest = max(newest, ts)
+			addUsage(e.Namespace, 1, int64(len(e.Message)))
+		}
+	}
+
+	if stats.TotalEntries > 0 {
+		stats.OldestEntry = time.Unix(0, oldest)
+		stats.NewestEntry = time.Unix(0, newest)
+	}
+	for _, u := range usage {
+		stats.Namespaces = append(stats.Namespaces, *u)
+	}
+	sort.Slice(stats.Namespaces, func(i, j int) bool {
+		a, b := stats.Namespaces[i], stats.Namespaces[j]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return a.Namespace < b.Namespace
+	})
+
+	return stats, nil
+}
+
+// ListNamespaces returns distinct namespace values.
+func (s *Store) ListNamespaces(ctx context.Context) ([]string, error) {
+	return s.distinct(func(cm *chunkMeta) []string {
+		names := make([]string, 0, len(cm.Namespaces))
+		for ns := range cm.Namespaces {
+			names = append(names, ns)
+		}
+		return names
+	}, func(e *storage.LogEntry) string { return e.Namespace })
+}
+
+// ListClusters returns distinct non-empty cluster values.
+func (s *Store) ListClusters(ctx context.Context) ([]string, error) {
+	clusters, err := s.distinct(func(cm *chunkMeta) []string { return cm.Clusters },
+		func(e *storage.LogEntry) string { return e.Cluster })
+	if len(clusters) > 0 && clusters[0] == "" {
+		clusters = clusters[1:]
+	}
+	return clusters, err
+}
+
+// ListContainers returns distinct container values.
+func (s *Store) ListContainers(ctx context.Context) ([]string, error) {
+	return s.distinct(func(cm *chunkMeta) []string { return cm.Containers },
+		func(e *storage.LogEntry) string { return e.Container })
+}
+
+// ListPods returns distinct pod values in namespace, or in all
+// namespaces if it's empty. Chunks index pods without their namespace,
+// so the pods of other namespaces sharing a chunk with namespace are
+// included too.
+func (s *Store) ListPods(ctx context.Context, namespace string) ([]string, error) {
+	pods, err := s.distinct(func(cm *chunkMeta) []string {
+		if _, ok := cm.Namespaces[namespace]; namespace != "" && !ok {
+			return nil
+		}
+		return cm.Pods
+	}, func(e *storage.LogEntry) string {
+		if namespace != "" && e.Namespace != namespace {
+			return ""
+		}
+		return e.Pod
+	})
+	if len(pods) > 0 && pods[0] == "" {
+		pods = pods[1:]
+	}
+	return pods, err
+}
+
+// distinct collects sorted distinct values from the index and buffers.
+func (s *Store) distinct(fromMeta func(*chunkMeta) []string, fromEntry func(*storage.LogEntry) string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return nil, storage.ErrStorageClosed
+	}
+
+	set := make(map[string]bool)
+	for _, cm := range s.metas {
+		for _, v := range fromMeta(cm) {
+			set[v] = true
+		}
+	}
+	for _, buf := range [][]storage.LogEntry{s.flushing, s.head} {
+		for i := range buf {
+			set[fromEntry(&buf[i])] = true
+		}
+	}
+	return sortedKeys(set), nil
+}
+
+// Close implements storage.Store. Buffered entries are uploaded.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.stop)
+	<-s.done
+	return s.flush(context.Background(), false)
+}
+
+// matcher evaluates a query against entries in memory.
+type matcher struct {
+	q     *storage.Query
+	terms []string // Lowercased search terms, all must match
+}
+
+func newMatcher(q *storage.Query) *matcher {
+	return &matcher{q: q, terms: searchTerms(q.Search)}
+}
+
+// searchTerms splits a search into lowercased terms. Quoted phrases are
+// kept whole; AND and trailing prefix wildcards are accepted and ignored.
+func searchTerms(search string) []string {
+	var terms []string
+	for i, part := range strings.Split(search, `"`) {
+		if i%2 == 1 {
+			if part = strings.TrimSpace(part); part != "" {
+				terms = append(terms, strings.ToLower(part))
+			}
+			continue
+		}
+		for _, word := range strings.Fields(part) {
+			if word == "AND" {
+				continue
+			}
+			if word = strings.TrimSuffix(word, "*"); word != "" {
+				terms = append(terms, strings.ToLower(word))
+			}
+		}
+	}
+	return terms
+}
+
+// match reports whether e satisfies the query filters and cursor.
+func (m *matcher) match(e *storage.LogEntry) bool {
+	q := m.q
+	ts := e.Timestamp.UnixNano()
+	if !q.StartTime.IsZero() && ts < q.StartTime.UnixNano() {
+		return false
+	}
+	if !q.EndTime.IsZero() && ts >= q.EndTime.UnixNano() {
+		return false
+	}
+	if q.Cluster != "" && e.Cluster != q.Cluster {
+		return false
+	}
+	if q.Namespace != "" && e.Namespace != q.Namespace {
+		return false
+	}
+	if q.Pod != "" && e.Pod != q.Pod {
+		return false
+	}
+	if q.Container != "" && e.Container != q.Container {
+		return false
+	}
+	if e.Severity < q.MinSeverity {
+		return false
+	}
+	for k, v := range q.Attributes {
+		if e.Attributes[k] != v {
+			return false
+		}
+	}
+	for _, expr := range q.AttrExprs {
+		if !expr.Match(e.Attributes) {
+			return false
+		}
+	}
+	if len(m.terms) > 0 {
+		msg := strings.ToLower(e.Message)
+		for _, t := range m.terms {
+			if !strings.Contains(msg, t) {
+				return false
+			}
+		}
+	}
+
+	p := q.Pagination
+	byTimestamp := p.OrderBy == storage.OrderByTimestamp
+	if byTimestamp && !p.AfterTimestamp.IsZero() {
+		after := p.AfterTimestamp.UnixNano()
+		if ts < after || ts == after && e.ID <= p.AfterID {
+			return false
+		}
+	} else if p.AfterID > 0 && e.ID <= p.AfterID {
+		return false
+	}
+	if byTimestamp && !p.BeforeTimestamp.IsZero() {
+		before, beforeID := p.BeforeTimestamp.UnixNano(), p.BeforeID
+		if beforeID == 0 {
+			beforeID = math.MaxInt64
+		}
+		if ts > before || ts == before && e.ID >= beforeID {
+			return false
+		}
+	} else if p.BeforeID > 0 && e.ID >= p.BeforeID {
+		return false
+	}
+	if p.MaxID > 0 && e.ID > p.MaxID {
+		return false
+	}
+	return true
+}
+
+// less reports whether a comes before b in the query's result order.
+// sample reports whether a matching entry is kept in the query's sample.
+func (m *matcher) sample() bool {
+	return !m.q.Sampled() || rand.Float64() < m.q.Sample
+}
+
+func (m *matcher) less(a, b *storage.LogEntry) bool {
+	asc := m.q.Pagination.Order == storage.OrderAsc
+	if m.q.Pagination.OrderBy == storage.OrderByTimestamp {
+		if at, bt := a.Timestamp.UnixNano(), b.Timestamp.UnixNano(); at != bt {
+			return (at < bt) == asc
+		}
+	}
+	return (a.ID < b.ID) == asc
+}
+
+func (m *matcher) sort(entries []storage.LogEntry) {
+	sort.Slice(entries, func(i, j int) bool { return m.less(&entries[i], &entries[j]) })
+}
+
+// bound returns a key no entry of the chunk can come before in the
+// result order.
+func (m *matcher) bound(cm *chunkMeta) storage.LogEntry {
+	if m.q.Pagination.Order == storage.OrderAsc {
+		return storage.LogEntry{ID: cm.MinID, Timestamp: time.Unix(0, cm.MinTime)}
+	}
+	return storage.LogEntry{ID: cm.MaxID, Timestamp: time.Unix(0, cm.MaxTime)}
+}
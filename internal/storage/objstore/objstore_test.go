@@ -0,0 +1,338 @@
+package objstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// memBucket is an in-memory Bucket for tests.
+type memBucket struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	gets    int
+}
+
+func newMemBucket() *memBucket {
+	return &memBucket{objects: make(map[string][]byte)}
+}
+
+func (b *memBucket) Put(ctx context.Context, key string, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.objects[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (b *memBucket) Get(ctx context.Context, key string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.gets++
+	data, ok := b.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", key, ErrObjectNotFound)
+	}
+	return data, nil
+}
+
+func (b *memBucket) Delete(ctx context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.objects, key)
+	return nil
+}
+
+func (b *memBucket) List(ctx context.Context, prefix string) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var keys []string
+	for k := range b.objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (b *memBucket) keys(prefix string) []string {
+	keys, _ := b.List(context.Background(), prefix)
+	return keys
+}
+
+func newTestStore(t *testing.T, bucket Bucket, chunkEntries int) *Store {
+	t.Helper()
+	s, err := New(Config{Bucket: bucket, Prefix: "logs/", ChunkEntries: chunkEntries, FlushInterval: -1})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	return s
+}
+
+func TestStore(t *testing.T) {
+	storage.StoreTestSuite(t, func() (storage.Store, func()) {
+		s := newTestStore(t, newMemBucket(), 0)
+		return s, func() { s.Close() }
+	})
+}
+
+func TestChunksAndReopen(t *testing.T) {
+	bucket := newMemBucket()
+	s := newTestStore(t, bucket, 4)
+	ctx := context.Background()
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var batch storage.LogBatch
+	for i := 0; i < 10; i++ {
+		batch = append(batch, storage.LogEntry{
+			Timestamp: base.Add(time.Duration(i) * time.Minute),
+			Namespace: []string{"a", "b"}[i%2],
+			Pod:       "pod",
+			Container: "c",
+			Message:   fmt.Sprintf("message %d", i),
+		})
+	}
+	s.Write(ctx, batch[:5])
+	s.Write(ctx, batch[5:])
+	// Resent batch is deduplicated
+	s.Write(ctx, batch[:2])
+
+	// Two full chunks uploaded on write, the rest buffered
+	if got := len(bucket.keys("logs/" + chunksDir)); got != 2 {
+		t.Fatalf("got %d chunks before close, want 2", got)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	s = newTestStore(t, bucket, 4)
+	defer s.Close()
+
+	result, err := s.Query(ctx, storage.Query{Namespace: "b", Pagination: storage.Pagination{Limit: 2, Order: storage.OrderAsc}})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entries) != 2 || result.Entries[0].Message != "message 1" || !result.HasMore {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	stats, err := s.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.TotalEntries != 10 || len(stats.Namespaces) != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// IDs continue after the uploaded chunks
+	s.Write(ctx, storage.LogBatch{{Timestamp: base.Add(time.Hour), Namespace: "a", Pod: "pod", Container: "c", Message: "late"}})
+	e, err := s.GetByID(ctx, 11)
+	if err != nil || e.Message != "late" {
+		t.Errorf("GetByID(11) = %+v, %v", e, err)
+	}
+}
+
+// TestQueryMatchesScan checks chunk pruning and early termination against
+// a plain sort of all entries, across orders and cursors.
+func TestQueryMatchesScan(t *testing.T) {
+	s := newTestStore(t, newMemBucket(), 7)
+	defer s.Close()
+	ctx := context.Background()
+
+	// Timestamps out of ID order, as with late batches
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var all []storage.LogEntry
+	for i := 0; i < 50; i++ {
+		e := storage.LogEntry{
+			Timestamp: base.Add(time.Duration((i*37)%50) * time.Second),
+			Namespace: "ns",
+			Pod:       fmt.Sprintf("pod-%d", i%3),
+			Container: "c",
+			Severity:  storage.Severity(i % 6),
+			Message:   fmt.Sprintf("Request %d done", i),
+		}
+		s.Write(ctx, storage.LogBatch{e})
+		e.ID = int64(i + 1)
+		all = append(all, e)
+	}
+
+	queries := map[string]storage.Query{
+		"id desc":          {},
+		"id asc":           {Pagination: storage.Pagination{Order: storage.OrderAsc}},
+		"timestamp desc":   {Pagination: storage.Pagination{OrderBy: storage.OrderByTimestamp}},
+		"timestamp asc":    {Pagination: storage.Pagination{OrderBy: storage.OrderByTimestamp, Order: storage.OrderAsc}},
+		"pod and severity": {Pod: "pod-1", MinSeverity: storage.SeverityWarn},
+		"search":           {Search: "request AND done"},
+		"time range":       {StartTime: base.Add(10 * time.Second), EndTime: base.Add(20 * time.Second), Pagination: storage.Pagination{OrderBy: storage.OrderByTimestamp}},
+	}
+
+	for name, q := range queries {
+		t.Run(name, func(t *testing.T) {
+			m := newMatcher(&q)
+			var want []int64
+			for i := range all {
+				if m.match(&all[i]) {
+					want = append(want, all[i].ID)
+				}
+			}
+			sort.Slice(want, func(i, j int) bool {
+				return m.less(&all[want[i]-1], &all[want[j]-1])
+			})
+
+			// Page through with the store's cursors
+			var got []int64
+			q.Pagination.Limit = 6
+			for page := 0; page < 20; page++ {
+				result, err := s.Query(ctx, q)
+				if err != nil {
+					t.Fatalf("Query failed: %v", err)
+				}
+				for _, e := range result.Entries {
+					got = append(got, e.ID)
+				}
+				if !result.HasMore {
+					break
+				}
+				last := result.Entries[len(result.Entries)-1]
+				if q.Pagination.Order == storage.OrderAsc {
+					q.Pagination.AfterID, q.Pagination.AfterTimestamp = last.ID, last.Timestamp
+				} else {
+					q.Pagination.BeforeID, q.Pagination.BeforeTimestamp = last.ID, last.Timestamp
+				}
+			}
+
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("got  %v\nwant %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDeleteRewritesChunks(t *testing.T) {
+	bucket := newMemBucket()
+	s := newTestStore(t, bucket, 4)
+	defer s.Close()
+	ctx := context.Background()
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var batch storage.LogBatch
+	for i := 0; i < 10; i++ {
+		batch = append(batch, storage.LogEntry{
+			Timestamp: base.Add(time.Duration(i) * time.Hour),
+			Namespace: "ns", Pod: "pod", Container: "c",
+			Message: fmt.Sprintf("m%d", i),
+		})
+	}
+	s.Write(ctx, batch)
+
+	// Drops chunk 1-4 and entry 5 from chunk 5-8; 9 and 10 stay buffered
+	deleted, err := s.Delete(ctx, base.Add(5*time.Hour))
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if deleted != 5 {
+		t.Errorf("Delete returned %d, want 5", deleted)
+	}
+
+	want := []string{"logs/chunks/00000000000000000006-00000000000000000008-3.ndjson.gz"}
+	if got := bucket.keys("logs/" + chunksDir); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("chunks = %v, want %v", got, want)
+	}
+
+	result, err := s.Query(ctx, storage.Query{Pagination: storage.Pagination{Order: storage.OrderAsc}})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entries) != 5 || result.Entries[0].Message != "m5" {
+		t.Errorf("unexpected entries after delete: %+v", result.Entries)
+	}
+}
+
+func TestReopenResolvesInterruptedRewrite(t *testing.T) {
+	bucket := newMemBucket()
+	s := newTestStore(t, bucket, 4)
+	ctx := context.Background()
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var batch storage.LogBatch
+	for i := 0; i < 4; i++ {
+		batch = append(batch, storage.LogEntry{
+			Timestamp: base.Add(time.Duration(i) * time.Hour),
+			Namespace: "ns", Pod: "pod", Container: "c",
+			Message: fmt.Sprintf("m%d", i),
+		})
+	}
+	s.Write(ctx, batch)
+	s.Close()
+
+	// Simulate a crash after a rewritten chunk was uploaded but before
+	// the original was deleted
+	kept := slices.Clone(batch[2:])
+	kept[0].ID, kept[1].ID = 3, 4
+	data, meta, err := encodeChunk(kept)
+	if err != nil {
+		t.Fatalf("encodeChunk failed: %v", err)
+	}
+	idx, _ := json.Marshal(meta)
+	bucket.Put(ctx, chunkKey("logs/", meta.Name), data)
+	bucket.Put(ctx, indexKey("logs/", meta.Name), idx)
+
+	s = newTestStore(t, bucket, 4)
+	defer s.Close()
+
+	stats, err := s.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.TotalEntries != 2 {
+		t.Errorf("TotalEntries = %d, want 2", stats.TotalEntries)
+	}
+	if got := bucket.keys("logs/" + indexDir); len(got) != 1 || !strings.Contains(got[0], meta.Name) {
+		t.Errorf("index objects = %v, want only %s", got, meta.Name)
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	bucket := newMemBucket()
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	open := func() *Store {
+		s, err := New(Config{Bucket: bucket, CacheDir: dir, ChunkEntries: 2, FlushInterval: -1})
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+		return s
+	}
+
+	s := open()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.Write(ctx, storage.LogBatch{
+		{Timestamp: base, Namespace: "ns", Pod: "pod", Container: "c", Message: "one"},
+		{Timestamp: base.Add(time.Second), Namespace: "ns", Pod: "pod", Container: "c", Message: "two"},
+	})
+	s.Close()
+
+	// A new process finds the uploaded chunk in the cache directory
+	s = open()
+	defer s.Close()
+	bucket.gets = 0
+	result, err := s.Query(ctx, storage.Query{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entries) != 2 {
+		t.Errorf("got %d entries, want 2", len(result.Entries))
+	}
+	if bucket.gets != 0 {
+		t.Errorf("fetched %d objects from the bucket, want 0", bucket.gets)
+	}
+}
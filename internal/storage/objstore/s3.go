@@ -0,0 +1,304 @@
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config configures an S3-compatible bucket.
+type S3Config struct {
+	// Endpoint is the service URL, e.g. "https://s3.eu-west-1.amazonaws.com"
+	// or "http://minio:9000". Default: AWS endpoint for Region.
+	Endpoint string
+
+	// Region used for request signing. Default: "us-east-1".
+	Region string
+
+	// Bucket name.
+	Bucket string
+
+	// Static credentials.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Optional, for temporary credentials
+
+	// PathStyle addresses the bucket as endpoint/bucket instead of
+	// bucket.endpoint. Most S3-compatible servers (MinIO, Ceph) need it.
+	PathStyle bool
+
+	// HTTPClient overrides the default client (30s timeout).
+	HTTPClient *http.Client
+}
+
+// S3Bucket is a Bucket backed by an S3-compatible object store,
+// using AWS Signature Version 4.
+type S3Bucket struct {
+	endpoint *url.URL
+	cfg      S3Config
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3Bucket creates a bucket client. It doesn't contact the server.
+func NewS3Bucket(cfg S3Config) (*S3Bucket, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("s3: bucket is required")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("s3: access key ID and secret access key are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("s3: invalid endpoint %q", cfg.Endpoint)
+	}
+
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &S3Bucket{endpoint: endpoint, cfg: cfg, client: client, now: time.Now}, nil
+}
+
+// Put implements Bucket.
+func (b *S3Bucket) Put(ctx context.Context, key string, data []byte) error {
+	resp, err := b.do(ctx, http.MethodPut, key, nil, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get implements Bucket.
+func (b *S3Bucket) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := b.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("s3: read %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// Delete implements Bucket.
+func (b *S3Bucket) Delete(ctx context.Context, key string) error {
+	resp, err := b.do(ctx, http.MethodDelete, key, nil, nil)
+	if errors.Is(err, ErrObjectNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// listResult is the ListObjectsV2 response body.
+type listResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List implements Bucket.
+func (b *S3Bucket) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := b.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result listResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("s3: decode list: %w", err)
+		}
+
+		for _, c := range result.Contents {
+			keys = append(keys, c.Key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// do sends a signed request for key and returns the response if it
+// succeeded. Non-2xx responses are turned into errors.
+func (b *S3Bucket) do(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Response, error) {
+	u := *b.endpoint
+	path := strings.TrimSuffix(u.Path, "/")
+	if b.cfg.PathStyle {
+		path += "/" + b.cfg.Bucket
+	} else {
+		u.Host = b.cfg.Bucket + "." + u.Host
+	}
+	u.Path = path + "/" + key
+	u.RawPath = escapePath(u.Path)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("s3: %w", err)
+	}
+	req.ContentLength = int64(len(body))
+	b.sign(req, body)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3: %s %s: %w", method, key, err)
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && key != "" {
+		return nil, fmt.Errorf("s3: %s: %w", key, ErrObjectNotFound)
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("s3: %s %s: %s: %s", method, key, resp.Status, strings.TrimSpace(string(msg)))
+}
+
+// sign adds AWS Signature Version 4 headers to req.
+func (b *S3Bucket) sign(req *http.Request, body []byte) {
+	now := b.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	payloadHash := sha256.Sum256(body)
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", hex.EncodeToString(payloadHash[:]))
+	if b.cfg.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", b.cfg.SessionToken)
+	}
+
+	scope := date + "/" + b.cfg.Region + "/s3/aws4_request"
+	signedHeaders, canonicalRequest := canonicalRequest(req)
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex(canonicalRequest)
+
+	key := hmacSHA256([]byte("AWS4"+b.cfg.SecretAccessKey), date)
+	key = hmacSHA256(key, b.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+b.cfg.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// canonicalRequest builds the SigV4 canonical request, signing the host
+// header and all x-amz-* and range headers.
+func canonicalRequest(req *http.Request) (signedHeaders, canonical string) {
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "range" || lower == "content-type" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString(req.Method + "\n")
+	sb.WriteString(req.URL.EscapedPath() + "\n")
+	sb.WriteString(req.URL.RawQuery + "\n")
+	for _, name := range names {
+		sb.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders = strings.Join(names, ";")
+	sb.WriteString("\n" + signedHeaders + "\n")
+	sb.WriteString(req.Header.Get("x-amz-content-sha256"))
+
+	return signedHeaders, sb.String()
+}
+
+// canonicalQuery encodes query sorted by key with SigV4 escaping.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, uriEscape(k, true)+"="+uriEscape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath escapes each segment of an object path.
+func escapePath(path string) string {
+	return uriEscape(path, false)
+}
+
+// uriEscape percent-encodes everything except unreserved characters
+// (and "/" unless encodeSlash), as SigV4 requires.
+func uriEscape(s string, encodeSlash bool) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			sb.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
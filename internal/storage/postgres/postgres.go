@@ -0,0 +1,783 @@
+// Package postgres implements storage.Store on PostgreSQL, for larger
+// clusters that prefer a shared external database to a local SQLite file.
+//
+// Entries live in a single logs table. Full-text search uses a generated
+// tsvector column with websearch_to_tsquery syntax (words, "quoted
+// phrases", OR and -negation) rather than FTS5 syntax. Attributes are
+// stored as JSONB. Requires PostgreSQL 12 or later.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+const (
+	defaultMaxOpenConns = 10
+	defaultQueryLimit   = 100
+)
+
+// Config holds PostgreSQL store configuration.
+type Config struct {
+	// DSN is a lib/pq connection string, e.g.
+	// "postgres://kubelogs:secret@db:5432/kubelogs?sslmode=require".
+	DSN string
+
+	// MaxOpenConns bounds the connection pool. Default: 10.
+	MaxOpenConns int
+}
+
+// Store implements storage.Store using PostgreSQL.
+type Store struct {
+	db *sql.DB
+
+	mu     sync.Mutex // Protects closed
+	closed bool
+
+	// writeMu serializes inserts so IDs become visible in order, which
+	// live tailing by ID relies on. Several servers writing to one
+	// database can still commit IDs out of order.
+	writeMu sync.Mutex
+
+	written storage.WriteSignal
+}
+
+func init() {
+	// Options: "dsn" and "max_open_conns".
+	storage.Register("postgres", func(opts storage.Options) (storage.Store, error) {
+		cfg := Config{DSN: opts["dsn"]}
+		if v := opts["max_open_conns"]; v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("postgres: invalid max_open_conns %q", v)
+			}
+			cfg.MaxOpenConns = n
+		}
+		return New(cfg)
+	})
+}
+
+// New connects to the database and creates the schema if needed.
+func New(cfg Config) (*Store, error) {
+	if cfg.DSN == "" {
+		return nil, errors.New("postgres: DSN is required")
+	}
+	if cfg.MaxOpenConns <= 0 {
+		cfg.MaxOpenConns = defaultMaxOpenConns
+	}
+
+	db, err := sql.Open("postgres", cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxOpenConns)
+
+	if err := createSchema(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// createSchema runs schemaSQL under an advisory lock, so servers starting
+// together don't race creating the same objects.
+func createSchema(db *sql.DB) error {
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('kubelogs_schema'))`); err != nil {
+		return fmt.Errorf("lock schema: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, schemaSQL); err != nil {
+		return fmt.Errorf("create schema: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// checkOpen returns ErrStorageClosed after Close.
+func (s *Store) checkOpen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return storage.ErrStorageClosed
+	}
+	return nil
+}
+
+// Written implements storage.Notifier. Writes by other servers sharing
+// the database aren't announced.
+func (s *Store) Written() <-chan struct{} {
+	return s.written.Written()
+}
+
+// Write implements storage.Store. The batch is inserted with one
+// statement; entries already stored are skipped.
+func (s *Store) Write(ctx context.Context, entries storage.LogBatch) (int, error) {
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	if err := s.checkOpen(); err != nil {
+		return 0, err
+	}
+
+	n := len(entries)
+	var (
+		timestamps = make([]int64, n)
+		clusters   = make([]string, n)
+		namespaces = make([]string, n)
+		pods       = make([]string, n)
+		containers = make([]string, n)
+		severities = make([]int64, n)
+		messages   = make([]string, n)
+		attrs      = make([]string, n)
+		hashes     = make([]int64, n)
+		expires    = make([]int64, n) // 0 follows retention
+	)
+	for i, e := range entries {
+		timestamps[i] = e.Timestamp.UnixNano()
+		clusters[i] = e.Cluster
+		namespaces[i] = e.Namespace
+		pods[i] = e.Pod
+		containers[i] = e.Container
+		severities[i] = int64(e.Severity)
+		messages[i] = pgText(e.Message)
+		if len(e.Attributes) > 0 {
+			clean := make(map[string]string, len(e.Attributes))
+			for k, v := range e.Attributes {
+				clean[pgText(k)] = pgText(v)
+			}
+			b, _ := json.Marshal(clean)
+			attrs[i] = string(b)
+		}
+		hashes[i] = storage.DedupHash(&e)
+		if !e.ExpiresAt.IsZero() {
+			expires[i] = e.ExpiresAt.UnixNano()
+		}
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO logs (timestamp, cluster, namespace, pod, container, severity, message, attributes, dedup_hash, expires_at)
+		SELECT ts, cl, ns, pod, c, sev, msg, NULLIF(attrs, '')::jsonb, h, NULLIF(exp, 0)
+		FROM unnest($1::bigint[], $2::text[], $3::text[], $4::text[], $5::text[], $6::smallint[], $7::text[], $8::text[], $9::bigint[], $10::bigint[])
+			WITH ORDINALITY AS t(ts, cl, ns, pod, c, sev, msg, attrs, h, exp, ord)
+		ORDER BY ord
+		ON CONFLICT (dedup_hash) DO NOTHING
+	`,
+		pq.Array(timestamps),
+		pq.Array(clusters),
+		pq.Array(namespaces),
+		pq.Array(pods),
+		pq.Array(containers),
+		pq.Array(severities),
+		pq.Array(messages),
+		pq.Array(attrs),
+		pq.Array(hashes),
+		pq.Array(expires),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("insert: %w", err)
+	}
+	s.written.Publish()
+	return n, nil
+}
+
+// pgText makes s storable in a TEXT or JSONB value, which can't hold NUL
+// characters or invalid UTF-8.
+func pgText(s string) string {
+	s = strings.ToValidUTF8(s, "�")
+	return strings.ReplaceAll(s, "\x00", "")
+}
+
+// Query implements storage.Store.
+func (s *Store) Query(ctx context.Context, q storage.Query) (*storage.QueryResult, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	limit := q.Pagination.Limit
+	if limit <= 0 {
+		limit = defaultQueryLimit
+	}
+
+	query, args := buildQuery(q)
+	entries, err := s.queryEntries(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &storage.QueryResult{
+		TotalEstimate: -1,
+	}
+	if q.Sampled() {
+		result.SampleRate = q.Sample
+	}
+	if q.Count {
+		n, err := s.countMatches(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		result.TotalEstimate = min(n, storage.MaxCount)
+		result.TotalCapped = n > storage.MaxCount
+	}
+	if len(entries) > limit {
+		result.HasMore = true
+		result.NextCursor = entries[limit].ID
+		if q.Pagination.OrderBy == storage.OrderByTimestamp {
+			result.NextCursorTimestamp = entries[limit].Timestamp
+		}
+		entries = entries[:limit]
+	}
+	result.Entries = entries
+
+	return result, nil
+}
+
+// countMatches counts the entries matching q's filters on every page, up
+// to storage.MaxCount+1 so the caller can tell the count was capped.
+func (s *Store) countMatches(ctx context.Context, q storage.Query) (int64, error) {
+	var b queryBuilder
+	b.sql.WriteString("SELECT COUNT(*) FROM (SELECT 1 FROM logs")
+	q.Pagination = storage.Pagination{MaxID: q.Pagination.MaxID}
+	b.where(q)
+	fmt.Fprintf(&b.sql, " LIMIT %d) AS matches", storage.MaxCount+1)
+
+	var n int64
+	if err := s.db.QueryRowContext(ctx, b.sql.String(), b.args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count: %w", err)
+	}
+	return n, nil
+}
+
+const selectColumns = `SELECT id, timestamp, cluster, namespace, pod, container, severity, message, attributes FROM logs`
+
+// queryEntries runs a query selecting selectColumns.
+func (s *Store) queryEntries(ctx context.Context, query string, args ...any) ([]storage.LogEntry, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]storage.LogEntry, 0)
+	for rows.Next() {
+		var e storage.LogEntry
+		var ts int64
+		var attrs []byte
+
+		if err := rows.Scan(&e.ID, &ts, &e.Cluster, &e.Namespace, &e.Pod, &e.Container, &e.Severity, &e.Message, &attrs); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		e.Timestamp = time.Unix(0, ts)
+		if len(attrs) > 0 {
+			json.Unmarshal(attrs, &e.Attributes)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	return entries, nil
+}
+
+// GetByID implements storage.Store.
+func (s *Store) GetByID(ctx context.Context, id int64) (*storage.LogEntry, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.queryEntries(ctx, selectColumns+` WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, storage.ErrNotFound
+	}
+	return &entries[0], nil
+}
+
+// GetByIDs implements storage.Store.
+func (s *Store) GetByIDs(ctx context.Context, ids []int64) ([]storage.LogEntry, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []storage.LogEntry{}, nil
+	}
+
+	rows, err := s.queryEntries(ctx, selectColumns+` WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[int64]storage.LogEntry, len(rows))
+	for _, e := range rows {
+		found[e.ID] = e
+	}
+
+	entries := make([]storage.LogEntry, 0, len(found))
+	for _, id := range ids {
+		if e, ok := found[id]; ok {
+			entries = append(entries, e)
+			delete(found, id)
+		}
+	}
+	return entries, nil
+}
+
+// Delete implements storage.Store.
+func (s *Store) Delete(ctx context.Context, olderThan time.Time) (int64, error) {
+	if err := s.checkOpen(); err != nil {
+		return 0, err
+	}
+
+	result, err := s.db.ExecContext(ctx, `DELETE FROM logs WHERE timestamp < $1`, olderThan.UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("delete: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// DeleteCluster implements storage.ClusterDeleter.
+func (s *Store) DeleteCluster(ctx context.Context, cluster string, olderThan time.Time) (int64, error) {
+	if err := s.checkOpen(); err != nil {
+		return 0, err
+	}
+
+	result, err := s.db.ExecContext(ctx, `DELETE FROM logs WHERE cluster = $1 AND timestamp < $2`, cluster, olderThan.UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("delete: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// DeleteExpired implements storage.ExpiryDeleter.
+func (s *Store) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	if err := s.checkOpen(); err != nil {
+		return 0, err
+	}
+
+	result, err := s.db.ExecContext(ctx, `DELETE FROM logs WHERE expires_at <= $1`, now.UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("delete: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// DeleteByQuery implements storage.QueryDeleter.
+func (s *Store) DeleteByQuery(ctx context.Context, q storage.Query) (int64, error) {
+	if err := s.checkOpen(); err != nil {
+		return 0, err
+	}
+	if !q.HasFilter() {
+		return 0, storage.ErrNoFilter
+	}
+
+	var b queryBuilder
+	b.sql.WriteString("DELETE FROM logs")
+	q.Pagination = storage.Pagination{}
+	b.where(q)
+
+	result, err := s.db.ExecContext(ctx, b.sql.String(), b.args...)
+	if err != nil {
+		return 0, fmt.Errorf("delete: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// Stats implements storage.Store. DiskSizeBytes includes indexes.
+func (s *Store) Stats(ctx context.Context) (*storage.Stats, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	stats := &storage.Stats{}
+	var oldest, newest sql.NullInt64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), MIN(timestamp), MAX(timestamp), pg_total_relation_size('logs') FROM logs
+	`).Scan(&stats.TotalEntries, &oldest, &newest, &stats.DiskSizeBytes)
+	if err != nil {
+		return nil, fmt.Errorf("count: %w", err)
+	}
+	if oldest.Valid {
+		stats.OldestEntry = time.Unix(0, oldest.Int64)
+	}
+	if newest.Valid {
+		stats.NewestEntry = time.Unix(0, newest.Int64)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT namespace, COUNT(*), COALESCE(SUM(octet_length(message)), 0) FROM logs
+		GROUP BY namespace
+		ORDER BY 3 DESC, namespace
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("namespace usage: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var u storage.NamespaceUsage
+		if err := rows.Scan(&u.Namespace, &u.Entries, &u.Bytes); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		stats.Namespaces = append(stats.Namespaces, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+
+	return stats, nil
+}
+
+// ListNamespaces returns distinct namespace values.
+func (s *Store) ListNamespaces(ctx context.Context) ([]string, error) {
+	return s.distinct(ctx, `SELECT DISTINCT namespace FROM logs ORDER BY namespace`)
+}
+
+// ListClusters returns distinct non-empty cluster values.
+func (s *Store) ListClusters(ctx context.Context) ([]string, error) {
+	return s.distinct(ctx, `SELECT DISTINCT cluster FROM logs WHERE cluster != '' ORDER BY cluster`)
+}
+
+// ListContainers returns distinct container values.
+func (s *Store) ListContainers(ctx context.Context) ([]string, error) {
+	return s.distinct(ctx, `SELECT DISTINCT container FROM logs ORDER BY container`)
+}
+
+// ListPods returns distinct pod values in namespace, or in all
+// namespaces if it's empty.
+func (s *Store) ListPods(ctx context.Context, namespace string) ([]string, error) {
+	if namespace == "" {
+		return s.distinct(ctx, `SELECT DISTINCT pod FROM logs ORDER BY pod`)
+	}
+	return s.distinct(ctx, `SELECT DISTINCT pod FROM logs WHERE namespace = $1 ORDER BY pod`, namespace)
+}
+
+// distinct runs a query returning one text column.
+func (s *Store) distinct(ctx context.Context, query string, args ...any) ([]string, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	values := make([]string, 0)
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+// Close implements storage.Store.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	// Wait for an in-flight insert
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.db.Close()
+}
+
+// Histogram implements storage.Aggregator.
+func (s *Store) Histogram(ctx context.Context, q storage.Query, interval time.Duration) ([]storage.HistogramBucket, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("histogram interval must be positive, got %v", interval)
+	}
+
+	var b queryBuilder
+	width := b.arg(int64(interval))
+	b.sql.WriteString("SELECT timestamp / " + width + " * " + width + ", severity, COUNT(*) FROM logs")
+	q.Pagination = storage.Pagination{}
+	b.where(q)
+	b.sql.WriteString(" GROUP BY 1, 2 ORDER BY 1, 2")
+
+	rows, err := s.db.QueryContext(ctx, b.sql.String(), b.args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := make([]storage.HistogramBucket, 0)
+	for rows.Next() {
+		var bucket storage.HistogramBucket
+		var start, severity int64
+		if err := rows.Scan(&start, &severity, &bucket.Count); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		bucket.Start = time.Unix(0, start)
+		bucket.Severity = storage.Severity(severity)
+		buckets = append(buckets, bucket)
+	}
+	return buckets, rows.Err()
+}
+
+// Aggregate implements storage.GroupAggregator.
+func (s *Store) Aggregate(ctx context.Context, q storage.Query, a storage.Aggregation) ([]storage.AggregateGroup, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+
+	query, args := buildAggregate(q, a)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	groups := make([]storage.AggregateGroup, 0)
+	for rows.Next() {
+		var start int64
+		var vmin, vmax sql.NullFloat64
+		g := storage.AggregateGroup{Keys: make([]string, len(a.GroupBy))}
+		dest := []any{&start}
+		for i := range g.Keys {
+			dest = append(dest, &g.Keys[i])
+		}
+		dest = append(dest, &g.Count, &g.ValueCount, &g.Sum, &vmin, &vmax)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		if a.Interval > 0 {
+			g.Start = time.Unix(0, start)
+		}
+		g.Min, g.Max = vmin.Float64, vmax.Float64
+		for i, f := range a.GroupBy {
+			if f == storage.GroupBySeverity {
+				n, _ := strconv.Atoi(g.Keys[i])
+				g.Keys[i] = storage.Severity(n).String()
+			}
+		}
+		groups = append(groups, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	storage.SortGroups(groups)
+	return groups, nil
+}
+
+// numericValue matches attribute values Aggregate summarizes.
+const numericValue = `^\s*[-+]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][-+]?[0-9]+)?\s*$`
+
+// buildAggregate constructs the parameterized SQL of an aggregation.
+// The inner select picks the bucket, keys and numeric value of each
+// entry; the outer one groups them.
+func buildAggregate(q storage.Query, a storage.Aggregation) (string, []any) {
+	var b queryBuilder
+
+	b.sql.WriteString("SELECT b")
+	for i := range a.GroupBy {
+		b.sql.WriteString(", k" + strconv.Itoa(i))
+	}
+	b.sql.WriteString(", COUNT(*), COUNT(v), COALESCE(SUM(v), 0), MIN(v), MAX(v) FROM (SELECT ")
+
+	if a.Interval > 0 {
+		width := b.arg(int64(a.Interval))
+		b.sql.WriteString("timestamp / " + width + " * " + width + " AS b")
+	} else {
+		b.sql.WriteString("0 AS b")
+	}
+	keys := []string{"b"}
+	for i, f := range a.GroupBy {
+		k := "k" + strconv.Itoa(i)
+		if attr, ok := storage.GroupAttr(f); ok {
+			b.sql.WriteString(", COALESCE(attributes->>" + b.arg(attr) + ", '') AS " + k)
+		} else {
+			b.sql.WriteString(", " + f + "::text AS " + k)
+		}
+		keys = append(keys, k)
+	}
+	if a.Value != "" {
+		value := "attributes->>" + b.arg(a.Value)
+		b.sql.WriteString(", CASE WHEN " + value + " ~ '" + numericValue + "' THEN (" + value + ")::double precision END AS v")
+	} else {
+		b.sql.WriteString(", NULL::double precision AS v")
+	}
+	b.sql.WriteString(" FROM logs")
+
+	q.Pagination = storage.Pagination{}
+	b.where(q)
+	b.sql.WriteString(") a GROUP BY " + strings.Join(keys, ", "))
+
+	return b.sql.String(), b.args
+}
+
+// queryBuilder accumulates SQL with numbered placeholders.
+type queryBuilder struct {
+	sql  strings.Builder
+	args []any
+}
+
+// arg adds a parameter and returns its placeholder.
+func (b *queryBuilder) arg(v any) string {
+	b.args = append(b.args, v)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+// buildQuery constructs a parameterized SQL query from Query.
+func buildQuery(q storage.Query) (string, []any) {
+	var b queryBuilder
+
+	b.sql.WriteString(selectColumns)
+	b.where(q)
+	if q.Sampled() {
+		b.sql.WriteString(" AND random() < " + b.arg(q.Sample))
+	}
+
+	byTimestamp := q.Pagination.OrderBy == storage.OrderByTimestamp
+	switch {
+	case byTimestamp && q.Pagination.Order == storage.OrderAsc:
+		b.sql.WriteString(" ORDER BY timestamp ASC, id ASC")
+	case byTimestamp:
+		b.sql.WriteString(" ORDER BY timestamp DESC, id DESC")
+	case q.Pagination.Order == storage.OrderAsc:
+		b.sql.WriteString(" ORDER BY id ASC")
+	default:
+		b.sql.WriteString(" ORDER BY id DESC")
+	}
+
+	limit := q.Pagination.Limit
+	if limit <= 0 {
+		limit = defaultQueryLimit
+	}
+	fmt.Fprintf(&b.sql, " LIMIT %d", limit+1)
+
+	return b.sql.String(), b.args
+}
+
+// attrTerm returns the condition matching attribute term t.
+func (b *queryBuilder) attrTerm(t storage.AttrTerm) string {
+	value := "attributes->>" + b.arg(t.Key)
+	if t.Op == storage.AttrExists {
+		return value + " IS NOT NULL"
+	}
+
+	var cond string
+	if t.IsGlob() {
+		cond = value + " LIKE " + b.arg(likeEscaper.Replace(t.Value))
+	} else {
+		cond = value + " = " + b.arg(t.Value)
+	}
+	if t.Op == storage.AttrNotEqual {
+		// Absent attributes extract as NULL
+		return "NOT COALESCE(" + cond + ", false)"
+	}
+	return cond
+}
+
+// likeEscaper turns an attribute term's * wildcards into a LIKE
+// pattern, escaping the LIKE metacharacters.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`, "*", "%")
+
+// where writes the WHERE clause selecting the entries matching q's
+// filters and cursor.
+func (b *queryBuilder) where(q storage.Query) {
+	b.sql.WriteString(" WHERE true")
+
+	if !q.StartTime.IsZero() {
+		b.sql.WriteString(" AND timestamp >= " + b.arg(q.StartTime.UnixNano()))
+	}
+	if !q.EndTime.IsZero() {
+		b.sql.WriteString(" AND timestamp < " + b.arg(q.EndTime.UnixNano()))
+	}
+
+	if q.Search != "" {
+		b.sql.WriteString(" AND search @@ websearch_to_tsquery('simple', " + b.arg(q.Search) + ")")
+	}
+
+	if q.Cluster != "" {
+		b.sql.WriteString(" AND cluster = " + b.arg(q.Cluster))
+	}
+	if q.Namespace != "" {
+		b.sql.WriteString(" AND namespace = " + b.arg(q.Namespace))
+	}
+	if q.Pod != "" {
+		b.sql.WriteString(" AND pod = " + b.arg(q.Pod))
+	}
+	if q.Container != "" {
+		b.sql.WriteString(" AND container = " + b.arg(q.Container))
+	}
+
+	if q.MinSeverity > storage.SeverityUnknown {
+		b.sql.WriteString(" AND severity >= " + b.arg(int64(q.MinSeverity)))
+	}
+
+	// One containment test covers every attribute filter and can use
+	// the GIN index
+	if len(q.Attributes) > 0 {
+		filter, _ := json.Marshal(q.Attributes)
+		b.sql.WriteString(" AND attributes @> " + b.arg(string(filter)) + "::jsonb")
+	}
+	for _, expr := range q.AttrExprs {
+		if len(expr) == 0 {
+			continue
+		}
+		terms := make([]string, len(expr))
+		for i, t := range expr {
+			terms[i] = b.attrTerm(t)
+		}
+		b.sql.WriteString(" AND (" + strings.Join(terms, " OR ") + ")")
+	}
+
+	byTimestamp := q.Pagination.OrderBy == storage.OrderByTimestamp
+
+	// Composite (timestamp, id) keyset when ordering by timestamp
+	if byTimestamp && !q.Pagination.AfterTimestamp.IsZero() {
+		b.sql.WriteString(" AND (timestamp, id) > (" + b.arg(q.Pagination.AfterTimestamp.UnixNano()) + ", " + b.arg(q.Pagination.AfterID) + ")")
+	} else if q.Pagination.AfterID > 0 {
+		b.sql.WriteString(" AND id > " + b.arg(q.Pagination.AfterID))
+	}
+	if byTimestamp && !q.Pagination.BeforeTimestamp.IsZero() {
+		beforeID := q.Pagination.BeforeID
+		if beforeID <= 0 {
+			beforeID = math.MaxInt64
+		}
+		b.sql.WriteString(" AND (timestamp, id) < (" + b.arg(q.Pagination.BeforeTimestamp.UnixNano()) + ", " + b.arg(beforeID) + ")")
+	} else if q.Pagination.BeforeID > 0 {
+		b.sql.WriteString(" AND id < " + b.arg(q.Pagination.BeforeID))
+	}
+	if q.Pagination.MaxID > 0 {
+		b.sql.WriteString(" AND id <= " + b.arg(q.Pagination.MaxID))
+	}
+}
@@ -0,0 +1,195 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// testDSN returns the database for tests that need one, from
+// KUBELOGS_TEST_POSTGRES_DSN, or skips the test if it isn't set.
+func testDSN(t *testing.T) string {
+	t.Helper()
+	dsn := os.Getenv("KUBELOGS_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("KUBELOGS_TEST_POSTGRES_DSN not set")
+	}
+	return dsn
+}
+
+// newTestStore connects to dsn and empties the logs table.
+func newTestStore(t *testing.T, dsn string) *Store {
+	t.Helper()
+	s, err := New(Config{DSN: dsn})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	if _, err := s.db.Exec(`TRUNCATE logs RESTART IDENTITY`); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	return s
+}
+
+func TestStore(t *testing.T) {
+	dsn := testDSN(t)
+	storage.StoreTestSuite(t, func() (storage.Store, func()) {
+		s := newTestStore(t, dsn)
+		return s, func() { s.Close() }
+	})
+}
+
+func TestSearchAndAttributes(t *testing.T) {
+	s := newTestStore(t, testDSN(t))
+	defer s.Close()
+	ctx := context.Background()
+
+	now := time.Now()
+	_, err := s.Write(ctx, storage.LogBatch{
+		{Timestamp: now, Namespace: "default", Pod: "api", Container: "app", Message: "Connection refused to database", Attributes: map[string]string{"user": "alice"}},
+		{Timestamp: now.Add(time.Millisecond), Namespace: "default", Pod: "api", Container: "app", Message: "request served", Attributes: map[string]string{"user": "bob"}},
+		{Timestamp: now.Add(2 * time.Millisecond), Namespace: "default", Pod: "api", Container: "app", Message: "binary \x00 payload \xff"},
+	})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query storage.Query
+		want  []string
+	}{
+		{"word", storage.Query{Search: "refused"}, []string{"Connection refused to database"}},
+		{"case insensitive", storage.Query{Search: "CONNECTION"}, []string{"Connection refused to database"}},
+		{"phrase", storage.Query{Search: `"request served"`}, []string{"request served"}},
+		{"or", storage.Query{Search: "refused or served"}, []string{"request served", "Connection refused to database"}},
+		{"attribute", storage.Query{Attributes: map[string]string{"user": "bob"}}, []string{"request served"}},
+		{"sanitized", storage.Query{Search: "payload"}, []string{"binary  payload �"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := s.Query(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("Query: %v", err)
+			}
+			got := make([]string, 0)
+			for _, e := range result.Entries {
+				got = append(got, e.Message)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("messages = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildQuery(t *testing.T) {
+	ts := time.Unix(0, 1000)
+
+	tests := []struct {
+		name     string
+		query    storage.Query
+		contains []string
+		args     []any
+	}{
+		{
+			name:     "defaults",
+			query:    storage.Query{},
+			contains: []string{"WHERE true ORDER BY id DESC LIMIT 101"},
+			args:     nil,
+		},
+		{
+			name: "filters",
+			query: storage.Query{
+				StartTime:   ts,
+				Search:      "error",
+				Namespace:   "default",
+				MinSeverity: storage.SeverityWarn,
+				Attributes:  map[string]string{"user": "alice"},
+			},
+			contains: []string{
+				"timestamp >= $1",
+				"search @@ websearch_to_tsquery('simple', $2)",
+				"namespace = $3",
+				"severity >= $4",
+				"attributes @> $5::jsonb",
+			},
+			args: []any{int64(1000), "error", "default", int64(storage.SeverityWarn), `{"user":"alice"}`},
+		},
+		{
+			name: "attribute expressions",
+			query: storage.Query{AttrExprs: []storage.AttrExpr{
+				{{Key: "region", Value: "us_*"}, {Key: "tier", Op: storage.AttrNotEqual, Value: "gold"}},
+				{{Key: "trace_id", Op: storage.AttrExists}},
+			}},
+			contains: []string{
+				"AND (attributes->>$1 LIKE $2 OR NOT COALESCE(attributes->>$3 = $4, false))",
+				"AND (attributes->>$5 IS NOT NULL)",
+			},
+			args: []any{"region", `us\_%`, "tier", "gold", "trace_id"},
+		},
+		{
+			name: "timestamp keyset",
+			query: storage.Query{Pagination: storage.Pagination{
+				OrderBy:         storage.OrderByTimestamp,
+				Order:           storage.OrderAsc,
+				AfterTimestamp:  ts,
+				AfterID:         7,
+				BeforeTimestamp: ts.Add(time.Second),
+				Limit:           10,
+			}},
+			contains: []string{
+				"(timestamp, id) > ($1, $2)",
+				"(timestamp, id) < ($3, $4)",
+				"ORDER BY timestamp ASC, id ASC LIMIT 11",
+			},
+			args: []any{int64(1000), int64(7), ts.Add(time.Second).UnixNano(), int64(9223372036854775807)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args := buildQuery(tt.query)
+			for _, c := range tt.contains {
+				if !strings.Contains(sql, c) {
+					t.Errorf("query %q missing %q", sql, c)
+				}
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %#v, want %#v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestBuildAggregate(t *testing.T) {
+	sql, args := buildAggregate(
+		storage.Query{Namespace: "shop", Pagination: storage.Pagination{Limit: 10}},
+		storage.Aggregation{
+			GroupBy:  []string{storage.GroupBySeverity, "attr.route"},
+			Interval: time.Minute,
+			Value:    "latency_ms",
+		},
+	)
+	for _, c := range []string{
+		"SELECT b, k0, k1, COUNT(*), COUNT(v), COALESCE(SUM(v), 0), MIN(v), MAX(v) FROM (",
+		"SELECT timestamp / $1 * $1 AS b, severity::text AS k0, COALESCE(attributes->>$2, '') AS k1",
+		"CASE WHEN attributes->>$3 ~ '" + numericValue + "' THEN (attributes->>$3)::double precision END AS v",
+		"FROM logs WHERE true AND namespace = $4",
+		") a GROUP BY b, k0, k1",
+	} {
+		if !strings.Contains(sql, c) {
+			t.Errorf("query %q missing %q", sql, c)
+		}
+	}
+	if strings.Contains(sql, "LIMIT") {
+		t.Errorf("query %q has a limit", sql)
+	}
+	want := []any{int64(time.Minute), "route", "latency_ms", "shop"}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %#v, want %#v", args, want)
+	}
+}
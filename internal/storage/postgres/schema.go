@@ -0,0 +1,34 @@
+package postgres
+
+// schemaSQL creates the logs table and its indexes. Statements are
+// idempotent and run on every start.
+const schemaSQL = `
+CREATE TABLE IF NOT EXISTS logs (
+    id         BIGSERIAL PRIMARY KEY,
+    timestamp  BIGINT NOT NULL,            -- Unix nanoseconds
+    cluster    TEXT NOT NULL DEFAULT '',
+    namespace  TEXT NOT NULL,
+    pod        TEXT NOT NULL,
+    container  TEXT NOT NULL,
+    severity   SMALLINT NOT NULL DEFAULT 0,
+    message    TEXT NOT NULL,
+    attributes JSONB,
+    dedup_hash BIGINT NOT NULL,
+    expires_at BIGINT,                     -- Unix nanoseconds, NULL follows retention
+    -- Full-text search. The "simple" configuration lowercases words
+    -- without stemming or stop words, like the SQLite FTS5 tokenizer.
+    search     TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', message)) STORED
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_logs_dedup ON logs (dedup_hash);
+CREATE INDEX IF NOT EXISTS idx_logs_timestamp ON logs (timestamp, id);
+CREATE INDEX IF NOT EXISTS idx_logs_source ON logs (namespace, pod, container);
+CREATE INDEX IF NOT EXISTS idx_logs_cluster ON logs (cluster, namespace);
+CREATE INDEX IF NOT EXISTS idx_logs_severity ON logs (severity);
+CREATE INDEX IF NOT EXISTS idx_logs_search ON logs USING GIN (search);
+CREATE INDEX IF NOT EXISTS idx_logs_attributes ON logs USING GIN (attributes jsonb_path_ops);
+
+-- Added after the first release
+ALTER TABLE logs ADD COLUMN IF NOT EXISTS expires_at BIGINT;
+CREATE INDEX IF NOT EXISTS idx_logs_expires ON logs (expires_at) WHERE expires_at IS NOT NULL;
+`
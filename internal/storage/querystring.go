@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseQueryString parses the filters of a query written in the query
+// string syntax of the HTTP API, such as
+// "namespace=prod&minSeverity=5&search=timeout&attr.region=eu". It's the
+// form saved queries are kept in, and what gRPC clients send as
+// query_string. Parameters other than filters, such as limit and order,
+// are ignored.
+func ParseQueryString(s string) (Query, error) {
+	var q Query
+	params, err := url.ParseQuery(s)
+	if err != nil {
+		return q, fmt.Errorf("invalid query string: %w", err)
+	}
+	err = ParseFilters(params, &q)
+	return q, err
+}
+
+// ParseFilters sets the filters of q from query parameters:
+//
+//	cluster, namespace, pod, container  exact match
+//	workload                            attr.workload, e.g. deployment/api
+//	search                              full-text search on the message
+//	minSeverity                         0 (unknown) to 6 (fatal)
+//	startTime, endTime                  RFC 3339
+//	attr.<key>=<value>                  attribute equal to value
+//	attr.<key>!=<value>                 attribute absent or not equal
+//	attr.<key>!=                        attribute present
+//
+// Attribute values may contain * wildcards, as in attr.region=us-*.
+// Attribute parameters are ANDed; the terms of one parameter separated
+// by | are ORed, as in attr.region=us-*|attr.tier=gold.
+//
+// Other parameters are ignored. Every valid filter is set even when
+// others are invalid, so lenient callers can ignore the error, which
+// names the invalid ones.
+func ParseFilters(params url.Values, q *Query) error {
+	var errs []error
+
+	if v := params.Get("cluster"); v != "" {
+		q.Cluster = v
+	}
+	if v := params.Get("namespace"); v != "" {
+		q.Namespace = v
+	}
+	if v := params.Get("pod"); v != "" {
+		q.Pod = v
+	}
+	if v := params.Get("container"); v != "" {
+		q.Container = v
+	}
+	if v := params.Get("workload"); v != "" {
+		if q.Attributes == nil {
+			q.Attributes = make(map[string]string)
+		}
+		q.Attributes[WorkloadAttribute] = v
+	}
+	if v := params.Get("search"); v != "" {
+		q.Search = v
+	}
+	if v := params.Get("minSeverity"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 && n <= int(SeverityFatal) {
+			q.MinSeverity = Severity(n)
+		} else {
+			errs = append(errs, fmt.Errorf("invalid minSeverity %q: must be 0 to %d", v, SeverityFatal))
+		}
+	}
+
+	// Time range filtering
+	if v := params.Get("startTime"); v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			q.StartTime = t
+		} else {
+			errs = append(errs, fmt.Errorf("invalid startTime %q: must be RFC 3339", v))
+		}
+	}
+	if v := params.Get("endTime"); v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			q.EndTime = t
+		} else {
+			errs = append(errs, fmt.Errorf("invalid endTime %q: must be RFC 3339", v))
+		}
+	}
+
+	// Attribute filters, sorted for deterministic query building
+	keys := slices.Sorted(maps.Keys(params))
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "attr.") {
+			continue
+		}
+		for _, v := range params[key] {
+			expr, err := parseAttrExpr(key + "=" + v)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			// Plain equality stays in Attributes, which stores match
+			// most efficiently
+			if t := expr[0]; len(expr) == 1 && t.Op == AttrEqual && !t.IsGlob() {
+				if _, dup := q.Attributes[t.Key]; !dup {
+					if q.Attributes == nil {
+						q.Attributes = make(map[string]string)
+					}
+					q.Attributes[t.Key] = t.Value
+					continue
+				}
+			}
+			q.AttrExprs = append(q.AttrExprs, expr)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// parseAttrExpr parses an attribute parameter such as
+// "attr.region=us-*|attr.tier!=gold".
+func parseAttrExpr(s string) (AttrExpr, error) {
+	var expr AttrExpr
+	for i, term := range strings.Split(s, "|attr.") {
+		if i == 0 {
+			term = strings.TrimPrefix(term, "attr.")
+		}
+		key, value, _ := strings.Cut(term, "=")
+		t := AttrTerm{Key: key, Op: AttrEqual, Value: value}
+		if k, ok := strings.CutSuffix(key, "!"); ok {
+			t.Key, t.Op = k, AttrNotEqual
+			if value == "" {
+				t.Op = AttrExists
+			}
+		}
+		if t.Key == "" {
+			return nil, fmt.Errorf("invalid attribute filter %q: missing key", "attr."+term)
+		}
+		expr = append(expr, t)
+	}
+	return expr, nil
+}
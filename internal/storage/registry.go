@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Options configure a store opened by name. Which keys apply depends on
+// the backend, e.g. "path" for "sqlite" or "dsn" for "postgres".
+type Options map[string]string
+
+// Factory opens a store from its options.
+type Factory func(opts Options) (Store, error)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = make(map[string]Factory)
+)
+
+// Register makes a backend available to Open under name. Backend
+// packages call it from init, so a backend is compiled in by importing
+// its package. Register panics if name is registered twice or factory
+// is nil.
+func Register(name string, factory Factory) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	if factory == nil {
+		panic("storage: Register factory is nil")
+	}
+	if _, dup := backends[name]; dup {
+		panic("storage: Register called twice for backend " + name)
+	}
+	backends[name] = factory
+}
+
+// Open opens a store with the backend registered under name.
+func Open(name string, opts Options) (Store, error) {
+	backendsMu.RLock()
+	factory, ok := backends[name]
+	backendsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("storage: unknown backend %q (registered: %v)", name, Backends())
+	}
+	return factory(opts)
+}
+
+// Backends returns the sorted names of the registered backends.
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
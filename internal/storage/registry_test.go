@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	var got Options
+	Register("test-registry", func(opts Options) (Store, error) {
+		got = opts
+		return nil, errors.New("not a real store")
+	})
+
+	if !slices.Contains(Backends(), "test-registry") {
+		t.Errorf("Backends() = %v, missing test-registry", Backends())
+	}
+
+	_, err := Open("test-registry", Options{"path": "/tmp/x"})
+	if err == nil || err.Error() != "not a real store" {
+		t.Errorf("Open error = %v, want the factory's", err)
+	}
+	if got["path"] != "/tmp/x" {
+		t.Errorf("factory got options %v", got)
+	}
+
+	_, err = Open("missing", nil)
+	if err == nil || !strings.Contains(err.Error(), `unknown backend "missing"`) {
+		t.Errorf("Open of unknown backend = %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("registering a backend twice didn't panic")
+		}
+	}()
+	Register("test-registry", func(Options) (Store, error) { return nil, nil })
+}
@@ -2,11 +2,22 @@ package remote
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+	_ "google.golang.org/grpc/encoding/gzip" // Registers the gzip compressor
 	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/status"
 
@@ -18,25 +29,120 @@ import (
 type Client struct {
 	conn   *grpc.ClientConn
 	client storagepb.StorageServiceClient
+	admin  storagepb.AdminServiceClient
+
+	writeDelay atomic.Int64      // Delay asked for by the last write reply
+	writeOpts  []grpc.CallOption // Compression of writes
+
+	// Severities of the entries accepted by the last successful write
+	severities atomic.Pointer[map[storage.Severity]int64]
+
+	// Batches are written over one WriteStream, opened on first use and
+	// again after an error. Writes are unary once a server turns out not
+	// to have it
+	streamMu     sync.Mutex
+	stream       storagepb.StorageService_WriteStreamClient
+	streamCancel context.CancelFunc
+	unaryWrites  atomic.Bool
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*clientOptions)
+
+type clientOptions struct {
+	tls         *tls.Config
+	token       string
+	compression string
+}
+
+// WithTLS encrypts the connection using cfg, e.g. from tlsconfig.Client.
+// Without it the connection is plaintext.
+func WithTLS(cfg *tls.Config) ClientOption {
+	return func(o *clientOptions) {
+		o.tls = cfg
+	}
+}
+
+// WithToken authenticates to servers requiring a token (KUBELOGS_GRPC_TOKEN,
+// a collector token or KUBELOGS_GRPC_ADMIN_TOKEN) by sending it with every
+// call.
+func WithToken(token string) ClientOption {
+	return func(o *clientOptions) {
+		o.token = token
+	}
+}
+
+// WithCompression compresses the batches sent by Write with the named
+// gRPC compressor, e.g. "gzip". Log messages compress well, so this cuts
+// the traffic of collectors at the cost of some CPU. The server must have
+// the compressor registered, as kubelogs servers do for gzip. "" and
+// "none" don't compress.
+func WithCompression(name string) ClientOption {
+	return func(o *clientOptions) {
+		o.compression = name
+	}
+}
+
+// TokenCredentials sends token as "authorization: Bearer <token>"
+// metadata with every call, over plaintext connections too.
+func TokenCredentials(token string) credentials.PerRPCCredentials {
+	return tokenCredentials(token)
+}
+
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return false
 }
 
 // NewClient creates a new remote storage client.
-func NewClient(addr string) (*Client, error) {
-	conn, err := grpc.NewClient(addr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+func NewClient(addr string, opts ...ClientOption) (*Client, error) {
+	var o clientOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	creds := insecure.NewCredentials()
+	if o.tls != nil {
+		creds = credentials.NewTLS(o.tls)
+	}
+
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
 		grpc.WithKeepaliveParams(keepalive.ClientParameters{
 			Time:                10 * time.Second, // Ping server every 10s if idle
 			Timeout:             5 * time.Second,  // Wait 5s for ping ack
 			PermitWithoutStream: true,             // Send pings even with no active RPCs
 		}),
-	)
+	}
+	if o.token != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(TokenCredentials(o.token)))
+	}
+
+	var writeOpts []grpc.CallOption
+	switch o.compression {
+	case "", "none":
+	default:
+		if encoding.GetCompressor(o.compression) == nil {
+			return nil, fmt.Errorf("unsupported compression %q", o.compression)
+		}
+		writeOpts = append(writeOpts, grpc.UseCompressor(o.compression))
+	}
+
+	conn, err := grpc.NewClient(addr, dialOpts...)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Client{
-		conn:   conn,
-		client: storagepb.NewStorageServiceClient(conn),
+		conn:      conn,
+		client:    storagepb.NewStorageServiceClient(conn),
+		admin:     storagepb.NewAdminServiceClient(conn),
+		writeOpts: writeOpts,
 	}, nil
 }
 
@@ -51,30 +157,130 @@ func (c *Client) Write(ctx context.Context, entries storage.LogBatch) (int, erro
 		pbEntries[i] = toProtoEntry(e)
 	}
 
-	resp, err := c.client.Write(writeCtx, &storagepb.WriteRequest{Entries: pbEntries})
+	req := &storagepb.WriteRequest{Entries: pbEntries}
+	var resp *storagepb.WriteResponse
+	var err error
+	if !c.unaryWrites.Load() {
+		resp, err = c.writeStream(writeCtx, req)
+		if status.Code(err) == codes.Unimplemented {
+			slog.Info("storage server has no write stream, using unary writes")
+			c.unaryWrites.Store(true)
+		}
+	}
+	if c.unaryWrites.Load() {
+		resp, err = c.client.Write(writeCtx, req, c.writeOpts...)
+	}
 	if err != nil {
+		c.writeDelay.Store(int64(retryDelay(err)))
 		return 0, err
 	}
 
+	c.writeDelay.Store(int64(time.Duration(resp.RetryAfterMillis) * time.Millisecond))
+	var severities map[storage.Severity]int64
+	if resp.SeverityCounts != nil {
+		severities = make(map[storage.Severity]int64, len(resp.SeverityCounts))
+		for sev, n := range resp.SeverityCounts {
+			severities[storage.Severity(sev)] = n
+		}
+	}
+	c.severities.Store(&severities)
 	return int(resp.Count), nil
 }
 
+// WrittenSeverities implements storage.SeverityCounter. It is nil after
+// writes to servers that don't count severities.
+func (c *Client) WrittenSeverities() map[storage.Severity]int64 {
+	if p := c.severities.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// writeStream sends req over the write stream and waits for its
+// acknowledgement. The stream is closed if that fails or ctx ends first,
+// since a later acknowledgement couldn't be told apart.
+func (c *Client) writeStream(ctx context.Context, req *storagepb.WriteRequest) (*storagepb.WriteResponse, error) {
+	c.streamMu.Lock()
+	defer c.streamMu.Unlock()
+
+	if c.stream == nil {
+		streamCtx, cancel := context.WithCancel(context.Background())
+		stream, err := c.client.WriteStream(streamCtx, c.writeOpts...)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		c.stream, c.streamCancel = stream, cancel
+	}
+
+	type result struct {
+		resp *storagepb.WriteResponse
+		err  error
+	}
+	done := make(chan result, 1)
+	stream := c.stream
+	go func() {
+		// A stream ended by the server fails Send with io.EOF; Recv
+		// returns its status
+		if err := stream.Send(req); err != nil && !errors.Is(err, io.EOF) {
+			done <- result{err: err}
+			return
+		}
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			err = status.Error(codes.Unavailable, "write stream closed by server")
+		}
+		done <- result{resp, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			c.closeStream()
+		}
+		return r.resp, r.err
+	case <-ctx.Done():
+		c.closeStream()
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+// closeStream ends the write stream, if open. The caller must hold
+// streamMu.
+func (c *Client) closeStream() {
+	if c.stream == nil {
+		return
+	}
+	c.stream.CloseSend()
+	c.streamCancel()
+	c.stream, c.streamCancel = nil, nil
+}
+
+// WriteDelay implements storage.WriteThrottler: the delay the server
+// asked for in its reply to the last Write, or in a RESOURCE_EXHAUSTED
+// rejection of it.
+func (c *Client) WriteDelay() time.Duration {
+	return time.Duration(c.writeDelay.Load())
+}
+
+// retryDelay returns the delay a RESOURCE_EXHAUSTED error asks for, one
+// second if it doesn't say, and 0 for other errors.
+func retryDelay(err error) time.Duration {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.ResourceExhausted {
+		return 0
+	}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.RetryInfo); ok && info.RetryDelay != nil {
+			return info.RetryDelay.AsDuration()
+		}
+	}
+	return time.Second
+}
+
 // Query searches for log entries matching the given criteria.
 func (c *Client) Query(ctx context.Context, q storage.Query) (*storage.QueryResult, error) {
-	req := &storagepb.QueryRequest{
-		StartTimeNanos: q.StartTime.UnixNano(),
-		EndTimeNanos:   q.EndTime.UnixNano(),
-		Search:         q.Search,
-		Namespace:      q.Namespace,
-		Pod:            q.Pod,
-		Container:      q.Container,
-		MinSeverity:    uint32(q.MinSeverity),
-		Attributes:     q.Attributes,
-		Limit:          int32(q.Pagination.Limit),
-		AfterId:        q.Pagination.AfterID,
-		BeforeId:       q.Pagination.BeforeID,
-		Order:          toProtoOrder(q.Pagination.Order),
-	}
+	req := toProtoQuery(q)
 
 	resp, err := c.client.Query(ctx, req)
 	if err != nil {
@@ -86,12 +292,19 @@ func (c *Client) Query(ctx context.Context, q storage.Query) (*storage.QueryResu
 		entries[i] = fromProtoEntry(e)
 	}
 
-	return &storage.QueryResult{
+	result := &storage.QueryResult{
 		Entries:       entries,
 		HasMore:       resp.HasMore,
 		NextCursor:    resp.NextCursor,
 		TotalEstimate: resp.TotalEstimate,
-	}, nil
+		SampleRate:    resp.SampleRate,
+		TotalCapped:   resp.TotalCapped,
+	}
+	if resp.NextCursorTimestampNanos != 0 {
+		result.NextCursorTimestamp = time.Unix(0, resp.NextCursorTimestampNanos)
+	}
+
+	return result, nil
 }
 
 // GetByID retrieves a single entry by its ID.
@@ -108,10 +321,57 @@ func (c *Client) GetByID(ctx context.Context, id int64) (*storage.LogEntry, erro
 	return &entry, nil
 }
 
-// Delete removes entries older than the given timestamp.
+// GetByIDs retrieves multiple entries by ID in one call.
+func (c *Client) GetByIDs(ctx context.Context, ids []int64) ([]storage.LogEntry, error) {
+	resp, err := c.client.GetByIDs(ctx, &storagepb.GetByIDsRequest{Ids: ids})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]storage.LogEntry, len(resp.Entries))
+	for i, e := range resp.Entries {
+		entries[i] = fromProtoEntry(e)
+	}
+	return entries, nil
+}
+
+// Delete implements storage.Store. The server deletes only with the token
+// of a preview, so this returns storage.ErrConfirmationRequired without
+// calling it; use PreviewDelete and ConfirmDelete instead. For the same
+// reason Client doesn't implement storage.QueryDeleter; use
+// PreviewDeleteByQuery and ConfirmDeleteByQuery.
 func (c *Client) Delete(ctx context.Context, olderThan time.Time) (int64, error) {
-	resp, err := c.client.Delete(ctx, &storagepb.DeleteRequest{
+	return 0, storage.ErrConfirmationRequired
+}
+
+// DeletePreview is what a delete would remove, and the token confirming
+// it.
+type DeletePreview struct {
+	Matched        int64 // -1 if the server's store can't count
+	Token          string
+	ExpiresAt      time.Time
+	SecondApprover bool // Someone else must confirm
+}
+
+// PreviewDelete counts the entries older than the given timestamp and
+// returns the token ConfirmDelete needs. Servers with token
+// authentication accept it only with the admin token.
+func (c *Client) PreviewDelete(ctx context.Context, olderThan time.Time) (DeletePreview, error) {
+	resp, err := c.admin.PreviewDelete(ctx, &storagepb.DeleteRequest{
+		OlderThanNanos: olderThan.UnixNano(),
+	})
+	if err != nil {
+		return DeletePreview{}, err
+	}
+	return fromProtoPreview(resp), nil
+}
+
+// ConfirmDelete removes entries older than the given timestamp, with the
+// token of a PreviewDelete of the same timestamp.
+func (c *Client) ConfirmDelete(ctx context.Context, olderThan time.Time, token string) (int64, error) {
+	resp, err := c.admin.Delete(ctx, &storagepb.DeleteRequest{
 		OlderThanNanos: olderThan.UnixNano(),
+		Confirm:        token,
 	})
 	if err != nil {
 		return 0, err
@@ -120,6 +380,45 @@ func (c *Client) Delete(ctx context.Context, olderThan time.Time) (int64, error)
 	return resp.DeletedCount, nil
 }
 
+// PreviewDeleteByQuery counts the entries matching a query's filters and
+// returns the token ConfirmDeleteByQuery needs. It fails with
+// UNIMPLEMENTED if the server's store can't delete by query.
+func (c *Client) PreviewDeleteByQuery(ctx context.Context, q storage.Query) (DeletePreview, error) {
+	if !q.HasFilter() {
+		return DeletePreview{}, storage.ErrNoFilter
+	}
+	resp, err := c.admin.PreviewDeleteByQuery(ctx, &storagepb.DeleteByQueryRequest{Query: toProtoQuery(q)})
+	if err != nil {
+		return DeletePreview{}, err
+	}
+	return fromProtoPreview(resp), nil
+}
+
+// ConfirmDeleteByQuery removes entries matching a query's filters, with
+// the token of a PreviewDeleteByQuery of the same query.
+func (c *Client) ConfirmDeleteByQuery(ctx context.Context, q storage.Query, token string) (int64, error) {
+	if !q.HasFilter() {
+		return 0, storage.ErrNoFilter
+	}
+	resp, err := c.admin.DeleteByQuery(ctx, &storagepb.DeleteByQueryRequest{
+		Query:   toProtoQuery(q),
+		Confirm: token,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return resp.DeletedCount, nil
+}
+
+func fromProtoPreview(p *storagepb.PreviewDeleteResponse) DeletePreview {
+	return DeletePreview{
+		Matched:        p.Matched,
+		Token:          p.Token,
+		ExpiresAt:      time.Unix(0, p.ExpiresAtNanos),
+		SecondApprover: p.SecondApprover,
+	}
+}
+
 // Stats returns storage statistics.
 func (c *Client) Stats(ctx context.Context) (*storage.Stats, error) {
 	resp, err := c.client.Stats(ctx, &storagepb.StatsRequest{})
@@ -127,24 +426,200 @@ func (c *Client) Stats(ctx context.Context) (*storage.Stats, error) {
 		return nil, err
 	}
 
-	return &storage.Stats{
+	stats := &storage.Stats{
 		TotalEntries:  resp.TotalEntries,
 		DiskSizeBytes: resp.DiskSizeBytes,
 		OldestEntry:   time.Unix(0, resp.OldestEntryNanos),
 		NewestEntry:   time.Unix(0, resp.NewestEntryNanos),
-	}, nil
+	}
+	for _, u := range resp.Namespaces {
+		stats.Namespaces = append(stats.Namespaces, storage.NamespaceUsage{
+			Namespace: u.Namespace,
+			Entries:   u.Entries,
+			Bytes:     u.Bytes,
+		})
+	}
+	return stats, nil
+}
+
+// ReportStatus implements storage.StatusReporter.
+func (c *Client) ReportStatus(ctx context.Context, s storage.CollectorStatus) error {
+	_, err := c.client.ReportCollectorStatus(ctx, &storagepb.ReportCollectorStatusRequest{
+		Node:              s.Node,
+		Cluster:           s.Cluster,
+		StartedAtNanos:    s.StartedAt.UnixNano(),
+		IntervalMillis:    s.Interval.Milliseconds(),
+		ActiveStreams:     int32(s.ActiveStreams),
+		CatchingUpStreams: int32(s.CatchingUpStreams),
+		LinesRead:         s.LinesRead,
+		StreamErrors:      s.StreamErrors,
+		EntriesWritten:    s.EntriesWritten,
+		WriteErrors:       s.WriteErrors,
+		BufferedEntries:   int32(s.BufferedEntries),
+		RetryQueueBatches: int32(s.RetryQueueBatches),
+		CircuitOpen:       s.CircuitOpen,
+	})
+	return err
+}
+
+// Histogram implements storage.Aggregator. It fails with UNIMPLEMENTED
+// if the server's store can't count entries.
+func (c *Client) Histogram(ctx context.Context, q storage.Query, interval time.Duration) ([]storage.HistogramBucket, error) {
+	resp, err := c.client.Aggregate(ctx, &storagepb.AggregateRequest{
+		Query:         toProtoQuery(q),
+		IntervalNanos: int64(interval),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make([]storage.HistogramBucket, len(resp.Buckets))
+	for i, b := range resp.Buckets {
+		buckets[i] = storage.HistogramBucket{
+			Start:    time.Unix(0, b.StartNanos),
+			Severity: storage.Severity(b.Severity),
+			Count:    b.Count,
+		}
+	}
+	return buckets, nil
+}
+
+// Aggregate implements storage.GroupAggregator. It fails with
+// UNIMPLEMENTED if the server's store can't aggregate.
+func (c *Client) Aggregate(ctx context.Context, q storage.Query, a storage.Aggregation) ([]storage.AggregateGroup, error) {
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	// Without fields or a value the server would count a histogram, so
+	// group by severity and add the groups up
+	groupBy := a.GroupBy
+	if len(groupBy) == 0 && a.Value == "" {
+		groupBy = []string{storage.GroupBySeverity}
+	}
+	resp, err := c.client.Aggregate(ctx, &storagepb.AggregateRequest{
+		Query:          toProtoQuery(q),
+		IntervalNanos:  int64(a.Interval),
+		GroupBy:        groupBy,
+		ValueAttribute: a.Value,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]storage.AggregateGroup, len(resp.Groups))
+	for i, g := range resp.Groups {
+		groups[i] = storage.AggregateGroup{
+			Keys:       g.Keys,
+			Count:      g.Count,
+			ValueCount: g.ValueCount,
+			Sum:        g.Sum,
+			Min:        g.Min,
+			Max:        g.Max,
+		}
+		if a.Interval > 0 {
+			groups[i].Start = time.Unix(0, g.StartNanos)
+		}
+		if len(a.GroupBy) == 0 {
+			groups[i].Keys = nil
+		}
+	}
+	return storage.MergeGroups(groups), nil
+}
+
+// Tail calls fn with entries matching q as they are written, oldest
+// first, until ctx is canceled or fn returns an error. It starts after
+// q.Pagination.AfterID, or with entries written after the call if unset.
+// Other pagination fields are ignored.
+func (c *Client) Tail(ctx context.Context, q storage.Query, fn func(storage.LogBatch) error) error {
+	stream, err := c.client.Tail(ctx, toProtoQuery(q))
+	if err != nil {
+		return err
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		entries := make(storage.LogBatch, len(resp.Entries))
+		for i, e := range resp.Entries {
+			entries[i] = fromProtoEntry(e)
+		}
+		if err := fn(entries); err != nil {
+			return err
+		}
+	}
+}
+
+// Trace returns the first limit entries of the distributed trace
+// traceID across all namespaces and pods, oldest first and grouped by
+// container, narrowed by q's other filters. See storage.GetTrace.
+func (c *Client) Trace(ctx context.Context, traceID string, q storage.Query, limit int) (*storage.Trace, error) {
+	return storage.GetTrace(ctx, c, traceID, q, limit)
 }
 
 // Close releases resources.
 func (c *Client) Close() error {
+	c.streamMu.Lock()
+	c.closeStream()
+	c.streamMu.Unlock()
 	return c.conn.Close()
 }
 
+// toProtoQuery converts a storage.Query to a protobuf QueryRequest.
+func toProtoQuery(q storage.Query) *storagepb.QueryRequest {
+	req := &storagepb.QueryRequest{
+		Search:      q.Search,
+		Cluster:     q.Cluster,
+		Namespace:   q.Namespace,
+		Pod:         q.Pod,
+		Container:   q.Container,
+		MinSeverity: uint32(q.MinSeverity),
+		Attributes:  q.Attributes,
+		Sample:      q.Sample,
+		Consistency: storagepb.Consistency(q.Consistency), // Same values
+		Count:       q.Count,
+		Limit:       int32(q.Pagination.Limit),
+		AfterId:     q.Pagination.AfterID,
+		BeforeId:    q.Pagination.BeforeID,
+		MaxId:       q.Pagination.MaxID,
+		Order:       toProtoOrder(q.Pagination.Order),
+		OrderBy:     toProtoOrderBy(q.Pagination.OrderBy),
+	}
+	// Zero times mean no filter, and don't fit in nanoseconds
+	if !q.StartTime.IsZero() {
+		req.StartTimeNanos = q.StartTime.UnixNano()
+	}
+	if !q.EndTime.IsZero() {
+		req.EndTimeNanos = q.EndTime.UnixNano()
+	}
+	if !q.Pagination.AfterTimestamp.IsZero() {
+		req.AfterTimestampNanos = q.Pagination.AfterTimestamp.UnixNano()
+	}
+	if !q.Pagination.BeforeTimestamp.IsZero() {
+		req.BeforeTimestampNanos = q.Pagination.BeforeTimestamp.UnixNano()
+	}
+	for _, expr := range q.AttrExprs {
+		pb := &storagepb.AttributeExpr{Terms: make([]*storagepb.AttributeTerm, len(expr))}
+		for i, t := range expr {
+			// AttrOp and AttributeOp share their values
+			pb.Terms[i] = &storagepb.AttributeTerm{Key: t.Key, Op: storagepb.AttributeOp(t.Op), Value: t.Value}
+		}
+		req.AttributeExprs = append(req.AttributeExprs, pb)
+	}
+	return req
+}
+
 // toProtoEntry converts a storage.LogEntry to protobuf.
 func toProtoEntry(e storage.LogEntry) *storagepb.LogEntry {
-	return &storagepb.LogEntry{
+	pe := &storagepb.LogEntry{
 		Id:             e.ID,
 		TimestampNanos: e.Timestamp.UnixNano(),
+		Cluster:        e.Cluster,
 		Namespace:      e.Namespace,
 		Pod:            e.Pod,
 		Container:      e.Container,
@@ -152,6 +627,10 @@ func toProtoEntry(e storage.LogEntry) *storagepb.LogEntry {
 		Message:        e.Message,
 		Attributes:     e.Attributes,
 	}
+	if !e.ExpiresAt.IsZero() {
+		pe.ExpiresAtNanos = e.ExpiresAt.UnixNano()
+	}
+	return pe
 }
 
 // fromProtoEntry converts a protobuf LogEntry to storage.LogEntry.
@@ -159,6 +638,7 @@ func fromProtoEntry(e *storagepb.LogEntry) storage.LogEntry {
 	return storage.LogEntry{
 		ID:         e.Id,
 		Timestamp:  time.Unix(0, e.TimestampNanos),
+		Cluster:    e.Cluster,
 		Namespace:  e.Namespace,
 		Pod:        e.Pod,
 		Container:  e.Container,
@@ -175,3 +655,11 @@ func toProtoOrder(o storage.Order) storagepb.Order {
 	}
 	return storagepb.Order_ORDER_DESC
 }
+
+// toProtoOrderBy converts storage.OrderBy to protobuf OrderBy.
+func toProtoOrderBy(o storage.OrderBy) storagepb.OrderBy {
+	if o == storage.OrderByTimestamp {
+		return storagepb.OrderBy_ORDER_BY_TIMESTAMP
+	}
+	return storagepb.OrderBy_ORDER_BY_ID
+}
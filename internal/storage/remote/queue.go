@@ -0,0 +1,86 @@
+package remote
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/kubelogs/kubelogs/api/storagepb"
+	"github.com/kubelogs/kubelogs/internal/queue"
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// errWriteOnly is returned by QueueWriter for everything but writes.
+var errWriteOnly = errors.New("remote: queue writer is write-only")
+
+// QueueWriter implements storage.Store by publishing batches to an ingest
+// queue that storage servers consume, instead of calling a server. Only
+// Write is supported, which is all collectors need.
+type QueueWriter struct {
+	stream *queue.Stream
+}
+
+// NewQueueWriter returns a writer publishing to stream.
+func NewQueueWriter(stream *queue.Stream) *QueueWriter {
+	return &QueueWriter{stream: stream}
+}
+
+// Write publishes the batch as a WriteRequest carrying each entry's dedup
+// hash and a batch ID derived from them, so a retried batch keeps its ID.
+// All entries count as written once queued.
+func (w *QueueWriter) Write(ctx context.Context, entries storage.LogBatch) (int, error) {
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	pbEntries := make([]*storagepb.LogEntry, len(entries))
+	batch := sha256.New()
+	for i, e := range entries {
+		pbEntries[i] = toProtoEntry(e)
+		pbEntries[i].DedupHash = storage.DedupHash(&e)
+		batch.Write(binary.LittleEndian.AppendUint64(nil, uint64(pbEntries[i].DedupHash)))
+	}
+	data, err := proto.Marshal(&storagepb.WriteRequest{
+		Entries: pbEntries,
+		BatchId: hex.EncodeToString(batch.Sum(nil)[:16]),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("encode batch: %w", err)
+	}
+
+	if err := w.stream.Publish(ctx, data); err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+func (w *QueueWriter) Query(context.Context, storage.Query) (*storage.QueryResult, error) {
+	return nil, errWriteOnly
+}
+
+func (w *QueueWriter) GetByID(context.Context, int64) (*storage.LogEntry, error) {
+	return nil, errWriteOnly
+}
+
+func (w *QueueWriter) GetByIDs(context.Context, []int64) ([]storage.LogEntry, error) {
+	return nil, errWriteOnly
+}
+
+func (w *QueueWriter) Delete(context.Context, time.Time) (int64, error) {
+	return 0, errWriteOnly
+}
+
+func (w *QueueWriter) Stats(context.Context) (*storage.Stats, error) {
+	return nil, errWriteOnly
+}
+
+// Close closes the queue connection.
+func (w *QueueWriter) Close() error {
+	return w.stream.Close()
+}
@@ -0,0 +1,273 @@
+package router
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// syncInterval is how often queries look for entries the router didn't
+// write itself, e.g. those written by other servers sharing a store, and
+// how long eventually consistent queries may miss the router's writes.
+const syncInterval = time.Second
+
+// idRange gives the entries lo to hi of one store the router IDs from
+// global up.
+type idRange struct {
+	global, lo, hi int64
+}
+
+// end returns the last router ID of the range.
+func (rg idRange) end() int64 {
+	return rg.global + rg.hi - rg.lo
+}
+
+// loadIDs reads the highest local ID with a router ID of each store and
+// the next router ID from the database.
+func (r *Router) loadIDs(ctx context.Context) error {
+	err := r.db.QueryRowContext(ctx,
+		`SELECT global_lo, store, local_lo, local_hi FROM router_ids ORDER BY global_lo DESC LIMIT 1`,
+	).Scan(&r.lastRange.global, &r.lastStore, &r.lastRange.lo, &r.lastRange.hi)
+	if err == sql.ErrNoRows {
+		r.lastStore = -1
+		r.next = 1
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	r.next = r.lastRange.end() + 1
+
+	rows, err := r.db.QueryContext(ctx, `SELECT store, MAX(local_hi) FROM router_ids GROUP BY store`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var store int
+		var hi int64
+		if err := rows.Scan(&store, &hi); err != nil {
+			return err
+		}
+		// Ranges of stores since removed keep their IDs taken
+		if store < len(r.marks) {
+			r.marks[store] = hi
+		}
+	}
+	return rows.Err()
+}
+
+// visible returns the highest local ID with a router ID of each store,
+// first giving IDs to new entries if consistency c requires it: strongly
+// consistent queries see every write through the router acknowledged
+// before they started, others may miss them for syncInterval.
+func (r *Router) visible(ctx context.Context, c storage.Consistency) ([]int64, error) {
+	writes := r.writes.Load()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if (c == storage.ConsistencyStrong && r.synced < writes) || time.Since(r.lastSync) >= syncInterval {
+		if err := r.sync(ctx, c); err != nil {
+			return nil, err
+		}
+	}
+	return append([]int64(nil), r.marks...), nil
+}
+
+// sync gives the entries the stores made visible since the last sync
+// router IDs, store by store. Callers hold r.mu.
+func (r *Router) sync(ctx context.Context, c storage.Consistency) error {
+	// Reading the newest entries stores buffered writes first, so the
+	// writes counted so far are all found
+	writes := r.writes.Load()
+	newest := make([]int64, len(r.stores))
+	for i, s := range r.stores {
+		res, err := s.Query(ctx, storage.Query{
+			Pagination:  storage.Pagination{Limit: 1, Order: storage.OrderDesc},
+			Consistency: c,
+		})
+		if err != nil {
+			return fmt.Errorf("router: newest entry of store %d: %w", i, err)
+		}
+		if len(res.Entries) > 0 {
+			newest[i] = res.Entries[0].ID
+		}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("router: begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	next, lastStore, last := r.next, r.lastStore, r.lastRange
+	marks := append([]int64(nil), r.marks...)
+	for i, n := range newest {
+		if n <= marks[i] {
+			continue
+		}
+		if i == lastStore {
+			// Nothing was numbered since this store's last range, so it
+			// grows rather than adding a range per sync
+			last.hi = n
+			_, err = tx.ExecContext(ctx, `UPDATE router_ids SET local_hi = ? WHERE global_lo = ?`, last.hi, last.global)
+		} else {
+			last, lastStore = idRange{global: next, lo: marks[i] + 1, hi: n}, i
+			_, err = tx.ExecContext(ctx,
+				`INSERT INTO router_ids (global_lo, store, local_lo, local_hi) VALUES (?, ?, ?, ?)`,
+				last.global, i, last.lo, last.hi,
+			)
+		}
+		if err != nil {
+			return fmt.Errorf("router: save IDs: %w", err)
+		}
+		next += n - marks[i]
+		marks[i] = n
+	}
+	if next != r.next {
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("router: save IDs: %w", err)
+		}
+		r.next, r.lastStore, r.lastRange, r.marks = next, lastStore, last, marks
+	}
+
+	if c == storage.ConsistencyStrong {
+		r.synced = writes
+	}
+	r.lastSync = time.Now()
+	return nil
+}
+
+// localAt returns the highest local ID of a store whose router ID is at
+// most id, or 0 if there is none.
+func (r *Router) localAt(ctx context.Context, store int, id int64) (int64, error) {
+	var rg idRange
+	err := r.db.QueryRowContext(ctx,
+		`SELECT global_lo, local_lo, local_hi FROM router_ids WHERE store = ? AND global_lo <= ? ORDER BY global_lo DESC LIMIT 1`,
+		store, id,
+	).Scan(&rg.global, &rg.lo, &rg.hi)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("router: translate ID: %w", err)
+	}
+	return min(rg.hi, rg.lo+id-rg.global), nil
+}
+
+// afterID translates an exclusive lower ID cursor for one store: router
+// IDs above id are exactly the local IDs above the result.
+func (r *Router) afterID(ctx context.Context, store int, id int64) (int64, error) {
+	if id <= 0 {
+		return id, nil
+	}
+	return r.localAt(ctx, store, id)
+}
+
+// beforeID translates an exclusive upper ID cursor for one store.
+func (r *Router) beforeID(ctx context.Context, store int, id int64) (int64, error) {
+	if id <= 0 {
+		return id, nil
+	}
+	local, err := r.localAt(ctx, store, id-1)
+	return local + 1, err
+}
+
+// globalIDs replaces the local IDs of a store's entries with their
+// router IDs, or 0 for IDs without one.
+func (r *Router) globalIDs(ctx context.Context, store int, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	lo, hi := ids[0], ids[0]
+	for _, id := range ids {
+		lo, hi = min(lo, id), max(hi, id)
+	}
+
+	// The first range is the last one starting at or before lo
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT global_lo, local_lo, local_hi FROM router_ids
+		WHERE store = ?1 AND local_lo <= ?3
+		  AND local_lo >= (SELECT COALESCE(MAX(local_lo), 0) FROM router_ids WHERE store = ?1 AND local_lo <= ?2)
+		ORDER BY local_lo`,
+		store, lo, hi,
+	)
+	if err != nil {
+		return fmt.Errorf("router: translate IDs: %w", err)
+	}
+	defer rows.Close()
+	var ranges []idRange
+	for rows.Next() {
+		var rg idRange
+		if err := rows.Scan(&rg.global, &rg.lo, &rg.hi); err != nil {
+			return fmt.Errorf("router: translate IDs: %w", err)
+		}
+		ranges = append(ranges, rg)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("router: translate IDs: %w", err)
+	}
+
+	for k, id := range ids {
+		j := sort.Search(len(ranges), func(j int) bool { return ranges[j].lo > id }) - 1
+		if j < 0 || id > ranges[j].hi {
+			ids[k] = 0
+			continue
+		}
+		ids[k] = ranges[j].global + id - ranges[j].lo
+	}
+	return nil
+}
+
+// splitID returns the store index and local ID of a router ID. ok is
+// false for IDs the router didn't give out or whose store was removed.
+func (r *Router) splitID(ctx context.Context, id int64) (store int, local int64, ok bool, err error) {
+	var rg idRange
+	err = r.db.QueryRowContext(ctx,
+		`SELECT global_lo, store, local_lo, local_hi FROM router_ids WHERE global_lo <= ? ORDER BY global_lo DESC LIMIT 1`,
+		id,
+	).Scan(&rg.global, &store, &rg.lo, &rg.hi)
+	if err == sql.ErrNoRows {
+		return 0, 0, false, nil
+	}
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("router: translate ID: %w", err)
+	}
+	if id > rg.end() || store >= len(r.stores) {
+		return 0, 0, false, nil
+	}
+	return store, rg.lo + id - rg.global, true, nil
+}
+
+// pruneIDs drops the ranges of entries the stores no longer hold, such
+// as after retention. Each store keeps its last range, which holds its
+// highest local ID with a router ID.
+func (r *Router) pruneIDs(ctx context.Context) error {
+	for i, s := range r.stores {
+		res, err := s.Query(ctx, storage.Query{
+			Pagination:  storage.Pagination{Limit: 1, Order: storage.OrderAsc},
+			Consistency: storage.ConsistencyEventual,
+		})
+		if err != nil {
+			return err
+		}
+		oldest := int64(math.MaxInt64)
+		if len(res.Entries) > 0 {
+			oldest = res.Entries[0].ID
+		}
+		_, err = r.db.ExecContext(ctx,
+			`DELETE FROM router_ids WHERE store = ?1 AND local_hi < ?2
+			  AND global_lo < (SELECT MAX(global_lo) FROM router_ids WHERE store = ?1)`,
+			i, oldest,
+		)
+		if err != nil {
+			return fmt.Errorf("router: prune IDs: %w", err)
+		}
+	}
+	return nil
+}
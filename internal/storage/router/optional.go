@@ -0,0 +1,370 @@
+package router
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// Flush implements storage.WriteOptimizer for stores that support it.
+func (r *Router) Flush(ctx context.Context) error {
+	for _, s := range r.stores {
+		if wo, ok := s.(storage.WriteOptimizer); ok {
+			if err := wo.Flush(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SetWriteBuffer implements storage.WriteOptimizer for stores that support it.
+func (r *Router) SetWriteBuffer(entries int) {
+	for _, s := range r.stores {
+		if wo, ok := s.(storage.WriteOptimizer); ok {
+			wo.SetWriteBuffer(entries)
+		}
+	}
+}
+
+// Written implements storage.Notifier for writes through the router.
+func (r *Router) Written() <-chan struct{} {
+	return r.written.Written()
+}
+
+// DeleteCluster implements storage.ClusterDeleter for stores that support it.
+func (r *Router) DeleteCluster(ctx context.Context, cluster string, olderThan time.Time) (int64, error) {
+	var deleted int64
+	for _, s := range r.stores {
+		if cd, ok := s.(storage.ClusterDeleter); ok {
+			n, err := cd.DeleteCluster(ctx, cluster, olderThan)
+			deleted += n
+			if err != nil {
+				return deleted, err
+			}
+		}
+	}
+	return deleted, nil
+}
+
+// DeleteExpired implements storage.ExpiryDeleter for stores that support
+// it.
+func (r *Router) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	var deleted int64
+	for _, s := range r.stores {
+		if ed, ok := s.(storage.ExpiryDeleter); ok {
+			n, err := ed.DeleteExpired(ctx, now)
+			deleted += n
+			if err != nil {
+				return deleted, err
+			}
+		}
+	}
+	return deleted, r.pruneIDs(ctx)
+}
+
+// DeleteByQuery implements storage.QueryDeleter for the stores q may
+// match that support it.
+func (r *Router) DeleteByQuery(ctx context.Context, q storage.Query) (int64, error) {
+	if !q.HasFilter() {
+		return 0, storage.ErrNoFilter
+	}
+	var deleted int64
+	for _, i := range r.queryStores(&q) {
+		if qd, ok := r.stores[i].(storage.QueryDeleter); ok {
+			n, err := qd.DeleteByQuery(ctx, q)
+			deleted += n
+			if err != nil {
+				return deleted, err
+			}
+		}
+	}
+	return deleted, nil
+}
+
+// RebuildSearchIndex implements storage.SearchIndexRebuilder for the
+// stores that support it, one after another. Progress totals grow as
+// each store starts.
+func (r *Router) RebuildSearchIndex(ctx context.Context, progress func(storage.IndexProgress)) (int64, error) {
+	var done storage.IndexProgress
+	for _, s := range r.stores {
+		rb, ok := s.(storage.SearchIndexRebuilder)
+		if !ok {
+			continue
+		}
+		var last storage.IndexProgress
+		n, err := rb.RebuildSearchIndex(ctx, func(p storage.IndexProgress) {
+			last = p
+			if progress != nil {
+				progress(storage.IndexProgress{Indexed: done.Indexed + p.Indexed, Total: done.Total + p.Total})
+			}
+		})
+		done.Indexed += n
+		done.Total += last.Total
+		if err != nil {
+			return done.Indexed, err
+		}
+	}
+	return done.Indexed, nil
+}
+
+// Rollups implements storage.RollupReader by merging the rollups of the
+// stores that keep them; stores without rollups are left out.
+func (r *Router) Rollups(ctx context.Context, q storage.RollupQuery) ([]storage.Rollup, error) {
+	stores := r.stores
+	if q.Namespace != "" {
+		stores = []storage.Store{r.stores[r.storeFor(q.Namespace)]}
+	}
+
+	type key struct{ ns, pod, container string }
+	merged := make(map[key]*storage.Rollup)
+	for _, s := range stores {
+		rr, ok := s.(storage.RollupReader)
+		if !ok {
+			continue
+		}
+		// Limits apply after merging: the same source may have moved
+		// between stores when routes changed
+		sq := q
+		sq.Limit = 0
+		rollups, err := rr.Rollups(ctx, sq)
+		if err != nil {
+			return nil, err
+		}
+		for _, ru := range rollups {
+			k := key{ru.Namespace, ru.Pod, ru.Container}
+			if m, ok := merged[k]; ok {
+				m.Lines += ru.Lines
+				m.Bytes += ru.Bytes
+				if ru.LastSeen.After(m.LastSeen) {
+					m.LastSeen = ru.LastSeen
+				}
+				continue
+			}
+			ru := ru
+			merged[k] = &ru
+		}
+	}
+
+	result := make([]storage.Rollup, 0, len(merged))
+	for _, ru := range merged {
+		result = append(result, *ru)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		av, bv := a.Bytes, b.Bytes
+		if q.OrderByLines {
+			av, bv = a.Lines, b.Lines
+		}
+		if av != bv {
+			return av > bv
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Pod != b.Pod {
+			return a.Pod < b.Pod
+		}
+		return a.Container < b.Container
+	})
+	if q.Limit > 0 && len(result) > q.Limit {
+		result = result[:q.Limit]
+	}
+	return result, nil
+}
+
+// Patterns implements storage.PatternReader by merging the patterns of
+// the stores that keep them; stores without patterns are left out.
+func (r *Router) Patterns(ctx context.Context, q storage.PatternQuery) ([]storage.PatternSighting, error) {
+	merged := make(map[string]*storage.PatternSighting)
+	for i, s := range r.stores {
+		pr, ok := s.(storage.PatternReader)
+		if !ok {
+			continue
+		}
+		// Filters and limits apply after merging: a pattern new to one
+		// store may have been seen long before in another
+		sightings, err := pr.Patterns(ctx, storage.PatternQuery{})
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]int64, len(sightings))
+		for k, p := range sightings {
+			ids[k] = p.FirstID
+		}
+		if err := r.globalIDs(ctx, i, ids); err != nil {
+			return nil, err
+		}
+		for k := range sightings {
+			sightings[k].FirstID = ids[k]
+		}
+		for _, p := range sightings {
+			m, ok := merged[p.Pattern]
+			if !ok {
+				p := p
+				merged[p.Pattern] = &p
+				continue
+			}
+			if p.FirstSeen.Before(m.FirstSeen) {
+				m.FirstSeen, m.FirstID = p.FirstSeen, p.FirstID
+				m.Namespace, m.Pod, m.Example = p.Namespace, p.Pod, p.Example
+			}
+			if p.LastSeen.After(m.LastSeen) {
+				m.LastSeen = p.LastSeen
+			}
+			m.Count += p.Count
+			m.MaxSeverity = max(m.MaxSeverity, p.MaxSeverity)
+		}
+	}
+
+	result := make([]storage.PatternSighting, 0, len(merged))
+	for _, p := range merged {
+		if p.FirstSeen.Before(q.FirstSeenAfter) || p.MaxSeverity < q.MinSeverity {
+			continue
+		}
+		result = append(result, *p)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if !a.FirstSeen.Equal(b.FirstSeen) {
+			return a.FirstSeen.After(b.FirstSeen)
+		}
+		return a.Pattern < b.Pattern
+	})
+	if q.Limit > 0 && len(result) > q.Limit {
+		result = result[:q.Limit]
+	}
+	return result, nil
+}
+
+// Histogram implements storage.Aggregator by adding up the histograms of
+// the stores that can compute them; other stores are left out.
+func (r *Router) Histogram(ctx context.Context, q storage.Query, interval time.Duration) ([]storage.HistogramBucket, error) {
+	type key struct {
+		start    int64
+		severity storage.Severity
+	}
+	counts := make(map[key]int64)
+	for _, i := range r.queryStores(&q) {
+		agg, ok := r.stores[i].(storage.Aggregator)
+		if !ok {
+			continue
+		}
+		buckets, err := agg.Histogram(ctx, q, interval)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range buckets {
+			counts[key{b.Start.UnixNano(), b.Severity}] += b.Count
+		}
+	}
+
+	result := make([]storage.HistogramBucket, 0, len(counts))
+	for k, n := range counts {
+		result = append(result, storage.HistogramBucket{Start: time.Unix(0, k.start), Severity: k.severity, Count: n})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if !a.Start.Equal(b.Start) {
+			return a.Start.Before(b.Start)
+		}
+		return a.Severity < b.Severity
+	})
+	return result, nil
+}
+
+// Aggregate implements storage.GroupAggregator by combining the groups
+// of the stores that can aggregate; other stores are left out.
+func (r *Router) Aggregate(ctx context.Context, q storage.Query, a storage.Aggregation) ([]storage.AggregateGroup, error) {
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	var groups []storage.AggregateGroup
+	for _, i := range r.queryStores(&q) {
+		agg, ok := r.stores[i].(storage.GroupAggregator)
+		if !ok {
+			continue
+		}
+		g, err := agg.Aggregate(ctx, q, a)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g...)
+	}
+	return storage.MergeGroups(groups), nil
+}
+
+// filterLister matches the filter listing methods of the bundled stores.
+type filterLister interface {
+	ListNamespaces(ctx context.Context) ([]string, error)
+	ListContainers(ctx context.Context) ([]string, error)
+	ListPods(ctx context.Context, namespace string) ([]string, error)
+}
+
+// clusterLister matches stores that can list cluster values.
+type clusterLister interface {
+	ListClusters(ctx context.Context) ([]string, error)
+}
+
+// ListNamespaces returns distinct namespace values across stores.
+func (r *Router) ListNamespaces(ctx context.Context) ([]string, error) {
+	return r.distinct(func(s storage.Store) ([]string, error) {
+		if fl, ok := s.(filterLister); ok {
+			return fl.ListNamespaces(ctx)
+		}
+		return nil, nil
+	})
+}
+
+// ListContainers returns distinct container values across stores.
+func (r *Router) ListContainers(ctx context.Context) ([]string, error) {
+	return r.distinct(func(s storage.Store) ([]string, error) {
+		if fl, ok := s.(filterLister); ok {
+			return fl.ListContainers(ctx)
+		}
+		return nil, nil
+	})
+}
+
+// ListPods returns distinct pod values in namespace across stores.
+func (r *Router) ListPods(ctx context.Context, namespace string) ([]string, error) {
+	return r.distinct(func(s storage.Store) ([]string, error) {
+		if fl, ok := s.(filterLister); ok {
+			return fl.ListPods(ctx, namespace)
+		}
+		return nil, nil
+	})
+}
+
+// ListClusters returns distinct cluster values across stores.
+func (r *Router) ListClusters(ctx context.Context) ([]string, error) {
+	return r.distinct(func(s storage.Store) ([]string, error) {
+		if cl, ok := s.(clusterLister); ok {
+			return cl.ListClusters(ctx)
+		}
+		return nil, nil
+	})
+}
+
+// distinct merges the values list returns for each store.
+func (r *Router) distinct(list func(storage.Store) ([]string, error)) ([]string, error) {
+	set := make(map[string]bool)
+	for _, s := range r.stores {
+		values, err := list(s)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range values {
+			set[v] = true
+		}
+	}
+
+	values := make([]string, 0, len(set))
+	for v := range set {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values, nil
+}
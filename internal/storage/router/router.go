@@ -0,0 +1,384 @@
+// Package router implements storage.Store over several stores, sending
+// each entry to a store chosen by its namespace.
+//
+// Queries fan out to every store that can hold matching entries and the
+// results are merged. The router numbers entries itself, in the order
+// the stores make them visible, so IDs and cursors are ordered across
+// stores as live tails need. Which router IDs stand for which entries of
+// each store is kept in the metadata database (see ids.go).
+package router
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+const defaultQueryLimit = 100
+
+// Route sends entries from matching namespaces to a store.
+type Route struct {
+	// Namespaces are exact names or path.Match patterns, e.g. "prod-*".
+	Namespaces []string
+
+	// Store is the index of the target in Config.Stores.
+	Store int
+}
+
+// Config holds router configuration.
+type Config struct {
+	// Stores are the backing stores. The router's IDs refer to stores by
+	// position, so reordering stores invalidates existing IDs.
+	Stores []storage.Store
+
+	// Routes are matched in order; the first match wins.
+	Routes []Route
+
+	// Default is the index of the store for namespaces no route matches.
+	Default int
+
+	// DB is the metadata database, which keeps the router's IDs in its
+	// router_ids table.
+	DB *sql.DB
+}
+
+// Router implements storage.Store by routing on namespace.
+type Router struct {
+	stores   []storage.Store
+	routes   []Route
+	fallback int
+	db       *sql.DB
+
+	writes  atomic.Int64 // Writes through the router, for strong queries
+	written storage.WriteSignal
+
+	mu        sync.Mutex // Guards the fields below, and syncs
+	marks     []int64    // Highest local ID with a router ID, per store
+	next      int64      // Next router ID
+	lastStore int        // Store of the newest range, or -1
+	lastRange idRange    // Newest range
+	synced    int64      // Writes seen by the last strong sync
+	lastSync  time.Time
+}
+
+// New creates a router. The router owns the stores and closes them on
+// Close; cfg.DB stays open.
+func New(cfg Config) (*Router, error) {
+	if len(cfg.Stores) == 0 {
+		return nil, errors.New("router: no stores")
+	}
+	if cfg.DB == nil {
+		return nil, errors.New("router: no database")
+	}
+	if cfg.Default < 0 || cfg.Default >= len(cfg.Stores) {
+		return nil, fmt.Errorf("router: default store %d out of range", cfg.Default)
+	}
+	for i, r := range cfg.Routes {
+		if r.Store < 0 || r.Store >= len(cfg.Stores) {
+			return nil, fmt.Errorf("router: route %d: store %d out of range", i, r.Store)
+		}
+		for _, pattern := range r.Namespaces {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("router: route %d: invalid pattern %q", i, pattern)
+			}
+		}
+	}
+
+	r := &Router{
+		stores:   cfg.Stores,
+		routes:   cfg.Routes,
+		fallback: cfg.Default,
+		db:       cfg.DB,
+		marks:    make([]int64, len(cfg.Stores)),
+	}
+	if err := r.loadIDs(context.Background()); err != nil {
+		return nil, fmt.Errorf("router: load IDs: %w", err)
+	}
+	return r, nil
+}
+
+// storeFor returns the index of the store receiving namespace ns.
+func (r *Router) storeFor(ns string) int {
+	for _, route := range r.routes {
+		for _, pattern := range route.Namespaces {
+			if ok, _ := path.Match(pattern, ns); ok {
+				return route.Store
+			}
+		}
+	}
+	return r.fallback
+}
+
+// Write implements storage.Store.
+func (r *Router) Write(ctx context.Context, entries storage.LogBatch) (int, error) {
+	batches := make(map[int]storage.LogBatch)
+	var order []int
+	for _, e := range entries {
+		i := r.storeFor(e.Namespace)
+		if _, ok := batches[i]; !ok {
+			order = append(order, i)
+		}
+		batches[i] = append(batches[i], e)
+	}
+
+	written := 0
+	defer r.written.Publish()
+	defer r.writes.Add(1)
+	for _, i := range order {
+		n, err := r.stores[i].Write(ctx, batches[i])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// queryStores returns the stores a query must visit. A namespace filter
+// narrows it to the store that namespace routes to.
+func (r *Router) queryStores(q *storage.Query) []int {
+	if q.Namespace != "" {
+		return []int{r.storeFor(q.Namespace)}
+	}
+	stores := make([]int, len(r.stores))
+	for i := range stores {
+		stores[i] = i
+	}
+	return stores
+}
+
+// Query implements storage.Store.
+func (r *Router) Query(ctx context.Context, q storage.Query) (*storage.QueryResult, error) {
+	limit := q.Pagination.Limit
+	if limit <= 0 {
+		limit = defaultQueryLimit
+	}
+
+	marks, err := r.visible(ctx, q.Consistency)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each store returns one entry past the page, so the merged entry
+	// after the page is exact and can serve as the next cursor
+	result := &storage.QueryResult{}
+	var entries []storage.LogEntry
+	for _, i := range r.queryStores(&q) {
+		sq := q
+		sq.Pagination.Limit = limit + 1
+		if sq.Pagination.AfterID, err = r.afterID(ctx, i, q.Pagination.AfterID); err != nil {
+			return nil, err
+		}
+		if sq.Pagination.BeforeID, err = r.beforeID(ctx, i, q.Pagination.BeforeID); err != nil {
+			return nil, err
+		}
+		// Entries without a router ID yet stay hidden until they get one
+		sq.Pagination.MaxID = marks[i]
+		if q.Pagination.MaxID > 0 {
+			local, err := r.localAt(ctx, i, q.Pagination.MaxID)
+			if err != nil {
+				return nil, err
+			}
+			sq.Pagination.MaxID = min(sq.Pagination.MaxID, local)
+		}
+		if sq.Pagination.MaxID <= 0 {
+			continue
+		}
+
+		res, err := r.stores[i].Query(ctx, sq)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]int64, len(res.Entries))
+		for k, e := range res.Entries {
+			ids[k] = e.ID
+		}
+		if err := r.globalIDs(ctx, i, ids); err != nil {
+			return nil, err
+		}
+		for k, e := range res.Entries {
+			// Entries deleted while querying may have lost their range
+			if ids[k] == 0 {
+				continue
+			}
+			e.ID = ids[k]
+			entries = append(entries, e)
+		}
+		if result.TotalEstimate >= 0 && res.TotalEstimate >= 0 {
+			result.TotalEstimate += res.TotalEstimate
+			result.TotalCapped = result.TotalCapped || res.TotalCapped
+		} else {
+			result.TotalEstimate = -1
+		}
+		// Stores sample at the same rate, if they can
+		result.SampleRate = max(result.SampleRate, res.SampleRate)
+	}
+
+	if result.TotalEstimate > storage.MaxCount {
+		result.TotalEstimate, result.TotalCapped = storage.MaxCount, true
+	}
+	if result.TotalEstimate < 0 {
+		result.TotalCapped = false
+	}
+
+	sortEntries(entries, q.Pagination)
+	if len(entries) > limit {
+		result.HasMore = true
+		result.NextCursor = entries[limit].ID
+		if q.Pagination.OrderBy == storage.OrderByTimestamp {
+			result.NextCursorTimestamp = entries[limit].Timestamp
+		}
+		entries = entries[:limit]
+	}
+	if entries == nil {
+		entries = make([]storage.LogEntry, 0)
+	}
+	result.Entries = entries
+
+	return result, nil
+}
+
+// sortEntries sorts merged entries in the query's result order.
+func sortEntries(entries []storage.LogEntry, p storage.Pagination) {
+	asc := p.Order == storage.OrderAsc
+	byTimestamp := p.OrderBy == storage.OrderByTimestamp
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := &entries[i], &entries[j]
+		if byTimestamp && !a.Timestamp.Equal(b.Timestamp) {
+			return a.Timestamp.Before(b.Timestamp) == asc
+		}
+		return (a.ID < b.ID) == asc
+	})
+}
+
+// GetByID implements storage.Store.
+func (r *Router) GetByID(ctx context.Context, id int64) (*storage.LogEntry, error) {
+	i, local, ok, err := r.splitID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	e, err := r.stores[i].GetByID(ctx, local)
+	if err != nil {
+		return nil, err
+	}
+	e.ID = id
+	return e, nil
+}
+
+// GetByIDs implements storage.Store.
+func (r *Router) GetByIDs(ctx context.Context, ids []int64) ([]storage.LogEntry, error) {
+	type key struct {
+		store int
+		local int64
+	}
+	global := make(map[key]int64, len(ids))
+	byStore := make(map[int][]int64)
+	for _, id := range ids {
+		i, local, ok, err := r.splitID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			global[key{i, local}] = id
+			byStore[i] = append(byStore[i], local)
+		}
+	}
+
+	found := make(map[int64]storage.LogEntry, len(ids))
+	for i, locals := range byStore {
+		entries, err := r.stores[i].GetByIDs(ctx, locals)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			e.ID = global[key{i, e.ID}]
+			found[e.ID] = e
+		}
+	}
+
+	entries := make([]storage.LogEntry, 0, len(found))
+	for _, id := range ids {
+		if e, ok := found[id]; ok {
+			entries = append(entries, e)
+			delete(found, id)
+		}
+	}
+	return entries, nil
+}
+
+// Delete implements storage.Store.
+func (r *Router) Delete(ctx context.Context, olderThan time.Time) (int64, error) {
+	var deleted int64
+	for _, s := range r.stores {
+		n, err := s.Delete(ctx, olderThan)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, r.pruneIDs(ctx)
+}
+
+// Stats implements storage.Store by combining the stats of all stores.
+func (r *Router) Stats(ctx context.Context) (*storage.Stats, error) {
+	stats := &storage.Stats{}
+	usage := make(map[string]*storage.NamespaceUsage)
+	for _, s := range r.stores {
+		st, err := s.Stats(ctx)
+		if err != nil {
+			return nil, err
+		}
+		stats.TotalEntries += st.TotalEntries
+		stats.DiskSizeBytes += st.DiskSizeBytes
+		if !st.OldestEntry.IsZero() && (stats.OldestEntry.IsZero() || st.OldestEntry.Before(stats.OldestEntry)) {
+			stats.OldestEntry = st.OldestEntry
+		}
+		if st.NewestEntry.After(stats.NewestEntry) {
+			stats.NewestEntry = st.NewestEntry
+		}
+		for _, nu := range st.Namespaces {
+			u, ok := usage[nu.Namespace]
+			if !ok {
+				u = &storage.NamespaceUsage{Namespace: nu.Namespace}
+				usage[nu.Namespace] = u
+			}
+			u.Entries += nu.Entries
+			u.Bytes += nu.Bytes
+		}
+	}
+
+	for _, u := range usage {
+		stats.Namespaces = append(stats.Namespaces, *u)
+	}
+	sort.Slice(stats.Namespaces, func(i, j int) bool {
+		a, b := stats.Namespaces[i], stats.Namespaces[j]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return a.Namespace < b.Namespace
+	})
+
+	return stats, nil
+}
+
+// Close implements storage.Store by closing every store.
+func (r *Router) Close() error {
+	var errs []error
+	for _, s := range r.stores {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,296 @@
+package router
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/kubelogs/kubelogs/internal/storage/sqlite"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	meta, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create metadata store: %v", err)
+	}
+	t.Cleanup(func() { meta.Close() })
+	return meta.DB()
+}
+
+func newTestRouter(t *testing.T, routes ...Route) *Router {
+	t.Helper()
+	var stores []storage.Store
+	for i := 0; i < 3; i++ {
+		s, err := sqlite.New(sqlite.Config{Path: ":memory:"})
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+		stores = append(stores, s)
+	}
+	r, err := New(Config{Stores: stores, Routes: routes, DB: newTestDB(t)})
+	if err != nil {
+		t.Fatalf("Failed to create router: %v", err)
+	}
+	return r
+}
+
+func TestStore(t *testing.T) {
+	storage.StoreTestSuite(t, func() (storage.Store, func()) {
+		r := newTestRouter(t,
+			Route{Namespaces: []string{"production"}, Store: 1},
+			Route{Namespaces: []string{"stag*", "ns"}, Store: 2},
+		)
+		return r, func() { r.Close() }
+	})
+}
+
+func TestTailAcrossStores(t *testing.T) {
+	r := newTestRouter(t, Route{Namespaces: []string{"quiet"}, Store: 1})
+	defer r.Close()
+	ctx := context.Background()
+
+	written := 0
+	write := func(r *Router, ns string, n int) {
+		t.Helper()
+		var batch storage.LogBatch
+		for i := 0; i < n; i++ {
+			batch = append(batch, storage.LogEntry{
+				Timestamp: time.Now(), Namespace: ns, Pod: "pod", Container: "c",
+				Message: fmt.Sprintf("m%d", written),
+			})
+			written++
+		}
+		if _, err := r.Write(ctx, batch); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	// Tail like a live tail does, resuming after the last ID seen
+	var lastID int64
+	var tailed []storage.LogEntry
+	tail := func(r *Router) {
+		t.Helper()
+		q := storage.Query{Pagination: storage.Pagination{Limit: 10, Order: storage.OrderAsc}}
+		for {
+			q.Pagination.AfterID = lastID
+			result, err := r.Query(ctx, q)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			for _, e := range result.Entries {
+				if e.ID <= lastID {
+					t.Fatalf("entry %d after cursor %d", e.ID, lastID)
+				}
+				lastID = e.ID
+				tailed = append(tailed, e)
+			}
+			if !result.HasMore {
+				return
+			}
+		}
+	}
+
+	// The busy store's local IDs race ahead of the quiet one's, whose
+	// entries must still come after the ones already tailed
+	for round := 0; round < 5; round++ {
+		write(r, "busy", 20)
+		tail(r)
+		write(r, "quiet", 1)
+		tail(r)
+	}
+
+	// IDs outlive the router
+	restarted, err := New(Config{Stores: r.stores, Routes: r.routes, DB: r.db})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	e, err := restarted.GetByID(ctx, tailed[3].ID)
+	if err != nil || e.Message != tailed[3].Message {
+		t.Errorf("GetByID after restart = %+v, %v, want %q", e, err, tailed[3].Message)
+	}
+	write(restarted, "quiet", 1)
+	tail(restarted)
+	write(restarted, "busy", 1)
+	tail(restarted)
+
+	if len(tailed) != written {
+		t.Fatalf("tailed %d entries, want %d", len(tailed), written)
+	}
+	for i, e := range tailed {
+		if want := fmt.Sprintf("m%d", i); e.Message != want {
+			t.Errorf("entry %d is %q, want %q", i, e.Message, want)
+		}
+	}
+}
+
+func TestMergedPagination(t *testing.T) {
+	r := newTestRouter(t,
+		Route{Namespaces: []string{"a"}, Store: 1},
+		Route{Namespaces: []string{"b"}, Store: 2},
+	)
+	defer r.Close()
+	ctx := context.Background()
+
+	// Interleaved timestamps across the three stores
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var batch storage.LogBatch
+	for i := 0; i < 30; i++ {
+		batch = append(batch, storage.LogEntry{
+			Timestamp: base.Add(time.Duration((i*7)%30) * time.Second),
+			Namespace: []string{"a", "b", "c"}[i%3],
+			Pod:       "pod", Container: "c",
+			Message: fmt.Sprintf("m%d", i),
+		})
+	}
+	if n, err := r.Write(ctx, batch); err != nil || n != 30 {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+
+	for _, p := range []storage.Pagination{
+		{Limit: 4},
+		{Limit: 4, Order: storage.OrderAsc},
+		{Limit: 4, OrderBy: storage.OrderByTimestamp},
+		{Limit: 4, OrderBy: storage.OrderByTimestamp, Order: storage.OrderAsc},
+	} {
+		t.Run(fmt.Sprintf("order %d by %d", p.Order, p.OrderBy), func(t *testing.T) {
+			all, err := r.Query(ctx, storage.Query{Pagination: storage.Pagination{Limit: 100, Order: p.Order, OrderBy: p.OrderBy}})
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if len(all.Entries) != 30 {
+				t.Fatalf("got %d entries, want 30", len(all.Entries))
+			}
+
+			// Page with the last entry of each page as the cursor
+			q := storage.Query{Pagination: p}
+			var paged []storage.LogEntry
+			for {
+				result, err := r.Query(ctx, q)
+				if err != nil {
+					t.Fatalf("Query failed: %v", err)
+				}
+				paged = append(paged, result.Entries...)
+				if !result.HasMore {
+					break
+				}
+				last := result.Entries[len(result.Entries)-1]
+				if p.Order == storage.OrderAsc {
+					q.Pagination.AfterID, q.Pagination.AfterTimestamp = last.ID, last.Timestamp
+				} else {
+					q.Pagination.BeforeID, q.Pagination.BeforeTimestamp = last.ID, last.Timestamp
+				}
+			}
+			if fmt.Sprint(paged) != fmt.Sprint(all.Entries) {
+				t.Errorf("paged results differ from a single query")
+			}
+		})
+	}
+
+	// Namespace filters only visit the routed store
+	result, err := r.Query(ctx, storage.Query{Namespace: "b"})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entries) != 10 {
+		t.Errorf("got %d entries in namespace b, want 10", len(result.Entries))
+	}
+	e, err := r.GetByID(ctx, result.Entries[0].ID)
+	if err != nil || e.Message != result.Entries[0].Message {
+		t.Errorf("GetByID = %+v, %v", e, err)
+	}
+
+	stats, err := r.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.TotalEntries != 30 || len(stats.Namespaces) != 3 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	rollups, err := r.Rollups(ctx, storage.RollupQuery{By: storage.RollupByNamespace, Limit: 2})
+	if err != nil {
+		t.Fatalf("Rollups failed: %v", err)
+	}
+	if len(rollups) != 2 || rollups[0].Lines != 10 {
+		t.Errorf("unexpected rollups %+v", rollups)
+	}
+
+	// Entries span 30s, so each 10s bucket gets 10 across the stores
+	buckets, err := r.Histogram(ctx, storage.Query{}, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Histogram failed: %v", err)
+	}
+	if len(buckets) != 3 || !buckets[0].Start.Equal(base) || buckets[0].Count != 10 || buckets[2].Count != 10 {
+		t.Errorf("unexpected histogram %+v", buckets)
+	}
+}
+
+func TestSpecBuild(t *testing.T) {
+	dir := t.TempDir()
+	specPath := filepath.Join(dir, "routes.json")
+	os.WriteFile(specPath, []byte(`{
+		"stores": [
+			{"name": "main", "backend": "sqlite", "path": ":memory:"},
+			{"name": "prod", "backend": "sqlite", "path": ":memory:"}
+		],
+		"routes": [{"namespaces": ["prod-*"], "store": "prod"}]
+	}`), 0o644)
+
+	open := func(ss StoreSpec) (storage.Store, error) {
+		if ss.Backend != "sqlite" {
+			return nil, fmt.Errorf("unknown backend %q", ss.Backend)
+		}
+		return sqlite.New(sqlite.Config{Path: ss.Path})
+	}
+
+	spec, err := LoadSpec(specPath)
+	if err != nil {
+		t.Fatalf("LoadSpec failed: %v", err)
+	}
+	r, err := spec.Build(newTestDB(t), open)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	defer r.Close()
+	if got := r.storeFor("prod-eu"); got != 1 {
+		t.Errorf("prod-eu routed to %d, want 1", got)
+	}
+	if got := r.storeFor("dev"); got != 0 {
+		t.Errorf("dev routed to %d, want 0", got)
+	}
+
+	invalid := []Spec{
+		{},
+		{Stores: []StoreSpec{{Name: "a"}, {Name: "a"}}},
+		{Stores: []StoreSpec{{Name: "a"}}, Default: "b"},
+		{Stores: []StoreSpec{{Name: "a"}}, Routes: []RouteSpec{{Namespaces: []string{"x"}, Store: "b"}}},
+		{Stores: []StoreSpec{{Name: "a", Backend: "sqlite", Path: ":memory:"}}, Routes: []RouteSpec{{Namespaces: []string{"["}, Store: "a"}}},
+		{Stores: []StoreSpec{{Name: "a", Backend: "postgres"}}},
+	}
+	for i, spec := range invalid {
+		if _, err := spec.Build(newTestDB(t), open); err == nil {
+			t.Errorf("spec %d: expected error", i)
+		}
+	}
+}
+
+func TestStoreSpecOptions(t *testing.T) {
+	ss := StoreSpec{
+		Backend:   "s3",
+		Bucket:    "logs",
+		Prefix:    "prod/",
+		PathStyle: true,
+		Extra:     map[string]string{"prefix": "override/", "cache_max_bytes": "1024"},
+	}
+	want := storage.Options{"bucket": "logs", "prefix": "override/", "path_style": "true", "cache_max_bytes": "1024"}
+	if got := ss.Options(); !maps.Equal(got, want) {
+		t.Errorf("Options() = %v, want %v", got, want)
+	}
+}
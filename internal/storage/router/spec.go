@@ -0,0 +1,161 @@
+package router
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// Spec is a declarative routing configuration, read from JSON:
+//
+//	{
+//	  "stores": [
+//	    {"name": "main", "backend": "sqlite", "path": "/data/kubelogs.db"},
+//	    {"name": "prod", "backend": "s3", "bucket": "prod-logs"}
+//	  ],
+//	  "routes": [
+//	    {"namespaces": ["prod", "prod-*"], "store": "prod"}
+//	  ],
+//	  "default": "main"
+//	}
+//
+// Stores are numbered in the order listed, which is part of entry IDs:
+// append new stores rather than reordering existing ones.
+type Spec struct {
+	Stores []StoreSpec `json:"stores"`
+	Routes []RouteSpec `json:"routes"`
+
+	// Default names the store for unrouted namespaces. Default: the
+	// first store.
+	Default string `json:"default"`
+}
+
+// StoreSpec describes one backing store. Which fields apply depends on
+// the backend; the caller of Build interprets them, usually by opening
+// storage.Open(Backend, Options()).
+type StoreSpec struct {
+	Name    string `json:"name"`
+	Backend string `json:"backend"`
+
+	// Path is the database file of a "sqlite" store.
+	Path string `json:"path,omitempty"`
+
+	// DSN is the connection string of a "postgres" store.
+	DSN string `json:"dsn,omitempty"`
+
+	// Object storage location of an "s3" store. Credentials come from
+	// the environment.
+	Endpoint  string `json:"endpoint,omitempty"`
+	Region    string `json:"region,omitempty"`
+	Bucket    string `json:"bucket,omitempty"`
+	Prefix    string `json:"prefix,omitempty"`
+	PathStyle bool   `json:"pathStyle,omitempty"`
+	CacheDir  string `json:"cacheDir,omitempty"`
+
+	// Extra holds options of other backends, passed through as is.
+	Extra map[string]string `json:"options,omitempty"`
+}
+
+// Options returns the backend options of the store: the fields above
+// that are set, under their storage.Open option names, and Extra, which
+// takes precedence.
+func (ss StoreSpec) Options() storage.Options {
+	opts := make(storage.Options, len(ss.Extra)+8)
+	set := func(key, value string) {
+		if value != "" {
+			opts[key] = value
+		}
+	}
+	set("path", ss.Path)
+	set("dsn", ss.DSN)
+	set("endpoint", ss.Endpoint)
+	set("region", ss.Region)
+	set("bucket", ss.Bucket)
+	set("prefix", ss.Prefix)
+	set("cache_dir", ss.CacheDir)
+	if ss.PathStyle {
+		opts["path_style"] = "true"
+	}
+	for k, v := range ss.Extra {
+		opts[k] = v
+	}
+	return opts
+}
+
+// RouteSpec routes namespaces to a named store.
+type RouteSpec struct {
+	Namespaces []string `json:"namespaces"`
+	Store      string   `json:"store"`
+}
+
+// LoadSpec reads a Spec from a JSON file.
+func LoadSpec(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read routes: %w", err)
+	}
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("parse routes %s: %w", path, err)
+	}
+	return &spec, nil
+}
+
+// Build opens every store with open and returns a router over them,
+// keeping its IDs in db. If the spec is invalid or a store fails to open,
+// stores opened so far are closed.
+func (s *Spec) Build(db *sql.DB, open func(StoreSpec) (storage.Store, error)) (*Router, error) {
+	if len(s.Stores) == 0 {
+		return nil, errors.New("router: no stores")
+	}
+	index := make(map[string]int, len(s.Stores))
+	for i, ss := range s.Stores {
+		if ss.Name == "" {
+			return nil, fmt.Errorf("router: store %d has no name", i)
+		}
+		if _, dup := index[ss.Name]; dup {
+			return nil, fmt.Errorf("router: duplicate store %q", ss.Name)
+		}
+		index[ss.Name] = i
+	}
+
+	cfg := Config{DB: db}
+	if s.Default != "" {
+		i, ok := index[s.Default]
+		if !ok {
+			return nil, fmt.Errorf("router: unknown default store %q", s.Default)
+		}
+		cfg.Default = i
+	}
+	for _, rs := range s.Routes {
+		i, ok := index[rs.Store]
+		if !ok {
+			return nil, fmt.Errorf("router: route to unknown store %q", rs.Store)
+		}
+		cfg.Routes = append(cfg.Routes, Route{Namespaces: rs.Namespaces, Store: i})
+	}
+
+	for _, ss := range s.Stores {
+		store, err := open(ss)
+		if err != nil {
+			for _, opened := range cfg.Stores {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("open store %q: %w", ss.Name, err)
+		}
+		cfg.Stores = append(cfg.Stores, store)
+	}
+
+	r, err := New(cfg)
+	if err != nil {
+		for _, opened := range cfg.Stores {
+			opened.Close()
+		}
+		return nil, err
+	}
+	return r, nil
+}
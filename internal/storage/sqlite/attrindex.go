@@ -0,0 +1,115 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// attrColumnPrefix starts the names of the generated columns holding
+// indexed attributes, e.g. "attr.trace_id".
+const attrColumnPrefix = "attr."
+
+// attrColumns is the set of indexed attribute keys. Every shard has a
+// generated column extracting each of them from the attributes, with a
+// partial index over the entries that have it, so filters on them don't
+// scan the shard.
+type attrColumns map[string]bool
+
+// newAttrColumns returns the set of keys, checking they can name a
+// column.
+func newAttrColumns(keys []string) (attrColumns, error) {
+	cols := make(attrColumns, len(keys))
+	for _, k := range keys {
+		if k == "" || strings.ContainsAny(k, `"'`) {
+			return nil, fmt.Errorf("invalid indexed attribute %q", k)
+		}
+		cols[k] = true
+	}
+	return cols, nil
+}
+
+// attrColumn returns the quoted column of indexed attribute key.
+func attrColumn(key string) string {
+	return `"` + attrColumnPrefix + key + `"`
+}
+
+// attrIndex returns the quoted name of the index on the column of key
+// in table.
+func attrIndex(table, key string) string {
+	return `"idx_` + table + `_` + attrColumnPrefix + key + `"`
+}
+
+// expr returns the SQL expression of attribute key of alias l, and its
+// arguments appended to args: the generated column if key is indexed,
+// or else its extraction from the JSON attributes. Both are NULL for
+// entries without it.
+func (c attrColumns) expr(key string, args []any) (string, []any) {
+	if c[key] {
+		return "l." + attrColumn(key), args
+	}
+	// Quoted, so keys like label.app aren't read as nested paths
+	return "json_extract(l.attributes, ?)", append(args, `$."`+key+`"`)
+}
+
+// execQuerier is a *sql.DB or *sql.Tx.
+type execQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// syncAttrColumns adds the generated columns and indexes of the keys in
+// c that table lacks, and drops those of keys no longer indexed.
+func syncAttrColumns(ctx context.Context, db execQuerier, table string, c attrColumns) error {
+	// table_info leaves out generated columns
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("PRAGMA table_xinfo(%s)", table))
+	if err != nil {
+		return fmt.Errorf("list columns of %s: %w", table, err)
+	}
+	var have []string
+	for rows.Next() {
+		var cid, notnull, pk, hidden int
+		var name, ctype string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &ctype, &notnull, &dflt, &pk, &hidden); err != nil {
+			rows.Close()
+			return fmt.Errorf("list columns of %s: %w", table, err)
+		}
+		if key, ok := strings.CutPrefix(name, attrColumnPrefix); ok {
+			have = append(have, key)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("list columns of %s: %w", table, err)
+	}
+
+	var stmts []string
+	for _, key := range have {
+		if !c[key] {
+			stmts = append(stmts,
+				`DROP INDEX IF EXISTS `+attrIndex(table, key),
+				`ALTER TABLE `+table+` DROP COLUMN `+attrColumn(key))
+		}
+	}
+	for key := range c {
+		if slices.Contains(have, key) {
+			continue
+		}
+		// Virtual, so adding one rewrites nothing; only its index is
+		// built from the existing entries
+		stmts = append(stmts,
+			`ALTER TABLE `+table+` ADD COLUMN `+attrColumn(key)+
+				` TEXT GENERATED ALWAYS AS (json_extract(attributes, '$."`+key+`"')) VIRTUAL`,
+			`CREATE INDEX IF NOT EXISTS `+attrIndex(table, key)+` ON `+table+`(`+attrColumn(key)+`)`+
+				` WHERE `+attrColumn(key)+` IS NOT NULL`)
+	}
+	for _, stmt := range stmts {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("index attributes of %s: %w", table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// sqliteRecursive is SQLITE_RECURSIVE, which go-sqlite3 doesn't export.
+// SQLite checks it for recursive common table expressions.
+const sqliteRecursive = 33
+
+// consoleHiddenTables hold credentials and can't be read from the console.
+var consoleHiddenTables = map[string]bool{
+	"users":    true,
+	"sessions": true,
+}
+
+// consolePragmas can't change anything, whatever their argument.
+// data_version is read by FTS5 itself.
+var consolePragmas = map[string]bool{
+	"data_version":     true,
+	"table_info":       true,
+	"table_xinfo":      true,
+	"index_list":       true,
+	"index_info":       true,
+	"index_xinfo":      true,
+	"foreign_key_list": true,
+}
+
+// consoleAuthorizer allows only what a read-only SELECT needs.
+func consoleAuthorizer(op int, arg1, arg2, _ string) int {
+	switch op {
+	case sqlite3.SQLITE_SELECT, sqliteRecursive:
+		return sqlite3.SQLITE_OK
+	case sqlite3.SQLITE_READ:
+		if consoleHiddenTables[arg1] {
+			return sqlite3.SQLITE_DENY
+		}
+		return sqlite3.SQLITE_OK
+	case sqlite3.SQLITE_PRAGMA:
+		if consolePragmas[arg1] {
+			return sqlite3.SQLITE_OK
+		}
+		return sqlite3.SQLITE_DENY
+	case sqlite3.SQLITE_FUNCTION:
+		if arg2 == "load_extension" {
+			return sqlite3.SQLITE_DENY
+		}
+		return sqlite3.SQLITE_OK
+	}
+	return sqlite3.SQLITE_DENY
+}
+
+// setAuthorizer installs fn, or removes the authorizer if fn is nil, on
+// the connection.
+func setAuthorizer(conn *sql.Conn, fn func(int, string, string, string) int) error {
+	return conn.Raw(func(dc any) error {
+		c, ok := dc.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection %T", dc)
+		}
+		c.RegisterAuthorizer(fn)
+		return nil
+	})
+}
+
+// QueryReadOnly implements storage.SQLConsole. An authorizer on the
+// connection rejects every statement other than reads while the query
+// is prepared, so writes, schema changes, ATTACH and most PRAGMAs fail.
+// The query holds the store's only connection, so callers should bound
+// it with a short deadline.
+func (s *Store) QueryReadOnly(ctx context.Context, query string, maxRows int) (*storage.SQLResult, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, storage.ErrStorageClosed
+	}
+	s.mu.Unlock()
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := setAuthorizer(conn, consoleAuthorizer); err != nil {
+		return nil, err
+	}
+	defer setAuthorizer(conn, nil)
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := &storage.SQLResult{Columns: columns, Rows: [][]any{}}
+
+	values := make([]any, len(columns))
+	ptrs := make([]any, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if len(result.Rows) >= maxRows {
+			result.Truncated = true
+			break
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		row := make([]any, len(columns))
+		for i, v := range values {
+			switch v := v.(type) {
+			case []byte:
+				row[i] = string(v)
+			case time.Time:
+				row[i] = v.Format(time.RFC3339Nano)
+			default:
+				row[i] = v
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Schema implements storage.SQLConsole. Internal sqlite_ objects and
+// FTS5 shadow tables, which their virtual table creates, are left out.
+func (s *Store) Schema(ctx context.Context) ([]storage.SchemaObject, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT type, name, tbl_name, COALESCE(sql, '') FROM sqlite_master
+		WHERE type IN ('table', 'view', 'index', 'trigger') AND name NOT LIKE 'sqlite\_%' ESCAPE '\'
+		AND name NOT IN (SELECT name FROM pragma_table_list WHERE type = 'shadow')
+		ORDER BY tbl_name, type NOT IN ('table', 'view'), name`)
+	if err != nil {
+		return nil, fmt.Errorf("read schema: %w", err)
+	}
+	defer rows.Close()
+
+	var objects []storage.SchemaObject
+	for rows.Next() {
+		var o storage.SchemaObject
+		if err := rows.Scan(&o.Type, &o.Name, &o.Table, &o.SQL); err != nil {
+			return nil, fmt.Errorf("read schema: %w", err)
+		}
+		objects = append(objects, o)
+	}
+	return objects, rows.Err()
+}
@@ -0,0 +1,387 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+const (
+	// shedAfterFlushes is how many flushes in a row must find the write
+	// backlog above Config.FTSShedBacklog before FTS indexing is shed,
+	// so a single burst doesn't trigger it.
+	shedAfterFlushes = 3
+
+	// ftsIndexInterval is how often the background indexer checks for
+	// entries written while shedding.
+	ftsIndexInterval = time.Second
+
+	// ftsIndexChunk bounds the IDs the background indexer indexes per
+	// transaction, so writes waiting for the lock aren't held up long.
+	ftsIndexChunk = 5000
+)
+
+// updateShedding decides whether the next write sheds FTS indexing from
+// the entries waiting to be written: those buffered and those of
+// flushes waiting for the write lock. Shedding starts once the backlog
+// stays above s.shedBacklog for shedAfterFlushes flushes, and stops when
+// it falls to half of it. Callers hold s.writeMu.
+func (s *Store) updateShedding() {
+	if s.shedBacklog <= 0 {
+		return
+	}
+	s.mu.Lock()
+	backlog := s.pending + len(s.buffer)
+	s.mu.Unlock()
+
+	switch {
+	case backlog > s.shedBacklog:
+		s.overFlushes++
+		if s.overFlushes >= shedAfterFlushes && !s.shedding.Load() {
+			s.shedding.Store(true)
+			slog.Warn("write backlog high, shedding full-text indexing",
+				"db", s.path, "backlog", backlog, "threshold", s.shedBacklog)
+		}
+	case backlog <= s.shedBacklog/2:
+		s.overFlushes = 0
+		if s.shedding.Load() {
+			s.shedding.Store(false)
+			slog.Info("write backlog cleared, resuming full-text indexing",
+				"db", s.path, "backlog", backlog, "unindexed", s.ftsBacklog.Load())
+		}
+	default:
+		s.overFlushes = 0
+	}
+}
+
+// unindexedRange is the IDs a shedding write stored in one shard.
+type unindexedRange struct {
+	first, last int64
+	entries     int64
+}
+
+// dropFTSTrigger stops the FTS indexing of entries inserted into table
+// by tx, until restoreFTSTriggers.
+func dropFTSTrigger(ctx context.Context, tx *sql.Tx, table string) error {
+	if _, err := tx.ExecContext(ctx, `DROP TRIGGER IF EXISTS `+table+`_ai`); err != nil {
+		return fmt.Errorf("shed indexing of %s: %w", table, err)
+	}
+	return nil
+}
+
+// restoreFTSTriggers recreates the FTS triggers dropFTSTrigger dropped
+// and queues the entries inserted meanwhile for background indexing.
+func restoreFTSTriggers(ctx context.Context, tx *sql.Tx, ranges map[string]*unindexedRange) error {
+	for table, r := range ranges {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(ftsInsertTriggerSQL, table)); err != nil {
+			return fmt.Errorf("restore indexing of %s: %w", table, err)
+		}
+		if r.entries == 0 {
+			continue
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO fts_backlog (shard, first_id, last_id, entries) VALUES (?, ?, ?, ?)`,
+			table, r.first, r.last, r.entries)
+		if err != nil {
+			return fmt.Errorf("queue indexing of %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// indexRange adds the entries of table with IDs in [first, last] to its
+// FTS table and returns their number.
+func indexRange(ctx context.Context, tx *sql.Tx, table string, first, last int64) (int64, error) {
+	result, err := tx.ExecContext(ctx, `INSERT INTO `+table+`_fts(rowid, message)
+		SELECT id, message FROM `+table+` WHERE id BETWEEN ? AND ?`, first, last)
+	if err != nil {
+		return 0, fmt.Errorf("index %s: %w", table, err)
+	}
+	return result.RowsAffected()
+}
+
+// indexShardBacklog indexes all entries of table written while
+// shedding. Deletes call it first: removing an entry from an FTS table
+// that never indexed it corrupts the index.
+func indexShardBacklog(ctx context.Context, tx *sql.Tx, table string) error {
+	rows, err := tx.QueryContext(ctx, `SELECT first_id, last_id FROM fts_backlog WHERE shard = ?`, table)
+	if err != nil {
+		return fmt.Errorf("read index backlog: %w", err)
+	}
+	var ranges [][2]int64
+	for rows.Next() {
+		var r [2]int64
+		if err := rows.Scan(&r[0], &r[1]); err != nil {
+			rows.Close()
+			return fmt.Errorf("read index backlog: %w", err)
+		}
+		ranges = append(ranges, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("read index backlog: %w", err)
+	}
+
+	for _, r := range ranges {
+		if _, err := indexRange(ctx, tx, table, r[0], r[1]); err != nil {
+			return err
+		}
+	}
+	if len(ranges) > 0 {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM fts_backlog WHERE shard = ?`, table); err != nil {
+			return fmt.Errorf("clear index backlog: %w", err)
+		}
+	}
+	return nil
+}
+
+// ftsBacklogEntries returns the number of entries awaiting indexing, of
+// shard only unless it's empty.
+func ftsBacklogEntries(ctx context.Context, db execQuerier, shard string) (int64, error) {
+	rows, err := db.QueryContext(ctx, `SELECT COALESCE(SUM(entries), 0) FROM fts_backlog WHERE ? IN ('', shard)`, shard)
+	if err != nil {
+		return 0, fmt.Errorf("read index backlog: %w", err)
+	}
+	defer rows.Close()
+	var n int64
+	if rows.Next() {
+		if err := rows.Scan(&n); err != nil {
+			return 0, fmt.Errorf("read index backlog: %w", err)
+		}
+	}
+	return n, rows.Err()
+}
+
+// indexBacklog indexes up to about limit IDs of the oldest entries
+// awaiting indexing, unless shedding is still on, refreshes s.ftsBacklog
+// and returns the number of entries left. With shard set, only that
+// shard's entries are indexed, even while shedding, and counted, for
+// rebuilds.
+func (s *Store) indexBacklog(ctx context.Context, shard string, limit int64) (int64, error) {
+	unlock := s.lockWrite()
+	defer unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	for limit > 0 && (shard != "" || !s.shedding.Load()) {
+		var rowid, first, last, entries int64
+		var table string
+		err := tx.QueryRowContext(ctx, `SELECT rowid, shard, first_id, last_id, entries FROM fts_backlog
+			WHERE ? IN ('', shard) ORDER BY rowid LIMIT 1`, shard).
+			Scan(&rowid, &table, &first, &last, &entries)
+		if err == sql.ErrNoRows {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("read index backlog: %w", err)
+		}
+
+		end := min(last, first+limit-1)
+		n, err := indexRange(ctx, tx, table, first, end)
+		if err != nil {
+			return 0, err
+		}
+		limit -= end - first + 1
+		if end == last {
+			_, err = tx.ExecContext(ctx, `DELETE FROM fts_backlog WHERE rowid = ?`, rowid)
+		} else {
+			_, err = tx.ExecContext(ctx, `UPDATE fts_backlog SET first_id = ?, entries = ? WHERE rowid = ?`,
+				end+1, max(entries-n, 0), rowid)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("update index backlog: %w", err)
+		}
+	}
+
+	backlog, err := ftsBacklogEntries(ctx, tx, "")
+	if err != nil {
+		return 0, err
+	}
+	left := backlog
+	if shard != "" {
+		if left, err = ftsBacklogEntries(ctx, tx, shard); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	s.ftsBacklog.Store(backlog)
+	return left, nil
+}
+
+// RebuildSearchIndex implements storage.SearchIndexRebuilder, a shard at
+// a time. Each shard's FTS table is recreated from the current schema,
+// so tokenizer changes apply, and its entries are queued in fts_backlog
+// and indexed in chunks of ftsIndexChunk IDs between writes. Searches
+// miss the entries of the shard being rebuilt until their chunk is
+// indexed. If ctx ends the rebuild, the background indexer finishes the
+// queued entries.
+func (s *Store) RebuildSearchIndex(ctx context.Context, progress func(storage.IndexProgress)) (int64, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return 0, storage.ErrStorageClosed
+	}
+	s.mu.Unlock()
+
+	shards, total, err := s.countShards(ctx)
+	if err != nil {
+		return 0, err
+	}
+	p := storage.IndexProgress{Total: total}
+
+	// Progress counts what's left of a shard's queue, as the background
+	// indexer may take chunks of it too
+	for _, sh := range shards {
+		queued, err := s.resetShardIndex(ctx, sh)
+		if err != nil {
+			return p.Indexed, err
+		}
+		done := p.Indexed
+		for left := queued; left > 0; {
+			if left, err = s.indexBacklog(ctx, sh.name, ftsIndexChunk); err != nil {
+				return p.Indexed, err
+			}
+			p.Indexed = done + queued - left
+			if progress != nil {
+				progress(p)
+			}
+		}
+	}
+	return p.Indexed, nil
+}
+
+// countShards returns the shards and the number of entries they hold,
+// read in one transaction so that retention can't drop a shard between.
+func (s *Store) countShards(ctx context.Context) ([]shard, int64, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT name FROM log_shards ORDER BY day_start`)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query shards: %w", err)
+	}
+	var shards []shard
+	for rows.Next() {
+		var sh shard
+		if err := rows.Scan(&sh.name); err != nil {
+			rows.Close()
+			return nil, 0, fmt.Errorf("scan shard: %w", err)
+		}
+		shards = append(shards, sh)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("query shards: %w", err)
+	}
+
+	var total int64
+	for _, sh := range shards {
+		var n int64
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+sh.name).Scan(&n); err != nil {
+			return nil, 0, fmt.Errorf("count shard %s: %w", sh.name, err)
+		}
+		total += n
+	}
+	return shards, total, nil
+}
+
+// resetShardIndex recreates the FTS table of sh empty, queues all its
+// entries for indexing and returns their number. Shards retention
+// dropped meanwhile are skipped.
+func (s *Store) resetShardIndex(ctx context.Context, sh shard) (int64, error) {
+	unlock := s.lockWrite()
+	defer unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM log_shards WHERE name = ?)`, sh.name).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("check shard %s: %w", sh.name, err)
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	// The shard's triggers outlive its FTS table and work again once
+	// it's recreated
+	for _, stmt := range []string{
+		`DROP TABLE IF EXISTS ` + sh.name + `_fts`,
+		fmt.Sprintf(shardSchemaSQL, sh.name),
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return 0, fmt.Errorf("reset index of %s: %w", sh.name, err)
+		}
+	}
+
+	// The whole shard replaces the ranges left by shedding
+	if _, err := tx.ExecContext(ctx, `DELETE FROM fts_backlog WHERE shard = ?`, sh.name); err != nil {
+		return 0, fmt.Errorf("queue indexing of %s: %w", sh.name, err)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO fts_backlog (shard, first_id, last_id, entries)
+		SELECT ?, MIN(id), MAX(id), COUNT(*) FROM `+sh.name+` HAVING COUNT(*) > 0`, sh.name)
+	if err != nil {
+		return 0, fmt.Errorf("queue indexing of %s: %w", sh.name, err)
+	}
+
+	queued, err := ftsBacklogEntries(ctx, tx, sh.name)
+	if err != nil {
+		return 0, err
+	}
+	backlog, err := ftsBacklogEntries(ctx, tx, "")
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	s.ftsBacklog.Store(backlog)
+	return queued, nil
+}
+
+// indexLoop indexes the entries written while shedding, or left by an
+// interrupted rebuild, in the background, a chunk at a time, until s.stopIndex is closed.
+func (s *Store) indexLoop() {
+	defer close(s.indexDone)
+	ticker := time.NewTicker(ftsIndexInterval)
+	defer ticker.Stop()
+
+	ctx := context.Background()
+	for {
+		select {
+		case <-s.stopIndex:
+			return
+		case <-ticker.C:
+		}
+		// Chunks until the backlog is indexed, letting writes in between
+		for s.ftsBacklog.Load() > 0 {
+			if _, err := s.indexBacklog(ctx, "", ftsIndexChunk); err != nil {
+				slog.Warn("failed to index backlog", "db", s.path, "error", err)
+				break
+			}
+			if s.shedding.Load() {
+				break
+			}
+			select {
+			case <-s.stopIndex:
+				return
+			default:
+			}
+		}
+	}
+}
@@ -6,10 +6,12 @@ import (
 )
 
 // computeDedupHash generates a 64-bit FNV-1a hash for deduplication.
-// The hash is computed from timestamp + namespace + pod + container + message.
+// The hash is computed from timestamp + cluster + namespace + pod + container + message.
 // Null byte separators prevent collisions between different field combinations
 // (e.g., namespace="a", pod="bc" vs namespace="ab", pod="c").
-func computeDedupHash(timestampNano int64, namespace, pod, container, message string) int64 {
+// An empty cluster is left out, so entries written before clusters existed
+// keep their hashes.
+func computeDedupHash(timestampNano int64, cluster, namespace, pod, container, message string) int64 {
 	h := fnv.New64a()
 
 	// Write timestamp as 8 bytes (little-endian)
@@ -18,6 +20,10 @@ func computeDedupHash(timestampNano int64, namespace, pod, container, message st
 	h.Write(buf[:])
 
 	// Write strings with null separators
+	if cluster != "" {
+		h.Write([]byte(cluster))
+		h.Write([]byte{0})
+	}
 	h.Write([]byte(namespace))
 	h.Write([]byte{0})
 	h.Write([]byte(pod))
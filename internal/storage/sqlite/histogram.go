@@ -0,0 +1,177 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// Histogram implements storage.Aggregator.
+func (s *Store) Histogram(ctx context.Context, q storage.Query, interval time.Duration) ([]storage.HistogramBucket, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("histogram interval must be positive, got %v", interval)
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, storage.ErrStorageClosed
+	}
+	s.mu.Unlock()
+
+	// Flush so buffered writes are counted
+	if err := s.flushFor(ctx, q); err != nil {
+		return nil, err
+	}
+
+	q.Pagination = storage.Pagination{}
+	shards := s.queryShards(q)
+	buckets := make([]storage.HistogramBucket, 0)
+	if len(shards) == 0 {
+		return buckets, nil
+	}
+
+	// Count per shard, then add up buckets spanning shards
+	selects := make([]string, len(shards))
+	var args []any
+	for i, sh := range shards {
+		from, fromArgs := buildFrom(q, sh.name, s.attrColumns)
+		selects[i] = "SELECT l.timestamp / ? * ? AS bucket, l.severity AS severity, COUNT(*) AS n" + from + " GROUP BY 1, 2"
+		args = append(args, int64(interval), int64(interval))
+		args = append(args, fromArgs...)
+	}
+	query := "SELECT bucket, severity, SUM(n) FROM (" + unionAll(selects) + ") GROUP BY 1, 2 ORDER BY 1, 2"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b storage.HistogramBucket
+		var start int64
+		if err := rows.Scan(&start, &b.Severity, &b.Count); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		b.Start = time.Unix(0, start)
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+// Aggregate implements storage.GroupAggregator.
+func (s *Store) Aggregate(ctx context.Context, q storage.Query, a storage.Aggregation) ([]storage.AggregateGroup, error) {
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, storage.ErrStorageClosed
+	}
+	s.mu.Unlock()
+
+	// Flush so buffered writes are counted
+	if err := s.flushFor(ctx, q); err != nil {
+		return nil, err
+	}
+
+	q.Pagination = storage.Pagination{}
+	shards := s.queryShards(q)
+	groups := make([]storage.AggregateGroup, 0)
+	if len(shards) == 0 {
+		return groups, nil
+	}
+
+	// The innermost select picks the bucket, keys and raw value of each
+	// entry, the middle one converts numeric values, and the outer one
+	// summarizes the shard. Shard summaries are then combined.
+	cols := []string{"0 AS b"}
+	var colArgs []any
+	if a.Interval > 0 {
+		cols[0] = "l.timestamp / ? * ? AS b"
+		colArgs = append(colArgs, int64(a.Interval), int64(a.Interval))
+	}
+	keys := []string{"b"}
+	for i, f := range a.GroupBy {
+		k := fmt.Sprintf("k%d", i)
+		if attr, ok := storage.GroupAttr(f); ok {
+			var expr string
+			expr, colArgs = s.attrColumns.expr(attr, colArgs)
+			cols = append(cols, "COALESCE("+expr+", '') AS "+k)
+		} else {
+			cols = append(cols, "l."+f+" AS "+k)
+		}
+		keys = append(keys, k)
+	}
+	if a.Value != "" {
+		var expr string
+		expr, colArgs = s.attrColumns.expr(a.Value, colArgs)
+		cols = append(cols, expr+" AS v")
+	} else {
+		cols = append(cols, "NULL AS v")
+	}
+	groupBy := strings.Join(keys, ", ")
+
+	selects := make([]string, len(shards))
+	var args []any
+	for i, sh := range shards {
+		from, fromArgs := buildFrom(q, sh.name, s.attrColumns)
+		selects[i] = "SELECT " + groupBy + ", COUNT(*) AS n, COUNT(x) AS xn, TOTAL(x) AS xs, MIN(x) AS xmin, MAX(x) AS xmax" +
+			" FROM (SELECT " + groupBy + ", CASE WHEN json_valid(v) THEN CASE WHEN json_type(v) IN ('integer', 'real') THEN CAST(v AS REAL) END END AS x" +
+			" FROM (SELECT " + strings.Join(cols, ", ") + from + "))" +
+			" GROUP BY " + groupBy
+		args = append(args, colArgs...)
+		args = append(args, fromArgs...)
+	}
+	query := "SELECT " + groupBy + ", SUM(n), SUM(xn), TOTAL(xs), MIN(xmin), MAX(xmax) FROM (" + unionAll(selects) + ") GROUP BY " + groupBy
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var start int64
+		var vmin, vmax sql.NullFloat64
+		g := storage.AggregateGroup{Keys: make([]string, len(a.GroupBy))}
+		dest := []any{&start}
+		for i := range g.Keys {
+			dest = append(dest, &g.Keys[i])
+		}
+		dest = append(dest, &g.Count, &g.ValueCount, &g.Sum, &vmin, &vmax)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		if a.Interval > 0 {
+			g.Start = time.Unix(0, start)
+		}
+		g.Min, g.Max = vmin.Float64, vmax.Float64
+		nameSeverities(a, g.Keys)
+		groups = append(groups, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	storage.SortGroups(groups)
+	return groups, nil
+}
+
+// nameSeverities replaces the severity numbers in keys, grouped by a,
+// with their names.
+func nameSeverities(a storage.Aggregation, keys []string) {
+	for i, f := range a.GroupBy {
+		if f == storage.GroupBySeverity {
+			n, _ := strconv.Atoi(keys[i])
+			keys[i] = storage.Severity(n).String()
+		}
+	}
+}
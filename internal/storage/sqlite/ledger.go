@@ -0,0 +1,530 @@
+package sqlite
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// ledgerChunk bounds the ledger rows read per query while verifying, so
+// writes aren't held up by a long read.
+const ledgerChunk = 500
+
+// Kinds of ledger rows: a flush inserting entries, or what deleted them.
+const (
+	ledgerFlush     = "flush"
+	ledgerRetention = "retention" // Delete
+	ledgerCluster   = "cluster"   // DeleteCluster
+	ledgerExpired   = "expired"   // DeleteExpired
+	ledgerQuery     = "query"     // DeleteByQuery
+)
+
+// ledgerHead is the last row of the ledger.
+type ledgerHead struct {
+	seq  int64
+	hash []byte // Zero-length before the first row
+}
+
+// entryHasher hashes entries with their columns as stored. Each field is
+// length-prefixed, so no two entries hash the same bytes.
+type entryHasher struct {
+	buf []byte
+}
+
+// sum hashes the entry stored with id.
+func (e *entryHasher) sum(id, timestamp int64, cluster, namespace, pod, container string, severity int64, message string, attrs *string, expires *int64) [sha256.Size]byte {
+	b := e.buf[:0]
+	b = binary.LittleEndian.AppendUint64(b, uint64(id))
+	b = binary.LittleEndian.AppendUint64(b, uint64(timestamp))
+	b = binary.LittleEndian.AppendUint64(b, uint64(severity))
+	var exp int64
+	if expires != nil {
+		exp = *expires
+	}
+	b = binary.LittleEndian.AppendUint64(b, uint64(exp))
+	var attributes string
+	if attrs != nil {
+		attributes = *attrs
+	}
+	for _, s := range []string{cluster, namespace, pod, container, message, attributes} {
+		b = binary.AppendUvarint(b, uint64(len(s)))
+		b = append(b, s...)
+	}
+	e.buf = b
+	return sha256.Sum256(b)
+}
+
+// flushHashes collects the hashes of the entries a flush inserts. IDs
+// are allocated consecutively, so the hash of the entry with id is at
+// (id - first) * sha256.Size.
+type flushHashes struct {
+	entryHasher
+	first   int64
+	last    int64
+	entries int64
+	hashes  []byte
+}
+
+func newFlushHashes() *flushHashes {
+	return &flushHashes{}
+}
+
+// add hashes the entry stored with id, the next ID of the flush.
+func (f *flushHashes) add(id, timestamp int64, cluster, namespace, pod, container string, severity int64, message string, attrs *string, expires *int64) {
+	if f.entries == 0 {
+		f.first = id
+	}
+	f.last = id
+	f.entries++
+	sum := f.sum(id, timestamp, cluster, namespace, pod, container, severity, message, attrs, expires)
+	f.hashes = append(f.hashes, sum[:]...)
+}
+
+// idRanges collects the IDs of deleted entries as runs of consecutive
+// IDs, which is how they are mostly stored.
+type idRanges struct {
+	runs  [][2]int64 // First and last ID
+	count int64
+}
+
+// add adds id. IDs added in ascending order make the fewest runs.
+func (r *idRanges) add(id int64) {
+	r.count++
+	if n := len(r.runs); n > 0 && r.runs[n-1][1]+1 == id {
+		r.runs[n-1][1] = id
+		return
+	}
+	r.runs = append(r.runs, [2]int64{id, id})
+}
+
+// bounds returns the lowest and highest ID added.
+func (r *idRanges) bounds() (first, last int64) {
+	for i, run := range r.runs {
+		if i == 0 || run[0] < first {
+			first = run[0]
+		}
+		if i == 0 || run[1] > last {
+			last = run[1]
+		}
+	}
+	return first, last
+}
+
+// encode returns the runs as varints: each first ID as the difference
+// from the one before, followed by the run's length.
+func (r *idRanges) encode() []byte {
+	var b []byte
+	var prev int64
+	for _, run := range r.runs {
+		b = binary.AppendVarint(b, run[0]-prev)
+		b = binary.AppendUvarint(b, uint64(run[1]-run[0]))
+		prev = run[0]
+	}
+	return b
+}
+
+// decodeIDRanges appends the runs encoded in b to runs.
+func decodeIDRanges(runs [][2]int64, b []byte) ([][2]int64, error) {
+	var prev int64
+	for len(b) > 0 {
+		d, n := binary.Varint(b)
+		if n <= 0 {
+			return runs, errors.New("invalid deleted IDs")
+		}
+		b = b[n:]
+		length, n := binary.Uvarint(b)
+		if n <= 0 {
+			return runs, errors.New("invalid deleted IDs")
+		}
+		b = b[n:]
+		prev += d
+		runs = append(runs, [2]int64{prev, prev + int64(length)})
+	}
+	return runs, nil
+}
+
+// deletedIDs is the union of the IDs the ledger records as deleted.
+type deletedIDs [][2]int64 // Sorted, non-overlapping runs
+
+// newDeletedIDs sorts and merges runs.
+func newDeletedIDs(runs [][2]int64) deletedIDs {
+	slices.SortFunc(runs, func(a, b [2]int64) int { return cmp.Compare(a[0], b[0]) })
+	var out deletedIDs
+	for _, run := range runs {
+		if n := len(out); n > 0 && run[0] <= out[n-1][1]+1 {
+			out[n-1][1] = max(out[n-1][1], run[1])
+			continue
+		}
+		out = append(out, run)
+	}
+	return out
+}
+
+// covered counts the IDs from first to last that were deleted.
+func (d deletedIDs) covered(first, last int64) int64 {
+	var n int64
+	i := sort.Search(len(d), func(i int) bool { return d[i][1] >= first })
+	for ; i < len(d) && d[i][0] <= last; i++ {
+		n += min(d[i][1], last) - max(d[i][0], first) + 1
+	}
+	return n
+}
+
+// chainHash links a ledger row to the hash of the row before it.
+func chainHash(prev []byte, seq int64, kind, detail string, firstID, lastID, entries, createdAt int64, data []byte) []byte {
+	h := sha256.New()
+	h.Write(prev)
+	var buf []byte
+	for _, n := range []int64{seq, firstID, lastID, entries, createdAt} {
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(n))
+	}
+	for _, s := range []string{kind, detail, string(data)} {
+		buf = binary.AppendUvarint(buf, uint64(len(s)))
+		buf = append(buf, s...)
+	}
+	h.Write(buf)
+	return h.Sum(nil)
+}
+
+// loadLedgerHead reads the last row of the ledger.
+func loadLedgerHead(db *sql.DB) (ledgerHead, error) {
+	var head ledgerHead
+	err := db.QueryRow(`SELECT seq, hash FROM log_ledger ORDER BY seq DESC LIMIT 1`).Scan(&head.seq, &head.hash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return head, fmt.Errorf("read ledger head: %w", err)
+	}
+	return head, nil
+}
+
+// appendFlush records the entries of f, inserted by tx, as the next row
+// of the ledger and returns the new head. Callers hold s.writeMu.
+func (s *Store) appendFlush(ctx context.Context, tx *sql.Tx, f *flushHashes) (ledgerHead, error) {
+	return s.appendLedger(ctx, tx, ledgerFlush, "", f.first, f.last, f.entries, f.hashes)
+}
+
+// recordDeletes reports whether deletes must collect the IDs they
+// delete for the ledger: once it has rows, so that verification can
+// tell them from entries removed behind the store's back. Callers hold
+// s.writeMu.
+func (s *Store) recordDeletes() bool {
+	return s.ledgerHead.seq > 0
+}
+
+// appendDelete records the deletion of ids by tx, of the given kind
+// with detail saying which entries it selected, as the next row of the
+// ledger and returns the new head. Callers hold s.writeMu.
+func (s *Store) appendDelete(ctx context.Context, tx *sql.Tx, kind string, detail map[string]any, ids *idRanges) (ledgerHead, error) {
+	if ids == nil || ids.count == 0 {
+		return s.ledgerHead, nil
+	}
+	b, err := json.Marshal(detail)
+	if err != nil {
+		return s.ledgerHead, fmt.Errorf("encode ledger detail: %w", err)
+	}
+	first, last := ids.bounds()
+	return s.appendLedger(ctx, tx, kind, string(b), first, last, ids.count, ids.encode())
+}
+
+// appendLedger inserts the next row of the ledger with tx and returns
+// the new head. Callers hold s.writeMu.
+func (s *Store) appendLedger(ctx context.Context, tx *sql.Tx, kind, detail string, first, last, entries int64, data []byte) (ledgerHead, error) {
+	seq := s.ledgerHead.seq + 1
+	createdAt := time.Now().UnixNano()
+	head := ledgerHead{
+		seq:  seq,
+		hash: chainHash(s.ledgerHead.hash, seq, kind, detail, first, last, entries, createdAt, data),
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO log_ledger (seq, kind, detail, first_id, last_id, entries, data, hash, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, seq, kind, detail, first, last, entries, data, head.hash, createdAt)
+	if err != nil {
+		return s.ledgerHead, fmt.Errorf("insert ledger: %w", err)
+	}
+	return head, nil
+}
+
+// retentionDetail describes the entries Delete selects.
+func retentionDetail(olderThan time.Time) map[string]any {
+	return map[string]any{"before": olderThan.UTC().Format(time.RFC3339Nano)}
+}
+
+// queryDetail describes the entries DeleteByQuery selects with q.
+func queryDetail(q storage.Query) map[string]any {
+	d := make(map[string]any)
+	if !q.StartTime.IsZero() {
+		d["startTime"] = q.StartTime.UTC().Format(time.RFC3339Nano)
+	}
+	if !q.EndTime.IsZero() {
+		d["endTime"] = q.EndTime.UTC().Format(time.RFC3339Nano)
+	}
+	for k, v := range map[string]string{"search": q.Search, "cluster": q.Cluster, "namespace": q.Namespace, "pod": q.Pod, "container": q.Container} {
+		if v != "" {
+			d[k] = v
+		}
+	}
+	if q.MinSeverity > storage.SeverityUnknown {
+		d["minSeverity"] = q.MinSeverity.String()
+	}
+	if len(q.Attributes) > 0 {
+		d["attributes"] = q.Attributes
+	}
+	if len(q.AttrExprs) > 0 {
+		d["attrExprs"] = q.AttrExprs
+	}
+	return d
+}
+
+// ledgerRow is a flush or delete recorded in the ledger.
+type ledgerRow struct {
+	seq       int64
+	kind      string
+	detail    string
+	firstID   int64
+	lastID    int64
+	entries   int64
+	data      []byte // Entry hashes of a flush, or deleted IDs
+	hash      []byte
+	createdAt int64
+}
+
+// VerifyLedger implements storage.LedgerVerifier. It recomputes the hash
+// chain from its first row, and checks each entry a flush inserted
+// against the hash it recorded. Entries a flush inserted that are gone
+// must have been deleted by a delete recorded in the ledger. Buffered
+// entries aren't in the ledger yet.
+func (s *Store) VerifyLedger(ctx context.Context) (*storage.LedgerReport, error) {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return nil, storage.ErrStorageClosed
+	}
+
+	report := &storage.LedgerReport{}
+	fail := func(row ledgerRow, reason string) {
+		report.Failed++
+		if len(report.Failures) < storage.MaxLedgerFailures {
+			report.Failures = append(report.Failures, storage.LedgerFailure{
+				Seq: row.seq, FirstID: row.firstID, LastID: row.lastID, Reason: reason,
+			})
+		}
+	}
+
+	// Deletes may follow the flushes they explain by any number of rows,
+	// so they are collected first
+	var runs [][2]int64
+	err := s.eachLedgerRow(ctx, func(row ledgerRow) error {
+		if row.kind == ledgerFlush {
+			return nil
+		}
+		var err error
+		runs, err = decodeIDRanges(runs, row.data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	deleted := newDeletedIDs(runs)
+
+	var prev []byte
+	var seq int64
+	err = s.eachLedgerRow(ctx, func(row ledgerRow) error {
+		if row.seq != seq+1 {
+			fail(ledgerRow{seq: seq + 1}, storage.LedgerRowMissing)
+		}
+		if !bytes.Equal(chainHash(prev, row.seq, row.kind, row.detail, row.firstID, row.lastID, row.entries, row.createdAt, row.data), row.hash) {
+			fail(row, storage.LedgerChainMismatch)
+		}
+		// Continue from the recorded hash, so one changed row doesn't
+		// fail every row after it
+		prev = row.hash
+		seq = row.seq
+		report.HeadSeq = row.seq
+		report.HeadHash = hex.EncodeToString(row.hash)
+
+		if row.kind != ledgerFlush {
+			report.Deletes++
+			return nil
+		}
+		report.Flushes++
+		if row.entries != row.lastID-row.firstID+1 || int64(len(row.data)) != row.entries*sha256.Size {
+			// Only a changed row can be inconsistent
+			fail(row, storage.LedgerChainMismatch)
+			return nil
+		}
+		return s.checkFlush(ctx, row, deleted, report, fail)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// checkFlush checks the stored entries with the IDs of the flush row
+// against their recorded hashes, and that the missing ones were deleted.
+func (s *Store) checkFlush(ctx context.Context, row ledgerRow, deleted deletedIDs, report *storage.LedgerReport, fail func(ledgerRow, string)) error {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, timestamp, cluster, namespace, pod, container, severity, message, attributes, expires_at
+		FROM logs WHERE id BETWEEN ? AND ? ORDER BY id
+	`, row.firstID, row.lastID)
+	if err != nil {
+		return fmt.Errorf("query entries: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		h                 entryHasher
+		prevID            = row.firstID - 1
+		missing, verified int64
+		added, modified   bool
+	)
+	gap := func(first, last int64) {
+		if first > last {
+			return
+		}
+		n := last - first + 1
+		explained := deleted.covered(first, last)
+		report.Deleted += explained
+		if explained < n {
+			missing += n - explained
+		}
+	}
+	for rows.Next() {
+		var (
+			id, ts, severity                        int64
+			cluster, namespace, pod, container, msg string
+			attrs                                   sql.NullString
+			expires                                 sql.NullInt64
+		)
+		if err := rows.Scan(&id, &ts, &cluster, &namespace, &pod, &container, &severity, &msg, &attrs, &expires); err != nil {
+			return fmt.Errorf("scan entry: %w", err)
+		}
+		if id == prevID {
+			added = true
+			continue
+		}
+		gap(prevID+1, id-1)
+		prevID = id
+
+		var a *string
+		if attrs.Valid {
+			a = &attrs.String
+		}
+		var e *int64
+		if expires.Valid {
+			e = &expires.Int64
+		}
+		sum := h.sum(id, ts, cluster, namespace, pod, container, severity, msg, a, e)
+		slot := (id - row.firstID) * sha256.Size
+		if !bytes.Equal(sum[:], row.data[slot:slot+sha256.Size]) {
+			modified = true
+			continue
+		}
+		verified++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("query entries: %w", err)
+	}
+	gap(prevID+1, row.lastID)
+
+	report.Entries += verified
+	if added {
+		fail(row, storage.LedgerEntriesAdded)
+	}
+	if modified {
+		fail(row, storage.LedgerEntriesModified)
+	}
+	if missing > 0 {
+		fail(row, storage.LedgerEntriesMissing)
+	}
+	return nil
+}
+
+// eachLedgerRow calls fn with the rows of the ledger in order, reading
+// ledgerChunk rows at a time.
+func (s *Store) eachLedgerRow(ctx context.Context, fn func(ledgerRow) error) error {
+	var after int64
+	for {
+		rows, err := s.ledgerRows(ctx, after)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := fn(row); err != nil {
+				return err
+			}
+			after = row.seq
+		}
+		if len(rows) < ledgerChunk {
+			return nil
+		}
+	}
+}
+
+// ledgerRows reads up to ledgerChunk ledger rows after seq.
+func (s *Store) ledgerRows(ctx context.Context, after int64) ([]ledgerRow, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT seq, kind, detail, first_id, last_id, entries, data, hash, created_at
+		FROM log_ledger WHERE seq > ? ORDER BY seq LIMIT ?
+	`, after, ledgerChunk)
+	if err != nil {
+		return nil, fmt.Errorf("query ledger: %w", err)
+	}
+	defer rows.Close()
+
+	var out []ledgerRow
+	for rows.Next() {
+		var r ledgerRow
+		if err := rows.Scan(&r.seq, &r.kind, &r.detail, &r.firstID, &r.lastID, &r.entries, &r.data, &r.hash, &r.createdAt); err != nil {
+			return nil, fmt.Errorf("scan ledger: %w", err)
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// deleteRows deletes the rows of table matching where, adding their IDs
+// to ids unless it is nil, and returns how many it deleted.
+func deleteRows(ctx context.Context, tx *sql.Tx, table, where string, ids *idRanges, args ...any) (int64, error) {
+	if ids != nil {
+		if err := collectIDs(ctx, tx, table, where, ids, args...); err != nil {
+			return 0, err
+		}
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE `+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("delete: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n, nil
+}
+
+// collectIDs adds the IDs of the rows of table matching where to ids.
+func collectIDs(ctx context.Context, tx *sql.Tx, table, where string, ids *idRanges, args ...any) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM `+table+` WHERE `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return fmt.Errorf("select deleted IDs: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("scan deleted ID: %w", err)
+		}
+		ids.add(id)
+	}
+	return rows.Err()
+}
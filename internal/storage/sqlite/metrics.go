@@ -0,0 +1,83 @@
+package sqlite
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/kubelogs/kubelogs/internal/metrics"
+)
+
+// lockBuckets are wait and hold time buckets in seconds, from 100µs,
+// since uncontended locks are taken in far less than the 5ms DefBuckets
+// start at.
+var lockBuckets = []float64{.0001, .001, .005, .01, .05, .1, .5, 1, 5, 10, 30}
+
+// storeMetrics holds the instruments Store updates. Unregistered
+// instruments are nil and record nothing.
+type storeMetrics struct {
+	busy      *metrics.Counter
+	writeWait *metrics.Histogram
+	writeHeld *metrics.Histogram
+	commit    *metrics.Histogram
+}
+
+// RegisterMetrics registers the store's lock contention metrics with r,
+// labelled with the database path. Call before the store is used.
+//
+// They tell ingest stalls caused by lock contention from those caused
+// by the disk: writes waiting long for the write lock while commits are
+// quick point to contention, slow commits to fsync.
+func (s *Store) RegisterMetrics(r *metrics.Registry) {
+	s.metrics = storeMetrics{
+		busy: r.Counter("kubelogs_sqlite_busy_errors_total",
+			"Operations that failed with SQLITE_BUSY or SQLITE_LOCKED after the busy timeout.", "db", s.path),
+		writeWait: r.Histogram("kubelogs_sqlite_write_lock_wait_seconds",
+			"Time write transactions waited for the store's write lock.", lockBuckets, "db", s.path),
+		writeHeld: r.Histogram("kubelogs_sqlite_write_lock_held_seconds",
+			"Time write transactions held the store's write lock.", lockBuckets, "db", s.path),
+		commit: r.Histogram("kubelogs_sqlite_commit_seconds",
+			"Time spent committing batches of entries, mostly syncing to disk.", lockBuckets, "db", s.path),
+	}
+	r.CounterFunc("kubelogs_sqlite_conn_waits_total",
+		"Operations that waited for the database connection, held by another read or write.",
+		func() float64 { return float64(s.db.Stats().WaitCount) }, "db", s.path)
+	r.CounterFunc("kubelogs_sqlite_conn_wait_seconds_total",
+		"Total time operations waited for the database connection.",
+		func() float64 { return s.db.Stats().WaitDuration.Seconds() }, "db", s.path)
+	r.GaugeFunc("kubelogs_sqlite_fts_backlog_entries",
+		"Entries stored without full-text indexing while shedding it, awaiting background indexing.",
+		func() float64 { return float64(s.ftsBacklog.Load()) }, "db", s.path)
+	r.GaugeFunc("kubelogs_sqlite_fts_shedding",
+		"1 while new entries are stored without full-text indexing because of the write backlog.",
+		func() float64 {
+			if s.shedding.Load() {
+				return 1
+			}
+			return 0
+		}, "db", s.path)
+}
+
+// lockWrite takes s.writeMu, recording the wait, and returns the
+// function releasing it, which records how long it was held.
+func (s *Store) lockWrite() (unlock func()) {
+	start := time.Now()
+	s.writeMu.Lock()
+	locked := time.Now()
+	s.metrics.writeWait.Observe(locked.Sub(start).Seconds())
+	return func() {
+		s.metrics.writeHeld.Observe(time.Since(locked).Seconds())
+		s.writeMu.Unlock()
+	}
+}
+
+// countBusy counts err if SQLite reported the database busy or locked,
+// and returns it.
+func (s *Store) countBusy(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked) {
+		s.metrics.busy.Inc()
+	}
+	return err
+}
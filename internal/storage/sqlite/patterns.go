@@ -0,0 +1,133 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/patterns"
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// patternSighting accumulates the entries of one pattern in a flush.
+type patternSighting struct {
+	first       storage.LogEntry // Earliest by timestamp, with its ID
+	last        int64
+	count       int64
+	maxSeverity storage.Severity
+}
+
+// addPattern records an inserted entry, stored with ID id, in sightings.
+func addPattern(sightings map[string]*patternSighting, e *storage.LogEntry, id int64) {
+	t := patterns.Template(e.Message)
+	ts := e.Timestamp.UnixNano()
+	p, ok := sightings[t]
+	if !ok {
+		p = &patternSighting{first: *e, last: ts}
+		p.first.ID = id
+		sightings[t] = p
+	} else if ts < p.first.Timestamp.UnixNano() {
+		p.first = *e
+		p.first.ID = id
+	}
+	p.last = max(p.last, ts)
+	p.count++
+	p.maxSeverity = max(p.maxSeverity, e.Severity)
+}
+
+// writePatterns adds sightings to the pattern table within tx. An entry
+// earlier than a pattern's first one replaces it.
+func writePatterns(ctx context.Context, tx *sql.Tx, sightings map[string]*patternSighting) error {
+	if len(sightings) == 0 {
+		return nil
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO log_patterns (pattern, first_seen, last_seen, count, first_id, namespace, pod, example, max_severity)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (pattern) DO UPDATE SET
+			first_id = CASE WHEN excluded.first_seen < first_seen THEN excluded.first_id ELSE first_id END,
+			namespace = CASE WHEN excluded.first_seen < first_seen THEN excluded.namespace ELSE namespace END,
+			pod = CASE WHEN excluded.first_seen < first_seen THEN excluded.pod ELSE pod END,
+			example = CASE WHEN excluded.first_seen < first_seen THEN excluded.example ELSE example END,
+			first_seen = MIN(first_seen, excluded.first_seen),
+			last_seen = MAX(last_seen, excluded.last_seen),
+			count = count + excluded.count,
+			max_severity = MAX(max_severity, excluded.max_severity)
+	`)
+	if err != nil {
+		return fmt.Errorf("prepare patterns: %w", err)
+	}
+	defer stmt.Close()
+
+	for t, p := range sightings {
+		_, err := stmt.ExecContext(ctx, t,
+			p.first.Timestamp.UnixNano(), p.last, p.count,
+			p.first.ID, p.first.Namespace, p.first.Pod, p.first.Message,
+			p.maxSeverity,
+		)
+		if err != nil {
+			return fmt.Errorf("upsert pattern: %w", err)
+		}
+	}
+	return nil
+}
+
+// Patterns implements storage.PatternReader.
+func (s *Store) Patterns(ctx context.Context, q storage.PatternQuery) ([]storage.PatternSighting, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, storage.ErrStorageClosed
+	}
+	s.mu.Unlock()
+
+	// Flush so buffered writes are recorded
+	if err := s.Flush(ctx); err != nil {
+		return nil, err
+	}
+
+	var conditions []string
+	var args []any
+	if !q.FirstSeenAfter.IsZero() {
+		conditions = append(conditions, "first_seen >= ?")
+		args = append(args, q.FirstSeenAfter.UnixNano())
+	}
+	if q.MinSeverity > storage.SeverityUnknown {
+		conditions = append(conditions, "max_severity >= ?")
+		args = append(args, q.MinSeverity)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`SELECT pattern, first_seen, last_seen, count, first_id, namespace, pod, example, max_severity FROM log_patterns`)
+	if len(conditions) > 0 {
+		sb.WriteString(" WHERE " + strings.Join(conditions, " AND "))
+	}
+	sb.WriteString(" ORDER BY first_seen DESC, pattern")
+	if q.Limit > 0 {
+		sb.WriteString(" LIMIT ?")
+		args = append(args, q.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, sb.String(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	sightings := make([]storage.PatternSighting, 0)
+	for rows.Next() {
+		var p storage.PatternSighting
+		var first, last int64
+		err := rows.Scan(&p.Pattern, &first, &last, &p.Count, &p.FirstID, &p.Namespace, &p.Pod, &p.Example, &p.MaxSeverity)
+		if err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		p.FirstSeen = time.Unix(0, first)
+		p.LastSeen = time.Unix(0, last)
+		sightings = append(sightings, p)
+	}
+	return sightings, rows.Err()
+}
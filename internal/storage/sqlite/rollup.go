@@ -0,0 +1,242 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// rollupBucket is the width of a rollup time bucket. Rollup queries are
+// accurate to this granularity. It divides every UTC offset in use (all
+// multiples of 15 minutes), so local days in any time zone start on a
+// bucket boundary.
+const rollupBucket = 5 * time.Minute
+
+// rollupKey identifies one rollup row.
+type rollupKey struct {
+	bucket    int64
+	namespace string
+	pod       string
+	container string
+}
+
+// rollupCounts accumulates counters for one rollup row.
+type rollupCounts struct {
+	lines int64
+	bytes int64
+	last  int64 // Latest timestamp
+}
+
+// bucketStart returns the start of the bucket containing ts (Unix nanoseconds).
+func bucketStart(ts int64) int64 {
+	w := int64(rollupBucket)
+	b := ts - ts%w
+	if ts < 0 && ts%w != 0 {
+		b -= w
+	}
+	return b
+}
+
+// addRollup records an inserted entry in counts.
+func addRollup(counts map[rollupKey]*rollupCounts, e *storage.LogEntry) {
+	k := rollupKey{
+		bucket:    bucketStart(e.Timestamp.UnixNano()),
+		namespace: e.Namespace,
+		pod:       e.Pod,
+		container: e.Container,
+	}
+	c, ok := counts[k]
+	if !ok {
+		c = &rollupCounts{}
+		counts[k] = c
+	}
+	c.lines++
+	c.bytes += int64(len(e.Message))
+	c.last = max(c.last, e.Timestamp.UnixNano())
+}
+
+// writeRollups adds counts to the rollup table within tx.
+func writeRollups(ctx context.Context, tx *sql.Tx, counts map[rollupKey]*rollupCounts) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO log_rollups (bucket, namespace, pod, container, lines, bytes, last_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (bucket, namespace, pod, container) DO UPDATE SET
+			lines = lines + excluded.lines,
+			bytes = bytes + excluded.bytes,
+			last_seen = MAX(last_seen, excluded.last_seen)
+	`)
+	if err != nil {
+		return fmt.Errorf("prepare rollups: %w", err)
+	}
+	defer stmt.Close()
+
+	for k, c := range counts {
+		if _, err := stmt.ExecContext(ctx, k.bucket, k.namespace, k.pod, k.container, c.lines, c.bytes, c.last); err != nil {
+			return fmt.Errorf("upsert rollup: %w", err)
+		}
+	}
+	return nil
+}
+
+// upgradeRollups adds the last_seen column to rollup tables created
+// before it existed. Their rows keep 0, read as the bucket start.
+func upgradeRollups(db *sql.DB) error {
+	hasLastSeen, err := columnExists(db, "log_rollups", "last_seen")
+	if err != nil {
+		return fmt.Errorf("check rollups: %w", err)
+	}
+	if hasLastSeen {
+		return nil
+	}
+	if _, err := db.Exec(`ALTER TABLE log_rollups ADD COLUMN last_seen INTEGER NOT NULL DEFAULT 0`); err != nil {
+		return fmt.Errorf("upgrade rollups: %w", err)
+	}
+	return nil
+}
+
+// backfillRollups builds rollups from existing logs for databases created
+// before rollups existed. It is a no-op once any rollup is present.
+func backfillRollups(db *sql.DB) error {
+	var hasRollups, hasLogs bool
+	if err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM log_rollups)`).Scan(&hasRollups); err != nil {
+		return fmt.Errorf("check rollups: %w", err)
+	}
+	if hasRollups {
+		return nil
+	}
+	if err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM logs)`).Scan(&hasLogs); err != nil {
+		return fmt.Errorf("check logs: %w", err)
+	}
+	if !hasLogs {
+		return nil
+	}
+
+	// Integer division floors for the non-negative timestamps stored here
+	_, err := db.Exec(`
+		INSERT INTO log_rollups (bucket, namespace, pod, container, lines, bytes, last_seen)
+		SELECT (timestamp / ?) * ?, namespace, pod, container, COUNT(*), SUM(LENGTH(CAST(message AS BLOB))), MAX(timestamp)
+		FROM logs
+		GROUP BY 1, namespace, pod, container
+	`, int64(rollupBucket), int64(rollupBucket))
+	if err != nil {
+		return fmt.Errorf("insert rollups: %w", err)
+	}
+	return nil
+}
+
+// Rollups implements storage.RollupReader.
+func (s *Store) Rollups(ctx context.Context, q storage.RollupQuery) ([]storage.Rollup, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, storage.ErrStorageClosed
+	}
+	s.mu.Unlock()
+
+	// Flush so buffered writes are counted
+	if err := s.Flush(ctx); err != nil {
+		return nil, err
+	}
+
+	var cols string
+	switch q.By {
+	case storage.RollupByNamespace:
+		cols = "namespace"
+	case storage.RollupByPod:
+		cols = "namespace, pod"
+	case storage.RollupByContainer:
+		cols = "namespace, pod, container"
+	default:
+		return nil, fmt.Errorf("unknown rollup dimension %d", q.By)
+	}
+
+	var conditions []string
+	var args []any
+	if !q.StartTime.IsZero() {
+		conditions = append(conditions, "bucket >= ?")
+		args = append(args, bucketStart(q.StartTime.UnixNano()))
+	}
+	if !q.EndTime.IsZero() {
+		conditions = append(conditions, "bucket < ?")
+		args = append(args, q.EndTime.UnixNano())
+	}
+	if q.Namespace != "" {
+		conditions = append(conditions, "namespace = ?")
+		args = append(args, q.Namespace)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT " + cols + ", SUM(lines), SUM(bytes), MAX(CASE last_seen WHEN 0 THEN bucket ELSE last_seen END) FROM log_rollups")
+	if len(conditions) > 0 {
+		sb.WriteString(" WHERE " + strings.Join(conditions, " AND "))
+	}
+	sb.WriteString(" GROUP BY " + cols)
+	if q.OrderByLines {
+		sb.WriteString(" ORDER BY SUM(lines) DESC, SUM(bytes) DESC, " + cols)
+	} else {
+		sb.WriteString(" ORDER BY SUM(bytes) DESC, SUM(lines) DESC, " + cols)
+	}
+	if q.Limit > 0 {
+		sb.WriteString(" LIMIT ?")
+		args = append(args, q.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, sb.String(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	rollups := make([]storage.Rollup, 0)
+	for rows.Next() {
+		var r storage.Rollup
+		dest := []any{&r.Namespace}
+		if q.By >= storage.RollupByPod {
+			dest = append(dest, &r.Pod)
+		}
+		if q.By >= storage.RollupByContainer {
+			dest = append(dest, &r.Container)
+		}
+		var lastSeen int64
+		dest = append(dest, &r.Lines, &r.Bytes, &lastSeen)
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		r.LastSeen = time.Unix(0, lastSeen)
+		rollups = append(rollups, r)
+	}
+
+	return rollups, rows.Err()
+}
+
+// namespaceUsage sums rollups per namespace over all stored buckets.
+func (s *Store) namespaceUsage(ctx context.Context) ([]storage.NamespaceUsage, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT namespace, SUM(lines), SUM(bytes) FROM log_rollups
+		GROUP BY namespace
+		ORDER BY SUM(bytes) DESC, namespace
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("namespace usage: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []storage.NamespaceUsage
+	for rows.Next() {
+		var u storage.NamespaceUsage
+		if err := rows.Scan(&u.Namespace, &u.Entries, &u.Bytes); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
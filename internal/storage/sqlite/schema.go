@@ -3,7 +3,207 @@ package sqlite
 // baseSchemaSQL contains the DDL for creating tables and indexes that don't
 // depend on columns added by migrations. This is executed BEFORE migrations run.
 const baseSchemaSQL = `
-CREATE TABLE IF NOT EXISTS logs (
+-- Day shards of the logs table, see shardSchemaSQL. Each shard holds the
+-- entries with timestamps in [day_start, day_end) (Unix nanoseconds, UTC).
+CREATE TABLE IF NOT EXISTS log_shards (
+    name      TEXT PRIMARY KEY,
+    day_start INTEGER NOT NULL,
+    day_end   INTEGER NOT NULL
+);
+
+-- Next log entry ID. IDs are allocated across shards and never reused,
+-- even after retention drops every shard.
+CREATE TABLE IF NOT EXISTS log_sequence (
+    id      INTEGER PRIMARY KEY CHECK (id = 0),
+    next_id INTEGER NOT NULL
+);
+
+-- Authentication tables
+CREATE TABLE IF NOT EXISTS users (
+    id         INTEGER PRIMARY KEY,
+    username   TEXT NOT NULL UNIQUE,
+    password   TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id         TEXT PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at INTEGER NOT NULL,
+    expires_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at);
+
+-- Per-user bookmarks on log entries. No foreign key to logs: a bookmark
+-- outlives its entry when retention deletes it.
+CREATE TABLE IF NOT EXISTS bookmarks (
+    entry_id   INTEGER NOT NULL,
+    user_id    INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    note       TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (entry_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bookmarks_user ON bookmarks(user_id, created_at);
+
+-- Per-user saved searches: a name and a UI/API query string.
+CREATE TABLE IF NOT EXISTS saved_queries (
+    id         INTEGER PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name       TEXT NOT NULL,
+    query      TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    UNIQUE (user_id, name)
+);
+
+-- Per-user UI preferences (theme, columns, ...), a JSON object owned by the UI.
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id    INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    data       TEXT NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+-- Incident workspaces: shared reports grouping pinned queries, entries and markers.
+CREATE TABLE IF NOT EXISTS incidents (
+    id         INTEGER PRIMARY KEY,
+    title      TEXT NOT NULL,
+    notes      TEXT NOT NULL DEFAULT '',
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS incident_items (
+    id          INTEGER PRIMARY KEY,
+    incident_id INTEGER NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    kind        TEXT NOT NULL,
+    entry_id    INTEGER NOT NULL DEFAULT 0,
+    query       TEXT NOT NULL DEFAULT '',
+    timestamp   INTEGER NOT NULL DEFAULT 0,
+    label       TEXT NOT NULL DEFAULT '',
+    created_at  INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_items_incident ON incident_items(incident_id);
+
+-- Confirmed destructive admin operations (see package audit).
+CREATE TABLE IF NOT EXISTS audit_log (
+    id         INTEGER PRIMARY KEY,
+    time       INTEGER NOT NULL,
+    operation  TEXT NOT NULL,
+    filter     TEXT NOT NULL,
+    requester  TEXT NOT NULL,
+    approver   TEXT NOT NULL,
+    count      INTEGER NOT NULL
+);
+
+-- The retention policy last applied, a JSON object, in a single row.
+-- The server keeps to it while the configured one would delete logs
+-- sooner, until an admin confirms the change.
+CREATE TABLE IF NOT EXISTS retention_policy (
+    id         INTEGER PRIMARY KEY CHECK (id = 1),
+    policy     TEXT NOT NULL,
+    applied_at INTEGER NOT NULL
+);
+
+-- Hourly entry counts per namespace and severity (hour start in Unix
+-- nanoseconds), maintained by the server from the log store (see package
+-- logstats) and kept longer than the entries they count.
+CREATE TABLE IF NOT EXISTS log_stats (
+    hour      INTEGER NOT NULL,
+    namespace TEXT NOT NULL,
+    severity  INTEGER NOT NULL,
+    count     INTEGER NOT NULL,
+    PRIMARY KEY (hour, namespace, severity)
+);
+
+-- Ingest rollups: line and byte counts per source in fixed time buckets
+-- (bucket start in Unix nanoseconds), maintained on flush. last_seen is
+-- the latest entry's timestamp, 0 in rows from before it was tracked.
+CREATE TABLE IF NOT EXISTS log_rollups (
+    bucket    INTEGER NOT NULL,
+    namespace TEXT NOT NULL,
+    pod       TEXT NOT NULL,
+    container TEXT NOT NULL,
+    lines     INTEGER NOT NULL,
+    bytes     INTEGER NOT NULL,
+    last_seen INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (bucket, namespace, pod, container)
+);
+
+-- Message patterns (see internal/patterns) with their first and last
+-- timestamps, maintained on flush, for finding patterns that are new.
+CREATE TABLE IF NOT EXISTS log_patterns (
+    pattern      TEXT PRIMARY KEY,
+    first_seen   INTEGER NOT NULL,
+    last_seen    INTEGER NOT NULL,
+    count        INTEGER NOT NULL,
+    first_id     INTEGER NOT NULL,
+    namespace    TEXT NOT NULL,
+    pod          TEXT NOT NULL,
+    example      TEXT NOT NULL,
+    max_severity INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_log_patterns_first_seen ON log_patterns(first_seen);
+
+-- Entries written without full-text indexing while shedding it under
+-- ingest pressure (see fts.go), as ID ranges of a shard, indexed in the
+-- background. entries counts those of the range still unindexed.
+CREATE TABLE IF NOT EXISTS fts_backlog (
+    shard    TEXT NOT NULL,
+    first_id INTEGER NOT NULL,
+    last_id  INTEGER NOT NULL,
+    entries  INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_fts_backlog_shard ON fts_backlog(shard);
+
+-- Hash chain over inserted and deleted entries (see ledger.go). While
+-- Config.HashChain is set, each flush adds a "flush" row whose data holds
+-- the SHA-256 of each entry it inserted, with IDs first_id to last_id.
+-- Once the ledger has rows, each delete adds a row of its kind
+-- ("retention", "cluster", "expired" or "query"), with the filter it
+-- applied as JSON in detail and the IDs it deleted in data. hash chains
+-- the row to the one before it.
+CREATE TABLE IF NOT EXISTS log_ledger (
+    seq        INTEGER PRIMARY KEY,
+    kind       TEXT NOT NULL,
+    detail     TEXT NOT NULL,
+    first_id   INTEGER NOT NULL,
+    last_id    INTEGER NOT NULL,
+    entries    INTEGER NOT NULL,
+    data       BLOB NOT NULL,
+    hash       BLOB NOT NULL,
+    created_at INTEGER NOT NULL
+);
+
+-- IDs given out by the storage router (internal/storage/router), which
+-- numbers the entries of several stores in the order they became
+-- visible. Entries local_lo to local_hi of store (its position in the
+-- router's configuration) have the IDs from global_lo up.
+CREATE TABLE IF NOT EXISTS router_ids (
+    global_lo INTEGER PRIMARY KEY,
+    store     INTEGER NOT NULL,
+    local_lo  INTEGER NOT NULL,
+    local_hi  INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_router_ids_local ON router_ids(store, local_lo);
+CREATE INDEX IF NOT EXISTS idx_router_ids_global ON router_ids(store, global_lo);
+`
+
+// shardSchemaSQL creates one day shard; %[1]s is the shard name, e.g.
+// "logs_20240115". Every shard has its own indexes and FTS table, so
+// retention drops whole tables and FTS indexes stay small. The logs view
+// (see rebuildLogsView) unions all shards for ad-hoc reads. The trigger
+// indexing new entries is ftsInsertTriggerSQL.
+const shardSchemaSQL = `
+CREATE TABLE IF NOT EXISTS %[1]s (
     id          INTEGER PRIMARY KEY,
     timestamp   INTEGER NOT NULL,
     namespace   TEXT NOT NULL,
@@ -12,71 +212,73 @@ CREATE TABLE IF NOT EXISTS logs (
     severity    INTEGER NOT NULL,
     message     TEXT NOT NULL,
     attributes  TEXT,
-    dedup_hash  INTEGER
+    dedup_hash  INTEGER,
+    cluster     TEXT NOT NULL DEFAULT '',
+    expires_at  INTEGER
 );
 
-CREATE INDEX IF NOT EXISTS idx_logs_k8s
-    ON logs(namespace, pod, container);
+CREATE INDEX IF NOT EXISTS idx_%[1]s_k8s
+    ON %[1]s(namespace, pod, container);
+
+CREATE INDEX IF NOT EXISTS idx_%[1]s_cluster
+    ON %[1]s(cluster, namespace);
+
+CREATE INDEX IF NOT EXISTS idx_%[1]s_timestamp
+    ON %[1]s(timestamp DESC);
+
+CREATE INDEX IF NOT EXISTS idx_%[1]s_severity
+    ON %[1]s(severity);
 
-CREATE INDEX IF NOT EXISTS idx_logs_timestamp
-    ON logs(timestamp DESC);
+-- Few entries have their own expiry, so the index only holds those.
+CREATE INDEX IF NOT EXISTS idx_%[1]s_expires
+    ON %[1]s(expires_at) WHERE expires_at IS NOT NULL;
 
-CREATE INDEX IF NOT EXISTS idx_logs_severity
-    ON logs(severity);
+-- The dedup hash covers the timestamp, so duplicates always land in the
+-- same shard and a per-shard unique index is enough.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_%[1]s_dedup
+    ON %[1]s(dedup_hash) WHERE dedup_hash IS NOT NULL;
 
--- Note: idx_logs_dedup unique index is created by runMigrations() to handle
--- the case where duplicates exist in the database from before the index existed.
--- This allows the migration to deduplicate rows before creating the unique constraint.
-CREATE VIRTUAL TABLE IF NOT EXISTS logs_fts USING fts5(
+CREATE VIRTUAL TABLE IF NOT EXISTS %[1]s_fts USING fts5(
     message,
-    content='logs',
+    content='%[1]s',
     content_rowid='id',
     tokenize='porter unicode61 remove_diacritics 1'
 );
 
-CREATE TRIGGER IF NOT EXISTS logs_ai AFTER INSERT ON logs BEGIN
-    INSERT INTO logs_fts(rowid, message) VALUES (new.id, new.message);
-END;
-
-CREATE TRIGGER IF NOT EXISTS logs_ad AFTER DELETE ON logs BEGIN
-    INSERT INTO logs_fts(logs_fts, rowid, message)
+CREATE TRIGGER IF NOT EXISTS %[1]s_ad AFTER DELETE ON %[1]s BEGIN
+    INSERT INTO %[1]s_fts(%[1]s_fts, rowid, message)
         VALUES('delete', old.id, old.message);
 END;
 
-CREATE TRIGGER IF NOT EXISTS logs_au AFTER UPDATE ON logs BEGIN
-    INSERT INTO logs_fts(logs_fts, rowid, message)
+CREATE TRIGGER IF NOT EXISTS %[1]s_au AFTER UPDATE ON %[1]s BEGIN
+    INSERT INTO %[1]s_fts(%[1]s_fts, rowid, message)
         VALUES('delete', old.id, old.message);
-    INSERT INTO logs_fts(rowid, message) VALUES (new.id, new.message);
+    INSERT INTO %[1]s_fts(rowid, message) VALUES (new.id, new.message);
 END;
+`
 
--- Authentication tables
-CREATE TABLE IF NOT EXISTS users (
-    id         INTEGER PRIMARY KEY,
-    username   TEXT NOT NULL UNIQUE,
-    password   TEXT NOT NULL,
-    created_at INTEGER NOT NULL,
-    updated_at INTEGER NOT NULL
-);
+// ftsInsertTriggerSQL creates the trigger adding a shard's new entries to
+// its FTS table. Writes shedding FTS indexing drop it for the duration
+// of their transaction.
+const ftsInsertTriggerSQL = `
+CREATE TRIGGER IF NOT EXISTS %[1]s_ai AFTER INSERT ON %[1]s BEGIN
+    INSERT INTO %[1]s_fts(rowid, message) VALUES (new.id, new.message);
+END;
+`
 
-CREATE TABLE IF NOT EXISTS sessions (
-    id         TEXT PRIMARY KEY,
-    user_id    INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
-    created_at INTEGER NOT NULL,
-    expires_at INTEGER NOT NULL
-);
+// logsColumns are the columns of a shard, in order.
+const logsColumns = "id, timestamp, namespace, pod, container, severity, message, attributes, dedup_hash, cluster, expires_at"
 
-CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at);
-`
+// legacyLogsColumns are the columns of the unsharded logs table.
+const legacyLogsColumns = "id, timestamp, namespace, pod, container, severity, message, attributes, dedup_hash"
 
-// postMigrationSchemaSQL contains indexes that depend on columns which may be
-// added by migrations. This is executed AFTER migrations run to ensure the
-// columns exist (either from base schema for new DBs, or from migrations for old DBs).
-const postMigrationSchemaSQL = `
-CREATE UNIQUE INDEX IF NOT EXISTS idx_logs_dedup
-    ON logs(dedup_hash) WHERE dedup_hash IS NOT NULL;
-`
+// emptyLogsSQL stands in for the logs view body when there are no shards.
+const emptyLogsSQL = `SELECT 0 AS id, 0 AS timestamp, '' AS namespace, '' AS pod, '' AS container,
+    0 AS severity, '' AS message, NULL AS attributes, NULL AS dedup_hash, '' AS cluster,
+    NULL AS expires_at WHERE 0`
 
-// pragmaSQL contains performance-critical SQLite settings.
+// pragmaSQL contains performance-critical SQLite settings; memory
+// settings follow from Config (see tuning.go).
 // Uses DELETE journal mode instead of WAL for compatibility with
 // network-attached storage (Longhorn, NFS, etc.) where WAL's shared
 // memory files can cause I/O errors.
@@ -84,7 +286,5 @@ const pragmaSQL = `
 PRAGMA journal_mode = DELETE;
 PRAGMA synchronous = FULL;
 PRAGMA locking_mode = EXCLUSIVE;
-PRAGMA cache_size = -64000;
-PRAGMA temp_store = MEMORY;
 PRAGMA busy_timeout = 10000;
 `
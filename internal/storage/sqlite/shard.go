@@ -0,0 +1,322 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// shardDay is the time span of one logs shard.
+const shardDay = 24 * time.Hour
+
+// maxCompoundSelect keeps unions below SQLite's default limit of 500
+// terms per compound SELECT; larger unions are nested.
+const maxCompoundSelect = 400
+
+// shard is a table holding the log entries of one UTC day.
+type shard struct {
+	name  string
+	start int64 // Unix nanoseconds, inclusive
+	end   int64 // Unix nanoseconds, exclusive
+}
+
+// shardFor returns the shard for a timestamp in Unix nanoseconds.
+func shardFor(ts int64) shard {
+	t := time.Unix(0, ts).UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return shard{
+		name:  "logs_" + day.Format("20060102"),
+		start: day.UnixNano(),
+		end:   day.Add(shardDay).UnixNano(),
+	}
+}
+
+// loadShards reads the shard registry, sorted by start.
+func loadShards(ctx context.Context, db *sql.DB) ([]shard, error) {
+	rows, err := db.QueryContext(ctx, `SELECT name, day_start, day_end FROM log_shards ORDER BY day_start`)
+	if err != nil {
+		return nil, fmt.Errorf("query shards: %w", err)
+	}
+	defer rows.Close()
+
+	var shards []shard
+	for rows.Next() {
+		var sh shard
+		if err := rows.Scan(&sh.name, &sh.start, &sh.end); err != nil {
+			return nil, fmt.Errorf("scan shard: %w", err)
+		}
+		shards = append(shards, sh)
+	}
+	return shards, rows.Err()
+}
+
+// createShard creates a shard's tables, with columns for the indexed
+// attributes in attrCols, and registers it.
+func createShard(ctx context.Context, tx *sql.Tx, sh shard, attrCols attrColumns) error {
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(shardSchemaSQL+ftsInsertTriggerSQL, sh.name)); err != nil {
+		return fmt.Errorf("create shard %s: %w", sh.name, err)
+	}
+	if err := syncAttrColumns(ctx, tx, sh.name, attrCols); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO log_shards (name, day_start, day_end) VALUES (?, ?, ?)`,
+		sh.name, sh.start, sh.end)
+	if err != nil {
+		return fmt.Errorf("register shard %s: %w", sh.name, err)
+	}
+	return nil
+}
+
+// shardUpgrades are the columns added to shards after they were first
+// introduced, with the statements adding them and their indexes; %[1]s
+// is the shard name.
+var shardUpgrades = []struct{ column, sql string }{
+	{"cluster", `ALTER TABLE %[1]s ADD COLUMN cluster TEXT NOT NULL DEFAULT '';
+		CREATE INDEX IF NOT EXISTS idx_%[1]s_cluster ON %[1]s(cluster, namespace);`},
+	{"expires_at", `ALTER TABLE %[1]s ADD COLUMN expires_at INTEGER;
+		CREATE INDEX IF NOT EXISTS idx_%[1]s_expires ON %[1]s(expires_at) WHERE expires_at IS NOT NULL;`},
+}
+
+// upgradeShard adds columns introduced after a shard was created, along
+// with their indexes.
+func upgradeShard(db *sql.DB, sh shard) error {
+	for _, u := range shardUpgrades {
+		has, err := columnExists(db, sh.name, u.column)
+		if err != nil {
+			return fmt.Errorf("check shard %s: %w", sh.name, err)
+		}
+		if has {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf(u.sql, sh.name)); err != nil {
+			return fmt.Errorf("upgrade shard %s: %w", sh.name, err)
+		}
+	}
+	return nil
+}
+
+// dropShard removes a shard's tables and registry entry, adding the IDs
+// of its entries to ids unless it is nil. It returns the number of
+// entries the shard held.
+func dropShard(ctx context.Context, tx *sql.Tx, sh shard, ids *idRanges) (int64, error) {
+	var n int64
+	if ids != nil {
+		before := ids.count
+		if err := collectIDs(ctx, tx, sh.name, "1", ids); err != nil {
+			return 0, err
+		}
+		n = ids.count - before
+	} else if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+sh.name).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count shard %s: %w", sh.name, err)
+	}
+	for _, stmt := range []string{
+		`DROP TABLE IF EXISTS ` + sh.name + `_fts`,
+		`DROP TABLE IF EXISTS ` + sh.name,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return 0, fmt.Errorf("drop shard %s: %w", sh.name, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM log_shards WHERE name = ?`, sh.name); err != nil {
+		return 0, fmt.Errorf("unregister shard %s: %w", sh.name, err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM fts_backlog WHERE shard = ?`, sh.name); err != nil {
+		return 0, fmt.Errorf("unregister shard %s: %w", sh.name, err)
+	}
+	return n, nil
+}
+
+// rebuildLogsView recreates the logs view as the union of shards.
+func rebuildLogsView(ctx context.Context, tx *sql.Tx, shards []shard) error {
+	body := emptyLogsSQL
+	if len(shards) > 0 {
+		selects := make([]string, len(shards))
+		for i, sh := range shards {
+			selects[i] = "SELECT " + logsColumns + " FROM " + sh.name
+		}
+		body = unionAll(selects)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DROP VIEW IF EXISTS logs`); err != nil {
+		return fmt.Errorf("drop logs view: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE VIEW logs AS `+body); err != nil {
+		return fmt.Errorf("create logs view: %w", err)
+	}
+	return nil
+}
+
+// unionAll joins selects with UNION ALL, nesting groups so that no
+// compound SELECT exceeds maxCompoundSelect terms.
+func unionAll(selects []string) string {
+	for len(selects) > maxCompoundSelect {
+		var grouped []string
+		for i := 0; i < len(selects); i += maxCompoundSelect {
+			group := selects[i:min(i+maxCompoundSelect, len(selects))]
+			grouped = append(grouped, "SELECT * FROM ("+strings.Join(group, " UNION ALL ")+")")
+		}
+		selects = grouped
+	}
+	return strings.Join(selects, " UNION ALL ")
+}
+
+// migrateLegacyLogs moves entries from the single logs table of older
+// databases into day shards, keeping their IDs, and then drops it. Each
+// day is copied in its own transaction; an interrupted migration resumes
+// on the next open because copies skip IDs already present.
+func migrateLegacyLogs(db *sql.DB) error {
+	var legacy bool
+	err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'logs')`).Scan(&legacy)
+	if err != nil {
+		return fmt.Errorf("check logs table: %w", err)
+	}
+	if !legacy {
+		return nil
+	}
+
+	// Bring old tables up to date first: shards rely on dedup hashes
+	if err := runMigrations(db); err != nil {
+		return err
+	}
+
+	rows, err := db.Query(`SELECT DISTINCT timestamp / ? FROM logs`, int64(shardDay))
+	if err != nil {
+		return fmt.Errorf("query days: %w", err)
+	}
+	var days []int64
+	for rows.Next() {
+		var day int64
+		if err := rows.Scan(&day); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan day: %w", err)
+		}
+		days = append(days, day)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("query days: %w", err)
+	}
+
+	ctx := context.Background()
+	for _, day := range days {
+		sh := shardFor(day * int64(shardDay))
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("begin tx: %w", err)
+		}
+		// openShards adds indexed attributes afterwards
+		if err := createShard(ctx, tx, sh, nil); err != nil {
+			tx.Rollback()
+			return err
+		}
+		_, err = tx.Exec(`INSERT OR IGNORE INTO `+sh.name+` (`+legacyLogsColumns+`)
+			SELECT `+legacyLogsColumns+` FROM logs WHERE timestamp >= ? AND timestamp < ?`, sh.start, sh.end)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("copy %s: %w", sh.name, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("commit %s: %w", sh.name, err)
+		}
+	}
+
+	// Continue IDs after the legacy ones, then drop the table with its
+	// FTS index (triggers go with it)
+	_, err = db.Exec(`
+		INSERT OR IGNORE INTO log_sequence (id, next_id) SELECT 0, COALESCE(MAX(id), 0) + 1 FROM logs;
+		DROP TABLE IF EXISTS logs_fts;
+		DROP TABLE logs;
+	`)
+	if err != nil {
+		return fmt.Errorf("drop logs table: %w", err)
+	}
+	return nil
+}
+
+// ensureShards creates the shards needed to store batch. Callers hold
+// s.writeMu, which also guards changes to s.shards.
+func (s *Store) ensureShards(ctx context.Context, batch storage.LogBatch) error {
+	var missing []shard
+	for _, e := range batch {
+		sh := shardFor(e.Timestamp.UnixNano())
+		if !s.hasShard(sh) && !slices.Contains(missing, sh) {
+			missing = append(missing, sh)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	shards := append(slices.Clone(s.shards), missing...)
+	sort.Slice(shards, func(i, j int) bool { return shards[i].start < shards[j].start })
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, sh := range missing {
+		if err := createShard(ctx, tx, sh, s.attrColumns); err != nil {
+			return err
+		}
+	}
+	if err := rebuildLogsView(ctx, tx, shards); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	s.shardMu.Lock()
+	s.shards = shards
+	s.shardMu.Unlock()
+	return nil
+}
+
+// hasShard reports whether sh exists. Callers hold s.writeMu.
+func (s *Store) hasShard(sh shard) bool {
+	i := sort.Search(len(s.shards), func(i int) bool { return s.shards[i].start >= sh.start })
+	return i < len(s.shards) && s.shards[i].name == sh.name
+}
+
+// queryShards returns the shards that can hold entries matching q's time
+// range and timestamp cursor, in result order for timestamp ordering.
+func (s *Store) queryShards(q storage.Query) []shard {
+	lo, hi := int64(math.MinInt64), int64(math.MaxInt64)
+	if !q.StartTime.IsZero() {
+		lo = q.StartTime.UnixNano()
+	}
+	if !q.EndTime.IsZero() {
+		hi = q.EndTime.UnixNano()
+	}
+	if q.Pagination.OrderBy == storage.OrderByTimestamp {
+		if !q.Pagination.AfterTimestamp.IsZero() {
+			lo = max(lo, q.Pagination.AfterTimestamp.UnixNano())
+		}
+		if !q.Pagination.BeforeTimestamp.IsZero() {
+			hi = min(hi, q.Pagination.BeforeTimestamp.UnixNano()+1)
+		}
+	}
+
+	s.shardMu.RLock()
+	defer s.shardMu.RUnlock()
+
+	var shards []shard
+	for _, sh := range s.shards {
+		if sh.end > lo && sh.start < hi {
+			shards = append(shards, sh)
+		}
+	}
+	if q.Pagination.Order != storage.OrderAsc {
+		slices.Reverse(shards)
+	}
+	return shards
+}
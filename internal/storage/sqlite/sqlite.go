@@ -5,10 +5,12 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/kubelogs/kubelogs/internal/storage"
@@ -19,6 +21,10 @@ import (
 const (
 	defaultWriteBuffer = 1000
 	defaultQueryLimit  = 100
+
+	// eventualLag is how long entries may stay buffered unseen by queries
+	// with storage.ConsistencyEventual, which otherwise don't flush.
+	eventualLag = time.Second
 )
 
 // Store implements storage.Store using SQLite with FTS5.
@@ -27,11 +33,34 @@ type Store struct {
 	path   string
 	closed bool
 
-	mu     sync.Mutex // Protects buffer and closed flag
-	buffer storage.LogBatch
-	bufCap int
+	mu            sync.Mutex // Protects buffer, bufferedSince, pending and closed flag
+	buffer        storage.LogBatch
+	bufferedSince time.Time // When the oldest buffered entry was written
+	bufCap        int
+	pending       int // Entries taken from buffer by flushes not yet written
 
 	writeMu sync.Mutex // Serializes SQL write transactions
+	nextID  int64      // Next entry ID, guarded by writeMu
+
+	// FTS load shedding, see fts.go
+	shedBacklog int           // Config.FTSShedBacklog
+	overFlushes int           // Flushes in a row over shedBacklog, guarded by writeMu
+	shedding    atomic.Bool   // Whether writes skip FTS indexing
+	ftsBacklog  atomic.Int64  // Entries awaiting FTS indexing
+	stopIndex   chan struct{} // Closed to stop indexLoop
+	indexDone   chan struct{} // Closed when indexLoop returns
+
+	shardMu sync.RWMutex // Protects shards; changes also hold writeMu
+	shards  []shard      // Day shards, sorted by start
+
+	attrColumns attrColumns // Indexed attributes
+
+	hashChain  bool       // Config.HashChain
+	ledgerHead ledgerHead // Guarded by writeMu
+
+	metrics storeMetrics
+
+	written storage.WriteSignal
 }
 
 // Config holds SQLite store configuration.
@@ -42,6 +71,51 @@ type Config struct {
 
 	// WriteBufferSize is the number of entries to buffer before flushing.
 	WriteBufferSize int
+
+	// Preset sizes the page cache and memory map for the deployment:
+	// "small" (default), "medium" or "large". The fields below override
+	// it when set.
+	Preset string
+
+	// CacheSize is the page cache size in bytes (PRAGMA cache_size).
+	CacheSize int64
+
+	// MmapSize is the number of bytes of the database file memory-mapped
+	// for reads (PRAGMA mmap_size). Negative disables mapping.
+	MmapSize int64
+
+	// TempStore is where temporary tables and indexes for sorts and
+	// grouping live: "memory" or "file" (PRAGMA temp_store).
+	TempStore string
+
+	// IndexedAttributes are attribute keys, e.g. "trace_id", stored in
+	// indexed generated columns so filters on them don't scan. Columns
+	// are added to existing shards on open, and dropped when their key
+	// is removed.
+	IndexedAttributes []string
+
+	// FTSShedBacklog enables shedding full-text indexing under ingest
+	// pressure: while more than this many entries wait to be written,
+	// flush after flush, new entries are stored without it, and indexed
+	// in the background once the backlog clears. Until then searches
+	// miss them. 0 always indexes on write.
+	FTSShedBacklog int
+
+	// HashChain records each flush's entries, and then each delete, in
+	// a hash chain (the log_ledger table), so VerifyLedger can detect
+	// entries changed or removed after they were written.
+	HashChain bool
+}
+
+func init() {
+	// Options: see ConfigFromOptions.
+	storage.Register("sqlite", func(opts storage.Options) (storage.Store, error) {
+		cfg, err := ConfigFromOptions(opts)
+		if err != nil {
+			return nil, err
+		}
+		return New(cfg)
+	})
 }
 
 // New creates a new SQLite store.
@@ -49,6 +123,14 @@ func New(cfg Config) (*Store, error) {
 	if cfg.WriteBufferSize <= 0 {
 		cfg.WriteBufferSize = defaultWriteBuffer
 	}
+	tuning, err := cfg.tuning()
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: %w", err)
+	}
+	attrCols, err := newAttrColumns(cfg.IndexedAttributes)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: %w", err)
+	}
 
 	// Clean up stale WAL mode files before opening. These can cause
 	// SQLITE_IOERR_SHMSIZE errors if left over from a previous crash
@@ -67,7 +149,7 @@ func New(cfg Config) (*Store, error) {
 	db.SetMaxOpenConns(1)
 	db.SetMaxIdleConns(1)
 
-	if _, err := db.Exec(pragmaSQL); err != nil {
+	if _, err := db.Exec(pragmaSQL + tuning.pragmas()); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("set pragmas: %w", err)
 	}
@@ -85,30 +167,107 @@ func New(cfg Config) (*Store, error) {
 		return nil, fmt.Errorf("failed to set journal_mode=DELETE, got %q", journalMode)
 	}
 
-	// Create base schema (tables and indexes that don't depend on migrated columns)
+	// Create base schema
 	if _, err := db.Exec(baseSchemaSQL); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("create base schema: %w", err)
 	}
 
-	// Run migrations for existing databases (e.g., add dedup_hash column)
-	if err := runMigrations(db); err != nil {
+	// Move entries of databases created before sharding into day shards
+	if err := migrateLegacyLogs(db); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("run migrations: %w", err)
 	}
 
-	// Create post-migration schema (indexes that depend on migrated columns)
-	if _, err := db.Exec(postMigrationSchemaSQL); err != nil {
+	shards, nextID, err := openShards(db, attrCols)
+	if err != nil {
 		db.Close()
-		return nil, fmt.Errorf("create post-migration schema: %w", err)
+		return nil, err
 	}
 
-	return &Store{
+	// Build rollups for logs written before rollups existed
+	if err := upgradeRollups(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := backfillRollups(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("backfill rollups: %w", err)
+	}
+
+	// Entries left unindexed by shedding before a restart
+	ftsBacklog, err := ftsBacklogEntries(context.Background(), db, "")
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	head, err := loadLedgerHead(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s := &Store{
 		db:     db,
 		path:   cfg.Path,
 		buffer: make(storage.LogBatch, 0, cfg.WriteBufferSize),
 		bufCap: cfg.WriteBufferSize,
-	}, nil
+		nextID: nextID,
+		shards: shards,
+
+		attrColumns: attrCols,
+		shedBacklog: cfg.FTSShedBacklog,
+		hashChain:   cfg.HashChain,
+		ledgerHead:  head,
+		stopIndex:   make(chan struct{}),
+		indexDone:   make(chan struct{}),
+	}
+	s.ftsBacklog.Store(ftsBacklog)
+	go s.indexLoop()
+	return s, nil
+}
+
+// openShards loads the shard registry, upgrades older shards and their
+// indexed attributes, recreates the logs view over them and reads the
+// next entry ID.
+func openShards(db *sql.DB, attrCols attrColumns) ([]shard, int64, error) {
+	ctx := context.Background()
+	shards, err := loadShards(ctx, db)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, sh := range shards {
+		if err := upgradeShard(db, sh); err != nil {
+			return nil, 0, err
+		}
+		if err := syncAttrColumns(ctx, db, sh.name, attrCols); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := rebuildLogsView(ctx, tx, shards); err != nil {
+		return nil, 0, err
+	}
+	var nextID int64
+	err = tx.QueryRow(`
+		INSERT INTO log_sequence (id, next_id) SELECT 0, COALESCE(MAX(id), 0) + 1 FROM logs WHERE true
+		ON CONFLICT (id) DO UPDATE SET next_id = next_id
+		RETURNING next_id
+	`).Scan(&nextID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("read sequence: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, 0, fmt.Errorf("commit: %w", err)
+	}
+	return shards, nextID, nil
 }
 
 // Write implements storage.Store.
@@ -122,10 +281,16 @@ func (s *Store) Write(ctx context.Context, entries storage.LogBatch) (int, error
 		s.mu.Unlock()
 		return 0, storage.ErrStorageClosed
 	}
+	if len(s.buffer) == 0 {
+		s.bufferedSince = time.Now()
+	}
 	s.buffer = append(s.buffer, entries...)
 	needFlush := len(s.buffer) >= s.bufCap
 	s.mu.Unlock()
 
+	// Buffered entries are announced right away: queries flush them
+	defer s.written.Publish()
+
 	if needFlush {
 		if err := s.Flush(ctx); err != nil {
 			return 0, err
@@ -134,58 +299,138 @@ func (s *Store) Write(ctx context.Context, entries storage.LogBatch) (int, error
 	return len(entries), nil
 }
 
-// Flush implements storage.WriteOptimizer.
+// Written implements storage.Notifier.
+func (s *Store) Written() <-chan struct{} {
+	return s.written.Written()
+}
+
+// Flush implements storage.WriteOptimizer. When it returns without
+// error, every entry written before it was called is stored, including
+// those taken by concurrent flushes.
 func (s *Store) Flush(ctx context.Context) error {
-	// Step 1: Atomically swap the buffer (fast, under mu)
 	s.mu.Lock()
 	if s.closed {
 		s.mu.Unlock()
 		return storage.ErrStorageClosed
 	}
+	if len(s.buffer) == 0 && s.pending == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
+
+	// Step 1: Serialize SQL writes (may block other flushes, but not
+	// buffer appends). Flushes in progress finish first, so entries
+	// they fail to write are back in the buffer.
+	unlock := s.lockWrite()
+	defer unlock()
+
+	// Step 2: Atomically swap the buffer (fast, under mu)
+	s.mu.Lock()
 	if len(s.buffer) == 0 {
 		s.mu.Unlock()
 		return nil
 	}
 	batch := s.buffer
+	since := s.bufferedSince
 	s.buffer = make(storage.LogBatch, 0, s.bufCap)
+	s.pending += len(batch)
 	s.mu.Unlock()
-
-	// Step 2: Serialize SQL writes (may block other flushes, but not buffer appends)
-	s.writeMu.Lock()
-	defer s.writeMu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.pending -= len(batch)
+		s.mu.Unlock()
+	}()
+	requeue := func() {
+		s.mu.Lock()
+		s.buffer = append(batch, s.buffer...)
+		s.bufferedSince = since
+		s.mu.Unlock()
+	}
+	s.updateShedding()
 
 	// Check context before starting potentially slow operation
 	if err := ctx.Err(); err != nil {
 		// Re-queue batch on cancellation to avoid data loss
+		requeue()
+		return err
+	}
+
+	if err := s.writeBatch(ctx, batch); err != nil {
+		// Re-queue batch on failure
+		requeue()
+		return s.countBusy(err)
+	}
+
+	return nil
+}
+
+// flushFor flushes unless q is eventually consistent and no entry has
+// been buffered for eventualLag.
+func (s *Store) flushFor(ctx context.Context, q storage.Query) error {
+	if q.Consistency == storage.ConsistencyEventual {
 		s.mu.Lock()
-		s.buffer = append(batch, s.buffer...)
+		fresh := len(s.buffer) == 0 || time.Since(s.bufferedSince) < eventualLag
 		s.mu.Unlock()
+		if fresh {
+			return nil
+		}
+	}
+	return s.Flush(ctx)
+}
+
+// writeBatch inserts entries into their day shards in one transaction,
+// skipping duplicates, and without FTS indexing while shedding it.
+// Callers hold s.writeMu.
+func (s *Store) writeBatch(ctx context.Context, batch storage.LogBatch) error {
+	if err := s.ensureShards(ctx, batch); err != nil {
 		return err
 	}
 
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		// Re-queue batch on failure
-		s.mu.Lock()
-		s.buffer = append(batch, s.buffer...)
-		s.mu.Unlock()
 		return fmt.Errorf("begin tx: %w", err)
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.PrepareContext(ctx, `
-		INSERT OR IGNORE INTO logs (timestamp, namespace, pod, container, severity, message, attributes, dedup_hash)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-	`)
-	if err != nil {
-		s.mu.Lock()
-		s.buffer = append(batch, s.buffer...)
-		s.mu.Unlock()
-		return fmt.Errorf("prepare: %w", err)
-	}
-	defer stmt.Close()
+	stmts := make(map[string]*sql.Stmt)
+	defer func() {
+		for _, stmt := range stmts {
+			stmt.Close()
+		}
+	}()
 
+	nextID := s.nextID
+	rollups := make(map[rollupKey]*rollupCounts)
+	sightings := make(map[string]*patternSighting)
+	var unindexed map[string]*unindexedRange
+	if s.shedding.Load() {
+		unindexed = make(map[string]*unindexedRange)
+	}
+	var hashes *flushHashes
+	if s.hashChain {
+		hashes = newFlushHashes()
+	}
 	for _, e := range batch {
+		sh := shardFor(e.Timestamp.UnixNano())
+		stmt, ok := stmts[sh.name]
+		if !ok {
+			if unindexed != nil {
+				if err := dropFTSTrigger(ctx, tx, sh.name); err != nil {
+					return err
+				}
+				unindexed[sh.name] = &unindexedRange{first: nextID}
+			}
+			stmt, err = tx.PrepareContext(ctx, `
+				INSERT OR IGNORE INTO `+sh.name+` (id, timestamp, cluster, namespace, pod, container, severity, message, attributes, dedup_hash, expires_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`)
+			if err != nil {
+				return fmt.Errorf("prepare: %w", err)
+			}
+			stmts[sh.name] = stmt
+		}
+
 		var attrs *string
 		if len(e.Attributes) > 0 {
 			b, _ := json.Marshal(e.Attributes)
@@ -193,16 +438,25 @@ func (s *Store) Flush(ctx context.Context) error {
 			attrs = &str
 		}
 
+		var expires *int64
+		if !e.ExpiresAt.IsZero() {
+			ns := e.ExpiresAt.UnixNano()
+			expires = &ns
+		}
+
 		hash := computeDedupHash(
 			e.Timestamp.UnixNano(),
+			e.Cluster,
 			e.Namespace,
 			e.Pod,
 			e.Container,
 			e.Message,
 		)
 
-		_, err := stmt.ExecContext(ctx,
+		res, err := stmt.ExecContext(ctx,
+			nextID,
 			e.Timestamp.UnixNano(),
+			e.Cluster,
 			e.Namespace,
 			e.Pod,
 			e.Container,
@@ -210,21 +464,60 @@ func (s *Store) Flush(ctx context.Context) error {
 			e.Message,
 			attrs,
 			hash,
+			expires,
 		)
 		if err != nil {
-			s.mu.Lock()
-			s.buffer = append(batch, s.buffer...)
-			s.mu.Unlock()
 			return fmt.Errorf("insert: %w", err)
 		}
+
+		// Duplicates are ignored by the insert: they neither use an ID
+		// nor count towards rollups and patterns
+		if n, _ := res.RowsAffected(); n > 0 {
+			if r := unindexed[sh.name]; r != nil {
+				r.last = nextID
+				r.entries++
+			}
+			addPattern(sightings, &e, nextID)
+			if hashes != nil {
+				hashes.add(nextID, e.Timestamp.UnixNano(), e.Cluster, e.Namespace, e.Pod, e.Container,
+					int64(e.Severity), e.Message, attrs, expires)
+			}
+			nextID++
+			addRollup(rollups, &e)
+		}
+	}
+	if err := restoreFTSTriggers(ctx, tx, unindexed); err != nil {
+		return err
 	}
 
-	if err := tx.Commit(); err != nil {
-		s.mu.Lock()
-		s.buffer = append(batch, s.buffer...)
-		s.mu.Unlock()
+	if _, err := tx.ExecContext(ctx, `UPDATE log_sequence SET next_id = ?`, nextID); err != nil {
+		return fmt.Errorf("update sequence: %w", err)
+	}
+
+	if err := writeRollups(ctx, tx, rollups); err != nil {
+		return err
+	}
+	if err := writePatterns(ctx, tx, sightings); err != nil {
+		return err
+	}
+	head := s.ledgerHead
+	if hashes != nil && hashes.entries > 0 {
+		if head, err = s.appendFlush(ctx, tx, hashes); err != nil {
+			return err
+		}
+	}
+
+	start := time.Now()
+	err = tx.Commit()
+	s.metrics.commit.Observe(time.Since(start).Seconds())
+	if err != nil {
 		return fmt.Errorf("commit: %w", err)
 	}
+	s.nextID = nextID
+	s.ledgerHead = head
+	for _, r := range unindexed {
+		s.ftsBacklog.Add(r.entries)
+	}
 
 	return nil
 }
@@ -248,29 +541,104 @@ func (s *Store) Query(ctx context.Context, q storage.Query) (*storage.QueryResul
 	s.mu.Unlock()
 
 	// Flush before querying to ensure recent writes are visible
-	if err := s.Flush(ctx); err != nil {
+	if err := s.flushFor(ctx, q); err != nil {
 		return nil, err
 	}
 
-	query, args := buildQuery(q)
-	rows, err := s.db.QueryContext(ctx, query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("query: %w", err)
-	}
-	defer rows.Close()
-
 	limit := q.Pagination.Limit
 	if limit <= 0 {
 		limit = defaultQueryLimit
 	}
 
+	shards := s.queryShards(q)
 	entries := make([]storage.LogEntry, 0, limit)
+	var err error
+	if q.Pagination.OrderBy == storage.OrderByTimestamp {
+		// Shards don't overlap in time, so visit them in result order
+		// and stop once a page (plus the next cursor) is collected
+		for _, sh := range shards {
+			query, args := buildQuery(q, sh.name, s.attrColumns)
+			if entries, err = s.queryEntries(ctx, entries, query, args); err != nil {
+				return nil, err
+			}
+			if len(entries) > limit {
+				entries = entries[:limit+1]
+				break
+			}
+		}
+	} else if len(shards) > 0 {
+		query, args := buildUnionQuery(q, shards, s.attrColumns)
+		if entries, err = s.queryEntries(ctx, entries, query, args); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &storage.QueryResult{
+		TotalEstimate: -1,
+	}
+	if q.Sampled() {
+		result.SampleRate = q.Sample
+	}
+	if q.Count {
+		n, err := s.countMatches(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		result.TotalEstimate = min(n, storage.MaxCount)
+		result.TotalCapped = n > storage.MaxCount
+	}
+
+	// Check if we fetched more than limit (hasMore indicator)
+	if len(entries) > limit {
+		result.HasMore = true
+		result.NextCursor = entries[limit].ID
+		if q.Pagination.OrderBy == storage.OrderByTimestamp {
+			result.NextCursorTimestamp = entries[limit].Timestamp
+		}
+		entries = entries[:limit]
+	}
+	result.Entries = entries
+
+	return result, nil
+}
+
+// countMatches counts the entries matching q's filters on every page, up
+// to storage.MaxCount+1 so the caller can tell the count was capped.
+// Shards are counted one at a time, each only as far as the cap.
+func (s *Store) countMatches(ctx context.Context, q storage.Query) (int64, error) {
+	q.Sample = 0
+	q.Pagination = storage.Pagination{MaxID: q.Pagination.MaxID}
+
+	var total int64
+	for _, sh := range s.queryShards(q) {
+		from, args := buildFrom(q, sh.name, s.attrColumns)
+		args = append(args, storage.MaxCount+1-total)
+		var n int64
+		if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM (SELECT 1"+from+" LIMIT ?)", args...).Scan(&n); err != nil {
+			return 0, fmt.Errorf("count: %w", s.countBusy(err))
+		}
+		total += n
+		if total > storage.MaxCount {
+			break
+		}
+	}
+	return total, nil
+}
+
+// queryEntries runs a query built by buildQuery and appends the rows to entries.
+func (s *Store) queryEntries(ctx context.Context, entries []storage.LogEntry, query string, args []any) ([]storage.LogEntry, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", s.countBusy(err))
+	}
+	defer rows.Close()
+
 	for rows.Next() {
 		var e storage.LogEntry
 		var ts int64
 		var attrs sql.NullString
 
-		err := rows.Scan(&e.ID, &ts, &e.Namespace, &e.Pod, &e.Container, &e.Severity, &e.Message, &attrs)
+		err := rows.Scan(&e.ID, &ts, &e.Cluster, &e.Namespace, &e.Pod, &e.Container, &e.Severity, &e.Message, &attrs)
 		if err != nil {
 			return nil, fmt.Errorf("scan: %w", err)
 		}
@@ -286,20 +654,7 @@ func (s *Store) Query(ctx context.Context, q storage.Query) (*storage.QueryResul
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("rows: %w", err)
 	}
-
-	result := &storage.QueryResult{
-		TotalEstimate: -1,
-	}
-
-	// Check if we fetched more than limit (hasMore indicator)
-	if len(entries) > limit {
-		result.HasMore = true
-		result.NextCursor = entries[limit].ID
-		entries = entries[:limit]
-	}
-	result.Entries = entries
-
-	return result, nil
+	return entries, nil
 }
 
 // GetByID implements storage.Store.
@@ -316,9 +671,9 @@ func (s *Store) GetByID(ctx context.Context, id int64) (*storage.LogEntry, error
 	var attrs sql.NullString
 
 	err := s.db.QueryRowContext(ctx, `
-		SELECT id, timestamp, namespace, pod, container, severity, message, attributes
+		SELECT id, timestamp, cluster, namespace, pod, container, severity, message, attributes
 		FROM logs WHERE id = ?
-	`, id).Scan(&e.ID, &ts, &e.Namespace, &e.Pod, &e.Container, &e.Severity, &e.Message, &attrs)
+	`, id).Scan(&e.ID, &ts, &e.Cluster, &e.Namespace, &e.Pod, &e.Container, &e.Severity, &e.Message, &attrs)
 
 	if err == sql.ErrNoRows {
 		return nil, storage.ErrNotFound
@@ -335,6 +690,69 @@ func (s *Store) GetByID(ctx context.Context, id int64) (*storage.LogEntry, error
 	return &e, nil
 }
 
+// getByIDsChunk bounds the number of bound parameters per statement.
+const getByIDsChunk = 500
+
+// GetByIDs implements storage.Store.
+func (s *Store) GetByIDs(ctx context.Context, ids []int64) ([]storage.LogEntry, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, storage.ErrStorageClosed
+	}
+	s.mu.Unlock()
+
+	found := make(map[int64]storage.LogEntry, len(ids))
+	for start := 0; start < len(ids); start += getByIDsChunk {
+		chunk := ids[start:min(start+getByIDsChunk, len(ids))]
+
+		placeholders := strings.Repeat("?,", len(chunk))
+		args := make([]any, len(chunk))
+		for i, id := range chunk {
+			args[i] = id
+		}
+
+		rows, err := s.db.QueryContext(ctx, `
+			SELECT id, timestamp, cluster, namespace, pod, container, severity, message, attributes
+			FROM logs WHERE id IN (`+placeholders[:len(placeholders)-1]+`)
+		`, args...)
+		if err != nil {
+			return nil, fmt.Errorf("query: %w", err)
+		}
+
+		for rows.Next() {
+			var e storage.LogEntry
+			var ts int64
+			var attrs sql.NullString
+
+			if err := rows.Scan(&e.ID, &ts, &e.Cluster, &e.Namespace, &e.Pod, &e.Container, &e.Severity, &e.Message, &attrs); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scan: %w", err)
+			}
+
+			e.Timestamp = time.Unix(0, ts)
+			if attrs.Valid && attrs.String != "" {
+				json.Unmarshal([]byte(attrs.String), &e.Attributes)
+			}
+			found[e.ID] = e
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("rows: %w", err)
+		}
+	}
+
+	entries := make([]storage.LogEntry, 0, len(found))
+	for _, id := range ids {
+		if e, ok := found[id]; ok {
+			entries = append(entries, e)
+			delete(found, id)
+		}
+	}
+	return entries, nil
+}
+
 // Delete implements storage.Store.
 func (s *Store) Delete(ctx context.Context, olderThan time.Time) (int64, error) {
 	s.mu.Lock()
@@ -345,15 +763,251 @@ func (s *Store) Delete(ctx context.Context, olderThan time.Time) (int64, error)
 	s.mu.Unlock()
 
 	// Serialize with other writes to prevent SQLITE_BUSY
-	s.writeMu.Lock()
-	defer s.writeMu.Unlock()
+	unlock := s.lockWrite()
+	defer unlock()
+
+	cutoff := olderThan.UnixNano()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var ids *idRanges
+	if s.recordDeletes() {
+		ids = &idRanges{}
+	}
+
+	// Shards entirely before the cutoff are dropped; only the one
+	// straddling it needs a row-level delete
+	var deleted int64
+	var kept []shard
+	for _, sh := range s.shards {
+		switch {
+		case sh.end <= cutoff:
+			n, err := dropShard(ctx, tx, sh, ids)
+			if err != nil {
+				return 0, err
+			}
+			deleted += n
+		case sh.start < cutoff:
+			if err := indexShardBacklog(ctx, tx, sh.name); err != nil {
+				return 0, err
+			}
+			n, err := deleteRows(ctx, tx, sh.name, `timestamp < ?`, ids, cutoff)
+			if err != nil {
+				return 0, err
+			}
+			deleted += n
+			kept = append(kept, sh)
+		default:
+			kept = append(kept, sh)
+		}
+	}
+	if len(kept) < len(s.shards) {
+		if err := rebuildLogsView(ctx, tx, kept); err != nil {
+			return 0, err
+		}
+	}
+
+	// Drop rollup buckets that ended before the cutoff; the bucket
+	// straddling it is kept until it is fully expired.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM log_rollups WHERE bucket <= ?`, cutoff-int64(rollupBucket)); err != nil {
+		return 0, fmt.Errorf("delete rollups: %w", err)
+	}
+
+	// Patterns not seen since the cutoff are forgotten, and count as new
+	// if they return
+	if _, err := tx.ExecContext(ctx, `DELETE FROM log_patterns WHERE last_seen < ?`, cutoff); err != nil {
+		return 0, fmt.Errorf("delete patterns: %w", err)
+	}
+
+	head, err := s.appendDelete(ctx, tx, ledgerRetention, retentionDetail(olderThan), ids)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	s.ledgerHead = head
+
+	s.shardMu.Lock()
+	s.shards = kept
+	s.shardMu.Unlock()
+
+	return deleted, nil
+}
+
+// DeleteCluster implements storage.ClusterDeleter. Shards are kept even
+// when emptied; Delete drops them once they expire for every cluster.
+func (s *Store) DeleteCluster(ctx context.Context, cluster string, olderThan time.Time) (int64, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return 0, storage.ErrStorageClosed
+	}
+	s.mu.Unlock()
+
+	unlock := s.lockWrite()
+	defer unlock()
+
+	cutoff := olderThan.UnixNano()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var ids *idRanges
+	if s.recordDeletes() {
+		ids = &idRanges{}
+	}
+	var deleted int64
+	for _, sh := range s.shards {
+		if sh.start >= cutoff {
+			break
+		}
+		if err := indexShardBacklog(ctx, tx, sh.name); err != nil {
+			return 0, err
+		}
+		n, err := deleteRows(ctx, tx, sh.name, `cluster = ? AND timestamp < ?`, ids, cluster, cutoff)
+		if err != nil {
+			return 0, err
+		}
+		deleted += n
+	}
 
-	result, err := s.db.ExecContext(ctx, `DELETE FROM logs WHERE timestamp < ?`, olderThan.UnixNano())
+	detail := retentionDetail(olderThan)
+	detail["cluster"] = cluster
+	head, err := s.appendDelete(ctx, tx, ledgerCluster, detail, ids)
 	if err != nil {
-		return 0, fmt.Errorf("delete: %w", err)
+		return 0, err
 	}
 
-	return result.RowsAffected()
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	s.ledgerHead = head
+	return deleted, nil
+}
+
+// DeleteExpired implements storage.ExpiryDeleter. Shards are kept even
+// when emptied; Delete drops them once retention expires them.
+func (s *Store) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return 0, storage.ErrStorageClosed
+	}
+	s.mu.Unlock()
+
+	unlock := s.lockWrite()
+	defer unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	cutoff := now.UnixNano()
+	var ids *idRanges
+	if s.recordDeletes() {
+		ids = &idRanges{}
+	}
+	var deleted int64
+	for _, sh := range s.shards {
+		// Most shards have no expired entries; skip indexing their
+		// backlog then
+		var expired bool
+		err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM `+sh.name+` WHERE expires_at <= ?)`, cutoff).Scan(&expired)
+		if err != nil {
+			return 0, fmt.Errorf("check %s: %w", sh.name, err)
+		}
+		if !expired {
+			continue
+		}
+		if err := indexShardBacklog(ctx, tx, sh.name); err != nil {
+			return 0, err
+		}
+		n, err := deleteRows(ctx, tx, sh.name, `expires_at <= ?`, ids, cutoff)
+		if err != nil {
+			return 0, err
+		}
+		deleted += n
+	}
+
+	head, err := s.appendDelete(ctx, tx, ledgerExpired, map[string]any{"expiredBy": now.UTC().Format(time.RFC3339Nano)}, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	s.ledgerHead = head
+	return deleted, nil
+}
+
+// DeleteByQuery implements storage.QueryDeleter. Shards are kept even
+// when emptied, and rollups keep counting the deleted entries.
+func (s *Store) DeleteByQuery(ctx context.Context, q storage.Query) (int64, error) {
+	if !q.HasFilter() {
+		return 0, storage.ErrNoFilter
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return 0, storage.ErrStorageClosed
+	}
+	s.mu.Unlock()
+
+	// Flush so buffered entries matching q are deleted too
+	if err := s.Flush(ctx); err != nil {
+		return 0, err
+	}
+
+	unlock := s.lockWrite()
+	defer unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	q.Pagination = storage.Pagination{}
+	var ids *idRanges
+	if s.recordDeletes() {
+		ids = &idRanges{}
+	}
+	var deleted int64
+	for _, sh := range s.queryShards(q) {
+		if err := indexShardBacklog(ctx, tx, sh.name); err != nil {
+			return 0, err
+		}
+		from, args := buildFrom(q, sh.name, s.attrColumns)
+		n, err := deleteRows(ctx, tx, sh.name, `id IN (SELECT l.id`+from+`)`, ids, args...)
+		if err != nil {
+			return 0, err
+		}
+		deleted += n
+	}
+
+	head, err := s.appendDelete(ctx, tx, ledgerQuery, queryDetail(q), ids)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	s.ledgerHead = head
+	return deleted, nil
 }
 
 // Stats implements storage.Store.
@@ -393,6 +1047,12 @@ func (s *Store) Stats(ctx context.Context) (*storage.Stats, error) {
 		stats.DiskSizeBytes = pageCount * pageSize
 	}
 
+	namespaces, err := s.namespaceUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats.Namespaces = namespaces
+
 	return stats, nil
 }
 
@@ -408,39 +1068,16 @@ func (s *Store) Close() error {
 	s.buffer = nil
 	s.mu.Unlock()
 
+	close(s.stopIndex)
+	<-s.indexDone
+
 	// Wait for any in-flight writes to complete
-	s.writeMu.Lock()
-	defer s.writeMu.Unlock()
+	unlock := s.lockWrite()
+	defer unlock()
 
 	// Flush remaining buffer
 	if len(batch) > 0 {
-		tx, err := s.db.Begin()
-		if err == nil {
-			stmt, _ := tx.Prepare(`
-				INSERT OR IGNORE INTO logs (timestamp, namespace, pod, container, severity, message, attributes, dedup_hash)
-				VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-			`)
-			if stmt != nil {
-				for _, e := range batch {
-					var attrs *string
-					if len(e.Attributes) > 0 {
-						b, _ := json.Marshal(e.Attributes)
-						str := string(b)
-						attrs = &str
-					}
-					hash := computeDedupHash(
-						e.Timestamp.UnixNano(),
-						e.Namespace,
-						e.Pod,
-						e.Container,
-						e.Message,
-					)
-					stmt.Exec(e.Timestamp.UnixNano(), e.Namespace, e.Pod, e.Container, e.Severity, e.Message, attrs, hash)
-				}
-				stmt.Close()
-			}
-			tx.Commit()
-		}
+		s.writeBatch(context.Background(), batch)
 	}
 
 	return s.db.Close()
@@ -452,15 +1089,51 @@ func (s *Store) DB() *sql.DB {
 	return s.db
 }
 
-// buildQuery constructs a parameterized SQL query from Query.
-func buildQuery(q storage.Query) (string, []any) {
+// buildQuery constructs a parameterized SQL query from Query against
+// one shard table.
+func buildQuery(q storage.Query, table string, attrCols attrColumns) (string, []any) {
+	from, args := buildFrom(q, table, attrCols)
+
+	var sql strings.Builder
+	sql.WriteString("SELECT l.id, l.timestamp, l.cluster, l.namespace, l.pod, l.container, l.severity, l.message, l.attributes" + from)
+	if q.Sampled() {
+		// random() is uniform over int64, so this keeps a q.Sample fraction
+		sql.WriteString(" AND random() < ?")
+		args = append(args, int64(math.MinInt64+q.Sample*(1<<64)))
+	}
+	byTimestamp := q.Pagination.OrderBy == storage.OrderByTimestamp
+
+	switch {
+	case byTimestamp && q.Pagination.Order == storage.OrderAsc:
+		sql.WriteString(" ORDER BY l.timestamp ASC, l.id ASC")
+	case byTimestamp:
+		sql.WriteString(" ORDER BY l.timestamp DESC, l.id DESC")
+	case q.Pagination.Order == storage.OrderAsc:
+		sql.WriteString(" ORDER BY l.id ASC")
+	default:
+		sql.WriteString(" ORDER BY l.id DESC")
+	}
+
+	limit := q.Pagination.Limit
+	if limit <= 0 {
+		limit = defaultQueryLimit
+	}
+	sql.WriteString(fmt.Sprintf(" LIMIT %d", limit+1))
+
+	return sql.String(), args
+}
+
+// buildFrom returns the FROM and WHERE clauses selecting the entries of
+// table matching q's filters and cursor, as alias l. Filters on the
+// attributes in attrCols use their indexed columns.
+func buildFrom(q storage.Query, table string, attrCols attrColumns) (string, []any) {
 	var sql strings.Builder
 	var args []any
 
-	sql.WriteString("SELECT l.id, l.timestamp, l.namespace, l.pod, l.container, l.severity, l.message, l.attributes FROM logs l")
+	sql.WriteString(" FROM " + table + " l")
 
 	if q.Search != "" {
-		sql.WriteString(" JOIN logs_fts f ON l.id = f.rowid")
+		sql.WriteString(" JOIN " + table + "_fts f ON l.id = f.rowid")
 	}
 
 	sql.WriteString(" WHERE 1=1")
@@ -475,10 +1148,14 @@ func buildQuery(q storage.Query) (string, []any) {
 	}
 
 	if q.Search != "" {
-		sql.WriteString(" AND logs_fts MATCH ?")
+		sql.WriteString(" AND " + table + "_fts MATCH ?")
 		args = append(args, q.Search)
 	}
 
+	if q.Cluster != "" {
+		sql.WriteString(" AND l.cluster = ?")
+		args = append(args, q.Cluster)
+	}
 	if q.Namespace != "" {
 		sql.WriteString(" AND l.namespace = ?")
 		args = append(args, q.Namespace)
@@ -504,32 +1181,102 @@ func buildQuery(q storage.Query) (string, []any) {
 	}
 	sort.Strings(attrKeys)
 	for _, k := range attrKeys {
-		sql.WriteString(" AND json_extract(l.attributes, ?) = ?")
-		args = append(args, "$."+k, q.Attributes[k])
+		var expr string
+		expr, args = attrCols.expr(k, args)
+		sql.WriteString(" AND " + expr + " = ?")
+		args = append(args, q.Attributes[k])
+	}
+	for _, expr := range q.AttrExprs {
+		if len(expr) == 0 {
+			continue
+		}
+		terms := make([]string, len(expr))
+		for i, t := range expr {
+			terms[i], args = attrTermSQL(t, attrCols, args)
+		}
+		sql.WriteString(" AND (" + strings.Join(terms, " OR ") + ")")
 	}
 
-	if q.Pagination.AfterID > 0 {
+	byTimestamp := q.Pagination.OrderBy == storage.OrderByTimestamp
+
+	// Composite (timestamp, id) keyset when ordering by timestamp
+	if byTimestamp && !q.Pagination.AfterTimestamp.IsZero() {
+		sql.WriteString(" AND (l.timestamp, l.id) > (?, ?)")
+		args = append(args, q.Pagination.AfterTimestamp.UnixNano(), q.Pagination.AfterID)
+	} else if q.Pagination.AfterID > 0 {
 		sql.WriteString(" AND l.id > ?")
 		args = append(args, q.Pagination.AfterID)
 	}
-	if q.Pagination.BeforeID > 0 {
+	if byTimestamp && !q.Pagination.BeforeTimestamp.IsZero() {
+		sql.WriteString(" AND (l.timestamp, l.id) < (?, ?)")
+		args = append(args, q.Pagination.BeforeTimestamp.UnixNano(), beforeIDOrMax(q.Pagination.BeforeID))
+	} else if q.Pagination.BeforeID > 0 {
 		sql.WriteString(" AND l.id < ?")
 		args = append(args, q.Pagination.BeforeID)
 	}
+	if q.Pagination.MaxID > 0 {
+		sql.WriteString(" AND l.id <= ?")
+		args = append(args, q.Pagination.MaxID)
+	}
 
-	if q.Pagination.Order == storage.OrderAsc {
-		sql.WriteString(" ORDER BY l.id ASC")
-	} else {
-		sql.WriteString(" ORDER BY l.id DESC")
+	return sql.String(), args
+}
+
+// attrTermSQL returns the condition matching attribute term t, appending
+// its arguments to args.
+func attrTermSQL(t storage.AttrTerm, attrCols attrColumns, args []any) (string, []any) {
+	expr, args := attrCols.expr(t.Key, args)
+	if t.Op == storage.AttrExists {
+		return expr + " IS NOT NULL", args
+	}
+
+	cond := expr + " = ?"
+	value := t.Value
+	if t.IsGlob() {
+		cond = expr + " GLOB ?"
+		value = globEscaper.Replace(value)
+	}
+	args = append(args, value)
+	if t.Op == storage.AttrNotEqual {
+		// Absent attributes extract as NULL
+		return "NOT COALESCE(" + cond + ", 0)", args
 	}
+	return cond, args
+}
 
+// globEscaper escapes the GLOB metacharacters other than *, which
+// attribute terms use as their only wildcard.
+var globEscaper = strings.NewReplacer("?", "[?]", "[", "[[]")
+
+// buildUnionQuery runs buildQuery on each shard and merges the results
+// by ID. Each shard contributes at most a page, so the merge is cheap.
+func buildUnionQuery(q storage.Query, shards []shard, attrCols attrColumns) (string, []any) {
+	selects := make([]string, len(shards))
+	var args []any
+	for i, sh := range shards {
+		query, shardArgs := buildQuery(q, sh.name, attrCols)
+		selects[i] = "SELECT * FROM (" + query + ")"
+		args = append(args, shardArgs...)
+	}
+
+	order := "id DESC"
+	if q.Pagination.Order == storage.OrderAsc {
+		order = "id ASC"
+	}
 	limit := q.Pagination.Limit
 	if limit <= 0 {
 		limit = defaultQueryLimit
 	}
-	sql.WriteString(fmt.Sprintf(" LIMIT %d", limit+1))
+	return fmt.Sprintf("SELECT * FROM (%s) ORDER BY %s LIMIT %d", unionAll(selects), order, limit+1), args
+}
 
-	return sql.String(), args
+// beforeIDOrMax returns id, or the largest ID if id is unset, so a
+// timestamp-only BeforeTimestamp cursor includes every entry at that instant.
+func beforeIDOrMax(id int64) int64 {
+	if id > 0 {
+		return id
+	}
+	return math.MaxInt64
 }
 
 // ListNamespaces returns distinct namespace values.
@@ -559,6 +1306,33 @@ func (s *Store) ListNamespaces(ctx context.Context) ([]string, error) {
 	return namespaces, rows.Err()
 }
 
+// ListClusters returns distinct non-empty cluster values.
+func (s *Store) ListClusters(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, storage.ErrStorageClosed
+	}
+	s.mu.Unlock()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT cluster FROM logs WHERE cluster != '' ORDER BY cluster`)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	clusters := make([]string, 0)
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		clusters = append(clusters, c)
+	}
+
+	return clusters, rows.Err()
+}
+
 // ListContainers returns distinct container values.
 func (s *Store) ListContainers(ctx context.Context) ([]string, error) {
 	s.mu.Lock()
@@ -586,6 +1360,38 @@ func (s *Store) ListContainers(ctx context.Context) ([]string, error) {
 	return containers, rows.Err()
 }
 
+// ListPods returns distinct pod values in namespace, or in all
+// namespaces if it's empty.
+func (s *Store) ListPods(ctx context.Context, namespace string) ([]string, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, storage.ErrStorageClosed
+	}
+	s.mu.Unlock()
+
+	query, args := `SELECT DISTINCT pod FROM logs ORDER BY pod`, []any(nil)
+	if namespace != "" {
+		query, args = `SELECT DISTINCT pod FROM logs WHERE namespace = ? ORDER BY pod`, []any{namespace}
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	pods := make([]string, 0)
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		pods = append(pods, p)
+	}
+
+	return pods, rows.Err()
+}
+
 // runMigrations handles schema updates for existing databases.
 func runMigrations(db *sql.DB) error {
 	// Check if dedup_hash column exists
@@ -734,7 +1540,7 @@ func backfillDedupHashes(db *sql.DB) error {
 		}
 
 		for _, r := range batch {
-			hash := computeDedupHash(r.timestamp, r.namespace, r.pod, r.container, r.message)
+			hash := computeDedupHash(r.timestamp, "", r.namespace, r.pod, r.container, r.message)
 			if _, err := stmt.Exec(hash, r.id); err != nil {
 				stmt.Close()
 				tx.Rollback()
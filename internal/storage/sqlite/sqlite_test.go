@@ -3,15 +3,21 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net/http/httptest"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
-	"github.com/kubelogs/kubelogs/internal/storage"
+	"github.com/mattn/go-sqlite3"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/kubelogs/kubelogs/internal/fixtures"
+	"github.com/kubelogs/kubelogs/internal/metrics"
+	"github.com/kubelogs/kubelogs/internal/storage"
 )
 
 func TestStore(t *testing.T) {
@@ -67,6 +73,60 @@ func TestFTS5Search(t *testing.T) {
 	}
 }
 
+func TestFTS5SearchCorpora(t *testing.T) {
+	store, err := New(Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	base := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	var entries storage.LogBatch
+	for _, corpus := range fixtures.All() {
+		for i, line := range corpus.Lines {
+			entries = append(entries, storage.LogEntry{
+				Timestamp: base.Add(time.Duration(i) * time.Millisecond),
+				Namespace: "fixtures",
+				Pod:       corpus.Name,
+				Container: "app",
+				Severity:  storage.SeverityInfo,
+				Message:   line,
+			})
+		}
+	}
+
+	store.Write(context.Background(), entries)
+	store.Flush(context.Background())
+
+	tests := []struct {
+		name   string
+		search string
+		pod    string
+		want   int
+	}{
+		{"java exception class", "NullPointerException", "java", 1},
+		{"java caused by", `"Caused by"`, "java", 1},
+		{"go panic goroutine", "goroutine", "gopanic", 2},
+		{"go panic source path", `"worker.go"`, "gopanic", 3},
+		{"nginx upstream", "upstream", "nginx", 2},
+		{"klog forbidden", "forbidden", "klog", 2},
+		{"json trace id", "4bf92f3577b34da6a3ce929d0e0e4736", "json", 2},
+		{"connection refused across corpora", `"connection refused"`, "", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := store.Query(context.Background(), storage.Query{Search: tt.search, Pod: tt.pod})
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if len(result.Entries) != tt.want {
+				t.Errorf("Search %q returned %d entries, want %d", tt.search, len(result.Entries), tt.want)
+			}
+		})
+	}
+}
+
 func TestOrderAsc(t *testing.T) {
 	store, err := New(Config{Path: ":memory:"})
 	if err != nil {
@@ -99,6 +159,74 @@ func TestOrderAsc(t *testing.T) {
 	}
 }
 
+func TestOrderByTimestamp(t *testing.T) {
+	store, err := New(Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	// Late batch: entries written after newer ones get higher IDs
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.Write(context.Background(), storage.LogBatch{
+		{Timestamp: base.Add(2 * time.Second), Namespace: "ns", Pod: "pod", Container: "c", Severity: storage.SeverityInfo, Message: "t2"},
+		{Timestamp: base.Add(4 * time.Second), Namespace: "ns", Pod: "pod", Container: "c", Severity: storage.SeverityInfo, Message: "t4"},
+	})
+	store.Flush(context.Background())
+	store.Write(context.Background(), storage.LogBatch{
+		{Timestamp: base.Add(1 * time.Second), Namespace: "ns", Pod: "pod", Container: "c", Severity: storage.SeverityInfo, Message: "t1"},
+		{Timestamp: base.Add(3 * time.Second), Namespace: "ns", Pod: "pod", Container: "c", Severity: storage.SeverityInfo, Message: "t3a"},
+		{Timestamp: base.Add(3 * time.Second), Namespace: "ns", Pod: "pod", Container: "c", Severity: storage.SeverityInfo, Message: "t3b"},
+	})
+	store.Flush(context.Background())
+
+	tests := []struct {
+		name  string
+		order storage.Order
+		want  []string
+	}{
+		{"asc", storage.OrderAsc, []string{"t1", "t2", "t3a", "t3b", "t4"}},
+		{"desc", storage.OrderDesc, []string{"t4", "t3b", "t3a", "t2", "t1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Page through two at a time using the (timestamp, id) keyset
+			var got []string
+			var last *storage.LogEntry
+			for page := 0; page < 5; page++ {
+				p := storage.Pagination{Limit: 2, Order: tt.order, OrderBy: storage.OrderByTimestamp}
+				if last != nil {
+					if tt.order == storage.OrderAsc {
+						p.AfterTimestamp, p.AfterID = last.Timestamp, last.ID
+					} else {
+						p.BeforeTimestamp, p.BeforeID = last.Timestamp, last.ID
+					}
+				}
+
+				result, err := store.Query(context.Background(), storage.Query{Pagination: p})
+				if err != nil {
+					t.Fatalf("Query failed: %v", err)
+				}
+				for _, e := range result.Entries {
+					got = append(got, e.Message)
+				}
+				if !result.HasMore {
+					break
+				}
+				if result.NextCursorTimestamp.IsZero() {
+					t.Fatal("expected NextCursorTimestamp when ordering by timestamp")
+				}
+				last = &result.Entries[len(result.Entries)-1]
+			}
+
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestWriteBuffer(t *testing.T) {
 	store, err := New(Config{Path: ":memory:", WriteBufferSize: 5})
 	if err != nil {
@@ -122,6 +250,95 @@ func TestWriteBuffer(t *testing.T) {
 	}
 }
 
+func TestQueryCountCapped(t *testing.T) {
+	store, err := New(Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	// Spread over two day shards, so the cap carries across them
+	ctx := context.Background()
+	day := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	batch := make(storage.LogBatch, storage.MaxCount+10)
+	for i := range batch {
+		batch[i] = storage.LogEntry{Timestamp: day.Add(time.Duration(i) * time.Second), Namespace: "ns", Pod: "pod", Container: "c", Message: "msg"}
+	}
+	store.Write(ctx, batch)
+	store.Flush(ctx)
+
+	result, err := store.Query(ctx, storage.Query{Count: true})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if result.TotalEstimate != storage.MaxCount || !result.TotalCapped {
+		t.Errorf("TotalEstimate = %d (capped %v), want %d capped", result.TotalEstimate, result.TotalCapped, storage.MaxCount)
+	}
+
+	result, err = store.Query(ctx, storage.Query{Count: true, StartTime: day.Add(24 * time.Hour)})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if want := int64(len(batch) - 86400); result.TotalEstimate != want || result.TotalCapped {
+		t.Errorf("TotalEstimate of day 2 = %d (capped %v), want %d", result.TotalEstimate, result.TotalCapped, want)
+	}
+}
+
+func TestQueryConsistency(t *testing.T) {
+	store, err := New(Config{Path: ":memory:", WriteBufferSize: 100})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	store.Write(ctx, storage.LogBatch{
+		{Timestamp: time.Now(), Namespace: "ns", Pod: "pod", Container: "c", Message: "msg"},
+	})
+
+	eventual := storage.Query{Consistency: storage.ConsistencyEventual}
+	if result, _ := store.Query(ctx, eventual); len(result.Entries) != 0 {
+		t.Errorf("eventual query saw %d freshly buffered entries, want 0", len(result.Entries))
+	}
+
+	// Entries buffered for eventualLag are flushed anyway
+	store.mu.Lock()
+	store.bufferedSince = time.Now().Add(-eventualLag)
+	store.mu.Unlock()
+	if result, _ := store.Query(ctx, eventual); len(result.Entries) != 1 {
+		t.Errorf("eventual query saw %d stale buffered entries, want 1", len(result.Entries))
+	}
+}
+
+func TestFlushWaitsForFlushesInProgress(t *testing.T) {
+	store, err := New(Config{Path: ":memory:", WriteBufferSize: 100})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	store.Write(ctx, storage.LogBatch{
+		{Timestamp: time.Now(), Namespace: "ns", Pod: "pod", Container: "c", Message: "msg"},
+	})
+
+	// Hold up a flush, e.g. one triggered by a full buffer, then query
+	unlock := store.lockWrite()
+	go store.Flush(ctx)
+	time.Sleep(20 * time.Millisecond)
+	done := make(chan *storage.QueryResult)
+	go func() {
+		result, _ := store.Query(ctx, storage.Query{})
+		done <- result
+	}()
+	time.Sleep(20 * time.Millisecond)
+	unlock()
+
+	if result := <-done; result == nil || len(result.Entries) != 1 {
+		t.Errorf("query result = %+v, want the entry of the flush in progress", result)
+	}
+}
+
 func TestCombinedFilters(t *testing.T) {
 	store, err := New(Config{Path: ":memory:"})
 	if err != nil {
@@ -153,6 +370,108 @@ func TestCombinedFilters(t *testing.T) {
 	}
 }
 
+func TestAttributeFilterDottedKey(t *testing.T) {
+	store, err := New(Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	store.Write(context.Background(), storage.LogBatch{
+		{Timestamp: now, Namespace: "prod", Pod: "api-1", Container: "app", Message: "payments", Attributes: map[string]string{"label.team": "payments"}},
+		{Timestamp: now, Namespace: "prod", Pod: "web-1", Container: "app", Message: "core", Attributes: map[string]string{"label.team": "core"}},
+	})
+	store.Flush(context.Background())
+
+	result, err := store.Query(context.Background(), storage.Query{
+		Attributes: map[string]string{"label.team": "payments"},
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entries) != 1 || result.Entries[0].Message != "payments" {
+		t.Errorf("label.team filter returned %+v, want the payments entry", result.Entries)
+	}
+}
+
+func TestAttributeExpressions(t *testing.T) {
+	store, err := New(Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	store.Write(context.Background(), storage.LogBatch{
+		{Timestamp: now, Namespace: "prod", Message: "a", Attributes: map[string]string{"region": "us-east", "tier": "free", "trace_id": "abc"}},
+		{Timestamp: now, Namespace: "prod", Message: "b", Attributes: map[string]string{"region": "us-west", "tier": "gold"}},
+		{Timestamp: now, Namespace: "prod", Message: "c", Attributes: map[string]string{"region": "eu-west", "tier": "gold"}},
+		{Timestamp: now, Namespace: "prod", Message: "d", Attributes: map[string]string{"region": "eu?[1]"}},
+		{Timestamp: now, Namespace: "prod", Message: "e"},
+	})
+	store.Flush(context.Background())
+
+	tests := []struct {
+		query string
+		want  string // Messages, oldest first
+	}{
+		{"attr.region=us-*", "ab"},
+		{"attr.region=*-west", "bc"},
+		{"attr.region=eu?[1]", "d"},
+		{"attr.region=eu*[*", "d"},
+		{"attr.trace_id!=", "a"},
+		{"attr.tier!=gold", "ade"},
+		{"attr.region!=us-*", "cde"},
+		{"attr.region=us-*|attr.tier=gold", "abc"},
+		{"attr.region=eu-*|attr.trace_id!=&attr.tier!=free", "c"},
+		{"attr.tier=gold&attr.region=us-*", "b"},
+	}
+	for _, tt := range tests {
+		q, err := storage.ParseQueryString(tt.query)
+		if err != nil {
+			t.Fatalf("ParseQueryString(%q): %v", tt.query, err)
+		}
+		q.Pagination.Order = storage.OrderAsc
+		result, err := store.Query(context.Background(), q)
+		if err != nil {
+			t.Fatalf("Query(%q) failed: %v", tt.query, err)
+		}
+
+		var got, matched string
+		for _, e := range result.Entries {
+			got += e.Message
+		}
+		all, _ := store.Query(context.Background(), storage.Query{Pagination: storage.Pagination{Order: storage.OrderAsc}})
+		for _, e := range all.Entries {
+			if matchAll(q, e.Attributes) {
+				matched += e.Message
+			}
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.query, got, tt.want)
+		}
+		if matched != tt.want {
+			t.Errorf("%s: AttrExpr.Match got %q, want %q", tt.query, matched, tt.want)
+		}
+	}
+}
+
+// matchAll matches attrs against q's attribute filters in memory.
+func matchAll(q storage.Query, attrs map[string]string) bool {
+	for k, v := range q.Attributes {
+		if attrs[k] != v {
+			return false
+		}
+	}
+	for _, expr := range q.AttrExprs {
+		if !expr.Match(attrs) {
+			return false
+		}
+	}
+	return true
+}
+
 func TestConcurrentWrites(t *testing.T) {
 	// Use file-based DB to properly test locking behavior
 	tmpDir := t.TempDir()
@@ -330,6 +649,7 @@ func TestDeduplicationDifferentEntries(t *testing.T) {
 		{Timestamp: now, Namespace: "ns", Pod: "pod2", Container: "c", Severity: storage.SeverityInfo, Message: "msg1"},                     // different pod
 		{Timestamp: now, Namespace: "ns", Pod: "pod", Container: "c2", Severity: storage.SeverityInfo, Message: "msg1"},                     // different container
 		{Timestamp: now, Namespace: "ns", Pod: "pod", Container: "c", Severity: storage.SeverityInfo, Message: "msg2"},                      // different message
+		{Timestamp: now, Cluster: "east", Namespace: "ns", Pod: "pod", Container: "c", Severity: storage.SeverityInfo, Message: "msg1"},     // different cluster
 	}
 
 	store.Write(context.Background(), entries)
@@ -339,8 +659,8 @@ func TestDeduplicationDifferentEntries(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Stats failed: %v", err)
 	}
-	if stats.TotalEntries != 6 {
-		t.Errorf("Expected 6 distinct entries, got %d", stats.TotalEntries)
+	if stats.TotalEntries != 7 {
+		t.Errorf("Expected 7 distinct entries, got %d", stats.TotalEntries)
 	}
 }
 
@@ -348,31 +668,40 @@ func TestDedupHashCollisionResistance(t *testing.T) {
 	// Test that similar but different entries get different hashes
 	testCases := []struct {
 		ts        int64
+		cluster   string
 		namespace string
 		pod       string
 		container string
 		message   string
 	}{
-		{1000, "ns", "pod", "container", "msg"},
-		{1001, "ns", "pod", "container", "msg"},  // Different timestamp
-		{1000, "ns2", "pod", "container", "msg"}, // Different namespace
-		{1000, "ns", "pod2", "container", "msg"}, // Different pod
-		{1000, "ns", "pod", "container2", "msg"}, // Different container
-		{1000, "ns", "pod", "container", "msg2"}, // Different message
+		{1000, "", "ns", "pod", "container", "msg"},
+		{1001, "", "ns", "pod", "container", "msg"},   // Different timestamp
+		{1000, "", "ns2", "pod", "container", "msg"},  // Different namespace
+		{1000, "", "ns", "pod2", "container", "msg"},  // Different pod
+		{1000, "", "ns", "pod", "container2", "msg"},  // Different container
+		{1000, "", "ns", "pod", "container", "msg2"},  // Different message
+		{1000, "c1", "ns", "pod", "container", "msg"}, // Different cluster
 		// Test separator collision prevention
-		{1000, "ab", "c", "d", "msg"}, // namespace="ab", pod="c"
-		{1000, "a", "bc", "d", "msg"}, // namespace="a", pod="bc" - should be different hash
-		{1000, "a", "b", "cd", "msg"}, // container="cd"
-		{1000, "a", "b", "c", "dmsg"}, // message="dmsg"
+		{1000, "", "ab", "c", "d", "msg"}, // namespace="ab", pod="c"
+		{1000, "", "a", "bc", "d", "msg"}, // namespace="a", pod="bc" - should be different hash
+		{1000, "", "a", "b", "cd", "msg"}, // container="cd"
+		{1000, "", "a", "b", "c", "dmsg"}, // message="dmsg"
+		{1000, "a", "b", "c", "d", "msg"}, // cluster="a", namespace="b"
 	}
 
 	hashes := make(map[int64]int)
 	for i, tc := range testCases {
-		h := computeDedupHash(tc.ts, tc.namespace, tc.pod, tc.container, tc.message)
+		h := computeDedupHash(tc.ts, tc.cluster, tc.namespace, tc.pod, tc.container, tc.message)
 		if prev, exists := hashes[h]; exists {
 			t.Errorf("Hash collision: case %d has same hash as case %d", i, prev)
 		}
 		hashes[h] = i
+
+		// storage.DedupHash, used on the ingest queue path, must agree
+		e := storage.LogEntry{Timestamp: time.Unix(0, tc.ts), Cluster: tc.cluster, Namespace: tc.namespace, Pod: tc.pod, Container: tc.container, Message: tc.message}
+		if got := storage.DedupHash(&e); got != h {
+			t.Errorf("case %d: storage.DedupHash = %d, want %d", i, got, h)
+		}
 	}
 }
 
@@ -453,7 +782,7 @@ func TestMigrationWithDuplicateHashes(t *testing.T) {
 
 	// Step 2: Insert rows with duplicate dedup_hash values
 	now := time.Now().UnixNano()
-	hash := computeDedupHash(now, "ns", "pod", "container", "msg")
+	hash := computeDedupHash(now, "", "ns", "pod", "container", "msg")
 
 	// Insert 3 rows with the same hash (simulating duplicates)
 	for i := 0; i < 3; i++ {
@@ -468,7 +797,7 @@ func TestMigrationWithDuplicateHashes(t *testing.T) {
 	}
 
 	// Also insert a unique entry
-	hash2 := computeDedupHash(now+1, "ns", "pod", "container", "msg2")
+	hash2 := computeDedupHash(now+1, "", "ns", "pod", "container", "msg2")
 	_, err = db.Exec(`
 		INSERT INTO logs (timestamp, namespace, pod, container, severity, message, dedup_hash)
 		VALUES (?, ?, ?, ?, ?, ?, ?)
@@ -497,17 +826,26 @@ func TestMigrationWithDuplicateHashes(t *testing.T) {
 		t.Errorf("Expected 2 entries after deduplication, got %d", stats.TotalEntries)
 	}
 
-	// Step 5: Verify the unique index was created
+	// Step 5: Verify the entries moved to a day shard with a unique index
+	// and the legacy table is gone
 	var indexCount int
 	err = store.db.QueryRow(`
 		SELECT COUNT(*) FROM sqlite_master
-		WHERE type='index' AND name='idx_logs_dedup'
-	`).Scan(&indexCount)
+		WHERE type='index' AND name=?
+	`, "idx_"+shardFor(now).name+"_dedup").Scan(&indexCount)
 	if err != nil {
 		t.Fatalf("Failed to check index: %v", err)
 	}
 	if indexCount != 1 {
-		t.Errorf("Expected idx_logs_dedup index to exist, got count %d", indexCount)
+		t.Errorf("Expected shard dedup index to exist, got count %d", indexCount)
+	}
+
+	var tableType string
+	if err := store.db.QueryRow(`SELECT type FROM sqlite_master WHERE name='logs'`).Scan(&tableType); err != nil {
+		t.Fatalf("Failed to check logs: %v", err)
+	}
+	if tableType != "view" {
+		t.Errorf("Expected logs to be a view after migration, got %s", tableType)
 	}
 }
 
@@ -624,17 +962,17 @@ func TestMigrationWithNullHashes(t *testing.T) {
 		t.Errorf("Expected 0 NULL hashes after migration, got %d", nullCount)
 	}
 
-	// Verify index was created
+	// Verify the entries moved to a day shard with a unique index
 	var indexCount int
 	err = store.db.QueryRow(`
 		SELECT COUNT(*) FROM sqlite_master
-		WHERE type='index' AND name='idx_logs_dedup'
-	`).Scan(&indexCount)
+		WHERE type='index' AND name=?
+	`, "idx_"+shardFor(now).name+"_dedup").Scan(&indexCount)
 	if err != nil {
 		t.Fatalf("Failed to check index: %v", err)
 	}
 	if indexCount != 1 {
-		t.Errorf("Expected idx_logs_dedup index to exist")
+		t.Errorf("Expected shard dedup index to exist")
 	}
 }
 
@@ -723,7 +1061,7 @@ func TestMigrationMixedNullAndDuplicates(t *testing.T) {
 	}
 
 	// Insert duplicate hashes
-	dupHash := computeDedupHash(now+100, "ns", "pod", "container", "dup-msg")
+	dupHash := computeDedupHash(now+100, "", "ns", "pod", "container", "dup-msg")
 	for i := 0; i < 3; i++ {
 		_, err = db.Exec(`
 			INSERT INTO logs (timestamp, namespace, pod, container, severity, message, dedup_hash)
@@ -788,3 +1126,1141 @@ func TestIndexExists(t *testing.T) {
 		t.Error("Expected nonexistent_index to not exist")
 	}
 }
+
+func TestRollups(t *testing.T) {
+	store, err := New(Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	entries := storage.LogBatch{
+		{Timestamp: base, Namespace: "shop", Pod: "api-0", Container: "app", Message: "0123456789"},
+		{Timestamp: base.Add(time.Second), Namespace: "shop", Pod: "api-0", Container: "app", Message: "0123456789"},
+		{Timestamp: base.Add(time.Second), Namespace: "shop", Pod: "api-0", Container: "proxy", Message: "01234"},
+		{Timestamp: base.Add(2 * time.Second), Namespace: "shop", Pod: "api-1", Container: "app", Message: "x"},
+		{Timestamp: base.Add(3 * time.Second), Namespace: "shop", Pod: "api-1", Container: "app", Message: "y"},
+		{Timestamp: base.Add(4 * time.Second), Namespace: "shop", Pod: "api-1", Container: "app", Message: "z"},
+		{Timestamp: base.Add(-time.Hour), Namespace: "infra", Pod: "dns-0", Container: "dns", Message: "old"},
+	}
+	store.Write(ctx, entries)
+	// Duplicates are not counted twice
+	store.Write(ctx, entries[:1])
+	store.Flush(ctx)
+
+	tests := []struct {
+		name string
+		q    storage.RollupQuery
+		want []storage.Rollup
+	}{
+		{"by namespace", storage.RollupQuery{By: storage.RollupByNamespace}, []storage.Rollup{
+			{Namespace: "shop", Lines: 6, Bytes: 28, LastSeen: base.Add(4 * time.Second)},
+			{Namespace: "infra", Lines: 1, Bytes: 3, LastSeen: base.Add(-time.Hour)},
+		}},
+		{"by pod", storage.RollupQuery{By: storage.RollupByPod, StartTime: base}, []storage.Rollup{
+			{Namespace: "shop", Pod: "api-0", Lines: 3, Bytes: 25, LastSeen: base.Add(time.Second)},
+			{Namespace: "shop", Pod: "api-1", Lines: 3, Bytes: 3, LastSeen: base.Add(4 * time.Second)},
+		}},
+		{"by pod ordered by lines", storage.RollupQuery{By: storage.RollupByPod, StartTime: base, OrderByLines: true, Limit: 1}, []storage.Rollup{
+			{Namespace: "shop", Pod: "api-0", Lines: 3, Bytes: 25, LastSeen: base.Add(time.Second)},
+		}},
+		{"by container in namespace", storage.RollupQuery{By: storage.RollupByContainer, Namespace: "shop", Limit: 2}, []storage.Rollup{
+			{Namespace: "shop", Pod: "api-0", Container: "app", Lines: 2, Bytes: 20, LastSeen: base.Add(time.Second)},
+			{Namespace: "shop", Pod: "api-0", Container: "proxy", Lines: 1, Bytes: 5, LastSeen: base.Add(time.Second)},
+		}},
+		{"before", storage.RollupQuery{By: storage.RollupByNamespace, EndTime: base}, []storage.Rollup{
+			{Namespace: "infra", Lines: 1, Bytes: 3, LastSeen: base.Add(-time.Hour)},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.Rollups(ctx, tt.q)
+			if err != nil {
+				t.Fatalf("Rollups failed: %v", err)
+			}
+			for i := range got {
+				got[i].LastSeen = got[i].LastSeen.UTC()
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Rollups() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	stats, err := store.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	wantUsage := []storage.NamespaceUsage{
+		{Namespace: "shop", Entries: 6, Bytes: 28},
+		{Namespace: "infra", Entries: 1, Bytes: 3},
+	}
+	if fmt.Sprint(stats.Namespaces) != fmt.Sprint(wantUsage) {
+		t.Errorf("Stats.Namespaces = %+v, want %+v", stats.Namespaces, wantUsage)
+	}
+
+	// Retention drops expired buckets along with their entries
+	if _, err := store.Delete(ctx, base.Add(-30*time.Minute)); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	got, err := store.Rollups(ctx, storage.RollupQuery{By: storage.RollupByNamespace})
+	if err != nil {
+		t.Fatalf("Rollups failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Namespace != "shop" {
+		t.Errorf("Rollups after delete = %+v, want only shop", got)
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	store, err := New(Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	entries := storage.LogBatch{
+		{Timestamp: base, Namespace: "shop", Pod: "api-0", Container: "app", Severity: storage.SeverityInfo, Message: "request took 12ms"},
+		{Timestamp: base.Add(time.Minute), Namespace: "shop", Pod: "api-1", Container: "app", Severity: storage.SeverityWarn, Message: "request took 950ms"},
+		{Timestamp: base.Add(2 * time.Minute), Namespace: "shop", Pod: "api-1", Container: "app", Severity: storage.SeverityError, Message: "connection refused"},
+	}
+	store.Write(ctx, entries)
+	// Duplicates are not counted twice
+	store.Write(ctx, entries[:1])
+	store.Flush(ctx)
+	// An entry arriving late moves the pattern's first sighting back
+	store.Write(ctx, storage.LogBatch{
+		{Timestamp: base.Add(-time.Hour), Namespace: "infra", Pod: "gw-0", Container: "gw", Severity: storage.SeverityInfo, Message: "request took 3ms"},
+	})
+	store.Flush(ctx)
+
+	got, err := store.Patterns(ctx, storage.PatternQuery{})
+	if err != nil {
+		t.Fatalf("Patterns failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Patterns() = %+v, want 2 patterns", got)
+	}
+	refused, took := got[0], got[1]
+	if refused.Pattern != "connection refused" || refused.Count != 1 || refused.MaxSeverity != storage.SeverityError {
+		t.Errorf("Patterns()[0] = %+v, want connection refused once at error", refused)
+	}
+	if took.Pattern != "request took <num>" || took.Count != 3 || took.MaxSeverity != storage.SeverityWarn {
+		t.Errorf("Patterns()[1] = %+v, want request took <num> 3 times up to warn", took)
+	}
+	if !took.FirstSeen.Equal(base.Add(-time.Hour)) || !took.LastSeen.Equal(base.Add(time.Minute)) {
+		t.Errorf("request took seen %v to %v, want %v to %v", took.FirstSeen, took.LastSeen, base.Add(-time.Hour), base.Add(time.Minute))
+	}
+	if took.Namespace != "infra" || took.Pod != "gw-0" || took.Example != "request took 3ms" {
+		t.Errorf("request took first at %s/%s %q, want infra/gw-0", took.Namespace, took.Pod, took.Example)
+	}
+	if first, err := store.GetByID(ctx, took.FirstID); err != nil || first == nil || first.Message != took.Example {
+		t.Errorf("GetByID(FirstID) = %+v, %v, want the first entry", first, err)
+	}
+
+	got, err = store.Patterns(ctx, storage.PatternQuery{FirstSeenAfter: base})
+	if err != nil {
+		t.Fatalf("Patterns failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Pattern != "connection refused" {
+		t.Errorf("Patterns(FirstSeenAfter) = %+v, want only connection refused", got)
+	}
+	got, err = store.Patterns(ctx, storage.PatternQuery{MinSeverity: storage.SeverityWarn, Limit: 1})
+	if err != nil {
+		t.Fatalf("Patterns failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Pattern != "connection refused" {
+		t.Errorf("Patterns(MinSeverity, Limit) = %+v, want only connection refused", got)
+	}
+
+	// Retention forgets patterns not seen since the cutoff
+	if _, err := store.Delete(ctx, base.Add(90*time.Second)); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	got, err = store.Patterns(ctx, storage.PatternQuery{})
+	if err != nil {
+		t.Fatalf("Patterns failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Pattern != "connection refused" {
+		t.Errorf("Patterns after delete = %+v, want only connection refused", got)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	store, err := New(Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	// Late on one day and early the next, so buckets span two shards
+	base := time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)
+	store.Write(ctx, storage.LogBatch{
+		{Timestamp: base, Namespace: "shop", Pod: "p", Container: "c", Severity: storage.SeverityInfo, Message: "a"},
+		{Timestamp: base.Add(10 * time.Minute), Namespace: "shop", Pod: "p", Container: "c", Severity: storage.SeverityInfo, Message: "b"},
+		{Timestamp: base.Add(20 * time.Minute), Namespace: "shop", Pod: "p", Container: "c", Severity: storage.SeverityError, Message: "connection refused"},
+		{Timestamp: base.Add(90 * time.Minute), Namespace: "shop", Pod: "p", Container: "c", Severity: storage.SeverityInfo, Message: "c"},
+		{Timestamp: base.Add(100 * time.Minute), Namespace: "infra", Pod: "p", Container: "c", Severity: storage.SeverityWarn, Message: "d"},
+	})
+	store.Flush(ctx)
+
+	tests := []struct {
+		name     string
+		q        storage.Query
+		interval time.Duration
+		want     []storage.HistogramBucket
+	}{
+		{"hourly", storage.Query{}, time.Hour, []storage.HistogramBucket{
+			{Start: base, Severity: storage.SeverityInfo, Count: 2},
+			{Start: base, Severity: storage.SeverityError, Count: 1},
+			{Start: base.Add(time.Hour), Severity: storage.SeverityInfo, Count: 1},
+			{Start: base.Add(time.Hour), Severity: storage.SeverityWarn, Count: 1},
+		}},
+		{"daily across shards", storage.Query{}, 24 * time.Hour, []storage.HistogramBucket{
+			{Start: base.Add(-23 * time.Hour), Severity: storage.SeverityInfo, Count: 2},
+			{Start: base.Add(-23 * time.Hour), Severity: storage.SeverityError, Count: 1},
+			{Start: base.Add(time.Hour), Severity: storage.SeverityInfo, Count: 1},
+			{Start: base.Add(time.Hour), Severity: storage.SeverityWarn, Count: 1},
+		}},
+		{"filtered", storage.Query{Namespace: "shop", Search: "refused"}, time.Hour, []storage.HistogramBucket{
+			{Start: base, Severity: storage.SeverityError, Count: 1},
+		}},
+		{"time range", storage.Query{StartTime: base.Add(time.Hour), MinSeverity: storage.SeverityWarn}, time.Hour, []storage.HistogramBucket{
+			{Start: base.Add(time.Hour), Severity: storage.SeverityWarn, Count: 1},
+		}},
+		{"pagination ignored", storage.Query{Pagination: storage.Pagination{Limit: 1, AfterID: 4}}, 24 * time.Hour, []storage.HistogramBucket{
+			{Start: base.Add(-23 * time.Hour), Severity: storage.SeverityInfo, Count: 2},
+			{Start: base.Add(-23 * time.Hour), Severity: storage.SeverityError, Count: 1},
+			{Start: base.Add(time.Hour), Severity: storage.SeverityInfo, Count: 1},
+			{Start: base.Add(time.Hour), Severity: storage.SeverityWarn, Count: 1},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.Histogram(ctx, tt.q, tt.interval)
+			if err != nil {
+				t.Fatalf("Histogram failed: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Histogram() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Start.Equal(tt.want[i].Start) || got[i].Severity != tt.want[i].Severity || got[i].Count != tt.want[i].Count {
+					t.Errorf("bucket %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := store.Histogram(ctx, storage.Query{}, 0); err == nil {
+		t.Error("Histogram with zero interval succeeded")
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	store, err := New(Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	// Late on one day and early the next, so groups span two shards
+	base := time.Date(2024, 3, 1, 23, 30, 0, 0, time.UTC)
+	entry := func(offset time.Duration, pod string, sev storage.Severity, route, latency string) storage.LogEntry {
+		attrs := map[string]string{"route": route}
+		if latency != "" {
+			attrs["latency_ms"] = latency
+		}
+		return storage.LogEntry{Timestamp: base.Add(offset), Namespace: "shop", Pod: pod, Container: "c",
+			Severity: sev, Message: pod + route + latency, Attributes: attrs}
+	}
+	store.Write(ctx, storage.LogBatch{
+		entry(0, "api-1", storage.SeverityInfo, "/cart", "10"),
+		entry(time.Minute, "api-1", storage.SeverityInfo, "/cart", "30.5"),
+		entry(2*time.Minute, "api-2", storage.SeverityError, "/cart", "slow"),
+		entry(40*time.Minute, "api-2", storage.SeverityInfo, "/cart", "-2e1"),
+		entry(41*time.Minute, "api-2", storage.SeverityWarn, "/pay", ""),
+	})
+	store.Flush(ctx)
+
+	type group struct {
+		start  time.Time
+		keys   string
+		count  int64
+		values int64
+		min    float64
+		max    float64
+		avg    float64
+	}
+	tests := []struct {
+		name string
+		q    storage.Query
+		a    storage.Aggregation
+		want []group
+	}{
+		{"count", storage.Query{}, storage.Aggregation{}, []group{
+			{keys: "", count: 5},
+		}},
+		{"by pod", storage.Query{}, storage.Aggregation{GroupBy: []string{"pod"}}, []group{
+			{keys: "api-1", count: 2},
+			{keys: "api-2", count: 3},
+		}},
+		{"by severity and attribute", storage.Query{}, storage.Aggregation{GroupBy: []string{"severity", "attr.route"}}, []group{
+			{keys: "ERROR /cart", count: 1},
+			{keys: "INFO /cart", count: 3},
+			{keys: "WARN /pay", count: 1},
+		}},
+		{"value across shards", storage.Query{}, storage.Aggregation{GroupBy: []string{"attr.route"}, Value: "latency_ms"}, []group{
+			{keys: "/cart", count: 4, values: 3, min: -20, max: 30.5, avg: 20.5 / 3},
+			{keys: "/pay", count: 1},
+		}},
+		{"hourly buckets", storage.Query{Namespace: "shop"}, storage.Aggregation{Interval: time.Hour, Value: "latency_ms"}, []group{
+			{start: base.Add(-30 * time.Minute), count: 3, values: 2, min: 10, max: 30.5, avg: 20.25},
+			{start: base.Add(30 * time.Minute), count: 2, values: 1, min: -20, max: -20, avg: -20},
+		}},
+		{"filtered", storage.Query{MinSeverity: storage.SeverityWarn}, storage.Aggregation{GroupBy: []string{"pod", "attr.missing"}}, []group{
+			{keys: "api-2 ", count: 2},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.Aggregate(ctx, tt.q, tt.a)
+			if err != nil {
+				t.Fatalf("Aggregate failed: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Aggregate() = %+v, want %+v", got, tt.want)
+			}
+			for i, g := range got {
+				w := tt.want[i]
+				if !g.Start.Equal(w.start) || strings.Join(g.Keys, " ") != w.keys || g.Count != w.count ||
+					g.ValueCount != w.values || g.Min != w.min || g.Max != w.max || g.Avg() != w.avg {
+					t.Errorf("group %d = %+v (avg %v), want %+v", i, g, g.Avg(), w)
+				}
+			}
+		})
+	}
+
+	for _, a := range []storage.Aggregation{
+		{GroupBy: []string{"message"}},
+		{GroupBy: []string{"attr."}},
+		{GroupBy: []string{"pod", "pod"}},
+		{GroupBy: []string{"pod", "container", "namespace", "cluster", "severity"}},
+		{Interval: -time.Second},
+	} {
+		if _, err := store.Aggregate(ctx, storage.Query{}, a); err == nil {
+			t.Errorf("Aggregate(%+v) succeeded", a)
+		}
+	}
+}
+
+func TestRollupBackfill(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	ctx := context.Background()
+
+	store, err := New(Config{Path: dbPath})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	now := time.Now()
+	store.Write(ctx, storage.LogBatch{
+		{Timestamp: now, Namespace: "ns", Pod: "pod", Container: "c", Message: "hello"},
+		{Timestamp: now.Add(time.Second), Namespace: "ns", Pod: "pod", Container: "c", Message: "héllo"},
+	})
+	store.Flush(ctx)
+
+	// Simulate a database from before rollups existed, upgraded to a
+	// version without last_seen
+	if _, err := store.DB().Exec(`DELETE FROM log_rollups; ALTER TABLE log_rollups DROP COLUMN last_seen`); err != nil {
+		t.Fatalf("Failed to clear rollups: %v", err)
+	}
+	store.Close()
+
+	store, err = New(Config{Path: dbPath})
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer store.Close()
+
+	got, err := store.Rollups(ctx, storage.RollupQuery{By: storage.RollupByContainer})
+	if err != nil {
+		t.Fatalf("Rollups failed: %v", err)
+	}
+	want := []storage.Rollup{{Namespace: "ns", Pod: "pod", Container: "c", Lines: 2, Bytes: 11, LastSeen: time.Unix(0, now.Add(time.Second).UnixNano())}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Rollups() = %+v, want %+v", got, want)
+	}
+}
+
+func TestShards(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	ctx := context.Background()
+
+	store, err := New(Config{Path: dbPath})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	// Three days, written out of timestamp order
+	day := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	var batch storage.LogBatch
+	for _, d := range []int{2, 0, 1} {
+		for h := 0; h < 3; h++ {
+			batch = append(batch, storage.LogEntry{
+				Timestamp: day.Add(time.Duration(d)*24*time.Hour + time.Duration(h)*time.Hour),
+				Namespace: "ns", Pod: "pod", Container: "c",
+				Message: fmt.Sprintf("day %d hour %d", d, h),
+			})
+		}
+	}
+	store.Write(ctx, batch)
+	store.Flush(ctx)
+
+	var tables int
+	store.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name GLOB 'logs_2024011[567]'`).Scan(&tables)
+	if tables != 3 {
+		t.Errorf("got %d shard tables, want 3", tables)
+	}
+
+	messages := func(q storage.Query) []string {
+		t.Helper()
+		var got []string
+		for {
+			result, err := store.Query(ctx, q)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			for _, e := range result.Entries {
+				got = append(got, e.Message[4:])
+			}
+			if !result.HasMore {
+				return got
+			}
+			last := result.Entries[len(result.Entries)-1]
+			q.Pagination.BeforeID, q.Pagination.BeforeTimestamp = last.ID, last.Timestamp
+		}
+	}
+
+	tests := []struct {
+		name string
+		q    storage.Query
+		want string
+	}{
+		{"by ID", storage.Query{Pagination: storage.Pagination{Limit: 4}},
+			"[1 hour 2 1 hour 1 1 hour 0 0 hour 2 0 hour 1 0 hour 0 2 hour 2 2 hour 1 2 hour 0]"},
+		{"by timestamp", storage.Query{Pagination: storage.Pagination{Limit: 2, OrderBy: storage.OrderByTimestamp}},
+			"[2 hour 2 2 hour 1 2 hour 0 1 hour 2 1 hour 1 1 hour 0 0 hour 2 0 hour 1 0 hour 0]"},
+		{"time range", storage.Query{StartTime: day.Add(26 * time.Hour), EndTime: day.Add(49 * time.Hour)},
+			"[1 hour 2 2 hour 0]"},
+		{"search", storage.Query{Search: `"hour 1"`, Pagination: storage.Pagination{OrderBy: storage.OrderByTimestamp}},
+			"[2 hour 1 1 hour 1 0 hour 1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fmt.Sprint(messages(tt.q)); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+
+	// Retention drops the first day and trims the second
+	deleted, err := store.Delete(ctx, day.Add(25*time.Hour))
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if deleted != 4 {
+		t.Errorf("Delete returned %d, want 4", deleted)
+	}
+	shards, _ := loadShards(ctx, store.db)
+	if len(shards) != 2 || shards[0].name != "logs_20240116" {
+		t.Errorf("shards after delete = %v", shards)
+	}
+
+	// IDs are never reused, even after every shard is dropped
+	store.Delete(ctx, day.Add(72*time.Hour))
+	store.Close()
+
+	store, err = New(Config{Path: dbPath})
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer store.Close()
+	store.Write(ctx, storage.LogBatch{{Timestamp: day, Namespace: "ns", Pod: "pod", Container: "c", Message: "again"}})
+	result, err := store.Query(ctx, storage.Query{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entries) != 1 || result.Entries[0].ID != 10 {
+		t.Errorf("entries after reopen = %+v, want one with ID 10", result.Entries)
+	}
+}
+
+func TestUnionAll(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// More terms than a single compound SELECT allows
+	selects := make([]string, 1000)
+	for i := range selects {
+		selects[i] = fmt.Sprintf("SELECT %d AS n", i)
+	}
+	var count, sum int
+	if err := db.QueryRow(`SELECT COUNT(*), SUM(n) FROM (`+unionAll(selects)+`)`).Scan(&count, &sum); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if count != 1000 || sum != 999*1000/2 {
+		t.Errorf("got count %d sum %d", count, sum)
+	}
+}
+
+func TestShardClusterUpgrade(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	ctx := context.Background()
+	day := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	store, err := New(Config{Path: dbPath})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	store.Write(ctx, storage.LogBatch{{Timestamp: day, Namespace: "ns", Pod: "pod", Container: "c", Message: "old"}})
+	store.Close()
+
+	// Turn the shard into one created before clusters and entry expiry
+	// existed
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	_, err = db.Exec(`
+		DROP VIEW logs;
+		DROP INDEX idx_logs_20240115_cluster;
+		DROP INDEX idx_logs_20240115_expires;
+		ALTER TABLE logs_20240115 DROP COLUMN cluster;
+		ALTER TABLE logs_20240115 DROP COLUMN expires_at;
+	`)
+	db.Close()
+	if err != nil {
+		t.Fatalf("Failed to downgrade shard: %v", err)
+	}
+
+	store, err = New(Config{Path: dbPath})
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer store.Close()
+
+	store.Write(ctx, storage.LogBatch{{Timestamp: day.Add(time.Hour), Cluster: "east", Namespace: "ns", Pod: "pod", Container: "c", Message: "new"}})
+	result, err := store.Query(ctx, storage.Query{Cluster: "east"})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entries) != 1 || result.Entries[0].Message != "new" {
+		t.Errorf("entries in east = %+v, want only the new one", result.Entries)
+	}
+	old, err := store.GetByID(ctx, 1)
+	if err != nil || old.Cluster != "" {
+		t.Errorf("GetByID(1) = %+v, %v", old, err)
+	}
+
+	clusters, err := store.ListClusters(ctx)
+	if err != nil {
+		t.Fatalf("ListClusters failed: %v", err)
+	}
+	if len(clusters) != 1 || clusters[0] != "east" {
+		t.Errorf("ListClusters = %v, want [east]", clusters)
+	}
+	if ok, _ := indexExists(store.db, "logs_20240115", "idx_logs_20240115_cluster"); !ok {
+		t.Error("cluster index not recreated")
+	}
+	if ok, _ := indexExists(store.db, "logs_20240115", "idx_logs_20240115_expires"); !ok {
+		t.Error("expiry index not recreated")
+	}
+}
+
+func TestQueryReadOnly(t *testing.T) {
+	store, err := New(Config{Path: ":memory:", WriteBufferSize: 1})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	now := time.Now()
+	store.Write(ctx, storage.LogBatch{
+		{Timestamp: now, Namespace: "a", Pod: "pod", Container: "c", Message: "connection refused"},
+		{Timestamp: now, Namespace: "a", Pod: "pod", Container: "c", Message: "ok"},
+		{Timestamp: now, Namespace: "b", Pod: "pod", Container: "c", Message: "connection reset"},
+	})
+	if _, err := store.DB().Exec(`INSERT INTO users (username, password, created_at, updated_at) VALUES ('admin', 'hash', 0, 0)`); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+
+	result, err := store.QueryReadOnly(ctx, `SELECT namespace, COUNT(*) AS n FROM logs GROUP BY namespace ORDER BY namespace`, 10)
+	if err != nil {
+		t.Fatalf("QueryReadOnly failed: %v", err)
+	}
+	if fmt.Sprint(result.Columns) != "[namespace n]" || fmt.Sprint(result.Rows) != "[[a 2] [b 1]]" || result.Truncated {
+		t.Errorf("result = %+v", result)
+	}
+
+	for _, query := range []string{
+		`WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 3) SELECT i FROM n`,
+		fmt.Sprintf(`SELECT rowid FROM logs_%s_fts WHERE logs_%[1]s_fts MATCH 'connection'`, now.UTC().Format("20060102")),
+		`SELECT name FROM sqlite_master`,
+		`PRAGMA table_info(log_shards)`,
+	} {
+		if _, err := store.QueryReadOnly(ctx, query, 10); err != nil {
+			t.Errorf("QueryReadOnly(%q) failed: %v", query, err)
+		}
+	}
+
+	truncated, err := store.QueryReadOnly(ctx, `SELECT id FROM logs`, 2)
+	if err != nil {
+		t.Fatalf("QueryReadOnly failed: %v", err)
+	}
+	if len(truncated.Rows) != 2 || !truncated.Truncated {
+		t.Errorf("capped result = %+v, want 2 rows, truncated", truncated)
+	}
+
+	for _, query := range []string{
+		`DELETE FROM logs`,
+		`UPDATE log_sequence SET next_id = 0`,
+		`SELECT id FROM logs; DELETE FROM logs`,
+		`DROP TABLE log_rollups`,
+		`CREATE TABLE x (id INTEGER)`,
+		`PRAGMA journal_mode = WAL`,
+		`PRAGMA user_version`,
+		`ATTACH DATABASE ':memory:' AS other`,
+		`SELECT password FROM users`,
+		`SELECT COUNT(*) FROM sessions`,
+	} {
+		if _, err := store.QueryReadOnly(ctx, query, 10); err == nil {
+			t.Errorf("QueryReadOnly(%q) succeeded, want error", query)
+		}
+	}
+
+	// The authorizer is removed afterwards, and nothing was changed
+	if _, err := store.Write(ctx, storage.LogBatch{{Timestamp: now, Namespace: "a", Pod: "pod", Container: "c", Message: "after"}}); err != nil {
+		t.Fatalf("Write after console query failed: %v", err)
+	}
+	stats, err := store.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.TotalEntries != 4 {
+		t.Errorf("TotalEntries = %d, want 4", stats.TotalEntries)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = store.QueryReadOnly(timeout, `WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT COUNT(*) FROM n`, 10)
+	if err == nil {
+		t.Error("unbounded query finished, want timeout")
+	}
+}
+
+func TestSchema(t *testing.T) {
+	store, err := New(Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	store.Write(ctx, storage.LogBatch{{Timestamp: now, Namespace: "a", Pod: "pod", Container: "c", Message: "hello"}})
+	store.Flush(ctx)
+	shard := "logs_" + now.UTC().Format("20060102")
+
+	objects, err := store.Schema(ctx)
+	if err != nil {
+		t.Fatalf("Schema failed: %v", err)
+	}
+	found := map[string]string{}
+	for _, o := range objects {
+		found[o.Name] = o.Type
+		if strings.HasPrefix(o.Name, "sqlite_") || strings.HasSuffix(o.Name, "_fts_data") {
+			t.Errorf("internal object %s listed", o.Name)
+		}
+	}
+	if found["logs"] != "view" || found["users"] != "table" || found["idx_sessions_expires_at"] != "index" || found[shard+"_fts"] != "table" {
+		t.Errorf("schema objects = %v", found)
+	}
+}
+
+func TestTuning(t *testing.T) {
+	pragma := func(store *Store, name string) int64 {
+		t.Helper()
+		var v int64
+		if err := store.db.QueryRow("PRAGMA " + name).Scan(&v); err != nil {
+			t.Fatalf("PRAGMA %s: %v", name, err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		opts          storage.Options
+		wantCacheKiB  int64
+		wantMmap      int64
+		wantTempStore int64 // 1 file, 2 memory
+	}{
+		{storage.Options{}, 64 << 10, 0, 2},
+		{storage.Options{"preset": "medium"}, 256 << 10, 512 << 20, 2},
+		{storage.Options{"preset": "medium", "mmap_size": "-1", "cache_size": "1048576", "temp_store": "file"}, 1 << 10, 0, 1},
+	}
+	for i, tt := range tests {
+		tt.opts["path"] = filepath.Join(t.TempDir(), "test.db")
+		cfg, err := ConfigFromOptions(tt.opts)
+		if err != nil {
+			t.Fatalf("ConfigFromOptions(%v): %v", tt.opts, err)
+		}
+		store, err := New(cfg)
+		if err != nil {
+			t.Fatalf("New(%+v): %v", cfg, err)
+		}
+		if got := -pragma(store, "cache_size"); got != tt.wantCacheKiB {
+			t.Errorf("%d: cache_size = %d KiB, want %d", i, got, tt.wantCacheKiB)
+		}
+		if got := pragma(store, "mmap_size"); got != tt.wantMmap {
+			t.Errorf("%d: mmap_size = %d, want %d", i, got, tt.wantMmap)
+		}
+		if got := pragma(store, "temp_store"); got != tt.wantTempStore {
+			t.Errorf("%d: temp_store = %d, want %d", i, got, tt.wantTempStore)
+		}
+		store.Close()
+	}
+
+	for _, opts := range []storage.Options{{"preset": "huge"}, {"temp_store": "disk"}, {"mmap_size": "1G"}} {
+		cfg, err := ConfigFromOptions(opts)
+		if err == nil {
+			_, err = New(cfg)
+		}
+		if err == nil {
+			t.Errorf("options %v: expected error", opts)
+		}
+	}
+}
+
+func TestIndexedAttributes(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	now := time.Now()
+
+	// Entries written before the attributes were indexed
+	store, err := New(Config{Path: dbPath})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	store.Write(ctx, storage.LogBatch{
+		{Timestamp: now, Namespace: "a", Pod: "p", Container: "c", Message: "one", Attributes: map[string]string{"trace_id": "abc", "label.app": "web"}},
+		{Timestamp: now, Namespace: "a", Pod: "p", Container: "c", Message: "two", Attributes: map[string]string{"trace_id": "def"}},
+		{Timestamp: now, Namespace: "a", Pod: "p", Container: "c", Message: "three"},
+	})
+	store.Close()
+
+	store, err = New(Config{Path: dbPath, IndexedAttributes: []string{"trace_id", "label.app"}})
+	if err != nil {
+		t.Fatalf("New with indexed attributes: %v", err)
+	}
+	store.Write(ctx, storage.LogBatch{
+		{Timestamp: now.Add(time.Second), Namespace: "a", Pod: "p", Container: "c", Message: "four", Attributes: map[string]string{"trace_id": "abc"}},
+	})
+
+	messages := func(q storage.Query) []string {
+		t.Helper()
+		q.Pagination = storage.Pagination{Order: storage.OrderAsc}
+		result, err := store.Query(ctx, q)
+		if err != nil {
+			t.Fatalf("Query(%+v): %v", q, err)
+		}
+		var got []string
+		for _, e := range result.Entries {
+			got = append(got, e.Message)
+		}
+		return got
+	}
+	tests := []struct {
+		q    storage.Query
+		want []string
+	}{
+		{storage.Query{Attributes: map[string]string{"trace_id": "abc"}}, []string{"one", "four"}},
+		{storage.Query{Attributes: map[string]string{"label.app": "web"}}, []string{"one"}},
+		{storage.Query{AttrExprs: []storage.AttrExpr{{{Key: "trace_id", Op: storage.AttrNotEqual, Value: "abc"}}}}, []string{"two", "three"}},
+		{storage.Query{AttrExprs: []storage.AttrExpr{{{Key: "trace_id", Op: storage.AttrExists}}}}, []string{"one", "two", "four"}},
+		{storage.Query{AttrExprs: []storage.AttrExpr{{{Key: "trace_id", Value: "d*"}}}}, []string{"two"}},
+	}
+	for _, tt := range tests {
+		if got := messages(tt.q); !slices.Equal(got, tt.want) {
+			t.Errorf("Query(%+v) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+
+	shard := shardFor(now.UnixNano()).name
+	query, args := buildQuery(storage.Query{Attributes: map[string]string{"trace_id": "abc"}}, shard, store.attrColumns)
+	var plan strings.Builder
+	rows, err := store.db.Query("EXPLAIN QUERY PLAN "+query, args...)
+	if err != nil {
+		t.Fatalf("explain: %v", err)
+	}
+	for rows.Next() {
+		var id, parent, notused int
+		var detail string
+		rows.Scan(&id, &parent, &notused, &detail)
+		plan.WriteString(detail + "\n")
+	}
+	rows.Close()
+	if !strings.Contains(plan.String(), `idx_`+shard+`_attr.trace_id`) {
+		t.Errorf("query plan doesn't use the attribute index:\n%s", plan.String())
+	}
+	store.Close()
+
+	// Unindexed attributes lose their columns
+	store, err = New(Config{Path: dbPath, IndexedAttributes: []string{"trace_id"}})
+	if err != nil {
+		t.Fatalf("New without label.app: %v", err)
+	}
+	defer store.Close()
+	if got := messages(storage.Query{Attributes: map[string]string{"label.app": "web"}}); !slices.Equal(got, []string{"one"}) {
+		t.Errorf("label.app query after unindexing = %v, want [one]", got)
+	}
+	var n int
+	store.db.QueryRow(`SELECT COUNT(*) FROM pragma_table_xinfo(?) WHERE name = 'attr.label.app'`, shard).Scan(&n)
+	if n != 0 {
+		t.Errorf("column attr.label.app still exists")
+	}
+
+	if _, err := New(Config{Path: ":memory:", IndexedAttributes: []string{`bad"key`}}); err == nil {
+		t.Error("expected error for invalid attribute key")
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	store, err := New(Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	reg := metrics.NewRegistry()
+	store.RegisterMetrics(reg)
+
+	ctx := context.Background()
+	now := time.Now()
+	for i := range 2 {
+		store.Write(ctx, storage.LogBatch{{Timestamp: now, Namespace: "a", Pod: "p", Container: "c", Message: fmt.Sprint("line ", i)}})
+		if err := store.Flush(ctx); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+	}
+	if err := store.countBusy(fmt.Errorf("insert: %w", sqlite3.Error{Code: sqlite3.ErrBusy})); err == nil {
+		t.Error("countBusy dropped the error")
+	}
+	store.countBusy(errors.New("disk I/O error"))
+
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`kubelogs_sqlite_write_lock_wait_seconds_count{db=":memory:"} 2` + "\n",
+		`kubelogs_sqlite_write_lock_held_seconds_count{db=":memory:"} 2` + "\n",
+		`kubelogs_sqlite_commit_seconds_count{db=":memory:"} 2` + "\n",
+		`kubelogs_sqlite_busy_errors_total{db=":memory:"} 1` + "\n",
+		`kubelogs_sqlite_conn_waits_total{db=":memory:"}`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestFTSShedding(t *testing.T) {
+	store, err := New(Config{Path: ":memory:", FTSShedBacklog: 10})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	flush := func(batch string, n int) {
+		t.Helper()
+		for i := range n {
+			store.Write(ctx, storage.LogBatch{{Timestamp: now, Namespace: "a", Pod: "p", Container: "c", Message: fmt.Sprintf("%s line %d", batch, i)}})
+		}
+		if err := store.Flush(ctx); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+	}
+	search := func(term string) int {
+		t.Helper()
+		result, err := store.Query(ctx, storage.Query{Search: term, Pagination: storage.Pagination{Limit: 100}})
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		return len(result.Entries)
+	}
+	checkIndex := func() {
+		t.Helper()
+		table := shardFor(now.UnixNano()).name
+		if _, err := store.db.Exec(`INSERT INTO ` + table + `_fts(` + table + `_fts) VALUES ('integrity-check')`); err != nil {
+			t.Errorf("FTS integrity check: %v", err)
+		}
+	}
+
+	// A backlog over the threshold sheds from the third flush on
+	flush("first", 20)
+	flush("second", 20)
+	if store.shedding.Load() {
+		t.Fatal("shedding after two flushes")
+	}
+	flush("third", 20)
+	if !store.shedding.Load() {
+		t.Fatal("not shedding after three flushes")
+	}
+	if n := search("second"); n != 20 {
+		t.Errorf("search second = %d, want 20", n)
+	}
+	if n := search("third"); n != 0 {
+		t.Errorf("search third = %d while shed, want 0", n)
+	}
+	if n := store.ftsBacklog.Load(); n != 20 {
+		t.Errorf("backlog = %d, want 20", n)
+	}
+	result, err := store.Query(ctx, storage.Query{Namespace: "a", Pagination: storage.Pagination{Limit: 100}})
+	if err != nil || len(result.Entries) != 60 {
+		t.Fatalf("Query = %v entries, %v; want 60 stored", len(result.Entries), err)
+	}
+
+	// A small backlog resumes indexing, and the shed entries are indexed
+	flush("fourth", 1)
+	if store.shedding.Load() {
+		t.Fatal("still shedding after backlog cleared")
+	}
+	if n := search("fourth"); n != 1 {
+		t.Errorf("search fourth = %d, want 1", n)
+	}
+	if _, err := store.indexBacklog(ctx, "", ftsIndexChunk); err != nil {
+		t.Fatalf("indexBacklog: %v", err)
+	}
+	if n := search("third"); n != 20 {
+		t.Errorf("search third = %d after indexing, want 20", n)
+	}
+	if n := store.ftsBacklog.Load(); n != 0 {
+		t.Errorf("backlog = %d after indexing, want 0", n)
+	}
+	checkIndex()
+
+	// Deleting entries still unindexed keeps the index consistent
+	store.overFlushes = shedAfterFlushes
+	flush("fifth", 20)
+	if !store.shedding.Load() {
+		t.Fatal("not shedding")
+	}
+	n, err := store.DeleteByQuery(ctx, storage.Query{Namespace: "a"})
+	if err != nil || n != 81 {
+		t.Fatalf("DeleteByQuery = %d, %v; want 81", n, err)
+	}
+	checkIndex()
+	var rows int
+	store.db.QueryRow(`SELECT COUNT(*) FROM fts_backlog`).Scan(&rows)
+	if rows != 0 {
+		t.Errorf("fts_backlog has %d rows after delete, want 0", rows)
+	}
+}
+
+func TestRebuildSearchIndex(t *testing.T) {
+	store, err := New(Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	today := time.Now()
+	yesterday := today.Add(-shardDay)
+	var batch storage.LogBatch
+	for i := range 30 {
+		ts := today
+		if i%3 == 0 {
+			ts = yesterday
+		}
+		batch = append(batch, storage.LogEntry{Timestamp: ts, Namespace: "a", Pod: "p", Container: "c", Message: fmt.Sprintf("checkout line %d", i)})
+	}
+	store.Write(ctx, batch)
+	if err := store.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	search := func() int {
+		t.Helper()
+		result, err := store.Query(ctx, storage.Query{Search: "checkout", Pagination: storage.Pagination{Limit: 100}})
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		return len(result.Entries)
+	}
+
+	// Lose today's index, and queue yesterday's as if shed
+	shards := []string{shardFor(yesterday.UnixNano()).name, shardFor(today.UnixNano()).name}
+	if _, err := store.db.Exec(`INSERT INTO ` + shards[1] + `_fts(` + shards[1] + `_fts) VALUES ('delete-all')`); err != nil {
+		t.Fatalf("delete-all: %v", err)
+	}
+	if _, err := store.db.Exec(`INSERT INTO fts_backlog VALUES (?, 1, 1, 1)`, shards[0]); err != nil {
+		t.Fatalf("queue backlog: %v", err)
+	}
+	if n := search(); n != 10 {
+		t.Fatalf("search = %d before rebuild, want 10", n)
+	}
+
+	var updates []storage.IndexProgress
+	n, err := store.RebuildSearchIndex(ctx, func(p storage.IndexProgress) {
+		updates = append(updates, p)
+	})
+	if err != nil {
+		t.Fatalf("RebuildSearchIndex: %v", err)
+	}
+	if n != 30 {
+		t.Errorf("indexed %d entries, want 30", n)
+	}
+	if len(updates) != 2 || updates[0] != (storage.IndexProgress{Indexed: 10, Total: 30}) || updates[1] != (storage.IndexProgress{Indexed: 30, Total: 30}) {
+		t.Errorf("progress = %+v, want 10 then 30 of 30", updates)
+	}
+	if n := search(); n != 30 {
+		t.Errorf("search = %d after rebuild, want 30", n)
+	}
+	if n := store.ftsBacklog.Load(); n != 0 {
+		t.Errorf("backlog = %d after rebuild, want 0", n)
+	}
+	for _, table := range shards {
+		if _, err := store.db.Exec(`INSERT INTO ` + table + `_fts(` + table + `_fts) VALUES ('integrity-check')`); err != nil {
+			t.Errorf("%s integrity check: %v", table, err)
+		}
+	}
+
+	// New entries are still indexed as they're written
+	store.Write(ctx, storage.LogBatch{{Timestamp: today, Namespace: "a", Pod: "p", Container: "c", Message: "checkout done"}})
+	store.Flush(ctx)
+	if n := search(); n != 31 {
+		t.Errorf("search = %d after write, want 31", n)
+	}
+}
+
+func TestHashChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.db")
+	open := func() *Store {
+		t.Helper()
+		store, err := New(Config{Path: path, HashChain: true})
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+		return store
+	}
+	ctx := context.Background()
+	today := time.Now()
+	yesterday := today.Add(-shardDay)
+	write := func(store *Store, ts time.Time, msgs ...string) {
+		t.Helper()
+		var batch storage.LogBatch
+		for _, msg := range msgs {
+			batch = append(batch, storage.LogEntry{Timestamp: ts, Namespace: "a", Pod: "p", Container: "c", Message: msg,
+				Attributes: map[string]string{"user": "alice"}})
+		}
+		store.Write(ctx, batch)
+		if err := store.Flush(ctx); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+	}
+	verify := func(store *Store) *storage.LedgerReport {
+		t.Helper()
+		report, err := store.VerifyLedger(ctx)
+		if err != nil {
+			t.Fatalf("VerifyLedger: %v", err)
+		}
+		return report
+	}
+
+	store := open()
+	write(store, yesterday, "login", "logout")
+	write(store, today, "charge", "refund", "settle")
+	write(store, today, "charge") // Duplicate only: no ledger row
+	report := verify(store)
+	if report.Flushes != 2 || report.Entries != 5 || report.Failed != 0 || report.HeadSeq != 2 {
+		t.Fatalf("report = %+v, want 2 flushes of 5 entries verified", report)
+	}
+	store.Close()
+
+	// The chain continues after a restart
+	store = open()
+	defer store.Close()
+	write(store, today, "export")
+	report = verify(store)
+	if report.Flushes != 3 || report.Entries != 6 || report.Failed != 0 {
+		t.Fatalf("report after restart = %+v, want 3 flushes of 6 entries verified", report)
+	}
+	head := report.HeadHash
+
+	// A changed entry fails its flush, a changed ledger row the chain
+	todayShard := shardFor(today.UnixNano()).name
+	if _, err := store.db.Exec(`UPDATE ` + todayShard + ` SET message = 'charge!' WHERE message = 'refund'`); err != nil {
+		t.Fatalf("tamper entry: %v", err)
+	}
+	if _, err := store.db.Exec(`UPDATE log_ledger SET created_at = created_at + 1 WHERE seq = 3`); err != nil {
+		t.Fatalf("tamper ledger: %v", err)
+	}
+	report = verify(store)
+	want := []storage.LedgerFailure{
+		{Seq: 2, FirstID: 3, LastID: 5, Reason: storage.LedgerEntriesModified},
+		{Seq: 3, FirstID: 6, LastID: 6, Reason: storage.LedgerChainMismatch},
+	}
+	if report.Failed != 2 || !slices.Equal(report.Failures, want) {
+		t.Errorf("failures = %+v, want %+v", report.Failures, want)
+	}
+	if report.HeadHash != head {
+		t.Errorf("head hash changed to %s, want %s", report.HeadHash, head)
+	}
+	if _, err := store.db.Exec(`UPDATE log_ledger SET created_at = created_at - 1 WHERE seq = 3`); err != nil {
+		t.Fatalf("restore ledger: %v", err)
+	}
+
+	// Deletes are recorded, and the rest of a partly deleted flush is
+	// still checked
+	if _, err := store.DeleteByQuery(ctx, storage.Query{Search: "settle"}); err != nil {
+		t.Fatalf("DeleteByQuery: %v", err)
+	}
+	var detail string
+	if err := store.db.QueryRow(`SELECT detail FROM log_ledger WHERE kind = 'query'`).Scan(&detail); err != nil || detail != `{"search":"settle"}` {
+		t.Errorf("query delete detail = %q (%v), want the filter", detail, err)
+	}
+
+	// Entries removed behind the store's back fail their flush, even
+	// once retention deletes the rest of it
+	yesterdayShard := shardFor(yesterday.UnixNano()).name
+	if _, err := store.db.Exec(`DELETE FROM ` + yesterdayShard + ` WHERE message = 'logout'`); err != nil {
+		t.Fatalf("remove entry: %v", err)
+	}
+	if _, err := store.Delete(ctx, today.Add(-time.Hour)); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	report = verify(store)
+	want = []storage.LedgerFailure{
+		{Seq: 1, FirstID: 1, LastID: 2, Reason: storage.LedgerEntriesMissing},
+		{Seq: 2, FirstID: 3, LastID: 5, Reason: storage.LedgerEntriesModified},
+	}
+	if report.Failed != 2 || !slices.Equal(report.Failures, want) {
+		t.Errorf("failures after deletes = %+v, want %+v", report.Failures, want)
+	}
+	// charge and export verified; settle and login deleted
+	if report.Flushes != 3 || report.Deletes != 2 || report.Entries != 2 || report.Deleted != 2 || report.HeadSeq != 5 {
+		t.Errorf("report after deletes = %+v, want 3 flushes, 2 deletes of 2 entries and 2 entries verified", report)
+	}
+}
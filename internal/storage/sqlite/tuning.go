@@ -0,0 +1,112 @@
+package sqlite
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kubelogs/kubelogs/internal/storage"
+)
+
+// preset is a set of memory settings sized for a deployment.
+type preset struct {
+	CacheSize int64  // Bytes of the page cache
+	MmapSize  int64  // Bytes of the database file mapped for reads; 0 disables
+	TempStore string // Where temporary tables and indexes live: "memory" or "file"
+}
+
+// presets are the memory settings by deployment size. "small" suits a
+// collector storing logs on its node, and is the default; "medium" and
+// "large" suit a storage server with 2-4 GiB and 8 GiB or more of memory.
+// Mapped pages count towards the page cache of the host, not the heap,
+// and are given back under memory pressure. SQLite caps the map at
+// SQLITE_MAX_MMAP_SIZE, just under 2 GiB unless built with a larger one.
+var presets = map[string]preset{
+	"small":  {CacheSize: 64 << 20, MmapSize: 0, TempStore: "memory"},
+	"medium": {CacheSize: 256 << 20, MmapSize: 512 << 20, TempStore: "memory"},
+	"large":  {CacheSize: 1 << 30, MmapSize: 2 << 30, TempStore: "memory"},
+}
+
+const defaultPreset = "small"
+
+// tuning returns the memory settings of cfg: its preset's, overridden by
+// those set.
+func (cfg Config) tuning() (preset, error) {
+	name := cfg.Preset
+	if name == "" {
+		name = defaultPreset
+	}
+	p, ok := presets[name]
+	if !ok {
+		return preset{}, fmt.Errorf("unknown preset %q: must be small, medium or large", name)
+	}
+	if cfg.CacheSize > 0 {
+		p.CacheSize = cfg.CacheSize
+	}
+	if cfg.MmapSize != 0 {
+		p.MmapSize = max(cfg.MmapSize, 0)
+	}
+	if cfg.TempStore != "" {
+		p.TempStore = strings.ToLower(cfg.TempStore)
+	}
+	if p.TempStore != "memory" && p.TempStore != "file" {
+		return preset{}, fmt.Errorf("invalid temp_store %q: must be memory or file", p.TempStore)
+	}
+	return p, nil
+}
+
+// pragmas returns the PRAGMA statements applying p.
+func (p preset) pragmas() string {
+	// A negative cache_size is in KiB rather than pages
+	return fmt.Sprintf("PRAGMA cache_size = -%d;\nPRAGMA mmap_size = %d;\nPRAGMA temp_store = %s;\n",
+		p.CacheSize>>10, p.MmapSize, strings.ToUpper(p.TempStore))
+}
+
+// ConfigFromOptions builds a Config from storage.Open options: "path"
+// (default "kubelogs.db"), "write_buffer", "preset", "cache_size",
+// "mmap_size" (bytes) and "temp_store" overriding the preset,
+// "indexed_attributes", separated by spaces, "fts_shed_backlog" and
+// "hash_chain". Other options are ignored.
+func ConfigFromOptions(opts storage.Options) (Config, error) {
+	cfg := Config{
+		Path:              opts["path"],
+		Preset:            opts["preset"],
+		TempStore:         opts["temp_store"],
+		IndexedAttributes: strings.Fields(opts["indexed_attributes"]),
+	}
+	if cfg.Path == "" {
+		cfg.Path = "kubelogs.db"
+	}
+	for key, dst := range map[string]*int64{
+		"cache_size": &cfg.CacheSize,
+		"mmap_size":  &cfg.MmapSize,
+	} {
+		if v := opts[key]; v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return cfg, fmt.Errorf("sqlite: invalid %s %q", key, v)
+			}
+			*dst = n
+		}
+	}
+	for key, dst := range map[string]*int{
+		"write_buffer":     &cfg.WriteBufferSize,
+		"fts_shed_backlog": &cfg.FTSShedBacklog,
+	} {
+		if v := opts[key]; v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return cfg, fmt.Errorf("sqlite: invalid %s %q", key, v)
+			}
+			*dst = n
+		}
+	}
+	if v := opts["hash_chain"]; v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("sqlite: invalid hash_chain %q", v)
+		}
+		cfg.HashChain = b
+	}
+	return cfg, nil
+}
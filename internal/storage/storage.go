@@ -11,6 +11,12 @@ import (
 var (
 	ErrNotFound      = errors.New("storage: entry not found")
 	ErrStorageClosed = errors.New("storage: storage is closed")
+	ErrNoFilter      = errors.New("storage: query has no filter")
+
+	// ErrConfirmationRequired is returned by Delete of stores that only
+	// delete once a preview of the delete is confirmed, such as a remote
+	// store; they offer their own preview and confirm methods.
+	ErrConfirmationRequired = errors.New("storage: delete requires confirmation")
 )
 
 // Store defines the interface for log storage backends.
@@ -27,8 +33,14 @@ type Store interface {
 	// Returns ErrNotFound if the entry doesn't exist.
 	GetByID(ctx context.Context, id int64) (*LogEntry, error)
 
+	// GetByIDs retrieves multiple entries in one call.
+	// Entries are returned in the order of ids; missing IDs and
+	// duplicates are skipped.
+	GetByIDs(ctx context.Context, ids []int64) ([]LogEntry, error)
+
 	// Delete removes entries older than the given timestamp.
-	// Returns the number of entries deleted.
+	// Returns the number of entries deleted, or ErrConfirmationRequired
+	// if the store deletes only what a confirmed preview covers.
 	Delete(ctx context.Context, olderThan time.Time) (int64, error)
 
 	// Stats returns storage statistics.
@@ -44,6 +56,17 @@ type Stats struct {
 	DiskSizeBytes int64
 	OldestEntry   time.Time
 	NewestEntry   time.Time
+
+	// Namespaces breaks stored volume down per namespace, largest first.
+	// Empty if the backend doesn't track it.
+	Namespaces []NamespaceUsage
+}
+
+// NamespaceUsage is the stored volume of one namespace.
+type NamespaceUsage struct {
+	Namespace string
+	Entries   int64
+	Bytes     int64 // Sum of message lengths, an estimate of stored size
 }
 
 // WriteOptimizer is an optional interface for write-heavy workloads.
@@ -54,3 +77,278 @@ type WriteOptimizer interface {
 	// SetWriteBuffer configures the write buffer size.
 	SetWriteBuffer(entries int)
 }
+
+// Notifier is an optional interface for stores that announce writes, so
+// readers waiting for new entries, like live tails, don't have to poll.
+type Notifier interface {
+	// Written returns a channel that is closed by the next write that
+	// adds entries. Call it before querying, so a write in between isn't
+	// missed. Writes by other processes sharing the database aren't
+	// announced.
+	Written() <-chan struct{}
+}
+
+// WriteThrottler is an optional interface for stores whose server can
+// ask writers to slow down.
+type WriteThrottler interface {
+	// WriteDelay returns how long the server asked writers to wait after
+	// the last write, or 0 if it didn't.
+	WriteDelay() time.Duration
+}
+
+// SeverityCounter is an optional interface for stores whose server
+// reports the severities of the entries it accepted.
+type SeverityCounter interface {
+	// WrittenSeverities returns the entries per severity the server
+	// accepted in the last successful write, or nil if it didn't say.
+	WrittenSeverities() map[Severity]int64
+}
+
+// StatusReporter is an optional interface for stores that forward
+// collector health reports to the server, such as the remote client.
+type StatusReporter interface {
+	// ReportStatus sends a collector's current status.
+	ReportStatus(ctx context.Context, s CollectorStatus) error
+}
+
+// CollectorStatus is the health of one collector. Counters are totals
+// since the collector started.
+type CollectorStatus struct {
+	Node      string
+	Cluster   string
+	StartedAt time.Time
+	Interval  time.Duration // Time until the next report
+
+	ActiveStreams     int
+	CatchingUpStreams int
+	LinesRead         int64
+	StreamErrors      int64
+	EntriesWritten    int64
+	WriteErrors       int64
+	BufferedEntries   int
+	RetryQueueBatches int
+	CircuitOpen       bool
+}
+
+// ClusterDeleter is an optional interface for stores that can apply
+// retention to a single cluster.
+type ClusterDeleter interface {
+	// DeleteCluster removes entries of cluster older than olderThan and
+	// returns the number deleted. An empty cluster selects entries
+	// without a cluster name.
+	DeleteCluster(ctx context.Context, cluster string, olderThan time.Time) (int64, error)
+}
+
+// ExpiryDeleter is an optional interface for stores that keep the
+// ExpiresAt of entries.
+type ExpiryDeleter interface {
+	// DeleteExpired removes entries whose ExpiresAt is at or before now
+	// and returns the number deleted.
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// QueryDeleter is an optional interface for stores that can delete the
+// entries matching arbitrary filters, e.g. to purge a leaked secret or a
+// noisy namespace.
+type QueryDeleter interface {
+	// DeleteByQuery removes the entries matching q's filters and returns
+	// the number deleted. Pagination is ignored. A query without filters
+	// fails with ErrNoFilter rather than deleting everything.
+	DeleteByQuery(ctx context.Context, q Query) (int64, error)
+}
+
+// SearchIndexRebuilder is an optional interface for stores whose
+// full-text index can be rebuilt from the stored entries, e.g. after a
+// tokenizer change or when the index is suspected to be corrupt.
+type SearchIndexRebuilder interface {
+	// RebuildSearchIndex discards the full-text index and indexes all
+	// stored entries again in batches, calling progress, if not nil,
+	// after each batch. It returns the number of entries indexed.
+	RebuildSearchIndex(ctx context.Context, progress func(IndexProgress)) (int64, error)
+}
+
+// IndexProgress is how far a search index rebuild has got.
+type IndexProgress struct {
+	Indexed int64 // Entries indexed so far
+	Total   int64 // Entries stored when the rebuild started
+}
+
+// RollupReader is an optional interface for stores that keep per-source
+// line and byte counters in fixed time buckets, so volume statistics
+// don't need to scan log entries.
+type RollupReader interface {
+	// Rollups returns volume per source aggregated by q.By, largest first.
+	Rollups(ctx context.Context, q RollupQuery) ([]Rollup, error)
+}
+
+// RollupDimension selects how rollups are grouped.
+type RollupDimension uint8
+
+const (
+	RollupByNamespace RollupDimension = iota
+	RollupByPod                       // namespace and pod
+	RollupByContainer                 // namespace, pod and container
+)
+
+// RollupQuery selects rollups to aggregate.
+type RollupQuery struct {
+	// Time range; buckets starting in [StartTime, EndTime) are included.
+	// Zero values are unbounded.
+	StartTime time.Time
+	EndTime   time.Time
+
+	By        RollupDimension
+	Namespace string // Optional namespace filter
+
+	// OrderByLines sorts by line count instead of bytes.
+	OrderByLines bool
+
+	// Limit caps the number of results; 0 returns all.
+	Limit int
+}
+
+// Rollup is the volume of one source over a query's time range.
+// Fields finer than the query dimension are empty.
+type Rollup struct {
+	Namespace string
+	Pod       string
+	Container string
+	Lines     int64
+	Bytes     int64 // Sum of message lengths
+	// LastSeen is the timestamp of the source's latest entry, or for
+	// buckets counted before it was tracked, the start of its latest
+	// bucket.
+	LastSeen time.Time
+}
+
+// PatternReader is an optional interface for stores that record when
+// each message pattern (see package patterns) was first and last seen,
+// so failure modes that never occurred before can be found without
+// scanning entries.
+type PatternReader interface {
+	// Patterns returns the patterns matching q, most recently first seen
+	// first.
+	Patterns(ctx context.Context, q PatternQuery) ([]PatternSighting, error)
+}
+
+// PatternQuery selects patterns by when they were first seen.
+type PatternQuery struct {
+	// FirstSeenAfter keeps patterns first seen at or after it. Zero
+	// keeps all.
+	FirstSeenAfter time.Time
+
+	// MinSeverity keeps patterns with an entry at least this severe.
+	MinSeverity Severity
+
+	// Limit caps the number of results; 0 returns all.
+	Limit int
+}
+
+// PatternSighting is the record of one message pattern.
+type PatternSighting struct {
+	Pattern   string
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Count     int64
+
+	// The first entry with the pattern, by timestamp
+	FirstID   int64
+	Namespace string
+	Pod       string
+	Example   string
+
+	// MaxSeverity is the severity of the pattern's most severe entry.
+	MaxSeverity Severity
+}
+
+// Aggregator is an optional interface for stores that can count
+// matching entries without returning them, e.g. for a volume chart.
+type Aggregator interface {
+	// Histogram counts the entries matching q's filters per severity in
+	// buckets of interval, aligned to the Unix epoch. Pagination is
+	// ignored. Buckets without entries are left out; the rest are sorted
+	// by start, then severity.
+	Histogram(ctx context.Context, q Query, interval time.Duration) ([]HistogramBucket, error)
+}
+
+// HistogramBucket is the number of entries of one severity in the
+// interval starting at Start.
+type HistogramBucket struct {
+	Start    time.Time
+	Severity Severity
+	Count    int64
+}
+
+// SQLConsole is an optional interface for stores that can run ad-hoc
+// read-only SQL, for analytics the Query API can't express.
+type SQLConsole interface {
+	// QueryReadOnly runs a read-only query and returns at most maxRows
+	// rows. Statements that would modify the database fail, as do reads
+	// of credential tables.
+	QueryReadOnly(ctx context.Context, query string, maxRows int) (*SQLResult, error)
+
+	// Schema returns the definitions of tables, views, indexes and
+	// triggers.
+	Schema(ctx context.Context) ([]SchemaObject, error)
+}
+
+// SQLResult is the tabular result of a console query. Values are nil,
+// int64, float64 or string.
+type SQLResult struct {
+	Columns   []string
+	Rows      [][]any
+	Truncated bool // More rows matched than were returned
+}
+
+// SchemaObject is a table, view, index or trigger definition.
+type SchemaObject struct {
+	Type  string // "table", "view", "index" or "trigger"
+	Name  string
+	Table string // Table an index or trigger belongs to, or the object's own name
+	SQL   string // Defining statement; empty for automatic indexes
+}
+
+// LedgerVerifier is an optional interface for stores that keep a hash
+// chain over the entries they insert and delete, so that entries
+// changed or removed behind the store's back can be detected.
+type LedgerVerifier interface {
+	// VerifyLedger checks every link of the chain against the entries
+	// still stored.
+	VerifyLedger(ctx context.Context) (*LedgerReport, error)
+}
+
+// MaxLedgerFailures bounds the failures listed in a LedgerReport.
+const MaxLedgerFailures = 100
+
+// Reasons a row of the ledger failed verification.
+const (
+	LedgerRowMissing      = "row_missing"      // The ledger skips the row's sequence number
+	LedgerChainMismatch   = "chain_mismatch"   // The row doesn't hash to its recorded chain hash
+	LedgerEntriesModified = "entries_modified" // Entries of the flush don't hash to what it recorded
+	LedgerEntriesAdded    = "entries_added"    // More than one entry holds an ID of the flush
+	LedgerEntriesMissing  = "entries_missing"  // Entries of the flush are gone, but no delete recorded it
+)
+
+// LedgerReport is the result of verifying a hash chain.
+type LedgerReport struct {
+	Flushes int64 // Flushes recorded in the ledger
+	Deletes int64 // Deletes recorded in the ledger, by retention or otherwise
+	Entries int64 // Entries matching the hash their flush recorded
+	Deleted int64 // Entries of flushes gone since, by a recorded delete
+
+	// HeadSeq and HeadHash identify the last row of the ledger. Keeping
+	// them elsewhere detects a chain rewritten up to its head.
+	HeadSeq  int64
+	HeadHash string // Hex-encoded
+
+	Failed   int64           // Failures found, each a row and a reason
+	Failures []LedgerFailure // The first MaxLedgerFailures of them
+}
+
+// LedgerFailure is a row of the ledger failing verification.
+type LedgerFailure struct {
+	Seq     int64
+	FirstID int64 // Lowest and highest ID of the entries it inserted or deleted
+	LastID  int64
+	Reason  string
+}
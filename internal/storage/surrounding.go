@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// Surrounding holds an entry and the entries of the same container
+// written around it, for reading a hit in context.
+type Surrounding struct {
+	// Before holds the entries preceding Entry, oldest first.
+	Before []LogEntry
+	// Entry is the entry asked for.
+	Entry LogEntry
+	// After holds the entries following Entry, oldest first.
+	After []LogEntry
+
+	// HasMoreBefore and HasMoreAfter report whether the container has
+	// more entries beyond Before and After.
+	HasMoreBefore bool
+	HasMoreAfter  bool
+}
+
+// GetSurrounding returns the entry with the given ID and up to before
+// and after entries of the same cluster, namespace, pod and container
+// around it, by timestamp. Entries with the same timestamp are ordered
+// by ID. It works on any Store, through keyset queries.
+func GetSurrounding(ctx context.Context, s Store, id int64, before, after int) (*Surrounding, error) {
+	e, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Surrounding{Entry: *e}
+	source := Query{
+		Cluster:   e.Cluster,
+		Namespace: e.Namespace,
+		Pod:       e.Pod,
+		Container: e.Container,
+	}
+
+	if before > 0 {
+		q := source
+		q.Pagination = Pagination{
+			Limit:           before,
+			Order:           OrderDesc,
+			OrderBy:         OrderByTimestamp,
+			BeforeTimestamp: e.Timestamp,
+			BeforeID:        e.ID,
+		}
+		result, err := s.Query(ctx, q)
+		if err != nil {
+			return nil, fmt.Errorf("query before: %w", err)
+		}
+		res.Before = result.Entries
+		slices.Reverse(res.Before)
+		res.HasMoreBefore = result.HasMore
+	}
+
+	if after > 0 {
+		q := source
+		q.Pagination = Pagination{
+			Limit:          after,
+			Order:          OrderAsc,
+			OrderBy:        OrderByTimestamp,
+			AfterTimestamp: e.Timestamp,
+			AfterID:        e.ID,
+		}
+		result, err := s.Query(ctx, q)
+		if err != nil {
+			return nil, fmt.Errorf("query after: %w", err)
+		}
+		res.After = result.Entries
+		res.HasMoreAfter = result.HasMore
+	}
+
+	return res, nil
+}
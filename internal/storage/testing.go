@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 )
@@ -111,6 +112,42 @@ func StoreTestSuite(t *testing.T, newStore func() (Store, func())) {
 		}
 	})
 
+	t.Run("QueryClusterFilter", func(t *testing.T) {
+		store, cleanup := newStore()
+		defer cleanup()
+
+		// Same namespace and pod in two clusters
+		now := time.Now()
+		entries := LogBatch{
+			{Timestamp: now, Cluster: "east", Namespace: "production", Pod: "api-1", Container: "app", Severity: SeverityInfo, Message: "east log"},
+			{Timestamp: now, Cluster: "west", Namespace: "production", Pod: "api-1", Container: "app", Severity: SeverityInfo, Message: "west log"},
+		}
+
+		store.Write(context.Background(), entries)
+		if wo, ok := store.(WriteOptimizer); ok {
+			wo.Flush(context.Background())
+		}
+
+		result, err := store.Query(context.Background(), Query{Cluster: "west", Namespace: "production"})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(result.Entries) != 1 {
+			t.Fatalf("Query returned %d entries, want 1", len(result.Entries))
+		}
+		if result.Entries[0].Cluster != "west" || result.Entries[0].Message != "west log" {
+			t.Errorf("Expected the west entry, got %+v", result.Entries[0])
+		}
+
+		all, err := store.Query(context.Background(), Query{Namespace: "production"})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(all.Entries) != 2 {
+			t.Errorf("Query returned %d entries, want 2", len(all.Entries))
+		}
+	})
+
 	t.Run("QuerySeverityFilter", func(t *testing.T) {
 		store, cleanup := newStore()
 		defer cleanup()
@@ -197,6 +234,49 @@ func StoreTestSuite(t *testing.T, newStore func() (Store, func())) {
 		}
 	})
 
+	t.Run("GetByIDs", func(t *testing.T) {
+		store, cleanup := newStore()
+		defer cleanup()
+
+		now := time.Now()
+		entries := LogBatch{
+			{Timestamp: now, Namespace: "ns", Pod: "pod", Container: "c", Severity: SeverityInfo, Message: "first"},
+			{Timestamp: now.Add(time.Second), Namespace: "ns", Pod: "pod", Container: "c", Severity: SeverityInfo, Message: "second"},
+			{Timestamp: now.Add(2 * time.Second), Namespace: "ns", Pod: "pod", Container: "c", Severity: SeverityInfo, Message: "third"},
+		}
+
+		store.Write(context.Background(), entries)
+		if wo, ok := store.(WriteOptimizer); ok {
+			wo.Flush(context.Background())
+		}
+
+		result, _ := store.Query(context.Background(), Query{Pagination: Pagination{Order: OrderAsc}})
+		if len(result.Entries) != 3 {
+			t.Fatalf("Query returned %d entries, want 3", len(result.Entries))
+		}
+		first, third := result.Entries[0].ID, result.Entries[2].ID
+
+		// Request order is preserved; missing IDs and duplicates are skipped
+		got, err := store.GetByIDs(context.Background(), []int64{third, 99999, first, third})
+		if err != nil {
+			t.Fatalf("GetByIDs failed: %v", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("GetByIDs returned %d entries, want 2", len(got))
+		}
+		if got[0].Message != "third" || got[1].Message != "first" {
+			t.Errorf("GetByIDs order = [%q, %q], want [third, first]", got[0].Message, got[1].Message)
+		}
+
+		got, err = store.GetByIDs(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("GetByIDs(nil) failed: %v", err)
+		}
+		if len(got) != 0 {
+			t.Errorf("GetByIDs(nil) returned %d entries, want 0", len(got))
+		}
+	})
+
 	t.Run("Delete", func(t *testing.T) {
 		store, cleanup := newStore()
 		defer cleanup()
@@ -226,6 +306,183 @@ func StoreTestSuite(t *testing.T, newStore func() (Store, func())) {
 		}
 	})
 
+	t.Run("DeleteCluster", func(t *testing.T) {
+		store, cleanup := newStore()
+		defer cleanup()
+
+		cd, ok := store.(ClusterDeleter)
+		if !ok {
+			t.Skip("store does not implement ClusterDeleter")
+		}
+
+		base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		entries := LogBatch{
+			{Timestamp: base, Cluster: "east", Namespace: "ns", Pod: "pod", Container: "c", Severity: SeverityInfo, Message: "east old"},
+			{Timestamp: base, Cluster: "west", Namespace: "ns", Pod: "pod", Container: "c", Severity: SeverityInfo, Message: "west old"},
+			{Timestamp: base, Namespace: "ns", Pod: "pod", Container: "c", Severity: SeverityInfo, Message: "unnamed old"},
+			{Timestamp: base.Add(24 * time.Hour), Cluster: "east", Namespace: "ns", Pod: "pod", Container: "c", Severity: SeverityInfo, Message: "east new"},
+		}
+
+		store.Write(context.Background(), entries)
+		if wo, ok := store.(WriteOptimizer); ok {
+			wo.Flush(context.Background())
+		}
+
+		deleted, err := cd.DeleteCluster(context.Background(), "east", base.Add(12*time.Hour))
+		if err != nil {
+			t.Fatalf("DeleteCluster failed: %v", err)
+		}
+		if deleted != 1 {
+			t.Errorf("DeleteCluster returned %d, want 1", deleted)
+		}
+
+		result, err := store.Query(context.Background(), Query{Pagination: Pagination{Order: OrderAsc}})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		var got []string
+		for _, e := range result.Entries {
+			got = append(got, e.Message)
+		}
+		if len(got) != 3 || got[0] != "west old" || got[1] != "unnamed old" || got[2] != "east new" {
+			t.Errorf("remaining entries = %v", got)
+		}
+	})
+
+	t.Run("DeleteExpired", func(t *testing.T) {
+		store, cleanup := newStore()
+		defer cleanup()
+
+		ed, ok := store.(ExpiryDeleter)
+		if !ok {
+			t.Skip("store does not implement ExpiryDeleter")
+		}
+
+		base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		entries := LogBatch{
+			{Timestamp: base, Namespace: "ns", Pod: "pod", Container: "c", Message: "kept"},
+			{Timestamp: base, Namespace: "ns", Pod: "pod", Container: "c", Message: "expired", ExpiresAt: base.Add(time.Hour)},
+			{Timestamp: base, Namespace: "ns", Pod: "pod", Container: "c", Message: "expiring", ExpiresAt: base.Add(3 * time.Hour)},
+		}
+
+		store.Write(context.Background(), entries)
+		if wo, ok := store.(WriteOptimizer); ok {
+			wo.Flush(context.Background())
+		}
+
+		deleted, err := ed.DeleteExpired(context.Background(), base.Add(2*time.Hour))
+		if err != nil {
+			t.Fatalf("DeleteExpired failed: %v", err)
+		}
+		if deleted != 1 {
+			t.Errorf("DeleteExpired returned %d, want 1", deleted)
+		}
+
+		result, err := store.Query(context.Background(), Query{Pagination: Pagination{Order: OrderAsc}})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		var got []string
+		for _, e := range result.Entries {
+			got = append(got, e.Message)
+		}
+		if len(got) != 2 || got[0] != "kept" || got[1] != "expiring" {
+			t.Errorf("remaining entries = %v", got)
+		}
+	})
+
+	t.Run("Sample", func(t *testing.T) {
+		store, cleanup := newStore()
+		defer cleanup()
+
+		base := time.Now().Add(-time.Hour)
+		entries := make(LogBatch, 1000)
+		for i := range entries {
+			entries[i] = LogEntry{Timestamp: base.Add(time.Duration(i) * time.Millisecond), Namespace: "ns", Pod: "pod", Container: "c", Severity: SeverityInfo, Message: "msg"}
+		}
+		store.Write(context.Background(), entries)
+		if wo, ok := store.(WriteOptimizer); ok {
+			wo.Flush(context.Background())
+		}
+
+		result, err := store.Query(context.Background(), Query{Namespace: "ns", Sample: 0.1, Pagination: Pagination{Limit: 1000}})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if result.SampleRate == 0 {
+			t.Skip("store does not sample")
+		}
+		// 100 expected; the bounds are over 6 standard deviations away
+		if n := len(result.Entries); n < 40 || n > 180 || result.HasMore {
+			t.Errorf("sampled %d entries (hasMore %v), want about 100", n, result.HasMore)
+		}
+		if result.SampleRate != 0.1 {
+			t.Errorf("SampleRate = %v, want 0.1", result.SampleRate)
+		}
+
+		result, err = store.Query(context.Background(), Query{Namespace: "ns", Sample: 1, Pagination: Pagination{Limit: 1000}})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(result.Entries) != 1000 || result.SampleRate != 0 {
+			t.Errorf("Sample 1 returned %d entries at rate %v, want all 1000 unsampled", len(result.Entries), result.SampleRate)
+		}
+	})
+
+	t.Run("DeleteByQuery", func(t *testing.T) {
+		store, cleanup := newStore()
+		defer cleanup()
+
+		qd, ok := store.(QueryDeleter)
+		if !ok {
+			t.Skip("store does not implement QueryDeleter")
+		}
+
+		base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		entries := LogBatch{
+			{Timestamp: base, Namespace: "shop", Pod: "pod", Container: "c", Severity: SeverityInfo, Message: "token=abc123 issued"},
+			{Timestamp: base.Add(time.Second), Namespace: "shop", Pod: "pod", Container: "c", Severity: SeverityInfo, Message: "order placed"},
+			{Timestamp: base.Add(2 * time.Second), Namespace: "noisy", Pod: "pod", Container: "c", Severity: SeverityDebug, Message: "tick", Attributes: map[string]string{"loop": "1"}},
+			{Timestamp: base.Add(3 * time.Second), Namespace: "noisy", Pod: "pod", Container: "c", Severity: SeverityError, Message: "tick failed", Attributes: map[string]string{"loop": "2"}},
+		}
+
+		store.Write(context.Background(), entries)
+		if wo, ok := store.(WriteOptimizer); ok {
+			wo.Flush(context.Background())
+		}
+
+		if _, err := qd.DeleteByQuery(context.Background(), Query{Pagination: Pagination{Limit: 1}}); !errors.Is(err, ErrNoFilter) {
+			t.Errorf("DeleteByQuery without filters: err = %v, want ErrNoFilter", err)
+		}
+
+		deleted, err := qd.DeleteByQuery(context.Background(), Query{Namespace: "noisy", Attributes: map[string]string{"loop": "1"}})
+		if err != nil {
+			t.Fatalf("DeleteByQuery failed: %v", err)
+		}
+		if deleted != 1 {
+			t.Errorf("DeleteByQuery returned %d, want 1", deleted)
+		}
+		deleted, err = qd.DeleteByQuery(context.Background(), Query{Namespace: "shop", EndTime: base.Add(time.Second)})
+		if err != nil {
+			t.Fatalf("DeleteByQuery failed: %v", err)
+		}
+		if deleted != 1 {
+			t.Errorf("DeleteByQuery returned %d, want 1", deleted)
+		}
+
+		result, err := store.Query(context.Background(), Query{Pagination: Pagination{Order: OrderAsc}})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		var got []string
+		for _, e := range result.Entries {
+			got = append(got, e.Message)
+		}
+		if len(got) != 2 || got[0] != "order placed" || got[1] != "tick failed" {
+			t.Errorf("remaining entries = %v", got)
+		}
+	})
+
 	t.Run("Stats", func(t *testing.T) {
 		store, cleanup := newStore()
 		defer cleanup()
@@ -295,4 +552,124 @@ func StoreTestSuite(t *testing.T, newStore func() (Store, func())) {
 			t.Errorf("Second page has %d entries, want 3", len(result2.Entries))
 		}
 	})
+
+	t.Run("Count", func(t *testing.T) {
+		store, cleanup := newStore()
+		defer cleanup()
+
+		now := time.Now()
+		entries := make(LogBatch, 10)
+		for i := range entries {
+			entries[i] = LogEntry{Timestamp: now.Add(time.Duration(i) * time.Second), Namespace: "ns", Pod: "pod", Container: "c", Severity: SeverityInfo, Message: "msg"}
+		}
+		entries[0].Namespace = "other"
+		store.Write(context.Background(), entries)
+		if wo, ok := store.(WriteOptimizer); ok {
+			wo.Flush(context.Background())
+		}
+
+		result, err := store.Query(context.Background(), Query{Namespace: "ns", Pagination: Pagination{Limit: 3}})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if result.TotalEstimate != -1 {
+			t.Errorf("TotalEstimate without Count = %d, want -1", result.TotalEstimate)
+		}
+
+		result, err = store.Query(context.Background(), Query{Namespace: "ns", Count: true, Pagination: Pagination{Limit: 3}})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if result.TotalEstimate == -1 {
+			t.Skip("store does not count")
+		}
+		if result.TotalEstimate != 9 || result.TotalCapped {
+			t.Errorf("TotalEstimate = %d (capped %v), want 9", result.TotalEstimate, result.TotalCapped)
+		}
+
+		// The count covers every page, not what's after the cursor
+		result, err = store.Query(context.Background(), Query{Namespace: "ns", Count: true, Pagination: Pagination{Limit: 3, AfterID: result.NextCursor}})
+		if err != nil {
+			t.Fatalf("Query page 2 failed: %v", err)
+		}
+		if result.TotalEstimate != 9 {
+			t.Errorf("TotalEstimate of page 2 = %d, want 9", result.TotalEstimate)
+		}
+	})
+
+	t.Run("MaxID", func(t *testing.T) {
+		store, cleanup := newStore()
+		defer cleanup()
+		ctx := context.Background()
+
+		now := time.Now()
+		write := func(msg string, ts time.Time) {
+			t.Helper()
+			if _, err := store.Write(ctx, LogBatch{{Timestamp: ts, Namespace: "ns", Pod: "pod", Container: "c", Message: msg}}); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			if wo, ok := store.(WriteOptimizer); ok {
+				wo.Flush(ctx)
+			}
+		}
+		write("before 1", now)
+		write("before 2", now.Add(time.Second))
+
+		latest, err := store.Query(ctx, Query{Pagination: Pagination{Limit: 1}})
+		if err != nil || len(latest.Entries) != 1 {
+			t.Fatalf("Query latest = %v, %v", latest, err)
+		}
+		snapshot := latest.Entries[0].ID
+
+		// A late entry sorts first by timestamp but was stored after the snapshot
+		write("after", now.Add(-time.Hour))
+
+		for _, orderBy := range []OrderBy{OrderByID, OrderByTimestamp} {
+			result, err := store.Query(ctx, Query{
+				Pagination: Pagination{Limit: 10, Order: OrderAsc, OrderBy: orderBy, MaxID: snapshot},
+			})
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			var got []string
+			for _, e := range result.Entries {
+				got = append(got, e.Message)
+			}
+			if len(got) != 2 || got[0] != "before 1" || got[1] != "before 2" {
+				t.Errorf("OrderBy %d: got %v, want the entries before the snapshot", orderBy, got)
+			}
+		}
+	})
+
+	t.Run("Notifier", func(t *testing.T) {
+		store, cleanup := newStore()
+		defer cleanup()
+
+		n, ok := store.(Notifier)
+		if !ok {
+			t.Skip("store does not implement Notifier")
+		}
+		ctx := context.Background()
+
+		written := n.Written()
+		select {
+		case <-written:
+			t.Fatal("Written closed before any write")
+		default:
+		}
+		if _, err := store.Write(ctx, LogBatch{{Timestamp: time.Now(), Namespace: "ns", Message: "hello"}}); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		select {
+		case <-written:
+		case <-time.After(time.Second):
+			t.Fatal("Written not closed by a write")
+		}
+
+		// Announced entries are visible to queries
+		result, err := store.Query(ctx, Query{})
+		if err != nil || len(result.Entries) != 1 {
+			t.Errorf("Query after notification = %v, %v, want the entry", result, err)
+		}
+	})
 }
@@ -0,0 +1,81 @@
+package storage
+
+import "context"
+
+// TraceAttribute is the attribute holding the ID of the distributed
+// trace an entry was logged in, set by collectors from trace_id fields
+// and by OTLP ingest.
+const TraceAttribute = "trace_id"
+
+// Trace holds the entries logged in one distributed trace, across
+// namespaces and pods, grouped by the container that logged them.
+type Trace struct {
+	TraceID string
+
+	// Sources holds the entries by container, ordered by their first
+	// entry, so the services a request went through appear in the
+	// order it reached them.
+	Sources []TraceSource
+
+	// HasMore reports whether the trace has entries beyond the limit,
+	// which are later than all those returned.
+	HasMore bool
+}
+
+// TraceSource is the part of a trace logged by one container.
+type TraceSource struct {
+	Cluster   string
+	Namespace string
+	Pod       string
+	Container string
+
+	// Entries holds the container's entries of the trace, oldest first.
+	Entries []LogEntry
+}
+
+// GetTrace returns the first limit entries, by timestamp, whose
+// TraceAttribute is traceID and which match q's other filters, such as
+// a time range, grouped by container. q's pagination is ignored. It
+// works on any Store, through one ascending query; stores indexing
+// trace_id answer it without scanning.
+func GetTrace(ctx context.Context, s Store, traceID string, q Query, limit int) (*Trace, error) {
+	attrs := make(map[string]string, len(q.Attributes)+1)
+	for k, v := range q.Attributes {
+		attrs[k] = v
+	}
+	attrs[TraceAttribute] = traceID
+	q.Attributes = attrs
+	q.Sample = 0
+	q.Pagination = Pagination{
+		Limit:   limit,
+		Order:   OrderAsc,
+		OrderBy: OrderByTimestamp,
+	}
+
+	result, err := s.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	trace := &Trace{TraceID: traceID, HasMore: result.HasMore}
+	type sourceKey struct{ cluster, namespace, pod, container string }
+	index := make(map[sourceKey]int)
+	// Entries come oldest first, so sources are appended in order of
+	// their first entry
+	for _, e := range result.Entries {
+		key := sourceKey{e.Cluster, e.Namespace, e.Pod, e.Container}
+		i, ok := index[key]
+		if !ok {
+			i = len(trace.Sources)
+			index[key] = i
+			trace.Sources = append(trace.Sources, TraceSource{
+				Cluster:   e.Cluster,
+				Namespace: e.Namespace,
+				Pod:       e.Pod,
+				Container: e.Container,
+			})
+		}
+		trace.Sources[i].Entries = append(trace.Sources[i].Entries, e)
+	}
+	return trace, nil
+}
@@ -0,0 +1,195 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testPKI writes a CA plus server and client certificates signed by it.
+type testPKI struct {
+	dir                            string
+	ca, serverCert, serverKey      string
+	clientCert, clientKey, otherCA string
+}
+
+func newTestPKI(t *testing.T) testPKI {
+	t.Helper()
+	dir := t.TempDir()
+	p := testPKI{dir: dir}
+
+	caKey, caCert, caDER := newCA(t, "test CA")
+	p.ca = writePEM(t, dir, "ca.crt", "CERTIFICATE", caDER)
+	_, _, otherDER := newCA(t, "other CA")
+	p.otherCA = writePEM(t, dir, "other.crt", "CERTIFICATE", otherDER)
+
+	issue := func(name string, usage x509.ExtKeyUsage) (string, string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(time.Now().UnixNano()),
+			Subject:      pkix.Name{CommonName: name},
+			DNSNames:     []string{name},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return writePEM(t, dir, name+".crt", "CERTIFICATE", der), writePEM(t, dir, name+".key", "EC PRIVATE KEY", keyDER)
+	}
+	p.serverCert, p.serverKey = issue("kubelogs-server", x509.ExtKeyUsageServerAuth)
+	p.clientCert, p.clientKey = issue("collector", x509.ExtKeyUsageClientAuth)
+	return p
+}
+
+func newCA(t *testing.T, name string) (*ecdsa.PrivateKey, *x509.Certificate, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, cert, der
+}
+
+func writePEM(t *testing.T, dir, name, typ string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// handshake connects to a TLS listener using serverCfg with clientCfg
+// and returns the first error either side saw.
+func handshake(t *testing.T, serverCfg, clientCfg *tls.Config) error {
+	t.Helper()
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", serverCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if conn.(*tls.Conn).Handshake() == nil {
+			conn.Write([]byte("x"))
+		}
+	}()
+
+	conn, err := tls.Dial("tcp", lis.Addr().String(), clientCfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// With TLS 1.3 the server checks the client certificate after the
+	// client's side of the handshake completes; the read sees its verdict.
+	_, err = conn.Read(make([]byte, 1))
+	return err
+}
+
+func TestTLS(t *testing.T) {
+	p := newTestPKI(t)
+
+	plain, err := Server(p.serverCert, p.serverKey, "")
+	if err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+	mutual, err := Server(p.serverCert, p.serverKey, p.ca)
+	if err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+
+	client := func(ca, cert, key, serverName string) *tls.Config {
+		t.Helper()
+		cfg, err := Client(ca, cert, key, serverName)
+		if err != nil {
+			t.Fatalf("Client failed: %v", err)
+		}
+		return cfg
+	}
+
+	tests := []struct {
+		name   string
+		server *tls.Config
+		client *tls.Config
+		ok     bool
+	}{
+		{"server TLS", plain, client(p.ca, "", "", "kubelogs-server"), true},
+		{"by IP", plain, client(p.ca, "", "", ""), true},
+		{"wrong server name", plain, client(p.ca, "", "", "elsewhere"), false},
+		{"untrusted server", plain, client(p.otherCA, "", "", "kubelogs-server"), false},
+		{"mutual TLS", mutual, client(p.ca, p.clientCert, p.clientKey, "kubelogs-server"), true},
+		{"mutual TLS without client certificate", mutual, client(p.ca, "", "", "kubelogs-server"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := handshake(t, tt.server, tt.client)
+			if tt.ok && err != nil {
+				t.Errorf("handshake failed: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Error("handshake succeeded, want failure")
+			}
+		})
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	p := newTestPKI(t)
+
+	if _, err := Server("", p.serverKey, ""); err == nil {
+		t.Error("Server without certificate succeeded")
+	}
+	if _, err := Server(p.serverCert, p.serverKey, p.serverKey); err == nil {
+		t.Error("Server with a CA file holding no certificates succeeded")
+	}
+	if _, err := Client(p.ca, p.clientCert, "", ""); err == nil {
+		t.Error("Client with certificate but no key succeeded")
+	}
+	if _, err := Client(filepath.Join(p.dir, "missing.crt"), "", "", ""); err == nil {
+		t.Error("Client with missing CA file succeeded")
+	}
+}
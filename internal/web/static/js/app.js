@@ -1,5 +1,6 @@
-// ANSI escape sequence parser - converts ANSI color codes to HTML spans with Tailwind classes
-function parseAnsi(text) {
+// ANSI escape sequence parser - converts ANSI color codes to HTML spans with Tailwind classes.
+// Links are server-provided spans ({start, end, kind, href, traceId}) rendered as anchors.
+function parseAnsi(text, links = []) {
     if (!text) return '';
 
     // HTML escape function to prevent XSS
@@ -73,8 +74,7 @@ function parseAnsi(text) {
     while ((match = ansiRegex.exec(text)) !== null) {
         // Add text before this escape sequence (escaped for safety)
         if (match.index > lastIndex) {
-            const textSegment = text.substring(lastIndex, match.index);
-            result += wrapWithCurrentStyle(escapeHtml(textSegment));
+            result += wrapWithCurrentStyle(renderSegment(lastIndex, match.index));
         }
 
         // Parse the escape sequence parameters
@@ -138,12 +138,36 @@ function parseAnsi(text) {
 
     // Add remaining text after the last escape sequence
     if (lastIndex < text.length) {
-        const textSegment = text.substring(lastIndex);
-        result += wrapWithCurrentStyle(escapeHtml(textSegment));
+        result += wrapWithCurrentStyle(renderSegment(lastIndex, text.length));
     }
 
     return result;
 
+    // Helper function to escape text[start:end], wrapping the parts covered by links
+    function renderSegment(start, end) {
+        let out = '';
+        let pos = start;
+        for (const link of links) {
+            const from = Math.max(link.start, pos);
+            const to = Math.min(link.end, end);
+            if (from >= to) continue;
+            out += escapeHtml(text.substring(pos, from));
+            out += renderLink(link, escapeHtml(text.substring(from, to)));
+            pos = to;
+        }
+        return out + escapeHtml(text.substring(pos, end));
+    }
+
+    // Helper function to build an anchor; hrefs are http(s) URLs chosen by the server
+    function renderLink(link, html) {
+        const cls = 'underline decoration-dotted hover:text-blue-300';
+        if (link.kind === 'trace' && !link.href) {
+            return `<a href="#" class="${cls}" data-trace-id="${escapeHtml(link.traceId)}" title="Filter by trace">${html}</a>`;
+        }
+        if (!/^https?:\/\//i.test(link.href || '')) return html;
+        return `<a href="${escapeHtml(link.href)}" class="${cls}" target="_blank" rel="noopener noreferrer">${html}</a>`;
+    }
+
     // Helper function to wrap text with current style
     function wrapWithCurrentStyle(text) {
         if (!text) return '';
@@ -215,12 +239,35 @@ function parseAnsi(text) {
     }
 }
 
+// UI preferences, kept in localStorage and, with auth, on the server per user.
+const SETTINGS_KEY = 'kubelogs.settings';
+
+function defaultSettings() {
+    return {
+        theme: 'dark',              // 'dark' or 'light'
+        columns: { namespace: false, pod: false, container: true, attrs: true },
+        timestampFormat: 'local',   // 'local', 'time' (time of day only) or 'utc' (ISO 8601)
+        wrap: true                  // Wrap long messages instead of cutting them off
+    };
+}
+
+// mergeSettings fills in defaults for settings missing from saved ones,
+// such as columns added since they were saved.
+function mergeSettings(saved) {
+    const defaults = defaultSettings();
+    return { ...defaults, ...saved, columns: { ...defaults.columns, ...(saved.columns || {}) } };
+}
+
 function app() {
     return {
         entries: [],
-        namespaces: [],
-        containers: [],
+        clusters: [],
+        namespaces: [],          // { value, entries, lastSeen }
+        containers: [],          // { value, entries, lastSeen }
+        pods: [],                // Pod names in the selected namespace, for autocomplete
+        workloads: [],           // Workload names with entries in the selected namespace
         filters: {
+            cluster: '',
             namespace: '',
             pod: '',
             container: '',
@@ -234,24 +281,41 @@ function app() {
         tailing: true,
         connected: false,
         showShortcuts: false,
+        showStorage: false,      // Whether the per-namespace storage modal is visible
+        showCollectors: false,   // Whether the collector fleet modal is visible
+        showSettings: false,     // Whether the settings panel is visible
+        showContext: false,      // Whether the surrounding lines modal is visible
+        context: null,           // { before, entry, after, hasMoreBefore, hasMoreAfter }
+        contextLines: 50,        // Entries loaded on each side of the context entry
+        settings: defaultSettings(),
+        syncPreferences: false,  // Whether settings are also saved on the server (auth only)
         eventSource: null,
         stats: {
             totalEntries: 0,
-            diskSizeBytes: 0
+            diskSizeBytes: 0,
+            namespaces: []
         },
+        collectors: [],          // Latest status report of every collector
+        collectorSummary: { healthy: 0, degraded: 0, stale: 0 },
         maxEntries: 1000,
-        oldestLoadedId: null,    // Cursor for backward pagination
+        olderCursor: null,       // Opaque cursor token for backward pagination
         hasMoreOlder: true,      // Whether more historical entries exist
+        resultTotal: -1,         // Matches of the historical query, up to the server's cap, or -1
+        resultCapped: false,     // Whether there are more matches than resultTotal
         loadingOlder: false,     // Prevent concurrent requests
         selectedEntry: null,     // Currently selected log entry for detail panel
         detailPanelOpen: false,  // Whether detail panel is visible
         showCopyToast: false,    // Whether to show "Copied" toast
         lastSeenId: null,        // Track highest seen ID to prevent duplicates on SSE reconnection
         seenIds: new Set(),      // Set of entry IDs currently in the entries array for fast dedup
+        savedQueries: [],        // The user's saved queries (auth only)
+        selectedQueryId: '',     // Saved query last recalled, as a string for the select
 
         init() {
+            this.loadSettings();
             this.loadFilters();
             this.loadStats();
+            this.loadCollectors();
 
             if (this.isLiveMode()) {
                 this.startTailing();
@@ -263,6 +327,7 @@ function app() {
             setInterval(() => this.loadFilters(), 30000);
             // Refresh stats periodically
             setInterval(() => this.loadStats(), 10000);
+            setInterval(() => this.loadCollectors(), 30000);
         },
 
         isLiveMode() {
@@ -288,15 +353,221 @@ function app() {
 
         async loadFilters() {
             try {
-                const [nsResp, cResp] = await Promise.all([
-                    fetch('/api/filters/namespaces'),
-                    fetch('/api/filters/containers')
+                const [clResp, nsResp, cResp] = await Promise.all([
+                    fetch('/api/filters/clusters'),
+                    fetch('/api/filters/namespaces?stats=true'),
+                    fetch('/api/filters/containers?stats=true')
                 ]);
+                // Stores without clusters answer 501; the selector stays hidden
+                this.clusters = clResp.ok ? await clResp.json() : [];
                 this.namespaces = await nsResp.json();
                 this.containers = await cResp.json();
             } catch (err) {
                 console.error('Failed to load filters:', err);
             }
+            this.loadWorkloads();
+            this.loadPods();
+        },
+
+        // loadPods lists the pods of the selected namespace, or of all
+        // namespaces, as suggestions for the pod filter.
+        async loadPods() {
+            const params = new URLSearchParams();
+            if (this.filters.namespace) params.set('namespace', this.filters.namespace);
+            try {
+                const resp = await fetch(`/api/filters/pods?${params}`);
+                this.pods = resp.ok ? await resp.json() : [];
+            } catch (err) {
+                console.error('Failed to load pods:', err);
+            }
+        },
+
+        // filterOptionLabel describes a filter value with its volume, e.g.
+        // "production (1.2M entries, last log 3s ago)".
+        filterOptionLabel(v) {
+            if (!v.entries) return v.value;
+            let label = `${v.value} (${this.formatCount(v.entries)} entries`;
+            if (v.lastSeen) label += `, last log ${this.formatAgo(v.lastSeen)}`;
+            return label + ')';
+        },
+
+        // formatCount abbreviates a count: 950, 12.3K, 1.2M.
+        formatCount(n) {
+            const units = ['', 'K', 'M', 'B'];
+            let i = 0;
+            while (n >= 1000 && i < units.length - 1) {
+                n /= 1000;
+                i++;
+            }
+            return i === 0 ? String(n) : `${n.toFixed(1)}${units[i]}`;
+        },
+
+        // formatAgo describes how long ago a Unix nanosecond time was.
+        formatAgo(nanos) {
+            const secs = Math.max(0, Math.round((Date.now() - nanos / 1e6) / 1000));
+            if (secs < 60) return `${secs}s ago`;
+            if (secs < 3600) return `${Math.floor(secs / 60)}m ago`;
+            if (secs < 86400) return `${Math.floor(secs / 3600)}h ago`;
+            return `${Math.floor(secs / 86400)}d ago`;
+        },
+
+        // loadWorkloads lists the workloads of the selected namespace, or of
+        // all namespaces, for the workload selector.
+        async loadWorkloads() {
+            const params = new URLSearchParams();
+            if (this.filters.cluster) params.set('cluster', this.filters.cluster);
+            if (this.filters.namespace) params.set('namespace', this.filters.namespace);
+            try {
+                const resp = await fetch(`/api/filters/workloads?${params}`);
+                // Stores that can't aggregate answer 501; the selector stays hidden
+                const workloads = resp.ok ? await resp.json() : [];
+                this.workloads = [...new Set(workloads.map(w => w.workload))].sort();
+            } catch (err) {
+                console.error('Failed to load workloads:', err);
+            }
+        },
+
+        // setWorkload shows the logs of every pod a workload ran, across
+        // restarts and rollouts.
+        setWorkload(workload) {
+            if (workload) {
+                this.filters.attributes.workload = workload;
+            } else {
+                delete this.filters.attributes.workload;
+            }
+            this.applyFilters();
+        },
+
+        loadSettings() {
+            try {
+                this.settings = mergeSettings(JSON.parse(localStorage.getItem(SETTINGS_KEY) || '{}'));
+            } catch (err) {
+                console.error('Failed to load settings:', err);
+            }
+            this.applyTheme();
+        },
+
+        // loadServerPreferences replaces the local settings with the user's
+        // saved ones, so they follow the user across browsers.
+        async loadServerPreferences() {
+            this.syncPreferences = true;
+            try {
+                const resp = await fetch('/api/preferences');
+                if (!resp.ok) return;
+                const saved = await resp.json();
+                if (Object.keys(saved).length > 0) {
+                    this.settings = mergeSettings(saved);
+                    localStorage.setItem(SETTINGS_KEY, JSON.stringify(this.settings));
+                    this.applyTheme();
+                }
+            } catch (err) {
+                console.error('Failed to load preferences:', err);
+            }
+        },
+
+        async saveSettings() {
+            localStorage.setItem(SETTINGS_KEY, JSON.stringify(this.settings));
+            this.applyTheme();
+            if (!this.syncPreferences) return;
+            try {
+                await fetch('/api/preferences', {
+                    method: 'PUT',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(this.settings)
+                });
+            } catch (err) {
+                console.error('Failed to save preferences:', err);
+            }
+        },
+
+        resetSettings() {
+            this.settings = defaultSettings();
+            this.saveSettings();
+        },
+
+        applyTheme() {
+            document.documentElement.classList.toggle('light', this.settings.theme === 'light');
+        },
+
+        async loadSavedQueries() {
+            try {
+                const resp = await fetch('/api/queries');
+                if (resp.ok) {
+                    this.savedQueries = (await resp.json()).queries;
+                }
+            } catch (err) {
+                console.error('Failed to load saved queries:', err);
+            }
+        },
+
+        // savedQueryParams encodes the current filters, without the time range,
+        // as a saved query string.
+        savedQueryParams() {
+            const params = new URLSearchParams();
+            if (this.filters.cluster) params.set('cluster', this.filters.cluster);
+            if (this.filters.namespace) params.set('namespace', this.filters.namespace);
+            if (this.filters.pod) params.set('pod', this.filters.pod);
+            if (this.filters.container) params.set('container', this.filters.container);
+            if (this.filters.minSeverity) params.set('minSeverity', this.filters.minSeverity);
+            if (this.filters.search) params.set('search', this.filters.search);
+            for (const [k, v] of Object.entries(this.filters.attributes)) {
+                params.set(`attr.${k}`, v);
+            }
+            return params.toString();
+        },
+
+        applySavedQuery() {
+            const saved = this.savedQueries.find(q => String(q.id) === this.selectedQueryId);
+            if (!saved) return;
+            const params = new URLSearchParams(saved.query);
+            const attributes = {};
+            for (const [k, v] of params) {
+                if (k.startsWith('attr.')) attributes[k.slice(5)] = v;
+            }
+            this.filters.cluster = params.get('cluster') || '';
+            this.filters.namespace = params.get('namespace') || '';
+            this.filters.pod = params.get('pod') || '';
+            this.filters.container = params.get('container') || '';
+            this.filters.minSeverity = parseInt(params.get('minSeverity')) || 0;
+            this.filters.search = params.get('search') || '';
+            this.filters.attributes = attributes;
+            this.applyFilters();
+        },
+
+        async saveQuery() {
+            const current = this.savedQueries.find(q => String(q.id) === this.selectedQueryId);
+            const name = prompt('Save filters as:', current ? current.name : '');
+            if (!name) return;
+
+            // Saving under an existing name overwrites that query
+            const existing = this.savedQueries.find(q => q.name === name);
+            const body = JSON.stringify({ name, query: this.savedQueryParams() });
+            try {
+                const resp = existing
+                    ? await fetch(`/api/queries/${existing.id}`, { method: 'PUT', body })
+                    : await fetch('/api/queries', { method: 'POST', body });
+                if (!resp.ok) {
+                    alert(await resp.text());
+                    return;
+                }
+                const id = existing ? existing.id : (await resp.json()).id;
+                await this.loadSavedQueries();
+                this.selectedQueryId = String(id);
+            } catch (err) {
+                console.error('Failed to save query:', err);
+            }
+        },
+
+        async deleteSavedQuery() {
+            const saved = this.savedQueries.find(q => String(q.id) === this.selectedQueryId);
+            if (!saved || !confirm(`Delete saved query "${saved.name}"?`)) return;
+            try {
+                await fetch(`/api/queries/${saved.id}`, { method: 'DELETE' });
+                this.selectedQueryId = '';
+                await this.loadSavedQueries();
+            } catch (err) {
+                console.error('Failed to delete saved query:', err);
+            }
         },
 
         async loadStats() {
@@ -308,6 +579,17 @@ function app() {
             }
         },
 
+        async loadCollectors() {
+            try {
+                const resp = await fetch('/api/collectors');
+                const data = await resp.json();
+                this.collectors = data.collectors;
+                this.collectorSummary = data.summary;
+            } catch (err) {
+                console.error('Failed to load collectors:', err);
+            }
+        },
+
         stopStreaming() {
             if (this.eventSource) {
                 this.eventSource.close();
@@ -320,6 +602,7 @@ function app() {
             this.stopStreaming();
 
             const params = new URLSearchParams();
+            if (this.filters.cluster) params.set('cluster', this.filters.cluster);
             if (this.filters.namespace) params.set('namespace', this.filters.namespace);
             if (this.filters.pod) params.set('pod', this.filters.pod);
             if (this.filters.container) params.set('container', this.filters.container);
@@ -347,15 +630,18 @@ function app() {
 
             params.set('order', 'desc');
             params.set('limit', '100');
+            params.set('count', 'true');
 
             try {
                 const resp = await fetch(`/api/logs?${params}`);
                 const data = await resp.json();
+                this.resultTotal = data.total ?? -1;
+                this.resultCapped = !!data.totalCapped;
 
                 if (data.entries && data.entries.length > 0) {
                     // Reverse to show chronological order (oldest first in array)
                     this.entries = data.entries.reverse();
-                    this.oldestLoadedId = this.entries[0].id;
+                    this.olderCursor = data.nextCursor || null;
                     this.hasMoreOlder = data.hasMore;
 
                     // Populate seenIds for deduplication
@@ -371,7 +657,7 @@ function app() {
                     });
                 } else {
                     this.entries = [];
-                    this.oldestLoadedId = null;
+                    this.olderCursor = null;
                     this.hasMoreOlder = false;
                     this.seenIds = new Set();
                     this.lastSeenId = null;
@@ -385,8 +671,10 @@ function app() {
             if (this.eventSource) {
                 this.eventSource.close();
             }
+            this.resultTotal = -1;
 
             const params = new URLSearchParams();
+            if (this.filters.cluster) params.set('cluster', this.filters.cluster);
             if (this.filters.namespace) params.set('namespace', this.filters.namespace);
             if (this.filters.pod) params.set('pod', this.filters.pod);
             if (this.filters.container) params.set('container', this.filters.container);
@@ -397,9 +685,9 @@ function app() {
             }
             // Note: Live mode doesn't use time filter - streams all new entries
 
-            // If reconnecting, pass lastSeenId to skip initial batch (server-side optimization)
+            // If reconnecting, resume after lastSeenId and skip the initial batch
             if (this.lastSeenId) {
-                params.set('lastId', this.lastSeenId);
+                params.set('afterId', this.lastSeenId);
             }
 
             this.eventSource = new EventSource(`/api/logs/stream?${params}`);
@@ -424,19 +712,10 @@ function app() {
                     this.lastSeenId = entry.id;
                 }
 
-                // Track oldest loaded ID for backward pagination
-                if (this.oldestLoadedId === null || entry.id < this.oldestLoadedId) {
-                    this.oldestLoadedId = entry.id;
-                }
-
                 // Keep max entries in memory (trim oldest when tailing)
                 while (this.entries.length > this.maxEntries) {
                     const removed = this.entries.shift();
                     this.seenIds.delete(removed.id);
-                    // Update oldest ID when removing from front
-                    if (this.entries.length > 0) {
-                        this.oldestLoadedId = this.entries[0].id;
-                    }
                 }
 
                 // Auto-scroll if tailing
@@ -450,6 +729,13 @@ function app() {
                 }
             };
 
+            // Cursor for paging back past the initial batch (only sent when more exist)
+            this.eventSource.addEventListener('cursor', (e) => {
+                const data = JSON.parse(e.data);
+                this.olderCursor = data.cursor;
+                this.hasMoreOlder = true;
+            });
+
             this.eventSource.onerror = () => {
                 this.connected = false;
                 // Reconnect after 2 seconds
@@ -473,7 +759,7 @@ function app() {
 
         applyFilters() {
             this.entries = [];
-            this.oldestLoadedId = null;
+            this.olderCursor = null;
             this.hasMoreOlder = true;
             this.loadingOlder = false;
             this.lastSeenId = null;
@@ -490,7 +776,7 @@ function app() {
 
         clearLogs() {
             this.entries = [];
-            this.oldestLoadedId = null;
+            this.olderCursor = null;
             this.hasMoreOlder = true;
             this.lastSeenId = null;
             this.seenIds = new Set();
@@ -500,11 +786,16 @@ function app() {
             if (this.loadingOlder || !this.hasMoreOlder || this.entries.length === 0) {
                 return;
             }
+            if (!this.olderCursor) {
+                this.hasMoreOlder = false;
+                return;
+            }
 
             this.loadingOlder = true;
 
             // Build query params matching current filters
             const params = new URLSearchParams();
+            if (this.filters.cluster) params.set('cluster', this.filters.cluster);
             if (this.filters.namespace) params.set('namespace', this.filters.namespace);
             if (this.filters.pod) params.set('pod', this.filters.pod);
             if (this.filters.container) params.set('container', this.filters.container);
@@ -532,16 +823,23 @@ function app() {
                 }
             }
 
-            // Use beforeId for backward pagination with descending order
-            params.set('beforeId', this.oldestLoadedId);
+            // Cursor tokens are tied to the filters and order they were issued for
+            params.set('cursor', this.olderCursor);
             params.set('order', 'desc');
             params.set('limit', '100');
 
             try {
                 const resp = await fetch(`/api/logs?${params}`);
+                if (!resp.ok) {
+                    // Stale or mismatched cursor
+                    this.olderCursor = null;
+                    this.hasMoreOlder = false;
+                    return;
+                }
                 const data = await resp.json();
 
                 if (!data.entries || data.entries.length === 0) {
+                    this.olderCursor = null;
                     this.hasMoreOlder = false;
                 } else {
                     // Preserve scroll position
@@ -558,10 +856,7 @@ function app() {
 
                     this.entries = [...olderEntries, ...this.entries];
 
-                    // Update cursor to oldest entry
-                    if (olderEntries.length > 0) {
-                        this.oldestLoadedId = olderEntries[0].id;
-                    }
+                    this.olderCursor = data.nextCursor || null;
                     this.hasMoreOlder = data.hasMore;
 
                     // Trim from end if exceeding maxEntries (remove newest when in historical mode)
@@ -671,8 +966,16 @@ function app() {
                         this.closeDetailPanel();
                     } else if (this.showShortcuts) {
                         this.showShortcuts = false;
+                    } else if (this.showStorage) {
+                        this.showStorage = false;
+                    } else if (this.showCollectors) {
+                        this.showCollectors = false;
+                    } else if (this.showSettings) {
+                        this.showSettings = false;
+                    } else if (this.showContext) {
+                        this.showContext = false;
                     } else {
-                        this.filters = { namespace: '', pod: '', container: '', minSeverity: 0, search: '', timeSpan: 'live', startTime: '', endTime: '', attributes: {} };
+                        this.filters = { cluster: '', namespace: '', pod: '', container: '', minSeverity: 0, search: '', timeSpan: 'live', startTime: '', endTime: '', attributes: {} };
                         this.applyFilters();
                     }
                     break;
@@ -686,6 +989,46 @@ function app() {
                    `${pad(date.getHours())}:${pad(date.getMinutes())}:${pad(date.getSeconds())}.${pad(date.getMilliseconds(), 3)}`;
         },
 
+        // formatEntryTime formats an entry's timestamp in the table as chosen in
+        // the settings.
+        formatEntryTime(nanos) {
+            const date = new Date(nanos / 1000000);
+            switch (this.settings.timestampFormat) {
+                case 'time': {
+                    const pad = (n, w = 2) => String(n).padStart(w, '0');
+                    return `${pad(date.getHours())}:${pad(date.getMinutes())}:${pad(date.getSeconds())}.${pad(date.getMilliseconds(), 3)}`;
+                }
+                case 'utc':
+                    return date.toISOString();
+                default:
+                    return this.formatTimestamp(nanos);
+            }
+        },
+
+        formatBytes(n) {
+            const units = ['B', 'KB', 'MB', 'GB', 'TB'];
+            let i = 0;
+            while (n >= 1024 && i < units.length - 1) {
+                n /= 1024;
+                i++;
+            }
+            return `${i === 0 ? n : n.toFixed(1)} ${units[i]}`;
+        },
+
+        // Share of the largest namespace, for the usage bars
+        namespaceShare(ns) {
+            const max = this.stats.namespaces.length ? this.stats.namespaces[0].bytes : 0;
+            return max > 0 ? Math.round(ns.bytes / max * 100) : 0;
+        },
+
+        collectorHealthClass(health) {
+            switch (health) {
+                case 'healthy': return 'text-green-400';
+                case 'degraded': return 'text-yellow-400';
+                default: return 'text-gray-500';
+            }
+        },
+
         severityLabel(s) {
             const labels = ['UNK', 'TRC', 'DBG', 'INF', 'WRN', 'ERR', 'FTL'];
             return labels[s] || 'UNK';
@@ -712,9 +1055,22 @@ function app() {
         },
 
         // Render message with ANSI color support
-        renderMessage(text) {
-            if (!text) return '';
-            return parseAnsi(text);
+        renderMessage(entry) {
+            if (!entry?.message) return '';
+            return parseAnsi(entry.message, entry.links || []);
+        },
+
+        // Handles clicks on links in rendered messages. Returns true if a
+        // link was clicked, so the row or panel doesn't also act on it.
+        onMessageClick(event) {
+            const link = event.target.closest('a');
+            if (!link) return false;
+            event.stopPropagation();
+            if (link.dataset.traceId) {
+                event.preventDefault();
+                this.addQuickFilter('attr', 'trace_id', link.dataset.traceId);
+            }
+            return true;
         },
 
         selectEntry(entry) {
@@ -726,6 +1082,23 @@ function app() {
             this.detailPanelOpen = false;
         },
 
+        // showEntryContext opens the lines its container wrote around entry.
+        async showEntryContext(entry, lines = 50) {
+            if (!entry) return;
+            this.contextLines = lines;
+            try {
+                const resp = await fetch(`/api/logs/${entry.id}/context?before=${lines}&after=${lines}`);
+                if (!resp.ok) throw new Error(await resp.text());
+                this.context = await resp.json();
+                this.showContext = true;
+                this.$nextTick(() => {
+                    document.getElementById('context-entry')?.scrollIntoView({ block: 'center' });
+                });
+            } catch (err) {
+                console.error('Failed to load context:', err);
+            }
+        },
+
         truncateValue(value, maxLen = 12) {
             if (!value) return '';
             if (value.length <= maxLen) return value;
@@ -764,7 +1137,9 @@ function app() {
 
         addQuickFilter(type, key, value) {
             if (!value && value !== 0) return;
-            if (type === 'namespace') {
+            if (type === 'cluster') {
+                this.filters.cluster = value;
+            } else if (type === 'namespace') {
                 this.filters.namespace = value;
             } else if (type === 'pod') {
                 this.filters.pod = value;